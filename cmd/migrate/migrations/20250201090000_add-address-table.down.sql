@@ -0,0 +1,5 @@
+ALTER TABLE orders
+  DROP COLUMN `shippingAddress`,
+  DROP COLUMN `billingAddress`;
+
+DROP TABLE IF EXISTS addresses;
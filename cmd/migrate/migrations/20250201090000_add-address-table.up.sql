@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS addresses (
+  `id` CHAR(36) NOT NULL DEFAULT (UUID()),          -- Unique identifier for the address
+  `userId` CHAR(36) NOT NULL,                       -- Owner of the address
+  `label` VARCHAR(64) NOT NULL,                     -- User facing label (e.g., Home, Office)
+  `fullName` VARCHAR(255) NOT NULL,                 -- Recipient name
+  `phone` VARCHAR(20) NOT NULL DEFAULT '',          -- Recipient phone number (E.164)
+  `line1` VARCHAR(255) NOT NULL DEFAULT '',
+  `line2` VARCHAR(255) NOT NULL DEFAULT '',
+  `city` VARCHAR(255) NOT NULL DEFAULT '',
+  `state` VARCHAR(255) NOT NULL DEFAULT '',
+  `postalCode` VARCHAR(20) NOT NULL DEFAULT '',
+  `country` VARCHAR(2) NOT NULL DEFAULT '',         -- ISO 3166-1 alpha-2 country code
+  `isDefaultShipping` BOOLEAN NOT NULL DEFAULT FALSE,
+  `isDefaultBilling` BOOLEAN NOT NULL DEFAULT FALSE,
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updatedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id),
+  INDEX (`userId`),
+  FOREIGN KEY (userId) REFERENCES users(`id`) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+ALTER TABLE orders
+  ADD COLUMN `shippingAddress` JSON DEFAULT NULL,   -- Snapshot of the shipping address at checkout
+  ADD COLUMN `billingAddress` JSON DEFAULT NULL;    -- Snapshot of the billing address at checkout
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS carts (
+  `id` CHAR(36) NOT NULL DEFAULT (UUID()),          -- Unique identifier for the cart
+  `userId` CHAR(36) NULL DEFAULT NULL,              -- Owner of the cart, NULL for anonymous carts
+  `sessionId` CHAR(36) NULL DEFAULT NULL,           -- Anonymous session the cart belongs to
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updatedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id),
+  UNIQUE KEY (userId),
+  UNIQUE KEY (sessionId),
+  INDEX (`updatedAt`),                              -- Abandoned cart lookups
+  FOREIGN KEY (userId) REFERENCES users(`id`) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS cartitems (
+  `id` CHAR(36) NOT NULL DEFAULT (UUID()),
+  `cartId` CHAR(36) NOT NULL,
+  `productId` CHAR(36) NOT NULL,
+  `quantity` INT UNSIGNED NOT NULL,
+  `priceAtAdd` DECIMAL(10, 2) NOT NULL,             -- Unit price when the item was added or last updated
+  `currency` CHAR(3) NOT NULL,                      -- ISO 4217 currency code
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updatedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id),
+  UNIQUE KEY (cartId, productId),
+  FOREIGN KEY (cartId) REFERENCES carts(`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+  FOREIGN KEY (productId) REFERENCES products(`productId`) ON DELETE CASCADE ON UPDATE CASCADE
+);
@@ -0,0 +1,6 @@
+DELETE FROM orders WHERE userId IS NULL;
+
+ALTER TABLE orders
+  DROP INDEX `guestEmail`,
+  DROP COLUMN `guestEmail`,
+  MODIFY COLUMN `userId` CHAR(36) NOT NULL;
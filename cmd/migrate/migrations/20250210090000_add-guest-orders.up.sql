@@ -0,0 +1,4 @@
+ALTER TABLE orders
+  MODIFY COLUMN `userId` CHAR(36) NULL DEFAULT NULL,            -- NULL for guest orders until the buyer registers
+  ADD COLUMN `guestEmail` VARCHAR(255) NULL DEFAULT NULL,       -- Buyer email for guest checkout
+  ADD INDEX (`guestEmail`);
@@ -0,0 +1,5 @@
+ALTER TABLE orderitems DROP COLUMN `appliedDiscounts`;
+ALTER TABLE orders DROP COLUMN `discount`;
+
+DROP TABLE IF EXISTS promotionredemptions;
+DROP TABLE IF EXISTS promotions;
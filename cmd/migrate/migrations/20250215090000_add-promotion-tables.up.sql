@@ -0,0 +1,47 @@
+CREATE TABLE IF NOT EXISTS promotions (
+  `id` CHAR(36) NOT NULL DEFAULT (UUID()),
+  `code` VARCHAR(64) NULL DEFAULT NULL,             -- Coupon code, NULL for promotions applied automatically
+  `name` VARCHAR(255) NOT NULL,
+  `type` ENUM('percentage', 'fixed_amount', 'free_shipping', 'buy_x_get_y') NOT NULL,
+  `value` DECIMAL(10, 2) NOT NULL DEFAULT 0,        -- Percentage or amount, depending on type
+  `currency` CHAR(3) NOT NULL DEFAULT '',           -- ISO 4217 currency of value and minSpend for fixed amounts
+  `buyQuantity` INT UNSIGNED NOT NULL DEFAULT 0,
+  `getQuantity` INT UNSIGNED NOT NULL DEFAULT 0,
+  `productIds` JSON DEFAULT NULL,                   -- Restricts the promotion to these products
+  `categories` JSON DEFAULT NULL,                   -- Restricts the promotion to these categories
+  `minSpend` DECIMAL(10, 2) NOT NULL DEFAULT 0,
+  `startsAt` TIMESTAMP NULL DEFAULT NULL,
+  `endsAt` TIMESTAMP NULL DEFAULT NULL,
+  `usageLimit` INT UNSIGNED NOT NULL DEFAULT 0,     -- 0 means unlimited
+  `perUserLimit` INT UNSIGNED NOT NULL DEFAULT 0,   -- 0 means unlimited
+  `usageCount` INT UNSIGNED NOT NULL DEFAULT 0,
+  `isActive` BOOLEAN NOT NULL DEFAULT TRUE,
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updatedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id),
+  UNIQUE KEY (code)
+);
+
+CREATE TABLE IF NOT EXISTS promotionredemptions (
+  `id` CHAR(36) NOT NULL DEFAULT (UUID()),
+  `promotionId` CHAR(36) NOT NULL,
+  `orderId` CHAR(36) NOT NULL,
+  `userId` CHAR(36) NULL DEFAULT NULL,
+  `guestEmail` VARCHAR(255) NULL DEFAULT NULL,
+  `code` VARCHAR(64) NOT NULL DEFAULT '',
+  `discount` DECIMAL(10, 2) NOT NULL DEFAULT 0,     -- Amount taken off the order by the promotion
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id),
+  INDEX (`promotionId`, `userId`),
+  INDEX (`promotionId`, `guestEmail`),
+  FOREIGN KEY (promotionId) REFERENCES promotions(`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+  FOREIGN KEY (orderId) REFERENCES orders(`id`) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+ALTER TABLE orders
+  ADD COLUMN `discount` DECIMAL(10, 2) NOT NULL DEFAULT 0;      -- Total discount from promotions
+
+ALTER TABLE orderitems
+  ADD COLUMN `appliedDiscounts` JSON DEFAULT NULL;              -- Promotions that make up the discount, for auditing
@@ -0,0 +1,10 @@
+ALTER TABLE orderitems DROP COLUMN `taxBreakdown`;
+
+ALTER TABLE orders
+  DROP COLUMN `taxInclusive`,
+  DROP COLUMN `taxBreakdown`,
+  DROP COLUMN `tax`;
+
+ALTER TABLE products DROP COLUMN `taxCategory`;
+
+DROP TABLE IF EXISTS taxrates;
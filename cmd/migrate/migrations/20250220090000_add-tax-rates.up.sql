@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS taxrates (
+  `id` CHAR(36) NOT NULL DEFAULT (UUID()),
+  `name` VARCHAR(64) NOT NULL,                      -- Tax name shown in the breakdown (e.g., VAT, State tax)
+  `country` VARCHAR(64) NOT NULL,                   -- Country as stored on addresses
+  `region` VARCHAR(64) NOT NULL DEFAULT '',         -- State or region, empty for the whole country
+  `taxCategory` VARCHAR(64) NOT NULL DEFAULT '',    -- Product tax category, empty for every category
+  `rate` DECIMAL(7, 4) NOT NULL,                    -- Rate in percent
+  `isActive` BOOLEAN NOT NULL DEFAULT TRUE,
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updatedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id),
+  UNIQUE KEY (country, region, taxCategory, name)
+);
+
+ALTER TABLE products
+  ADD COLUMN `taxCategory` VARCHAR(64) NOT NULL DEFAULT '';     -- Tax category, empty for the standard rate
+
+ALTER TABLE orders
+  ADD COLUMN `tax` DECIMAL(10, 2) NOT NULL DEFAULT 0,           -- Total tax of the order
+  ADD COLUMN `taxBreakdown` JSON DEFAULT NULL,                  -- Tax per name and rate
+  ADD COLUMN `taxInclusive` BOOLEAN NOT NULL DEFAULT FALSE;     -- Tax is contained in the prices rather than added
+
+ALTER TABLE orderitems
+  ADD COLUMN `taxBreakdown` JSON DEFAULT NULL;                  -- Tax per name and rate for this item
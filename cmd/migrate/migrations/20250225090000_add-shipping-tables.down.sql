@@ -0,0 +1,13 @@
+ALTER TABLE orders
+  DROP COLUMN `shippingCost`,
+  DROP COLUMN `shippingMethod`,
+  DROP COLUMN `shippingMethodId`;
+
+ALTER TABLE products
+  DROP COLUMN `height`,
+  DROP COLUMN `width`,
+  DROP COLUMN `length`,
+  DROP COLUMN `weight`;
+
+DROP TABLE IF EXISTS shippingmethods;
+DROP TABLE IF EXISTS shippingzones;
@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS shippingzones (
+  `id` CHAR(36) NOT NULL DEFAULT (UUID()),
+  `name` VARCHAR(255) NOT NULL,
+  `countries` JSON NOT NULL,                        -- Countries as stored on addresses, "*" for every destination
+  `regions` JSON DEFAULT NULL,                      -- Restricts the zone to these states or regions
+  `isActive` BOOLEAN NOT NULL DEFAULT TRUE,
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updatedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS shippingmethods (
+  `id` CHAR(36) NOT NULL DEFAULT (UUID()),
+  `zoneId` CHAR(36) NOT NULL,
+  `name` VARCHAR(255) NOT NULL,
+  `rateType` ENUM('flat', 'weight', 'price') NOT NULL,
+  `flatRate` DECIMAL(10, 2) NOT NULL DEFAULT 0,
+  `tiers` JSON DEFAULT NULL,                        -- Rate table for weight and price methods
+  `freeAbove` DECIMAL(10, 2) NOT NULL DEFAULT 0,    -- Order amount from which shipping is free, 0 for never
+  `volumetricDivisor` DECIMAL(10, 2) NOT NULL DEFAULT 0,
+  `currency` CHAR(3) NOT NULL,
+  `minDeliveryDays` INT UNSIGNED NOT NULL DEFAULT 0,
+  `maxDeliveryDays` INT UNSIGNED NOT NULL DEFAULT 0,
+  `isActive` BOOLEAN NOT NULL DEFAULT TRUE,
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updatedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id),
+  FOREIGN KEY (zoneId) REFERENCES shippingzones(`id`) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+ALTER TABLE products
+  ADD COLUMN `weight` DECIMAL(10, 3) NOT NULL DEFAULT 0,        -- Weight in kilograms
+  ADD COLUMN `length` DECIMAL(10, 2) NOT NULL DEFAULT 0,        -- Dimensions in centimetres
+  ADD COLUMN `width` DECIMAL(10, 2) NOT NULL DEFAULT 0,
+  ADD COLUMN `height` DECIMAL(10, 2) NOT NULL DEFAULT 0;
+
+ALTER TABLE orders
+  ADD COLUMN `shippingMethodId` CHAR(36) NULL DEFAULT NULL,
+  ADD COLUMN `shippingMethod` VARCHAR(255) NOT NULL DEFAULT '', -- Method name at checkout
+  ADD COLUMN `shippingCost` DECIMAL(10, 2) NOT NULL DEFAULT 0;  -- Included in total
@@ -0,0 +1,22 @@
+ALTER TABLE promotionredemptions
+  MODIFY COLUMN `discount` DECIMAL(10, 2) NOT NULL DEFAULT 0;
+
+ALTER TABLE orderitems
+  MODIFY COLUMN `price` DECIMAL(10, 2) NOT NULL,
+  MODIFY COLUMN `totalPrice` DECIMAL(10, 2) NOT NULL,
+  MODIFY COLUMN `subTotal` DECIMAL(10, 2) NOT NULL,
+  MODIFY COLUMN `discount` DECIMAL(10, 2) NOT NULL DEFAULT 0,
+  MODIFY COLUMN `tax` DECIMAL(10, 2) NOT NULL DEFAULT 0;
+
+ALTER TABLE orders
+  MODIFY COLUMN `total` DECIMAL(10, 2) NOT NULL,
+  MODIFY COLUMN `subtotal` DECIMAL(10, 2) NOT NULL,
+  MODIFY COLUMN `discount` DECIMAL(10, 2) NOT NULL DEFAULT 0,
+  MODIFY COLUMN `tax` DECIMAL(10, 2) NOT NULL DEFAULT 0,
+  MODIFY COLUMN `shippingCost` DECIMAL(10, 2) NOT NULL DEFAULT 0;
+
+ALTER TABLE cartitems
+  MODIFY COLUMN `priceAtAdd` DECIMAL(10, 2) NOT NULL;
+
+ALTER TABLE products
+  MODIFY COLUMN `price` DECIMAL(10, 2) NOT NULL;
@@ -0,0 +1,24 @@
+-- Amounts are kept in minor units in the application. Four decimals let the
+-- columns hold three-decimal currencies such as BHD or KWD exactly.
+ALTER TABLE products
+  MODIFY COLUMN `price` DECIMAL(19, 4) NOT NULL;
+
+ALTER TABLE cartitems
+  MODIFY COLUMN `priceAtAdd` DECIMAL(19, 4) NOT NULL;
+
+ALTER TABLE orders
+  MODIFY COLUMN `total` DECIMAL(19, 4) NOT NULL,
+  MODIFY COLUMN `subtotal` DECIMAL(19, 4) NOT NULL,
+  MODIFY COLUMN `discount` DECIMAL(19, 4) NOT NULL DEFAULT 0,
+  MODIFY COLUMN `tax` DECIMAL(19, 4) NOT NULL DEFAULT 0,
+  MODIFY COLUMN `shippingCost` DECIMAL(19, 4) NOT NULL DEFAULT 0;
+
+ALTER TABLE orderitems
+  MODIFY COLUMN `price` DECIMAL(19, 4) NOT NULL,
+  MODIFY COLUMN `totalPrice` DECIMAL(19, 4) NOT NULL,
+  MODIFY COLUMN `subTotal` DECIMAL(19, 4) NOT NULL,
+  MODIFY COLUMN `discount` DECIMAL(19, 4) NOT NULL DEFAULT 0,
+  MODIFY COLUMN `tax` DECIMAL(19, 4) NOT NULL DEFAULT 0;
+
+ALTER TABLE promotionredemptions
+  MODIFY COLUMN `discount` DECIMAL(19, 4) NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS exchangerates;
+DROP TABLE IF EXISTS productprices;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS productprices (
+  `productId` CHAR(36) NOT NULL,
+  `currency` CHAR(3) NOT NULL,                      -- ISO 4217 currency code
+  `price` DECIMAL(19, 4) NOT NULL,                  -- Price in that currency, overrides the converted base price
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updatedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (productId, currency),
+  FOREIGN KEY (productId) REFERENCES products(productId) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS exchangerates (
+  `base` CHAR(3) NOT NULL,                          -- Currency converted from
+  `quote` CHAR(3) NOT NULL,                         -- Currency converted to
+  `rate` DECIMAL(18, 8) NOT NULL,                   -- Units of quote per unit of base
+  `updatedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (base, quote)
+);
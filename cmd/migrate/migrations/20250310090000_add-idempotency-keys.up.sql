@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS idempotencykeys (
+  `idempotencyKey` VARCHAR(255) NOT NULL,           -- Idempotency-Key header sent by the client
+  `scope` VARCHAR(36) NOT NULL DEFAULT '',          -- User the key belongs to, empty for anonymous requests
+  `fingerprint` CHAR(64) NOT NULL,                  -- SHA-256 of the method, path and body
+  `statusCode` INT NOT NULL DEFAULT 0,
+  `responseHeaders` JSON DEFAULT NULL,
+  `responseBody` MEDIUMBLOB DEFAULT NULL,
+  `completed` BOOLEAN NOT NULL DEFAULT FALSE,       -- False while the first request is in flight
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `expiresAt` TIMESTAMP NOT NULL,
+
+  PRIMARY KEY (idempotencyKey, scope),
+  KEY (expiresAt)
+);
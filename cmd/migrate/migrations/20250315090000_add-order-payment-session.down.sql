@@ -0,0 +1,3 @@
+ALTER TABLE orders
+  DROP KEY `paymentSessionId`,
+  DROP COLUMN `paymentSessionId`;
@@ -0,0 +1,3 @@
+ALTER TABLE orders
+  ADD COLUMN `paymentSessionId` VARCHAR(255) NULL DEFAULT NULL, -- Stripe Checkout Session the buyer pays through
+  ADD KEY (paymentSessionId);
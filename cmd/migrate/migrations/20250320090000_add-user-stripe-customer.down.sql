@@ -0,0 +1,3 @@
+ALTER TABLE users
+  DROP KEY `stripeCustomerId`,
+  DROP COLUMN `stripeCustomerId`;
@@ -0,0 +1,3 @@
+ALTER TABLE users
+  ADD COLUMN `stripeCustomerId` VARCHAR(255) NULL DEFAULT NULL, -- Stripe customer created for the user, NULL until they pay
+  ADD UNIQUE KEY (stripeCustomerId);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS payment_events (
+  `id` VARCHAR(255) NOT NULL,                                   -- Stripe event ID, delivering an event again does not add a row
+  `type` VARCHAR(255) NOT NULL,
+  `payload` JSON NOT NULL,                                      -- Verified event body as received
+  `status` VARCHAR(20) NOT NULL DEFAULT 'pending',              -- pending, processing, processed, failed or dead
+  `attempts` INT NOT NULL DEFAULT 0,
+  `lastError` TEXT DEFAULT NULL,
+  `nextAttemptAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, -- Due for the worker after this
+  `processedAt` TIMESTAMP NULL DEFAULT NULL,
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updatedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id),
+  KEY (status, nextAttemptAt)
+);
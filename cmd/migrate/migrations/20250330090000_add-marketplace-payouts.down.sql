@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS store_payouts;
+DROP TABLE IF EXISTS commission_rules;
+
+ALTER TABLE orderitems
+  DROP COLUMN `category`,
+  DROP COLUMN `storeId`;
+
+ALTER TABLE products
+  DROP FOREIGN KEY `fk_products_store`,
+  DROP KEY `storeId`,
+  DROP COLUMN `storeId`;
+
+ALTER TABLE storeowners
+  DROP KEY `stripeAccountId`,
+  DROP COLUMN `stripeAccountId`;
@@ -0,0 +1,44 @@
+ALTER TABLE storeowners
+  ADD COLUMN `stripeAccountId` VARCHAR(255) NULL DEFAULT NULL UNIQUE; -- Stripe connected account the store is paid out to
+
+ALTER TABLE products
+  ADD COLUMN `storeId` CHAR(36) NULL DEFAULT NULL,                   -- Store selling the product, NULL for the platform's own products
+  ADD KEY (storeId),
+  ADD CONSTRAINT fk_products_store FOREIGN KEY (storeId) REFERENCES storeowners(`storeId`) ON DELETE SET NULL ON UPDATE CASCADE;
+
+ALTER TABLE orderitems
+  ADD COLUMN `storeId` CHAR(36) NULL DEFAULT NULL,                   -- Store the item was sold by at checkout
+  ADD COLUMN `category` VARCHAR(255) NOT NULL DEFAULT '';            -- Product category at checkout, for commission rules
+
+CREATE TABLE IF NOT EXISTS commission_rules (
+  `id` CHAR(36) NOT NULL DEFAULT (UUID()),
+  `storeId` CHAR(36) NULL DEFAULT NULL,                              -- Limits the rule to a store
+  `category` VARCHAR(255) NOT NULL DEFAULT '',                       -- Limits the rule to a product category
+  `rate` DECIMAL(5, 2) NOT NULL,                                     -- Percentage of the sale kept by the platform
+  `isActive` BOOLEAN NOT NULL DEFAULT TRUE,
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updatedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id),
+  FOREIGN KEY (storeId) REFERENCES storeowners(`storeId`) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS store_payouts (
+  `id` CHAR(36) NOT NULL DEFAULT (UUID()),
+  `storeId` CHAR(36) NOT NULL,
+  `orderId` CHAR(36) NOT NULL,
+  `gross` DECIMAL(19, 4) NOT NULL,                                   -- What the buyer paid for the store's items, without tax
+  `commission` DECIMAL(19, 4) NOT NULL,                              -- Kept by the platform
+  `amount` DECIMAL(19, 4) NOT NULL,                                  -- Owed to the store
+  `currency` CHAR(3) NOT NULL,
+  `status` VARCHAR(20) NOT NULL DEFAULT 'pending',                   -- pending, paid or reversed
+  `transferId` VARCHAR(255) NULL DEFAULT NULL,                       -- Stripe transfer that paid the store
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updatedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id),
+  UNIQUE KEY (storeId, orderId),
+  KEY (orderId),
+  FOREIGN KEY (storeId) REFERENCES storeowners(`storeId`) ON DELETE CASCADE ON UPDATE CASCADE,
+  FOREIGN KEY (orderId) REFERENCES orders(`id`) ON DELETE CASCADE ON UPDATE CASCADE
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS shipments;
+DROP TABLE IF EXISTS store_orders;
@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS store_orders (
+  `id` CHAR(36) NOT NULL DEFAULT (UUID()),
+  `orderId` CHAR(36) NOT NULL,                                       -- Order the buyer placed
+  `storeId` CHAR(36) NULL DEFAULT NULL,                              -- Store fulfilling the sub-order, NULL for the platform's own products
+  `status` ENUM('pending', 'processing', 'shipped', 'completed', 'cancelled', 'refunded')
+      NOT NULL DEFAULT 'pending',
+  `subtotal` DECIMAL(19, 4) NOT NULL,                                -- Items before discounts and tax
+  `discount` DECIMAL(19, 4) NOT NULL DEFAULT 0,
+  `tax` DECIMAL(19, 4) NOT NULL DEFAULT 0,
+  `shippingCost` DECIMAL(19, 4) NOT NULL DEFAULT 0,                  -- Share of the order's shipping
+  `total` DECIMAL(19, 4) NOT NULL,                                   -- Share of the order's total
+  `currency` CHAR(3) NOT NULL,
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updatedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id),
+  KEY (orderId),
+  KEY (storeId),
+  FOREIGN KEY (orderId) REFERENCES orders(`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+  FOREIGN KEY (storeId) REFERENCES storeowners(`storeId`) ON DELETE SET NULL ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS shipments (
+  `id` CHAR(36) NOT NULL DEFAULT (UUID()),
+  `storeOrderId` CHAR(36) NOT NULL,
+  `carrier` VARCHAR(100) NOT NULL,
+  `trackingNumber` VARCHAR(255) NOT NULL,
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id),
+  KEY (storeOrderId),
+  FOREIGN KEY (storeOrderId) REFERENCES store_orders(`id`) ON DELETE CASCADE ON UPDATE CASCADE
+);
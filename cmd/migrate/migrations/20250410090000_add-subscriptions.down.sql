@@ -0,0 +1,12 @@
+ALTER TABLE orders
+  DROP FOREIGN KEY `fk_orders_subscription`,
+  DROP KEY `subscriptionId`,
+  DROP COLUMN `subscriptionId`,
+  DROP COLUMN `invoiceId`;
+
+DROP TABLE IF EXISTS subscriptions;
+
+ALTER TABLE products
+  DROP COLUMN `billingIntervalCount`,
+  DROP COLUMN `billingInterval`,
+  DROP COLUMN `isSubscribable`;
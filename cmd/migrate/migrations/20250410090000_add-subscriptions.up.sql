@@ -0,0 +1,36 @@
+ALTER TABLE products
+  ADD COLUMN `isSubscribable` BOOLEAN NOT NULL DEFAULT FALSE,        -- Can be bought as a recurring subscription
+  ADD COLUMN `billingInterval` VARCHAR(10) NOT NULL DEFAULT '',      -- day, week, month or year, empty when not subscribable
+  ADD COLUMN `billingIntervalCount` INT NOT NULL DEFAULT 1;          -- Number of intervals between renewals
+
+CREATE TABLE IF NOT EXISTS subscriptions (
+  `id` CHAR(36) NOT NULL DEFAULT (UUID()),
+  `userId` CHAR(36) NOT NULL,
+  `productId` CHAR(36) NOT NULL,
+  `storeId` CHAR(36) NULL DEFAULT NULL,                              -- Store selling the product at subscription time
+  `category` VARCHAR(255) NOT NULL DEFAULT '',
+  `productName` VARCHAR(255) NOT NULL,
+  `quantity` INT NOT NULL,
+  `unitPrice` DECIMAL(19, 4) NOT NULL,                               -- Price per unit charged on every renewal
+  `currency` CHAR(3) NOT NULL,
+  `billingInterval` VARCHAR(10) NOT NULL,
+  `billingIntervalCount` INT NOT NULL DEFAULT 1,
+  `status` VARCHAR(20) NOT NULL DEFAULT 'incomplete',                -- incomplete, active, paused, past_due or cancelled
+  `paymentSubscriptionId` VARCHAR(255) NULL DEFAULT NULL UNIQUE,     -- Subscription at the payment gateway
+  `shippingAddress` JSON NOT NULL,                                   -- Renewals are shipped here
+  `renewsAt` TIMESTAMP NULL DEFAULT NULL,                            -- When the next renewal is charged
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updatedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id),
+  KEY (userId),
+  FOREIGN KEY (userId) REFERENCES users(`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+  FOREIGN KEY (productId) REFERENCES products(`productId`) ON DELETE CASCADE ON UPDATE CASCADE,
+  FOREIGN KEY (storeId) REFERENCES storeowners(`storeId`) ON DELETE SET NULL ON UPDATE CASCADE
+);
+
+ALTER TABLE orders
+  ADD COLUMN `subscriptionId` CHAR(36) NULL DEFAULT NULL,            -- Subscription the order renews
+  ADD COLUMN `invoiceId` VARCHAR(255) NULL DEFAULT NULL UNIQUE,      -- Gateway invoice that paid the renewal
+  ADD KEY (subscriptionId),
+  ADD CONSTRAINT fk_orders_subscription FOREIGN KEY (subscriptionId) REFERENCES subscriptions(`id`) ON DELETE SET NULL ON UPDATE CASCADE;
@@ -0,0 +1,7 @@
+ALTER TABLE orders
+  DROP COLUMN `storeCredit`;
+
+DROP TABLE IF EXISTS order_tenders;
+DROP TABLE IF EXISTS wallet_entries;
+DROP TABLE IF EXISTS wallets;
+DROP TABLE IF EXISTS gift_cards;
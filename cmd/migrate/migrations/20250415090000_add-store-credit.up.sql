@@ -0,0 +1,64 @@
+CREATE TABLE IF NOT EXISTS gift_cards (
+  `id` CHAR(36) NOT NULL DEFAULT (UUID()),
+  `code` VARCHAR(32) NOT NULL UNIQUE,                                -- Redeemable code, stored without separators
+  `initialBalance` DECIMAL(19, 4) NOT NULL,                          -- Value the card was issued with
+  `balance` DECIMAL(19, 4) NOT NULL,                                 -- Value left to spend
+  `currency` CHAR(3) NOT NULL,
+  `status` VARCHAR(20) NOT NULL DEFAULT 'active',                    -- active or disabled
+  `recipientEmail` VARCHAR(255) NULL DEFAULT NULL,                   -- Who the card was issued to
+  `note` VARCHAR(255) NOT NULL DEFAULT '',
+  `expiresAt` TIMESTAMP NULL DEFAULT NULL,                           -- The card cannot be spent after, NULL when it never expires
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updatedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS wallets (
+  `userId` CHAR(36) NOT NULL,
+  `currency` CHAR(3) NOT NULL,
+  `balance` DECIMAL(19, 4) NOT NULL DEFAULT 0,                       -- Sum of the wallet entries of the user in the currency
+  `updatedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (userId, currency),
+  FOREIGN KEY (userId) REFERENCES users(`id`) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS wallet_entries (
+  `id` CHAR(36) NOT NULL DEFAULT (UUID()),
+  `userId` CHAR(36) NOT NULL,
+  `amount` DECIMAL(19, 4) NOT NULL,                                  -- Credited when positive, spent when negative
+  `currency` CHAR(3) NOT NULL,
+  `reason` VARCHAR(20) NOT NULL,                                     -- gift_card, order, release, refund or adjustment
+  `orderId` CHAR(36) NULL DEFAULT NULL,                              -- Order the credit was spent on or refunded from
+  `giftCardId` CHAR(36) NULL DEFAULT NULL,                           -- Gift card redeemed into the wallet
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id),
+  KEY (userId, createdAt),
+  FOREIGN KEY (userId) REFERENCES users(`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+  FOREIGN KEY (orderId) REFERENCES orders(`id`) ON DELETE SET NULL ON UPDATE CASCADE,
+  FOREIGN KEY (giftCardId) REFERENCES gift_cards(`id`) ON DELETE SET NULL ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS order_tenders (
+  `id` CHAR(36) NOT NULL DEFAULT (UUID()),
+  `orderId` CHAR(36) NOT NULL,
+  `type` VARCHAR(20) NOT NULL,                                       -- gift_card or wallet
+  `giftCardId` CHAR(36) NULL DEFAULT NULL,                           -- Card paid with, for gift card tenders
+  `userId` CHAR(36) NULL DEFAULT NULL,                               -- Wallet paid with, for wallet tenders
+  `amount` DECIMAL(19, 4) NOT NULL,
+  `currency` CHAR(3) NOT NULL,
+  `status` VARCHAR(20) NOT NULL DEFAULT 'applied',                   -- applied, released or refunded
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updatedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id),
+  KEY (orderId),
+  FOREIGN KEY (orderId) REFERENCES orders(`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+  FOREIGN KEY (giftCardId) REFERENCES gift_cards(`id`) ON DELETE RESTRICT ON UPDATE CASCADE,
+  FOREIGN KEY (userId) REFERENCES users(`id`) ON DELETE SET NULL ON UPDATE CASCADE
+);
+
+ALTER TABLE orders
+  ADD COLUMN `storeCredit` DECIMAL(19, 4) NOT NULL DEFAULT 0;        -- Paid with gift cards and wallet balance, the rest is charged
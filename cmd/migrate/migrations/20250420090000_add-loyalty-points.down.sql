@@ -0,0 +1,8 @@
+ALTER TABLE orders
+  DROP COLUMN `loyaltyDiscount`,
+  DROP COLUMN `loyaltyPoints`;
+
+DROP TABLE IF EXISTS loyalty_entries;
+DROP TABLE IF EXISTS loyalty_accounts;
+DROP TABLE IF EXISTS loyalty_tiers;
+DROP TABLE IF EXISTS loyalty_earn_rules;
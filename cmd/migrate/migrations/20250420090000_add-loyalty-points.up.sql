@@ -0,0 +1,55 @@
+CREATE TABLE IF NOT EXISTS loyalty_earn_rules (
+  `id` CHAR(36) NOT NULL DEFAULT (UUID()),
+  `type` VARCHAR(20) NOT NULL,                                       -- spend or category
+  `currency` VARCHAR(3) NOT NULL DEFAULT '',                         -- Limits the rule to orders in a currency
+  `category` VARCHAR(255) NOT NULL DEFAULT '',                       -- Product category a category rule applies to
+  `rate` DECIMAL(10, 4) NOT NULL,                                    -- Points per unit spent, or the multiplier of a category
+  `isActive` BOOLEAN NOT NULL DEFAULT TRUE,
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updatedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS loyalty_tiers (
+  `id` CHAR(36) NOT NULL DEFAULT (UUID()),
+  `name` VARCHAR(100) NOT NULL,
+  `minSpend` DECIMAL(19, 4) NOT NULL,                                -- Rolling spend that reaches the tier
+  `currency` CHAR(3) NOT NULL,
+  `multiplier` DECIMAL(6, 4) NOT NULL DEFAULT 1,                     -- Applied to the points earned in the tier
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id),
+  UNIQUE KEY (name, currency)
+);
+
+CREATE TABLE IF NOT EXISTS loyalty_accounts (
+  `userId` CHAR(36) NOT NULL,
+  `balance` INT NOT NULL DEFAULT 0,                                  -- Sum of the loyalty entries of the user
+  `updatedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (userId),
+  FOREIGN KEY (userId) REFERENCES users(`id`) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS loyalty_entries (
+  `id` CHAR(36) NOT NULL DEFAULT (UUID()),
+  `userId` CHAR(36) NOT NULL,
+  `points` INT NOT NULL,                                             -- Earned when positive, spent or taken back when negative
+  `reason` VARCHAR(20) NOT NULL,                                     -- earn, redeem, release, reverse, refund or adjustment
+  `orderId` CHAR(36) NULL DEFAULT NULL,                              -- Order the points were earned on or spent on
+  `spend` DECIMAL(19, 4) NOT NULL DEFAULT 0,                         -- Counted towards the tier of the user
+  `currency` VARCHAR(3) NOT NULL DEFAULT '',
+  `note` VARCHAR(255) NOT NULL DEFAULT '',
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id),
+  KEY (userId, createdAt),
+  UNIQUE KEY (orderId, reason),                                      -- An order earns, spends and gives back points once
+  FOREIGN KEY (userId) REFERENCES users(`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+  FOREIGN KEY (orderId) REFERENCES orders(`id`) ON DELETE SET NULL ON UPDATE CASCADE
+);
+
+ALTER TABLE orders
+  ADD COLUMN `loyaltyPoints` INT NOT NULL DEFAULT 0,                 -- Points redeemed on the order
+  ADD COLUMN `loyaltyDiscount` DECIMAL(19, 4) NOT NULL DEFAULT 0;    -- What the redeemed points took off, the rest is charged
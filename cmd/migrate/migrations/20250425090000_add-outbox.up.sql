@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS outbox_messages (
+  `id` VARCHAR(255) NOT NULL,
+  `topic` VARCHAR(100) NOT NULL,                                -- email, or the domain event, e.g. order.paid
+  `dedupeKey` VARCHAR(255) DEFAULT NULL,                        -- A message with a key already in the outbox is not added again
+  `payload` JSON NOT NULL,
+  `status` VARCHAR(20) NOT NULL DEFAULT 'pending',              -- pending, processing, sent, failed or dead
+  `attempts` INT NOT NULL DEFAULT 0,
+  `lastError` TEXT DEFAULT NULL,
+  `nextAttemptAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, -- Due for the dispatcher after this
+  `sentAt` TIMESTAMP NULL DEFAULT NULL,
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updatedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id),
+  UNIQUE KEY (dedupeKey),
+  KEY (status, nextAttemptAt)
+);
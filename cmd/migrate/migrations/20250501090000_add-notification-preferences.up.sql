@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS notification_preferences (
+  `email` VARCHAR(255) NOT NULL,                                -- Address the preference is for, so guests can unsubscribe too
+  `category` VARCHAR(50) NOT NULL,                              -- orders, payments or shipping
+  `enabled` BOOLEAN NOT NULL DEFAULT TRUE,                      -- A category without a row is enabled
+  `updatedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (email, category)
+);
@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+  `id` VARCHAR(255) NOT NULL,
+  `storeId` CHAR(36) NULL DEFAULT NULL,                         -- Store the endpoint gets the events of, every event when NULL
+  `url` VARCHAR(2048) NOT NULL,
+  `secret` VARCHAR(255) NOT NULL,                               -- Signs the payloads sent to the endpoint
+  `events` JSON NOT NULL,                                       -- Event types the endpoint subscribed to, e.g. ["order.paid"]
+  `isActive` BOOLEAN NOT NULL DEFAULT TRUE,
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updatedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id),
+  KEY (storeId),
+  FOREIGN KEY (storeId) REFERENCES storeowners(`storeId`) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+  `id` VARCHAR(255) NOT NULL,
+  `endpointId` VARCHAR(255) NOT NULL,
+  `eventId` VARCHAR(255) NOT NULL,                              -- The outbox message the event came from
+  `event` VARCHAR(100) NOT NULL,                                -- Event type, e.g. product.stock_changed
+  `payload` JSON NOT NULL,                                      -- Body posted to the endpoint, the same on every attempt
+  `status` VARCHAR(20) NOT NULL DEFAULT 'pending',              -- pending, processing, delivered, failed or dead
+  `attempts` INT NOT NULL DEFAULT 0,
+  `responseStatus` INT NOT NULL DEFAULT 0,                      -- HTTP status of the last attempt, 0 when no response came
+  `responseBody` TEXT DEFAULT NULL,                             -- Start of the body of the last response
+  `lastError` TEXT DEFAULT NULL,
+  `nextAttemptAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, -- Due for the sender after this
+  `deliveredAt` TIMESTAMP NULL DEFAULT NULL,
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updatedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id),
+  UNIQUE KEY (endpointId, eventId),
+  KEY (status, nextAttemptAt),
+  FOREIGN KEY (endpointId) REFERENCES webhook_endpoints(`id`) ON DELETE CASCADE ON UPDATE CASCADE
+);
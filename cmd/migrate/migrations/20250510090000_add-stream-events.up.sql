@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS stream_events (
+  `id` BIGINT NOT NULL AUTO_INCREMENT,                 -- Every instance reads the rows after the last one it saw
+  `topic` VARCHAR(255) NOT NULL,                       -- Bus topic, e.g. orders.<userId> or product.<productId>
+  `payload` JSON NOT NULL,                             -- The event pushed to the streams
+  `createdAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id),
+  KEY (createdAt)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE orders
+  ADD COLUMN `cardRefunded` DECIMAL(19, 4) NOT NULL DEFAULT 0;       -- Given back on the card so far, wallet refunds are in wallet_entries
@@ -0,0 +1,3 @@
+DELETE FROM idempotencykeys WHERE scope LIKE 'session:%';
+ALTER TABLE idempotencykeys
+  MODIFY COLUMN `scope` VARCHAR(36) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE idempotencykeys
+  MODIFY COLUMN `scope` VARCHAR(64) NOT NULL DEFAULT '';             -- User the key belongs to, or session:<id> for anonymous requests
@@ -0,0 +1,9 @@
+ALTER TABLE shippingmethods
+  MODIFY COLUMN `flatRate` DECIMAL(10, 2) NOT NULL DEFAULT 0,
+  MODIFY COLUMN `freeAbove` DECIMAL(10, 2) NOT NULL DEFAULT 0;
+
+UPDATE promotions SET value = amount WHERE type = 'fixed_amount';
+
+ALTER TABLE promotions
+  MODIFY COLUMN `minSpend` DECIMAL(10, 2) NOT NULL DEFAULT 0,
+  DROP COLUMN `amount`;
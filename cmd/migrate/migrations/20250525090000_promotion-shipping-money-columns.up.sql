@@ -0,0 +1,11 @@
+-- Fixed amounts off move out of value, which only holds percentages from now
+-- on. Amounts use the same precision as the other money columns.
+ALTER TABLE promotions
+  ADD COLUMN `amount` DECIMAL(19, 4) NOT NULL DEFAULT 0 AFTER `value`,
+  MODIFY COLUMN `minSpend` DECIMAL(19, 4) NOT NULL DEFAULT 0;
+
+UPDATE promotions SET amount = value, value = 0 WHERE type = 'fixed_amount';
+
+ALTER TABLE shippingmethods
+  MODIFY COLUMN `flatRate` DECIMAL(19, 4) NOT NULL DEFAULT 0,
+  MODIFY COLUMN `freeAbove` DECIMAL(19, 4) NOT NULL DEFAULT 0;
@@ -0,0 +1,233 @@
+package address
+
+import (
+	"fmt"
+	"net/http"
+
+	"ecom-api/internal/adapters/framework/left/services/auth"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/application/core/types/entity/payloads"
+	"ecom-api/internal/ports/right/rports"
+	"ecom-api/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type AddressHandler struct {
+	store     rports.AddressStore
+	userStore rports.UserStore
+}
+
+func NewAddressHandler(store rports.AddressStore, userStore rports.UserStore) *AddressHandler {
+	return &AddressHandler{store: store, userStore: userStore}
+}
+
+func (handler *AddressHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/addresses", auth.WithJWTAuth(handler.handleGetAddresses, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodGet)
+	router.HandleFunc("/address", auth.WithJWTAuth(handler.handleCreateAddress, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodPost)
+	router.HandleFunc("/address/{addressId}", auth.WithJWTAuth(handler.handleGetAddress, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodGet)
+	router.HandleFunc("/address/{addressId}", auth.WithJWTAuth(handler.handleUpdateAddress, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodPut)
+	router.HandleFunc("/address/{addressId}", auth.WithJWTAuth(handler.handleDeleteAddress, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodDelete)
+	router.HandleFunc("/address/{addressId}/default/shipping", auth.WithJWTAuth(handler.handleSetDefaultShipping, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodPut)
+	router.HandleFunc("/address/{addressId}/default/billing", auth.WithJWTAuth(handler.handleSetDefaultBilling, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodPut)
+}
+
+func (handler *AddressHandler) handleGetAddresses(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+
+	addresses, err := handler.store.GetAddressesByUserID(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, addresses, nil)
+}
+
+func (handler *AddressHandler) handleCreateAddress(w http.ResponseWriter, r *http.Request) {
+	var payload payloads.UserAddressPayload
+
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors)
+		return
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+
+	// the first address of a user becomes the default for both purposes
+	existing, err := handler.store.GetAddressesByUserID(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	address := entity.UserAddress{
+		UserID:            userID,
+		Label:             payload.Label,
+		FullName:          payload.FullName,
+		Phone:             payload.Phone,
+		Address:           entity.Address(payload.Address),
+		IsDefaultShipping: payload.IsDefaultShipping || len(existing) == 0,
+		IsDefaultBilling:  payload.IsDefaultBilling || len(existing) == 0,
+	}
+
+	addressId, err := handler.store.CreateAddress(address)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	address.ID = addressId
+
+	utils.WriteJSON(w, http.StatusCreated, address, nil)
+}
+
+func (handler *AddressHandler) handleGetAddress(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	address, ok := handler.getOwnedAddress(w, r)
+	if !ok {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, address, nil)
+}
+
+func (handler *AddressHandler) handleUpdateAddress(w http.ResponseWriter, r *http.Request) {
+	var payload payloads.UserAddressPayload
+
+	if r.Method != http.MethodPut {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	address, ok := handler.getOwnedAddress(w, r)
+	if !ok {
+		return
+	}
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors)
+		return
+	}
+
+	address.Label = payload.Label
+	address.FullName = payload.FullName
+	address.Phone = payload.Phone
+	address.Address = entity.Address(payload.Address)
+	address.IsDefaultShipping = address.IsDefaultShipping || payload.IsDefaultShipping
+	address.IsDefaultBilling = address.IsDefaultBilling || payload.IsDefaultBilling
+
+	if err := handler.store.UpdateAddress(*address); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, address, nil)
+}
+
+func (handler *AddressHandler) handleDeleteAddress(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	address, ok := handler.getOwnedAddress(w, r)
+	if !ok {
+		return
+	}
+
+	if err := handler.store.DeleteAddress(address.ID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"addressId": address.ID}, nil)
+}
+
+func (handler *AddressHandler) handleSetDefaultShipping(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	address, ok := handler.getOwnedAddress(w, r)
+	if !ok {
+		return
+	}
+
+	if err := handler.store.SetDefaultShippingAddress(address.UserID, address.ID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"defaultShippingAddressId": address.ID}, nil)
+}
+
+func (handler *AddressHandler) handleSetDefaultBilling(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	address, ok := handler.getOwnedAddress(w, r)
+	if !ok {
+		return
+	}
+
+	if err := handler.store.SetDefaultBillingAddress(address.UserID, address.ID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"defaultBillingAddressId": address.ID}, nil)
+}
+
+// getOwnedAddress loads the address named in the URL and makes sure it
+// belongs to the authenticated user. It writes the error response itself.
+func (handler *AddressHandler) getOwnedAddress(w http.ResponseWriter, r *http.Request) (*entity.UserAddress, bool) {
+	vars := mux.Vars(r)
+	addressId, ok := vars["addressId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing address ID"))
+		return nil, false
+	}
+
+	address, err := handler.store.GetAddressByID(addressId)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return nil, false
+	}
+
+	if address.UserID != auth.GetUserIDFromContext(r.Context()) {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("address not found"))
+		return nil, false
+	}
+
+	return address, true
+}
@@ -0,0 +1,165 @@
+package address
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"ecom-api/internal/adapters/framework/left/services/auth"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/application/core/types/entity/payloads"
+	"ecom-api/internal/ports/right/rports"
+)
+
+// mockAddressStore keeps the address books in a map.
+type mockAddressStore struct {
+	rports.AddressStore
+	addresses map[string]*entity.UserAddress
+	deleted   []string
+}
+
+func (m *mockAddressStore) CreateAddress(address entity.UserAddress) (string, error) {
+	address.ID = fmt.Sprintf("address-%d", len(m.addresses)+1)
+	m.addresses[address.ID] = &address
+	return address.ID, nil
+}
+
+func (m *mockAddressStore) GetAddressByID(addressID string) (*entity.UserAddress, error) {
+	address, ok := m.addresses[addressID]
+	if !ok {
+		return nil, errors.New("address not found")
+	}
+	copied := *address
+	return &copied, nil
+}
+
+func (m *mockAddressStore) GetAddressesByUserID(userID string) ([]*entity.UserAddress, error) {
+	var addresses []*entity.UserAddress
+	for _, address := range m.addresses {
+		if address.UserID == userID {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses, nil
+}
+
+func (m *mockAddressStore) UpdateAddress(address entity.UserAddress) error {
+	m.addresses[address.ID] = &address
+	return nil
+}
+
+func (m *mockAddressStore) DeleteAddress(addressID string) error {
+	m.deleted = append(m.deleted, addressID)
+	delete(m.addresses, addressID)
+	return nil
+}
+
+func (m *mockAddressStore) SetDefaultShippingAddress(userID, addressID string) error {
+	for _, address := range m.addresses {
+		if address.UserID == userID {
+			address.IsDefaultShipping = address.ID == addressID
+		}
+	}
+	return nil
+}
+
+func TestAddressHandlers(t *testing.T) {
+	request := func(handler *AddressHandler, userID, method, path string, body interface{}) *httptest.ResponseRecorder {
+		marshalled, _ := json.Marshal(body)
+		req, err := http.NewRequest(method, path, bytes.NewBuffer(marshalled))
+		if err != nil {
+			t.Fatalf("error requesting %v", err)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, userID))
+
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+
+		router.HandleFunc("/address", handler.handleCreateAddress).Methods(http.MethodPost)
+		router.HandleFunc("/address/{addressId}", handler.handleGetAddress).Methods(http.MethodGet)
+		router.HandleFunc("/address/{addressId}", handler.handleUpdateAddress).Methods(http.MethodPut)
+		router.HandleFunc("/address/{addressId}", handler.handleDeleteAddress).Methods(http.MethodDelete)
+		router.HandleFunc("/address/{addressId}/default/shipping", handler.handleSetDefaultShipping).Methods(http.MethodPut)
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	home := payloads.UserAddressPayload{
+		Label:    "Home",
+		FullName: "Jane Doe",
+		Address:  payloads.PostalAddressPayload{Line1: "1 Main St", City: "Springfield", PostalCode: "12345", Country: "US"},
+	}
+
+	t.Run("the first address of a user becomes their default", func(t *testing.T) {
+		store := &mockAddressStore{addresses: map[string]*entity.UserAddress{}}
+		handler := NewAddressHandler(store, nil)
+
+		rr := request(handler, "user-1", http.MethodPost, "/address", home)
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		first := store.addresses["address-1"]
+		assert.Equal(t, "user-1", first.UserID)
+		assert.True(t, first.IsDefaultShipping)
+		assert.True(t, first.IsDefaultBilling)
+
+		office := home
+		office.Label = "Office"
+		rr = request(handler, "user-1", http.MethodPost, "/address", office)
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		second := store.addresses["address-2"]
+		assert.False(t, second.IsDefaultShipping)
+		assert.False(t, second.IsDefaultBilling)
+	})
+
+	t.Run("an address without line1, city, country or postal code is refused", func(t *testing.T) {
+		store := &mockAddressStore{addresses: map[string]*entity.UserAddress{}}
+		handler := NewAddressHandler(store, nil)
+
+		empty := home
+		empty.Address = payloads.PostalAddressPayload{}
+		assert.Equal(t, http.StatusBadRequest, request(handler, "user-1", http.MethodPost, "/address", empty).Code)
+
+		noCity := home
+		noCity.Address.City = ""
+		assert.Equal(t, http.StatusBadRequest, request(handler, "user-1", http.MethodPost, "/address", noCity).Code)
+		assert.Empty(t, store.addresses)
+	})
+
+	t.Run("a user cannot read, change or delete the address of another", func(t *testing.T) {
+		store := &mockAddressStore{addresses: map[string]*entity.UserAddress{
+			"address-1": {ID: "address-1", UserID: "user-1", Label: "Home", FullName: "Jane Doe", IsDefaultShipping: true},
+			"address-2": {ID: "address-2", UserID: "user-2", Label: "Home", FullName: "John Roe", IsDefaultShipping: true},
+		}}
+		handler := NewAddressHandler(store, nil)
+
+		assert.Equal(t, http.StatusNotFound, request(handler, "user-2", http.MethodGet, "/address/address-1", nil).Code)
+
+		renamed := home
+		renamed.Label = "Stolen"
+		assert.Equal(t, http.StatusNotFound, request(handler, "user-2", http.MethodPut, "/address/address-1", renamed).Code)
+		assert.Equal(t, "Home", store.addresses["address-1"].Label)
+
+		assert.Equal(t, http.StatusNotFound, request(handler, "user-2", http.MethodPut, "/address/address-1/default/shipping", nil).Code)
+		assert.True(t, store.addresses["address-2"].IsDefaultShipping)
+
+		assert.Equal(t, http.StatusNotFound, request(handler, "user-2", http.MethodDelete, "/address/address-1", nil).Code)
+		assert.Empty(t, store.deleted)
+
+		// the owner still can
+		assert.Equal(t, http.StatusOK, request(handler, "user-1", http.MethodGet, "/address/address-1", nil).Code)
+		assert.Equal(t, http.StatusOK, request(handler, "user-1", http.MethodDelete, "/address/address-1", nil).Code)
+		assert.Equal(t, []string{"address-1"}, store.deleted)
+	})
+
+	t.Run("a missing address is not found", func(t *testing.T) {
+		handler := NewAddressHandler(&mockAddressStore{addresses: map[string]*entity.UserAddress{}}, nil)
+		assert.Equal(t, http.StatusNotFound, request(handler, "user-1", http.MethodGet, "/address/address-9", nil).Code)
+	})
+}
@@ -63,6 +63,19 @@ func WithJWTAuth(handlerFunc http.HandlerFunc, store rports.UserStore, allowedRo
 	}
 }
 
+// middleware function that authenticates the request when a token is present
+// and lets it through anonymously otherwise
+func WithOptionalJWTAuth(handlerFunc http.HandlerFunc, store rports.UserStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if utils.GetTokenFromRequest(r) == "" {
+			handlerFunc(w, r)
+			return
+		}
+
+		WithJWTAuth(handlerFunc, store, "admin", "storeowner", "user")(w, r)
+	}
+}
+
 func CreateJWT(secret []byte, userID, userRole string) (string, error) {
 	expiration := time.Second * time.Duration(configs.Envs.JWTExpirationInSeconds)
 
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"ecom-api/pkg/configs"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const orderAccessPurpose = "order_access"
+
+// CreateOrderAccessToken signs a token that grants read access to a single
+// order without an account. It is mailed to guest buyers.
+func CreateOrderAccessToken(secret []byte, orderID, email string) (string, error) {
+	expiration := time.Second * time.Duration(configs.Envs.OrderTokenTTLInSeconds)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"purpose": orderAccessPurpose,
+		"orderID": orderID,
+		"email":   email,
+		"exp":     time.Now().Add(expiration).Unix(),
+	})
+
+	return token.SignedString(secret)
+}
+
+// ValidateOrderAccessToken checks the signature and expiry of an order access
+// token and returns the order ID and email it was issued for.
+func ValidateOrderAccessToken(tokenString string) (string, string, error) {
+	token, err := validateJWT(tokenString)
+	if err != nil {
+		return "", "", err
+	}
+	if !token.Valid {
+		return "", "", fmt.Errorf("invalid token")
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	purpose, _ := claims["purpose"].(string)
+	orderID, _ := claims["orderID"].(string)
+	email, _ := claims["email"].(string)
+
+	if purpose != orderAccessPurpose || orderID == "" {
+		return "", "", fmt.Errorf("not an order access token")
+	}
+
+	return orderID, email, nil
+}
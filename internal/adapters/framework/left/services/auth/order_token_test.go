@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"testing"
+
+	"ecom-api/pkg/configs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderAccessToken(t *testing.T) {
+	secret := []byte(configs.Envs.JWTSecret)
+
+	token, err := CreateOrderAccessToken(secret, "order-1", "guest@mail.com")
+	require.NoError(t, err, "error creating order access token")
+
+	orderID, email, err := ValidateOrderAccessToken(token)
+	require.NoError(t, err, "error validating order access token")
+	assert.Equal(t, "order-1", orderID)
+	assert.Equal(t, "guest@mail.com", email)
+
+	sessionToken, err := CreateJWT(secret, "abc", "user")
+	require.NoError(t, err, "error creating JWT")
+
+	_, _, err = ValidateOrderAccessToken(sessionToken)
+	assert.Error(t, err, "expected a session token to be rejected")
+}
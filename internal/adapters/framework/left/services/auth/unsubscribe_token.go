@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const unsubscribePurpose = "unsubscribe"
+
+// CreateUnsubscribeToken signs a token that turns off a category of order
+// emails for an address. It goes in the unsubscribe link of every order email
+// and does not expire, so links in old emails keep working.
+func CreateUnsubscribeToken(secret []byte, email, category string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"purpose":  unsubscribePurpose,
+		"email":    email,
+		"category": category,
+	})
+
+	return token.SignedString(secret)
+}
+
+// ValidateUnsubscribeToken checks the signature of an unsubscribe token and
+// returns the address and category it was issued for.
+func ValidateUnsubscribeToken(tokenString string) (string, string, error) {
+	token, err := validateJWT(tokenString)
+	if err != nil {
+		return "", "", err
+	}
+	if !token.Valid {
+		return "", "", fmt.Errorf("invalid token")
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	purpose, _ := claims["purpose"].(string)
+	email, _ := claims["email"].(string)
+	category, _ := claims["category"].(string)
+
+	if purpose != unsubscribePurpose || email == "" || category == "" {
+		return "", "", fmt.Errorf("not an unsubscribe token")
+	}
+
+	return email, category, nil
+}
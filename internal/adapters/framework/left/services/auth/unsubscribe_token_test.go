@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"testing"
+
+	"ecom-api/pkg/configs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnsubscribeToken(t *testing.T) {
+	secret := []byte(configs.Envs.JWTSecret)
+
+	token, err := CreateUnsubscribeToken(secret, "guest@mail.com", "shipping")
+	require.NoError(t, err, "error creating unsubscribe token")
+
+	email, category, err := ValidateUnsubscribeToken(token)
+	require.NoError(t, err, "error validating unsubscribe token")
+	assert.Equal(t, "guest@mail.com", email)
+	assert.Equal(t, "shipping", category)
+
+	orderToken, err := CreateOrderAccessToken(secret, "order-1", "guest@mail.com")
+	require.NoError(t, err, "error creating order access token")
+
+	_, _, err = ValidateUnsubscribeToken(orderToken)
+	assert.Error(t, err, "expected an order access token to be rejected")
+
+	_, _, err = ValidateUnsubscribeToken(token + "x")
+	assert.Error(t, err, "expected a tampered token to be rejected")
+}
@@ -0,0 +1,11 @@
+package cart
+
+import (
+	"fmt"
+
+	"ecom-api/pkg/configs"
+)
+
+func guestOrderLink(orderID, accessToken string) string {
+	return fmt.Sprintf("%s:%s/api/v1/order/guest/%s?token=%s", configs.Envs.PublicHost, configs.Envs.Port, orderID, accessToken)
+}
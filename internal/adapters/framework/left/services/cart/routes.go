@@ -2,11 +2,17 @@ package cart
 
 import (
 	"ecom-api/internal/adapters/framework/left/services/auth"
+	"ecom-api/internal/adapters/framework/left/services/idempotency"
+	"ecom-api/internal/adapters/framework/left/services/loyalty"
+	"ecom-api/internal/adapters/framework/left/services/notification"
+	"ecom-api/internal/adapters/framework/left/services/outbox"
 	"ecom-api/internal/application/core/types/entity"
 	"ecom-api/internal/application/core/types/entity/payloads"
 	"ecom-api/internal/ports/right/rports"
+	"ecom-api/pkg/configs"
 	"ecom-api/utils"
 	"fmt"
+	"log"
 	"net/http"
 
 	"github.com/go-playground/validator/v10"
@@ -14,30 +20,74 @@ import (
 )
 
 type CartHandler struct {
-	store        rports.ProductStore
-	orderStore   rports.OrderStore
-	userStore    rports.UserStore
-	paymentStore rports.PaymentStore
+	store          rports.ProductStore
+	orderStore     rports.OrderStore
+	userStore      rports.UserStore
+	paymentStore   rports.PaymentStore
+	addressStore   rports.AddressStore
+	cartStore      rports.CartStore
+	promotionStore rports.PromotionStore
+	taxCalculator  rports.TaxCalculator
+	shippingStore  rports.ShippingStore
+	priceListStore rports.PriceListStore
+	exchangeRates  rports.ExchangeRateProvider
+
+	idempotencyStore rports.IdempotencyStore
+	payoutStore      rports.PayoutStore
+	storeOwnerStore  rports.StoreOwnerStore
+	creditStore      rports.CreditStore
+	loyalty          *loyalty.Program
+	notifier         *notification.Notifier
+	events           *outbox.Dispatcher
 }
 
-func NewCartHandler(store rports.ProductStore, orderStore rports.OrderStore, userStore rports.UserStore, paymentStore rports.PaymentStore) *CartHandler {
+func NewCartHandler(store rports.ProductStore, orderStore rports.OrderStore, userStore rports.UserStore, paymentStore rports.PaymentStore, addressStore rports.AddressStore, cartStore rports.CartStore, promotionStore rports.PromotionStore, taxCalculator rports.TaxCalculator, shippingStore rports.ShippingStore, priceListStore rports.PriceListStore, exchangeRates rports.ExchangeRateProvider, idempotencyStore rports.IdempotencyStore, payoutStore rports.PayoutStore, storeOwnerStore rports.StoreOwnerStore, creditStore rports.CreditStore, loyaltyProgram *loyalty.Program, notifier *notification.Notifier, events *outbox.Dispatcher) *CartHandler {
 	return &CartHandler{
-		store:        store,
-		orderStore:   orderStore,
-		userStore:    userStore,
-		paymentStore: paymentStore,
+		store:          store,
+		orderStore:     orderStore,
+		userStore:      userStore,
+		paymentStore:   paymentStore,
+		addressStore:   addressStore,
+		cartStore:      cartStore,
+		promotionStore: promotionStore,
+		taxCalculator:  taxCalculator,
+		shippingStore:  shippingStore,
+		priceListStore: priceListStore,
+		exchangeRates:  exchangeRates,
+
+		idempotencyStore: idempotencyStore,
+		payoutStore:      payoutStore,
+		storeOwnerStore:  storeOwnerStore,
+		creditStore:      creditStore,
+		loyalty:          loyaltyProgram,
+		notifier:         notifier,
+		events:           events,
 	}
 }
 
 func (handler *CartHandler) RegisterRoutes(router *mux.Router) {
-	router.HandleFunc("/cart/checkout", auth.WithJWTAuth(handler.handleCartCheckout, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodPost)
+	router.HandleFunc("/cart", auth.WithOptionalJWTAuth(handler.handleGetCart, handler.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/cart", auth.WithOptionalJWTAuth(handler.handleClearCart, handler.userStore)).Methods(http.MethodDelete)
+	router.HandleFunc("/cart/items", auth.WithOptionalJWTAuth(handler.handleAddCartItem, handler.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/cart/items/{productId}", auth.WithOptionalJWTAuth(handler.handleUpdateCartItem, handler.userStore)).Methods(http.MethodPut)
+	router.HandleFunc("/cart/items/{productId}", auth.WithOptionalJWTAuth(handler.handleRemoveCartItem, handler.userStore)).Methods(http.MethodDelete)
+	router.HandleFunc("/cart/shipping/quote", auth.WithOptionalJWTAuth(handler.handleShippingQuote, handler.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/cart/checkout", auth.WithJWTAuth(idempotency.WithIdempotencyKey(handler.handleCartCheckout, handler.idempotencyStore), handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodPost)
+	router.HandleFunc("/cart/guest/checkout", idempotency.WithIdempotencyKey(handler.handleGuestCheckout, handler.idempotencyStore)).Methods(http.MethodPost)
 	router.HandleFunc("/cart/delete_orderitem/{orderItemId}", auth.WithJWTAuth(handler.handleOrderItemDeletion, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodDelete)
 
 	router.HandleFunc("/order/delete/{orderId}", auth.WithJWTAuth(handler.handleOrderDeletion, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodDelete)
+	router.HandleFunc("/order/guest/{orderId}", handler.handleGetGuestOrder).Methods(http.MethodGet)
 	router.HandleFunc("/order/{orderId}", auth.WithJWTAuth(handler.handleGetOrderById, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodGet)
 	router.HandleFunc("/order/user/{userId}", auth.WithJWTAuth(handler.handleGetOrderByUserId, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodGet)
 	router.HandleFunc("/order/update/paymentstatus/{orderId}", auth.WithJWTAuth(handler.handlerUpdateOrderPaymentStatus, handler.userStore)).Methods(http.MethodPost)
-	router.HandleFunc("/order/update/status/{orderId}", auth.WithJWTAuth(handler.handlerUpdateOrderStatus, handler.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/order/update/status/{orderId}", auth.WithJWTAuth(handler.handlerUpdateOrderStatus, handler.userStore, "admin")).Methods(http.MethodPost)
+	router.HandleFunc("/order/summary/{orderId}", auth.WithJWTAuth(handler.handleGetOrderSummary, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodGet)
+
+	router.HandleFunc("/store/orders", auth.WithJWTAuth(handler.handleGetStoreOrders, handler.userStore, "admin", "storeowner")).Methods(http.MethodGet)
+	router.HandleFunc("/store/order/{storeOrderId}", auth.WithJWTAuth(handler.handleGetStoreOrder, handler.userStore, "admin", "storeowner")).Methods(http.MethodGet)
+	router.HandleFunc("/store/order/{storeOrderId}/status", auth.WithJWTAuth(handler.handleUpdateStoreOrderStatus, handler.userStore, "admin", "storeowner")).Methods(http.MethodPost)
+	router.HandleFunc("/store/order/{storeOrderId}/shipment", auth.WithJWTAuth(handler.handleCreateShipment, handler.userStore, "admin", "storeowner")).Methods(http.MethodPost)
 	router.HandleFunc("/orderitem/{orderId}", auth.WithJWTAuth(handler.handleGetOrderItemByOrderId, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodGet)
 
 }
@@ -47,7 +97,194 @@ func (handler *CartHandler) handleCartCheckout(w http.ResponseWriter, r *http.Re
 	userID := auth.GetUserIDFromContext(r.Context())
 
 	var cart payloads.CartCheckoutPayload
-	// var storeCustomer *entity.User
+
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	// an empty body checks out the stored cart with the default addresses
+	if r.ContentLength != 0 {
+		if err := utils.ParseJSON(r, &cart); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	if err := utils.Validate.Struct(cart); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors)
+		return
+	}
+
+	items, storedCart, err := handler.checkoutItems(w, r, cart.Items)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if len(items) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("cart is empty"))
+		return
+	}
+
+	currency, err := checkoutCurrency(r, cart.Items)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	productIDs, err := getCartItemsIDs(items)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	products, err := handler.store.GetProductsByIDs(productIDs)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	shippingAddress, billingAddress, err := handler.resolveCheckoutAddresses(cart, userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// the buyer is looked up first, nothing fails between placing the order
+	// and starting its payment without cancelling the order
+	buyer, err := handler.userStore.GetUserByID(userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	orderId, subTotal, totalPrice, err := handler.createOrder(products, checkout{
+		userID:          userID,
+		items:           items,
+		currency:        currency,
+		shippingAddress: shippingAddress,
+		billingAddress:  billingAddress,
+		couponCodes:     cart.CouponCodes,
+		shippingMethod:  cart.ShippingMethodID,
+		giftCardCodes:   cart.GiftCardCodes,
+		useWallet:       cart.UseWallet,
+		loyaltyPoints:   cart.LoyaltyPoints,
+	})
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	session, order, err := handler.startPayment(orderId, buyer.Email, idempotency.KeyFromContext(r.Context()), nil)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	if storedCart != nil {
+		if err := handler.cartStore.ClearCart(storedCart.ID); err != nil {
+			log.Printf("failed to clear cart %s after order %s: %v", storedCart.ID, orderId, err)
+		}
+	}
+
+	response := map[string]interface{}{
+		"total":           totalPrice,
+		"subTotal":        subTotal,
+		"userId":          userID,
+		"orderId":         orderId,
+		"loyaltyPoints":   order.LoyaltyPoints,
+		"loyaltyDiscount": order.LoyaltyDiscount,
+		"storeCredit":     order.StoreCredit,
+		"amountDue":       order.AmountDue(),
+	}
+	if session != nil {
+		response["paymentSessionId"] = session.ID
+		response["paymentUrl"] = session.URL
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response, nil)
+}
+
+func (handler *CartHandler) handleShippingQuote(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+
+	var payload payloads.ShippingQuotePayload
+
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	// an empty body quotes the stored cart to the default shipping address
+	if r.ContentLength != 0 {
+		if err := utils.ParseJSON(r, &payload); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors)
+		return
+	}
+
+	items, _, err := handler.checkoutItems(w, r, payload.Items)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if len(items) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("cart is empty"))
+		return
+	}
+
+	currency, err := checkoutCurrency(r, payload.Items)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	productIDs, err := getCartItemsIDs(items)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	products, err := handler.store.GetProductsByIDs(productIDs)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	address, err := handler.resolveAddress(payload.ShippingAddressID, payload.ShippingAddress, userID, handler.addressStore.GetDefaultShippingAddress)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if address == nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("shipping address is required, provide an address ID or an inline address"))
+		return
+	}
+
+	quotes, err := handler.shippingQuotes(products, checkout{
+		userID:          userID,
+		items:           items,
+		currency:        currency,
+		shippingAddress: *address,
+		couponCodes:     payload.CouponCodes,
+	})
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{"methods": quotes}, nil)
+}
+
+func (handler *CartHandler) handleGuestCheckout(w http.ResponseWriter, r *http.Request) {
+	var cart payloads.GuestCheckoutPayload
 
 	if r.Method != http.MethodPost {
 		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
@@ -65,7 +302,48 @@ func (handler *CartHandler) handleCartCheckout(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	productIDs, err := getCartItemsIDs(cart.Items)
+	if cart.ShippingAddressID != "" || cart.BillingAddressID != "" {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("saved addresses require an account, send the address inline"))
+		return
+	}
+	if cart.UseWallet {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("paying from a wallet requires an account, please log in"))
+		return
+	}
+	if cart.LoyaltyPoints > 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("redeeming loyalty points requires an account, please log in"))
+		return
+	}
+
+	// the response must not tell which emails have an account, the order is
+	// placed the same way and only its email tells the owner to log in
+	existing, err := handler.userStore.GetUserByEmail(cart.Email)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	placed := map[string]string{}
+	if existing != nil && existing.ID != "" {
+		placed["has_account"] = "true"
+	}
+
+	items, storedCart, err := handler.checkoutItems(w, r, cart.Items)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if len(items) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("cart is empty"))
+		return
+	}
+
+	currency, err := checkoutCurrency(r, cart.Items)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	productIDs, err := getCartItemsIDs(items)
 	if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, err)
 		return
@@ -77,41 +355,298 @@ func (handler *CartHandler) handleCartCheckout(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	orderId, subTotal, totalPrice, err := handler.createOrder(products, cart.Items, userID)
+	shippingAddress, billingAddress, err := handler.resolveCheckoutAddresses(cart.CartCheckoutPayload, "")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	orderId, subTotal, totalPrice, err := handler.createOrder(products, checkout{
+		guestEmail:      cart.Email,
+		items:           items,
+		currency:        currency,
+		shippingAddress: shippingAddress,
+		billingAddress:  billingAddress,
+		couponCodes:     cart.CouponCodes,
+		shippingMethod:  cart.ShippingMethodID,
+		giftCardCodes:   cart.GiftCardCodes,
+		useWallet:       cart.UseWallet,
+	})
 	if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	// -------------------- for checkout session -------------------
-	// storeCustomer, err = handler.userStore.GetUserByID(userID)
-	// if err != nil {
-	// 	utils.WriteError(w, http.StatusInternalServerError, err)
-	// 	return
-	// }
+	accessToken, err := auth.CreateOrderAccessToken([]byte(configs.Envs.JWTSecret), orderId, cart.Email)
+	if err != nil {
+		if cancelErr := handler.cancelUnpaidOrder(orderId); cancelErr != nil {
+			log.Printf("failed to cancel order %s after access token error: %v", orderId, cancelErr)
+		}
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// guests follow their order through the link in the order placed email,
+	// never through the response, so only the owner of the inbox can read it
+	placed["order_link"] = guestOrderLink(orderId, accessToken)
+	session, order, err := handler.startPayment(orderId, cart.Email, idempotency.KeyFromContext(r.Context()), placed)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	if storedCart != nil {
+		if err := handler.cartStore.ClearCart(storedCart.ID); err != nil {
+			log.Printf("failed to clear cart %s after order %s: %v", storedCart.ID, orderId, err)
+		}
+	}
+
+	response := map[string]interface{}{
+		"total":       totalPrice,
+		"subTotal":    subTotal,
+		"email":       cart.Email,
+		"orderId":     orderId,
+		"storeCredit": order.StoreCredit,
+		"amountDue":   order.AmountDue(),
+	}
+	if session != nil {
+		response["paymentSessionId"] = session.ID
+		response["paymentUrl"] = session.URL
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response, nil)
+}
+
+func (handler *CartHandler) handleGetGuestOrder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	vars := mux.Vars(r)
+	orderId, ok := vars["orderId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing order ID"))
+		return
+	}
 
-	// session, err := handler.paymentStore.CreateCheckoutSession(orderId, totalPrice, storeCustomer.Email)
-	// if err != nil {
-	// 	utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("error creating checkout session: %v", err))
-	// 	return
-	// }
+	tokenOrderId, _, err := auth.ValidateOrderAccessToken(r.URL.Query().Get("token"))
+	if err != nil || tokenOrderId != orderId {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("permission denied"))
+		return
+	}
+
+	order, err := handler.orderStore.GetOrderByID(orderId)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	orderitems, err := handler.orderStore.GetOrderItemsByOrderId(orderId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
 
-	// utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
-	// 	"paid":     session.PaymentIntent.Amount,
-	// 	"customer": session.Customer,
-	// 	"items":    session.DisplayItems,
-	// 	"balance":  session.Customer.Balance,
-	// 	"currency": session.PaymentIntent.Currency,
-	// }, nil)
+	storeOrders, err := handler.storeOrdersWithDetails(orderId, orderitems)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
 
 	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"total":    totalPrice,
-		"subTotal": subTotal,
-		"userId":   userID,
-		"orderId":  orderId,
+		"order":       order,
+		"items":       orderitems,
+		"storeOrders": storeOrders,
 	}, nil)
 }
 
+func (handler *CartHandler) handleGetCart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	currency, err := storefrontCurrency(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	cart, err := handler.getCart(w, r, false)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if cart == nil {
+		cart = &entity.Cart{}
+	}
+
+	priced, err := handler.priceCart(cart, currency)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, priced, nil)
+}
+
+func (handler *CartHandler) handleAddCartItem(w http.ResponseWriter, r *http.Request) {
+	var payload payloads.CartItemPayload
+
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors)
+		return
+	}
+
+	currency, err := storefrontCurrency(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	cart, err := handler.getCart(w, r, true)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	quantity := payload.Quantity
+	for _, item := range cart.Items {
+		if item.ProductID == payload.ProductID {
+			quantity += item.Quantity
+		}
+	}
+
+	if err := handler.saveCartItem(cart.ID, payload.ProductID, quantity, currency); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	handler.writePricedCart(w, r, http.StatusCreated)
+}
+
+func (handler *CartHandler) handleUpdateCartItem(w http.ResponseWriter, r *http.Request) {
+	var payload payloads.CartItemQuantityPayload
+
+	if r.Method != http.MethodPut {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	vars := mux.Vars(r)
+	productId, ok := vars["productId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing product ID"))
+		return
+	}
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors)
+		return
+	}
+
+	currency, err := storefrontCurrency(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	cart, err := handler.getCart(w, r, false)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if cart == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("cart not found"))
+		return
+	}
+
+	if payload.Quantity == 0 {
+		err = handler.cartStore.RemoveCartItem(cart.ID, productId)
+	} else {
+		err = handler.saveCartItem(cart.ID, productId, payload.Quantity, currency)
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	handler.writePricedCart(w, r, http.StatusOK)
+}
+
+func (handler *CartHandler) handleRemoveCartItem(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	vars := mux.Vars(r)
+	productId, ok := vars["productId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing product ID"))
+		return
+	}
+
+	cart, err := handler.getCart(w, r, false)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if cart == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("cart not found"))
+		return
+	}
+
+	if err := handler.cartStore.RemoveCartItem(cart.ID, productId); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	handler.writePricedCart(w, r, http.StatusOK)
+}
+
+func (handler *CartHandler) handleClearCart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	cart, err := handler.getCart(w, r, false)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if cart == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("cart not found"))
+		return
+	}
+
+	if err := handler.cartStore.ClearCart(cart.ID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	handler.writePricedCart(w, r, http.StatusOK)
+}
+
 func (handler *CartHandler) handleOrderDeletion(w http.ResponseWriter, r *http.Request) {
 
 	if r.Method != http.MethodDelete {
@@ -172,11 +707,15 @@ func (handler *CartHandler) handleGetOrderById(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	order, err := handler.orderStore.GetOrderByID(orderId)
-	if err != nil {
+	order, _, err := handler.callerOrder(r, orderId)
+	if err == errOrderNotFound {
 		utils.WriteError(w, http.StatusNotFound, err)
 		return
 	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
 	utils.WriteJSON(w, http.StatusOK, order, nil)
 }
 
@@ -193,6 +732,19 @@ func (handler *CartHandler) handleGetOrderByUserId(w http.ResponseWriter, r *htt
 		return
 	}
 
+	// buyers list their own orders, stores see theirs through the store orders
+	if userId != auth.GetUserIDFromContext(r.Context()) {
+		_, isAdmin, err := handler.callerStoreID(r)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if !isAdmin {
+			utils.WriteError(w, http.StatusForbidden, fmt.Errorf("you can only list your own orders"))
+			return
+		}
+	}
+
 	var orders []*entity.Order
 	var err error
 
@@ -217,14 +769,34 @@ func (handler *CartHandler) handleGetOrderItemByOrderId(w http.ResponseWriter, r
 		return
 	}
 
+	_, storeID, err := handler.callerOrder(r, orderId)
+	if err == errOrderNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
 	var orderitems []*entity.OrderItem
-	var err error
 
 	orderitems, err = handler.orderStore.GetOrderItemsByOrderId(orderId)
 	if err != nil {
 		utils.WriteError(w, http.StatusNotFound, err)
 		return
 	}
+
+	// a store only sees what it sold
+	if storeID != "" {
+		sold := []*entity.OrderItem{}
+		for _, item := range orderitems {
+			if item.StoreID == storeID {
+				sold = append(sold, item)
+			}
+		}
+		orderitems = sold
+	}
 	utils.WriteJSON(w, http.StatusOK, orderitems, nil)
 }
 
@@ -253,13 +825,14 @@ func (handler *CartHandler) handlerUpdateOrderPaymentStatus(w http.ResponseWrite
 }
 
 func (handler *CartHandler) handlerUpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
+	var statusParams payloads.OrderStatusPayload
+
 	if r.Method != http.MethodPost {
 		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
 		return
 	}
 
 	vars := mux.Vars(r)
-	var status string
 
 	orderId, ok := vars["orderId"]
 	if !ok {
@@ -267,11 +840,48 @@ func (handler *CartHandler) handlerUpdateOrderStatus(w http.ResponseWriter, r *h
 		return
 	}
 
-	err := handler.orderStore.UpdateOrderPaymentStatus(orderId, status)
+	if err := utils.ParseJSON(r, &statusParams); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(statusParams); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors)
+		return
+	}
+
+	// cancelling puts stock, coupons and credit back and refunding returns
+	// the money, both go through their own flows
+	switch statusParams.Status {
+	case configs.Envs.OrderStatusProcessing, configs.Envs.OrderStatusShipped, configs.Envs.OrderStatusCompleted:
+	case configs.Envs.OrderStatusCancelled, configs.Envs.OrderStatusRefunded:
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("orders are %s through their payment, not their status", statusParams.Status))
+		return
+	default:
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unknown order status %s", statusParams.Status))
+		return
+	}
+
+	order, err := handler.orderStore.GetOrderByID(orderId)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
+	if order.ID == "" {
+		utils.WriteError(w, http.StatusNotFound, errOrderNotFound)
+		return
+	}
+
+	if err := handler.moveOrder(order, statusParams.Status); err != nil {
+		utils.WriteError(w, http.StatusConflict, err)
+		return
+	}
 
-	utils.WriteJSON(w, http.StatusCreated, map[string]string{"orderId": orderId, "orderSatus": status}, nil)
+	order, err = handler.orderStore.GetOrderByID(orderId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, order, nil)
 }
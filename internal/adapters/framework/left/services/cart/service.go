@@ -1,12 +1,90 @@
 package cart
 
 import (
+	"ecom-api/internal/adapters/framework/left/services/auth"
+	"ecom-api/internal/adapters/framework/left/services/notification"
+	"ecom-api/internal/application/core/credit"
+	"ecom-api/internal/application/core/fulfilment"
+	"ecom-api/internal/application/core/payout"
+	"ecom-api/internal/application/core/pricing"
+	"ecom-api/internal/application/core/promotion"
+	"ecom-api/internal/application/core/shipping"
 	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/application/core/types/entity/payloads"
 	"ecom-api/pkg/configs"
+	"ecom-api/utils"
 	"fmt"
-	"math"
+	"log"
+	"net/http"
+	"strings"
+	"time"
 )
 
+// CartSessionHeader carries the anonymous cart session between requests.
+const CartSessionHeader = "X-Cart-Session"
+
+// StorefrontCurrencyHeader selects the currency prices are shown and charged
+// in. The currency query parameter does the same for plain links.
+const StorefrontCurrencyHeader = "X-Currency"
+
+// requestedCurrency returns the currency selected by the request, empty when
+// none is.
+func requestedCurrency(r *http.Request) (string, error) {
+	currency := r.Header.Get(StorefrontCurrencyHeader)
+	if currency == "" {
+		currency = r.URL.Query().Get("currency")
+	}
+	if currency == "" {
+		return "", nil
+	}
+
+	if utils.Validate.Var(currency, "len=3,alpha") != nil {
+		return "", fmt.Errorf("invalid currency %q, expected an ISO 4217 code", currency)
+	}
+	return strings.ToUpper(currency), nil
+}
+
+// storefrontCurrency returns the currency selected by the request or the
+// default currency of the store.
+func storefrontCurrency(r *http.Request) (string, error) {
+	currency, err := requestedCurrency(r)
+	if err != nil || currency != "" {
+		return currency, err
+	}
+	return strings.ToUpper(configs.Envs.DEFAULT_CURRENCY), nil
+}
+
+// checkoutCurrency returns the currency an order is placed in: the one the
+// items sent with the request are in, else the storefront currency, in which
+// the stored cart is re-priced. Items in different currencies, or in another
+// currency than the one requested, are refused rather than summed.
+func checkoutCurrency(r *http.Request, items []entity.CartCheckoutItem) (string, error) {
+	requested, err := requestedCurrency(r)
+	if err != nil {
+		return "", err
+	}
+
+	currency := ""
+	for _, item := range items {
+		if item.Currency == "" {
+			continue
+		}
+		itemCurrency := strings.ToUpper(item.Currency)
+		if currency != "" && itemCurrency != currency {
+			return "", fmt.Errorf("cart mixes %s and %s items, check them out separately", currency, itemCurrency)
+		}
+		currency = itemCurrency
+	}
+
+	switch {
+	case currency == "":
+		return storefrontCurrency(r)
+	case requested != "" && requested != currency:
+		return "", fmt.Errorf("cart is in %s but the checkout was requested in %s", currency, requested)
+	}
+	return currency, nil
+}
+
 func getCartItemsIDs(items []entity.CartCheckoutItem) ([]string, error) {
 	productIds := make([]string, len(items))
 	for index, item := range items {
@@ -29,6 +107,9 @@ func checkIfCartIsInStock(cartItems []entity.CartCheckoutItem, products map[stri
 		if !ok {
 			return fmt.Errorf("product %s is not available in the store, please refresh your cart", item.ProductID)
 		}
+		if !product.IsActive {
+			return fmt.Errorf("product %s is no longer available", product.Name)
+		}
 		if product.Quantity < item.Quantity {
 			return fmt.Errorf("product %s is not available in the quantity requested", product.Name)
 		}
@@ -36,81 +117,1037 @@ func checkIfCartIsInStock(cartItems []entity.CartCheckoutItem, products map[stri
 	return nil
 }
 
-func calculateTotalPrice(cartItems []entity.CartCheckoutItem, products map[string]entity.Product) (float64, float64) {
-	var totalPriceAfterTaxAndDis float64
-	var totalPriceBeforeTaxAndDis float64
+func calculateTotalPrice(cartItems []entity.CartCheckoutItem, products map[string]entity.Product, discounts *promotion.Result, taxes *entity.TaxResult) (entity.Money, entity.Money, error) {
+	var totalPriceAfterTaxAndDis entity.Money
+	var totalPriceBeforeTaxAndDis entity.Money
 
-	for _, item := range cartItems {
+	for index, item := range cartItems {
+		itemTotalBefore, itemTotalAfter, err := calculateIndivisualProductPricing(products[item.ProductID], item, discounts.LineDiscount(index), taxes.AddedTax(index))
+		if err != nil {
+			return entity.Money{}, entity.Money{}, err
+		}
+
+		if totalPriceBeforeTaxAndDis, err = totalPriceBeforeTaxAndDis.Add(itemTotalBefore); err != nil {
+			return entity.Money{}, entity.Money{}, err
+		}
+		if totalPriceAfterTaxAndDis, err = totalPriceAfterTaxAndDis.Add(itemTotalAfter); err != nil {
+			return entity.Money{}, entity.Money{}, err
+		}
+	}
+
+	return totalPriceBeforeTaxAndDis, totalPriceAfterTaxAndDis, nil
+}
+
+func calculateIndivisualProductPricing(product entity.Product, item entity.CartCheckoutItem, discount, tax entity.Money) (entity.Money, entity.Money, error) {
+	totalPriceBeforeTaxAndDis := product.Price.Mul(item.Quantity)
+
+	totalPriceAfterTaxAndDis, err := totalPriceBeforeTaxAndDis.Sub(discount)
+	if err != nil {
+		return entity.Money{}, entity.Money{}, err
+	}
+	totalPriceAfterTaxAndDis, err = totalPriceAfterTaxAndDis.Add(tax)
+	if err != nil {
+		return entity.Money{}, entity.Money{}, err
+	}
+
+	return totalPriceBeforeTaxAndDis, totalPriceAfterTaxAndDis, nil
+}
+
+func promotionLines(cartItems []entity.CartCheckoutItem, products map[string]entity.Product) []promotion.Line {
+	lines := make([]promotion.Line, len(cartItems))
+	for index, item := range cartItems {
+		product := products[item.ProductID]
+		lines[index] = promotion.Line{
+			ProductID: item.ProductID,
+			Category:  product.Category,
+			Quantity:  item.Quantity,
+			UnitPrice: product.Price,
+		}
+	}
+	return lines
+}
+
+// taxLines returns what the buyer pays for every line once discounts are
+// taken off, which is the amount tax is computed on.
+func taxLines(cartItems []entity.CartCheckoutItem, products map[string]entity.Product, discounts *promotion.Result) []entity.TaxLine {
+	lines := make([]entity.TaxLine, len(cartItems))
+	for index, item := range cartItems {
+		product := products[item.ProductID]
+		lines[index] = entity.TaxLine{
+			TaxCategory: product.TaxCategory,
+			Amount:      entity.NewMoney(product.Price.Mul(item.Quantity).Amount-discounts.LineDiscount(index).Amount, product.Price.Currency),
+		}
+	}
+	return lines
+}
+
+func shippingItems(cartItems []entity.CartCheckoutItem, products map[string]entity.Product) []shipping.Item {
+	items := make([]shipping.Item, len(cartItems))
+	for index, item := range cartItems {
 		product := products[item.ProductID]
-		itemTotalBefore := product.Price * float64(item.Quantity)
-		itemTotalAfter := itemTotalBefore - (item.Discount * product.Price) + (item.Tax * product.Price)
+		items[index] = shipping.Item{
+			Quantity: item.Quantity,
+			Weight:   product.Weight,
+			Length:   product.Length,
+			Width:    product.Width,
+			Height:   product.Height,
+		}
+	}
+	return items
+}
+
+// quoteShipping prices the methods of the zone covering address for an order
+// worth amount after discounts. It returns nil quotes when no shipping zone is
+// configured at all, in which case orders carry no shipping cost.
+func (handler *CartHandler) quoteShipping(address entity.Address, items []shipping.Item, amount entity.Money, freeShipping bool) ([]entity.ShippingQuote, error) {
+	zones, err := handler.shippingStore.GetAllShippingZones()
+	if err != nil {
+		return nil, err
+	}
+	if len(zones) == 0 {
+		return nil, nil
+	}
 
-		totalPriceBeforeTaxAndDis += itemTotalBefore
-		totalPriceAfterTaxAndDis += itemTotalAfter
+	zone := shipping.MatchZone(zones, address)
+	if zone == nil {
+		return nil, fmt.Errorf("we do not ship to %s", address.String())
 	}
 
-	return roundToTwoDecimals(totalPriceBeforeTaxAndDis), roundToTwoDecimals(totalPriceAfterTaxAndDis)
+	methods, err := handler.shippingStore.GetShippingMethodsByZoneID(zone.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	quotes := shipping.Quote(methods, items, amount, freeShipping)
+	if len(quotes) == 0 {
+		return nil, fmt.Errorf("no shipping method is available for this order")
+	}
+
+	return quotes, nil
 }
 
-func calculateIndivisualProductPricing(product entity.Product, item entity.CartCheckoutItem) (float64, float64) {
-	totalPriceBeforeTaxAndDis := product.Price * float64(item.Quantity)
-	totalPriceAfterTaxAndDis := totalPriceBeforeTaxAndDis - (item.Discount * product.Price) + (item.Tax * product.Price)
+// chooseShipping returns the quote of the method picked at checkout. Without
+// quotes shipping is not configured and the order ships at no cost.
+func chooseShipping(quotes []entity.ShippingQuote, methodID, currency string) (entity.ShippingQuote, error) {
+	if quotes == nil {
+		return entity.ShippingQuote{Cost: entity.NewMoney(0, currency)}, nil
+	}
+	if methodID == "" {
+		return entity.ShippingQuote{}, fmt.Errorf("shipping method is required, request a shipping quote to see the available methods")
+	}
+
+	for _, quote := range quotes {
+		if quote.MethodID == methodID {
+			return quote, nil
+		}
+	}
 
-	return roundToTwoDecimals(totalPriceBeforeTaxAndDis), roundToTwoDecimals(totalPriceAfterTaxAndDis)
+	return entity.ShippingQuote{}, fmt.Errorf("shipping method %s is not available for this order", methodID)
 }
 
-func (handler *CartHandler) createOrder(products []entity.Product, cartItems []entity.CartCheckoutItem, userID string) (string, float64, float64, error) {
+// resolvePromotions returns the automatic promotions the cart qualifies for
+// followed by the requested coupons. An ineligible coupon fails the checkout
+// while an ineligible automatic promotion is skipped.
+func (handler *CartHandler) resolvePromotions(co checkout, subtotal entity.Money) ([]*entity.Promotion, error) {
+	now := time.Now()
+	promotions := []*entity.Promotion{}
+
+	automatic, err := handler.promotionStore.GetAutomaticPromotions()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range automatic {
+		redemptions, err := handler.promotionStore.CountRedemptions(p.ID, co.userID, co.guestEmail)
+		if err != nil {
+			return nil, err
+		}
+		if promotion.CheckEligibility(p, now, subtotal, redemptions) == nil {
+			promotions = append(promotions, p)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, code := range co.couponCodes {
+		p, err := handler.promotionStore.GetPromotionByCode(code)
+		if err != nil || p.Code == "" {
+			return nil, fmt.Errorf("coupon %s is not valid", code)
+		}
+		if seen[p.ID] {
+			continue
+		}
+		seen[p.ID] = true
+
+		redemptions, err := handler.promotionStore.CountRedemptions(p.ID, co.userID, co.guestEmail)
+		if err != nil {
+			return nil, err
+		}
+		if err := promotion.CheckEligibility(p, now, subtotal, redemptions); err != nil {
+			return nil, err
+		}
+		promotions = append(promotions, p)
+	}
+
+	return promotions, nil
+}
+
+// reservePromotions counts a redemption for every applied promotion. On
+// failure the reservations made so far are given back.
+func (handler *CartHandler) reservePromotions(applied []entity.AppliedDiscount) error {
+	for index, discount := range applied {
+		if err := handler.promotionStore.ReservePromotionUsage(discount.PromotionID); err != nil {
+			handler.releasePromotions(applied[:index])
+			return fmt.Errorf("coupon %s is no longer available: %v", discount.Code, err)
+		}
+	}
+	return nil
+}
+
+func (handler *CartHandler) releasePromotions(applied []entity.AppliedDiscount) {
+	for _, discount := range applied {
+		if err := handler.promotionStore.ReleasePromotionUsage(discount.PromotionID); err != nil {
+			log.Printf("failed to release promotion %s: %v", discount.PromotionID, err)
+		}
+	}
+}
+
+// getCart returns the cart of the authenticated user or of the anonymous
+// session named in CartSessionHeader. When a user sends a session header the
+// anonymous cart is merged into theirs. With create set, a missing cart (and,
+// for anonymous callers, a missing session) is created. Returns nil when there
+// is no cart and create is false.
+func (handler *CartHandler) getCart(w http.ResponseWriter, r *http.Request, create bool) (*entity.Cart, error) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	sessionID := r.Header.Get(CartSessionHeader)
+	if sessionID != "" && utils.Validate.Var(sessionID, "uuid") != nil {
+		return nil, fmt.Errorf("invalid cart session")
+	}
+
+	var sessionCart *entity.Cart
+	if sessionID != "" {
+		var err error
+		sessionCart, err = handler.cartStore.GetCartBySessionID(sessionID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if userID == "" {
+		if sessionCart == nil && create {
+			if sessionID == "" {
+				sessionID = utils.GenerateRandomUniqueIdentifier()
+			}
+			cartId, err := handler.cartStore.CreateCart(entity.Cart{SessionID: sessionID})
+			if err != nil {
+				return nil, err
+			}
+			sessionCart = &entity.Cart{ID: cartId, SessionID: sessionID, Items: []*entity.CartItem{}}
+		}
+		if sessionCart != nil {
+			w.Header().Set(CartSessionHeader, sessionCart.SessionID)
+		}
+		return sessionCart, nil
+	}
+
+	cart, err := handler.cartStore.GetCartByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if sessionCart != nil {
+		if cart, err = handler.mergeCarts(sessionCart, cart, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if cart == nil && create {
+		cartId, err := handler.cartStore.CreateCart(entity.Cart{UserID: userID})
+		if err != nil {
+			return nil, err
+		}
+		cart = &entity.Cart{ID: cartId, UserID: userID, Items: []*entity.CartItem{}}
+	}
+
+	return cart, nil
+}
+
+// mergeCarts moves an anonymous cart into the user's cart, adding up the
+// quantities of products present in both.
+func (handler *CartHandler) mergeCarts(sessionCart, userCart *entity.Cart, userID string) (*entity.Cart, error) {
+	if userCart == nil {
+		if err := handler.cartStore.AssignCartToUser(sessionCart.ID, userID); err != nil {
+			return nil, err
+		}
+		sessionCart.UserID = userID
+		sessionCart.SessionID = ""
+		return sessionCart, nil
+	}
+
+	quantities := make(map[string]int)
+	for _, item := range userCart.Items {
+		quantities[item.ProductID] = item.Quantity
+	}
+
+	for _, item := range sessionCart.Items {
+		item.CartID = userCart.ID
+		item.Quantity += quantities[item.ProductID]
+		if err := handler.cartStore.UpsertCartItem(*item); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := handler.cartStore.DeleteCart(sessionCart.ID); err != nil {
+		return nil, err
+	}
+
+	items, err := handler.cartStore.GetCartItems(userCart.ID)
+	if err != nil {
+		return nil, err
+	}
+	userCart.Items = items
+
+	return userCart, nil
+}
+
+// localPrices resolves the price of every product in currency, from its price
+// list or at the exchange rate. Products that cannot be priced in currency are
+// reported in unpriced instead.
+func (handler *CartHandler) localPrices(products []entity.Product, currency string) (map[string]entity.Money, map[string]error, error) {
+	productIDs := make([]string, len(products))
+	for index, product := range products {
+		productIDs[index] = product.ProductId
+	}
+
+	priceLists, err := handler.priceListStore.GetPricesByProductIDs(productIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	prices := make(map[string]entity.Money)
+	unpriced := make(map[string]error)
+	for _, product := range products {
+		price, err := pricing.Resolve(product.Price, priceLists[product.ProductId], currency, handler.exchangeRates)
+		if err != nil {
+			unpriced[product.ProductId] = fmt.Errorf("product %s is not sold in %s: %v", product.Name, currency, err)
+			continue
+		}
+		prices[product.ProductId] = price
+	}
+
+	return prices, unpriced, nil
+}
+
+// localizeProducts returns products priced in currency and fails when one of
+// them cannot be.
+func (handler *CartHandler) localizeProducts(products []entity.Product, currency string) ([]entity.Product, error) {
+	prices, unpriced, err := handler.localPrices(products, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	localized := make([]entity.Product, len(products))
+	for index, product := range products {
+		if err, ok := unpriced[product.ProductId]; ok {
+			return nil, err
+		}
+		product.Price = prices[product.ProductId]
+		product.Currency = product.Price.Currency
+		localized[index] = product
+	}
+
+	return localized, nil
+}
+
+// saveCartItem stores the quantity of a product in the cart at its current
+// price in currency.
+func (handler *CartHandler) saveCartItem(cartID, productID string, quantity int, currency string) error {
+	product, err := handler.store.GetProductByID(productID)
+	if err != nil {
+		return err
+	}
+	if product.ProductId == "" || !product.IsActive {
+		return fmt.Errorf("product %s is not available", productID)
+	}
+
+	localized, err := handler.localizeProducts([]entity.Product{*product}, currency)
+	if err != nil {
+		return err
+	}
+	product = &localized[0]
+	if product.Quantity < quantity {
+		return fmt.Errorf("product %s is not available in the quantity requested", product.Name)
+	}
+
+	return handler.cartStore.UpsertCartItem(entity.CartItem{
+		CartID:     cartID,
+		ProductID:  productID,
+		Quantity:   quantity,
+		PriceAtAdd: product.Price,
+		Currency:   product.Currency,
+	})
+}
+
+func (handler *CartHandler) writePricedCart(w http.ResponseWriter, r *http.Request, status int) {
+	currency, err := storefrontCurrency(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	cart, err := handler.getCart(w, r, false)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if cart == nil {
+		cart = &entity.Cart{}
+	}
+
+	priced, err := handler.priceCart(cart, currency)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, status, priced, nil)
+}
+
+// priceCart re-prices the cart in currency against the live catalogue and
+// flags lines that are no longer available or whose price moved since they
+// were added.
+func (handler *CartHandler) priceCart(cart *entity.Cart, currency string) (*entity.PricedCart, error) {
+	priced := &entity.PricedCart{
+		ID:        cart.ID,
+		UserID:    cart.UserID,
+		SessionID: cart.SessionID,
+		Lines:     []entity.PricedLine{},
+		Currency:  currency,
+		UpdatedAt: cart.UpdatedAt,
+	}
+	priced.Subtotal = entity.NewMoney(0, priced.Currency)
+
+	if len(cart.Items) == 0 {
+		return priced, nil
+	}
+
+	productIDs := make([]string, len(cart.Items))
+	for index, item := range cart.Items {
+		productIDs[index] = item.ProductID
+	}
+
+	products, err := handler.store.GetProductsByIDs(productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	prices, unpriced, err := handler.localPrices(products, currency)
+	if err != nil {
+		return nil, err
+	}
+
 	productsMap := make(map[string]entity.Product)
 	for _, product := range products {
+		if _, ok := unpriced[product.ProductId]; ok {
+			continue
+		}
+		product.Price = prices[product.ProductId]
+		product.Currency = product.Price.Currency
 		productsMap[product.ProductId] = product
 	}
 
-	if err := checkIfCartIsInStock(cartItems, productsMap); err != nil {
-		return "", 0, 0, err
+	for _, item := range cart.Items {
+		line := entity.PricedLine{
+			ProductID:  item.ProductID,
+			Quantity:   item.Quantity,
+			PriceAtAdd: item.PriceAtAdd,
+			Currency:   item.Currency,
+		}
+
+		product, ok := productsMap[item.ProductID]
+		switch {
+		case unpriced[item.ProductID] != nil:
+			line.Issue = unpriced[item.ProductID].Error()
+		case !ok:
+			line.Issue = "product no longer exists"
+		case !product.IsActive:
+			line.Issue = "product is no longer available"
+		case product.Quantity < item.Quantity:
+			line.Issue = fmt.Sprintf("only %d left in stock", product.Quantity)
+		default:
+			line.Available = true
+		}
+
+		if ok {
+			line.ProductName = product.Name
+			line.UnitPrice = product.Price
+			line.Currency = product.Currency
+			line.PriceChanged = product.Price != item.PriceAtAdd
+			if line.PriceChanged && line.Issue == "" {
+				line.Issue = fmt.Sprintf("price changed from %s to %s", item.PriceAtAdd, product.Price)
+			}
+		}
+
+		// every product is priced in currency at this point, the sum cannot mix currencies
+		if line.Available {
+			line.LineTotal = line.UnitPrice.Mul(line.Quantity)
+			priced.Subtotal.Amount += line.LineTotal.Amount
+		}
+
+		priced.HasIssues = priced.HasIssues || line.Issue != ""
+		priced.Lines = append(priced.Lines, line)
 	}
 
-	totalPriceBeforeTaxAndDis, totalPriceAfterTaxAndDis := calculateTotalPrice(cartItems, productsMap)
+	return priced, nil
+}
 
-	for _, item := range cartItems {
-		product := productsMap[item.ProductID]
-		product.Quantity -= item.Quantity
-		handler.store.UpdateProduct(product)
-	}
-
-	orderId, err := handler.orderStore.CreateOrder(entity.Order{
-		UserID:        userID,
-		Total:         totalPriceAfterTaxAndDis,
-		Subtotal:      totalPriceBeforeTaxAndDis,
-		Status:        configs.Envs.OrderStatusPending,
-		PaymentStatus: configs.Envs.PaymentStatusPending,
-		PaymentMethod: "Credit Card",
-		Address:       "USA, New York, 123 wall street, Apt:10032, 143B",
-		Currency:      configs.Envs.DEFAULT_CURRENCY,
+// checkoutItems returns the items sent with the checkout request or, when none
+// were sent, the items of the stored cart along with that cart.
+func (handler *CartHandler) checkoutItems(w http.ResponseWriter, r *http.Request, items []entity.CartCheckoutItem) ([]entity.CartCheckoutItem, *entity.Cart, error) {
+	if len(items) > 0 {
+		return items, nil, nil
+	}
+
+	storedCart, err := handler.getCart(w, r, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	if storedCart == nil {
+		return nil, nil, nil
+	}
+
+	return cartItemsToCheckoutItems(storedCart.Items), storedCart, nil
+}
+
+func cartItemsToCheckoutItems(items []*entity.CartItem) []entity.CartCheckoutItem {
+	checkoutItems := make([]entity.CartCheckoutItem, len(items))
+	for index, item := range items {
+		checkoutItems[index] = entity.CartCheckoutItem{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			Currency:  item.Currency,
+		}
+	}
+	return checkoutItems
+}
+
+// resolveCheckoutAddresses picks the shipping and billing addresses for an order.
+// A saved address ID wins over an inline address, which wins over the user's
+// default. Billing falls back to the shipping address when nothing else is set.
+func (handler *CartHandler) resolveCheckoutAddresses(cart payloads.CartCheckoutPayload, userID string) (entity.OrderAddress, entity.OrderAddress, error) {
+	shipping, err := handler.resolveAddress(cart.ShippingAddressID, cart.ShippingAddress, userID, handler.addressStore.GetDefaultShippingAddress)
+	if err != nil {
+		return entity.OrderAddress{}, entity.OrderAddress{}, err
+	}
+	if shipping == nil {
+		return entity.OrderAddress{}, entity.OrderAddress{}, fmt.Errorf("shipping address is required, provide an address ID or an inline address")
+	}
+
+	billing, err := handler.resolveAddress(cart.BillingAddressID, cart.BillingAddress, userID, handler.addressStore.GetDefaultBillingAddress)
+	if err != nil {
+		return entity.OrderAddress{}, entity.OrderAddress{}, err
+	}
+	if billing == nil {
+		billing = shipping
+	}
+
+	return *shipping, *billing, nil
+}
+
+func (handler *CartHandler) resolveAddress(addressID string, inline *payloads.OrderAddressPayload, userID string, getDefault func(userID string) (*entity.UserAddress, error)) (*entity.OrderAddress, error) {
+	if addressID != "" {
+		address, err := handler.addressStore.GetAddressByID(addressID)
+		if err != nil || address.UserID != userID {
+			return nil, fmt.Errorf("address %s not found", addressID)
+		}
+		snapshot := address.Snapshot()
+		return &snapshot, nil
+	}
+
+	if inline != nil {
+		return &entity.OrderAddress{FullName: inline.FullName, Phone: inline.Phone, Address: entity.Address(inline.Address)}, nil
+	}
+
+	address, err := getDefault(userID)
+	if err != nil {
+		return nil, err
+	}
+	if address == nil {
+		return nil, nil
+	}
+	snapshot := address.Snapshot()
+	return &snapshot, nil
+}
+
+// pricedCheckout is what quoting shipping and placing an order both derive
+// from the cart before anything is reserved.
+type pricedCheckout struct {
+	products  map[string]entity.Product
+	currency  string
+	subtotal  entity.Money // Sum of the lines before discounts
+	discounts *promotion.Result
+}
+
+// discounted returns what the buyer pays for the goods once discounts are taken off.
+func (p *pricedCheckout) discounted() entity.Money {
+	return entity.NewMoney(p.subtotal.Amount-p.discounts.Discount.Amount, p.currency)
+}
+
+func (handler *CartHandler) priceCheckout(products []entity.Product, co checkout) (*pricedCheckout, error) {
+	products, err := handler.localizeProducts(products, co.currency)
+	if err != nil {
+		return nil, err
+	}
+	currency := co.currency
+
+	productsMap := make(map[string]entity.Product)
+	for _, product := range products {
+		productsMap[product.ProductId] = product
+	}
+
+	if err := checkIfCartIsInStock(co.items, productsMap); err != nil {
+		return nil, err
+	}
+
+	lines := promotionLines(co.items, productsMap)
+	subtotal := entity.NewMoney(0, currency)
+	for _, line := range lines {
+		subtotal.Amount += line.Total().Amount
+	}
+
+	promotions, err := handler.resolvePromotions(co, subtotal)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pricedCheckout{
+		products:  productsMap,
+		currency:  currency,
+		subtotal:  subtotal,
+		discounts: promotion.Apply(promotions, lines),
+	}, nil
+}
+
+// shippingQuotes prices shipping for a checkout the same way createOrder does,
+// promotions included, so the quoted costs are the ones charged.
+func (handler *CartHandler) shippingQuotes(products []entity.Product, co checkout) ([]entity.ShippingQuote, error) {
+	priced, err := handler.priceCheckout(products, co)
+	if err != nil {
+		return nil, err
+	}
+
+	quotes, err := handler.quoteShipping(co.shippingAddress.Address, shippingItems(co.items, priced.products), priced.discounted(), priced.discounts.FreeShipping)
+	if err != nil {
+		return nil, err
+	}
+	if quotes == nil {
+		quotes = []entity.ShippingQuote{}
+	}
+
+	return quotes, nil
+}
+
+// checkout collects what createOrder needs to place an order. Either userID
+// or guestEmail identifies the buyer.
+type checkout struct {
+	userID          string
+	guestEmail      string
+	items           []entity.CartCheckoutItem
+	currency        string // Currency the order is priced and placed in
+	shippingAddress entity.OrderAddress
+	billingAddress  entity.OrderAddress
+	couponCodes     []string
+	shippingMethod  string
+	giftCardCodes   []string
+	useWallet       bool // Pay what the gift cards leave from the wallet of userID
+	loyaltyPoints   int  // Points of userID to take off the order
+}
+
+func (handler *CartHandler) createOrder(products []entity.Product, co checkout) (string, entity.Money, entity.Money, error) {
+	cartItems := co.items
+
+	priced, err := handler.priceCheckout(products, co)
+	if err != nil {
+		return "", entity.Money{}, entity.Money{}, err
+	}
+	productsMap, currency, discounts := priced.products, priced.currency, priced.discounts
+
+	taxes, err := handler.taxCalculator.CalculateTax(entity.TaxRequest{
+		Address:  co.shippingAddress.Address,
+		Currency: currency,
+		Lines:    taxLines(cartItems, productsMap, discounts),
 	})
+	if err != nil {
+		return "", entity.Money{}, entity.Money{}, fmt.Errorf("failed to calculate tax: %v", err)
+	}
 
+	quotes, err := handler.quoteShipping(co.shippingAddress.Address, shippingItems(cartItems, productsMap), priced.discounted(), discounts.FreeShipping)
 	if err != nil {
-		return "", 0, 0, err
+		return "", entity.Money{}, entity.Money{}, err
 	}
 
-	for _, item := range cartItems {
-		indivisualSubTotalPrice, indivisualTotalPrice := calculateIndivisualProductPricing(productsMap[item.ProductID], item)
-		handler.orderStore.CreateOrderItem(entity.OrderItem{
-			OrderID:     orderId,
-			ProductID:   item.ProductID,
-			ProductName: productsMap[item.ProductID].Name,
-			Quantity:    item.Quantity,
-			Price:       productsMap[item.ProductID].Price,
-			TotalPrice:  indivisualTotalPrice,
-			Subtotal:    indivisualSubTotalPrice,
-			Currency:    item.Currency,
-			Discount:    item.Discount,
-			Tax:         item.Tax,
+	delivery, err := chooseShipping(quotes, co.shippingMethod, currency)
+	if err != nil {
+		return "", entity.Money{}, entity.Money{}, err
+	}
+
+	totalPriceBeforeTaxAndDis, totalPriceAfterTaxAndDis, err := calculateTotalPrice(cartItems, productsMap, discounts, taxes)
+	if err != nil {
+		return "", entity.Money{}, entity.Money{}, err
+	}
+	if totalPriceAfterTaxAndDis, err = totalPriceAfterTaxAndDis.Add(delivery.Cost); err != nil {
+		return "", entity.Money{}, entity.Money{}, err
+	}
+
+	// points come off first, gift cards and the wallet pay what they leave
+	points, loyaltyDiscount, err := handler.loyalty.Quote(co.userID, co.loyaltyPoints, totalPriceAfterTaxAndDis)
+	if err != nil {
+		return "", entity.Money{}, entity.Money{}, err
+	}
+	due, err := totalPriceAfterTaxAndDis.Sub(loyaltyDiscount)
+	if err != nil {
+		return "", entity.Money{}, entity.Money{}, err
+	}
+
+	tenders, storeCredit, err := handler.tenderStoreCredit(co, due)
+	if err != nil {
+		return "", entity.Money{}, entity.Money{}, err
+	}
+	paymentMethod := "Credit Card"
+	switch {
+	case due.IsZero():
+		paymentMethod = "Loyalty Points"
+	case !storeCredit.IsZero() && storeCredit == due:
+		paymentMethod = "Store Credit"
+	}
+
+	order := entity.Order{
+		UserID:          co.userID,
+		Total:           totalPriceAfterTaxAndDis,
+		Subtotal:        totalPriceBeforeTaxAndDis,
+		Status:          configs.Envs.OrderStatusPending,
+		PaymentStatus:   configs.Envs.PaymentStatusPending,
+		PaymentMethod:   paymentMethod,
+		Address:         co.shippingAddress.String(),
+		Currency:        currency,
+		ShippingAddress: co.shippingAddress,
+		BillingAddress:  co.billingAddress,
+		GuestEmail:      co.guestEmail,
+		Discount:        discounts.Discount,
+		Tax:             taxes.Tax,
+		TaxBreakdown:    taxes.Breakdown,
+		TaxInclusive:    taxes.Inclusive,
+
+		ShippingMethodID: delivery.MethodID,
+		ShippingMethod:   delivery.Name,
+		ShippingCost:     delivery.Cost,
+	}
+
+	orderItems := make([]*entity.OrderItem, 0, len(cartItems))
+	for index, item := range cartItems {
+		product := productsMap[item.ProductID]
+		indivisualSubTotalPrice, indivisualTotalPrice, err := calculateIndivisualProductPricing(product, item, discounts.LineDiscount(index), taxes.AddedTax(index))
+		if err != nil {
+			return "", entity.Money{}, entity.Money{}, err
+		}
+		orderItems = append(orderItems, &entity.OrderItem{
+			ProductID:        item.ProductID,
+			ProductName:      product.Name,
+			Quantity:         item.Quantity,
+			Price:            product.Price,
+			TotalPrice:       indivisualTotalPrice,
+			Subtotal:         indivisualSubTotalPrice,
+			Currency:         currency,
+			Discount:         discounts.LineDiscount(index),
+			Tax:              taxes.Lines[index].Tax,
+			AppliedDiscounts: discounts.LineDiscounts[index],
+			TaxBreakdown:     taxes.Lines[index].Breakdown,
+			StoreID:          product.StoreID,
+			Category:         product.Category,
+		})
+	}
+
+	storeOrders, err := fulfilment.Split(order, orderItems)
+	if err != nil {
+		return "", entity.Money{}, entity.Money{}, fmt.Errorf("failed to split the order by store: %v", err)
+	}
+
+	redemptions := make([]entity.PromotionRedemption, 0, len(discounts.Applied))
+	for _, applied := range discounts.Applied {
+		redemptions = append(redemptions, entity.PromotionRedemption{
+			PromotionID: applied.PromotionID,
+			UserID:      co.userID,
+			GuestEmail:  co.guestEmail,
+			Code:        applied.Code,
+			Discount:    applied.Amount,
 		})
 	}
 
+	if err := handler.reservePromotions(discounts.Applied); err != nil {
+		return "", entity.Money{}, entity.Money{}, err
+	}
+
+	// the products were priced in the order currency, so only the stock is
+	// written back, and only while there is enough of it
+	quantities := make(map[string]int, len(cartItems))
+	taken := make([]string, 0, len(cartItems))
+	for _, item := range cartItems {
+		if _, ok := quantities[item.ProductID]; !ok {
+			taken = append(taken, item.ProductID)
+		}
+		quantities[item.ProductID] += item.Quantity
+	}
+	if err := handler.store.TakeStock(quantities); err != nil {
+		handler.releasePromotions(discounts.Applied)
+		return "", entity.Money{}, entity.Money{}, err
+	}
+	handler.events.PublishProducts(handler.store, entity.TopicProductStockChanged, taken...)
+
+	// the order, its items, store orders and redemptions are written together,
+	// so a buyer is never charged for an order missing any of them
+	orderId, err := handler.orderStore.PlaceOrder(order, orderItems, storeOrders, redemptions)
+	if err != nil {
+		handler.releasePromotions(discounts.Applied)
+		if returnErr := handler.store.ReturnStock(quantities); returnErr != nil {
+			log.Printf("failed to return the stock of an order that was not created: %v", returnErr)
+		} else {
+			handler.events.PublishProducts(handler.store, entity.TopicProductStockChanged, taken...)
+		}
+		return "", entity.Money{}, entity.Money{}, err
+	}
+
+	if points > 0 {
+		if err := handler.payWithPoints(orderId, co.userID, points, loyaltyDiscount); err != nil {
+			return "", entity.Money{}, entity.Money{}, err
+		}
+	}
+
+	if len(tenders) > 0 {
+		if err := handler.payWithStoreCredit(orderId, tenders, storeCredit); err != nil {
+			return "", entity.Money{}, entity.Money{}, err
+		}
+	}
+
 	return orderId, totalPriceBeforeTaxAndDis, totalPriceAfterTaxAndDis, nil
 }
 
-func roundToTwoDecimals(value float64) float64 {
-	return math.Round(value*100) / 100
+// tenderStoreCredit works out how much of total the gift cards of a checkout
+// and, when asked, the wallet of the buyer pay. Nothing is taken yet, see
+// payWithStoreCredit.
+func (handler *CartHandler) tenderStoreCredit(co checkout, total entity.Money) ([]entity.OrderTender, entity.Money, error) {
+	if len(co.giftCardCodes) == 0 && !co.useWallet {
+		return nil, entity.NewMoney(0, total.Currency), nil
+	}
+
+	cards := make([]*entity.GiftCard, 0, len(co.giftCardCodes))
+	seen := map[string]bool{}
+	for _, code := range co.giftCardCodes {
+		card, err := handler.creditStore.GetGiftCardByCode(credit.NormalizeCode(code))
+		if err != nil {
+			return nil, entity.Money{}, err
+		}
+		if card.ID == "" {
+			return nil, entity.Money{}, fmt.Errorf("gift card %s not found", code)
+		}
+		if seen[card.ID] {
+			continue
+		}
+		seen[card.ID] = true
+		cards = append(cards, card)
+	}
+
+	wallet := entity.NewMoney(0, total.Currency)
+	if co.useWallet {
+		if co.userID == "" {
+			return nil, entity.Money{}, fmt.Errorf("paying from a wallet requires an account")
+		}
+		var err error
+		if wallet, err = handler.creditStore.GetWalletBalance(co.userID, total.Currency); err != nil {
+			return nil, entity.Money{}, err
+		}
+	}
+
+	return credit.Tender(total, cards, wallet, co.userID, time.Now())
+}
+
+// payWithPoints takes the points redeemed on a new order off the balance of
+// its buyer. When they were spent on another order in the meantime, the order
+// is cancelled, which gives its stock and coupons back.
+func (handler *CartHandler) payWithPoints(orderID, userID string, points int, discount entity.Money) error {
+	taken, err := handler.loyalty.Redeem(userID, orderID, points)
+	if err == nil && taken {
+		if err = handler.orderStore.SetOrderLoyaltyDiscount(orderID, points, discount); err == nil {
+			return nil
+		}
+	}
+
+	if cancelErr := handler.cancelUnpaidOrder(orderID); cancelErr != nil {
+		log.Printf("failed to cancel order %s after loyalty points error: %v", orderID, cancelErr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to redeem loyalty points: %v", err)
+	}
+	return fmt.Errorf("loyalty points balance changed during checkout, please try again")
+}
+
+// payWithStoreCredit takes the tenders of a new order off their cards and
+// wallet. When they no longer cover their amounts, spent on another order in
+// the meantime, the order is cancelled, which gives its stock, coupons and
+// redeemed points back.
+func (handler *CartHandler) payWithStoreCredit(orderID string, tenders []entity.OrderTender, amount entity.Money) error {
+	taken, err := handler.creditStore.ApplyOrderTenders(orderID, tenders)
+	if err == nil && taken {
+		if err = handler.orderStore.SetOrderStoreCredit(orderID, amount); err == nil {
+			return nil
+		}
+	}
+
+	if cancelErr := handler.cancelUnpaidOrder(orderID); cancelErr != nil {
+		log.Printf("failed to cancel order %s after store credit error: %v", orderID, cancelErr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to pay with store credit: %v", err)
+	}
+	return fmt.Errorf("gift card or wallet balance changed during checkout, please try again")
+}
+
+// cancelUnpaidOrder cancels an order that will not be paid and gives back the
+// store credit tendered for it and the points redeemed on it along with its
+// stock and coupons. The order.cancelled event is written with the
+// cancellation.
+func (handler *CartHandler) cancelUnpaidOrder(orderID string) error {
+	order, err := handler.orderStore.GetOrderByID(orderID)
+	if err != nil {
+		return err
+	}
+	order.Status = configs.Envs.OrderStatusCancelled
+	event, err := entity.NewOrderEvent(entity.TopicOrderCancelled, *order)
+	if err != nil {
+		return err
+	}
+
+	cancelled, err := handler.orderStore.CancelOrder(orderID, event)
+	if err != nil || !cancelled {
+		return err
+	}
+	handler.events.PublishOrderStock(handler.orderStore, handler.store, orderID)
+
+	if _, err := handler.creditStore.ReleaseOrderTenders(orderID, entity.TenderReleased); err != nil {
+		return err
+	}
+	return handler.loyalty.OrderCancelled(orderID)
+}
+
+// openCheckoutSession opens the checkout session of an order, split between
+// the stores that sold its items.
+func (handler *CartHandler) openCheckoutSession(order entity.Order, items []*entity.OrderItem, email, idempotencyKey string) (*entity.CheckoutSession, error) {
+	rules, err := handler.payoutStore.GetCommissionRules()
+	if err != nil {
+		return nil, err
+	}
+	shares, err := payout.Split(items, rules, float64(configs.Envs.CommissionPercent))
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := map[string]string{}
+	for _, share := range shares {
+		store, err := handler.storeOwnerStore.GetStoreOwnerByID(share.StoreID)
+		if err != nil {
+			return nil, err
+		}
+		accounts[share.StoreID] = store.StripeAccountID
+	}
+
+	split, err := payout.PaymentSplit(order, items, shares, accounts)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler.paymentStore.CreateCheckoutSession(order, items, email, split, idempotencyKey)
+}
+
+// startPayment opens a checkout session for what is due on a new order and
+// returns it for the buyer to pay through, along with the order. An order with
+// nothing left to pay, store credit and points included, is marked paid right
+// away, writing the order.paid event, and earns its points, no session is
+// returned. When the payment cannot be started the order is cancelled, which
+// gives its stock, coupons, store credit and points back, so a retry places a
+// fresh order without holding anything twice. The order placed email,
+// rendered with placed on top of the order, goes out once the payment is
+// started, ahead of the payment received one for orders paid right away.
+func (handler *CartHandler) startPayment(orderID, email, idempotencyKey string, placed map[string]string) (*entity.CheckoutSession, *entity.Order, error) {
+	session, order, err := handler.beginPayment(orderID, email, idempotencyKey, placed)
+	if err != nil {
+		if cancelErr := handler.cancelUnpaidOrder(orderID); cancelErr != nil {
+			log.Printf("failed to cancel order %s after payment error: %v", orderID, cancelErr)
+		}
+		return nil, nil, err
+	}
+	return session, order, nil
+}
+
+// beginPayment does the work of startPayment, leaving the order as it is on
+// failure.
+func (handler *CartHandler) beginPayment(orderID, email, idempotencyKey string, placed map[string]string) (*entity.CheckoutSession, *entity.Order, error) {
+	order, err := handler.orderStore.GetOrderByID(orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if order.AmountDue().IsZero() {
+		placedEmail, err := handler.notifier.OrderEmail(notification.OrderPlaced, *order, notification.OrderPlaced+":"+orderID, placed)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		order.PaymentStatus = configs.Envs.PaymentStatusPaid
+		order.Status = configs.Envs.OrderStatusProcessing
+		event, err := entity.NewOrderEvent(entity.TopicOrderPaid, *order)
+		if err != nil {
+			return nil, nil, err
+		}
+		messages := []entity.OutboxMessage{event}
+
+		paidEmail, err := handler.notifier.OrderEmail(notification.PaymentReceived, *order, notification.PaymentReceived+":"+orderID, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, email := range []*entity.OutboxMessage{placedEmail, paidEmail} {
+			if email != nil {
+				messages = append(messages, *email)
+			}
+		}
+
+		if _, err := handler.orderStore.MarkOrderPaid(orderID, messages...); err != nil {
+			return nil, nil, err
+		}
+		// the order is paid either way, the points can be added by hand
+		if err := handler.loyalty.OrderPaid(orderID); err != nil {
+			log.Printf("failed to credit the loyalty points of order %s: %v", orderID, err)
+		}
+		return nil, order, nil
+	}
+
+	items, err := handler.orderStore.GetOrderItemsByOrderId(orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	session, err := handler.openCheckoutSession(*order, items, email, idempotencyKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating checkout session: %v", err)
+	}
+
+	// the webhook finds the order through the session metadata, so a failure
+	// here only loses the lookup by session
+	if err := handler.orderStore.SetOrderPaymentSession(orderID, session.ID); err != nil {
+		log.Printf("failed to record checkout session %s for order %s: %v", session.ID, orderID, err)
+	}
+
+	// the order is placed at this point, a mail failure must not undo it
+	if err := handler.notifier.SendOrderEmail(notification.OrderPlaced, *order, notification.OrderPlaced+":"+orderID, placed); err != nil {
+		log.Printf("failed to send order placed email for order %s: %v", orderID, err)
+	}
+
+	return session, order, nil
 }
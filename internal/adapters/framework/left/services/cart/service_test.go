@@ -0,0 +1,594 @@
+package cart
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ecom-api/internal/adapters/framework/left/services/auth"
+	"ecom-api/internal/adapters/framework/left/services/outbox"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/application/core/types/entity/payloads"
+	"ecom-api/internal/ports/right/rports"
+	"ecom-api/pkg/configs"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockAddressStore keeps the address books in a map. Methods checkout does not
+// use are left to the embedded interface and panic when called.
+type mockAddressStore struct {
+	rports.AddressStore
+	addresses map[string]*entity.UserAddress
+}
+
+func (m *mockAddressStore) GetAddressByID(addressID string) (*entity.UserAddress, error) {
+	address, ok := m.addresses[addressID]
+	if !ok {
+		return nil, errors.New("address not found")
+	}
+	return address, nil
+}
+
+func (m *mockAddressStore) defaultAddress(userID string, billing bool) *entity.UserAddress {
+	for _, address := range m.addresses {
+		if address.UserID == userID && ((billing && address.IsDefaultBilling) || (!billing && address.IsDefaultShipping)) {
+			return address
+		}
+	}
+	return nil
+}
+
+func (m *mockAddressStore) GetDefaultShippingAddress(userID string) (*entity.UserAddress, error) {
+	return m.defaultAddress(userID, false), nil
+}
+
+func (m *mockAddressStore) GetDefaultBillingAddress(userID string) (*entity.UserAddress, error) {
+	return m.defaultAddress(userID, true), nil
+}
+
+func TestResolveCheckoutAddresses(t *testing.T) {
+	addresses := &mockAddressStore{addresses: map[string]*entity.UserAddress{
+		"home":   {ID: "home", UserID: "user-1", FullName: "Jane Doe", Address: entity.Address{Line1: "1 Main St", Country: "US"}, IsDefaultShipping: true, IsDefaultBilling: true},
+		"office": {ID: "office", UserID: "user-1", FullName: "Jane Doe", Address: entity.Address{Line1: "9 Work Rd", Country: "US"}},
+		"other":  {ID: "other", UserID: "user-2", FullName: "John Roe", Address: entity.Address{Line1: "5 Elm St", Country: "US"}},
+	}}
+	handler := &CartHandler{addressStore: addresses}
+
+	t.Run("the defaults are used when checkout names no address", func(t *testing.T) {
+		shipping, billing, err := handler.resolveCheckoutAddresses(payloads.CartCheckoutPayload{}, "user-1")
+		assert.NoError(t, err)
+		assert.Equal(t, "1 Main St", shipping.Address.Line1)
+		assert.Equal(t, shipping, billing)
+	})
+
+	t.Run("a saved address is copied onto the order", func(t *testing.T) {
+		shipping, billing, err := handler.resolveCheckoutAddresses(payloads.CartCheckoutPayload{ShippingAddressID: "office"}, "user-1")
+		assert.NoError(t, err)
+		assert.Equal(t, entity.OrderAddress{FullName: "Jane Doe", Address: entity.Address{Line1: "9 Work Rd", Country: "US"}}, shipping)
+		assert.Equal(t, "1 Main St", billing.Address.Line1)
+
+		// editing the address book afterwards leaves the snapshot alone
+		addresses.addresses["office"].Address.Line1 = "10 Work Rd"
+		assert.Equal(t, "9 Work Rd", shipping.Address.Line1)
+		addresses.addresses["office"].Address.Line1 = "9 Work Rd"
+	})
+
+	t.Run("the address of another user cannot be shipped or billed to", func(t *testing.T) {
+		_, _, err := handler.resolveCheckoutAddresses(payloads.CartCheckoutPayload{ShippingAddressID: "other"}, "user-1")
+		assert.EqualError(t, err, "address other not found")
+
+		_, _, err = handler.resolveCheckoutAddresses(payloads.CartCheckoutPayload{ShippingAddressID: "home", BillingAddressID: "other"}, "user-1")
+		assert.EqualError(t, err, "address other not found")
+	})
+
+	t.Run("a user without addresses must give one", func(t *testing.T) {
+		_, _, err := handler.resolveCheckoutAddresses(payloads.CartCheckoutPayload{}, "user-3")
+		assert.Error(t, err)
+
+		inline := &payloads.OrderAddressPayload{FullName: "Sam Poe", Address: payloads.PostalAddressPayload{Line1: "2 Side St", City: "Springfield", PostalCode: "12345", Country: "US"}}
+		shipping, _, err := handler.resolveCheckoutAddresses(payloads.CartCheckoutPayload{ShippingAddress: inline}, "user-3")
+		assert.NoError(t, err)
+		assert.Equal(t, "Sam Poe", shipping.FullName)
+	})
+}
+
+func TestCheckoutInlineAddress(t *testing.T) {
+	checkout := func(handle http.HandlerFunc, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodPost, "/cart/checkout", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("error requesting %v", err)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, "user-1"))
+		rr := httptest.NewRecorder()
+		handle(rr, req)
+		return rr
+	}
+
+	// validation runs before any store is used, so the handler needs none
+	handler := &CartHandler{}
+
+	t.Run("an empty inline address is refused", func(t *testing.T) {
+		rr := checkout(handler.handleCartCheckout, `{"shippingAddress": {"fullName": "Sam Poe", "address": {}}}`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		rr = checkout(handler.handleCartCheckout, `{"billingAddress": {"fullName": "Sam Poe", "address": {}}}`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		rr = checkout(handler.handleGuestCheckout, `{"email": "guest@example.com", "shippingAddress": {"fullName": "Sam Poe", "address": {}}}`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("a guest checking out with the email of an account is answered like any guest", func(t *testing.T) {
+		handler := &CartHandler{
+			userStore: &mockUserStore{users: map[string]*entity.User{
+				"user-1": {ID: "user-1", Email: "jane@example.com"},
+			}},
+			cartStore: &mockCartStore{carts: map[string]*entity.Cart{}},
+		}
+		address := `"shippingAddress": {"fullName": "Sam Poe", "address": {"line1": "2 Side St", "city": "Springfield", "postal_code": "12345", "country": "US"}}`
+
+		registered := checkout(handler.handleGuestCheckout, `{"email": "jane@example.com", `+address+`}`)
+		unknown := checkout(handler.handleGuestCheckout, `{"email": "sam@example.com", `+address+`}`)
+
+		assert.Equal(t, unknown.Code, registered.Code)
+		assert.Equal(t, unknown.Body.String(), registered.Body.String())
+		assert.NotContains(t, registered.Body.String(), "jane@example.com")
+	})
+
+	t.Run("an inline address without a postal code is refused", func(t *testing.T) {
+		rr := checkout(handler.handleGuestCheckout, `{"email": "guest@example.com", "shippingAddress": {"fullName": "Sam Poe", "address": {"line1": "2 Side St", "city": "Springfield", "country": "US"}}}`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), "PostalCode")
+	})
+}
+
+// mockCartStore keeps carts and their items in maps.
+type mockCartStore struct {
+	rports.CartStore
+	carts   map[string]*entity.Cart
+	deleted []string
+}
+
+func (m *mockCartStore) find(match func(cart *entity.Cart) bool) *entity.Cart {
+	for _, cart := range m.carts {
+		if match(cart) {
+			copied := *cart
+			copied.Items = m.items(cart.ID)
+			return &copied
+		}
+	}
+	return nil
+}
+
+func (m *mockCartStore) items(cartID string) []*entity.CartItem {
+	items := []*entity.CartItem{}
+	for _, item := range m.carts[cartID].Items {
+		copied := *item
+		items = append(items, &copied)
+	}
+	return items
+}
+
+func (m *mockCartStore) CreateCart(cart entity.Cart) (string, error) {
+	cart.ID = fmt.Sprintf("cart-%d", len(m.carts)+1)
+	m.carts[cart.ID] = &cart
+	return cart.ID, nil
+}
+
+func (m *mockCartStore) GetCartByUserID(userID string) (*entity.Cart, error) {
+	return m.find(func(cart *entity.Cart) bool { return cart.UserID == userID }), nil
+}
+
+func (m *mockCartStore) GetCartBySessionID(sessionID string) (*entity.Cart, error) {
+	return m.find(func(cart *entity.Cart) bool { return cart.SessionID == sessionID }), nil
+}
+
+func (m *mockCartStore) AssignCartToUser(cartID, userID string) error {
+	m.carts[cartID].UserID = userID
+	m.carts[cartID].SessionID = ""
+	return nil
+}
+
+func (m *mockCartStore) DeleteCart(cartID string) error {
+	m.deleted = append(m.deleted, cartID)
+	delete(m.carts, cartID)
+	return nil
+}
+
+func (m *mockCartStore) UpsertCartItem(item entity.CartItem) error {
+	cart := m.carts[item.CartID]
+	for _, existing := range cart.Items {
+		if existing.ProductID == item.ProductID {
+			existing.Quantity = item.Quantity
+			return nil
+		}
+	}
+	cart.Items = append(cart.Items, &item)
+	return nil
+}
+
+func (m *mockCartStore) GetCartItems(cartID string) ([]*entity.CartItem, error) {
+	return m.items(cartID), nil
+}
+
+func TestGetCart(t *testing.T) {
+	const session = "5d9c7a4e-1f0b-4b7e-9a53-1c2f3e4d5a6b"
+
+	getCart := func(handler *CartHandler, userID, sessionID string, create bool) (*entity.Cart, *httptest.ResponseRecorder, error) {
+		req, err := http.NewRequest(http.MethodGet, "/cart", nil)
+		if err != nil {
+			t.Fatalf("error requesting %v", err)
+		}
+		if userID != "" {
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, userID))
+		}
+		if sessionID != "" {
+			req.Header.Set(CartSessionHeader, sessionID)
+		}
+		rr := httptest.NewRecorder()
+		cart, err := handler.getCart(rr, req, create)
+		return cart, rr, err
+	}
+
+	quantities := func(cart *entity.Cart) map[string]int {
+		counted := map[string]int{}
+		for _, item := range cart.Items {
+			counted[item.ProductID] = item.Quantity
+		}
+		return counted
+	}
+
+	t.Run("an anonymous cart is merged into the cart of the user logging in", func(t *testing.T) {
+		carts := &mockCartStore{carts: map[string]*entity.Cart{
+			"cart-user": {ID: "cart-user", UserID: "user-1", Items: []*entity.CartItem{
+				{CartID: "cart-user", ProductID: "product-1", Quantity: 1},
+				{CartID: "cart-user", ProductID: "product-2", Quantity: 4},
+			}},
+			"cart-session": {ID: "cart-session", SessionID: session, Items: []*entity.CartItem{
+				{CartID: "cart-session", ProductID: "product-1", Quantity: 2},
+				{CartID: "cart-session", ProductID: "product-3", Quantity: 1},
+			}},
+		}}
+		handler := &CartHandler{cartStore: carts}
+
+		cart, _, err := getCart(handler, "user-1", session, false)
+		assert.NoError(t, err)
+		assert.Equal(t, "cart-user", cart.ID)
+		assert.Equal(t, map[string]int{"product-1": 3, "product-2": 4, "product-3": 1}, quantities(cart))
+		assert.Equal(t, []string{"cart-session"}, carts.deleted)
+
+		// sending the old session again merges nothing more
+		cart, _, err = getCart(handler, "user-1", session, false)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int{"product-1": 3, "product-2": 4, "product-3": 1}, quantities(cart))
+	})
+
+	t.Run("an anonymous cart becomes the cart of a user who had none", func(t *testing.T) {
+		carts := &mockCartStore{carts: map[string]*entity.Cart{
+			"cart-session": {ID: "cart-session", SessionID: session, Items: []*entity.CartItem{
+				{CartID: "cart-session", ProductID: "product-1", Quantity: 2},
+			}},
+		}}
+		handler := &CartHandler{cartStore: carts}
+
+		cart, _, err := getCart(handler, "user-1", session, false)
+		assert.NoError(t, err)
+		assert.Equal(t, "cart-session", cart.ID)
+		assert.Equal(t, "user-1", carts.carts["cart-session"].UserID)
+		assert.Empty(t, carts.carts["cart-session"].SessionID)
+		assert.Empty(t, carts.deleted)
+	})
+
+	t.Run("an anonymous caller gets a session cart and its header", func(t *testing.T) {
+		carts := &mockCartStore{carts: map[string]*entity.Cart{}}
+		handler := &CartHandler{cartStore: carts}
+
+		cart, _, err := getCart(handler, "", "", false)
+		assert.NoError(t, err)
+		assert.Nil(t, cart)
+
+		cart, rr, err := getCart(handler, "", "", true)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, cart.SessionID)
+		assert.Equal(t, cart.SessionID, rr.Header().Get(CartSessionHeader))
+
+		again, _, err := getCart(handler, "", cart.SessionID, true)
+		assert.NoError(t, err)
+		assert.Equal(t, cart.ID, again.ID)
+		assert.Len(t, carts.carts, 1)
+	})
+
+	t.Run("a session that is not a UUID is refused", func(t *testing.T) {
+		handler := &CartHandler{cartStore: &mockCartStore{carts: map[string]*entity.Cart{}}}
+
+		_, _, err := getCart(handler, "", "cart-session", true)
+		assert.EqualError(t, err, "invalid cart session")
+	})
+}
+
+// mockOrderStore serves a single order and records cancellations.
+type mockOrderStore struct {
+	rports.OrderStore
+	order     entity.Order
+	cancelled []string
+}
+
+func (m *mockOrderStore) GetOrderByID(orderID string) (*entity.Order, error) {
+	order := m.order
+	return &order, nil
+}
+
+func (m *mockOrderStore) GetOrderItemsByOrderId(orderID string) ([]*entity.OrderItem, error) {
+	return nil, errors.New("connection lost")
+}
+
+func (m *mockOrderStore) CancelOrder(orderID string, messages ...entity.OutboxMessage) (bool, error) {
+	m.cancelled = append(m.cancelled, orderID)
+	// already cancelled, so nothing more is given back
+	return false, nil
+}
+
+func TestStartPayment(t *testing.T) {
+	orderStore := &mockOrderStore{order: entity.Order{
+		ID:     "order-1",
+		Total:  entity.NewMoney(2500, "USD"),
+		Status: "pending",
+	}}
+	handler := &CartHandler{orderStore: orderStore}
+
+	// an order whose payment cannot be started would hold its stock, coupons
+	// and store credit, and a retry would place a second one
+	_, _, err := handler.startPayment("order-1", "buyer@example.com", "", nil)
+	assert.Error(t, err)
+	assert.Equal(t, []string{"order-1"}, orderStore.cancelled)
+}
+
+// mockOrderReadStore serves one order with its items and store orders.
+type mockOrderReadStore struct {
+	rports.OrderStore
+	order       entity.Order
+	items       []*entity.OrderItem
+	storeOrders []*entity.StoreOrder
+}
+
+func (m *mockOrderReadStore) GetOrderByID(orderID string) (*entity.Order, error) {
+	if orderID != m.order.ID {
+		return &entity.Order{}, nil
+	}
+	order := m.order
+	return &order, nil
+}
+
+func (m *mockOrderReadStore) GetOrderItemsByOrderId(orderID string) ([]*entity.OrderItem, error) {
+	return m.items, nil
+}
+
+func (m *mockOrderReadStore) GetStoreOrdersByOrderID(orderID string) ([]*entity.StoreOrder, error) {
+	return m.storeOrders, nil
+}
+
+func (m *mockOrderReadStore) GetOrdersByUserID(userID string) ([]*entity.Order, error) {
+	if userID != m.order.UserID {
+		return nil, nil
+	}
+	order := m.order
+	return []*entity.Order{&order}, nil
+}
+
+type mockUserStore struct {
+	rports.UserStore
+	users map[string]*entity.User
+}
+
+func (m *mockUserStore) GetUserByID(userID string) (*entity.User, error) {
+	if user, ok := m.users[userID]; ok {
+		return user, nil
+	}
+	return &entity.User{}, nil
+}
+
+func (m *mockUserStore) GetUserByEmail(email string) (*entity.User, error) {
+	for _, user := range m.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return &entity.User{}, nil
+}
+
+type mockStoreOwnerStore struct {
+	rports.StoreOwnerStore
+	stores map[string]*entity.StoreOwner
+}
+
+func (m *mockStoreOwnerStore) GetStoreOwnerByEmail(email string) (*entity.StoreOwner, error) {
+	if store, ok := m.stores[email]; ok {
+		return store, nil
+	}
+	return &entity.StoreOwner{}, nil
+}
+
+func TestOrderAccess(t *testing.T) {
+	handler := &CartHandler{
+		orderStore: &mockOrderReadStore{
+			order: entity.Order{ID: "order-1", UserID: "buyer"},
+			items: []*entity.OrderItem{
+				{ID: "item-1", OrderID: "order-1", StoreID: "store-1"},
+				{ID: "item-2", OrderID: "order-1", StoreID: "store-2"},
+			},
+			storeOrders: []*entity.StoreOrder{
+				{ID: "store-order-1", OrderID: "order-1", StoreID: "store-1"},
+				{ID: "store-order-2", OrderID: "order-1", StoreID: "store-2"},
+			},
+		},
+		userStore: &mockUserStore{users: map[string]*entity.User{
+			"buyer":    {ID: "buyer", Email: "buyer@example.com", Role: "user"},
+			"stranger": {ID: "stranger", Email: "stranger@example.com", Role: "user"},
+			"seller":   {ID: "seller", Email: "seller@example.com", Role: "storeowner"},
+			"rival":    {ID: "rival", Email: "rival@example.com", Role: "storeowner"},
+			"admin":    {ID: "admin", Email: "admin@example.com", Role: "admin"},
+		}},
+		storeOwnerStore: &mockStoreOwnerStore{stores: map[string]*entity.StoreOwner{
+			"seller@example.com": {StoreID: "store-1"},
+			"rival@example.com":  {StoreID: "store-9"},
+		}},
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/order/{orderId}", handler.handleGetOrderById)
+	router.HandleFunc("/order/user/{userId}", handler.handleGetOrderByUserId)
+	router.HandleFunc("/orderitem/{orderId}", handler.handleGetOrderItemByOrderId)
+
+	get := func(userID, path string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		if err != nil {
+			t.Fatalf("error requesting %v", err)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, userID))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("the buyer, a selling store and admins read the order", func(t *testing.T) {
+		for _, userID := range []string{"buyer", "seller", "admin"} {
+			assert.Equal(t, http.StatusOK, get(userID, "/order/order-1").Code, userID)
+			assert.Equal(t, http.StatusOK, get(userID, "/orderitem/order-1").Code, userID)
+		}
+	})
+
+	t.Run("anyone else cannot tell the order from a missing one", func(t *testing.T) {
+		for _, userID := range []string{"stranger", "rival"} {
+			assert.Equal(t, http.StatusNotFound, get(userID, "/order/order-1").Code, userID)
+			assert.Equal(t, http.StatusNotFound, get(userID, "/orderitem/order-1").Code, userID)
+		}
+		assert.Equal(t, http.StatusNotFound, get("buyer", "/order/order-9").Code)
+	})
+
+	t.Run("a store only sees the items it sold", func(t *testing.T) {
+		rr := get("seller", "/orderitem/order-1")
+
+		var items []*entity.OrderItem
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &items))
+		assert.Len(t, items, 1)
+		assert.Equal(t, "item-1", items[0].ID)
+	})
+
+	t.Run("only buyers and admins list the orders of a buyer", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, get("buyer", "/order/user/buyer").Code)
+		assert.Equal(t, http.StatusOK, get("admin", "/order/user/buyer").Code)
+		assert.Equal(t, http.StatusForbidden, get("stranger", "/order/user/buyer").Code)
+		assert.Equal(t, http.StatusForbidden, get("seller", "/order/user/buyer").Code)
+	})
+}
+
+// mockFulfilmentStore keeps one order and its store orders, and records the
+// outbox messages written with each move of the order.
+type mockFulfilmentStore struct {
+	rports.OrderStore
+	order       entity.Order
+	storeOrders []*entity.StoreOrder
+	messages    []entity.OutboxMessage
+}
+
+func (m *mockFulfilmentStore) GetOrderByID(orderID string) (*entity.Order, error) {
+	if orderID != m.order.ID {
+		return &entity.Order{}, nil
+	}
+	order := m.order
+	return &order, nil
+}
+
+func (m *mockFulfilmentStore) GetStoreOrdersByOrderID(orderID string) ([]*entity.StoreOrder, error) {
+	storeOrders := make([]*entity.StoreOrder, len(m.storeOrders))
+	for i, storeOrder := range m.storeOrders {
+		copied := *storeOrder
+		storeOrders[i] = &copied
+	}
+	return storeOrders, nil
+}
+
+func (m *mockFulfilmentStore) UpdateStoreOrderStatus(storeOrderID, fromStatus, toStatus string) (bool, error) {
+	for _, storeOrder := range m.storeOrders {
+		if storeOrder.ID == storeOrderID && storeOrder.Status == fromStatus {
+			storeOrder.Status = toStatus
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockFulfilmentStore) MoveOrderStatus(orderID, fromStatus, toStatus string, messages ...entity.OutboxMessage) (bool, error) {
+	if m.order.Status != fromStatus {
+		return false, nil
+	}
+	m.order.Status = toStatus
+	m.messages = append(m.messages, messages...)
+	return true, nil
+}
+
+func TestUpdateOrderStatus(t *testing.T) {
+	processing := configs.Envs.OrderStatusProcessing
+	shipped := configs.Envs.OrderStatusShipped
+	cancelled := configs.Envs.OrderStatusCancelled
+
+	update := func(handler *CartHandler, orderID, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodPost, "/order/update/status/"+orderID, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("error requesting %v", err)
+		}
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/order/update/status/{orderId}", handler.handlerUpdateOrderStatus)
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("the order and its live store orders move on with a status event", func(t *testing.T) {
+		store := &mockFulfilmentStore{
+			order: entity.Order{ID: "order-1", Status: processing},
+			storeOrders: []*entity.StoreOrder{
+				{ID: "store-order-1", OrderID: "order-1", Status: processing},
+				{ID: "store-order-2", OrderID: "order-1", Status: cancelled},
+			},
+		}
+		handler := &CartHandler{orderStore: store, events: outbox.NewDispatcher(nil)}
+
+		rr := update(handler, "order-1", `{"status": "`+shipped+`"}`)
+
+		assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		assert.Equal(t, shipped, store.order.Status)
+		assert.Equal(t, shipped, store.storeOrders[0].Status)
+		assert.Equal(t, cancelled, store.storeOrders[1].Status)
+		assert.Len(t, store.messages, 1)
+		assert.Equal(t, entity.TopicOrderStatusChanged, store.messages[0].Topic)
+	})
+
+	t.Run("a status is required and must follow the fulfilment", func(t *testing.T) {
+		store := &mockFulfilmentStore{order: entity.Order{ID: "order-1", Status: configs.Envs.OrderStatusPending}}
+		handler := &CartHandler{orderStore: store, events: outbox.NewDispatcher(nil)}
+
+		assert.Equal(t, http.StatusBadRequest, update(handler, "order-1", `{}`).Code)
+		assert.Equal(t, http.StatusBadRequest, update(handler, "order-1", `{"status": "teleported"}`).Code)
+		assert.Equal(t, http.StatusConflict, update(handler, "order-1", `{"status": "`+shipped+`"}`).Code)
+		assert.Equal(t, http.StatusNotFound, update(handler, "order-9", `{"status": "`+processing+`"}`).Code)
+		assert.Equal(t, configs.Envs.OrderStatusPending, store.order.Status)
+		assert.Empty(t, store.messages)
+	})
+
+	t.Run("cancelling and refunding are left to their own flows", func(t *testing.T) {
+		store := &mockFulfilmentStore{order: entity.Order{ID: "order-1", Status: processing}}
+		handler := &CartHandler{orderStore: store, events: outbox.NewDispatcher(nil)}
+
+		assert.Equal(t, http.StatusBadRequest, update(handler, "order-1", `{"status": "`+cancelled+`"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, update(handler, "order-1", `{"status": "`+configs.Envs.OrderStatusRefunded+`"}`).Code)
+		assert.Equal(t, processing, store.order.Status)
+	})
+}
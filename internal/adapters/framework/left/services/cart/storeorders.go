@@ -0,0 +1,537 @@
+package cart
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"ecom-api/internal/adapters/framework/left/services/auth"
+	"ecom-api/internal/adapters/framework/left/services/notification"
+	"ecom-api/internal/application/core/fulfilment"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/application/core/types/entity/payloads"
+	"ecom-api/pkg/configs"
+	"ecom-api/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+var errStoreOrderNotFound = fmt.Errorf("store order not found")
+var errOrderNotFound = fmt.Errorf("order not found")
+
+func orderStatuses() fulfilment.Statuses {
+	return fulfilment.Statuses{
+		Pending:    configs.Envs.OrderStatusPending,
+		Processing: configs.Envs.OrderStatusProcessing,
+		Shipped:    configs.Envs.OrderStatusShipped,
+		Completed:  configs.Envs.OrderStatusCompleted,
+		Cancelled:  configs.Envs.OrderStatusCancelled,
+		Refunded:   configs.Envs.OrderStatusRefunded,
+	}
+}
+
+// storeOrdersWithDetails returns the store orders of an order with the items
+// and shipments of each.
+func (handler *CartHandler) storeOrdersWithDetails(orderID string, items []*entity.OrderItem) ([]*entity.StoreOrder, error) {
+	storeOrders, err := handler.orderStore.GetStoreOrdersByOrderID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, storeOrder := range storeOrders {
+		if err := handler.fillStoreOrder(storeOrder, items); err != nil {
+			return nil, err
+		}
+	}
+	if storeOrders == nil {
+		storeOrders = []*entity.StoreOrder{}
+	}
+	return storeOrders, nil
+}
+
+// fillStoreOrder attaches the items of the order sold by the store and the
+// shipments of the store order.
+func (handler *CartHandler) fillStoreOrder(storeOrder *entity.StoreOrder, items []*entity.OrderItem) error {
+	for _, item := range items {
+		if item.StoreID == storeOrder.StoreID {
+			storeOrder.Items = append(storeOrder.Items, item)
+		}
+	}
+
+	shipments, err := handler.orderStore.GetShipmentsByStoreOrderID(storeOrder.ID)
+	if err != nil {
+		return err
+	}
+	storeOrder.Shipments = shipments
+	return nil
+}
+
+// callerStoreID returns the store run by the caller and whether the caller is
+// an admin. The store is empty for admins and anyone not running a store.
+func (handler *CartHandler) callerStoreID(r *http.Request) (string, bool, error) {
+	user, err := handler.userStore.GetUserByID(auth.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		return "", false, err
+	}
+	if user.Role == "admin" {
+		return "", true, nil
+	}
+
+	store, err := handler.storeOwnerStore.GetStoreOwnerByEmail(user.Email)
+	if err != nil {
+		return "", false, err
+	}
+	return store.StoreID, false, nil
+}
+
+// callerStoreOrder returns the store order of the request when the caller may
+// manage it, an admin or the owner of its store.
+func (handler *CartHandler) callerStoreOrder(r *http.Request) (*entity.StoreOrder, bool, error) {
+	storeOrderID, ok := mux.Vars(r)["storeOrderId"]
+	if !ok {
+		return nil, false, errStoreOrderNotFound
+	}
+
+	storeID, isAdmin, err := handler.callerStoreID(r)
+	if err != nil {
+		return nil, false, err
+	}
+
+	storeOrder, err := handler.orderStore.GetStoreOrderByID(storeOrderID)
+	if err != nil {
+		return nil, false, err
+	}
+	// a store owner cannot tell the store orders of other stores from missing ones
+	if storeOrder.ID == "" || (!isAdmin && (storeID == "" || storeOrder.StoreID != storeID)) {
+		return nil, false, errStoreOrderNotFound
+	}
+
+	return storeOrder, isAdmin, nil
+}
+
+// callerOrder returns an order when the caller may read it: its buyer, an
+// admin or the owner of a store selling in it. The store is set only when the
+// caller reads the order as a store owner, who should only see its own items.
+func (handler *CartHandler) callerOrder(r *http.Request, orderID string) (*entity.Order, string, error) {
+	order, err := handler.orderStore.GetOrderByID(orderID)
+	if err != nil {
+		return nil, "", err
+	}
+	// nobody can tell the orders of others from missing ones
+	if order.ID == "" {
+		return nil, "", errOrderNotFound
+	}
+	if order.UserID != "" && order.UserID == auth.GetUserIDFromContext(r.Context()) {
+		return order, "", nil
+	}
+
+	storeID, isAdmin, err := handler.callerStoreID(r)
+	if err != nil {
+		return nil, "", err
+	}
+	if isAdmin {
+		return order, "", nil
+	}
+	if storeID == "" {
+		return nil, "", errOrderNotFound
+	}
+
+	storeOrders, err := handler.orderStore.GetStoreOrdersByOrderID(order.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, storeOrder := range storeOrders {
+		if storeOrder.StoreID == storeID {
+			return order, storeID, nil
+		}
+	}
+	return nil, "", errOrderNotFound
+}
+
+// moveStoreOrder moves a store order to another status and the order it
+// belongs to along with it.
+func (handler *CartHandler) moveStoreOrder(storeOrder *entity.StoreOrder, status string) error {
+	statuses := orderStatuses()
+	if !statuses.CanTransition(storeOrder.Status, status) {
+		return fmt.Errorf("store order cannot go from %s to %s", storeOrder.Status, status)
+	}
+
+	moved, err := handler.orderStore.UpdateStoreOrderStatus(storeOrder.ID, storeOrder.Status, status)
+	if err != nil {
+		return err
+	}
+	if !moved {
+		return fmt.Errorf("store order %s was updated meanwhile, try again", storeOrder.ID)
+	}
+	storeOrder.Status = status
+
+	return handler.rollupOrderStatus(storeOrder.OrderID)
+}
+
+// rollupOrderStatus sets the status of an order from the status of its store
+// orders. The order.status_changed event, and the email telling the buyer the
+// order as a whole was delivered, cancelled or refunded, are written with the
+// status so they go out exactly when it changes.
+func (handler *CartHandler) rollupOrderStatus(orderID string) error {
+	storeOrders, err := handler.orderStore.GetStoreOrdersByOrderID(orderID)
+	if err != nil {
+		return err
+	}
+
+	statuses := make([]string, 0, len(storeOrders))
+	for _, storeOrder := range storeOrders {
+		statuses = append(statuses, storeOrder.Status)
+	}
+
+	status := orderStatuses().Rollup(statuses)
+	if status == "" {
+		return nil
+	}
+
+	order, err := handler.orderStore.GetOrderByID(orderID)
+	if err != nil {
+		return err
+	}
+	if order.Status == status {
+		return nil
+	}
+
+	moved, err := handler.moveOrderStatus(order, status)
+	if err != nil {
+		return err
+	}
+	if !moved {
+		// another store order moved the order meanwhile, roll up what it left
+		return handler.rollupOrderStatus(orderID)
+	}
+	return nil
+}
+
+// moveOrderStatus moves an order to status along with the status changed
+// event and the email telling the buyer. It returns false when the order was
+// moved meanwhile.
+func (handler *CartHandler) moveOrderStatus(order *entity.Order, status string) (bool, error) {
+	fromStatus := order.Status
+	moved := *order
+	moved.Status = status
+
+	event, err := entity.NewOrderEvent(entity.TopicOrderStatusChanged, moved)
+	if err != nil {
+		return false, err
+	}
+	messages := []entity.OutboxMessage{event}
+	// a mail failure must not keep the order from moving
+	email, err := handler.orderStatusEmail(moved)
+	if err != nil {
+		log.Printf("failed to render %s email for order %s: %v", status, order.ID, err)
+	} else if email != nil {
+		messages = append(messages, *email)
+	}
+
+	ok, err := handler.orderStore.MoveOrderStatus(order.ID, fromStatus, status, messages...)
+	if err != nil || !ok {
+		return false, err
+	}
+	order.Status = status
+	handler.events.Wake()
+	return true, nil
+}
+
+// moveOrder moves an order to another status of its fulfilment. Its store
+// orders move along and the order follows them, an order without store orders
+// is moved itself.
+func (handler *CartHandler) moveOrder(order *entity.Order, status string) error {
+	statuses := orderStatuses()
+	if !statuses.CanTransition(order.Status, status) {
+		return fmt.Errorf("order cannot go from %s to %s", order.Status, status)
+	}
+
+	storeOrders, err := handler.orderStore.GetStoreOrdersByOrderID(order.ID)
+	if err != nil {
+		return err
+	}
+	if len(storeOrders) == 0 {
+		moved, err := handler.moveOrderStatus(order, status)
+		if err != nil {
+			return err
+		}
+		if !moved {
+			return fmt.Errorf("order %s was updated meanwhile, try again", order.ID)
+		}
+		return nil
+	}
+
+	// store orders already past the status, or cancelled, are left as they are
+	for _, storeOrder := range storeOrders {
+		if !statuses.CanTransition(storeOrder.Status, status) {
+			continue
+		}
+		if _, err := handler.orderStore.UpdateStoreOrderStatus(storeOrder.ID, storeOrder.Status, status); err != nil {
+			return err
+		}
+	}
+	return handler.rollupOrderStatus(order.ID)
+}
+
+// orderStatusEmail renders the lifecycle email of the status an order moved
+// to, nil when it has none. Shipped orders are told about per shipment
+// instead, with the tracking number.
+func (handler *CartHandler) orderStatusEmail(order entity.Order) (*entity.OutboxMessage, error) {
+	var email string
+	switch order.Status {
+	case configs.Envs.OrderStatusCompleted:
+		email = notification.OrderDelivered
+	case configs.Envs.OrderStatusCancelled:
+		email = notification.OrderCancelled
+	case configs.Envs.OrderStatusRefunded:
+		email = notification.OrderRefunded
+	default:
+		return nil, nil
+	}
+	return handler.notifier.OrderEmail(email, order, email+":"+order.ID, nil)
+}
+
+// shipmentEmail renders the email telling the buyer a parcel of their order
+// is on its way, keyed by the shipment so it goes out once per parcel.
+func (handler *CartHandler) shipmentEmail(orderID string, shipment entity.Shipment) (*entity.OutboxMessage, error) {
+	order, err := handler.orderStore.GetOrderByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	return handler.notifier.OrderEmail(notification.OrderShipped, *order, notification.OrderShipped+":"+shipment.ID, map[string]string{
+		"carrier":         shipment.Carrier,
+		"tracking_number": shipment.TrackingNumber,
+	})
+}
+
+func (handler *CartHandler) handleGetOrderSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	orderId, ok := mux.Vars(r)["orderId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing order ID"))
+		return
+	}
+
+	user, err := handler.userStore.GetUserByID(auth.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	order, err := handler.orderStore.GetOrderByID(orderId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if order.ID == "" || (user.Role != "admin" && order.UserID != user.ID) {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("order not found"))
+		return
+	}
+
+	orderitems, err := handler.orderStore.GetOrderItemsByOrderId(orderId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	storeOrders, err := handler.storeOrdersWithDetails(orderId, orderitems)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"order":       order,
+		"items":       orderitems,
+		"storeOrders": storeOrders,
+	}, nil)
+}
+
+func (handler *CartHandler) handleGetStoreOrders(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	// store owners see their own store orders, admins pick the store
+	storeID, isAdmin, err := handler.callerStoreID(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if isAdmin {
+		storeID = r.URL.Query().Get("storeId")
+	}
+	if storeID == "" {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("store not found"))
+		return
+	}
+
+	storeOrders, err := handler.orderStore.GetStoreOrdersByStoreID(storeID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if storeOrders == nil {
+		storeOrders = []*entity.StoreOrder{}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, storeOrders, nil)
+}
+
+func (handler *CartHandler) handleGetStoreOrder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	storeOrder, _, err := handler.callerStoreOrder(r)
+	if err == errStoreOrderNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	order, err := handler.orderStore.GetOrderByID(storeOrder.OrderID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	items, err := handler.orderStore.GetOrderItemsByOrderId(storeOrder.OrderID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := handler.fillStoreOrder(storeOrder, items); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// the store gets where to ship, not the rest of the order
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"storeOrder":      storeOrder,
+		"shippingAddress": order.ShippingAddress,
+		"shippingMethod":  order.ShippingMethod,
+	}, nil)
+}
+
+func (handler *CartHandler) handleUpdateStoreOrderStatus(w http.ResponseWriter, r *http.Request) {
+	var statusParams payloads.StoreOrderStatusPayload
+
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	if err := utils.ParseJSON(r, &statusParams); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(statusParams); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors)
+		return
+	}
+
+	storeOrder, isAdmin, err := handler.callerStoreOrder(r)
+	if err == errStoreOrderNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// money goes back through the payment refund, which only admins make
+	if statusParams.Status == configs.Envs.OrderStatusRefunded && !isAdmin {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only admins refund store orders"))
+		return
+	}
+
+	if err := handler.moveStoreOrder(storeOrder, statusParams.Status); err != nil {
+		utils.WriteError(w, http.StatusConflict, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, storeOrder, nil)
+}
+
+func (handler *CartHandler) handleCreateShipment(w http.ResponseWriter, r *http.Request) {
+	var shipmentParams payloads.ShipmentPayload
+
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	if err := utils.ParseJSON(r, &shipmentParams); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(shipmentParams); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors)
+		return
+	}
+
+	storeOrder, _, err := handler.callerStoreOrder(r)
+	if err == errStoreOrderNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// the first parcel ships the store order, more can follow while it is shipped
+	if storeOrder.Status != configs.Envs.OrderStatusProcessing && storeOrder.Status != configs.Envs.OrderStatusShipped {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("store order is %s, nothing to ship", storeOrder.Status))
+		return
+	}
+
+	// the ID keys the shipped email, which is written with the shipment
+	shipment := entity.Shipment{
+		ID:             utils.GenerateRandomUniqueIdentifier(),
+		StoreOrderID:   storeOrder.ID,
+		Carrier:        shipmentParams.Carrier,
+		TrackingNumber: shipmentParams.TrackingNumber,
+	}
+	var messages []entity.OutboxMessage
+	// a mail failure must not keep the parcel from being recorded
+	email, err := handler.shipmentEmail(storeOrder.OrderID, shipment)
+	if err != nil {
+		log.Printf("failed to render shipped email for shipment %s: %v", shipment.ID, err)
+	} else if email != nil {
+		messages = append(messages, *email)
+	}
+
+	if _, err := handler.orderStore.CreateShipment(shipment, messages...); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	handler.events.Wake()
+
+	if storeOrder.Status == configs.Envs.OrderStatusProcessing {
+		if err := handler.moveStoreOrder(storeOrder, configs.Envs.OrderStatusShipped); err != nil {
+			utils.WriteError(w, http.StatusConflict, err)
+			return
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, map[string]interface{}{
+		"shipment":   shipment,
+		"storeOrder": storeOrder,
+	}, nil)
+}
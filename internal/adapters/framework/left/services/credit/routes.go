@@ -0,0 +1,315 @@
+package credit
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"ecom-api/internal/adapters/framework/left/services/auth"
+	"ecom-api/internal/application/core/credit"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/application/core/types/entity/payloads"
+	"ecom-api/internal/ports/right/rports"
+	"ecom-api/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type CreditHandler struct {
+	store     rports.CreditStore
+	userStore rports.UserStore
+}
+
+func NewCreditHandler(store rports.CreditStore, userStore rports.UserStore) *CreditHandler {
+	return &CreditHandler{store: store, userStore: userStore}
+}
+
+func (handler *CreditHandler) RegisterRoutes(router *mux.Router) {
+	//admin routes
+	router.HandleFunc("/giftcards", auth.WithJWTAuth(handler.handleGetGiftCards, handler.userStore, "admin")).Methods(http.MethodGet)
+	router.HandleFunc("/giftcards", auth.WithJWTAuth(handler.handleCreateGiftCard, handler.userStore, "admin")).Methods(http.MethodPost)
+	router.HandleFunc("/giftcard/activate/{giftCardId}", auth.WithJWTAuth(handler.handleGiftCardActivation, handler.userStore, "admin")).Methods(http.MethodPut)
+	router.HandleFunc("/giftcard/deactivate/{giftCardId}", auth.WithJWTAuth(handler.handleGiftCardDeactivation, handler.userStore, "admin")).Methods(http.MethodPut)
+	router.HandleFunc("/wallet/credit/{userId}", auth.WithJWTAuth(handler.handleCreditWallet, handler.userStore, "admin")).Methods(http.MethodPost)
+
+	router.HandleFunc("/giftcard/balance/{code}", auth.WithJWTAuth(handler.handleGetGiftCardBalance, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodGet)
+	router.HandleFunc("/wallet", auth.WithJWTAuth(handler.handleGetWallet, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodGet)
+	router.HandleFunc("/wallet/redeem", auth.WithJWTAuth(handler.handleRedeemGiftCard, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodPost)
+}
+
+func (handler *CreditHandler) handleGetGiftCards(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	cards, err := handler.store.GetGiftCards()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, cards, nil)
+}
+
+func (handler *CreditHandler) handleCreateGiftCard(w http.ResponseWriter, r *http.Request) {
+	var payload payloads.GiftCardPayload
+
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors)
+		return
+	}
+
+	if payload.ExpiresAt != nil && !payload.ExpiresAt.After(time.Now()) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("expiresAt must be in the future"))
+		return
+	}
+
+	code, err := credit.NewCode()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	amount := entity.MoneyFromMajor(payload.Amount, payload.Currency)
+	card := entity.GiftCard{
+		Code:           code,
+		InitialBalance: amount,
+		Balance:        amount,
+		Currency:       amount.Currency,
+		Status:         entity.GiftCardActive,
+		RecipientEmail: payload.RecipientEmail,
+		Note:           payload.Note,
+		ExpiresAt:      payload.ExpiresAt,
+	}
+
+	cardId, err := handler.store.CreateGiftCard(card)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	card.ID = cardId
+
+	utils.WriteJSON(w, http.StatusCreated, card, nil)
+}
+
+func (handler *CreditHandler) handleGiftCardActivation(w http.ResponseWriter, r *http.Request) {
+	handler.setGiftCardStatus(w, r, entity.GiftCardActive)
+}
+
+func (handler *CreditHandler) handleGiftCardDeactivation(w http.ResponseWriter, r *http.Request) {
+	handler.setGiftCardStatus(w, r, entity.GiftCardDisabled)
+}
+
+func (handler *CreditHandler) setGiftCardStatus(w http.ResponseWriter, r *http.Request, status string) {
+	if r.Method != http.MethodPut {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	vars := mux.Vars(r)
+	cardId, ok := vars["giftCardId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing gift card ID"))
+		return
+	}
+
+	found, err := handler.store.SetGiftCardStatus(cardId, status)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !found {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("gift card %s not found", cardId))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"giftCardId": cardId, "status": status}, nil)
+}
+
+func (handler *CreditHandler) handleCreditWallet(w http.ResponseWriter, r *http.Request) {
+	var payload payloads.WalletCreditPayload
+
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	vars := mux.Vars(r)
+	userId, ok := vars["userId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing user ID"))
+		return
+	}
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors)
+		return
+	}
+
+	user, err := handler.userStore.GetUserByID(userId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if user.ID == "" {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("user %s not found", userId))
+		return
+	}
+
+	amount := entity.MoneyFromMajor(payload.Amount, payload.Currency)
+	entry := entity.WalletEntry{
+		UserID:   userId,
+		Amount:   amount,
+		Currency: amount.Currency,
+		Reason:   entity.WalletAdjustment,
+	}
+	entry.ID, err = handler.store.CreditWallet(entry)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, entry, nil)
+}
+
+func (handler *CreditHandler) handleGetGiftCardBalance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	vars := mux.Vars(r)
+	code, ok := vars["code"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing gift card code"))
+		return
+	}
+
+	card, err := handler.store.GetGiftCardByCode(credit.NormalizeCode(code))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if card.ID == "" {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("gift card not found"))
+		return
+	}
+
+	// whoever holds the code sees what it is worth, not who it was issued to
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"code":      card.Code,
+		"balance":   card.Balance,
+		"currency":  card.Currency,
+		"status":    card.Status,
+		"expiresAt": card.ExpiresAt,
+	}, nil)
+}
+
+func (handler *CreditHandler) handleGetWallet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	balances, err := handler.store.GetWalletBalances(userId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	entries, err := handler.store.GetWalletEntries(userId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if balances == nil {
+		balances = []*entity.WalletBalance{}
+	}
+	if entries == nil {
+		entries = []*entity.WalletEntry{}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"balances": balances,
+		"entries":  entries,
+	}, nil)
+}
+
+func (handler *CreditHandler) handleRedeemGiftCard(w http.ResponseWriter, r *http.Request) {
+	var payload payloads.GiftCardRedeemPayload
+
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors)
+		return
+	}
+
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	card, err := handler.store.GetGiftCardByCode(credit.NormalizeCode(payload.Code))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if card.ID == "" {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("gift card not found"))
+		return
+	}
+	if err := credit.Spendable(card, card.Currency, time.Now()); err != nil {
+		utils.WriteError(w, http.StatusConflict, err)
+		return
+	}
+
+	// the card may have been spent since it was read, the store only moves
+	// what is still on it
+	redeemed, err := handler.store.RedeemGiftCard(card.ID, userId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if redeemed.IsZero() {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("gift card has no balance left"))
+		return
+	}
+
+	balance, err := handler.store.GetWalletBalance(userId, card.Currency)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"redeemed":      redeemed,
+		"walletBalance": balance,
+	}, nil)
+}
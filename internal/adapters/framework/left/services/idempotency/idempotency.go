@@ -0,0 +1,176 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"ecom-api/internal/adapters/framework/left/services/auth"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/ports/right/rports"
+	"ecom-api/pkg/configs"
+	"ecom-api/utils"
+)
+
+// Header is the request header carrying the client's idempotency key.
+const Header = "Idempotency-Key"
+
+// ReplayedHeader is set on responses replayed from a stored request.
+const ReplayedHeader = "Idempotent-Replayed"
+
+// cartSessionHeader and currencyHeader are the cart's X-Cart-Session and
+// X-Currency, the request they come with is another request when they change.
+const (
+	cartSessionHeader = "X-Cart-Session"
+	currencyHeader    = "X-Currency"
+)
+
+type contextKey string
+
+const keyContextKey contextKey = "idempotencyKey"
+
+// middleware function that makes unsafe requests sent with an Idempotency-Key
+// safe to retry. The first request with a key is handled and its response
+// stored, retries with the same key and body get the stored response back,
+// and reuse of the key for another request is refused. Requests without the
+// header are handled as usual. Wrap it inside the auth middleware so keys are
+// scoped to the user, anonymous requests are scoped to their cart session.
+func WithIdempotencyKey(handlerFunc http.HandlerFunc, store rports.IdempotencyStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(Header)
+		if key == "" {
+			handlerFunc(w, r)
+			return
+		}
+		if len(key) > 255 {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("%s must be at most 255 characters", Header))
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := auth.GetUserIDFromContext(r.Context())
+		if scope == "" {
+			session := r.Header.Get(cartSessionHeader)
+			if utils.Validate.Var(session, "required,uuid") != nil {
+				utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("%s needs a valid %s on anonymous requests", Header, cartSessionHeader))
+				return
+			}
+			scope = "session:" + session
+		}
+		record := entity.IdempotencyRecord{
+			Key:         key,
+			Scope:       scope,
+			Fingerprint: fingerprint(r, body),
+			ExpiresAt:   time.Now().Add(time.Second * time.Duration(configs.Envs.IdempotencyKeyTTL)),
+		}
+
+		existing, err := store.ReserveIdempotencyKey(record)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		if existing != nil {
+			switch {
+			case existing.Fingerprint != record.Fingerprint:
+				utils.WriteError(w, http.StatusUnprocessableEntity, fmt.Errorf("%s %s was already used for a different request", Header, key))
+			case !existing.Completed:
+				utils.WriteError(w, http.StatusConflict, fmt.Errorf("a request with %s %s is still being processed", Header, key))
+			default:
+				replay(w, existing)
+			}
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		ctx := context.WithValue(r.Context(), keyContextKey, providerKey(scope, record.Fingerprint, key))
+		handlerFunc(recorder, r.WithContext(ctx))
+
+		// a server error leaves nothing worth replaying, let the client retry it
+		if recorder.status >= http.StatusInternalServerError {
+			if err := store.ReleaseIdempotencyKey(key, scope); err != nil {
+				log.Printf("failed to release idempotency key %s: %v", key, err)
+			}
+			return
+		}
+
+		record.StatusCode = recorder.status
+		record.ResponseHeaders = w.Header().Clone()
+		record.ResponseBody = recorder.body.Bytes()
+		if err := store.CompleteIdempotencyKey(record); err != nil {
+			log.Printf("failed to store response for idempotency key %s: %v", key, err)
+		}
+	}
+}
+
+// KeyFromContext returns the idempotency key to pass on to the payment
+// provider for the current request, empty when the request carries none. It
+// is derived from the client's key, user and request so that it is unique per
+// request even when clients pick colliding keys.
+func KeyFromContext(ctx context.Context) string {
+	key, ok := ctx.Value(keyContextKey).(string)
+	if !ok {
+		return ""
+	}
+
+	return key
+}
+
+func fingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s\n", r.Method, r.URL.Path)
+	fmt.Fprintf(hash, "%s\n%s\n", r.Header.Get(cartSessionHeader), r.Header.Get(currencyHeader))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func providerKey(scope, fingerprint, key string) string {
+	sum := sha256.Sum256([]byte(scope + "\n" + fingerprint + "\n" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+func replay(w http.ResponseWriter, record *entity.IdempotencyRecord) {
+	for name, values := range record.ResponseHeaders {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(record.StatusCode)
+	w.Write(record.ResponseBody)
+}
+
+// responseRecorder passes the response through to the client while keeping a
+// copy to store.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(data []byte) (int, error) {
+	rec.wroteHeader = true
+	rec.body.Write(data)
+	return rec.ResponseWriter.Write(data)
+}
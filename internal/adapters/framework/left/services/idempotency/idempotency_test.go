@@ -0,0 +1,137 @@
+package idempotency
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ecom-api/internal/application/core/types/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockIdempotencyStore struct {
+	records map[string]*entity.IdempotencyRecord
+}
+
+func (m *mockIdempotencyStore) ReserveIdempotencyKey(record entity.IdempotencyRecord) (*entity.IdempotencyRecord, error) {
+	if existing, ok := m.records[record.Scope+record.Key]; ok {
+		return existing, nil
+	}
+	m.records[record.Scope+record.Key] = &record
+	return nil, nil
+}
+
+func (m *mockIdempotencyStore) CompleteIdempotencyKey(record entity.IdempotencyRecord) error {
+	record.Completed = true
+	m.records[record.Scope+record.Key] = &record
+	return nil
+}
+
+func (m *mockIdempotencyStore) ReleaseIdempotencyKey(key, scope string) error {
+	delete(m.records, scope+key)
+	return nil
+}
+
+func TestWithIdempotencyKey(t *testing.T) {
+	store := &mockIdempotencyStore{records: map[string]*entity.IdempotencyRecord{}}
+	calls := 0
+	status := http.StatusCreated
+	handler := WithIdempotencyKey(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Equal(t, r.Header.Get(Header) != "", KeyFromContext(r.Context()) != "")
+		w.WriteHeader(status)
+		w.Write([]byte(`{"orderId":"1"}`))
+	}, store)
+
+	const session = "6f1c2a9e-3b7d-4e8a-9c51-2d4f6a8b0e13"
+	sendAs := func(key, body, session, currency string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/cart/guest/checkout", bytes.NewBufferString(body))
+		if key != "" {
+			req.Header.Set(Header, key)
+		}
+		if session != "" {
+			req.Header.Set(cartSessionHeader, session)
+		}
+		if currency != "" {
+			req.Header.Set(currencyHeader, currency)
+		}
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+	send := func(key, body string) *httptest.ResponseRecorder {
+		return sendAs(key, body, session, "")
+	}
+
+	t.Run("replays the stored response to a retry", func(t *testing.T) {
+		first := send("key-1", `{"a":1}`)
+		retry := send("key-1", `{"a":1}`)
+
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Equal(t, first.Body.String(), retry.Body.String())
+		assert.Equal(t, "true", retry.Header().Get(ReplayedHeader))
+	})
+
+	t.Run("refuses reuse of a key for another body", func(t *testing.T) {
+		rr := send("key-1", `{"a":2}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("refuses a retry while the first request is in flight", func(t *testing.T) {
+		pending := httptest.NewRequest(http.MethodPost, "/cart/guest/checkout", nil)
+		pending.Header.Set(cartSessionHeader, session)
+		store.records["session:"+session+"key-2"] = &entity.IdempotencyRecord{Key: "key-2", Scope: "session:" + session, Fingerprint: fingerprint(pending, []byte(`{}`))}
+		rr := send("key-2", `{}`)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("lets a failed request be retried", func(t *testing.T) {
+		calls = 0
+		status = http.StatusInternalServerError
+		send("key-3", `{}`)
+		status = http.StatusCreated
+		rr := send("key-3", `{}`)
+
+		assert.Equal(t, 2, calls)
+		assert.Equal(t, http.StatusCreated, rr.Code)
+	})
+
+	t.Run("handles requests without a key every time", func(t *testing.T) {
+		calls = 0
+		send("", `{}`)
+		send("", `{}`)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("refuses reuse of a key in another currency", func(t *testing.T) {
+		calls = 0
+		sendAs("key-4", `{}`, session, "USD")
+		rr := sendAs("key-4", `{}`, session, "EUR")
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("scopes anonymous keys to their cart session", func(t *testing.T) {
+		calls = 0
+		other := "0b9e7d4c-5a3f-4b21-8e6d-7c2a1f9e3d50"
+		sendAs("key-5", `{}`, session, "")
+		rr := sendAs("key-5", `{}`, other, "")
+
+		assert.Equal(t, 2, calls)
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Empty(t, rr.Header().Get(ReplayedHeader))
+		assert.Contains(t, store.records, "session:"+other+"key-5")
+	})
+
+	t.Run("refuses anonymous keys without a cart session", func(t *testing.T) {
+		calls = 0
+		assert.Equal(t, http.StatusBadRequest, sendAs("key-6", `{}`, "", "").Code)
+		assert.Equal(t, http.StatusBadRequest, sendAs("key-6", `{}`, "not-a-session", "").Code)
+		assert.Equal(t, 0, calls)
+	})
+}
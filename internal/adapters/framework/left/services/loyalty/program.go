@@ -0,0 +1,173 @@
+package loyalty
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"ecom-api/internal/application/core/loyalty"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/ports/right/rports"
+	"ecom-api/pkg/configs"
+)
+
+// Program earns, spends and gives back the points of orders as their payment
+// status changes. The cart and payment handlers call it wherever an order is
+// paid, cancelled or refunded.
+type Program struct {
+	store      rports.LoyaltyStore
+	orderStore rports.OrderStore
+}
+
+func NewProgram(store rports.LoyaltyStore, orderStore rports.OrderStore) *Program {
+	return &Program{store: store, orderStore: orderStore}
+}
+
+// Tier returns the tier a user is in, nil when none, along with their spend
+// over the last LOYALTY_TIER_WINDOW_IN_DAYS.
+func (p *Program) Tier(userID string) (*entity.LoyaltyTier, []entity.Money, error) {
+	since := time.Now().AddDate(0, 0, -int(configs.Envs.LoyaltyTierWindow))
+	spend, err := p.store.GetLoyaltySpend(userID, since)
+	if err != nil {
+		return nil, nil, err
+	}
+	tiers, err := p.store.GetLoyaltyTiers()
+	if err != nil {
+		return nil, nil, err
+	}
+	return loyalty.Tier(tiers, spend), spend, nil
+}
+
+// Quote works out how many of the requested points a user can spend on an
+// order with due left to pay, and what they take off it. Nothing is taken
+// yet, see Redeem.
+func (p *Program) Quote(userID string, points int, due entity.Money) (int, entity.Money, error) {
+	if points == 0 {
+		return 0, entity.NewMoney(0, due.Currency), nil
+	}
+	if userID == "" {
+		return 0, entity.Money{}, fmt.Errorf("redeeming loyalty points requires an account")
+	}
+
+	balance, err := p.store.GetLoyaltyBalance(userID)
+	if err != nil {
+		return 0, entity.Money{}, err
+	}
+	return loyalty.Redeem(points, balance, due, configs.Envs.LoyaltyPointsPerUnit)
+}
+
+// Redeem takes the points spent on a new order off the balance of its buyer,
+// false when they were spent elsewhere since they were quoted.
+func (p *Program) Redeem(userID, orderID string, points int) (bool, error) {
+	return p.store.RedeemLoyaltyPoints(userID, orderID, points)
+}
+
+// OrderPaid credits the buyer of a paid order with the points it earns and
+// counts what they paid towards their tier. Guests earn nothing. Safe to run
+// again for the same order.
+func (p *Program) OrderPaid(orderID string) error {
+	order, err := p.orderStore.GetOrderByID(orderID)
+	if err != nil {
+		return err
+	}
+	if order.ID == "" {
+		return fmt.Errorf("order %s not found", orderID)
+	}
+	if order.UserID == "" {
+		return nil
+	}
+
+	items, err := p.orderStore.GetOrderItemsByOrderId(orderID)
+	if err != nil {
+		return err
+	}
+	rules, err := p.store.GetLoyaltyEarnRules()
+	if err != nil {
+		return err
+	}
+	// the tier is the one the buyer was in before this order
+	tier, _, err := p.Tier(order.UserID)
+	if err != nil {
+		return err
+	}
+
+	points := loyalty.Earn(*order, items, rules, tier)
+	spend := loyalty.Spend(*order)
+	created, err := p.store.AddLoyaltyEntry(entity.LoyaltyEntry{
+		UserID:   order.UserID,
+		Points:   points,
+		Reason:   entity.LoyaltyEarn,
+		OrderID:  order.ID,
+		Spend:    spend,
+		Currency: spend.Currency,
+	})
+	if err != nil {
+		return err
+	}
+	if created {
+		log.Printf("Order %s earned %d loyalty points", order.ID, points)
+	}
+	return nil
+}
+
+// OrderCancelled gives back the points spent on an order that will not be paid.
+func (p *Program) OrderCancelled(orderID string) error {
+	entries, err := p.store.GetOrderLoyaltyEntries(orderID)
+	if err != nil {
+		return err
+	}
+	return p.giveBack(entries, entity.LoyaltyRelease)
+}
+
+// OrderRefunded takes back the points a refunded order earned, along with
+// the spend it counted towards the tier of its buyer, and gives back the
+// points spent on it. Safe to run again for the same order.
+func (p *Program) OrderRefunded(orderID string) error {
+	entries, err := p.store.GetOrderLoyaltyEntries(orderID)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.Reason != entity.LoyaltyEarn {
+			continue
+		}
+		if _, err := p.store.AddLoyaltyEntry(entity.LoyaltyEntry{
+			UserID:   entry.UserID,
+			Points:   -entry.Points,
+			Reason:   entity.LoyaltyReverse,
+			OrderID:  orderID,
+			Spend:    entity.NewMoney(-entry.Spend.Amount, entry.Currency),
+			Currency: entry.Currency,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return p.giveBack(entries, entity.LoyaltyRefund)
+}
+
+// giveBack returns the points spent on an order under reason, unless they
+// were already given back.
+func (p *Program) giveBack(entries []*entity.LoyaltyEntry, reason string) error {
+	var redeemed *entity.LoyaltyEntry
+	for _, entry := range entries {
+		switch entry.Reason {
+		case entity.LoyaltyRedeem:
+			redeemed = entry
+		case entity.LoyaltyRelease, entity.LoyaltyRefund:
+			return nil
+		}
+	}
+	if redeemed == nil {
+		return nil
+	}
+
+	_, err := p.store.AddLoyaltyEntry(entity.LoyaltyEntry{
+		UserID:  redeemed.UserID,
+		Points:  -redeemed.Points,
+		Reason:  reason,
+		OrderID: redeemed.OrderID,
+	})
+	return err
+}
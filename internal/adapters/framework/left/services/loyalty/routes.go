@@ -0,0 +1,294 @@
+package loyalty
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"ecom-api/internal/adapters/framework/left/services/auth"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/application/core/types/entity/payloads"
+	"ecom-api/internal/ports/right/rports"
+	"ecom-api/pkg/configs"
+	"ecom-api/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type LoyaltyHandler struct {
+	program   *Program
+	userStore rports.UserStore
+}
+
+func NewLoyaltyHandler(program *Program, userStore rports.UserStore) *LoyaltyHandler {
+	return &LoyaltyHandler{program: program, userStore: userStore}
+}
+
+func (handler *LoyaltyHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/loyalty", auth.WithJWTAuth(handler.handleGetLoyaltyAccount, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodGet)
+	router.HandleFunc("/loyalty/history", auth.WithJWTAuth(handler.handleGetLoyaltyHistory, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodGet)
+
+	//admin routes
+	router.HandleFunc("/loyalty/earn_rules", auth.WithJWTAuth(handler.handleGetEarnRules, handler.userStore, "admin")).Methods(http.MethodGet)
+	router.HandleFunc("/loyalty/earn_rules", auth.WithJWTAuth(handler.handleCreateEarnRule, handler.userStore, "admin")).Methods(http.MethodPost)
+	router.HandleFunc("/loyalty/earn_rule/{ruleId}", auth.WithJWTAuth(handler.handleDeleteEarnRule, handler.userStore, "admin")).Methods(http.MethodDelete)
+	router.HandleFunc("/loyalty/tiers", auth.WithJWTAuth(handler.handleGetTiers, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodGet)
+	router.HandleFunc("/loyalty/tiers", auth.WithJWTAuth(handler.handleCreateTier, handler.userStore, "admin")).Methods(http.MethodPost)
+	router.HandleFunc("/loyalty/tier/{tierId}", auth.WithJWTAuth(handler.handleDeleteTier, handler.userStore, "admin")).Methods(http.MethodDelete)
+	router.HandleFunc("/loyalty/adjust/{userId}", auth.WithJWTAuth(handler.handleAdjustPoints, handler.userStore, "admin")).Methods(http.MethodPost)
+}
+
+func (handler *LoyaltyHandler) handleGetLoyaltyAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	userId := auth.GetUserIDFromContext(r.Context())
+
+	balance, err := handler.program.store.GetLoyaltyBalance(userId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	tier, spend, err := handler.program.Tier(userId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if spend == nil {
+		spend = []entity.Money{}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"points":           balance,
+		"pointsPerUnit":    configs.Envs.LoyaltyPointsPerUnit,
+		"tier":             tier,
+		"rollingSpend":     spend,
+		"tierWindowInDays": configs.Envs.LoyaltyTierWindow,
+	}, nil)
+}
+
+func (handler *LoyaltyHandler) handleGetLoyaltyHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	entries, err := handler.program.store.GetLoyaltyEntries(auth.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if entries == nil {
+		entries = []*entity.LoyaltyEntry{}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, entries, nil)
+}
+
+func (handler *LoyaltyHandler) handleGetEarnRules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	rules, err := handler.program.store.GetLoyaltyEarnRules()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, rules, nil)
+}
+
+func (handler *LoyaltyHandler) handleCreateEarnRule(w http.ResponseWriter, r *http.Request) {
+	var ruleParams payloads.LoyaltyEarnRulePayload
+
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	if err := utils.ParseJSON(r, &ruleParams); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(ruleParams); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors)
+		return
+	}
+
+	rule := entity.LoyaltyEarnRule{
+		Type:     ruleParams.Type,
+		Currency: strings.ToUpper(ruleParams.Currency),
+		Rate:     ruleParams.Rate,
+		IsActive: true,
+	}
+	if rule.Type == entity.LoyaltyRuleCategory {
+		rule.Category = ruleParams.Category
+	}
+	ruleID, err := handler.program.store.CreateLoyaltyEarnRule(rule)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	rule.ID = ruleID
+
+	utils.WriteJSON(w, http.StatusCreated, rule, nil)
+}
+
+func (handler *LoyaltyHandler) handleDeleteEarnRule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	ruleID, ok := mux.Vars(r)["ruleId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing rule ID"))
+		return
+	}
+
+	if err := handler.program.store.DeleteLoyaltyEarnRule(ruleID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "loyalty earn rule deleted"}, nil)
+}
+
+func (handler *LoyaltyHandler) handleGetTiers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	tiers, err := handler.program.store.GetLoyaltyTiers()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if tiers == nil {
+		tiers = []*entity.LoyaltyTier{}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, tiers, nil)
+}
+
+func (handler *LoyaltyHandler) handleCreateTier(w http.ResponseWriter, r *http.Request) {
+	var tierParams payloads.LoyaltyTierPayload
+
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	if err := utils.ParseJSON(r, &tierParams); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(tierParams); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors)
+		return
+	}
+
+	minSpend := entity.MoneyFromMajor(tierParams.MinSpend, tierParams.Currency)
+	tier := entity.LoyaltyTier{
+		Name:       tierParams.Name,
+		MinSpend:   minSpend,
+		Currency:   minSpend.Currency,
+		Multiplier: tierParams.Multiplier,
+	}
+	tierID, err := handler.program.store.CreateLoyaltyTier(tier)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	tier.ID = tierID
+
+	utils.WriteJSON(w, http.StatusCreated, tier, nil)
+}
+
+func (handler *LoyaltyHandler) handleDeleteTier(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	tierID, ok := mux.Vars(r)["tierId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing tier ID"))
+		return
+	}
+
+	if err := handler.program.store.DeleteLoyaltyTier(tierID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "loyalty tier deleted"}, nil)
+}
+
+func (handler *LoyaltyHandler) handleAdjustPoints(w http.ResponseWriter, r *http.Request) {
+	var adjustment payloads.LoyaltyAdjustmentPayload
+
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	userId, ok := mux.Vars(r)["userId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing user ID"))
+		return
+	}
+
+	if err := utils.ParseJSON(r, &adjustment); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(adjustment); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors)
+		return
+	}
+
+	user, err := handler.userStore.GetUserByID(userId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if user.ID == "" {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("user %s not found", userId))
+		return
+	}
+
+	if _, err := handler.program.store.AddLoyaltyEntry(entity.LoyaltyEntry{
+		UserID: userId,
+		Points: adjustment.Points,
+		Reason: entity.LoyaltyAdjustment,
+		Note:   adjustment.Note,
+	}); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	balance, err := handler.program.store.GetLoyaltyBalance(userId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, map[string]interface{}{
+		"userId":   userId,
+		"adjusted": adjustment.Points,
+		"points":   balance,
+	}, nil)
+}
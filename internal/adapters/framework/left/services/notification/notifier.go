@@ -0,0 +1,75 @@
+package notification
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/ports/right/rports"
+)
+
+// Notifier sends the emails of the API. Emails are rendered right away, so a
+// broken template fails the caller, and written to the outbox for the
+// dispatcher to deliver through the mailer, so a slow or failing SMTP server
+// never holds up or fails a request.
+type Notifier struct {
+	mailer      rports.Mailer
+	templates   *Templates
+	outbox      rports.OutboxStore
+	preferences rports.NotificationStore
+	userStore   rports.UserStore
+	onQueued    func()
+}
+
+func NewNotifier(mailer rports.Mailer, templates *Templates, outbox rports.OutboxStore, preferences rports.NotificationStore, userStore rports.UserStore) *Notifier {
+	return &Notifier{mailer: mailer, templates: templates, outbox: outbox, preferences: preferences, userStore: userStore}
+}
+
+// OnQueued registers a function called whenever Send queues an email, which
+// is how the dispatcher learns there is mail waiting before its next poll.
+func (n *Notifier) OnQueued(fn func()) {
+	n.onQueued = fn
+}
+
+// Email renders the email template for data into an outbox message for the
+// addresses in to, for a store to write along with the change it tells
+// about. A non-empty key sends the email once however often it is written.
+func (n *Notifier) Email(to []string, subject, template, key string, data map[string]string) (entity.OutboxMessage, error) {
+	html, text, err := n.templates.Render(template, data)
+	if err != nil {
+		return entity.OutboxMessage{}, err
+	}
+	return entity.NewOutboxMessage(entity.TopicEmail, key, entity.Email{To: to, Subject: subject, HTML: html, Text: text})
+}
+
+// Send renders an email like Email does and writes it to the outbox on its
+// own, for emails that go with no change of state.
+func (n *Notifier) Send(to []string, subject, template, key string, data map[string]string) error {
+	message, err := n.Email(to, subject, template, key, data)
+	if err != nil {
+		return err
+	}
+	return n.queue(message)
+}
+
+// queue writes a message to the outbox and wakes the dispatcher.
+func (n *Notifier) queue(message entity.OutboxMessage) error {
+	if err := n.outbox.AddOutboxMessages(message); err != nil {
+		return err
+	}
+
+	if n.onQueued != nil {
+		n.onQueued()
+	}
+	return nil
+}
+
+// Deliver hands an email from the outbox to the mailer. The dispatcher calls
+// it for every message on the email topic.
+func (n *Notifier) Deliver(message *entity.OutboxMessage) error {
+	var email entity.Email
+	if err := json.Unmarshal(message.Payload, &email); err != nil {
+		return fmt.Errorf("invalid email in outbox message %s: %v", message.ID, err)
+	}
+	return n.mailer.Send(email)
+}
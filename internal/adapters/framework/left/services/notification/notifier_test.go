@@ -0,0 +1,231 @@
+package notification
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ecom-api/internal/adapters/framework/right/mail_repo"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/ports/right/rports"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTemplates(t *testing.T, name, html, text string) string {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, name+".html"), []byte(html), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, name+".txt"), []byte(text), 0o644))
+	return dir
+}
+
+func TestTemplates(t *testing.T) {
+	t.Run("the HTML body is escaped and the plain text one is not", func(t *testing.T) {
+		templates := NewTemplates(writeTemplates(t, "hello", "<p>Dear, {{.username}}</p>", "Dear, {{.username}}"))
+
+		html, text, err := templates.Render("hello", map[string]string{"username": "Tom & <Jerry>"})
+		assert.NoError(t, err)
+		assert.Equal(t, "<p>Dear, Tom &amp; &lt;Jerry&gt;</p>", html)
+		assert.Equal(t, "Dear, Tom & <Jerry>", text)
+	})
+
+	t.Run("templates are parsed once", func(t *testing.T) {
+		dir := writeTemplates(t, "hello", "<p>{{.username}}</p>", "{{.username}}")
+		templates := NewTemplates(dir)
+		_, _, err := templates.Render("hello", nil)
+		assert.NoError(t, err)
+
+		assert.NoError(t, os.RemoveAll(dir))
+		_, text, err := templates.Render("hello", map[string]string{"username": "Jane"})
+		assert.NoError(t, err)
+		assert.Equal(t, "Jane", text)
+	})
+
+	t.Run("an email needs both bodies", func(t *testing.T) {
+		dir := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "hello.html"), []byte("<p>hi</p>"), 0o644))
+
+		_, _, err := NewTemplates(dir).Render("hello", nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("every email of the API renders", func(t *testing.T) {
+		templates := NewTemplates("../../../../../../static")
+		names := []string{"confirmation"}
+		for name := range orderEmails {
+			names = append(names, name)
+		}
+		for _, name := range names {
+			_, _, err := templates.Render(name, map[string]string{})
+			assert.NoError(t, err, name)
+		}
+	})
+}
+
+// mockOutboxStore keeps the messages written to it in a slice.
+type mockOutboxStore struct {
+	rports.OutboxStore
+	messages []entity.OutboxMessage
+}
+
+func (m *mockOutboxStore) AddOutboxMessages(messages ...entity.OutboxMessage) error {
+	m.messages = append(m.messages, messages...)
+	return nil
+}
+
+func TestNotifier(t *testing.T) {
+	dir := writeTemplates(t, "hello", "<p>Dear, {{.username}}</p>", "Dear, {{.username}}")
+
+	t.Run("emails are written to the outbox and delivered from it", func(t *testing.T) {
+		mailer := mail_repo.NewMemoryMailer()
+		outbox := &mockOutboxStore{}
+		notifier := NewNotifier(mailer, NewTemplates(dir), outbox, nil, nil)
+		queued := 0
+		notifier.OnQueued(func() { queued++ })
+
+		assert.NoError(t, notifier.Send([]string{"jane@example.com"}, "Hello", "hello", "hello:jane", map[string]string{"username": "Jane"}))
+		assert.Empty(t, mailer.Sent())
+		assert.Equal(t, 1, queued)
+		assert.Len(t, outbox.messages, 1)
+		assert.Equal(t, entity.TopicEmail, outbox.messages[0].Topic)
+		assert.Equal(t, "hello:jane", outbox.messages[0].Key)
+
+		assert.NoError(t, notifier.Deliver(&outbox.messages[0]))
+		sent := mailer.Sent()
+		assert.Len(t, sent, 1)
+		assert.Equal(t, []string{"jane@example.com"}, sent[0].To)
+		assert.Equal(t, "Hello", sent[0].Subject)
+		assert.Equal(t, "<p>Dear, Jane</p>", sent[0].HTML)
+		assert.Equal(t, "Dear, Jane", sent[0].Text)
+	})
+
+	t.Run("an email that does not render is not written", func(t *testing.T) {
+		outbox := &mockOutboxStore{}
+		notifier := NewNotifier(mail_repo.NewMemoryMailer(), NewTemplates(dir), outbox, nil, nil)
+
+		assert.Error(t, notifier.Send([]string{"jane@example.com"}, "Hello", "missing", "", nil))
+		assert.Empty(t, outbox.messages)
+	})
+
+	t.Run("a failed delivery is reported to the dispatcher", func(t *testing.T) {
+		mailer := mail_repo.NewMemoryMailer()
+		notifier := NewNotifier(mailer, NewTemplates(dir), &mockOutboxStore{}, nil, nil)
+		message, err := notifier.Email([]string{"jane@example.com"}, "Hello", "hello", "", nil)
+		assert.NoError(t, err)
+
+		mailer.FailWith(fmt.Errorf("connection refused"))
+		assert.Error(t, notifier.Deliver(&message))
+		assert.Error(t, notifier.Deliver(&entity.OutboxMessage{Topic: entity.TopicEmail, Payload: []byte("not json")}))
+	})
+}
+
+// mockNotificationStore keeps the preferences of every address in a map.
+type mockNotificationStore struct {
+	preferences map[string]map[string]bool
+}
+
+func (m *mockNotificationStore) GetNotificationPreferences(email string) (map[string]bool, error) {
+	return m.preferences[email], nil
+}
+
+func (m *mockNotificationStore) SetNotificationPreference(email, category string, enabled bool) error {
+	if m.preferences == nil {
+		m.preferences = map[string]map[string]bool{}
+	}
+	if m.preferences[email] == nil {
+		m.preferences[email] = map[string]bool{}
+	}
+	m.preferences[email][category] = enabled
+	return nil
+}
+
+// mockUserStore knows a single user.
+type mockUserStore struct {
+	rports.UserStore
+	user entity.User
+}
+
+func (m *mockUserStore) GetUserByID(id string) (*entity.User, error) {
+	if id != m.user.ID {
+		return &entity.User{}, nil
+	}
+	return &m.user, nil
+}
+
+func TestOrderEmails(t *testing.T) {
+	users := &mockUserStore{user: entity.User{ID: "user-1", FirstName: "Jane", LastName: "Doe", Email: "jane@example.com"}}
+	newNotifier := func(preferences *mockNotificationStore) (*Notifier, *mockOutboxStore) {
+		outbox := &mockOutboxStore{}
+		return NewNotifier(mail_repo.NewMemoryMailer(), NewTemplates("../../../../../../static"), outbox, preferences, users), outbox
+	}
+	delivered := func(t *testing.T, message entity.OutboxMessage) entity.Email {
+		var email entity.Email
+		assert.NoError(t, json.Unmarshal(message.Payload, &email))
+		return email
+	}
+
+	t.Run("the email of a user order goes to the user", func(t *testing.T) {
+		notifier, outbox := newNotifier(&mockNotificationStore{})
+		order := entity.Order{ID: "order-1", UserID: "user-1", Currency: "USD", Total: entity.NewMoney(1999, "USD")}
+
+		assert.NoError(t, notifier.SendOrderEmail(OrderShipped, order, "order_shipped:shipment-1", map[string]string{"carrier": "UPS", "tracking_number": "1Z999"}))
+		assert.Len(t, outbox.messages, 1)
+		assert.Equal(t, "order_shipped:shipment-1", outbox.messages[0].Key)
+
+		email := delivered(t, outbox.messages[0])
+		assert.Equal(t, []string{"jane@example.com"}, email.To)
+		assert.Equal(t, "Your Order Is On Its Way", email.Subject)
+		assert.Contains(t, email.Text, "Dear, Jane Doe")
+		assert.Contains(t, email.Text, "Tracking number: 1Z999")
+		assert.Contains(t, email.Text, "/api/v1/notifications/unsubscribe?token=")
+	})
+
+	t.Run("the email of a guest order goes to the guest", func(t *testing.T) {
+		notifier, _ := newNotifier(&mockNotificationStore{})
+		order := entity.Order{ID: "order-2", GuestEmail: "guest@example.com", Currency: "USD", Total: entity.NewMoney(500, "USD")}
+
+		message, err := notifier.OrderEmail(OrderPlaced, order, "order_placed:order-2", map[string]string{"order_link": "http://localhost/order"})
+		assert.NoError(t, err)
+		assert.NotNil(t, message)
+
+		email := delivered(t, *message)
+		assert.Equal(t, []string{"guest@example.com"}, email.To)
+		assert.Contains(t, email.Text, "Total: 5.00 USD")
+		assert.Contains(t, email.Text, "http://localhost/order")
+		assert.Contains(t, email.Text, "Create an account with this email address")
+
+		// a guest using the email of an account is told to log in instead
+		message, err = notifier.OrderEmail(OrderPlaced, order, "order_placed:order-2", map[string]string{"order_link": "http://localhost/order", "has_account": "true"})
+		assert.NoError(t, err)
+		email = delivered(t, *message)
+		assert.Contains(t, email.Text, "You already have an account with this email address")
+		assert.NotContains(t, email.Text, "Create an account")
+		assert.Contains(t, email.HTML, "You already have an account with this email address")
+	})
+
+	t.Run("a category the buyer turned off is not emailed", func(t *testing.T) {
+		preferences := &mockNotificationStore{}
+		assert.NoError(t, preferences.SetNotificationPreference("jane@example.com", entity.NotifyShipping, false))
+		notifier, outbox := newNotifier(preferences)
+		order := entity.Order{ID: "order-1", UserID: "user-1", Currency: "USD"}
+
+		assert.NoError(t, notifier.SendOrderEmail(OrderDelivered, order, "order_delivered:order-1", nil))
+		assert.Empty(t, outbox.messages)
+
+		assert.NoError(t, notifier.SendOrderEmail(OrderRefunded, order, "order_refunded:order-1", nil))
+		assert.Len(t, outbox.messages, 1)
+	})
+
+	t.Run("an order without an email address is not emailed", func(t *testing.T) {
+		notifier, _ := newNotifier(&mockNotificationStore{})
+
+		message, err := notifier.OrderEmail(OrderCancelled, entity.Order{ID: "order-3", Currency: "USD"}, "order_cancelled:order-3", nil)
+		assert.NoError(t, err)
+		assert.Nil(t, message)
+
+		_, err = notifier.OrderEmail("order_lost", entity.Order{ID: "order-3"}, "", nil)
+		assert.Error(t, err)
+	})
+}
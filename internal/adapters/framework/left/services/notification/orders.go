@@ -0,0 +1,122 @@
+package notification
+
+import (
+	"fmt"
+
+	"ecom-api/internal/adapters/framework/left/services/auth"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/pkg/configs"
+)
+
+// The lifecycle emails of an order, named after their templates.
+const (
+	OrderPlaced     = "order_placed"
+	PaymentReceived = "purchase_success"
+	PaymentFailed   = "payment_failed"
+	OrderShipped    = "order_shipped"
+	OrderDelivered  = "order_delivered"
+	OrderCancelled  = "order_cancelled"
+	OrderRefunded   = "order_refunded"
+)
+
+// orderEmail is the subject of a lifecycle email and the category buyers turn
+// it off with.
+type orderEmail struct {
+	subject  string
+	category string
+}
+
+var orderEmails = map[string]orderEmail{
+	OrderPlaced:     {"Your Order Has Been Placed", entity.NotifyOrders},
+	PaymentReceived: {"Purchase Successfull!! 🎉", entity.NotifyPayments},
+	PaymentFailed:   {"Your Payment Did Not Go Through", entity.NotifyPayments},
+	OrderShipped:    {"Your Order Is On Its Way", entity.NotifyShipping},
+	OrderDelivered:  {"Your Order Has Been Delivered", entity.NotifyShipping},
+	OrderCancelled:  {"Your Order Has Been Cancelled", entity.NotifyOrders},
+	OrderRefunded:   {"Your Order Has Been Refunded", entity.NotifyPayments},
+}
+
+// orderBuyer returns who placed an order, the guest buyer or the user it
+// belongs to. The email is empty when neither has one.
+func (n *Notifier) orderBuyer(order entity.Order) (name, email string, err error) {
+	name, email = order.ShippingAddress.FullName, order.GuestEmail
+	if order.UserID != "" {
+		user, err := n.userStore.GetUserByID(order.UserID)
+		if err != nil {
+			return "", "", err
+		}
+		if user.ID != "" {
+			name, email = user.FirstName+" "+user.LastName, user.Email
+		}
+	}
+	return name, email, nil
+}
+
+// UnsubscribeLink is where the buyer at email turns off a category of order
+// emails without signing in.
+func UnsubscribeLink(email, category string) (string, error) {
+	token, err := auth.CreateUnsubscribeToken([]byte(configs.Envs.JWTSecret), email, category)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%s/api/v1/notifications/unsubscribe?token=%s", configs.Envs.PublicHost, configs.Envs.Port, token), nil
+}
+
+// OrderEmail renders the lifecycle email kind of an order into an outbox
+// message for its buyer, for the order store to write along with the
+// transition it tells about. The template gets the order, the buyer and an
+// unsubscribe link on top of data. It returns nil when the buyer has no email
+// or turned the category of the email off.
+func (n *Notifier) OrderEmail(kind string, order entity.Order, key string, data map[string]string) (*entity.OutboxMessage, error) {
+	lifecycle, ok := orderEmails[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown order email %s", kind)
+	}
+
+	name, email, err := n.orderBuyer(order)
+	if err != nil || email == "" {
+		return nil, err
+	}
+
+	preferences, err := n.preferences.GetNotificationPreferences(email)
+	if err != nil {
+		return nil, err
+	}
+	if enabled, ok := preferences[lifecycle.category]; ok && !enabled {
+		return nil, nil
+	}
+
+	unsubscribeLink, err := UnsubscribeLink(email, lifecycle.category)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{
+		"username":         name,
+		"email":            configs.Envs.FromEmail,
+		"order_id":         order.ID,
+		"address":          order.ShippingAddress.String(),
+		"total":            order.Total.String() + " " + order.Currency,
+		"unsubscribe_link": unsubscribeLink,
+	}
+	for k, v := range data {
+		values[k] = v
+	}
+
+	message, err := n.Email([]string{email}, lifecycle.subject, kind, key, values)
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// SendOrderEmail renders a lifecycle email like OrderEmail does and writes it
+// to the outbox on its own, for transitions whose store does not take
+// messages.
+func (n *Notifier) SendOrderEmail(kind string, order entity.Order, key string, data map[string]string) error {
+	message, err := n.OrderEmail(kind, order, key, data)
+	if err != nil || message == nil {
+		return err
+	}
+	return n.queue(*message)
+}
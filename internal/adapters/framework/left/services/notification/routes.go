@@ -0,0 +1,142 @@
+package notification
+
+import (
+	"fmt"
+	"net/http"
+
+	"ecom-api/internal/adapters/framework/left/services/auth"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/application/core/types/entity/payloads"
+	"ecom-api/internal/ports/right/rports"
+	"ecom-api/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type NotificationHandler struct {
+	preferences rports.NotificationStore
+	userStore   rports.UserStore
+}
+
+func NewNotificationHandler(preferences rports.NotificationStore, userStore rports.UserStore) *NotificationHandler {
+	return &NotificationHandler{preferences: preferences, userStore: userStore}
+}
+
+func (handler *NotificationHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/notifications/preferences", auth.WithJWTAuth(handler.handleGetPreferences, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodGet)
+	router.HandleFunc("/notifications/preferences", auth.WithJWTAuth(handler.handleUpdatePreferences, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodPost)
+
+	// the link in order emails, guests have no account to sign in with
+	router.HandleFunc("/notifications/unsubscribe", handler.handleUnsubscribe).Methods(http.MethodGet, http.MethodPost)
+}
+
+// preferencesOf returns whether every category is emailed to an address.
+func (handler *NotificationHandler) preferencesOf(email string) (map[string]bool, error) {
+	stored, err := handler.preferences.GetNotificationPreferences(email)
+	if err != nil {
+		return nil, err
+	}
+
+	preferences := make(map[string]bool, len(entity.NotificationCategories))
+	for _, category := range entity.NotificationCategories {
+		enabled, ok := stored[category]
+		preferences[category] = enabled || !ok
+	}
+	return preferences, nil
+}
+
+func (handler *NotificationHandler) handleGetPreferences(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	user, err := handler.userStore.GetUserByID(auth.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	preferences, err := handler.preferencesOf(user.Email)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{"email": user.Email, "preferences": preferences}, nil)
+}
+
+func (handler *NotificationHandler) handleUpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	var preferencesParams payloads.NotificationPreferencesPayload
+
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	if err := utils.ParseJSON(r, &preferencesParams); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(preferencesParams); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors)
+		return
+	}
+
+	for category := range preferencesParams.Preferences {
+		if !entity.IsNotificationCategory(category) {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unknown notification category %s", category))
+			return
+		}
+	}
+
+	user, err := handler.userStore.GetUserByID(auth.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	for category, enabled := range preferencesParams.Preferences {
+		if err := handler.preferences.SetNotificationPreference(user.Email, category, enabled); err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	preferences, err := handler.preferencesOf(user.Email)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{"email": user.Email, "preferences": preferences}, nil)
+}
+
+// handleUnsubscribe turns off the category of emails an unsubscribe link was
+// made for. Mail clients that unsubscribe in one click post to the same link.
+func (handler *NotificationHandler) handleUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	email, category, err := auth.ValidateUnsubscribeToken(r.URL.Query().Get("token"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid unsubscribe link"))
+		return
+	}
+	if !entity.IsNotificationCategory(category) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unknown notification category %s", category))
+		return
+	}
+
+	if err := handler.preferences.SetNotificationPreference(email, category, false); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{"email": email, "category": category, "unsubscribed": true}, nil)
+}
@@ -0,0 +1,56 @@
+package notification
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"ecom-api/internal/adapters/framework/left/services/auth"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/pkg/configs"
+)
+
+func TestNotificationHandlers(t *testing.T) {
+	unsubscribe := func(handler *NotificationHandler, token string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodGet, "/notifications/unsubscribe?token="+token, nil)
+		if err != nil {
+			t.Fatalf("error requesting %v", err)
+		}
+
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+
+		router.HandleFunc("/notifications/unsubscribe", handler.handleUnsubscribe)
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("an unsubscribe link turns its category off", func(t *testing.T) {
+		preferences := &mockNotificationStore{}
+		handler := NewNotificationHandler(preferences, nil)
+		token, err := auth.CreateUnsubscribeToken([]byte(configs.Envs.JWTSecret), "guest@example.com", entity.NotifyShipping)
+		assert.NoError(t, err)
+
+		rr := unsubscribe(handler, token)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, map[string]bool{entity.NotifyShipping: false}, preferences.preferences["guest@example.com"])
+
+		current, err := handler.preferencesOf("guest@example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]bool{entity.NotifyOrders: true, entity.NotifyPayments: true, entity.NotifyShipping: false}, current)
+	})
+
+	t.Run("a forged or foreign token is refused", func(t *testing.T) {
+		preferences := &mockNotificationStore{}
+		handler := NewNotificationHandler(preferences, nil)
+		orderToken, err := auth.CreateOrderAccessToken([]byte(configs.Envs.JWTSecret), "order-1", "guest@example.com")
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusBadRequest, unsubscribe(handler, orderToken).Code)
+		assert.Equal(t, http.StatusBadRequest, unsubscribe(handler, "not-a-token").Code)
+		assert.Empty(t, preferences.preferences)
+	})
+}
@@ -0,0 +1,70 @@
+package notification
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"path/filepath"
+	"sync"
+	texttemplate "text/template"
+)
+
+// Templates renders the emails kept in a directory. An email is a pair of
+// templates named after it, name.html for the HTML body and name.txt for the
+// plain text one. Both are parsed on first use and kept for the next.
+type Templates struct {
+	dir string
+
+	mu     sync.Mutex
+	parsed map[string]*emailTemplate
+}
+
+type emailTemplate struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+func NewTemplates(dir string) *Templates {
+	return &Templates{dir: dir, parsed: make(map[string]*emailTemplate)}
+}
+
+// Render fills both bodies of the email called name with data. The HTML
+// body escapes what it is given, the plain text one does not.
+func (t *Templates) Render(name string, data interface{}) (string, string, error) {
+	tmpl, err := t.lookup(name)
+	if err != nil {
+		return "", "", err
+	}
+
+	var html, text bytes.Buffer
+	if err := tmpl.html.Execute(&html, data); err != nil {
+		return "", "", fmt.Errorf("failed to render template %s: %v", name, err)
+	}
+	if err := tmpl.text.Execute(&text, data); err != nil {
+		return "", "", fmt.Errorf("failed to render template %s: %v", name, err)
+	}
+
+	return html.String(), text.String(), nil
+}
+
+func (t *Templates) lookup(name string) (*emailTemplate, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if tmpl, ok := t.parsed[name]; ok {
+		return tmpl, nil
+	}
+
+	html, err := htmltemplate.ParseFiles(filepath.Join(t.dir, name+".html"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %v", err)
+	}
+	text, err := texttemplate.ParseFiles(filepath.Join(t.dir, name+".txt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %v", err)
+	}
+
+	tmpl := &emailTemplate{html: html, text: text}
+	t.parsed[name] = tmpl
+	return tmpl, nil
+}
@@ -0,0 +1,118 @@
+package outbox
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"ecom-api/internal/adapters/framework/left/services/worker"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/ports/right/rports"
+	"ecom-api/pkg/configs"
+)
+
+// Subscriber delivers the messages of a topic. An error has the message
+// retried later, so a subscriber must be safe to run again for a message.
+type Subscriber func(message *entity.OutboxMessage) error
+
+// Dispatcher delivers the outbox. Emails go to the mailer and domain events to
+// the subscribers of their topic. A failed message is retried with
+// exponential backoff and dead-lettered after OUTBOX_MAX_ATTEMPTS attempts.
+type Dispatcher struct {
+	store       rports.OutboxStore
+	subscribers map[string][]Subscriber
+	poller      *worker.Poller
+
+	delivered    atomic.Int64
+	failed       atomic.Int64
+	deadLettered atomic.Int64
+}
+
+func NewDispatcher(store rports.OutboxStore) *Dispatcher {
+	return &Dispatcher{store: store, subscribers: make(map[string][]Subscriber), poller: worker.NewPoller()}
+}
+
+// Subscribe has the messages of topic delivered to subscriber. Messages of a
+// topic nobody subscribed to are marked sent as they come. Subscribe before
+// Run.
+func (d *Dispatcher) Subscribe(topic string, subscriber Subscriber) {
+	d.subscribers[topic] = append(d.subscribers[topic], subscriber)
+}
+
+// Run delivers due messages until ctx is done. It polls every
+// OUTBOX_INTERVAL_IN_SECONDS and right after Wake.
+func (d *Dispatcher) Run(ctx context.Context) {
+	d.poller.Run(ctx, time.Second*time.Duration(configs.Envs.OutboxInterval), d.processDue)
+}
+
+// Wake has the dispatcher look for due messages without waiting for its next
+// poll.
+func (d *Dispatcher) Wake() {
+	d.poller.Wake()
+}
+
+// Publish writes domain events that go with no change of state in a store
+// transaction to the outbox, and wakes the dispatcher for them.
+func (d *Dispatcher) Publish(messages ...entity.OutboxMessage) error {
+	if err := d.store.AddOutboxMessages(messages...); err != nil {
+		return err
+	}
+	d.Wake()
+	return nil
+}
+
+// Stats reports the depth of the outbox and what the dispatcher did since the
+// API started.
+func (d *Dispatcher) Stats() (*entity.OutboxStats, error) {
+	depth, oldestDueAt, err := d.store.GetOutboxDepth()
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.OutboxStats{
+		Depth:        depth,
+		OldestDueAt:  oldestDueAt,
+		Delivered:    d.delivered.Load(),
+		Failed:       d.failed.Load(),
+		DeadLettered: d.deadLettered.Load(),
+	}, nil
+}
+
+func (d *Dispatcher) processDue() {
+	if err := worker.Drain(d.store.ClaimOutboxMessages, d.deliver); err != nil {
+		log.Printf("failed to claim outbox messages: %v", err)
+	}
+}
+
+func (d *Dispatcher) deliver(message *entity.OutboxMessage) {
+	err := d.publish(message)
+	if err == nil {
+		d.delivered.Add(1)
+		if err := d.store.MarkOutboxMessageSent(message.ID); err != nil {
+			log.Printf("failed to mark outbox message %s sent: %v", message.ID, err)
+		}
+		return
+	}
+
+	d.failed.Add(1)
+	dead := message.Attempts >= int(configs.Envs.OutboxAttempts)
+	if dead {
+		d.deadLettered.Add(1)
+	}
+	log.Printf("outbox message %s (%s) failed on attempt %d: %v", message.ID, message.Topic, message.Attempts, err)
+	if err := d.store.MarkOutboxMessageFailed(message.ID, err.Error(), time.Now().Add(worker.RetryDelay(message.Attempts)), dead); err != nil {
+		log.Printf("failed to mark outbox message %s failed: %v", message.ID, err)
+	}
+}
+
+// publish hands a message to every subscriber of its topic. A subscriber that
+// failed before gets the message again along with the others.
+func (d *Dispatcher) publish(message *entity.OutboxMessage) error {
+	for _, subscriber := range d.subscribers[message.Topic] {
+		if err := subscriber(message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package outbox
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/ports/right/rports"
+	"ecom-api/pkg/configs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mockOutboxStore is an outbox without leases, every unsent message due by
+// now is claimed.
+type mockOutboxStore struct {
+	rports.OutboxStore
+	messages []*entity.OutboxMessage
+}
+
+func (m *mockOutboxStore) add(topic string) *entity.OutboxMessage {
+	message := &entity.OutboxMessage{ID: fmt.Sprintf("message-%d", len(m.messages)+1), Topic: topic, Status: entity.OutboxPending, NextAttemptAt: time.Now()}
+	m.messages = append(m.messages, message)
+	return message
+}
+
+func (m *mockOutboxStore) ClaimOutboxMessages(limit int, lease time.Duration) ([]*entity.OutboxMessage, error) {
+	var claimed []*entity.OutboxMessage
+	for _, message := range m.messages {
+		if (message.Status == entity.OutboxPending || message.Status == entity.OutboxFailed) && !message.NextAttemptAt.After(time.Now()) && len(claimed) < limit {
+			message.Status = entity.OutboxProcessing
+			message.Attempts++
+			claimed = append(claimed, message)
+		}
+	}
+	return claimed, nil
+}
+
+func (m *mockOutboxStore) MarkOutboxMessageSent(id string) error {
+	for _, message := range m.messages {
+		if message.ID == id {
+			message.Status = entity.OutboxSent
+		}
+	}
+	return nil
+}
+
+func (m *mockOutboxStore) MarkOutboxMessageFailed(id, lastError string, nextAttemptAt time.Time, dead bool) error {
+	for _, message := range m.messages {
+		if message.ID == id {
+			message.Status = entity.OutboxFailed
+			if dead {
+				message.Status = entity.OutboxDead
+			}
+			message.LastError = lastError
+			message.NextAttemptAt = nextAttemptAt
+		}
+	}
+	return nil
+}
+
+func (m *mockOutboxStore) GetOutboxDepth() (map[string]int, *time.Time, error) {
+	depth := map[string]int{}
+	for _, message := range m.messages {
+		if message.Status != entity.OutboxSent {
+			depth[message.Status]++
+		}
+	}
+	return depth, nil, nil
+}
+
+func TestDispatcher(t *testing.T) {
+	t.Run("messages go to the subscribers of their topic", func(t *testing.T) {
+		store := &mockOutboxStore{}
+		dispatcher := NewDispatcher(store)
+		var emails, events []string
+		dispatcher.Subscribe(entity.TopicEmail, func(message *entity.OutboxMessage) error {
+			emails = append(emails, message.ID)
+			return nil
+		})
+		dispatcher.Subscribe(entity.TopicOrderPaid, func(message *entity.OutboxMessage) error {
+			events = append(events, message.ID)
+			return nil
+		})
+
+		store.add(entity.TopicEmail)
+		store.add(entity.TopicOrderPaid)
+		unheard := store.add(entity.TopicOrderCancelled)
+		dispatcher.processDue()
+
+		assert.Equal(t, []string{"message-1"}, emails)
+		assert.Equal(t, []string{"message-2"}, events)
+		assert.Equal(t, entity.OutboxSent, unheard.Status, "nobody listens, there is nothing to retry")
+
+		stats, err := dispatcher.Stats()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), stats.Delivered)
+		assert.Empty(t, stats.Depth)
+	})
+
+	t.Run("failed messages back off and are dead-lettered", func(t *testing.T) {
+		store := &mockOutboxStore{}
+		dispatcher := NewDispatcher(store)
+		dispatcher.Subscribe(entity.TopicEmail, func(message *entity.OutboxMessage) error {
+			return fmt.Errorf("connection refused")
+		})
+
+		message := store.add(entity.TopicEmail)
+		dispatcher.processDue()
+		assert.Equal(t, entity.OutboxFailed, message.Status)
+		assert.Equal(t, "connection refused", message.LastError)
+		assert.WithinDuration(t, time.Now().Add(30*time.Second), message.NextAttemptAt, time.Second)
+
+		// not due yet
+		dispatcher.processDue()
+		assert.Equal(t, 1, message.Attempts)
+
+		for message.Status == entity.OutboxFailed {
+			message.NextAttemptAt = time.Now()
+			dispatcher.processDue()
+		}
+		assert.Equal(t, entity.OutboxDead, message.Status)
+		assert.Equal(t, int(configs.Envs.OutboxAttempts), message.Attempts)
+
+		stats, err := dispatcher.Stats()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(configs.Envs.OutboxAttempts), stats.Failed)
+		assert.Equal(t, int64(1), stats.DeadLettered)
+		assert.Equal(t, map[string]int{entity.OutboxDead: 1}, stats.Depth)
+	})
+}
@@ -0,0 +1,70 @@
+package outbox
+
+import (
+	"log"
+
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/ports/right/rports"
+)
+
+// The helpers below publish the domain events of changes made outside a store
+// transaction. The change is made by the time they run, so a failure is
+// logged rather than failing the request.
+
+// PublishProduct publishes the domain event topic about product as the change
+// left it.
+func (d *Dispatcher) PublishProduct(topic string, product entity.Product) {
+	event, err := entity.NewProductEvent(topic, product)
+	if err == nil {
+		err = d.Publish(event)
+	}
+	if err != nil {
+		log.Printf("failed to publish %s event of product %s: %v", topic, product.ProductId, err)
+	}
+}
+
+// PublishProducts loads the products with the IDs and publishes the domain
+// event topic about each of them.
+func (d *Dispatcher) PublishProducts(products rports.ProductStore, topic string, productIDs ...string) {
+	if len(productIDs) == 0 {
+		return
+	}
+
+	loaded, err := products.GetProductsByIDs(productIDs)
+	if err != nil {
+		log.Printf("failed to load products %v for their %s events: %v", productIDs, topic, err)
+		return
+	}
+
+	events := make([]entity.OutboxMessage, 0, len(loaded))
+	for _, product := range loaded {
+		event, err := entity.NewProductEvent(topic, product)
+		if err != nil {
+			log.Printf("failed to publish %s event of product %s: %v", topic, product.ProductId, err)
+			continue
+		}
+		events = append(events, event)
+	}
+	if len(events) == 0 {
+		return
+	}
+	if err := d.Publish(events...); err != nil {
+		log.Printf("failed to publish %s events of products %v: %v", topic, productIDs, err)
+	}
+}
+
+// PublishOrderStock publishes the product.stock_changed events of the products
+// of an order, once the order took their stock or gave it back.
+func (d *Dispatcher) PublishOrderStock(orders rports.OrderStore, products rports.ProductStore, orderID string) {
+	items, err := orders.GetOrderItemsByOrderId(orderID)
+	if err != nil {
+		log.Printf("failed to load the items of order %s for their stock events: %v", orderID, err)
+		return
+	}
+
+	productIDs := make([]string, 0, len(items))
+	for _, item := range items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	d.PublishProducts(products, entity.TopicProductStockChanged, productIDs...)
+}
@@ -0,0 +1,97 @@
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"ecom-api/internal/adapters/framework/left/services/auth"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/ports/right/rports"
+	"ecom-api/utils"
+
+	"github.com/gorilla/mux"
+)
+
+type OutboxHandler struct {
+	dispatcher *Dispatcher
+	userStore  rports.UserStore
+}
+
+func NewOutboxHandler(dispatcher *Dispatcher, userStore rports.UserStore) *OutboxHandler {
+	return &OutboxHandler{dispatcher: dispatcher, userStore: userStore}
+}
+
+func (handler *OutboxHandler) RegisterRoutes(router *mux.Router) {
+	//admin routes
+	router.HandleFunc("/outbox/stats", auth.WithJWTAuth(handler.handleGetOutboxStats, handler.userStore, "admin")).Methods(http.MethodGet)
+	router.HandleFunc("/outbox/messages/failed", auth.WithJWTAuth(handler.handleGetFailedOutboxMessages, handler.userStore, "admin")).Methods(http.MethodGet)
+	router.HandleFunc("/outbox/message/replay/{messageId}", auth.WithJWTAuth(handler.handleReplayOutboxMessage, handler.userStore, "admin")).Methods(http.MethodPost)
+}
+
+func (handler *OutboxHandler) handleGetOutboxStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	stats, err := handler.dispatcher.Stats()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, stats, nil)
+}
+
+func (handler *OutboxHandler) handleGetFailedOutboxMessages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	messages, err := handler.dispatcher.store.GetOutboxMessagesByStatus(entity.OutboxFailed, entity.OutboxDead)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// emails are left out, they hold verification codes and order links
+	response := make([]map[string]interface{}, 0, len(messages))
+	for _, message := range messages {
+		entry := map[string]interface{}{"message": message}
+		if message.Topic != entity.TopicEmail {
+			entry["payload"] = json.RawMessage(message.Payload)
+		}
+		response = append(response, entry)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response, nil)
+}
+
+func (handler *OutboxHandler) handleReplayOutboxMessage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	messageId, ok := mux.Vars(r)["messageId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing message ID"))
+		return
+	}
+
+	replayed, err := handler.dispatcher.store.ReplayOutboxMessage(messageId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !replayed {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("outbox message %s is not a failed message", messageId))
+		return
+	}
+
+	handler.dispatcher.Wake()
+
+	utils.WriteJSON(w, http.StatusAccepted, map[string]bool{"replayed": true}, nil)
+}
@@ -0,0 +1,41 @@
+package payment
+
+import (
+	"github.com/stripe/stripe-go"
+)
+
+// purchaseBuyer is who a purchase email goes to.
+type purchaseBuyer struct {
+	name  string
+	email string
+}
+
+// resolveBuyer works out who paid for a charge made outside an order.
+// Webhooks come from Stripe and carry no user session, so the buyer is found
+// through the user linked to the Stripe customer, then through the email the
+// charge was paid with. Returns nil when the charge cannot be traced to
+// anyone.
+func (handler *PaymentHandler) resolveBuyer(charge stripe.Charge) (*purchaseBuyer, error) {
+	if charge.Customer != nil && charge.Customer.ID != "" {
+		user, err := handler.userStore.GetUserByStripeCustomerID(charge.Customer.ID)
+		if err != nil {
+			return nil, err
+		}
+		if user != nil {
+			return &purchaseBuyer{name: user.FirstName + " " + user.LastName, email: user.Email}, nil
+		}
+	}
+
+	buyer := &purchaseBuyer{email: charge.ReceiptEmail}
+	if charge.BillingDetails != nil {
+		buyer.name = charge.BillingDetails.Name
+		if buyer.email == "" {
+			buyer.email = charge.BillingDetails.Email
+		}
+	}
+	if buyer.email != "" {
+		return buyer, nil
+	}
+
+	return nil, nil
+}
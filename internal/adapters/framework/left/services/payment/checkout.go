@@ -0,0 +1,162 @@
+package payment
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"ecom-api/internal/adapters/framework/left/services/notification"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/pkg/configs"
+
+	"github.com/stripe/stripe-go"
+)
+
+// checkoutSessionOrderID returns the order a checkout session was opened for,
+// empty for sessions this API did not create.
+func checkoutSessionOrderID(session stripe.CheckoutSession) string {
+	if orderID := session.Metadata["order_id"]; orderID != "" {
+		return orderID
+	}
+	return session.ClientReferenceID
+}
+
+// checkoutSessionUnpaid reports whether a completed session is still waiting
+// for a delayed payment method to settle, in which case the outcome arrives
+// later as an async payment event.
+func checkoutSessionUnpaid(raw json.RawMessage) bool {
+	var status struct {
+		PaymentStatus string `json:"payment_status"`
+	}
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return false
+	}
+	return status.PaymentStatus == "unpaid"
+}
+
+// markOrderPaid records the payment of an order, starts processing it and
+// credits its buyer with the points it earns. The payment received email and the
+// order.paid event are written with the payment, so they go out once. An
+// order already marked paid is left alone, so a replayed event cannot move a
+// shipped order back. Only a pending order is marked paid, a payment settling
+// after its order was cancelled, its stock and coupons given back already, is
+// refunded through paymentIntentID instead.
+func (handler *PaymentHandler) markOrderPaid(orderID, paymentIntentID string) error {
+	order, err := handler.orderStore.GetOrderByID(orderID)
+	if err != nil {
+		return err
+	}
+	if order.ID == "" {
+		return fmt.Errorf("order %s not found", orderID)
+	}
+
+	if order.PaymentStatus != configs.Envs.PaymentStatusPaid {
+		marked := false
+		if order.Status == configs.Envs.OrderStatusPending {
+			order.PaymentStatus = configs.Envs.PaymentStatusPaid
+			order.Status = configs.Envs.OrderStatusProcessing
+			event, err := entity.NewOrderEvent(entity.TopicOrderPaid, *order)
+			if err != nil {
+				return err
+			}
+			messages := []entity.OutboxMessage{event}
+
+			email, err := handler.notifier.OrderEmail(notification.PaymentReceived, *order, "purchase_success:"+orderID, nil)
+			if err != nil {
+				return err
+			}
+			if email != nil {
+				messages = append(messages, *email)
+			}
+
+			if marked, err = handler.orderStore.MarkOrderPaid(orderID, messages...); err != nil {
+				return err
+			}
+		}
+
+		// the order was cancelled before or while the payment settled, unless
+		// another delivery of the payment marked it paid meanwhile
+		if !marked {
+			if order, err = handler.orderStore.GetOrderByID(orderID); err != nil {
+				return err
+			}
+			if order.PaymentStatus != configs.Envs.PaymentStatusPaid {
+				return handler.refundCancelledOrder(orderID, paymentIntentID)
+			}
+		}
+	}
+
+	// the points are credited once, an earlier attempt may have failed on them
+	return handler.loyalty.OrderPaid(orderID)
+}
+
+// refundCancelledOrder gives back the whole of a payment that settled after
+// its order was cancelled. The refund is keyed by the order, so a redelivered
+// payment is refunded once.
+func (handler *PaymentHandler) refundCancelledOrder(orderID, paymentIntentID string) error {
+	if paymentIntentID == "" {
+		return fmt.Errorf("order %s was paid after it was cancelled but the payment is unknown, refund it by hand", orderID)
+	}
+	log.Printf("Order %s was paid after it was cancelled, refunding payment %s", orderID, paymentIntentID)
+
+	if _, err := handler.paymentStore.CreateRefund(paymentIntentID, 0, "cancelled_order_refund:"+orderID); err != nil {
+		return fmt.Errorf("failed to refund the payment of cancelled order %s: %v", orderID, err)
+	}
+	return nil
+}
+
+// notifyPaymentFailed tells the buyer of a pending order that a payment attempt
+// failed. The email shares its key with the one cancelUnpaidOrder sends, so a
+// buyer hears about the failed payment of an order once.
+func (handler *PaymentHandler) notifyPaymentFailed(orderID string) error {
+	order, err := handler.orderStore.GetOrderByID(orderID)
+	if err != nil {
+		return err
+	}
+	if order.ID == "" || order.Status != configs.Envs.OrderStatusPending {
+		return nil
+	}
+	return handler.notifier.SendOrderEmail(notification.PaymentFailed, *order, notification.PaymentFailed+":"+orderID, nil)
+}
+
+// cancelUnpaidOrder cancels the order of a session that will not be paid, which
+// releases its stock, coupons, the store credit tendered for it and the points
+// redeemed on it, and writes the order.cancelled event along with the email
+// the buyer is told with, OrderCancelled or PaymentFailed. Stripe may deliver
+// the event more than once, an order that is no longer pending is left as it
+// is.
+func (handler *PaymentHandler) cancelUnpaidOrder(orderID, email string) error {
+	order, err := handler.orderStore.GetOrderByID(orderID)
+	if err != nil {
+		return err
+	}
+	order.Status = configs.Envs.OrderStatusCancelled
+	event, err := entity.NewOrderEvent(entity.TopicOrderCancelled, *order)
+	if err != nil {
+		return err
+	}
+	messages := []entity.OutboxMessage{event}
+
+	message, err := handler.notifier.OrderEmail(email, *order, email+":"+orderID, nil)
+	if err != nil {
+		return err
+	}
+	if message != nil {
+		messages = append(messages, *message)
+	}
+
+	cancelled, err := handler.orderStore.CancelOrder(orderID, messages...)
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return nil
+	}
+	log.Printf("Order %s cancelled, its checkout session will not be paid", orderID)
+	handler.events.PublishOrderStock(handler.orderStore, handler.productStore, orderID)
+
+	if _, err := handler.creditStore.ReleaseOrderTenders(orderID, entity.TenderReleased); err != nil {
+		return err
+	}
+	return handler.loyalty.OrderCancelled(orderID)
+}
@@ -0,0 +1,284 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"ecom-api/internal/adapters/framework/left/services/notification"
+	"ecom-api/internal/adapters/framework/left/services/worker"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/pkg/configs"
+
+	"github.com/stripe/stripe-go"
+)
+
+// RunEventWorker processes stored webhook events until ctx is done. It polls
+// every PAYMENT_EVENT_INTERVAL_IN_SECONDS and right after the webhook stores a
+// new event. A failed event is retried with exponential backoff and marked
+// dead after PAYMENT_EVENT_MAX_ATTEMPTS attempts.
+func (handler *PaymentHandler) RunEventWorker(ctx context.Context) {
+	handler.eventWorker.Run(ctx, time.Second*time.Duration(configs.Envs.PaymentEventInterval), handler.processDueEvents)
+}
+
+// QueuePaymentEvent stores a gateway event for the worker and wakes it. A
+// redelivered event is stored once, so it is accepted without being queued
+// again. Gateways that do not call the webhook, like the fake one, hand their
+// events in here.
+func (handler *PaymentHandler) QueuePaymentEvent(event entity.PaymentEvent) error {
+	created, err := handler.paymentEventStore.SavePaymentEvent(event)
+	if err != nil {
+		return err
+	}
+
+	if created {
+		handler.eventWorker.Wake()
+	} else {
+		log.Printf("Payment event %s was already received", event.ID)
+	}
+	return nil
+}
+
+func (handler *PaymentHandler) processDueEvents() {
+	if err := worker.Drain(handler.paymentEventStore.ClaimPaymentEvents, handler.processStoredEvent); err != nil {
+		log.Printf("failed to claim payment events: %v", err)
+	}
+}
+
+func (handler *PaymentHandler) processStoredEvent(stored *entity.PaymentEvent) {
+	var event stripe.Event
+	err := json.Unmarshal(stored.Payload, &event)
+	if err == nil {
+		err = handler.processPaymentEvent(event)
+	}
+
+	if err == nil {
+		if err := handler.paymentEventStore.MarkPaymentEventProcessed(stored.ID); err != nil {
+			log.Printf("failed to mark payment event %s processed: %v", stored.ID, err)
+		}
+		return
+	}
+
+	dead := stored.Attempts >= int(configs.Envs.PaymentEventAttempts)
+	log.Printf("payment event %s (%s) failed on attempt %d: %v", stored.ID, stored.Type, stored.Attempts, err)
+	if err := handler.paymentEventStore.MarkPaymentEventFailed(stored.ID, err.Error(), time.Now().Add(worker.RetryDelay(stored.Attempts)), dead); err != nil {
+		log.Printf("failed to mark payment event %s failed: %v", stored.ID, err)
+	}
+}
+
+// processPaymentEvent acts on a verified webhook event. Stripe delivers events
+// at least once, so every branch must be safe to run again for the same event.
+func (handler *PaymentHandler) processPaymentEvent(event stripe.Event) error {
+	switch event.Type {
+	case "customer.created":
+		var customer stripe.Customer
+		if err := json.Unmarshal(event.Data.Raw, &customer); err != nil {
+			return fmt.Errorf("webhook error: %v", err)
+		}
+		log.Printf("New customer created: %s", customer.ID)
+
+	case "payment_method.attached":
+		var paymentMethod stripe.PaymentMethod
+		if err := json.Unmarshal(event.Data.Raw, &paymentMethod); err != nil {
+			return fmt.Errorf("webhook error: %v", err)
+		}
+		log.Printf("Payment method attached: %s", paymentMethod.ID)
+
+	case "payment_intent.created":
+		var paymentIntent stripe.PaymentIntent
+		if err := json.Unmarshal(event.Data.Raw, &paymentIntent); err != nil {
+			return fmt.Errorf("webhook error: %v", err)
+		}
+		log.Printf("PaymentIntent created: %s", paymentIntent.ID)
+
+	case "payment_intent.succeeded":
+		var paymentIntent stripe.PaymentIntent
+		if err := json.Unmarshal(event.Data.Raw, &paymentIntent); err != nil {
+			return fmt.Errorf("webhook error: %v", err)
+		}
+		log.Printf("PaymentIntent succeeded: %s", paymentIntent.ID)
+
+	case "charge.succeeded":
+		var charge stripe.Charge
+		if err := json.Unmarshal(event.Data.Raw, &charge); err != nil {
+			return fmt.Errorf("webhook error: %v", err)
+		}
+		log.Printf("Charge succeeded for charge ID: %s, amount: %d", charge.ID, charge.Amount)
+
+		if orderID := charge.Metadata["order_id"]; orderID != "" {
+			if err := handler.recordStorePayouts(orderID, charge); err != nil {
+				return fmt.Errorf("failed to record store payouts: %v", err)
+			}
+			// orders get their purchase email when they are marked paid
+			break
+		}
+
+		buyer, err := handler.resolveBuyer(charge)
+		if err != nil {
+			return err
+		}
+		if buyer == nil {
+			log.Printf("No buyer found for charge %s, skipping purchase email", charge.ID)
+			break
+		}
+
+		if err := handler.notifier.Send([]string{buyer.email}, "Purchase Successfull!! 🎉", "purchase_success", "purchase_success:"+charge.ID, map[string]string{
+			"username": buyer.name,
+			"email":    configs.Envs.FromEmail,
+		}); err != nil {
+			return fmt.Errorf("failed to queue purchase email: %v", err)
+		}
+
+	case "charge.refunded":
+		var charge stripe.Charge
+		if err := json.Unmarshal(event.Data.Raw, &charge); err != nil {
+			return fmt.Errorf("webhook error: %v", err)
+		}
+		log.Printf("Charge refunded: %s, amount refunded: %d", charge.ID, charge.AmountRefunded)
+
+		orderID := charge.Metadata["order_id"]
+		if orderID == "" {
+			break
+		}
+		// partial refunds count against what can still be refunded to the wallet
+		refunded := entity.NewMoney(charge.AmountRefunded, strings.ToUpper(string(charge.Currency)))
+		if err := handler.orderStore.RecordCardRefund(orderID, refunded); err != nil {
+			return fmt.Errorf("failed to record card refund: %v", err)
+		}
+		// stores keep their payouts through partial refunds, which are settled
+		// with them by hand
+		if !charge.Refunded {
+			break
+		}
+		if err := handler.markOrderRefunded(orderID); err != nil {
+			return fmt.Errorf("failed to refund order: %v", err)
+		}
+		// the gift cards and wallet that paid the rest get their credit back
+		if _, err := handler.creditStore.ReleaseOrderTenders(orderID, entity.TenderRefunded); err != nil {
+			return fmt.Errorf("failed to refund store credit: %v", err)
+		}
+
+	case "invoice.paid":
+		var invoice stripe.Invoice
+		if err := json.Unmarshal(event.Data.Raw, &invoice); err != nil {
+			return fmt.Errorf("webhook error: %v", err)
+		}
+		log.Printf("Invoice paid: %s, amount paid: %d", invoice.ID, invoice.AmountPaid)
+
+		if err := handler.createRenewalOrder(invoice); err != nil {
+			return fmt.Errorf("failed to create renewal order: %v", err)
+		}
+
+	case "customer.subscription.updated", "customer.subscription.deleted":
+		var subscription stripe.Subscription
+		if err := json.Unmarshal(event.Data.Raw, &subscription); err != nil {
+			return fmt.Errorf("webhook error: %v", err)
+		}
+		log.Printf("Subscription %s: %s", event.Type, subscription.ID)
+
+		if err := handler.syncSubscription(subscription.ID); err != nil {
+			return fmt.Errorf("failed to sync subscription: %v", err)
+		}
+
+	case "payment_intent.payment_failed":
+		var paymentIntent stripe.PaymentIntent
+		if err := json.Unmarshal(event.Data.Raw, &paymentIntent); err != nil {
+			return fmt.Errorf("webhook error: %v", err)
+		}
+		log.Printf("PaymentIntent failed: %s, error: %v", paymentIntent.ID, paymentIntent.LastPaymentError)
+
+		// a declined card leaves the checkout session open to try again, the
+		// buyer is told while the order waits
+		if orderID := paymentIntent.Metadata["order_id"]; orderID != "" {
+			if err := handler.notifyPaymentFailed(orderID); err != nil {
+				return fmt.Errorf("failed to notify the failed payment: %v", err)
+			}
+		}
+
+	case "setup_intent.succeeded":
+		var setupIntent stripe.SetupIntent
+		if err := json.Unmarshal(event.Data.Raw, &setupIntent); err != nil {
+			return fmt.Errorf("webhook error: %v", err)
+		}
+		log.Printf("SetupIntent succeeded: %s", setupIntent.ID)
+
+		// the first card a customer saves becomes the one charged by default
+		if setupIntent.Customer == nil || setupIntent.PaymentMethod == nil {
+			break
+		}
+		defaultPaymentMethod, err := handler.chargePaymentMethod(setupIntent.Customer.ID, "")
+		if err != nil {
+			return err
+		}
+		if defaultPaymentMethod == "" {
+			if err := handler.paymentStore.SetDefaultPaymentMethod(setupIntent.Customer.ID, setupIntent.PaymentMethod.ID); err != nil {
+				return err
+			}
+		}
+
+	case "payment_method.detached":
+		var paymentMethod stripe.PaymentMethod
+		if err := json.Unmarshal(event.Data.Raw, &paymentMethod); err != nil {
+			return fmt.Errorf("webhook error: %v", err)
+		}
+		log.Printf("Payment method detached: %s", paymentMethod.ID)
+
+	case "checkout.session.completed", "checkout.session.async_payment_succeeded":
+		var session stripe.CheckoutSession
+		if err := json.Unmarshal(event.Data.Raw, &session); err != nil {
+			return fmt.Errorf("webhook error: %v", err)
+		}
+		log.Printf("Checkout session %s: %s", event.Type, session.ID)
+
+		orderID := checkoutSessionOrderID(session)
+		if orderID == "" {
+			log.Printf("Checkout session %s has no order, ignoring", session.ID)
+			break
+		}
+
+		if event.Type == "checkout.session.completed" && checkoutSessionUnpaid(event.Data.Raw) {
+			log.Printf("Checkout session %s completed, waiting for the payment of order %s", session.ID, orderID)
+			break
+		}
+
+		paymentIntentID := ""
+		if session.PaymentIntent != nil {
+			paymentIntentID = session.PaymentIntent.ID
+		}
+		if err := handler.markOrderPaid(orderID, paymentIntentID); err != nil {
+			return fmt.Errorf("failed to update order status: %v", err)
+		}
+
+	case "checkout.session.expired", "checkout.session.async_payment_failed":
+		var session stripe.CheckoutSession
+		if err := json.Unmarshal(event.Data.Raw, &session); err != nil {
+			return fmt.Errorf("webhook error: %v", err)
+		}
+		log.Printf("Checkout session %s: %s", event.Type, session.ID)
+
+		orderID := checkoutSessionOrderID(session)
+		if orderID == "" {
+			log.Printf("Checkout session %s has no order, ignoring", session.ID)
+			break
+		}
+
+		// an expired session was left, a failed one was tried
+		email := notification.OrderCancelled
+		if event.Type != "checkout.session.expired" {
+			email = notification.PaymentFailed
+		}
+		if err := handler.cancelUnpaidOrder(orderID, email); err != nil {
+			return fmt.Errorf("failed to cancel order: %v", err)
+		}
+
+	default:
+		// Stripe retries anything but a 2xx, so events we do not act on are
+		// acknowledged too
+		log.Printf("Ignoring webhook event %s of type %s", event.ID, event.Type)
+	}
+
+	return nil
+}
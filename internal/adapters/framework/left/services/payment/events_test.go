@@ -0,0 +1,1100 @@
+package payment
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"ecom-api/internal/adapters/framework/left/services/loyalty"
+	"ecom-api/internal/adapters/framework/left/services/notification"
+	"ecom-api/internal/adapters/framework/left/services/outbox"
+	"ecom-api/internal/adapters/framework/right/fakepayment_repo"
+	"ecom-api/internal/adapters/framework/right/mail_repo"
+	"ecom-api/internal/application/core/payout"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/application/core/types/entity/payloads"
+	"ecom-api/internal/ports/right/rports"
+	"ecom-api/pkg/configs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stripe/stripe-go"
+)
+
+// mockOrderStore keeps orders in a map. Methods the payment events do not use
+// are left to the embedded interface and panic when called.
+type mockOrderStore struct {
+	rports.OrderStore
+	orders      map[string]*entity.Order
+	items       map[string][]*entity.OrderItem
+	storeOrders map[string][]*entity.StoreOrder
+	outbox      []entity.OutboxMessage
+}
+
+func (m *mockOrderStore) GetOrderByID(orderID string) (*entity.Order, error) {
+	if order, ok := m.orders[orderID]; ok {
+		found := *order
+		return &found, nil
+	}
+	return &entity.Order{}, nil
+}
+
+func (m *mockOrderStore) GetOrderItemsByOrderId(orderID string) ([]*entity.OrderItem, error) {
+	return m.items[orderID], nil
+}
+
+func (m *mockOrderStore) UpdateOrderPaymentStatus(orderId, status string) error {
+	m.orders[orderId].PaymentStatus = status
+	return nil
+}
+
+func (m *mockOrderStore) UpdateOrderStatus(orderId, status string) error {
+	m.orders[orderId].Status = status
+	return nil
+}
+
+func (m *mockOrderStore) UpdateStoreOrdersStatus(orderID, fromStatus, toStatus string) error {
+	for _, storeOrder := range m.storeOrders[orderID] {
+		if storeOrder.Status == fromStatus {
+			storeOrder.Status = toStatus
+		}
+	}
+	return nil
+}
+
+func (m *mockOrderStore) CreateOrder(order entity.Order) (string, error) {
+	order.ID = fmt.Sprintf("order-%d", len(m.orders)+1)
+	m.orders[order.ID] = &order
+	return order.ID, nil
+}
+
+func (m *mockOrderStore) GetOrderByInvoiceID(invoiceID string) (*entity.Order, error) {
+	for _, order := range m.orders {
+		if order.InvoiceID == invoiceID {
+			found := *order
+			return &found, nil
+		}
+	}
+	return &entity.Order{}, nil
+}
+
+func (m *mockOrderStore) CreateOrderItem(orderItem entity.OrderItem) error {
+	m.items[orderItem.OrderID] = append(m.items[orderItem.OrderID], &orderItem)
+	return nil
+}
+
+func (m *mockOrderStore) CreateStoreOrder(storeOrder entity.StoreOrder) (string, error) {
+	m.storeOrders[storeOrder.OrderID] = append(m.storeOrders[storeOrder.OrderID], &storeOrder)
+	return storeOrder.OrderID + "-" + storeOrder.StoreID, nil
+}
+
+func (m *mockOrderStore) MarkOrderPaid(orderID string, messages ...entity.OutboxMessage) (bool, error) {
+	order := m.orders[orderID]
+	if order.PaymentStatus == configs.Envs.PaymentStatusPaid || order.Status != configs.Envs.OrderStatusPending {
+		return false, nil
+	}
+	order.PaymentStatus = configs.Envs.PaymentStatusPaid
+	order.Status = configs.Envs.OrderStatusProcessing
+	m.UpdateStoreOrdersStatus(orderID, configs.Envs.OrderStatusPending, configs.Envs.OrderStatusProcessing)
+	m.outbox = append(m.outbox, messages...)
+	return true, nil
+}
+
+func (m *mockOrderStore) CancelOrder(orderID string, messages ...entity.OutboxMessage) (bool, error) {
+	order := m.orders[orderID]
+	if order.Status != configs.Envs.OrderStatusPending {
+		return false, nil
+	}
+	order.Status = configs.Envs.OrderStatusCancelled
+	m.outbox = append(m.outbox, messages...)
+	return true, nil
+}
+
+func (m *mockOrderStore) RecordCardRefund(orderID string, refunded entity.Money) error {
+	if order, ok := m.orders[orderID]; ok && refunded.Amount > order.CardRefunded.Amount {
+		order.CardRefunded = refunded
+	}
+	return nil
+}
+
+func (m *mockOrderStore) RefundOrder(orderID string, messages ...entity.OutboxMessage) (bool, error) {
+	order := m.orders[orderID]
+	if order.PaymentStatus == configs.Envs.PaymentStatusRefunded {
+		return false, nil
+	}
+	order.PaymentStatus = configs.Envs.PaymentStatusRefunded
+	order.Status = configs.Envs.OrderStatusRefunded
+	for _, status := range []string{configs.Envs.OrderStatusProcessing, configs.Envs.OrderStatusShipped, configs.Envs.OrderStatusCompleted} {
+		m.UpdateStoreOrdersStatus(orderID, status, configs.Envs.OrderStatusRefunded)
+	}
+	m.outbox = append(m.outbox, messages...)
+	return true, nil
+}
+
+// topics lists the topics of the messages written with order changes.
+func (m *mockOrderStore) topics() []string {
+	var topics []string
+	for _, message := range m.outbox {
+		topics = append(topics, message.Topic)
+	}
+	return topics
+}
+
+// mockOutboxStore keeps the messages written outside an order change.
+type mockOutboxStore struct {
+	rports.OutboxStore
+	messages []entity.OutboxMessage
+}
+
+func (m *mockOutboxStore) AddOutboxMessages(messages ...entity.OutboxMessage) error {
+	m.messages = append(m.messages, messages...)
+	return nil
+}
+
+// mockNotificationStore keeps the email categories turned off per address.
+type mockNotificationStore struct {
+	off map[string]string
+}
+
+func (m *mockNotificationStore) GetNotificationPreferences(email string) (map[string]bool, error) {
+	if category, ok := m.off[email]; ok {
+		return map[string]bool{category: false}, nil
+	}
+	return nil, nil
+}
+
+func (m *mockNotificationStore) SetNotificationPreference(email, category string, enabled bool) error {
+	return nil
+}
+
+// mockPaymentEventStore is an inbox without leases, every pending event is due.
+type mockPaymentEventStore struct {
+	rports.PaymentEventStore
+	events []*entity.PaymentEvent
+}
+
+func (m *mockPaymentEventStore) SavePaymentEvent(event entity.PaymentEvent) (bool, error) {
+	for _, stored := range m.events {
+		if stored.ID == event.ID {
+			return false, nil
+		}
+	}
+	event.Status = entity.PaymentEventPending
+	m.events = append(m.events, &event)
+	return true, nil
+}
+
+func (m *mockPaymentEventStore) ClaimPaymentEvents(limit int, lease time.Duration) ([]*entity.PaymentEvent, error) {
+	var claimed []*entity.PaymentEvent
+	for _, event := range m.events {
+		if event.Status == entity.PaymentEventPending && len(claimed) < limit {
+			event.Status = entity.PaymentEventProcessing
+			event.Attempts++
+			claimed = append(claimed, event)
+		}
+	}
+	return claimed, nil
+}
+
+func (m *mockPaymentEventStore) MarkPaymentEventProcessed(id string) error {
+	return m.mark(id, entity.PaymentEventProcessed, "")
+}
+
+func (m *mockPaymentEventStore) MarkPaymentEventFailed(id, lastError string, nextAttemptAt time.Time, dead bool) error {
+	return m.mark(id, entity.PaymentEventFailed, lastError)
+}
+
+func (m *mockPaymentEventStore) mark(id, status, lastError string) error {
+	for _, event := range m.events {
+		if event.ID == id {
+			event.Status = status
+			event.LastError = lastError
+		}
+	}
+	return nil
+}
+
+// mockPayoutStore keeps the payout ledger in memory, one payout per store and
+// order like the table.
+type mockPayoutStore struct {
+	rports.PayoutStore
+	rules   []*entity.CommissionRule
+	payouts []*entity.StorePayout
+}
+
+func (m *mockPayoutStore) GetCommissionRules() ([]*entity.CommissionRule, error) {
+	return m.rules, nil
+}
+
+func (m *mockPayoutStore) CreateStorePayout(payout entity.StorePayout) (bool, error) {
+	for _, stored := range m.payouts {
+		if stored.StoreID == payout.StoreID && stored.OrderID == payout.OrderID {
+			return false, nil
+		}
+	}
+	payout.ID = fmt.Sprintf("payout-%d", len(m.payouts)+1)
+	m.payouts = append(m.payouts, &payout)
+	return true, nil
+}
+
+func (m *mockPayoutStore) GetStorePayoutsByOrderID(orderID string) ([]*entity.StorePayout, error) {
+	return m.find(func(payout *entity.StorePayout) bool { return payout.OrderID == orderID }), nil
+}
+
+func (m *mockPayoutStore) GetPendingStorePayouts(storeID string) ([]*entity.StorePayout, error) {
+	return m.find(func(payout *entity.StorePayout) bool {
+		return payout.StoreID == storeID && payout.Status == entity.StorePayoutPending
+	}), nil
+}
+
+func (m *mockPayoutStore) MarkStorePayoutPaid(payoutID, transferID string) (bool, error) {
+	for _, payout := range m.payouts {
+		if payout.ID == payoutID && payout.Status == entity.StorePayoutPending {
+			payout.Status = entity.StorePayoutPaid
+			payout.TransferID = transferID
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockPayoutStore) MarkStorePayoutReversed(payoutID string) (bool, error) {
+	for _, payout := range m.payouts {
+		if payout.ID == payoutID && payout.Status != entity.StorePayoutReversed {
+			payout.Status = entity.StorePayoutReversed
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockPayoutStore) find(match func(*entity.StorePayout) bool) []*entity.StorePayout {
+	var found []*entity.StorePayout
+	for _, payout := range m.payouts {
+		if match(payout) {
+			copied := *payout
+			found = append(found, &copied)
+		}
+	}
+	return found
+}
+
+type mockStoreOwnerStore struct {
+	rports.StoreOwnerStore
+	stores map[string]*entity.StoreOwner
+}
+
+func (m *mockStoreOwnerStore) GetStoreOwnerByID(storeID string) (*entity.StoreOwner, error) {
+	if store, ok := m.stores[storeID]; ok {
+		found := *store
+		return &found, nil
+	}
+	return &entity.StoreOwner{}, nil
+}
+
+// mockUserStore knows no users, purchase emails are skipped.
+type mockUserStore struct {
+	rports.UserStore
+}
+
+func (m *mockUserStore) GetUserByID(id string) (*entity.User, error) {
+	return &entity.User{}, nil
+}
+
+func (m *mockUserStore) GetUserByStripeCustomerID(customerID string) (*entity.User, error) {
+	return nil, nil
+}
+
+type mockSubscriptionStore struct {
+	rports.SubscriptionStore
+	subscriptions map[string]*entity.Subscription
+}
+
+func (m *mockSubscriptionStore) CreateSubscription(subscription entity.Subscription) (string, error) {
+	subscription.ID = fmt.Sprintf("subscription-%d", len(m.subscriptions)+1)
+	m.subscriptions[subscription.ID] = &subscription
+	return subscription.ID, nil
+}
+
+func (m *mockSubscriptionStore) GetSubscriptionByPaymentID(paymentSubscriptionID string) (*entity.Subscription, error) {
+	for _, subscription := range m.subscriptions {
+		if subscription.PaymentSubscriptionID == paymentSubscriptionID {
+			found := *subscription
+			return &found, nil
+		}
+	}
+	return &entity.Subscription{}, nil
+}
+
+func (m *mockSubscriptionStore) UpdateSubscription(subscription entity.Subscription) error {
+	m.subscriptions[subscription.ID] = &subscription
+	return nil
+}
+
+// mockProductStore only counts the stock taken.
+type mockProductStore struct {
+	rports.ProductStore
+	taken map[string]int
+}
+
+func (m *mockProductStore) DecreaseProductStock(id string, quantity int) error {
+	m.taken[id] += quantity
+	return nil
+}
+
+func (m *mockProductStore) GetProductsByIDs(ids []string) ([]entity.Product, error) {
+	products := make([]entity.Product, 0, len(ids))
+	for _, id := range ids {
+		products = append(products, entity.Product{ProductId: id, Quantity: 10 - m.taken[id]})
+	}
+	return products, nil
+}
+
+// mockCreditStore keeps the store credit tendered per order.
+type mockCreditStore struct {
+	rports.CreditStore
+	tenders map[string][]*entity.OrderTender
+}
+
+func (m *mockCreditStore) ReleaseOrderTenders(orderID, status string) (entity.Money, error) {
+	released := entity.Money{}
+	for _, tender := range m.tenders[orderID] {
+		if tender.Status != entity.TenderApplied {
+			continue
+		}
+		tender.Status = status
+		released, _ = released.Add(tender.Amount)
+	}
+	return released, nil
+}
+
+// mockLoyaltyStore keeps a points ledger, one entry per order and reason.
+type mockLoyaltyStore struct {
+	rports.LoyaltyStore
+	rules   []*entity.LoyaltyEarnRule
+	entries []*entity.LoyaltyEntry
+}
+
+func (m *mockLoyaltyStore) GetLoyaltyEarnRules() ([]*entity.LoyaltyEarnRule, error) {
+	return m.rules, nil
+}
+
+func (m *mockLoyaltyStore) GetLoyaltyTiers() ([]*entity.LoyaltyTier, error) {
+	return nil, nil
+}
+
+func (m *mockLoyaltyStore) GetLoyaltySpend(userID string, since time.Time) ([]entity.Money, error) {
+	return nil, nil
+}
+
+func (m *mockLoyaltyStore) GetOrderLoyaltyEntries(orderID string) ([]*entity.LoyaltyEntry, error) {
+	var entries []*entity.LoyaltyEntry
+	for _, entry := range m.entries {
+		if entry.OrderID == orderID {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (m *mockLoyaltyStore) AddLoyaltyEntry(entry entity.LoyaltyEntry) (bool, error) {
+	for _, existing := range m.entries {
+		if entry.OrderID != "" && existing.OrderID == entry.OrderID && existing.Reason == entry.Reason {
+			return false, nil
+		}
+	}
+	m.entries = append(m.entries, &entry)
+	return true, nil
+}
+
+func (m *mockLoyaltyStore) balance(userID string) int {
+	points := 0
+	for _, entry := range m.entries {
+		if entry.UserID == userID {
+			points += entry.Points
+		}
+	}
+	return points
+}
+
+func newFakeGatewayHandler() (*PaymentHandler, *fakepayment_repo.Gateway, *mockOrderStore, *mockPaymentEventStore) {
+	gateway := fakepayment_repo.NewGateway()
+	orderStore := &mockOrderStore{orders: map[string]*entity.Order{}, items: map[string][]*entity.OrderItem{}, storeOrders: map[string][]*entity.StoreOrder{}}
+	eventStore := &mockPaymentEventStore{}
+	payoutStore := &mockPayoutStore{}
+	storeOwnerStore := &mockStoreOwnerStore{stores: map[string]*entity.StoreOwner{}}
+	subscriptionStore := &mockSubscriptionStore{subscriptions: map[string]*entity.Subscription{}}
+	productStore := &mockProductStore{taken: map[string]int{}}
+	creditStore := &mockCreditStore{tenders: map[string][]*entity.OrderTender{}}
+	loyaltyProgram := loyalty.NewProgram(&mockLoyaltyStore{}, orderStore)
+	notifier := notification.NewNotifier(mail_repo.NewMemoryMailer(), notification.NewTemplates("../../../../../../static"), &mockOutboxStore{}, &mockNotificationStore{}, &mockUserStore{})
+	handler := NewPaymentHandler(gateway, &mockUserStore{}, orderStore, nil, eventStore, payoutStore, storeOwnerStore, subscriptionStore, productStore, nil, creditStore, loyaltyProgram, notifier, outbox.NewDispatcher(&mockOutboxStore{}))
+	gateway.OnEvent(handler.QueuePaymentEvent)
+	return handler, gateway, orderStore, eventStore
+}
+
+func pendingOrder(id string) *entity.Order {
+	return &entity.Order{
+		ID:            id,
+		Total:         entity.NewMoney(4200, "USD"),
+		Currency:      "USD",
+		Status:        configs.Envs.OrderStatusPending,
+		PaymentStatus: configs.Envs.PaymentStatusPending,
+	}
+}
+
+func assertAllProcessed(t *testing.T, eventStore *mockPaymentEventStore) {
+	t.Helper()
+	for _, event := range eventStore.events {
+		assert.Equal(t, entity.PaymentEventProcessed, event.Status, "%s: %s", event.Type, event.LastError)
+	}
+}
+
+func TestCheckoutThroughFakeGateway(t *testing.T) {
+	t.Run("a paid session marks the order paid", func(t *testing.T) {
+		handler, gateway, orderStore, eventStore := newFakeGatewayHandler()
+		order := pendingOrder("order-1")
+		orderStore.orders[order.ID] = order
+		storeOrder := &entity.StoreOrder{ID: "store-order-1", OrderID: order.ID, StoreID: "s1", Status: configs.Envs.OrderStatusPending}
+		orderStore.storeOrders[order.ID] = []*entity.StoreOrder{storeOrder}
+
+		session, err := gateway.CreateCheckoutSession(*order, nil, "", entity.PaymentSplit{}, "")
+		assert.NoError(t, err)
+		assert.NoError(t, gateway.CompleteCheckoutSession(session.ID))
+		handler.processDueEvents()
+
+		assert.Equal(t, configs.Envs.PaymentStatusPaid, order.PaymentStatus)
+		assert.Equal(t, configs.Envs.OrderStatusProcessing, order.Status)
+		assert.Equal(t, configs.Envs.OrderStatusProcessing, storeOrder.Status, "the stores start on their parts")
+		assert.Len(t, eventStore.events, 3)
+		assertAllProcessed(t, eventStore)
+	})
+
+	t.Run("an expired session cancels the order", func(t *testing.T) {
+		handler, gateway, orderStore, eventStore := newFakeGatewayHandler()
+		order := pendingOrder("order-2")
+		orderStore.orders[order.ID] = order
+
+		session, err := gateway.CreateCheckoutSession(*order, nil, "", entity.PaymentSplit{}, "")
+		assert.NoError(t, err)
+		assert.NoError(t, gateway.ExpireCheckoutSession(session.ID))
+		handler.processDueEvents()
+
+		assert.Equal(t, configs.Envs.OrderStatusCancelled, order.Status)
+		assert.Equal(t, configs.Envs.PaymentStatusPending, order.PaymentStatus)
+		assertAllProcessed(t, eventStore)
+	})
+
+	t.Run("a payment settling after the order was cancelled is refunded", func(t *testing.T) {
+		handler, gateway, orderStore, eventStore := newFakeGatewayHandler()
+		order := pendingOrder("order-4")
+		orderStore.orders[order.ID] = order
+
+		session, err := gateway.CreateCheckoutSession(*order, nil, "", entity.PaymentSplit{}, "")
+		assert.NoError(t, err)
+		// cancelled by an admin while the buyer was still on the payment page
+		order.Status = configs.Envs.OrderStatusCancelled
+		assert.NoError(t, gateway.CompleteCheckoutSession(session.ID))
+		handler.processDueEvents()
+		// the refund reports back as an event of its own
+		handler.processDueEvents()
+		assertAllProcessed(t, eventStore)
+
+		assert.NotEqual(t, configs.Envs.PaymentStatusPaid, order.PaymentStatus)
+		assert.NotEqual(t, configs.Envs.OrderStatusProcessing, order.Status)
+		refunds := 0
+		for _, event := range gateway.Events() {
+			if event.Type == "charge.refunded" {
+				refunds++
+			}
+		}
+		assert.Equal(t, 1, refunds)
+		_, err = gateway.CreateRefund(paidCharge(t, gateway).PaymentIntent, 0, "")
+		assert.Error(t, err, "the whole payment was refunded")
+
+		// a redelivered payment is refunded once
+		assert.NoError(t, handler.markOrderPaid(order.ID, paidCharge(t, gateway).PaymentIntent))
+	})
+
+	t.Run("a redelivered event is processed once", func(t *testing.T) {
+		handler, gateway, orderStore, eventStore := newFakeGatewayHandler()
+		order := pendingOrder("order-3")
+		orderStore.orders[order.ID] = order
+
+		session, _ := gateway.CreateCheckoutSession(*order, nil, "", entity.PaymentSplit{}, "")
+		assert.NoError(t, gateway.CompleteCheckoutSession(session.ID))
+		assert.NoError(t, handler.QueuePaymentEvent(gateway.Events()[0]))
+		handler.processDueEvents()
+
+		assert.Len(t, eventStore.events, 3)
+		assertAllProcessed(t, eventStore)
+	})
+}
+
+func TestCardRefundsThroughFakeGateway(t *testing.T) {
+	handler, gateway, orderStore, eventStore := newFakeGatewayHandler()
+	order := pendingOrder("order-1")
+	orderStore.orders[order.ID] = order
+
+	session, err := gateway.CreateCheckoutSession(*order, nil, "", entity.PaymentSplit{}, "")
+	assert.NoError(t, err)
+	assert.NoError(t, gateway.CompleteCheckoutSession(session.ID))
+	handler.processDueEvents()
+
+	// a partial refund counts against what is left to refund, the order stays paid
+	_, err = gateway.CreateRefund(paidCharge(t, gateway).PaymentIntent, 1000, "")
+	assert.NoError(t, err)
+	handler.processDueEvents()
+	assertAllProcessed(t, eventStore)
+	assert.Equal(t, entity.NewMoney(1000, "USD"), order.CardRefunded)
+	assert.Equal(t, configs.Envs.PaymentStatusPaid, order.PaymentStatus)
+
+	_, err = gateway.CreateRefund(paidCharge(t, gateway).PaymentIntent, 0, "")
+	assert.NoError(t, err)
+	handler.processDueEvents()
+	assertAllProcessed(t, eventStore)
+	assert.Equal(t, entity.NewMoney(4200, "USD"), order.CardRefunded)
+	assert.Equal(t, configs.Envs.PaymentStatusRefunded, order.PaymentStatus)
+}
+
+func TestStoreCreditThroughFakeGateway(t *testing.T) {
+	creditedOrder := func(handler *PaymentHandler, orderStore *mockOrderStore, id string) (*entity.Order, *entity.OrderTender) {
+		order := pendingOrder(id)
+		order.StoreCredit = entity.NewMoney(1200, "USD")
+		orderStore.orders[order.ID] = order
+		tender := &entity.OrderTender{OrderID: order.ID, Type: entity.TenderGiftCard, GiftCardID: "card-1", Amount: order.StoreCredit, Currency: "USD", Status: entity.TenderApplied}
+		handler.creditStore.(*mockCreditStore).tenders[order.ID] = []*entity.OrderTender{tender}
+		return order, tender
+	}
+
+	t.Run("only what the credit leaves is charged and a full refund gives the credit back", func(t *testing.T) {
+		handler, gateway, orderStore, eventStore := newFakeGatewayHandler()
+		order, tender := creditedOrder(handler, orderStore, "order-1")
+
+		session, err := gateway.CreateCheckoutSession(*order, nil, "", entity.PaymentSplit{}, "")
+		assert.NoError(t, err)
+		assert.NoError(t, gateway.CompleteCheckoutSession(session.ID))
+		handler.processDueEvents()
+		assertAllProcessed(t, eventStore)
+		assert.Equal(t, configs.Envs.PaymentStatusPaid, order.PaymentStatus)
+
+		var charge stripe.Charge
+		for _, event := range gateway.Events() {
+			if event.Type == "charge.succeeded" {
+				var body stripe.Event
+				assert.NoError(t, json.Unmarshal(event.Payload, &body))
+				assert.NoError(t, json.Unmarshal(body.Data.Raw, &charge))
+			}
+		}
+		assert.Equal(t, int64(3000), charge.Amount)
+		assert.Equal(t, entity.TenderApplied, tender.Status)
+
+		_, err = gateway.CreateRefund(charge.PaymentIntent, 0, "")
+		assert.NoError(t, err)
+		handler.processDueEvents()
+		assertAllProcessed(t, eventStore)
+		assert.Equal(t, entity.TenderRefunded, tender.Status)
+	})
+
+	t.Run("an expired session gives the credit back", func(t *testing.T) {
+		handler, gateway, orderStore, eventStore := newFakeGatewayHandler()
+		order, tender := creditedOrder(handler, orderStore, "order-2")
+
+		session, err := gateway.CreateCheckoutSession(*order, nil, "", entity.PaymentSplit{}, "")
+		assert.NoError(t, err)
+		assert.NoError(t, gateway.ExpireCheckoutSession(session.ID))
+		handler.processDueEvents()
+		assertAllProcessed(t, eventStore)
+
+		assert.Equal(t, configs.Envs.OrderStatusCancelled, order.Status)
+		assert.Equal(t, entity.TenderReleased, tender.Status)
+	})
+}
+
+func TestLoyaltyThroughFakeGateway(t *testing.T) {
+	withLoyalty := func(handler *PaymentHandler, orderStore *mockOrderStore) *mockLoyaltyStore {
+		loyaltyStore := &mockLoyaltyStore{rules: []*entity.LoyaltyEarnRule{{Type: entity.LoyaltyRuleSpend, Rate: 1, IsActive: true}}}
+		handler.loyalty = loyalty.NewProgram(loyaltyStore, orderStore)
+		return loyaltyStore
+	}
+
+	t.Run("a paid order earns points and a full refund takes them back", func(t *testing.T) {
+		handler, gateway, orderStore, eventStore := newFakeGatewayHandler()
+		loyaltyStore := withLoyalty(handler, orderStore)
+		order := pendingOrder("order-1")
+		order.UserID = "user-1"
+		orderStore.orders[order.ID] = order
+		orderStore.items[order.ID] = []*entity.OrderItem{{Subtotal: entity.NewMoney(4200, "USD"), Discount: entity.NewMoney(200, "USD")}}
+
+		session, err := gateway.CreateCheckoutSession(*order, nil, "", entity.PaymentSplit{}, "")
+		assert.NoError(t, err)
+		assert.NoError(t, gateway.CompleteCheckoutSession(session.ID))
+		handler.processDueEvents()
+		assertAllProcessed(t, eventStore)
+		assert.Equal(t, 40, loyaltyStore.balance("user-1"))
+
+		// a replayed payment earns nothing more
+		assert.NoError(t, handler.loyalty.OrderPaid(order.ID))
+		assert.Equal(t, 40, loyaltyStore.balance("user-1"))
+
+		_, err = gateway.CreateRefund(paidCharge(t, gateway).PaymentIntent, 0, "")
+		assert.NoError(t, err)
+		handler.processDueEvents()
+		assertAllProcessed(t, eventStore)
+		assert.Equal(t, configs.Envs.PaymentStatusRefunded, order.PaymentStatus)
+		assert.Equal(t, 0, loyaltyStore.balance("user-1"))
+		assert.Len(t, loyaltyStore.entries, 2)
+		// the status event is written with the refund, and only once
+		assert.NoError(t, handler.markOrderRefunded(order.ID))
+		var changed int
+		for _, topic := range orderStore.topics() {
+			if topic == entity.TopicOrderStatusChanged {
+				changed++
+			}
+		}
+		assert.Equal(t, 1, changed)
+	})
+
+	t.Run("an expired session gives the redeemed points back", func(t *testing.T) {
+		handler, gateway, orderStore, eventStore := newFakeGatewayHandler()
+		loyaltyStore := withLoyalty(handler, orderStore)
+		order := pendingOrder("order-2")
+		order.UserID = "user-1"
+		order.LoyaltyPoints = 500
+		order.LoyaltyDiscount = entity.NewMoney(500, "USD")
+		orderStore.orders[order.ID] = order
+		loyaltyStore.entries = []*entity.LoyaltyEntry{{UserID: "user-1", Points: -500, Reason: entity.LoyaltyRedeem, OrderID: order.ID}}
+
+		session, err := gateway.CreateCheckoutSession(*order, nil, "", entity.PaymentSplit{}, "")
+		assert.NoError(t, err)
+		assert.NoError(t, gateway.ExpireCheckoutSession(session.ID))
+		handler.processDueEvents()
+		assertAllProcessed(t, eventStore)
+
+		assert.Equal(t, configs.Envs.OrderStatusCancelled, order.Status)
+		assert.Equal(t, 0, loyaltyStore.balance("user-1"))
+		assert.Equal(t, entity.LoyaltyRelease, loyaltyStore.entries[1].Reason)
+	})
+}
+
+func TestSavedCardThroughFakeGateway(t *testing.T) {
+	handler, gateway, _, eventStore := newFakeGatewayHandler()
+	customer, err := gateway.CreateCustomer(&payloads.CustomerPayload{Name: "Jane Doe", Email: "jane@example.com"}, "")
+	assert.NoError(t, err)
+
+	setupIntent, err := gateway.CreateSetupIntent(customer.ID, "")
+	assert.NoError(t, err)
+	paymentMethod, err := gateway.ConfirmSetupIntent(setupIntent.ID, fakepayment_repo.CardRequiresAction)
+	assert.NoError(t, err)
+	handler.processDueEvents()
+	assertAllProcessed(t, eventStore)
+
+	// the first saved card becomes the default and is charged when none is chosen
+	chosen, err := handler.chargePaymentMethod(customer.ID, "")
+	assert.NoError(t, err)
+	assert.Equal(t, paymentMethod.ID, chosen)
+
+	intent, err := gateway.CreateCharge(&payloads.CustomerChargeRequest{Amount: 900, Currency: "usd", PaymentMethodID: chosen}, customer.ID, "")
+	assert.NoError(t, err)
+	response := chargeResponse(intent)
+	assert.Equal(t, entity.PaymentIntentRequiresAction, response["status"])
+	assert.NotEmpty(t, response["clientSecret"])
+
+	_, err = handler.chargePaymentMethod(customer.ID, "pm_of_someone_else")
+	assert.Equal(t, errPaymentMethodNotFound, err)
+}
+
+// paidCharge returns the last charge the gateway reported as succeeded.
+func paidCharge(t *testing.T, gateway *fakepayment_repo.Gateway) stripe.Charge {
+	t.Helper()
+	var charge stripe.Charge
+	for _, event := range gateway.Events() {
+		if event.Type == "charge.succeeded" {
+			var stripeEvent stripe.Event
+			assert.NoError(t, json.Unmarshal(event.Payload, &stripeEvent))
+			assert.NoError(t, json.Unmarshal(stripeEvent.Data.Raw, &charge))
+		}
+	}
+	return charge
+}
+
+func TestStorePayoutsThroughFakeGateway(t *testing.T) {
+	usd := func(amount int64) entity.Money { return entity.NewMoney(amount, "USD") }
+
+	// checkout splits the payment the same way, see CartHandler.openCheckoutSession
+	checkout := func(t *testing.T, handler *PaymentHandler, gateway *fakepayment_repo.Gateway, order *entity.Order, items []*entity.OrderItem, accounts map[string]string) {
+		t.Helper()
+		shares, err := payout.Split(items, nil, float64(configs.Envs.CommissionPercent))
+		assert.NoError(t, err)
+		split, err := payout.PaymentSplit(*order, items, shares, accounts)
+		assert.NoError(t, err)
+
+		session, err := gateway.CreateCheckoutSession(*order, items, "", split, "")
+		assert.NoError(t, err)
+		assert.NoError(t, gateway.CompleteCheckoutSession(session.ID))
+		handler.processDueEvents()
+	}
+
+	t.Run("stores of a shared order are paid by transfer once onboarded", func(t *testing.T) {
+		handler, gateway, orderStore, eventStore := newFakeGatewayHandler()
+		payoutStore := handler.payoutStore.(*mockPayoutStore)
+		storeOwnerStore := handler.storeOwnerStore.(*mockStoreOwnerStore)
+		storeOwnerStore.stores["s1"] = &entity.StoreOwner{StoreID: "s1", StripeAccountID: "acct_1"}
+		storeOwnerStore.stores["s2"] = &entity.StoreOwner{StoreID: "s2"}
+
+		order := pendingOrder("order-4")
+		order.Total = usd(10000)
+		orderStore.orders[order.ID] = order
+		orderStore.items[order.ID] = []*entity.OrderItem{
+			{StoreID: "s1", TotalPrice: usd(6000)},
+			{StoreID: "s2", TotalPrice: usd(3000)},
+			{TotalPrice: usd(1000)},
+		}
+
+		checkout(t, handler, gateway, order, orderStore.items[order.ID], map[string]string{"s1": "acct_1"})
+		assertAllProcessed(t, eventStore)
+
+		assert.Len(t, payoutStore.payouts, 2)
+		assert.Equal(t, entity.StorePayoutPaid, payoutStore.payouts[0].Status)
+		assert.Equal(t, usd(5400), payoutStore.payouts[0].Amount)
+		assert.Equal(t, entity.StorePayoutPending, payoutStore.payouts[1].Status, "s2 has no account yet")
+
+		transfers, _ := gateway.Transfers()
+		assert.Len(t, transfers, 1)
+		assert.Equal(t, entity.Transfer{ID: payoutStore.payouts[0].TransferID, Destination: "acct_1", Amount: usd(5400), TransferGroup: "order-4"}, transfers[0])
+
+		storeOwnerStore.stores["s2"].StripeAccountID = "acct_2"
+		settled, err := handler.settleStorePayouts("s2")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, settled)
+		assert.Equal(t, entity.StorePayoutPaid, payoutStore.payouts[1].Status)
+
+		// the charge is redelivered, nothing is paid twice
+		assert.NoError(t, handler.recordStorePayouts(order.ID, paidCharge(t, gateway)))
+		transfers, _ = gateway.Transfers()
+		assert.Len(t, transfers, 2)
+
+		_, err = gateway.CreateRefund(paidCharge(t, gateway).PaymentIntent, 0, "")
+		assert.NoError(t, err)
+		handler.processDueEvents()
+		assertAllProcessed(t, eventStore)
+
+		_, reversed := gateway.Transfers()
+		for _, storePayout := range payoutStore.payouts {
+			assert.Equal(t, entity.StorePayoutReversed, storePayout.Status)
+			assert.True(t, reversed[storePayout.TransferID])
+		}
+	})
+
+	t.Run("the only store of an order is paid with the charge", func(t *testing.T) {
+		handler, gateway, orderStore, eventStore := newFakeGatewayHandler()
+		payoutStore := handler.payoutStore.(*mockPayoutStore)
+		handler.storeOwnerStore.(*mockStoreOwnerStore).stores["s1"] = &entity.StoreOwner{StoreID: "s1", StripeAccountID: "acct_1"}
+
+		order := pendingOrder("order-5")
+		orderStore.orders[order.ID] = order
+		orderStore.items[order.ID] = []*entity.OrderItem{{StoreID: "s1", TotalPrice: usd(4200)}}
+
+		checkout(t, handler, gateway, order, orderStore.items[order.ID], map[string]string{"s1": "acct_1"})
+		assertAllProcessed(t, eventStore)
+
+		charge := paidCharge(t, gateway)
+		assert.Equal(t, int64(420), charge.ApplicationFeeAmount)
+		transfers, _ := gateway.Transfers()
+		assert.Len(t, transfers, 1, "no transfer besides the one of the charge")
+		assert.Equal(t, usd(3780), transfers[0].Amount)
+
+		assert.Len(t, payoutStore.payouts, 1)
+		assert.Equal(t, entity.StorePayoutPaid, payoutStore.payouts[0].Status)
+		assert.Equal(t, charge.Transfer.ID, payoutStore.payouts[0].TransferID)
+	})
+}
+
+func TestSubscriptionRenewalsThroughFakeGateway(t *testing.T) {
+	handler, gateway, orderStore, eventStore := newFakeGatewayHandler()
+	subscriptionStore := handler.subscriptionStore.(*mockSubscriptionStore)
+	productStore := handler.productStore.(*mockProductStore)
+	payoutStore := handler.payoutStore.(*mockPayoutStore)
+
+	customer, err := gateway.CreateCustomer(&payloads.CustomerPayload{Name: "Jane Doe", Email: "jane@example.com"}, "")
+	assert.NoError(t, err)
+	paymentMethod, err := gateway.CreatePaymentMethod(customer.ID)
+	assert.NoError(t, err)
+
+	// subscribing saves the subscription, then starts it at the gateway, see
+	// handleCreateSubscription
+	sub := entity.Subscription{
+		UserID:          "user-1",
+		ProductID:       "product-1",
+		StoreID:         "s1",
+		ProductName:     "Coffee beans",
+		Quantity:        2,
+		UnitPrice:       entity.NewMoney(1250, "USD"),
+		Currency:        "USD",
+		Interval:        "month",
+		IntervalCount:   1,
+		Status:          entity.SubscriptionIncomplete,
+		ShippingAddress: entity.OrderAddress{FullName: "Jane Doe"},
+	}
+	sub.ID, err = subscriptionStore.CreateSubscription(sub)
+	assert.NoError(t, err)
+	payment, err := gateway.CreateSubscription(customer.ID, entity.SubscriptionPlan{
+		ProductID:       sub.ProductID,
+		ProductName:     sub.ProductName,
+		UnitPrice:       sub.UnitPrice,
+		Interval:        sub.Interval,
+		IntervalCount:   sub.IntervalCount,
+		Quantity:        sub.Quantity,
+		PaymentMethodID: paymentMethod.ID,
+	}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.SubscriptionActive, payment.Status)
+	sub.PaymentSubscriptionID = payment.ID
+	applyPaymentSubscription(&sub, payment)
+	assert.NoError(t, subscriptionStore.UpdateSubscription(sub))
+
+	handler.processDueEvents()
+	assertAllProcessed(t, eventStore)
+
+	renewals := func() []*entity.Order {
+		var orders []*entity.Order
+		for _, order := range orderStore.orders {
+			if order.SubscriptionID == sub.ID {
+				orders = append(orders, order)
+			}
+		}
+		return orders
+	}
+
+	t.Run("the first payment is the first order", func(t *testing.T) {
+		orders := renewals()
+		assert.Len(t, orders, 1)
+		order := orders[0]
+		assert.Equal(t, entity.NewMoney(2500, "USD"), order.Total)
+		assert.Equal(t, configs.Envs.PaymentStatusPaid, order.PaymentStatus)
+		assert.Equal(t, configs.Envs.OrderStatusProcessing, order.Status)
+		assert.NotEmpty(t, order.InvoiceID)
+		assert.Len(t, orderStore.items[order.ID], 1)
+		assert.Len(t, orderStore.storeOrders[order.ID], 1)
+		assert.Equal(t, configs.Envs.OrderStatusProcessing, orderStore.storeOrders[order.ID][0].Status)
+		assert.Equal(t, 2, productStore.taken["product-1"])
+		assert.Len(t, payoutStore.payouts, 1, "the store is owed its share")
+	})
+
+	t.Run("every paid renewal is an order", func(t *testing.T) {
+		assert.NoError(t, gateway.RenewSubscription(payment.ID))
+		handler.processDueEvents()
+		assertAllProcessed(t, eventStore)
+		assert.Len(t, renewals(), 2)
+		assert.Equal(t, 4, productStore.taken["product-1"])
+
+		// a redelivered invoice is not ordered twice
+		for _, event := range gateway.Events() {
+			if event.Type == "invoice.paid" {
+				event.ID += "-again"
+				assert.NoError(t, handler.QueuePaymentEvent(event))
+			}
+		}
+		handler.processDueEvents()
+		assertAllProcessed(t, eventStore)
+		assert.Len(t, renewals(), 2)
+	})
+
+	t.Run("a skipped or paused renewal is not ordered", func(t *testing.T) {
+		renewsAt := *subscriptionStore.subscriptions[sub.ID].RenewsAt
+		skipped, err := gateway.SkipSubscriptionRenewal(payment.ID, renewsAt.AddDate(0, 1, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, entity.SubscriptionActive, skipped.Status)
+		assert.NoError(t, gateway.RenewSubscription(payment.ID))
+
+		_, err = gateway.PauseSubscription(payment.ID, true)
+		assert.NoError(t, err)
+		assert.NoError(t, gateway.RenewSubscription(payment.ID))
+		handler.processDueEvents()
+		assertAllProcessed(t, eventStore)
+
+		assert.Len(t, renewals(), 2)
+		assert.Equal(t, entity.SubscriptionPaused, subscriptionStore.subscriptions[sub.ID].Status, "synced from the gateway")
+	})
+
+	t.Run("a cancelled subscription stops renewing", func(t *testing.T) {
+		assert.NoError(t, gateway.CancelSubscription(payment.ID))
+		handler.processDueEvents()
+		assertAllProcessed(t, eventStore)
+
+		assert.Equal(t, entity.SubscriptionCancelled, subscriptionStore.subscriptions[sub.ID].Status)
+		assert.Nil(t, subscriptionStore.subscriptions[sub.ID].RenewsAt)
+		assert.Error(t, gateway.RenewSubscription(payment.ID))
+	})
+}
+
+func TestOutboxThroughFakeGateway(t *testing.T) {
+	t.Run("a paid order writes its event and purchase email once", func(t *testing.T) {
+		handler, gateway, orderStore, eventStore := newFakeGatewayHandler()
+		outbox := &mockOutboxStore{}
+		handler.notifier = notification.NewNotifier(mail_repo.NewMemoryMailer(), notification.NewTemplates("../../../../../../static"), outbox, &mockNotificationStore{}, &mockUserStore{})
+		order := pendingOrder("order-1")
+		order.GuestEmail = "jane@example.com"
+		orderStore.orders[order.ID] = order
+
+		session, err := gateway.CreateCheckoutSession(*order, nil, "", entity.PaymentSplit{}, "")
+		assert.NoError(t, err)
+		assert.NoError(t, gateway.CompleteCheckoutSession(session.ID))
+		handler.processDueEvents()
+		assertAllProcessed(t, eventStore)
+
+		assert.Equal(t, []string{entity.TopicOrderPaid, entity.TopicEmail}, orderStore.topics())
+		assert.Equal(t, "order.paid:order-1", orderStore.outbox[0].Key)
+		assert.Equal(t, "purchase_success:order-1", orderStore.outbox[1].Key)
+
+		var event entity.OrderEvent
+		assert.NoError(t, json.Unmarshal(orderStore.outbox[0].Payload, &event))
+		assert.Equal(t, "order-1", event.OrderID)
+		assert.Equal(t, configs.Envs.PaymentStatusPaid, event.PaymentStatus)
+		assert.Equal(t, order.Total, event.Total)
+
+		var email entity.Email
+		assert.NoError(t, json.Unmarshal(orderStore.outbox[1].Payload, &email))
+		assert.Equal(t, []string{"jane@example.com"}, email.To)
+
+		// the charge of an order leaves the email to the order
+		assert.Empty(t, outbox.messages)
+
+		// a replayed payment writes nothing more
+		assert.NoError(t, handler.markOrderPaid(order.ID, ""))
+		assert.Len(t, orderStore.outbox, 2)
+	})
+
+	t.Run("an expired session writes the cancelled event", func(t *testing.T) {
+		handler, gateway, orderStore, eventStore := newFakeGatewayHandler()
+		order := pendingOrder("order-2")
+		orderStore.orders[order.ID] = order
+
+		session, err := gateway.CreateCheckoutSession(*order, nil, "", entity.PaymentSplit{}, "")
+		assert.NoError(t, err)
+		assert.NoError(t, gateway.ExpireCheckoutSession(session.ID))
+		handler.processDueEvents()
+		assertAllProcessed(t, eventStore)
+
+		assert.Equal(t, []string{entity.TopicOrderCancelled}, orderStore.topics())
+		var event entity.OrderEvent
+		assert.NoError(t, json.Unmarshal(orderStore.outbox[0].Payload, &event))
+		assert.Equal(t, configs.Envs.OrderStatusCancelled, event.Status)
+	})
+
+	t.Run("a cancelled order publishes the stock it gave back", func(t *testing.T) {
+		handler, gateway, orderStore, eventStore := newFakeGatewayHandler()
+		events := &mockOutboxStore{}
+		handler.events = outbox.NewDispatcher(events)
+		order := pendingOrder("order-3")
+		orderStore.orders[order.ID] = order
+		orderStore.items[order.ID] = []*entity.OrderItem{{OrderID: order.ID, ProductID: "product-1", Quantity: 2}, {OrderID: order.ID, ProductID: "product-2", Quantity: 1}}
+
+		session, err := gateway.CreateCheckoutSession(*order, nil, "", entity.PaymentSplit{}, "")
+		assert.NoError(t, err)
+		assert.NoError(t, gateway.ExpireCheckoutSession(session.ID))
+		handler.processDueEvents()
+		assertAllProcessed(t, eventStore)
+
+		var restocked []string
+		for _, message := range events.messages {
+			assert.Equal(t, entity.TopicProductStockChanged, message.Topic)
+			var event entity.ProductEvent
+			assert.NoError(t, json.Unmarshal(message.Payload, &event))
+			restocked = append(restocked, event.ProductID)
+		}
+		assert.Equal(t, []string{"product-1", "product-2"}, restocked)
+	})
+}
+
+func TestOrderEmailsThroughFakeGateway(t *testing.T) {
+	emailed := func(t *testing.T, orderStore *mockOrderStore) []string {
+		var keys []string
+		for _, message := range orderStore.outbox {
+			if message.Topic == entity.TopicEmail {
+				keys = append(keys, message.Key)
+			}
+		}
+		return keys
+	}
+
+	t.Run("an expired session tells the buyer the order is cancelled", func(t *testing.T) {
+		handler, gateway, orderStore, eventStore := newFakeGatewayHandler()
+		order := pendingOrder("order-1")
+		order.GuestEmail = "jane@example.com"
+		orderStore.orders[order.ID] = order
+
+		session, err := gateway.CreateCheckoutSession(*order, nil, "", entity.PaymentSplit{}, "")
+		assert.NoError(t, err)
+		assert.NoError(t, gateway.ExpireCheckoutSession(session.ID))
+		handler.processDueEvents()
+		assertAllProcessed(t, eventStore)
+
+		assert.Equal(t, []string{entity.TopicOrderCancelled, entity.TopicEmail}, orderStore.topics())
+		assert.Equal(t, []string{"order_cancelled:order-1"}, emailed(t, orderStore))
+	})
+
+	t.Run("a failed payment tells the buyer it did not go through", func(t *testing.T) {
+		handler, gateway, orderStore, eventStore := newFakeGatewayHandler()
+		order := pendingOrder("order-2")
+		order.GuestEmail = "jane@example.com"
+		orderStore.orders[order.ID] = order
+
+		session, err := gateway.CreateCheckoutSession(*order, nil, "", entity.PaymentSplit{}, "")
+		assert.NoError(t, err)
+		assert.NoError(t, gateway.FailCheckoutSession(session.ID))
+		handler.processDueEvents()
+		assertAllProcessed(t, eventStore)
+
+		assert.Equal(t, configs.Envs.OrderStatusCancelled, orderStore.orders[order.ID].Status)
+		assert.Equal(t, []string{"payment_failed:order-2"}, emailed(t, orderStore))
+	})
+
+	t.Run("a declined card tells the buyer once and leaves the order to retry", func(t *testing.T) {
+		handler, gateway, orderStore, eventStore := newFakeGatewayHandler()
+		sent := &mockOutboxStore{}
+		handler.notifier = notification.NewNotifier(mail_repo.NewMemoryMailer(), notification.NewTemplates("../../../../../../static"), sent, &mockNotificationStore{}, &mockUserStore{})
+		order := pendingOrder("order-4")
+		order.GuestEmail = "jane@example.com"
+		orderStore.orders[order.ID] = order
+
+		session, err := gateway.CreateCheckoutSession(*order, nil, "", entity.PaymentSplit{}, "")
+		assert.NoError(t, err)
+		assert.NoError(t, gateway.DeclineCheckoutSession(session.ID))
+		assert.NoError(t, gateway.DeclineCheckoutSession(session.ID))
+		handler.processDueEvents()
+		assertAllProcessed(t, eventStore)
+
+		assert.Equal(t, configs.Envs.OrderStatusPending, orderStore.orders[order.ID].Status)
+		var keys []string
+		for _, message := range sent.messages {
+			keys = append(keys, message.Key)
+		}
+		// the outbox sends a key once, however often it is written
+		assert.Equal(t, []string{"payment_failed:order-4", "payment_failed:order-4"}, keys)
+
+		// the buyer pays on the second try and is not told again
+		sent.messages = nil
+		assert.NoError(t, gateway.CompleteCheckoutSession(session.ID))
+		handler.processDueEvents()
+		assertAllProcessed(t, eventStore)
+		assert.Equal(t, configs.Envs.OrderStatusProcessing, orderStore.orders[order.ID].Status)
+		assert.Empty(t, sent.messages)
+	})
+
+	t.Run("a buyer who turned payment emails off is not told", func(t *testing.T) {
+		handler, gateway, orderStore, eventStore := newFakeGatewayHandler()
+		handler.notifier = notification.NewNotifier(mail_repo.NewMemoryMailer(), notification.NewTemplates("../../../../../../static"), &mockOutboxStore{}, &mockNotificationStore{off: map[string]string{"jane@example.com": entity.NotifyPayments}}, &mockUserStore{})
+		order := pendingOrder("order-3")
+		order.GuestEmail = "jane@example.com"
+		orderStore.orders[order.ID] = order
+
+		session, err := gateway.CreateCheckoutSession(*order, nil, "", entity.PaymentSplit{}, "")
+		assert.NoError(t, err)
+		assert.NoError(t, gateway.CompleteCheckoutSession(session.ID))
+		handler.processDueEvents()
+		assertAllProcessed(t, eventStore)
+
+		assert.Equal(t, []string{entity.TopicOrderPaid}, orderStore.topics())
+	})
+}
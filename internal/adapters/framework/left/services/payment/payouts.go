@@ -0,0 +1,352 @@
+package payment
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"ecom-api/internal/adapters/framework/left/services/auth"
+	"ecom-api/internal/application/core/payout"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/application/core/types/entity/payloads"
+	"ecom-api/pkg/configs"
+	"ecom-api/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/stripe/stripe-go"
+)
+
+// recordStorePayouts adds what every store of a paid order is owed to the
+// payout ledger and pays the stores that have a connected account. A
+// destination charge already paid its only store, the charge carries the
+// transfer. Safe to run again for the same charge, the ledger holds one payout
+// per store and order and transfers are idempotent per payout.
+func (handler *PaymentHandler) recordStorePayouts(orderID string, charge stripe.Charge) error {
+	order, err := handler.orderStore.GetOrderByID(orderID)
+	if err != nil {
+		return err
+	}
+	if order.ID == "" {
+		log.Printf("Charge %s is for unknown order %s, no payouts recorded", charge.ID, orderID)
+		return nil
+	}
+
+	items, err := handler.orderStore.GetOrderItemsByOrderId(orderID)
+	if err != nil {
+		return err
+	}
+	rules, err := handler.payoutStore.GetCommissionRules()
+	if err != nil {
+		return err
+	}
+	shares, err := payout.Split(items, rules, float64(configs.Envs.CommissionPercent))
+	if err != nil {
+		return err
+	}
+
+	for _, share := range shares {
+		if _, err := handler.payoutStore.CreateStorePayout(entity.StorePayout{
+			StoreID:    share.StoreID,
+			OrderID:    orderID,
+			Gross:      share.Gross,
+			Commission: share.Commission,
+			Amount:     share.Net,
+			Currency:   order.Currency,
+			Status:     entity.StorePayoutPending,
+		}); err != nil {
+			return err
+		}
+	}
+
+	payouts, err := handler.payoutStore.GetStorePayoutsByOrderID(orderID)
+	if err != nil {
+		return err
+	}
+
+	// a charge cut short by store credit may not cover what the stores are
+	// owed, the platform pays them from its balance then
+	sourceChargeID := charge.ID
+	if !order.StoreCredit.IsZero() {
+		sourceChargeID = ""
+	}
+
+	for _, storePayout := range payouts {
+		if storePayout.Status != entity.StorePayoutPending {
+			continue
+		}
+
+		if charge.Transfer != nil && charge.Transfer.ID != "" && len(payouts) == 1 {
+			if _, err := handler.payoutStore.MarkStorePayoutPaid(storePayout.ID, charge.Transfer.ID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, err := handler.transferPayout(storePayout, sourceChargeID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// transferPayout pays a pending payout to the connected account of its store.
+// A store that is not onboarded yet keeps the payout pending until it is.
+// Returns whether the payout was paid.
+func (handler *PaymentHandler) transferPayout(storePayout *entity.StorePayout, sourceChargeId string) (bool, error) {
+	store, err := handler.storeOwnerStore.GetStoreOwnerByID(storePayout.StoreID)
+	if err != nil {
+		return false, err
+	}
+	if store.StripeAccountID == "" {
+		log.Printf("Store %s has no payout account, payout %s stays pending", storePayout.StoreID, storePayout.ID)
+		return false, nil
+	}
+
+	// a sale eaten whole by the commission leaves nothing to transfer
+	transferID := ""
+	if !storePayout.Amount.IsZero() {
+		transfer, err := handler.paymentStore.CreateTransfer(store.StripeAccountID, storePayout.Amount, storePayout.OrderID, sourceChargeId, "payout-"+storePayout.ID)
+		if err != nil {
+			return false, fmt.Errorf("failed to pay out %s to store %s: %v", storePayout.ID, storePayout.StoreID, err)
+		}
+		transferID = transfer.ID
+	}
+
+	return handler.payoutStore.MarkStorePayoutPaid(storePayout.ID, transferID)
+}
+
+// settleStorePayouts pays everything still owed to a store and returns how
+// many payouts were paid.
+func (handler *PaymentHandler) settleStorePayouts(storeID string) (int, error) {
+	pending, err := handler.payoutStore.GetPendingStorePayouts(storeID)
+	if err != nil {
+		return 0, err
+	}
+
+	settled := 0
+	for _, storePayout := range pending {
+		paid, err := handler.transferPayout(storePayout, "")
+		if err != nil {
+			return settled, err
+		}
+		if paid {
+			settled++
+		}
+	}
+
+	return settled, nil
+}
+
+// reverseStorePayouts takes back what the stores of a refunded order were
+// paid, and cancels what they were still owed.
+func (handler *PaymentHandler) reverseStorePayouts(orderID string) error {
+	payouts, err := handler.payoutStore.GetStorePayoutsByOrderID(orderID)
+	if err != nil {
+		return err
+	}
+
+	for _, storePayout := range payouts {
+		if storePayout.Status == entity.StorePayoutReversed {
+			continue
+		}
+
+		if storePayout.Status == entity.StorePayoutPaid && storePayout.TransferID != "" {
+			if err := handler.paymentStore.ReverseTransfer(storePayout.TransferID, "reversal-"+storePayout.ID); err != nil {
+				return fmt.Errorf("failed to reverse payout %s: %v", storePayout.ID, err)
+			}
+		}
+
+		if _, err := handler.payoutStore.MarkStorePayoutReversed(storePayout.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (handler *PaymentHandler) handleGetStorePayouts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	user, err := handler.userStore.GetUserByID(auth.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// store owners see their own ledger, admins pick the store
+	storeID := r.URL.Query().Get("storeId")
+	if user.Role == "storeowner" {
+		store, err := handler.storeOwnerStore.GetStoreOwnerByEmail(user.Email)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		storeID = store.StoreID
+	}
+	if storeID == "" {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("store not found"))
+		return
+	}
+
+	payouts, err := handler.payoutStore.GetStorePayoutsByStoreID(storeID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	balances, err := payout.Balances(payouts)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"storeId":  storeID,
+		"balances": balances,
+		"payouts":  payouts,
+	}, nil)
+}
+
+func (handler *PaymentHandler) handleSetStorePayoutAccount(w http.ResponseWriter, r *http.Request) {
+	var accountParams payloads.StorePayoutAccountPayload
+
+	if r.Method != http.MethodPut {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	storeID, ok := mux.Vars(r)["storeId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing store ID"))
+		return
+	}
+
+	if err := utils.ParseJSON(r, &accountParams); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(accountParams); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors)
+		return
+	}
+
+	found, err := handler.storeOwnerStore.SetStoreStripeAccount(storeID, accountParams.StripeAccountID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !found {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("store %s not found", storeID))
+		return
+	}
+
+	// what the store was owed before it was onboarded is paid out now
+	settled, err := handler.settleStorePayouts(storeID)
+	if err != nil {
+		log.Printf("failed to settle payouts of store %s: %v", storeID, err)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"storeId":         storeID,
+		"stripeAccountId": accountParams.StripeAccountID,
+		"settledPayouts":  settled,
+	}, nil)
+}
+
+func (handler *PaymentHandler) handleSettleStorePayouts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	storeID, ok := mux.Vars(r)["storeId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing store ID"))
+		return
+	}
+
+	settled, err := handler.settleStorePayouts(storeID)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{"storeId": storeID, "settledPayouts": settled}, nil)
+}
+
+func (handler *PaymentHandler) handleCreateCommissionRule(w http.ResponseWriter, r *http.Request) {
+	var ruleParams payloads.CommissionRulePayload
+
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	if err := utils.ParseJSON(r, &ruleParams); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(ruleParams); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors)
+		return
+	}
+
+	rule := entity.CommissionRule{
+		StoreID:  ruleParams.StoreID,
+		Category: ruleParams.Category,
+		Rate:     ruleParams.Rate,
+		IsActive: true,
+	}
+	ruleID, err := handler.payoutStore.CreateCommissionRule(rule)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	rule.ID = ruleID
+
+	utils.WriteJSON(w, http.StatusCreated, rule, nil)
+}
+
+func (handler *PaymentHandler) handleGetCommissionRules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	rules, err := handler.payoutStore.GetCommissionRules()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, rules, nil)
+}
+
+func (handler *PaymentHandler) handleDeleteCommissionRule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	ruleID, ok := mux.Vars(r)["ruleId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing rule ID"))
+		return
+	}
+
+	if err := handler.payoutStore.DeleteCommissionRule(ruleID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "commission rule deleted"}, nil)
+}
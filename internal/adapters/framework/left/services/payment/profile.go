@@ -0,0 +1,411 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"ecom-api/internal/adapters/framework/left/services/auth"
+	"ecom-api/internal/adapters/framework/left/services/idempotency"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/application/core/types/entity/payloads"
+	"ecom-api/pkg/configs"
+	"ecom-api/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+// stripeCustomerFor returns the Stripe customer of a user, creating it from
+// profile the first time the user pays. When another request links a customer
+// to the user first, that one is kept and the one created here is deleted.
+func (handler *PaymentHandler) stripeCustomerFor(user *entity.User, profile payloads.CustomerPayload, idempotencyKey string) (string, error) {
+	if user.StripeCustomerID != "" {
+		return user.StripeCustomerID, nil
+	}
+
+	profile.Email = user.Email
+	if profile.Name == "" {
+		profile.Name = user.FirstName + " " + user.LastName
+	}
+	if profile.Metadata == nil {
+		profile.Metadata = map[string]string{}
+	}
+	profile.Metadata["user_id"] = user.ID
+
+	customer, err := handler.paymentStore.CreateCustomer(&profile, idempotencyKey)
+	if err != nil {
+		return "", err
+	}
+
+	linked, err := handler.userStore.LinkStripeCustomer(user.ID, customer.ID)
+	if err != nil {
+		return "", err
+	}
+	if linked {
+		user.StripeCustomerID = customer.ID
+		return customer.ID, nil
+	}
+
+	if _, err := handler.paymentStore.DeleteCustomer(customer.ID); err != nil {
+		log.Printf("failed to delete duplicate stripe customer %s of user %s: %v", customer.ID, user.ID, err)
+	}
+	current, err := handler.userStore.GetUserByID(user.ID)
+	if err != nil {
+		return "", err
+	}
+	user.StripeCustomerID = current.StripeCustomerID
+	return current.StripeCustomerID, nil
+}
+
+// currentUser returns the authenticated user of the request.
+func (handler *PaymentHandler) currentUser(r *http.Request) (*entity.User, error) {
+	user, err := handler.userStore.GetUserByID(auth.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		return nil, err
+	}
+	if user.ID == "" {
+		return nil, fmt.Errorf("user not found")
+	}
+	return user, nil
+}
+
+func (handler *PaymentHandler) handleGetPaymentProfile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	user, err := handler.currentUser(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if user.StripeCustomerID == "" {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("no payment profile yet, it is created on your first payment"))
+		return
+	}
+
+	customer, err := handler.paymentStore.GetCustomer(user.StripeCustomerID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, customer, nil)
+}
+
+func (handler *PaymentHandler) handleCreatePaymentProfile(w http.ResponseWriter, r *http.Request) {
+	var profile payloads.PaymentProfilePayload
+
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	if err := utils.ParseJSON(r, &profile); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(profile); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors)
+		return
+	}
+
+	user, err := handler.currentUser(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if user.StripeCustomerID != "" {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("payment profile already exists"))
+		return
+	}
+
+	customer := payloads.CustomerPayload{
+		Name:        profile.Name,
+		Phone:       profile.Phone,
+		Description: profile.Description,
+		Address:     profile.Address,
+	}
+	if profile.Shipping != nil {
+		customer.Shipping = *profile.Shipping
+	}
+
+	customerId, err := handler.stripeCustomerFor(user, customer, idempotency.KeyFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, map[string]string{"customerId": customerId}, nil)
+}
+
+func (handler *PaymentHandler) handleProfilePaymentMethodCreation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	// card details are only taken through setup intents, the test card is a
+	// fixture for test mode
+	if !configs.Envs.StripeTestMode {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("test cards are only available in test mode, save cards through a setup intent"))
+		return
+	}
+
+	user, err := handler.currentUser(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	customerId, err := handler.stripeCustomerFor(user, payloads.CustomerPayload{}, "")
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	paymentMethod, err := handler.paymentStore.CreatePaymentMethod(customerId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, paymentMethod, nil)
+}
+
+func (handler *PaymentHandler) handleProfileCharge(w http.ResponseWriter, r *http.Request) {
+	var chargeParams payloads.CustomerChargeRequest
+
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	if err := utils.ParseJSON(r, &chargeParams); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(chargeParams); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors)
+		return
+	}
+
+	user, err := handler.currentUser(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	customerId, err := handler.stripeCustomerFor(user, payloads.CustomerPayload{}, "")
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	chargeParams.PaymentMethodID, err = handler.chargePaymentMethod(customerId, chargeParams.PaymentMethodID)
+	if err == errPaymentMethodNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	charge, err := handler.paymentStore.CreateCharge(&chargeParams, customerId, idempotency.KeyFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, chargeErrorStatus(err), err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, chargeResponse(charge), nil)
+}
+
+// errPaymentMethodNotFound is returned for payment methods that do not belong
+// to the customer, so other customers' cards cannot be probed.
+var errPaymentMethodNotFound = fmt.Errorf("payment method not found")
+
+// customerPaymentMethod checks that a payment method is saved on the customer.
+func (handler *PaymentHandler) customerPaymentMethod(customerId, paymentMethodId string) (*entity.PaymentMethod, error) {
+	paymentMethod, err := handler.paymentStore.GetPaymentMethod(paymentMethodId)
+	if err != nil {
+		return nil, errPaymentMethodNotFound
+	}
+	if paymentMethod.CustomerID == "" || paymentMethod.CustomerID != customerId {
+		return nil, errPaymentMethodNotFound
+	}
+	return paymentMethod, nil
+}
+
+// chargePaymentMethod picks what to charge: the chosen payment method when it
+// is saved on the customer, the customer's default one otherwise. Empty when
+// the customer has neither.
+func (handler *PaymentHandler) chargePaymentMethod(customerId, chosen string) (string, error) {
+	if chosen != "" {
+		if _, err := handler.customerPaymentMethod(customerId, chosen); err != nil {
+			return "", err
+		}
+		return chosen, nil
+	}
+
+	customer, err := handler.paymentStore.GetCustomer(customerId)
+	if err != nil {
+		return "", err
+	}
+	return customer.DefaultPaymentMethodID, nil
+}
+
+// chargeErrorStatus answers declines with 402 and gateway failures with 502.
+func chargeErrorStatus(err error) int {
+	if errors.Is(err, entity.ErrPaymentDeclined) {
+		return http.StatusPaymentRequired
+	}
+	return http.StatusBadGateway
+}
+
+// chargeResponse tells the client whether the charge went through or still
+// needs 3D Secure, which it completes with the client secret.
+func chargeResponse(intent *entity.PaymentIntent) map[string]interface{} {
+	response := map[string]interface{}{
+		"charge":          intent.Amount,
+		"paymentIntentId": intent.ID,
+		"status":          intent.Status,
+	}
+	if intent.Status == entity.PaymentIntentRequiresAction {
+		response["clientSecret"] = intent.ClientSecret
+	}
+	return response
+}
+
+func (handler *PaymentHandler) handleCreateSetupIntent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	user, err := handler.currentUser(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	customerId, err := handler.stripeCustomerFor(user, payloads.CustomerPayload{}, "")
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	intent, err := handler.paymentStore.CreateSetupIntent(customerId, idempotency.KeyFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, map[string]string{
+		"setupIntentId": intent.ID,
+		"clientSecret":  intent.ClientSecret,
+	}, nil)
+}
+
+func (handler *PaymentHandler) handleGetPaymentMethods(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	user, err := handler.currentUser(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if user.StripeCustomerID == "" {
+		utils.WriteJSON(w, http.StatusOK, map[string]interface{}{"paymentMethods": []*entity.PaymentMethod{}}, nil)
+		return
+	}
+
+	paymentMethods, err := handler.paymentStore.ListPaymentMethods(user.StripeCustomerID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	defaultPaymentMethod, err := handler.chargePaymentMethod(user.StripeCustomerID, "")
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"paymentMethods":       paymentMethods,
+		"defaultPaymentMethod": defaultPaymentMethod,
+	}, nil)
+}
+
+func (handler *PaymentHandler) handleSetDefaultPaymentMethod(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	paymentMethodId, ok := mux.Vars(r)["paymentMethodId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing payment method ID"))
+		return
+	}
+
+	user, err := handler.currentUser(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if _, err := handler.customerPaymentMethod(user.StripeCustomerID, paymentMethodId); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err := handler.paymentStore.SetDefaultPaymentMethod(user.StripeCustomerID, paymentMethodId); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"defaultPaymentMethod": paymentMethodId}, nil)
+}
+
+func (handler *PaymentHandler) handleDetachPaymentMethod(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	paymentMethodId, ok := mux.Vars(r)["paymentMethodId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing payment method ID"))
+		return
+	}
+
+	user, err := handler.currentUser(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if _, err := handler.customerPaymentMethod(user.StripeCustomerID, paymentMethodId); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err := handler.paymentStore.DetachPaymentMethod(paymentMethodId); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]bool{"isDetached": true}, nil)
+}
@@ -4,10 +4,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
 
 	"ecom-api/internal/adapters/framework/left/services/auth"
+	"ecom-api/internal/adapters/framework/left/services/idempotency"
+	"ecom-api/internal/adapters/framework/left/services/loyalty"
+	"ecom-api/internal/adapters/framework/left/services/notification"
+	"ecom-api/internal/adapters/framework/left/services/outbox"
+	"ecom-api/internal/adapters/framework/left/services/worker"
+	"ecom-api/internal/application/core/types/entity"
 	"ecom-api/internal/application/core/types/entity/payloads"
 	"ecom-api/internal/ports/right/rports"
 	"ecom-api/pkg/configs"
@@ -15,30 +20,71 @@ import (
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
-	"github.com/stripe/stripe-go"
 	"github.com/stripe/stripe-go/webhook"
 )
 
 type PaymentHandler struct {
-	paymentStore rports.PaymentStore
-	userStore    rports.UserStore
-	orderStore   rports.OrderStore
+	paymentStore      rports.PaymentStore
+	userStore         rports.UserStore
+	orderStore        rports.OrderStore
+	idempotencyStore  rports.IdempotencyStore
+	paymentEventStore rports.PaymentEventStore
+	payoutStore       rports.PayoutStore
+	storeOwnerStore   rports.StoreOwnerStore
+	subscriptionStore rports.SubscriptionStore
+	productStore      rports.ProductStore
+	addressStore      rports.AddressStore
+	creditStore       rports.CreditStore
+	loyalty           *loyalty.Program
+	notifier          *notification.Notifier
+	events            *outbox.Dispatcher
+
+	eventWorker *worker.Poller
 }
 
-func NewPaymentHandler(paymentStore rports.PaymentStore, userStore rports.UserStore, orderStore rports.OrderStore) *PaymentHandler {
-	return &PaymentHandler{paymentStore: paymentStore, userStore: userStore, orderStore: orderStore}
+func NewPaymentHandler(paymentStore rports.PaymentStore, userStore rports.UserStore, orderStore rports.OrderStore, idempotencyStore rports.IdempotencyStore, paymentEventStore rports.PaymentEventStore, payoutStore rports.PayoutStore, storeOwnerStore rports.StoreOwnerStore, subscriptionStore rports.SubscriptionStore, productStore rports.ProductStore, addressStore rports.AddressStore, creditStore rports.CreditStore, loyaltyProgram *loyalty.Program, notifier *notification.Notifier, events *outbox.Dispatcher) *PaymentHandler {
+	return &PaymentHandler{paymentStore: paymentStore, userStore: userStore, orderStore: orderStore, idempotencyStore: idempotencyStore, paymentEventStore: paymentEventStore, payoutStore: payoutStore, storeOwnerStore: storeOwnerStore, subscriptionStore: subscriptionStore, productStore: productStore, addressStore: addressStore, creditStore: creditStore, loyalty: loyaltyProgram, notifier: notifier, events: events, eventWorker: worker.NewPoller()}
 }
 
 func (handler *PaymentHandler) RegisterRoutes(router *mux.Router) {
-	router.HandleFunc("/create/customer", auth.WithJWTAuth(handler.handleCustomerCreation, handler.userStore, "admin", "user")).Methods(http.MethodPost)
-	router.HandleFunc("/customer/{id}", auth.WithJWTAuth(handler.handleGetCustomerById, handler.userStore, "admin")).Methods(http.MethodGet)
-	router.HandleFunc("/customers", auth.WithJWTAuth(handler.handleGetCustomers, handler.userStore, "admin")).Methods(http.MethodGet)
-	router.HandleFunc("customer/delete/{customerId}", auth.WithJWTAuth(handler.handleCustomerDeletion, handler.userStore, "admin", "storeowner")).Methods(http.MethodPost)
-
-	router.HandleFunc("/payment_method/{customerId}", auth.WithJWTAuth(handler.handlePaymentMethodCreation, handler.userStore, "admin", "user")).Methods(http.MethodPost)
-	router.HandleFunc("/charges/{customerId}", auth.WithJWTAuth(handler.handleCustomeChargeProcess, handler.userStore, "admin", "user")).Methods(http.MethodPost)
+	router.HandleFunc("/payment/profile", auth.WithJWTAuth(handler.handleGetPaymentProfile, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodGet)
+	router.HandleFunc("/payment/profile", auth.WithJWTAuth(idempotency.WithIdempotencyKey(handler.handleCreatePaymentProfile, handler.idempotencyStore), handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodPost)
+	router.HandleFunc("/payment/profile/payment_method", auth.WithJWTAuth(handler.handleProfilePaymentMethodCreation, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodPost)
+	router.HandleFunc("/payment/profile/setup_intent", auth.WithJWTAuth(idempotency.WithIdempotencyKey(handler.handleCreateSetupIntent, handler.idempotencyStore), handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodPost)
+	router.HandleFunc("/payment/profile/payment_methods", auth.WithJWTAuth(handler.handleGetPaymentMethods, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodGet)
+	router.HandleFunc("/payment/profile/payment_method/default/{paymentMethodId}", auth.WithJWTAuth(handler.handleSetDefaultPaymentMethod, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodPost)
+	router.HandleFunc("/payment/profile/payment_method/{paymentMethodId}", auth.WithJWTAuth(handler.handleDetachPaymentMethod, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodDelete)
+	router.HandleFunc("/payment/profile/charge", auth.WithJWTAuth(idempotency.WithIdempotencyKey(handler.handleProfileCharge, handler.idempotencyStore), handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodPost)
+
+	router.HandleFunc("/subscriptions", auth.WithJWTAuth(idempotency.WithIdempotencyKey(handler.handleCreateSubscription, handler.idempotencyStore), handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodPost)
+	router.HandleFunc("/subscriptions", auth.WithJWTAuth(handler.handleGetSubscriptions, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodGet)
+	router.HandleFunc("/subscription/{subscriptionId}/pause", auth.WithJWTAuth(handler.handlePauseSubscription, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodPost)
+	router.HandleFunc("/subscription/{subscriptionId}/resume", auth.WithJWTAuth(handler.handleResumeSubscription, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodPost)
+	router.HandleFunc("/subscription/{subscriptionId}/skip", auth.WithJWTAuth(handler.handleSkipSubscription, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodPost)
+	router.HandleFunc("/subscription/{subscriptionId}/quantity", auth.WithJWTAuth(handler.handleUpdateSubscriptionQuantity, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodPut)
+	router.HandleFunc("/subscription/{subscriptionId}", auth.WithJWTAuth(handler.handleCancelSubscription, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodDelete)
+
+	router.HandleFunc("/store/payouts", auth.WithJWTAuth(handler.handleGetStorePayouts, handler.userStore, "admin", "storeowner")).Methods(http.MethodGet)
 
 	router.HandleFunc("/payment/webhook", handler.handlePaymentLiveUpdateThroughWebhook).Methods(http.MethodPost)
+
+	//admin routes, these take raw Stripe customer IDs
+	router.HandleFunc("/create/customer", auth.WithJWTAuth(idempotency.WithIdempotencyKey(handler.handleCustomerCreation, handler.idempotencyStore), handler.userStore, "admin")).Methods(http.MethodPost)
+	router.HandleFunc("/customer/{id}", auth.WithJWTAuth(handler.handleGetCustomerById, handler.userStore, "admin")).Methods(http.MethodGet)
+	router.HandleFunc("/customers", auth.WithJWTAuth(handler.handleGetCustomers, handler.userStore, "admin")).Methods(http.MethodGet)
+	router.HandleFunc("/customer/delete/{customerId}", auth.WithJWTAuth(handler.handleCustomerDeletion, handler.userStore, "admin")).Methods(http.MethodPost)
+	router.HandleFunc("/payment_method/{customerId}", auth.WithJWTAuth(handler.handlePaymentMethodCreation, handler.userStore, "admin")).Methods(http.MethodPost)
+	router.HandleFunc("/charges/{customerId}", auth.WithJWTAuth(idempotency.WithIdempotencyKey(handler.handleCustomeChargeProcess, handler.idempotencyStore), handler.userStore, "admin")).Methods(http.MethodPost)
+	router.HandleFunc("/payment/refund/{paymentIntentId}", auth.WithJWTAuth(idempotency.WithIdempotencyKey(handler.handleRefund, handler.idempotencyStore), handler.userStore, "admin")).Methods(http.MethodPost)
+	router.HandleFunc("/payment/wallet_refund/{orderId}", auth.WithJWTAuth(idempotency.WithIdempotencyKey(handler.handleWalletRefund, handler.idempotencyStore), handler.userStore, "admin")).Methods(http.MethodPost)
+	router.HandleFunc("/store/{storeId}/payout_account", auth.WithJWTAuth(handler.handleSetStorePayoutAccount, handler.userStore, "admin")).Methods(http.MethodPut)
+	router.HandleFunc("/store/{storeId}/payouts/settle", auth.WithJWTAuth(handler.handleSettleStorePayouts, handler.userStore, "admin")).Methods(http.MethodPost)
+	router.HandleFunc("/commission_rule", auth.WithJWTAuth(handler.handleCreateCommissionRule, handler.userStore, "admin")).Methods(http.MethodPost)
+	router.HandleFunc("/commission_rules", auth.WithJWTAuth(handler.handleGetCommissionRules, handler.userStore, "admin")).Methods(http.MethodGet)
+	router.HandleFunc("/commission_rule/{ruleId}", auth.WithJWTAuth(handler.handleDeleteCommissionRule, handler.userStore, "admin")).Methods(http.MethodDelete)
+	router.HandleFunc("/payment/events/failed", auth.WithJWTAuth(handler.handleGetFailedPaymentEvents, handler.userStore, "admin")).Methods(http.MethodGet)
+	router.HandleFunc("/payment/event/{eventId}", auth.WithJWTAuth(handler.handleGetPaymentEvent, handler.userStore, "admin")).Methods(http.MethodGet)
+	router.HandleFunc("/payment/event/replay/{eventId}", auth.WithJWTAuth(handler.handleReplayPaymentEvent, handler.userStore, "admin")).Methods(http.MethodPost)
 }
 
 func (handler *PaymentHandler) handleCustomeChargeProcess(w http.ResponseWriter, r *http.Request) {
@@ -68,13 +114,61 @@ func (handler *PaymentHandler) handleCustomeChargeProcess(w http.ResponseWriter,
 		return
 	}
 
-	charge, err := handler.paymentStore.CreateStripeCharge(&chargeParams, customerId)
+	paymentMethodId, err := handler.chargePaymentMethod(customerId, chargeParams.PaymentMethodID)
+	if err == errPaymentMethodNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
+	chargeParams.PaymentMethodID = paymentMethodId
+
+	charge, err := handler.paymentStore.CreateCharge(&chargeParams, customerId, idempotency.KeyFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, chargeErrorStatus(err), err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, chargeResponse(charge), nil)
+}
+
+func (handler *PaymentHandler) handleRefund(w http.ResponseWriter, r *http.Request) {
+	var refundParams payloads.RefundPayload
+
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	paymentIntentId, ok := mux.Vars(r)["paymentIntentId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing payment intent ID"))
+		return
+	}
+
+	// the body is optional, without it the whole payment is refunded
+	if r.ContentLength > 0 {
+		if err := utils.ParseJSON(r, &refundParams); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	if err := utils.Validate.Struct(refundParams); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors)
+		return
+	}
 
-	utils.WriteJSON(w, http.StatusCreated, map[string]interface{}{"charge": charge.Amount}, nil)
+	refund, err := handler.paymentStore.CreateRefund(paymentIntentId, refundParams.Amount, idempotency.KeyFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, refund, nil)
 }
 
 func (handler *PaymentHandler) handleCustomerCreation(w http.ResponseWriter, r *http.Request) {
@@ -96,11 +190,36 @@ func (handler *PaymentHandler) handleCustomerCreation(w http.ResponseWriter, r *
 		return
 	}
 
-	cus, err := handler.paymentStore.CreateStripeCustomer(&customer)
+	// link the customer to the account with its email, so payments of the
+	// customer can be traced back to the user
+	user, err := handler.userStore.GetUserByEmail(customer.Email)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
+	if user.ID != "" && user.StripeCustomerID != "" {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("%s already has stripe customer %s", customer.Email, user.StripeCustomerID))
+		return
+	}
+	if user.ID != "" {
+		if customer.Metadata == nil {
+			customer.Metadata = map[string]string{}
+		}
+		customer.Metadata["user_id"] = user.ID
+	}
+
+	cus, err := handler.paymentStore.CreateCustomer(&customer, idempotency.KeyFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if user.ID != "" {
+		if _, err := handler.userStore.LinkStripeCustomer(user.ID, cus.ID); err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
 
 	utils.WriteJSON(w, http.StatusCreated, cus, nil)
 }
@@ -119,7 +238,7 @@ func (handler *PaymentHandler) handleGetCustomerById(w http.ResponseWriter, r *h
 		return
 	}
 
-	customer, err := handler.paymentStore.GetStripeCustomer(customerId)
+	customer, err := handler.paymentStore.GetCustomer(customerId)
 
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
@@ -135,7 +254,7 @@ func (handler *PaymentHandler) handleGetCustomers(w http.ResponseWriter, r *http
 		return
 	}
 
-	customerList, err := handler.paymentStore.GetAllStripeCustomers()
+	customerList, err := handler.paymentStore.GetAllCustomers()
 
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
@@ -146,7 +265,6 @@ func (handler *PaymentHandler) handleGetCustomers(w http.ResponseWriter, r *http
 }
 
 func (handler *PaymentHandler) handlePaymentMethodCreation(w http.ResponseWriter, r *http.Request) {
-	// var stripeCard payloads.StripeCardPayload
 	vars := mux.Vars(r)
 
 	if r.Method != http.MethodPost {
@@ -154,6 +272,13 @@ func (handler *PaymentHandler) handlePaymentMethodCreation(w http.ResponseWriter
 		return
 	}
 
+	// card details are only taken through setup intents, the test card is a
+	// fixture for test mode
+	if !configs.Envs.StripeTestMode {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("test cards are only available in test mode, save cards through a setup intent"))
+		return
+	}
+
 	customerId, ok := vars["customerId"]
 
 	if !ok {
@@ -161,40 +286,6 @@ func (handler *PaymentHandler) handlePaymentMethodCreation(w http.ResponseWriter
 		return
 	}
 
-	// if err := utils.ParseJSON(r, &stripeCard); err != nil {
-	// 	utils.WriteError(w, http.StatusBadRequest, err)
-	// 	return
-	// }
-
-	// if err := utils.Validate.Struct(stripeCard); err != nil {
-	// 	errors := err.(validator.ValidationErrors)
-	// 	utils.WriteError(w, http.StatusBadRequest, errors)
-	// 	return
-	// }
-
-	// if !luhn.Valid(stripeCard.Number) {
-	// 	utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("provide valid card number"))
-	// 	return
-	// }
-
-	// expMonth, err := strconv.Atoi(stripeCard.ExpMonth)
-	// if err != nil || expMonth < 1 || expMonth > 12 {
-	// 	utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("provide a valid expiration month (01-12)"))
-	// 	return
-	// }
-
-	// expYear, err := strconv.Atoi(stripeCard.ExpYear)
-	// if err != nil || expYear < time.Now().Year() {
-	// 	utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("expiration year must be this year or later"))
-	// 	return
-	// }
-
-	// currentYear, currentMonth, _ := time.Now().Date()
-	// if expYear == currentYear && expMonth < int(currentMonth) {
-	// 	utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("expiration date must be in the future"))
-	// 	return
-	// }
-
 	paymentMethod, err := handler.paymentStore.CreatePaymentMethod(customerId)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
@@ -227,6 +318,11 @@ func (handler *PaymentHandler) handleCustomerDeletion(w http.ResponseWriter, r *
 		return
 	}
 
+	if err := handler.userStore.UnlinkStripeCustomer(customerId); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
 	utils.WriteJSON(w, http.StatusCreated, map[string]bool{"isDeleted": isDeleted}, nil)
 }
 
@@ -257,139 +353,86 @@ func (handler *PaymentHandler) handlePaymentLiveUpdateThroughWebhook(w http.Resp
 		return
 	}
 
-	switch event.Type {
-	case "customer.created":
-		var customer stripe.Customer
-		if err := json.Unmarshal(event.Data.Raw, &customer); err != nil {
-			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("webhook error: %v", err))
-			return
-		}
-		log.Printf("New customer created: %s", customer.ID)
+	// once the event is safely stored it is acknowledged and the work is left
+	// to the worker
+	if err := handler.QueuePaymentEvent(entity.PaymentEvent{
+		ID:      event.ID,
+		Type:    event.Type,
+		Payload: payload,
+	}); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
 
-	case "payment_method.attached":
-		var paymentMethod stripe.PaymentMethod
-		if err := json.Unmarshal(event.Data.Raw, &paymentMethod); err != nil {
-			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("webhook error: %v", err))
-			return
-		}
-		log.Printf("Payment method attached: %s", paymentMethod.ID)
+	utils.WriteJSON(w, http.StatusOK, nil, nil)
+}
 
-	case "payment_intent.created":
-		var paymentIntent stripe.PaymentIntent
-		if err := json.Unmarshal(event.Data.Raw, &paymentIntent); err != nil {
-			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("webhook error: %v", err))
-			return
-		}
-		log.Printf("PaymentIntent created: %s", paymentIntent.ID)
+func (handler *PaymentHandler) handleGetFailedPaymentEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
 
-	case "payment_intent.succeeded":
-		var paymentIntent stripe.PaymentIntent
-		if err := json.Unmarshal(event.Data.Raw, &paymentIntent); err != nil {
-			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("webhook error: %v", err))
-			return
-		}
-		log.Printf("PaymentIntent succeeded: %s", paymentIntent.ID)
+	events, err := handler.paymentEventStore.GetPaymentEventsByStatus(entity.PaymentEventFailed, entity.PaymentEventDead)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
 
-	case "charge.succeeded":
-		var charge stripe.Charge
-		if err := json.Unmarshal(event.Data.Raw, &charge); err != nil {
-			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("webhook error: %v", err))
-			return
-		}
-		log.Printf("Charge succeeded for charge ID: %s, amount: %d", charge.ID, charge.Amount)
-		userID := auth.GetUserIDFromContext(r.Context())
-		user, err := handler.userStore.GetUserByID(userID)
-		if err != nil {
-			utils.WriteError(w, http.StatusInternalServerError, err)
-			return
-		}
-		HTMLTemplateEmailHandler(w, r, user.Email, map[string]string{
-			"username": user.FirstName + " " + user.LastName,
-			"email":    configs.Envs.FromEmail,
-			"address":  "USA, New York, 123 wall street, Apt:10032, 143B",
-		})
-
-	case "payment_intent.payment_failed":
-		var paymentIntent stripe.PaymentIntent
-		if err := json.Unmarshal(event.Data.Raw, &paymentIntent); err != nil {
-			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("webhook error: %v", err))
-			return
-		}
-		log.Printf("PaymentIntent failed: %s, error: %v", paymentIntent.ID, paymentIntent.LastPaymentError)
+	utils.WriteJSON(w, http.StatusOK, events, nil)
+}
 
-	case "payment_method.detached":
-		var paymentMethod stripe.PaymentMethod
-		if err := json.Unmarshal(event.Data.Raw, &paymentMethod); err != nil {
-			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("webhook error: %v", err))
-			return
-		}
-		log.Printf("Payment method detached: %s", paymentMethod.ID)
+func (handler *PaymentHandler) handleGetPaymentEvent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
 
-	case "checkout.session.completed":
-		var session stripe.CheckoutSession
-		if err := json.Unmarshal(event.Data.Raw, &session); err != nil {
-			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("webhook error: %v", err))
-			return
-		}
-		log.Printf("Checkout session completed: %s", session.ID)
-		// You could fulfill the order, update database, and send a confirmation email.
-
-		// -------- checkout session completion actions --------------
-		/*
-			orderID := session.Metadata["order_id"] // Extract order ID
-
-			if orderID == "" {
-				utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("order ID missing in metadata"))
-				return
-			}
-
-			err := handler.orderStore.UpdateOrderPaymentStatus(orderID, configs.Envs.PaymentStatusPaid)
-			if err != nil {
-				utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to update order status: %v", err))
-				return
-			}
-
-			userID, ok := r.Context().Value("userID").(string)
-			if !ok {
-				utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("user ID not found in context"))
-				return
-			}
-
-			user, err := handler.userStore.GetUserByID(userID)
-			if err != nil {
-				utils.WriteError(w, http.StatusInternalServerError, err)
-				return
-			}
-
-			HTMLTemplateEmailHandler(w, r, user.Email, map[string]string{
-				"username": user.FirstName + user.LastName,
-				"email":    user.Email,
-				"address":  "USA, New York, 123 wall street, Apt:10032, 143B",
-			})
-		*/
-
-	case "checkout.session.expired":
-		var session stripe.CheckoutSession
-		if err := json.Unmarshal(event.Data.Raw, &session); err != nil {
-			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("webhook error: %v", err))
-			return
-		}
-		log.Printf("Checkout session expired: %s", session.ID)
-		// Handle expired sessions, such as notifying the customer.
+	eventId, ok := mux.Vars(r)["eventId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing event ID"))
+		return
+	}
 
-	case "checkout.session.payment_failed":
-		var session stripe.CheckoutSession
-		if err := json.Unmarshal(event.Data.Raw, &session); err != nil {
-			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("webhook error: %v", err))
-			return
-		}
-		log.Printf("Payment failed for session: %s", session.ID)
-		// Handle failed payments, like sending an email to retry payment
+	event, err := handler.paymentEventStore.GetPaymentEventByID(eventId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if event.ID == "" {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("payment event %s not found", eventId))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"event":   event,
+		"payload": json.RawMessage(event.Payload),
+	}, nil)
+}
 
-	default:
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unhandled event type: %s", event.Type))
+func (handler *PaymentHandler) handleReplayPaymentEvent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, nil, nil)
+	eventId, ok := mux.Vars(r)["eventId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing event ID"))
+		return
+	}
+
+	replayed, err := handler.paymentEventStore.ReplayPaymentEvent(eventId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !replayed {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("payment event %s is not a failed event", eventId))
+		return
+	}
+
+	handler.eventWorker.Wake()
+
+	utils.WriteJSON(w, http.StatusAccepted, map[string]bool{"replayed": true}, nil)
 }
@@ -0,0 +1,138 @@
+package payment
+
+import (
+	"fmt"
+	"net/http"
+
+	"ecom-api/internal/adapters/framework/left/services/notification"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/application/core/types/entity/payloads"
+	"ecom-api/pkg/configs"
+	"ecom-api/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+// markOrderRefunded records that the whole order was given back, along with
+// its order.status_changed event and the email telling the buyer, then takes
+// back what its stores were owed and the points it earned.
+func (handler *PaymentHandler) markOrderRefunded(orderID string) error {
+	order, err := handler.orderStore.GetOrderByID(orderID)
+	if err != nil {
+		return err
+	}
+	order.PaymentStatus = configs.Envs.PaymentStatusRefunded
+	order.Status = configs.Envs.OrderStatusRefunded
+
+	event, err := entity.NewOrderEvent(entity.TopicOrderStatusChanged, *order)
+	if err != nil {
+		return err
+	}
+	messages := []entity.OutboxMessage{event}
+	email, err := handler.notifier.OrderEmail(notification.OrderRefunded, *order, notification.OrderRefunded+":"+orderID, nil)
+	if err != nil {
+		return err
+	}
+	if email != nil {
+		messages = append(messages, *email)
+	}
+
+	if _, err := handler.orderStore.RefundOrder(orderID, messages...); err != nil {
+		return err
+	}
+	handler.events.Wake()
+
+	// both are safe to run again, a retried refund finishes what a failed one
+	// left
+	if err := handler.reverseStorePayouts(orderID); err != nil {
+		return err
+	}
+	return handler.loyalty.OrderRefunded(orderID)
+}
+
+// handleWalletRefund refunds a paid order as store credit, into the wallet of
+// its buyer instead of onto the card. An order can be refunded a part at a
+// time, on the card or into the wallet, refunding what is left of it refunds
+// the order.
+func (handler *PaymentHandler) handleWalletRefund(w http.ResponseWriter, r *http.Request) {
+	var refundParams payloads.RefundPayload
+
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	orderID, ok := mux.Vars(r)["orderId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing order ID"))
+		return
+	}
+
+	// the body is optional, without it what is left of the order is refunded
+	if r.ContentLength > 0 {
+		if err := utils.ParseJSON(r, &refundParams); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	if err := utils.Validate.Struct(refundParams); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors)
+		return
+	}
+
+	order, err := handler.orderStore.GetOrderByID(orderID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if order.ID == "" {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("order %s not found", orderID))
+		return
+	}
+	if order.UserID == "" {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("guest orders have no wallet, refund the payment instead"))
+		return
+	}
+	if order.PaymentStatus != configs.Envs.PaymentStatusPaid {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("order %s is %s, only paid orders can be refunded", orderID, order.PaymentStatus))
+		return
+	}
+
+	// the zero amount refunds what is left
+	amount := entity.NewMoney(refundParams.Amount, order.Currency)
+	entryID, refundable, err := handler.creditStore.RefundOrderToWallet(order.ID, amount)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if entryID == "" {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("only %s %s of order %s is left to refund", refundable, refundable.Currency, orderID))
+		return
+	}
+	if amount.IsZero() {
+		amount = refundable
+	}
+
+	if amount == refundable {
+		if err := handler.markOrderRefunded(order.ID); err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	balance, err := handler.creditStore.GetWalletBalance(order.UserID, order.Currency)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, map[string]interface{}{
+		"orderId":       order.ID,
+		"walletEntryId": entryID,
+		"refunded":      amount,
+		"walletBalance": balance,
+	}, nil)
+}
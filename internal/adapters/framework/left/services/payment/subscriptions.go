@@ -0,0 +1,475 @@
+package payment
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"ecom-api/internal/adapters/framework/left/services/idempotency"
+	"ecom-api/internal/application/core/fulfilment"
+	"ecom-api/internal/application/core/subscription"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/application/core/types/entity/payloads"
+	"ecom-api/pkg/configs"
+	"ecom-api/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/stripe/stripe-go"
+)
+
+// errSubscriptionNotFound is returned for subscriptions of other users too, so
+// they cannot be probed.
+var errSubscriptionNotFound = fmt.Errorf("subscription not found")
+
+// createRenewalOrder turns a paid subscription invoice into an order, shipped
+// and paid out to its store like any other. Safe to run again for the same
+// invoice, an invoice has one order and the parts already made are kept.
+func (handler *PaymentHandler) createRenewalOrder(invoice stripe.Invoice) error {
+	if invoice.Subscription == nil || invoice.Subscription.ID == "" {
+		log.Printf("Invoice %s is not for a subscription, ignoring", invoice.ID)
+		return nil
+	}
+	// invoices for proration or metered usage deliver nothing
+	if invoice.BillingReason != stripe.InvoiceBillingReasonSubscriptionCreate && invoice.BillingReason != stripe.InvoiceBillingReasonSubscriptionCycle {
+		log.Printf("Invoice %s was billed for %s, no renewal order", invoice.ID, invoice.BillingReason)
+		return nil
+	}
+	if invoice.AmountPaid == 0 {
+		log.Printf("Nothing was paid on invoice %s, no renewal order", invoice.ID)
+		return nil
+	}
+
+	sub, err := handler.subscriptionStore.GetSubscriptionByPaymentID(invoice.Subscription.ID)
+	if err != nil {
+		return err
+	}
+	// the first invoice can be paid before the subscription is saved, the retry
+	// finds it
+	if sub.ID == "" {
+		return fmt.Errorf("subscription %s is not known yet", invoice.Subscription.ID)
+	}
+
+	order, err := handler.orderStore.GetOrderByInvoiceID(invoice.ID)
+	if err != nil {
+		return err
+	}
+
+	renewal, item, err := subscription.RenewalOrder(*sub, entity.NewMoney(invoice.AmountPaid, strings.ToUpper(string(invoice.Currency))), invoice.ID)
+	if err != nil {
+		return err
+	}
+
+	if order.ID == "" {
+		renewal.Status = configs.Envs.OrderStatusProcessing
+		renewal.PaymentStatus = configs.Envs.PaymentStatusPaid
+		if _, err := handler.orderStore.CreateOrder(renewal); err != nil {
+			return fmt.Errorf("failed to create renewal order: %v", err)
+		}
+		// read back by invoice, which is unique, rather than trust the latest order
+		if order, err = handler.orderStore.GetOrderByInvoiceID(invoice.ID); err != nil {
+			return err
+		}
+		log.Printf("Renewal order %s created for subscription %s", order.ID, sub.ID)
+	}
+
+	items, err := handler.orderStore.GetOrderItemsByOrderId(order.ID)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		item.OrderID = order.ID
+		if err := handler.orderStore.CreateOrderItem(item); err != nil {
+			return err
+		}
+
+		storeOrders, err := fulfilment.Split(*order, []*entity.OrderItem{&item})
+		if err != nil {
+			return err
+		}
+		for _, storeOrder := range storeOrders {
+			if _, err := handler.orderStore.CreateStoreOrder(storeOrder); err != nil {
+				return err
+			}
+		}
+
+		// the renewal is paid for, running short is for the store to sort out
+		if err := handler.productStore.DecreaseProductStock(sub.ProductID, sub.Quantity); err != nil {
+			log.Printf("failed to take the stock of renewal order %s: %v", order.ID, err)
+		} else {
+			handler.events.PublishProducts(handler.productStore, entity.TopicProductStockChanged, sub.ProductID)
+		}
+	}
+
+	if err := handler.loyalty.OrderPaid(order.ID); err != nil {
+		return err
+	}
+
+	if invoice.Charge == nil || invoice.Charge.ID == "" {
+		return nil
+	}
+	return handler.recordStorePayouts(order.ID, stripe.Charge{ID: invoice.Charge.ID})
+}
+
+// syncSubscription copies the status and next renewal of a subscription from
+// the gateway, which is where renewals fail, skips end and cancellations made
+// on the dashboard happen.
+func (handler *PaymentHandler) syncSubscription(paymentSubscriptionID string) error {
+	sub, err := handler.subscriptionStore.GetSubscriptionByPaymentID(paymentSubscriptionID)
+	if err != nil {
+		return err
+	}
+	if sub.ID == "" {
+		log.Printf("Subscription %s is not one of ours, ignoring", paymentSubscriptionID)
+		return nil
+	}
+
+	payment, err := handler.paymentStore.GetSubscription(paymentSubscriptionID)
+	if err != nil {
+		return err
+	}
+	applyPaymentSubscription(sub, payment)
+	return handler.subscriptionStore.UpdateSubscription(*sub)
+}
+
+func applyPaymentSubscription(sub *entity.Subscription, payment *entity.PaymentSubscription) {
+	sub.Status = payment.Status
+	sub.RenewsAt = payment.RenewsAt
+}
+
+// subscriptionAddress picks where renewals are shipped: the saved address of
+// the payload, its inline address, or the user's default shipping address.
+func (handler *PaymentHandler) subscriptionAddress(params payloads.SubscriptionPayload, userID string) (*entity.OrderAddress, error) {
+	if params.ShippingAddressID != "" {
+		address, err := handler.addressStore.GetAddressByID(params.ShippingAddressID)
+		if err != nil || address.UserID != userID {
+			return nil, fmt.Errorf("address %s not found", params.ShippingAddressID)
+		}
+		snapshot := address.Snapshot()
+		return &snapshot, nil
+	}
+
+	if params.ShippingAddress != nil {
+		return &entity.OrderAddress{FullName: params.ShippingAddress.FullName, Phone: params.ShippingAddress.Phone, Address: entity.Address(params.ShippingAddress.Address)}, nil
+	}
+
+	address, err := handler.addressStore.GetDefaultShippingAddress(userID)
+	if err != nil || address == nil {
+		return nil, fmt.Errorf("no shipping address, add one or save a default one")
+	}
+	snapshot := address.Snapshot()
+	return &snapshot, nil
+}
+
+// userSubscription returns the subscription of the request when it belongs to
+// the user, or the user is an admin.
+func (handler *PaymentHandler) userSubscription(r *http.Request) (*entity.Subscription, error) {
+	user, err := handler.currentUser(r)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := handler.subscriptionStore.GetSubscriptionByID(mux.Vars(r)["subscriptionId"])
+	if err != nil {
+		return nil, err
+	}
+	if sub.ID == "" || (sub.UserID != user.ID && user.Role != "admin") {
+		return nil, errSubscriptionNotFound
+	}
+	return sub, nil
+}
+
+// changeSubscription runs change on the subscription of the request when it is
+// in one of the statuses allowed, and saves what the gateway made of it.
+func (handler *PaymentHandler) changeSubscription(w http.ResponseWriter, r *http.Request, allowed []string, change func(sub *entity.Subscription) (*entity.PaymentSubscription, error)) {
+	sub, err := handler.userSubscription(r)
+	if err == errSubscriptionNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	permitted := false
+	for _, status := range allowed {
+		permitted = permitted || sub.Status == status
+	}
+	if !permitted {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("subscription is %s", sub.Status))
+		return
+	}
+
+	payment, err := change(sub)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadGateway, err)
+		return
+	}
+	applyPaymentSubscription(sub, payment)
+
+	if err := handler.subscriptionStore.UpdateSubscription(*sub); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, sub, nil)
+}
+
+func (handler *PaymentHandler) handleCreateSubscription(w http.ResponseWriter, r *http.Request) {
+	var subscriptionParams payloads.SubscriptionPayload
+
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	if err := utils.ParseJSON(r, &subscriptionParams); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(subscriptionParams); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors)
+		return
+	}
+
+	user, err := handler.currentUser(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	product, err := handler.productStore.GetProductByID(subscriptionParams.ProductID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if product.ProductId == "" || !product.IsActive {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("product %s not found", subscriptionParams.ProductID))
+		return
+	}
+	if !product.IsSubscribable {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("product %s cannot be subscribed to", product.ProductId))
+		return
+	}
+	if product.Quantity < subscriptionParams.Quantity {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("not enough stock: available %d, requested %d", product.Quantity, subscriptionParams.Quantity))
+		return
+	}
+
+	address, err := handler.subscriptionAddress(subscriptionParams, user.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	customerId, err := handler.stripeCustomerFor(user, payloads.CustomerPayload{}, "")
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	paymentMethodId, err := handler.chargePaymentMethod(customerId, subscriptionParams.PaymentMethodID)
+	if err == errPaymentMethodNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if paymentMethodId == "" {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("no payment method to charge, save a card first"))
+		return
+	}
+
+	sub := entity.Subscription{
+		UserID:          user.ID,
+		ProductID:       product.ProductId,
+		StoreID:         product.StoreID,
+		Category:        product.Category,
+		ProductName:     product.Name,
+		Quantity:        subscriptionParams.Quantity,
+		UnitPrice:       product.Price,
+		Currency:        product.Price.Currency,
+		Interval:        product.BillingInterval,
+		IntervalCount:   product.BillingIntervalCount,
+		Status:          entity.SubscriptionIncomplete,
+		ShippingAddress: *address,
+	}
+	sub.ID, err = handler.subscriptionStore.CreateSubscription(sub)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	payment, err := handler.paymentStore.CreateSubscription(customerId, entity.SubscriptionPlan{
+		ProductID:       sub.ProductID,
+		ProductName:     sub.ProductName,
+		UnitPrice:       sub.UnitPrice,
+		Interval:        sub.Interval,
+		IntervalCount:   sub.IntervalCount,
+		Quantity:        sub.Quantity,
+		PaymentMethodID: paymentMethodId,
+		Metadata:        map[string]string{"subscription_id": sub.ID, "user_id": user.ID},
+	}, idempotency.KeyFromContext(r.Context()))
+	if err != nil {
+		sub.Status = entity.SubscriptionCancelled
+		if err := handler.subscriptionStore.UpdateSubscription(sub); err != nil {
+			log.Printf("failed to cancel subscription %s: %v", sub.ID, err)
+		}
+		utils.WriteError(w, chargeErrorStatus(err), err)
+		return
+	}
+
+	sub.PaymentSubscriptionID = payment.ID
+	applyPaymentSubscription(&sub, payment)
+	if err := handler.subscriptionStore.UpdateSubscription(sub); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := map[string]interface{}{"subscription": sub}
+	// the first payment needs 3D Secure before the subscription is active
+	if payment.ClientSecret != "" {
+		response["clientSecret"] = payment.ClientSecret
+	}
+	utils.WriteJSON(w, http.StatusCreated, response, nil)
+}
+
+func (handler *PaymentHandler) handleGetSubscriptions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	user, err := handler.currentUser(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	subscriptions, err := handler.subscriptionStore.GetSubscriptionsByUserID(user.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if subscriptions == nil {
+		subscriptions = []*entity.Subscription{}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, subscriptions, nil)
+}
+
+func (handler *PaymentHandler) handlePauseSubscription(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	handler.changeSubscription(w, r, []string{entity.SubscriptionActive, entity.SubscriptionPastDue}, func(sub *entity.Subscription) (*entity.PaymentSubscription, error) {
+		return handler.paymentStore.PauseSubscription(sub.PaymentSubscriptionID, true)
+	})
+}
+
+func (handler *PaymentHandler) handleResumeSubscription(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	handler.changeSubscription(w, r, []string{entity.SubscriptionPaused}, func(sub *entity.Subscription) (*entity.PaymentSubscription, error) {
+		return handler.paymentStore.PauseSubscription(sub.PaymentSubscriptionID, false)
+	})
+}
+
+// handleSkipSubscription skips the next renewal, the one after it is charged
+// and shipped as usual.
+func (handler *PaymentHandler) handleSkipSubscription(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	handler.changeSubscription(w, r, []string{entity.SubscriptionActive}, func(sub *entity.Subscription) (*entity.PaymentSubscription, error) {
+		next := time.Now()
+		if sub.RenewsAt != nil && sub.RenewsAt.After(next) {
+			next = *sub.RenewsAt
+		}
+		until, err := subscription.NextRenewal(next, sub.Interval, sub.IntervalCount)
+		if err != nil {
+			return nil, err
+		}
+		return handler.paymentStore.SkipSubscriptionRenewal(sub.PaymentSubscriptionID, until)
+	})
+}
+
+func (handler *PaymentHandler) handleUpdateSubscriptionQuantity(w http.ResponseWriter, r *http.Request) {
+	var quantityParams payloads.SubscriptionQuantityPayload
+
+	if r.Method != http.MethodPut {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	if err := utils.ParseJSON(r, &quantityParams); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(quantityParams); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors)
+		return
+	}
+
+	handler.changeSubscription(w, r, []string{entity.SubscriptionActive, entity.SubscriptionPaused, entity.SubscriptionPastDue}, func(sub *entity.Subscription) (*entity.PaymentSubscription, error) {
+		payment, err := handler.paymentStore.UpdateSubscriptionQuantity(sub.PaymentSubscriptionID, quantityParams.Quantity)
+		if err != nil {
+			return nil, err
+		}
+		sub.Quantity = quantityParams.Quantity
+		return payment, nil
+	})
+}
+
+func (handler *PaymentHandler) handleCancelSubscription(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	sub, err := handler.userSubscription(r)
+	if err == errSubscriptionNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if sub.Status == entity.SubscriptionCancelled {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("subscription is %s", sub.Status))
+		return
+	}
+
+	if sub.PaymentSubscriptionID != "" {
+		if err := handler.paymentStore.CancelSubscription(sub.PaymentSubscriptionID); err != nil {
+			utils.WriteError(w, http.StatusBadGateway, err)
+			return
+		}
+	}
+
+	sub.Status = entity.SubscriptionCancelled
+	sub.RenewsAt = nil
+	if err := handler.subscriptionStore.UpdateSubscription(*sub); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "subscription cancelled"}, nil)
+}
@@ -0,0 +1,241 @@
+package pricing
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"ecom-api/internal/adapters/framework/left/services/auth"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/application/core/types/entity/payloads"
+	"ecom-api/internal/ports/right/rports"
+	"ecom-api/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type PricingHandler struct {
+	priceListStore rports.PriceListStore
+	rateStore      rports.ExchangeRateStore
+	productStore   rports.ProductStore
+	userStore      rports.UserStore
+}
+
+func NewPricingHandler(priceListStore rports.PriceListStore, rateStore rports.ExchangeRateStore, productStore rports.ProductStore, userStore rports.UserStore) *PricingHandler {
+	return &PricingHandler{
+		priceListStore: priceListStore,
+		rateStore:      rateStore,
+		productStore:   productStore,
+		userStore:      userStore,
+	}
+}
+
+func (handler *PricingHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/product/prices/{productId}", handler.handleGetProductPrices).Methods(http.MethodGet)
+	router.HandleFunc("/product/price/{productId}/{currency}", auth.WithJWTAuth(handler.handleSetProductPrice, handler.userStore, "admin", "storeowner")).Methods(http.MethodPut)
+	router.HandleFunc("/product/price/{productId}/{currency}", auth.WithJWTAuth(handler.handleDeleteProductPrice, handler.userStore, "admin", "storeowner")).Methods(http.MethodDelete)
+
+	//admin routes
+	router.HandleFunc("/exchangerates", auth.WithJWTAuth(handler.handleGetExchangeRates, handler.userStore, "admin")).Methods(http.MethodGet)
+	router.HandleFunc("/exchangerate/{base}/{quote}", auth.WithJWTAuth(handler.handleSetExchangeRate, handler.userStore, "admin")).Methods(http.MethodPut)
+	router.HandleFunc("/exchangerate/{base}/{quote}", auth.WithJWTAuth(handler.handleDeleteExchangeRate, handler.userStore, "admin")).Methods(http.MethodDelete)
+}
+
+func (handler *PricingHandler) handleGetProductPrices(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	vars := mux.Vars(r)
+	productId, ok := vars["productId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing product ID"))
+		return
+	}
+
+	prices, err := handler.priceListStore.GetProductPrices(productId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, prices, nil)
+}
+
+func (handler *PricingHandler) handleSetProductPrice(w http.ResponseWriter, r *http.Request) {
+	var payload payloads.ProductPricePayload
+
+	if r.Method != http.MethodPut {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	vars := mux.Vars(r)
+	productId, currency, err := productCurrencyVars(vars)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", errors))
+		return
+	}
+
+	product, err := handler.productStore.GetProductByID(productId)
+	if err != nil || product.ProductId == "" {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("product %s not found", productId))
+		return
+	}
+
+	price := entity.ProductPrice{
+		ProductID: productId,
+		Price:     entity.MoneyFromMajor(payload.Price, currency),
+	}
+
+	if err := handler.priceListStore.SetProductPrice(price); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, price, nil)
+}
+
+func (handler *PricingHandler) handleDeleteProductPrice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	vars := mux.Vars(r)
+	productId, currency, err := productCurrencyVars(vars)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := handler.priceListStore.DeleteProductPrice(productId, currency); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "product price deleted"}, nil)
+}
+
+func (handler *PricingHandler) handleGetExchangeRates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	rates, err := handler.rateStore.GetAllExchangeRates()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, rates, nil)
+}
+
+func (handler *PricingHandler) handleSetExchangeRate(w http.ResponseWriter, r *http.Request) {
+	var payload payloads.ExchangeRatePayload
+
+	if r.Method != http.MethodPut {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	base, quote, err := currencyPairVars(mux.Vars(r))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", errors))
+		return
+	}
+
+	rate := entity.ExchangeRate{Base: base, Quote: quote, Rate: payload.Rate}
+
+	if err := handler.rateStore.SetExchangeRate(rate); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, rate, nil)
+}
+
+func (handler *PricingHandler) handleDeleteExchangeRate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	base, quote, err := currencyPairVars(mux.Vars(r))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := handler.rateStore.DeleteExchangeRate(base, quote); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "exchange rate deleted"}, nil)
+}
+
+func productCurrencyVars(vars map[string]string) (string, string, error) {
+	productId, ok := vars["productId"]
+	if !ok {
+		return "", "", fmt.Errorf("missing product ID")
+	}
+
+	currency, err := currencyVar(vars, "currency")
+	if err != nil {
+		return "", "", err
+	}
+
+	return productId, currency, nil
+}
+
+func currencyPairVars(vars map[string]string) (string, string, error) {
+	base, err := currencyVar(vars, "base")
+	if err != nil {
+		return "", "", err
+	}
+
+	quote, err := currencyVar(vars, "quote")
+	if err != nil {
+		return "", "", err
+	}
+
+	if base == quote {
+		return "", "", fmt.Errorf("base and quote currencies must differ")
+	}
+
+	return base, quote, nil
+}
+
+func currencyVar(vars map[string]string, name string) (string, error) {
+	currency := strings.ToUpper(vars[name])
+	if utils.Validate.Var(currency, "len=3,alpha") != nil {
+		return "", fmt.Errorf("invalid %s currency %q, expected an ISO 4217 code", name, vars[name])
+	}
+	return currency, nil
+}
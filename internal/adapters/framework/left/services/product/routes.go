@@ -7,6 +7,8 @@ import (
 	"strings"
 
 	"ecom-api/internal/adapters/framework/left/services/auth"
+	"ecom-api/internal/adapters/framework/left/services/outbox"
+	"ecom-api/internal/application/core/types/entity"
 	"ecom-api/internal/application/core/types/entity/payloads"
 	"ecom-api/internal/ports/right/rports"
 	"ecom-api/utils"
@@ -18,10 +20,11 @@ import (
 type ProductHandler struct {
 	store     rports.ProductStore
 	userStore rports.UserStore
+	events    *outbox.Dispatcher
 }
 
-func NewProductHandler(store rports.ProductStore, userStore rports.UserStore) *ProductHandler {
-	return &ProductHandler{store: store, userStore: userStore}
+func NewProductHandler(store rports.ProductStore, userStore rports.UserStore, events *outbox.Dispatcher) *ProductHandler {
+	return &ProductHandler{store: store, userStore: userStore, events: events}
 }
 
 func (handler *ProductHandler) RegisterRoutes(router *mux.Router) {
@@ -165,11 +168,23 @@ func (handler *ProductHandler) handleDeleteProduct(w http.ResponseWriter, r *htt
 		return
 	}
 
-	err := handler.store.DeleteProductByID(productId)
+	// the event holds the product as it was before it went
+	product, err := handler.store.GetProductByID(productId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if product.ProductId == "" {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("product not found"))
+		return
+	}
+
+	err = handler.store.DeleteProductByID(productId)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
+	handler.events.PublishProduct(entity.TopicProductDeleted, *product)
 
 	utils.WriteJSON(w, http.StatusNoContent, productId, nil)
 }
@@ -199,6 +214,7 @@ func (handler *ProductHandler) handleUpdateProductById(w http.ResponseWriter, r
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
+	handler.events.PublishProducts(handler.store, entity.TopicProductUpdated, productId)
 
 	utils.WriteJSON(w, http.StatusCreated, productId, nil)
 }
@@ -295,6 +311,7 @@ func (handler *ProductHandler) handleProductDeactivation(w http.ResponseWriter,
 		utils.WriteError(w, http.StatusBadRequest, err)
 		return
 	}
+	handler.events.PublishProducts(handler.store, entity.TopicProductUpdated, productId)
 
 	utils.WriteJSON(w, http.StatusCreated, map[string]bool{"success": true}, nil)
 
@@ -318,6 +335,7 @@ func (handler *ProductHandler) handleProductActivation(w http.ResponseWriter, r
 		utils.WriteError(w, http.StatusBadRequest, err)
 		return
 	}
+	handler.events.PublishProducts(handler.store, entity.TopicProductUpdated, productId)
 
 	utils.WriteJSON(w, http.StatusCreated, map[string]bool{"success": true}, nil)
 
@@ -350,6 +368,7 @@ func (handler *ProductHandler) handleDecrementProductStock(w http.ResponseWriter
 		utils.WriteError(w, http.StatusBadRequest, err)
 		return
 	}
+	handler.events.PublishProducts(handler.store, entity.TopicProductStockChanged, productId)
 
 	utils.WriteJSON(w, http.StatusCreated, map[string]bool{"success": true}, nil)
 }
@@ -381,6 +400,7 @@ func (handler *ProductHandler) handleIncrementProductStock(w http.ResponseWriter
 		utils.WriteError(w, http.StatusBadRequest, err)
 		return
 	}
+	handler.events.PublishProducts(handler.store, entity.TopicProductStockChanged, productId)
 
 	utils.WriteJSON(w, http.StatusCreated, map[string]bool{"success": true}, nil)
 }
@@ -413,6 +433,7 @@ func (handler *ProductHandler) handleUpdateProductQuatity(w http.ResponseWriter,
 		utils.WriteError(w, http.StatusBadRequest, err)
 		return
 	}
+	handler.events.PublishProducts(handler.store, entity.TopicProductStockChanged, productId)
 
 	utils.WriteJSON(w, http.StatusCreated, map[string]bool{"success": true}, nil)
 }
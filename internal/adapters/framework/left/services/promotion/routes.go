@@ -0,0 +1,233 @@
+package promotion
+
+import (
+	"fmt"
+	"net/http"
+
+	"ecom-api/internal/adapters/framework/left/services/auth"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/application/core/types/entity/payloads"
+	"ecom-api/internal/ports/right/rports"
+	"ecom-api/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type PromotionHandler struct {
+	store     rports.PromotionStore
+	userStore rports.UserStore
+}
+
+func NewPromotionHandler(store rports.PromotionStore, userStore rports.UserStore) *PromotionHandler {
+	return &PromotionHandler{store: store, userStore: userStore}
+}
+
+func (handler *PromotionHandler) RegisterRoutes(router *mux.Router) {
+	//admin routes
+	router.HandleFunc("/promotions", auth.WithJWTAuth(handler.handleGetPromotions, handler.userStore, "admin")).Methods(http.MethodGet)
+	router.HandleFunc("/promotions", auth.WithJWTAuth(handler.handleCreatePromotion, handler.userStore, "admin")).Methods(http.MethodPost)
+	router.HandleFunc("/promotion/{promotionId}", auth.WithJWTAuth(handler.handleGetPromotion, handler.userStore, "admin")).Methods(http.MethodGet)
+	router.HandleFunc("/promotion/{promotionId}", auth.WithJWTAuth(handler.handleUpdatePromotion, handler.userStore, "admin")).Methods(http.MethodPut)
+	router.HandleFunc("/promotion/activate/{promotionId}", auth.WithJWTAuth(handler.handlePromotionActivation, handler.userStore, "admin")).Methods(http.MethodPut)
+	router.HandleFunc("/promotion/deactivate/{promotionId}", auth.WithJWTAuth(handler.handlePromotionDeactivation, handler.userStore, "admin")).Methods(http.MethodPut)
+}
+
+func (handler *PromotionHandler) handleGetPromotions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	promotions, err := handler.store.GetAllPromotions()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, promotions, nil)
+}
+
+func (handler *PromotionHandler) handleCreatePromotion(w http.ResponseWriter, r *http.Request) {
+	var payload payloads.PromotionPayload
+
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := validatePromotionPayload(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	promotion := promotionFromPayload(payload)
+
+	promotionId, err := handler.store.CreatePromotion(promotion)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	promotion.ID = promotionId
+
+	utils.WriteJSON(w, http.StatusCreated, promotion, nil)
+}
+
+func (handler *PromotionHandler) handleGetPromotion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	vars := mux.Vars(r)
+	promotionId, ok := vars["promotionId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing promotion ID"))
+		return
+	}
+
+	promotion, err := handler.store.GetPromotionByID(promotionId)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, promotion, nil)
+}
+
+func (handler *PromotionHandler) handleUpdatePromotion(w http.ResponseWriter, r *http.Request) {
+	var payload payloads.PromotionPayload
+
+	if r.Method != http.MethodPut {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	vars := mux.Vars(r)
+	promotionId, ok := vars["promotionId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing promotion ID"))
+		return
+	}
+
+	existing, err := handler.store.GetPromotionByID(promotionId)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := validatePromotionPayload(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	promotion := promotionFromPayload(payload)
+	promotion.ID = existing.ID
+	promotion.UsageCount = existing.UsageCount
+
+	if err := handler.store.UpdatePromotion(promotion); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, promotion, nil)
+}
+
+func (handler *PromotionHandler) handlePromotionActivation(w http.ResponseWriter, r *http.Request) {
+	handler.setPromotionActive(w, r, true)
+}
+
+func (handler *PromotionHandler) handlePromotionDeactivation(w http.ResponseWriter, r *http.Request) {
+	handler.setPromotionActive(w, r, false)
+}
+
+func (handler *PromotionHandler) setPromotionActive(w http.ResponseWriter, r *http.Request, isActive bool) {
+	if r.Method != http.MethodPut {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	vars := mux.Vars(r)
+	promotionId, ok := vars["promotionId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing promotion ID"))
+		return
+	}
+
+	if err := handler.store.SetPromotionActive(promotionId, isActive); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{"promotionId": promotionId, "isActive": isActive}, nil)
+}
+
+func validatePromotionPayload(payload payloads.PromotionPayload) error {
+	if err := utils.Validate.Struct(payload); err != nil {
+		return err.(validator.ValidationErrors)
+	}
+
+	switch payload.Type {
+	case entity.PromotionTypePercentage:
+		if payload.Value <= 0 || payload.Value > 100 {
+			return fmt.Errorf("percentage must be between 0 and 100")
+		}
+	case entity.PromotionTypeFixedAmount:
+		if payload.Value <= 0 || payload.Currency == "" {
+			return fmt.Errorf("fixed amount promotions need a positive value and a currency")
+		}
+	case entity.PromotionTypeBuyXGetY:
+		if payload.BuyQuantity <= 0 || payload.GetQuantity <= 0 || payload.Value > 100 {
+			return fmt.Errorf("buy x get y promotions need buyQuantity, getQuantity and a percentage up to 100")
+		}
+	}
+
+	if payload.MinSpend > 0 && payload.Currency == "" {
+		return fmt.Errorf("a minimum spend needs a currency")
+	}
+
+	if payload.StartsAt != nil && payload.EndsAt != nil && !payload.EndsAt.After(*payload.StartsAt) {
+		return fmt.Errorf("endsAt must be after startsAt")
+	}
+
+	return nil
+}
+
+func promotionFromPayload(payload payloads.PromotionPayload) entity.Promotion {
+	promotion := entity.Promotion{
+		Code:         payload.Code,
+		Name:         payload.Name,
+		Type:         payload.Type,
+		Value:        payload.Value,
+		Currency:     payload.Currency,
+		BuyQuantity:  payload.BuyQuantity,
+		GetQuantity:  payload.GetQuantity,
+		ProductIDs:   payload.ProductIDs,
+		Categories:   payload.Categories,
+		MinSpend:     entity.MoneyFromMajor(payload.MinSpend, payload.Currency),
+		StartsAt:     payload.StartsAt,
+		EndsAt:       payload.EndsAt,
+		UsageLimit:   payload.UsageLimit,
+		PerUserLimit: payload.PerUserLimit,
+		IsActive:     payload.IsActive,
+	}
+
+	// The amount off of a fixed amount promotion is money, Value stays a
+	// percentage for the other types.
+	if payload.Type == entity.PromotionTypeFixedAmount {
+		promotion.Amount = entity.MoneyFromMajor(payload.Value, payload.Currency)
+		promotion.Value = 0
+	}
+
+	return promotion
+}
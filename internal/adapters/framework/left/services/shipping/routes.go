@@ -0,0 +1,355 @@
+package shipping
+
+import (
+	"fmt"
+	"net/http"
+
+	"ecom-api/internal/adapters/framework/left/services/auth"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/application/core/types/entity/payloads"
+	"ecom-api/internal/ports/right/rports"
+	"ecom-api/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type ShippingHandler struct {
+	store     rports.ShippingStore
+	userStore rports.UserStore
+}
+
+func NewShippingHandler(store rports.ShippingStore, userStore rports.UserStore) *ShippingHandler {
+	return &ShippingHandler{store: store, userStore: userStore}
+}
+
+func (handler *ShippingHandler) RegisterRoutes(router *mux.Router) {
+	//admin routes
+	router.HandleFunc("/shipping/zones", auth.WithJWTAuth(handler.handleGetZones, handler.userStore, "admin")).Methods(http.MethodGet)
+	router.HandleFunc("/shipping/zones", auth.WithJWTAuth(handler.handleCreateZone, handler.userStore, "admin")).Methods(http.MethodPost)
+	router.HandleFunc("/shipping/zone/{zoneId}", auth.WithJWTAuth(handler.handleGetZone, handler.userStore, "admin")).Methods(http.MethodGet)
+	router.HandleFunc("/shipping/zone/{zoneId}", auth.WithJWTAuth(handler.handleUpdateZone, handler.userStore, "admin")).Methods(http.MethodPut)
+	router.HandleFunc("/shipping/zone/{zoneId}", auth.WithJWTAuth(handler.handleDeleteZone, handler.userStore, "admin")).Methods(http.MethodDelete)
+	router.HandleFunc("/shipping/zone/{zoneId}/methods", auth.WithJWTAuth(handler.handleGetZoneMethods, handler.userStore, "admin")).Methods(http.MethodGet)
+	router.HandleFunc("/shipping/zone/{zoneId}/methods", auth.WithJWTAuth(handler.handleCreateMethod, handler.userStore, "admin")).Methods(http.MethodPost)
+	router.HandleFunc("/shipping/method/{methodId}", auth.WithJWTAuth(handler.handleGetMethod, handler.userStore, "admin")).Methods(http.MethodGet)
+	router.HandleFunc("/shipping/method/{methodId}", auth.WithJWTAuth(handler.handleUpdateMethod, handler.userStore, "admin")).Methods(http.MethodPut)
+	router.HandleFunc("/shipping/method/{methodId}", auth.WithJWTAuth(handler.handleDeleteMethod, handler.userStore, "admin")).Methods(http.MethodDelete)
+}
+
+func (handler *ShippingHandler) handleGetZones(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	zones, err := handler.store.GetAllShippingZones()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, zones, nil)
+}
+
+func (handler *ShippingHandler) handleCreateZone(w http.ResponseWriter, r *http.Request) {
+	var payload payloads.ShippingZonePayload
+
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", errors))
+		return
+	}
+
+	zone := entity.ShippingZone{Name: payload.Name, Countries: payload.Countries, Regions: payload.Regions, IsActive: payload.IsActive}
+
+	zoneId, err := handler.store.CreateShippingZone(zone)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	zone.ID = zoneId
+
+	utils.WriteJSON(w, http.StatusCreated, zone, nil)
+}
+
+func (handler *ShippingHandler) handleGetZone(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	vars := mux.Vars(r)
+	zoneId, ok := vars["zoneId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing zone ID"))
+		return
+	}
+
+	zone, err := handler.store.GetShippingZoneByID(zoneId)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, zone, nil)
+}
+
+func (handler *ShippingHandler) handleUpdateZone(w http.ResponseWriter, r *http.Request) {
+	var payload payloads.ShippingZonePayload
+
+	if r.Method != http.MethodPut {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	vars := mux.Vars(r)
+	zoneId, ok := vars["zoneId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing zone ID"))
+		return
+	}
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", errors))
+		return
+	}
+
+	zone := entity.ShippingZone{ID: zoneId, Name: payload.Name, Countries: payload.Countries, Regions: payload.Regions, IsActive: payload.IsActive}
+
+	if err := handler.store.UpdateShippingZone(zone); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, zone, nil)
+}
+
+func (handler *ShippingHandler) handleDeleteZone(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	vars := mux.Vars(r)
+	zoneId, ok := vars["zoneId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing zone ID"))
+		return
+	}
+
+	if err := handler.store.DeleteShippingZone(zoneId); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"zoneId": zoneId}, nil)
+}
+
+func (handler *ShippingHandler) handleGetZoneMethods(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	vars := mux.Vars(r)
+	zoneId, ok := vars["zoneId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing zone ID"))
+		return
+	}
+
+	methods, err := handler.store.GetShippingMethodsByZoneID(zoneId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, methods, nil)
+}
+
+func (handler *ShippingHandler) handleCreateMethod(w http.ResponseWriter, r *http.Request) {
+	var payload payloads.ShippingMethodPayload
+
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	vars := mux.Vars(r)
+	zoneId, ok := vars["zoneId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing zone ID"))
+		return
+	}
+
+	if _, err := handler.store.GetShippingZoneByID(zoneId); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := validateMethodPayload(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	method := methodFromPayload(payload)
+	method.ZoneID = zoneId
+
+	methodId, err := handler.store.CreateShippingMethod(method)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	method.ID = methodId
+
+	utils.WriteJSON(w, http.StatusCreated, method, nil)
+}
+
+func (handler *ShippingHandler) handleGetMethod(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	vars := mux.Vars(r)
+	methodId, ok := vars["methodId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing method ID"))
+		return
+	}
+
+	method, err := handler.store.GetShippingMethodByID(methodId)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, method, nil)
+}
+
+func (handler *ShippingHandler) handleUpdateMethod(w http.ResponseWriter, r *http.Request) {
+	var payload payloads.ShippingMethodPayload
+
+	if r.Method != http.MethodPut {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	vars := mux.Vars(r)
+	methodId, ok := vars["methodId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing method ID"))
+		return
+	}
+
+	existing, err := handler.store.GetShippingMethodByID(methodId)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := validateMethodPayload(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	method := methodFromPayload(payload)
+	method.ID = existing.ID
+	method.ZoneID = existing.ZoneID
+
+	if err := handler.store.UpdateShippingMethod(method); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, method, nil)
+}
+
+func (handler *ShippingHandler) handleDeleteMethod(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	vars := mux.Vars(r)
+	methodId, ok := vars["methodId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing method ID"))
+		return
+	}
+
+	if err := handler.store.DeleteShippingMethod(methodId); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"methodId": methodId}, nil)
+}
+
+func validateMethodPayload(payload payloads.ShippingMethodPayload) error {
+	if err := utils.Validate.Struct(payload); err != nil {
+		return err.(validator.ValidationErrors)
+	}
+
+	if payload.RateType == entity.ShippingRateFlat {
+		return nil
+	}
+
+	if len(payload.Tiers) == 0 {
+		return fmt.Errorf("%s based methods need a rate table", payload.RateType)
+	}
+	for _, tier := range payload.Tiers {
+		if tier.Min < 0 || tier.Cost < 0 || (tier.Max != 0 && tier.Max <= tier.Min) {
+			return fmt.Errorf("invalid rate tier %.2f-%.2f", tier.Min, tier.Max)
+		}
+	}
+
+	return nil
+}
+
+func methodFromPayload(payload payloads.ShippingMethodPayload) entity.ShippingMethod {
+	tiers := make([]entity.ShippingRateTier, len(payload.Tiers))
+	for i, tier := range payload.Tiers {
+		tiers[i] = entity.ShippingRateTier{Min: tier.Min, Max: tier.Max, Cost: entity.MoneyFromMajor(tier.Cost, payload.Currency)}
+	}
+
+	return entity.ShippingMethod{
+		Name:              payload.Name,
+		RateType:          payload.RateType,
+		FlatRate:          entity.MoneyFromMajor(payload.FlatRate, payload.Currency),
+		Tiers:             tiers,
+		FreeAbove:         entity.MoneyFromMajor(payload.FreeAbove, payload.Currency),
+		VolumetricDivisor: payload.VolumetricDivisor,
+		Currency:          payload.Currency,
+		MinDeliveryDays:   payload.MinDeliveryDays,
+		MaxDeliveryDays:   payload.MaxDeliveryDays,
+		IsActive:          payload.IsActive,
+	}
+}
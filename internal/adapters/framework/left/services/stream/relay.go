@@ -0,0 +1,76 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/ports/right/rports"
+)
+
+// Topics are the outbox topics relayed to the event streams.
+var Topics = []string{
+	entity.TopicOrderPaid,
+	entity.TopicOrderCancelled,
+	entity.TopicOrderStatusChanged,
+	entity.TopicProductUpdated,
+	entity.TopicProductDeleted,
+	entity.TopicProductStockChanged,
+}
+
+// OrdersTopic is the bus topic of the order updates of a user.
+func OrdersTopic(userID string) string {
+	return "orders." + userID
+}
+
+// ProductTopic is the bus topic of the updates of a product.
+func ProductTopic(productID string) string {
+	return "product." + productID
+}
+
+// Relay puts the domain events of the outbox on the event bus, where the
+// streams of every instance pick them up. The outbox hands an event to one
+// instance, the bus takes it to all of them.
+type Relay struct {
+	bus rports.EventBus
+}
+
+func NewRelay(bus rports.EventBus) *Relay {
+	return &Relay{bus: bus}
+}
+
+// Forward publishes an event on the topic of the buyer of the order or of the
+// product it is about. It is the outbox subscriber of the Topics. Guest
+// orders have nobody to stream to and are skipped.
+func (r *Relay) Forward(message *entity.OutboxMessage) error {
+	var topic string
+
+	switch {
+	case strings.HasPrefix(message.Topic, "order."):
+		var event entity.OrderEvent
+		if err := json.Unmarshal(message.Payload, &event); err != nil {
+			return fmt.Errorf("invalid order event in outbox message %s: %v", message.ID, err)
+		}
+		if event.UserID == "" {
+			return nil
+		}
+		topic = OrdersTopic(event.UserID)
+
+	case strings.HasPrefix(message.Topic, "product."):
+		var event entity.ProductEvent
+		if err := json.Unmarshal(message.Payload, &event); err != nil {
+			return fmt.Errorf("invalid product event in outbox message %s: %v", message.ID, err)
+		}
+		topic = ProductTopic(event.ProductID)
+
+	default:
+		return nil
+	}
+
+	return r.bus.Publish(topic, entity.StreamEvent{
+		ID:   message.ID,
+		Type: message.Topic,
+		Data: json.RawMessage(message.Payload),
+	})
+}
@@ -0,0 +1,153 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"ecom-api/internal/adapters/framework/left/services/auth"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/ports/right/rports"
+	"ecom-api/utils"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// ProductSnapshot is the type of the events a stream opens with, the
+	// stock of every watched product at that moment.
+	ProductSnapshot = "product.snapshot"
+
+	// maxWatchedProducts is how many products a stream may watch.
+	maxWatchedProducts = 50
+	// keepAliveInterval is how often an idle stream gets a comment, so that
+	// proxies do not close it.
+	keepAliveInterval = 25 * time.Second
+)
+
+type StreamHandler struct {
+	bus          rports.EventBus
+	productStore rports.ProductStore
+	userStore    rports.UserStore
+}
+
+func NewStreamHandler(bus rports.EventBus, productStore rports.ProductStore, userStore rports.UserStore) *StreamHandler {
+	return &StreamHandler{bus: bus, productStore: productStore, userStore: userStore}
+}
+
+func (handler *StreamHandler) RegisterRoutes(router *mux.Router) {
+	// browsers cannot set headers on an EventSource, the token may be passed as ?token=
+	router.HandleFunc("/stream", auth.WithJWTAuth(handler.handleStream, handler.userStore, "admin", "storeowner", "user")).Methods(http.MethodGet)
+}
+
+// watchedProducts returns the product IDs of the comma separated products
+// query parameter, without blanks and repeats.
+func watchedProducts(r *http.Request) []string {
+	seen := map[string]bool{}
+	var productIDs []string
+	for _, productID := range strings.Split(r.URL.Query().Get("products"), ",") {
+		productID = strings.TrimSpace(productID)
+		if productID == "" || seen[productID] {
+			continue
+		}
+		seen[productID] = true
+		productIDs = append(productIDs, productID)
+	}
+	return productIDs
+}
+
+// writeEvent writes event to a stream in the Server-Sent Events format.
+func writeEvent(w http.ResponseWriter, event entity.StreamEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if event.ID != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", event.ID); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
+
+// handleStream pushes the status changes of the orders of the caller and the
+// stock changes of the products in ?products= as Server-Sent Events, until
+// the client goes away.
+func (handler *StreamHandler) handleStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	productIDs := watchedProducts(r)
+	if len(productIDs) > maxWatchedProducts {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("a stream watches at most %d products", maxWatchedProducts))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+
+	topics := []string{OrdersTopic(auth.GetUserIDFromContext(r.Context()))}
+	for _, productID := range productIDs {
+		topics = append(topics, ProductTopic(productID))
+	}
+	// subscribed before the snapshot is read, so no change falls in between
+	events, stop := handler.bus.Subscribe(topics...)
+	defer stop()
+
+	var products []entity.Product
+	if len(productIDs) > 0 {
+		var err error
+		products, err = handler.productStore.GetProductsByIDs(productIDs)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	for _, product := range products {
+		snapshot, err := entity.NewProductEvent(ProductSnapshot, product)
+		if err != nil {
+			return
+		}
+		if err := writeEvent(w, entity.StreamEvent{Type: ProductSnapshot, Data: snapshot.Payload}); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
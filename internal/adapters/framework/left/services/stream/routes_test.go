@@ -0,0 +1,151 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ecom-api/internal/adapters/framework/left/services/auth"
+	"ecom-api/internal/adapters/framework/right/bus_repo"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/ports/right/rports"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mockProductStore knows every product by its ID, with 5 in stock.
+type mockProductStore struct {
+	rports.ProductStore
+}
+
+func (m *mockProductStore) GetProductsByIDs(ids []string) ([]entity.Product, error) {
+	products := make([]entity.Product, 0, len(ids))
+	for _, id := range ids {
+		products = append(products, entity.Product{ProductId: id, Quantity: 5, IsActive: true})
+	}
+	return products, nil
+}
+
+// sseEvent is an event as read off a stream.
+type sseEvent struct {
+	id    string
+	event string
+	data  entity.StreamEvent
+}
+
+// readEvent reads the next event of a stream, skipping comments.
+func readEvent(t *testing.T, stream *bufio.Reader) sseEvent {
+	var event sseEvent
+	for {
+		line, err := stream.ReadString('\n')
+		if err != nil {
+			t.Fatalf("error reading the stream %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+
+		switch {
+		case line == "" && event.event != "":
+			return event
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.data))
+		}
+	}
+}
+
+func outboxEvent(t *testing.T, id, topic string, payload interface{}) *entity.OutboxMessage {
+	message, err := entity.NewOutboxMessage(topic, "", payload)
+	assert.NoError(t, err)
+	message.ID = id
+	return &message
+}
+
+func TestStreamHandler(t *testing.T) {
+	open := func(t *testing.T, handler *StreamHandler, userID, query string) (*bufio.Reader, func()) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler.handleStream(w, r.WithContext(context.WithValue(r.Context(), auth.UserKey, userID)))
+		}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/stream"+query, nil)
+		if err != nil {
+			t.Fatalf("error requesting %v", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("error requesting %v", err)
+		}
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		return bufio.NewReader(resp.Body), func() {
+			cancel()
+			resp.Body.Close()
+			server.Close()
+		}
+	}
+
+	t.Run("a stream gets the updates of its user's orders and watched products only", func(t *testing.T) {
+		bus := bus_repo.NewMemoryBus()
+		relay := NewRelay(bus)
+		handler := NewStreamHandler(bus, &mockProductStore{}, nil)
+
+		stream, stop := open(t, handler, "user-1", "?products=product-1,+product-1,product-2")
+		defer stop()
+
+		// the stream opens with the stock of every watched product
+		for _, productID := range []string{"product-1", "product-2"} {
+			snapshot := readEvent(t, stream)
+			assert.Equal(t, ProductSnapshot, snapshot.event)
+			var product entity.ProductEvent
+			assert.NoError(t, json.Unmarshal(snapshot.data.Data, &product))
+			assert.Equal(t, productID, product.ProductID)
+			assert.Equal(t, 5, product.Quantity)
+		}
+
+		assert.NoError(t, relay.Forward(outboxEvent(t, "event-1", entity.TopicOrderPaid, entity.OrderEvent{OrderID: "order-1", UserID: "user-2"})))
+		assert.NoError(t, relay.Forward(outboxEvent(t, "event-2", entity.TopicOrderCancelled, entity.OrderEvent{OrderID: "order-2"})))
+		assert.NoError(t, relay.Forward(outboxEvent(t, "event-3", entity.TopicProductStockChanged, entity.ProductEvent{ProductID: "product-3", Quantity: 1})))
+		assert.NoError(t, relay.Forward(outboxEvent(t, "event-4", entity.TopicOrderStatusChanged, entity.OrderEvent{OrderID: "order-3", UserID: "user-1", Status: "shipped"})))
+		assert.NoError(t, relay.Forward(outboxEvent(t, "event-5", entity.TopicProductStockChanged, entity.ProductEvent{ProductID: "product-2", Quantity: 4})))
+
+		status := readEvent(t, stream)
+		assert.Equal(t, "event-4", status.id)
+		assert.Equal(t, entity.TopicOrderStatusChanged, status.event)
+		var order entity.OrderEvent
+		assert.NoError(t, json.Unmarshal(status.data.Data, &order))
+		assert.Equal(t, "order-3", order.OrderID)
+		assert.Equal(t, "shipped", order.Status)
+
+		stock := readEvent(t, stream)
+		assert.Equal(t, "event-5", stock.id)
+		assert.Equal(t, entity.TopicProductStockChanged, stock.event)
+		var product entity.ProductEvent
+		assert.NoError(t, json.Unmarshal(stock.data.Data, &product))
+		assert.Equal(t, 4, product.Quantity)
+	})
+
+	t.Run("a stream watching too many products is refused", func(t *testing.T) {
+		handler := NewStreamHandler(bus_repo.NewMemoryBus(), &mockProductStore{}, nil)
+		productIDs := make([]string, maxWatchedProducts+1)
+		for i := range productIDs {
+			productIDs[i] = "product-" + strings.Repeat("x", i+1)
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "/stream?products="+strings.Join(productIDs, ","), nil)
+		if err != nil {
+			t.Fatalf("error requesting %v", err)
+		}
+		rr := httptest.NewRecorder()
+		handler.handleStream(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
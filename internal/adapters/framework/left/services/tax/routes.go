@@ -0,0 +1,170 @@
+package tax
+
+import (
+	"fmt"
+	"net/http"
+
+	"ecom-api/internal/adapters/framework/left/services/auth"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/application/core/types/entity/payloads"
+	"ecom-api/internal/ports/right/rports"
+	"ecom-api/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type TaxHandler struct {
+	store     rports.TaxStore
+	userStore rports.UserStore
+}
+
+func NewTaxHandler(store rports.TaxStore, userStore rports.UserStore) *TaxHandler {
+	return &TaxHandler{store: store, userStore: userStore}
+}
+
+func (handler *TaxHandler) RegisterRoutes(router *mux.Router) {
+	//admin routes
+	router.HandleFunc("/taxrates", auth.WithJWTAuth(handler.handleGetTaxRates, handler.userStore, "admin")).Methods(http.MethodGet)
+	router.HandleFunc("/taxrates", auth.WithJWTAuth(handler.handleCreateTaxRate, handler.userStore, "admin")).Methods(http.MethodPost)
+	router.HandleFunc("/taxrate/{taxRateId}", auth.WithJWTAuth(handler.handleGetTaxRate, handler.userStore, "admin")).Methods(http.MethodGet)
+	router.HandleFunc("/taxrate/{taxRateId}", auth.WithJWTAuth(handler.handleUpdateTaxRate, handler.userStore, "admin")).Methods(http.MethodPut)
+	router.HandleFunc("/taxrate/{taxRateId}", auth.WithJWTAuth(handler.handleDeleteTaxRate, handler.userStore, "admin")).Methods(http.MethodDelete)
+}
+
+func (handler *TaxHandler) handleGetTaxRates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	rates, err := handler.store.GetAllTaxRates()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, rates, nil)
+}
+
+func (handler *TaxHandler) handleCreateTaxRate(w http.ResponseWriter, r *http.Request) {
+	var payload payloads.TaxRatePayload
+
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", errors))
+		return
+	}
+
+	rate := taxRateFromPayload(payload)
+
+	rateId, err := handler.store.CreateTaxRate(rate)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	rate.ID = rateId
+
+	utils.WriteJSON(w, http.StatusCreated, rate, nil)
+}
+
+func (handler *TaxHandler) handleGetTaxRate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	vars := mux.Vars(r)
+	rateId, ok := vars["taxRateId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing tax rate ID"))
+		return
+	}
+
+	rate, err := handler.store.GetTaxRateByID(rateId)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, rate, nil)
+}
+
+func (handler *TaxHandler) handleUpdateTaxRate(w http.ResponseWriter, r *http.Request) {
+	var payload payloads.TaxRatePayload
+
+	if r.Method != http.MethodPut {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	vars := mux.Vars(r)
+	rateId, ok := vars["taxRateId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing tax rate ID"))
+		return
+	}
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", errors))
+		return
+	}
+
+	rate := taxRateFromPayload(payload)
+	rate.ID = rateId
+
+	if err := handler.store.UpdateTaxRate(rate); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, rate, nil)
+}
+
+func (handler *TaxHandler) handleDeleteTaxRate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	vars := mux.Vars(r)
+	rateId, ok := vars["taxRateId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing tax rate ID"))
+		return
+	}
+
+	if err := handler.store.DeleteTaxRate(rateId); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"taxRateId": rateId}, nil)
+}
+
+func taxRateFromPayload(payload payloads.TaxRatePayload) entity.TaxRate {
+	return entity.TaxRate{
+		Name:        payload.Name,
+		Country:     payload.Country,
+		Region:      payload.Region,
+		TaxCategory: payload.TaxCategory,
+		Rate:        payload.Rate,
+		IsActive:    payload.IsActive,
+	}
+}
@@ -2,11 +2,13 @@ package user
 
 import (
 	"fmt"
+	"log"
 	"net/http"
 	"time"
 
 	"ecom-api/internal/adapters/framework/left/services/auth"
 	"ecom-api/internal/adapters/framework/left/services/auth/token"
+	"ecom-api/internal/adapters/framework/left/services/notification"
 	"ecom-api/internal/application/core/types/entity"
 	"ecom-api/internal/application/core/types/entity/payloads"
 	"ecom-api/internal/ports/right/rports"
@@ -20,10 +22,12 @@ import (
 type UserHandler struct {
 	store      rports.UserStore
 	tokenStore *token.TokenStore
+	orderStore rports.OrderStore
+	notifier   *notification.Notifier
 }
 
-func NewUserHandler(store rports.UserStore, tokenStore *token.TokenStore) *UserHandler {
-	return &UserHandler{store: store, tokenStore: tokenStore}
+func NewUserHandler(store rports.UserStore, tokenStore *token.TokenStore, orderStore rports.OrderStore, notifier *notification.Notifier) *UserHandler {
+	return &UserHandler{store: store, tokenStore: tokenStore, orderStore: orderStore, notifier: notifier}
 }
 
 func (handler *UserHandler) RegisterRoutes(router *mux.Router) {
@@ -119,8 +123,13 @@ func (h *UserHandler) handleRegister(w http.ResponseWriter, r *http.Request) {
 	h.tokenStore.Set(user.Email, newToken, 7*time.Minute)
 
 	// send verification code to user email
-	HTMLTemplateEmailHandler(w, r, user.Email, map[string]string{"verification_code": newToken})
+	if err := h.notifier.Send([]string{user.Email}, "Verify Your Email", "confirmation", "", map[string]string{"verification_code": newToken}); err != nil {
+		log.Printf("failed to send verification email to %s: %v", user.Email, err)
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to send verification email"))
+		return
+	}
 
+	utils.WriteJSON(w, http.StatusOK, map[string]bool{"emailSent": true}, nil)
 }
 
 func (h *UserHandler) handleRegisterConfirmation(w http.ResponseWriter, r *http.Request) {
@@ -172,7 +181,21 @@ func (h *UserHandler) handleRegisterConfirmation(w http.ResponseWriter, r *http.
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
-	utils.WriteJSON(w, http.StatusCreated, map[string]string{"code": storedToken, "usertoken": user.Token}, nil)
+
+	// the email is verified at this point, so orders placed with it as a guest
+	// can safely move to the new account
+	newUser, err := h.store.GetUserByEmail(user.Email)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	linkedOrders, err := h.orderStore.AttachGuestOrdersToUser(user.Email, newUser.ID)
+	if err != nil {
+		log.Printf("failed to attach guest orders to user %s: %v", newUser.ID, err)
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, map[string]interface{}{"code": storedToken, "usertoken": user.Token, "linkedOrders": linkedOrders}, nil)
 }
 
 func (h *UserHandler) handleGetUser(w http.ResponseWriter, r *http.Request) {
@@ -15,7 +15,7 @@ import (
 
 func TestUserServiceHandlers(t *testing.T) {
 	userStore := &mockUserStore{}
-	handler := NewUserHandler(userStore, nil)
+	handler := NewUserHandler(userStore, nil, nil, nil)
 
 	t.Run("should fail if user payload is invalid", func(t *testing.T) {
 		payload := payloads.RegisterUserPayload{
@@ -118,6 +118,18 @@ func (m *mockUserStore) GetUsersByRole(role string) ([]*entity.User, error) {
 	panic("unimplemented")
 }
 
+func (m *mockUserStore) LinkStripeCustomer(userID, customerID string) (bool, error) {
+	return true, nil
+}
+
+func (m *mockUserStore) UnlinkStripeCustomer(customerID string) error {
+	return nil
+}
+
+func (m *mockUserStore) GetUserByStripeCustomerID(customerID string) (*entity.User, error) {
+	return &entity.User{}, nil
+}
+
 func (m *mockUserStore) GetUserByID(id string) (*entity.User, error) {
 	return &entity.User{}, nil
 }
@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// Endpoints are URLs given by store owners, posted to from inside the
+// network of the API. So a store owner cannot have the API call its own
+// services or the cloud metadata endpoint, an endpoint must be an https URL of
+// a public host, checked when it is registered and again on every connection
+// since its host may resolve elsewhere by then. Redirects are not followed.
+
+// publicIP reports whether ip is an address endpoints may be posted to, not a
+// loopback, private, link-local, multicast or unspecified one.
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+// CheckEndpointURL returns an error unless raw is an https URL whose host
+// resolves to public addresses only.
+func CheckEndpointURL(ctx context.Context, raw string) error {
+	endpointURL, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint URL: %v", err)
+	}
+	if endpointURL.Scheme != "https" {
+		return fmt.Errorf("endpoint URL must use https")
+	}
+	host := endpointURL.Hostname()
+	if host == "" {
+		return fmt.Errorf("endpoint URL has no host")
+	}
+
+	addresses, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve endpoint host %s: %v", host, err)
+	}
+	for _, address := range addresses {
+		if !publicIP(address.IP) {
+			return fmt.Errorf("endpoint host %s resolves to %s, which is not a public address", host, address.IP)
+		}
+	}
+	return nil
+}
+
+// refusePrivate is the dialer control refusing connections to addresses that
+// are not public. It runs on the address actually dialled, after resolution,
+// so a host cannot be pointed at the internal network after registration.
+func refusePrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !publicIP(ip) {
+		return fmt.Errorf("refusing to post a webhook to %s, not a public address", host)
+	}
+	return nil
+}
+
+// newClient returns the HTTP client endpoints are posted with. It only
+// connects to public addresses, goes through no proxy and does not follow
+// redirects, a redirect is recorded as the response of the delivery.
+func newClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: refusePrivate}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,316 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"ecom-api/internal/adapters/framework/left/services/auth"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/application/core/types/entity/payloads"
+	"ecom-api/internal/ports/right/rports"
+	"ecom-api/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+var errEndpointNotFound = fmt.Errorf("webhook endpoint not found")
+
+type WebhookHandler struct {
+	store           rports.WebhookStore
+	sender          *Sender
+	userStore       rports.UserStore
+	storeOwnerStore rports.StoreOwnerStore
+}
+
+func NewWebhookHandler(store rports.WebhookStore, sender *Sender, userStore rports.UserStore, storeOwnerStore rports.StoreOwnerStore) *WebhookHandler {
+	return &WebhookHandler{store: store, sender: sender, userStore: userStore, storeOwnerStore: storeOwnerStore}
+}
+
+func (handler *WebhookHandler) RegisterRoutes(router *mux.Router) {
+	//store owner routes, admins manage the endpoints of every store and of the platform
+	router.HandleFunc("/webhooks/endpoints", auth.WithJWTAuth(handler.handleCreateEndpoint, handler.userStore, "admin", "storeowner")).Methods(http.MethodPost)
+	router.HandleFunc("/webhooks/endpoints", auth.WithJWTAuth(handler.handleGetEndpoints, handler.userStore, "admin", "storeowner")).Methods(http.MethodGet)
+	router.HandleFunc("/webhooks/endpoint/{endpointId}", auth.WithJWTAuth(handler.handleDeleteEndpoint, handler.userStore, "admin", "storeowner")).Methods(http.MethodDelete)
+	router.HandleFunc("/webhooks/endpoint/deliveries/{endpointId}", auth.WithJWTAuth(handler.handleGetDeliveries, handler.userStore, "admin", "storeowner")).Methods(http.MethodGet)
+	router.HandleFunc("/webhooks/delivery/redeliver/{deliveryId}", auth.WithJWTAuth(handler.handleRedeliver, handler.userStore, "admin", "storeowner")).Methods(http.MethodPost)
+}
+
+// newSecret returns a random secret to sign the payloads of an endpoint with.
+func newSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
+
+// callerStoreID returns the store run by the caller and whether the caller is
+// an admin. The store is empty for admins and anyone not running a store.
+func (handler *WebhookHandler) callerStoreID(r *http.Request) (string, bool, error) {
+	user, err := handler.userStore.GetUserByID(auth.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		return "", false, err
+	}
+	if user.Role == "admin" {
+		return "", true, nil
+	}
+
+	store, err := handler.storeOwnerStore.GetStoreOwnerByEmail(user.Email)
+	if err != nil {
+		return "", false, err
+	}
+	return store.StoreID, false, nil
+}
+
+// callerEndpoint returns the endpoint with the ID when the caller may manage
+// it, an admin or the owner of its store.
+func (handler *WebhookHandler) callerEndpoint(r *http.Request, endpointID string) (*entity.WebhookEndpoint, error) {
+	storeID, isAdmin, err := handler.callerStoreID(r)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint, err := handler.store.GetWebhookEndpointByID(endpointID)
+	if err != nil {
+		return nil, err
+	}
+	// a store owner cannot tell the endpoints of others from missing ones
+	if endpoint.ID == "" || (!isAdmin && (storeID == "" || endpoint.StoreID != storeID)) {
+		return nil, errEndpointNotFound
+	}
+
+	return endpoint, nil
+}
+
+func (handler *WebhookHandler) handleCreateEndpoint(w http.ResponseWriter, r *http.Request) {
+	var endpointParams payloads.WebhookEndpointPayload
+
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	if err := utils.ParseJSON(r, &endpointParams); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(endpointParams); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors)
+		return
+	}
+
+	if err := CheckEndpointURL(r.Context(), endpointParams.URL); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	for _, event := range endpointParams.Events {
+		if !entity.IsWebhookEvent(event) {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unknown webhook event %s, one of %v", event, entity.WebhookEvents))
+			return
+		}
+	}
+
+	storeID, isAdmin, err := handler.callerStoreID(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if isAdmin {
+		storeID = endpointParams.StoreID
+		if storeID != "" {
+			store, err := handler.storeOwnerStore.GetStoreOwnerByID(storeID)
+			if err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, err)
+				return
+			}
+			if store.StoreID == "" {
+				utils.WriteError(w, http.StatusNotFound, fmt.Errorf("store not found"))
+				return
+			}
+		}
+	} else if storeID == "" || (endpointParams.StoreID != "" && endpointParams.StoreID != storeID) {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("store owners register endpoints of their own store"))
+		return
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	endpoint := entity.WebhookEndpoint{
+		StoreID:  storeID,
+		URL:      endpointParams.URL,
+		Secret:   secret,
+		Events:   endpointParams.Events,
+		IsActive: true,
+	}
+	endpointID, err := handler.store.CreateWebhookEndpoint(endpoint)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	endpoint.ID = endpointID
+
+	// the secret is shown this once, it is needed to check the signatures
+	utils.WriteJSON(w, http.StatusCreated, map[string]interface{}{
+		"endpoint": endpoint,
+		"secret":   secret,
+	}, nil)
+}
+
+func (handler *WebhookHandler) handleGetEndpoints(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	// store owners see the endpoints of their store, admins every endpoint or those of a store
+	storeID, isAdmin, err := handler.callerStoreID(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if isAdmin {
+		storeID = r.URL.Query().Get("storeId")
+	} else if storeID == "" {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("store not found"))
+		return
+	}
+
+	var endpoints []*entity.WebhookEndpoint
+	if storeID == "" {
+		endpoints, err = handler.store.GetWebhookEndpoints()
+	} else {
+		endpoints, err = handler.store.GetWebhookEndpointsByStoreID(storeID)
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if endpoints == nil {
+		endpoints = []*entity.WebhookEndpoint{}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, endpoints, nil)
+}
+
+func (handler *WebhookHandler) handleDeleteEndpoint(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	endpointId, ok := mux.Vars(r)["endpointId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing endpoint ID"))
+		return
+	}
+
+	endpoint, err := handler.callerEndpoint(r, endpointId)
+	if err == errEndpointNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	deleted, err := handler.store.DeleteWebhookEndpoint(endpoint.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !deleted {
+		utils.WriteError(w, http.StatusNotFound, errEndpointNotFound)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"endpointId": endpoint.ID}, nil)
+}
+
+func (handler *WebhookHandler) handleGetDeliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	endpointId, ok := mux.Vars(r)["endpointId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing endpoint ID"))
+		return
+	}
+
+	endpoint, err := handler.callerEndpoint(r, endpointId)
+	if err == errEndpointNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	deliveries, err := handler.store.GetWebhookDeliveriesByEndpointID(endpoint.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []*entity.WebhookDelivery{}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, deliveries, nil)
+}
+
+func (handler *WebhookHandler) handleRedeliver(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	deliveryId, ok := mux.Vars(r)["deliveryId"]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing delivery ID"))
+		return
+	}
+
+	delivery, err := handler.store.GetWebhookDeliveryByID(deliveryId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if delivery.ID == "" {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("webhook delivery not found"))
+		return
+	}
+
+	if _, err := handler.callerEndpoint(r, delivery.EndpointID); err == errEndpointNotFound {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("webhook delivery not found"))
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	queued, err := handler.store.RedeliverWebhookDelivery(delivery.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !queued {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("webhook delivery is being sent"))
+		return
+	}
+	handler.sender.Wake()
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"deliveryId": delivery.ID, "status": entity.WebhookPending}, nil)
+}
@@ -0,0 +1,215 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ecom-api/internal/adapters/framework/left/services/worker"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/ports/right/rports"
+	"ecom-api/pkg/configs"
+)
+
+// responseBodyLimit is how much of a response is kept in the delivery log.
+const responseBodyLimit = 1024
+
+// Headers of the requests posted to endpoints.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderEventID   = "X-Webhook-Id"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Sign returns the signature header of a body posted at timestamp, the
+// hex-encoded HMAC-SHA256 of "timestamp.body" under the endpoint secret.
+// Endpoints compute it again to check the body came from the API, and reject
+// old timestamps so a captured request cannot be replayed.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// Sender posts domain events to the webhook endpoints subscribed to them.
+// Publish turns an event from the outbox into a delivery per endpoint, which
+// the sender posts, retries with exponential backoff and gives up on after
+// WEBHOOK_MAX_ATTEMPTS attempts.
+type Sender struct {
+	store      rports.WebhookStore
+	orderStore rports.OrderStore
+	client     *http.Client
+	poller     *worker.Poller
+}
+
+func NewSender(store rports.WebhookStore, orderStore rports.OrderStore) *Sender {
+	return &Sender{
+		store:      store,
+		orderStore: orderStore,
+		client:     newClient(time.Second * time.Duration(configs.Envs.WebhookTimeout)),
+		poller:     worker.NewPoller(),
+	}
+}
+
+// Publish queues an event for every active endpoint subscribed to it that may
+// see it. It is the outbox subscriber of the webhook events, and safe to run
+// again for an event, an endpoint gets an event once.
+func (s *Sender) Publish(message *entity.OutboxMessage) error {
+	endpoints, err := s.store.GetActiveWebhookEndpoints(message.Topic)
+	if err != nil || len(endpoints) == 0 {
+		return err
+	}
+
+	stores, err := s.eventStores(message)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(entity.WebhookBody{
+		ID:        message.ID,
+		Type:      message.Topic,
+		CreatedAt: message.CreatedAt,
+		Data:      json.RawMessage(message.Payload),
+	})
+	if err != nil {
+		return err
+	}
+
+	var deliveries []entity.WebhookDelivery
+	for _, endpoint := range endpoints {
+		// a store only hears about its own orders and products
+		if endpoint.StoreID != "" && !stores[endpoint.StoreID] {
+			continue
+		}
+		deliveries = append(deliveries, entity.WebhookDelivery{
+			EndpointID: endpoint.ID,
+			EventID:    message.ID,
+			Event:      message.Topic,
+			Payload:    body,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	if err := s.store.AddWebhookDeliveries(deliveries...); err != nil {
+		return err
+	}
+	s.Wake()
+	return nil
+}
+
+// eventStores returns the stores an event is about, the stores that sold
+// items of an order or the store selling a product.
+func (s *Sender) eventStores(message *entity.OutboxMessage) (map[string]bool, error) {
+	stores := map[string]bool{}
+
+	switch {
+	case strings.HasPrefix(message.Topic, "order."):
+		var event entity.OrderEvent
+		if err := json.Unmarshal(message.Payload, &event); err != nil {
+			return nil, fmt.Errorf("invalid order event in outbox message %s: %v", message.ID, err)
+		}
+		storeOrders, err := s.orderStore.GetStoreOrdersByOrderID(event.OrderID)
+		if err != nil {
+			return nil, err
+		}
+		for _, storeOrder := range storeOrders {
+			stores[storeOrder.StoreID] = true
+		}
+
+	case strings.HasPrefix(message.Topic, "product."):
+		var event entity.ProductEvent
+		if err := json.Unmarshal(message.Payload, &event); err != nil {
+			return nil, fmt.Errorf("invalid product event in outbox message %s: %v", message.ID, err)
+		}
+		stores[event.StoreID] = true
+	}
+
+	delete(stores, "")
+	return stores, nil
+}
+
+// Run posts due deliveries until ctx is done. It polls every
+// WEBHOOK_INTERVAL_IN_SECONDS and right after Wake.
+func (s *Sender) Run(ctx context.Context) {
+	s.poller.Run(ctx, time.Second*time.Duration(configs.Envs.WebhookInterval), s.processDue)
+}
+
+// Wake has the sender look for due deliveries without waiting for its next
+// poll.
+func (s *Sender) Wake() {
+	s.poller.Wake()
+}
+
+func (s *Sender) processDue() {
+	if err := worker.Drain(s.store.ClaimWebhookDeliveries, s.deliver); err != nil {
+		log.Printf("failed to claim webhook deliveries: %v", err)
+	}
+}
+
+func (s *Sender) deliver(delivery *entity.WebhookDelivery) {
+	status, body, err := s.post(delivery)
+	if err == nil {
+		if err := s.store.MarkWebhookDeliveryDelivered(delivery.ID, status, body); err != nil {
+			log.Printf("failed to mark webhook delivery %s delivered: %v", delivery.ID, err)
+		}
+		return
+	}
+
+	dead := delivery.Attempts >= int(configs.Envs.WebhookAttempts)
+	log.Printf("webhook delivery %s (%s) failed on attempt %d: %v", delivery.ID, delivery.Event, delivery.Attempts, err)
+	if err := s.store.MarkWebhookDeliveryFailed(delivery.ID, status, body, err.Error(), time.Now().Add(worker.RetryDelay(delivery.Attempts)), dead); err != nil {
+		log.Printf("failed to mark webhook delivery %s failed: %v", delivery.ID, err)
+	}
+}
+
+// post sends a delivery to its endpoint, signed with the endpoint secret. It
+// returns the status and the start of the body of the response, and an error
+// unless the endpoint answered with a 2xx.
+func (s *Sender) post(delivery *entity.WebhookDelivery) (int, string, error) {
+	endpoint, err := s.store.GetWebhookEndpointByID(delivery.EndpointID)
+	if err != nil {
+		return 0, "", err
+	}
+	if endpoint.ID == "" {
+		return 0, "", fmt.Errorf("webhook endpoint %s not found", delivery.EndpointID)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderEventID, delivery.EventID)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(endpoint.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, responseBodyLimit))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, string(body), fmt.Errorf("endpoint answered %d", resp.StatusCode)
+	}
+	return resp.StatusCode, string(body), nil
+}
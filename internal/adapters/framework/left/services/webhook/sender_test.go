@@ -0,0 +1,297 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/ports/right/rports"
+	"ecom-api/pkg/configs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mockWebhookStore keeps endpoints and deliveries in slices, without leases.
+type mockWebhookStore struct {
+	rports.WebhookStore
+	endpoints  []*entity.WebhookEndpoint
+	deliveries []*entity.WebhookDelivery
+}
+
+func (m *mockWebhookStore) GetWebhookEndpointByID(id string) (*entity.WebhookEndpoint, error) {
+	for _, endpoint := range m.endpoints {
+		if endpoint.ID == id {
+			return endpoint, nil
+		}
+	}
+	return &entity.WebhookEndpoint{}, nil
+}
+
+func (m *mockWebhookStore) GetActiveWebhookEndpoints(event string) ([]*entity.WebhookEndpoint, error) {
+	var endpoints []*entity.WebhookEndpoint
+	for _, endpoint := range m.endpoints {
+		if endpoint.IsActive && endpoint.Subscribes(event) {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints, nil
+}
+
+func (m *mockWebhookStore) AddWebhookDeliveries(deliveries ...entity.WebhookDelivery) error {
+	for _, delivery := range deliveries {
+		if m.find(delivery.EndpointID, delivery.EventID) != nil {
+			continue
+		}
+		delivery := delivery
+		delivery.ID = fmt.Sprintf("delivery-%d", len(m.deliveries)+1)
+		delivery.Status = entity.WebhookPending
+		delivery.NextAttemptAt = time.Now()
+		m.deliveries = append(m.deliveries, &delivery)
+	}
+	return nil
+}
+
+func (m *mockWebhookStore) ClaimWebhookDeliveries(limit int, lease time.Duration) ([]*entity.WebhookDelivery, error) {
+	var claimed []*entity.WebhookDelivery
+	for _, delivery := range m.deliveries {
+		if (delivery.Status == entity.WebhookPending || delivery.Status == entity.WebhookFailed) && !delivery.NextAttemptAt.After(time.Now()) && len(claimed) < limit {
+			delivery.Status = entity.WebhookProcessing
+			delivery.Attempts++
+			claimed = append(claimed, delivery)
+		}
+	}
+	return claimed, nil
+}
+
+func (m *mockWebhookStore) MarkWebhookDeliveryDelivered(id string, responseStatus int, responseBody string) error {
+	for _, delivery := range m.deliveries {
+		if delivery.ID == id {
+			delivery.Status = entity.WebhookDelivered
+			delivery.ResponseStatus = responseStatus
+			delivery.ResponseBody = responseBody
+		}
+	}
+	return nil
+}
+
+func (m *mockWebhookStore) MarkWebhookDeliveryFailed(id string, responseStatus int, responseBody, lastError string, nextAttemptAt time.Time, dead bool) error {
+	for _, delivery := range m.deliveries {
+		if delivery.ID == id {
+			delivery.Status = entity.WebhookFailed
+			if dead {
+				delivery.Status = entity.WebhookDead
+			}
+			delivery.ResponseStatus = responseStatus
+			delivery.ResponseBody = responseBody
+			delivery.LastError = lastError
+			delivery.NextAttemptAt = nextAttemptAt
+		}
+	}
+	return nil
+}
+
+func (m *mockWebhookStore) find(endpointID, eventID string) *entity.WebhookDelivery {
+	for _, delivery := range m.deliveries {
+		if delivery.EndpointID == endpointID && delivery.EventID == eventID {
+			return delivery
+		}
+	}
+	return nil
+}
+
+// mockOrderStore knows which stores sold the items of every order.
+type mockOrderStore struct {
+	rports.OrderStore
+	stores map[string][]string
+}
+
+func (m *mockOrderStore) GetStoreOrdersByOrderID(orderID string) ([]*entity.StoreOrder, error) {
+	var storeOrders []*entity.StoreOrder
+	for _, storeID := range m.stores[orderID] {
+		storeOrders = append(storeOrders, &entity.StoreOrder{OrderID: orderID, StoreID: storeID})
+	}
+	return storeOrders, nil
+}
+
+func outboxEvent(t *testing.T, id, topic string, payload interface{}) *entity.OutboxMessage {
+	message, err := entity.NewOutboxMessage(topic, "", payload)
+	assert.NoError(t, err)
+	message.ID = id
+	message.CreatedAt = time.Now()
+	return &message
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":"event-1"}`)
+	signature := Sign("whsec_test", 1700000000, body)
+
+	assert.Regexp(t, `^t=1700000000,v1=[0-9a-f]{64}$`, signature)
+	assert.Equal(t, signature, Sign("whsec_test", 1700000000, body))
+	assert.NotEqual(t, signature, Sign("whsec_other", 1700000000, body))
+	assert.NotEqual(t, signature, Sign("whsec_test", 1700000001, body))
+	assert.NotEqual(t, signature, Sign("whsec_test", 1700000000, []byte(`{"id":"event-2"}`)))
+}
+
+func TestSender(t *testing.T) {
+	t.Run("an event goes to the subscribed endpoints that may see it", func(t *testing.T) {
+		store := &mockWebhookStore{endpoints: []*entity.WebhookEndpoint{
+			{ID: "platform", Events: []string{entity.TopicOrderPaid, entity.TopicProductStockChanged}, IsActive: true},
+			{ID: "store-a", StoreID: "a", Events: []string{entity.TopicOrderPaid}, IsActive: true},
+			{ID: "store-b", StoreID: "b", Events: []string{entity.TopicOrderPaid, entity.TopicProductStockChanged}, IsActive: true},
+			{ID: "store-a-products", StoreID: "a", Events: []string{entity.TopicProductStockChanged}, IsActive: true},
+			{ID: "inactive", Events: []string{entity.TopicOrderPaid}, IsActive: false},
+		}}
+		sender := NewSender(store, &mockOrderStore{stores: map[string][]string{"order-1": {"a"}}})
+
+		paid := outboxEvent(t, "event-1", entity.TopicOrderPaid, entity.OrderEvent{OrderID: "order-1"})
+		assert.NoError(t, sender.Publish(paid))
+		// the outbox may hand an event over again
+		assert.NoError(t, sender.Publish(paid))
+		assert.NoError(t, sender.Publish(outboxEvent(t, "event-2", entity.TopicProductStockChanged, entity.ProductEvent{ProductID: "product-1", StoreID: "b", Quantity: 3})))
+
+		var sent []string
+		for _, delivery := range store.deliveries {
+			sent = append(sent, delivery.EndpointID+" "+delivery.Event)
+		}
+		assert.Equal(t, []string{"platform order.paid", "store-a order.paid", "platform product.stock_changed", "store-b product.stock_changed"}, sent)
+
+		var body entity.WebhookBody
+		assert.NoError(t, json.Unmarshal(store.deliveries[0].Payload, &body))
+		assert.Equal(t, "event-1", body.ID)
+		assert.Equal(t, entity.TopicOrderPaid, body.Type)
+		assert.JSONEq(t, string(paid.Payload), string(body.Data))
+	})
+
+	t.Run("deliveries are signed and retried until the endpoint takes them", func(t *testing.T) {
+		answers := []int{http.StatusInternalServerError, http.StatusOK}
+		var received []*http.Request
+		var bodies [][]byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			received = append(received, r)
+			bodies = append(bodies, body)
+			w.WriteHeader(answers[0])
+			fmt.Fprint(w, "ok")
+			answers = answers[1:]
+		}))
+		defer server.Close()
+
+		store := &mockWebhookStore{endpoints: []*entity.WebhookEndpoint{
+			{ID: "erp", URL: server.URL, Secret: "whsec_erp", Events: []string{entity.TopicOrderPaid}, IsActive: true},
+		}}
+		sender := NewSender(store, &mockOrderStore{})
+		// the test server listens on loopback, which the sender's own client refuses
+		sender.client = server.Client()
+		assert.NoError(t, sender.Publish(outboxEvent(t, "event-1", entity.TopicOrderPaid, entity.OrderEvent{OrderID: "order-1"})))
+		delivery := store.deliveries[0]
+
+		sender.processDue()
+		assert.Equal(t, entity.WebhookFailed, delivery.Status)
+		assert.Equal(t, http.StatusInternalServerError, delivery.ResponseStatus)
+		assert.Equal(t, "endpoint answered 500", delivery.LastError)
+		assert.WithinDuration(t, time.Now().Add(30*time.Second), delivery.NextAttemptAt, 5*time.Second)
+
+		// not due yet
+		sender.processDue()
+		assert.Len(t, received, 1)
+
+		delivery.NextAttemptAt = time.Now()
+		sender.processDue()
+		assert.Equal(t, entity.WebhookDelivered, delivery.Status)
+		assert.Equal(t, "ok", delivery.ResponseBody)
+		assert.Len(t, received, 2)
+
+		request := received[1]
+		assert.Equal(t, entity.TopicOrderPaid, request.Header.Get(HeaderEvent))
+		assert.Equal(t, "event-1", request.Header.Get(HeaderEventID))
+		assert.Equal(t, delivery.ID, request.Header.Get(HeaderDelivery))
+		timestamp, err := strconv.ParseInt(request.Header.Get(HeaderTimestamp), 10, 64)
+		assert.NoError(t, err)
+		assert.Equal(t, Sign("whsec_erp", timestamp, bodies[1]), request.Header.Get(HeaderSignature))
+		assert.Equal(t, bodies[0], bodies[1], "every attempt posts the same body")
+	})
+
+	t.Run("a delivery is given up after the last attempt", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusGone)
+		}))
+		defer server.Close()
+
+		store := &mockWebhookStore{endpoints: []*entity.WebhookEndpoint{
+			{ID: "erp", URL: server.URL, Secret: "whsec_erp", Events: []string{entity.TopicOrderPaid}, IsActive: true},
+		}}
+		sender := NewSender(store, &mockOrderStore{})
+		// the test server listens on loopback, which the sender's own client refuses
+		sender.client = server.Client()
+		assert.NoError(t, sender.Publish(outboxEvent(t, "event-1", entity.TopicOrderPaid, entity.OrderEvent{OrderID: "order-1"})))
+		delivery := store.deliveries[0]
+		delivery.Attempts = int(configs.Envs.WebhookAttempts) - 1
+
+		sender.processDue()
+		assert.Equal(t, entity.WebhookDead, delivery.Status)
+		assert.Equal(t, http.StatusGone, delivery.ResponseStatus)
+	})
+	t.Run("endpoints on the internal network are not posted to", func(t *testing.T) {
+		var received int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received++
+		}))
+		defer server.Close()
+
+		store := &mockWebhookStore{endpoints: []*entity.WebhookEndpoint{
+			{ID: "erp", URL: server.URL, Secret: "whsec_erp", Events: []string{entity.TopicOrderPaid}, IsActive: true},
+		}}
+		sender := NewSender(store, &mockOrderStore{})
+		assert.NoError(t, sender.Publish(outboxEvent(t, "event-1", entity.TopicOrderPaid, entity.OrderEvent{OrderID: "order-1"})))
+		delivery := store.deliveries[0]
+
+		sender.processDue()
+		assert.Equal(t, entity.WebhookFailed, delivery.Status)
+		assert.Contains(t, delivery.LastError, "not a public address")
+		assert.Zero(t, received)
+	})
+
+	t.Run("redirects are not followed", func(t *testing.T) {
+		var followed bool
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/internal" {
+				followed = true
+				return
+			}
+			http.Redirect(w, r, "/internal", http.StatusFound)
+		}))
+		defer server.Close()
+
+		client := newClient(time.Second)
+		client.Transport = server.Client().Transport
+		response, err := client.Get(server.URL)
+		assert.NoError(t, err)
+		response.Body.Close()
+		assert.Equal(t, http.StatusFound, response.StatusCode)
+		assert.False(t, followed)
+	})
+}
+
+func TestCheckEndpointURL(t *testing.T) {
+	for _, refused := range []string{
+		"http://93.184.215.14/hooks",
+		"https://127.0.0.1/hooks",
+		"https://localhost/hooks",
+		"https://[::1]/hooks",
+		"https://10.0.0.7/hooks",
+		"https://192.168.1.1/hooks",
+		"https://169.254.169.254/latest/meta-data",
+		"https://0.0.0.0/hooks",
+	} {
+		assert.Error(t, CheckEndpointURL(context.Background(), refused), refused)
+	}
+
+	assert.NoError(t, CheckEndpointURL(context.Background(), "https://93.184.215.14/hooks"))
+}
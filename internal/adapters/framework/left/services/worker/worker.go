@@ -0,0 +1,87 @@
+// Package worker runs the background loops of the API that work through a
+// table of due rows, outbox messages, webhook deliveries and payment events.
+// Rows are claimed a batch at a time under a lease, so the rows of a worker
+// that died are picked up again, and failed rows are retried with exponential
+// backoff.
+package worker
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// Batch is how many rows a worker claims at a time.
+	Batch = 20
+	// Lease is how long a claimed row is left to its worker before it is
+	// considered abandoned and picked up again.
+	Lease = 5 * time.Minute
+)
+
+// RetryDelay doubles the wait after each failed attempt, starting at 30
+// seconds and capped at an hour.
+func RetryDelay(attempts int) time.Duration {
+	delay := 30 * time.Second
+	for i := 1; i < attempts && delay < time.Hour; i++ {
+		delay *= 2
+	}
+	if delay > time.Hour {
+		delay = time.Hour
+	}
+	return delay
+}
+
+// Drain claims due rows Batch at a time and hands each to handle, until a
+// batch comes back short. It stops at the first claim that fails.
+func Drain[T any](claim func(limit int, lease time.Duration) ([]T, error), handle func(T)) error {
+	for {
+		rows, err := claim(Batch, Lease)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			handle(row)
+		}
+
+		if len(rows) < Batch {
+			return nil
+		}
+	}
+}
+
+// Poller runs the loop of a worker, which looks for due rows on a timer and
+// whenever it is woken up.
+type Poller struct {
+	wake chan struct{}
+}
+
+func NewPoller() *Poller {
+	return &Poller{wake: make(chan struct{}, 1)}
+}
+
+// Run calls process until ctx is done, every interval and right after Wake.
+func (p *Poller) Run(ctx context.Context, interval time.Duration, process func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		process()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-p.wake:
+		}
+	}
+}
+
+// Wake has the loop run again without waiting for its next tick. A wake-up
+// already pending is enough, so Wake never blocks.
+func (p *Poller) Wake() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
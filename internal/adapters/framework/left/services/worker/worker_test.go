@@ -0,0 +1,44 @@
+package worker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, 30*time.Second, RetryDelay(1))
+	assert.Equal(t, time.Minute, RetryDelay(2))
+	assert.Equal(t, 4*time.Minute, RetryDelay(4))
+	assert.Equal(t, time.Hour, RetryDelay(12))
+}
+
+func TestDrain(t *testing.T) {
+	t.Run("batches are claimed until one comes back short", func(t *testing.T) {
+		due := make([]int, Batch+3)
+		for i := range due {
+			due[i] = i
+		}
+		var claims int
+		claim := func(limit int, lease time.Duration) ([]int, error) {
+			claims++
+			assert.Equal(t, Lease, lease)
+			n := min(limit, len(due))
+			claimed := due[:n]
+			due = due[n:]
+			return claimed, nil
+		}
+
+		var handled []int
+		assert.NoError(t, Drain(claim, func(row int) { handled = append(handled, row) }))
+		assert.Len(t, handled, Batch+3)
+		assert.Equal(t, 2, claims)
+	})
+
+	t.Run("a failed claim stops the drain", func(t *testing.T) {
+		claim := func(int, time.Duration) ([]int, error) { return nil, errors.New("database is down") }
+		assert.EqualError(t, Drain(claim, func(int) { t.Fatal("nothing was claimed") }), "database is down")
+	})
+}
@@ -0,0 +1,224 @@
+package address_repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/utils"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) CreateAddress(address entity.UserAddress) (string, error) {
+	address.ID = utils.GenerateRandomUniqueIdentifier()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	if address.IsDefaultShipping {
+		if _, err := tx.Exec("UPDATE addresses SET isDefaultShipping = FALSE WHERE userId = ?", address.UserID); err != nil {
+			return "", err
+		}
+	}
+	if address.IsDefaultBilling {
+		if _, err := tx.Exec("UPDATE addresses SET isDefaultBilling = FALSE WHERE userId = ?", address.UserID); err != nil {
+			return "", err
+		}
+	}
+
+	_, err = tx.Exec("INSERT INTO addresses (id, userId, label, fullName, phone, line1, line2, city, state, postalCode, country, isDefaultShipping, isDefaultBilling) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?)",
+		address.ID, address.UserID, address.Label, address.FullName, address.Phone,
+		address.Address.Line1, address.Address.Line2, address.Address.City, address.Address.State, address.Address.PostalCode, address.Address.Country,
+		address.IsDefaultShipping, address.IsDefaultBilling)
+	if err != nil {
+		return "", fmt.Errorf("failed to create address: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+
+	return address.ID, nil
+}
+
+func (s *Store) GetAddressByID(addressID string) (*entity.UserAddress, error) {
+	rows, err := s.db.Query("SELECT * FROM addresses WHERE id = ?", addressID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query address by id: %w", err)
+	}
+	defer rows.Close()
+
+	var address *entity.UserAddress
+	for rows.Next() {
+		address, err = scanRowsIntoAddress(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if address == nil {
+		return nil, errors.New("address not found")
+	}
+
+	return address, nil
+}
+
+func (s *Store) GetAddressesByUserID(userID string) ([]*entity.UserAddress, error) {
+	rows, err := s.db.Query("SELECT * FROM addresses WHERE userId = ? ORDER BY createdAt", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query addresses: %w", err)
+	}
+	defer rows.Close()
+
+	addresses := make([]*entity.UserAddress, 0)
+	for rows.Next() {
+		address, err := scanRowsIntoAddress(rows)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return addresses, nil
+}
+
+func (s *Store) UpdateAddress(address entity.UserAddress) error {
+	_, err := s.db.Exec("UPDATE addresses SET label = ?, fullName = ?, phone = ?, line1 = ?, line2 = ?, city = ?, state = ?, postalCode = ?, country = ? WHERE id = ?",
+		address.Label, address.FullName, address.Phone,
+		address.Address.Line1, address.Address.Line2, address.Address.City, address.Address.State, address.Address.PostalCode, address.Address.Country,
+		address.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update address: %w", err)
+	}
+
+	if address.IsDefaultShipping {
+		if err := s.SetDefaultShippingAddress(address.UserID, address.ID); err != nil {
+			return err
+		}
+	}
+	if address.IsDefaultBilling {
+		if err := s.SetDefaultBillingAddress(address.UserID, address.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) DeleteAddress(addressID string) error {
+	_, err := s.db.Exec("DELETE FROM addresses WHERE id = ?", addressID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *Store) SetDefaultShippingAddress(userID, addressID string) error {
+	return s.setDefault("isDefaultShipping", userID, addressID)
+}
+
+func (s *Store) SetDefaultBillingAddress(userID, addressID string) error {
+	return s.setDefault("isDefaultBilling", userID, addressID)
+}
+
+func (s *Store) GetDefaultShippingAddress(userID string) (*entity.UserAddress, error) {
+	return s.getDefault("isDefaultShipping", userID)
+}
+
+func (s *Store) GetDefaultBillingAddress(userID string) (*entity.UserAddress, error) {
+	return s.getDefault("isDefaultBilling", userID)
+}
+
+// setDefault clears the flag on every address of the user and sets it on the
+// given one, so a user never has two defaults of the same kind.
+func (s *Store) setDefault(column, userID, addressID string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(fmt.Sprintf("UPDATE addresses SET %s = FALSE WHERE userId = ?", column), userID); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(fmt.Sprintf("UPDATE addresses SET %s = TRUE WHERE id = ? AND userId = ?", column), addressID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("no address found with the given ID")
+	}
+
+	return tx.Commit()
+}
+
+func (s *Store) getDefault(column, userID string) (*entity.UserAddress, error) {
+	rows, err := s.db.Query(fmt.Sprintf("SELECT * FROM addresses WHERE userId = ? AND %s = TRUE LIMIT 1", column), userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query default address: %w", err)
+	}
+	defer rows.Close()
+
+	var address *entity.UserAddress
+	for rows.Next() {
+		address, err = scanRowsIntoAddress(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return address, nil
+}
+
+func scanRowsIntoAddress(rows *sql.Rows) (*entity.UserAddress, error) {
+	address := new(entity.UserAddress)
+
+	err := rows.Scan(
+		&address.ID,
+		&address.UserID,
+		&address.Label,
+		&address.FullName,
+		&address.Phone,
+		&address.Address.Line1,
+		&address.Address.Line2,
+		&address.Address.City,
+		&address.Address.State,
+		&address.Address.PostalCode,
+		&address.Address.Country,
+		&address.IsDefaultShipping,
+		&address.IsDefaultBilling,
+		&address.CreatedAt,
+		&address.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return address, nil
+}
@@ -0,0 +1,64 @@
+package bus_repo
+
+import (
+	"sync"
+
+	"ecom-api/internal/application/core/types/entity"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind by. The
+// events after that are dropped for it rather than holding up the others.
+const subscriberBuffer = 64
+
+// MemoryBus hands events to the subscribers of this instance only, for running
+// a single node and for tests.
+type MemoryBus struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan entity.StreamEvent]struct{}
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{subscribers: make(map[string]map[chan entity.StreamEvent]struct{})}
+}
+
+func (b *MemoryBus) Publish(topic string, event entity.StreamEvent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for subscriber := range b.subscribers[topic] {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBus) Subscribe(topics ...string) (<-chan entity.StreamEvent, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	subscriber := make(chan entity.StreamEvent, subscriberBuffer)
+	for _, topic := range topics {
+		if b.subscribers[topic] == nil {
+			b.subscribers[topic] = make(map[chan entity.StreamEvent]struct{})
+		}
+		b.subscribers[topic][subscriber] = struct{}{}
+	}
+
+	var once sync.Once
+	return subscriber, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+
+			for _, topic := range topics {
+				delete(b.subscribers[topic], subscriber)
+				if len(b.subscribers[topic]) == 0 {
+					delete(b.subscribers, topic)
+				}
+			}
+			close(subscriber)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package bus_repo
+
+import (
+	"testing"
+
+	"ecom-api/internal/application/core/types/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBus(t *testing.T) {
+	t.Run("an event reaches the subscribers of its topic only", func(t *testing.T) {
+		bus := NewMemoryBus()
+		orders, stopOrders := bus.Subscribe("orders.user-1")
+		defer stopOrders()
+		products, stopProducts := bus.Subscribe("product.product-1", "product.product-2")
+		defer stopProducts()
+
+		assert.NoError(t, bus.Publish("orders.user-1", entity.StreamEvent{ID: "event-1", Type: entity.TopicOrderPaid}))
+		assert.NoError(t, bus.Publish("product.product-2", entity.StreamEvent{ID: "event-2", Type: entity.TopicProductStockChanged}))
+		assert.NoError(t, bus.Publish("orders.user-2", entity.StreamEvent{ID: "event-3", Type: entity.TopicOrderPaid}))
+
+		assert.Equal(t, "event-1", (<-orders).ID)
+		assert.Equal(t, "event-2", (<-products).ID)
+		assert.Empty(t, orders)
+		assert.Empty(t, products)
+	})
+
+	t.Run("an unsubscribed channel is closed and hears nothing more", func(t *testing.T) {
+		bus := NewMemoryBus()
+		events, stop := bus.Subscribe("orders.user-1")
+		stop()
+		// stopping twice is harmless
+		stop()
+
+		assert.NoError(t, bus.Publish("orders.user-1", entity.StreamEvent{ID: "event-1"}))
+		_, open := <-events
+		assert.False(t, open)
+		assert.Empty(t, bus.subscribers)
+	})
+
+	t.Run("a subscriber falling behind loses events without holding up the others", func(t *testing.T) {
+		bus := NewMemoryBus()
+		slow, stopSlow := bus.Subscribe("product.product-1")
+		defer stopSlow()
+
+		for i := 0; i < subscriberBuffer+10; i++ {
+			assert.NoError(t, bus.Publish("product.product-1", entity.StreamEvent{Type: entity.TopicProductStockChanged}))
+		}
+		assert.Len(t, slow, subscriberBuffer)
+
+		fresh, stopFresh := bus.Subscribe("product.product-1")
+		defer stopFresh()
+		assert.NoError(t, bus.Publish("product.product-1", entity.StreamEvent{ID: "event-last"}))
+		assert.Equal(t, "event-last", (<-fresh).ID)
+	})
+}
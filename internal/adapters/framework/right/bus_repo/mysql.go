@@ -0,0 +1,175 @@
+package bus_repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/pkg/configs"
+)
+
+const (
+	// streamBatch is how many rows an instance reads at a time.
+	streamBatch = 500
+	// streamRetention is how long rows are kept, long past the moment every
+	// instance read them.
+	streamRetention = 10 * time.Minute
+	// cleanupInterval is how often an instance clears expired rows.
+	cleanupInterval = time.Minute
+	// streamWindow is how long after it was inserted a row is looked for
+	// again, in case it was committed after rows with higher IDs.
+	streamWindow = 5 * time.Second
+)
+
+// MySQLBus carries events between the instances of the API through the
+// stream_events table. Every instance tails the table and hands the new rows
+// to its own subscribers, so a stream gets the events published on any
+// instance. Updates are live only, a stream that was not open when an event
+// was published never gets it.
+type MySQLBus struct {
+	db    *sql.DB
+	local *MemoryBus
+}
+
+func NewMySQLBus(db *sql.DB) *MySQLBus {
+	return &MySQLBus{db: db, local: NewMemoryBus()}
+}
+
+func (b *MySQLBus) Publish(topic string, event entity.StreamEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = b.db.Exec("INSERT INTO stream_events (topic, payload) VALUES (?,?)", topic, payload)
+	return err
+}
+
+func (b *MySQLBus) Subscribe(topics ...string) (<-chan entity.StreamEvent, func()) {
+	return b.local.Subscribe(topics...)
+}
+
+// Run tails the table until ctx is done, every
+// EVENT_BUS_INTERVAL_IN_MILLISECONDS. It starts after the newest row, what
+// was published before the instance started is not replayed.
+func (b *MySQLBus) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Millisecond * time.Duration(configs.Envs.EventBusInterval))
+	defer ticker.Stop()
+
+	var cursor *streamCursor
+	var cleanedAt time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if cursor == nil {
+			var last int64
+			if err := b.db.QueryRow("SELECT COALESCE(MAX(id), 0) FROM stream_events").Scan(&last); err != nil {
+				log.Printf("failed to find the end of the event stream: %v", err)
+				continue
+			}
+			cursor = newStreamCursor(last)
+		}
+
+		if err := b.forward(cursor); err != nil {
+			log.Printf("failed to read the event stream: %v", err)
+		}
+
+		if time.Since(cleanedAt) > cleanupInterval {
+			if _, err := b.db.Exec("DELETE FROM stream_events WHERE createdAt < ?", time.Now().Add(-streamRetention)); err != nil {
+				log.Printf("failed to clear old stream events: %v", err)
+			}
+			cleanedAt = time.Now()
+		}
+	}
+}
+
+// streamCursor is where an instance is in the table. IDs are handed out when
+// a row is inserted, not when it is committed, so a row may show up after rows
+// with higher IDs were read. The rows of the last streamWindow are read again
+// on every pass and the ones handed over already are skipped.
+type streamCursor struct {
+	// start is the newest row when the instance started, the rows up to it
+	// are never handed over.
+	start int64
+	// last is the highest ID handed over.
+	last int64
+	// seen holds when each row of the window was handed over.
+	seen map[int64]time.Time
+}
+
+func newStreamCursor(start int64) *streamCursor {
+	return &streamCursor{start: start, last: start, seen: map[int64]time.Time{}}
+}
+
+// forward hands the rows committed since the last pass to the local
+// subscribers, first the ones of the window committed late, then the ones
+// after the last row handed over.
+func (b *MySQLBus) forward(cursor *streamCursor) error {
+	rows, err := b.db.Query("SELECT id, topic, payload FROM stream_events WHERE id > ? AND id <= ? AND createdAt >= NOW() - INTERVAL ? SECOND ORDER BY id",
+		cursor.start, cursor.last, int(streamWindow/time.Second))
+	if err != nil {
+		return err
+	}
+	if _, err := b.handOver(rows, cursor); err != nil {
+		return err
+	}
+
+	for {
+		rows, err := b.db.Query("SELECT id, topic, payload FROM stream_events WHERE id > ? ORDER BY id LIMIT ?", cursor.last, streamBatch)
+		if err != nil {
+			return err
+		}
+		read, err := b.handOver(rows, cursor)
+		if err != nil {
+			return err
+		}
+		if read < streamBatch {
+			break
+		}
+	}
+
+	for id, at := range cursor.seen {
+		if time.Since(at) > 2*streamWindow {
+			delete(cursor.seen, id)
+		}
+	}
+	return nil
+}
+
+// handOver publishes the rows not seen yet to the local subscribers, closes
+// rows and returns how many rows it read.
+func (b *MySQLBus) handOver(rows *sql.Rows, cursor *streamCursor) (int, error) {
+	defer rows.Close()
+
+	read := 0
+	for rows.Next() {
+		var id int64
+		var topic string
+		var payload []byte
+		if err := rows.Scan(&id, &topic, &payload); err != nil {
+			return read, err
+		}
+		read++
+		if id > cursor.last {
+			cursor.last = id
+		}
+		if _, ok := cursor.seen[id]; ok {
+			continue
+		}
+		cursor.seen[id] = time.Now()
+
+		var event entity.StreamEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			log.Printf("skipping invalid stream event %d: %v", id, err)
+			continue
+		}
+		b.local.Publish(topic, event)
+	}
+	return read, rows.Err()
+}
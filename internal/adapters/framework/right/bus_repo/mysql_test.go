@@ -0,0 +1,58 @@
+package bus_repo
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMySQLBusForward(t *testing.T) {
+	const window = "SELECT id, topic, payload FROM stream_events WHERE id > ? AND id <= ? AND createdAt >= NOW() - INTERVAL ? SECOND ORDER BY id"
+	const after = "SELECT id, topic, payload FROM stream_events WHERE id > ? ORDER BY id LIMIT ?"
+	columns := []string{"id", "topic", "payload"}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error opening mock database %v", err)
+	}
+	defer db.Close()
+
+	bus := NewMySQLBus(db)
+	events, stop := bus.Subscribe("orders.user-1")
+	defer stop()
+	cursor := newStreamCursor(10)
+
+	// rows 11 and 13 are committed, row 12 is not yet
+	mock.ExpectQuery(regexp.QuoteMeta(window)).
+		WithArgs(10, 10, 5).
+		WillReturnRows(sqlmock.NewRows(columns))
+	mock.ExpectQuery(regexp.QuoteMeta(after)).
+		WithArgs(10, streamBatch).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(11, "orders.user-1", []byte(`{"id":"event-11"}`)).
+			AddRow(13, "orders.user-1", []byte(`{"id":"event-13"}`)))
+	assert.NoError(t, bus.forward(cursor))
+	assert.Equal(t, "event-11", (<-events).ID)
+	assert.Equal(t, "event-13", (<-events).ID)
+	assert.Equal(t, int64(13), cursor.last)
+
+	// row 12 is committed after row 13 was read, the window finds it and the
+	// rows handed over already are not handed over again
+	mock.ExpectQuery(regexp.QuoteMeta(window)).
+		WithArgs(10, 13, 5).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(11, "orders.user-1", []byte(`{"id":"event-11"}`)).
+			AddRow(12, "orders.user-1", []byte(`{"id":"event-12"}`)).
+			AddRow(13, "orders.user-1", []byte(`{"id":"event-13"}`)))
+	mock.ExpectQuery(regexp.QuoteMeta(after)).
+		WithArgs(13, streamBatch).
+		WillReturnRows(sqlmock.NewRows(columns))
+	assert.NoError(t, bus.forward(cursor))
+	assert.Equal(t, "event-12", (<-events).ID)
+	assert.Empty(t, events)
+	assert.Equal(t, int64(13), cursor.last)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,189 @@
+package cart_repo
+
+import (
+	"database/sql"
+	"fmt"
+
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/utils"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) CreateCart(cart entity.Cart) (string, error) {
+	cart.ID = utils.GenerateRandomUniqueIdentifier()
+
+	_, err := s.db.Exec("INSERT INTO carts (id, userId, sessionId) VALUES (?,?,?)", cart.ID, nullableString(cart.UserID), nullableString(cart.SessionID))
+	if err != nil {
+		return "", fmt.Errorf("failed to create cart: %w", err)
+	}
+
+	return cart.ID, nil
+}
+
+func (s *Store) GetCartByUserID(userID string) (*entity.Cart, error) {
+	return s.getCart("SELECT * FROM carts WHERE userId = ?", userID)
+}
+
+func (s *Store) GetCartBySessionID(sessionID string) (*entity.Cart, error) {
+	return s.getCart("SELECT * FROM carts WHERE sessionId = ? AND userId IS NULL", sessionID)
+}
+
+func (s *Store) AssignCartToUser(cartID, userID string) error {
+	_, err := s.db.Exec("UPDATE carts SET userId = ?, sessionId = NULL WHERE id = ?", userID, cartID)
+	if err != nil {
+		return fmt.Errorf("failed to assign cart: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) DeleteCart(cartID string) error {
+	_, err := s.db.Exec("DELETE FROM carts WHERE id = ?", cartID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *Store) UpsertCartItem(item entity.CartItem) error {
+	_, err := s.db.Exec("INSERT INTO cartitems (id, cartId, productId, quantity, priceAtAdd, currency) VALUES (?,?,?,?,?,?) ON DUPLICATE KEY UPDATE quantity = VALUES(quantity), priceAtAdd = VALUES(priceAtAdd), currency = VALUES(currency)",
+		utils.GenerateRandomUniqueIdentifier(), item.CartID, item.ProductID, item.Quantity, item.PriceAtAdd.String(), item.Currency)
+	if err != nil {
+		return fmt.Errorf("failed to save cart item: %w", err)
+	}
+
+	return s.touchCart(item.CartID)
+}
+
+func (s *Store) RemoveCartItem(cartID, productID string) error {
+	_, err := s.db.Exec("DELETE FROM cartitems WHERE cartId = ? AND productId = ?", cartID, productID)
+	if err != nil {
+		return err
+	}
+
+	return s.touchCart(cartID)
+}
+
+func (s *Store) ClearCart(cartID string) error {
+	_, err := s.db.Exec("DELETE FROM cartitems WHERE cartId = ?", cartID)
+	if err != nil {
+		return err
+	}
+
+	return s.touchCart(cartID)
+}
+
+func (s *Store) GetCartItems(cartID string) ([]*entity.CartItem, error) {
+	rows, err := s.db.Query("SELECT * FROM cartitems WHERE cartId = ? ORDER BY createdAt", cartID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cart items: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]*entity.CartItem, 0)
+	for rows.Next() {
+		item, err := scanRowsIntoCartItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (s *Store) getCart(query string, arg string) (*entity.Cart, error) {
+	rows, err := s.db.Query(query, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cart: %w", err)
+	}
+	defer rows.Close()
+
+	var cart *entity.Cart
+	for rows.Next() {
+		cart, err = scanRowsIntoCart(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if cart == nil {
+		return nil, nil
+	}
+
+	cart.Items, err = s.GetCartItems(cart.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return cart, nil
+}
+
+// touchCart bumps updatedAt so abandoned carts can be found by last activity.
+func (s *Store) touchCart(cartID string) error {
+	_, err := s.db.Exec("UPDATE carts SET updatedAt = CURRENT_TIMESTAMP WHERE id = ?", cartID)
+	return err
+}
+
+func nullableString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+func scanRowsIntoCart(rows *sql.Rows) (*entity.Cart, error) {
+	cart := new(entity.Cart)
+	var userID, sessionID sql.NullString
+
+	err := rows.Scan(
+		&cart.ID,
+		&userID,
+		&sessionID,
+		&cart.CreatedAt,
+		&cart.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	cart.UserID = userID.String
+	cart.SessionID = sessionID.String
+
+	return cart, nil
+}
+
+func scanRowsIntoCartItem(rows *sql.Rows) (*entity.CartItem, error) {
+	item := new(entity.CartItem)
+	var priceAtAdd string
+
+	err := rows.Scan(
+		&item.ID,
+		&item.CartID,
+		&item.ProductID,
+		&item.Quantity,
+		&priceAtAdd,
+		&item.Currency,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	item.PriceAtAdd, err = entity.ParseMoney(priceAtAdd, item.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
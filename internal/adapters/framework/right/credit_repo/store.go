@@ -0,0 +1,421 @@
+package credit_repo
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/utils"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) CreateGiftCard(card entity.GiftCard) (string, error) {
+	card.ID = utils.GenerateRandomUniqueIdentifier()
+
+	_, err := s.db.Exec("INSERT INTO gift_cards (id, code, initialBalance, balance, currency, status, recipientEmail, note, expiresAt) VALUES (?,?,?,?,?,?,?,?,?)",
+		card.ID, card.Code, card.InitialBalance.String(), card.Balance.String(), card.Currency, card.Status, nullableString(card.RecipientEmail), card.Note, card.ExpiresAt)
+	if err != nil {
+		return "", fmt.Errorf("failed to create gift card: %w", err)
+	}
+
+	return card.ID, nil
+}
+
+func (s *Store) GetGiftCardByID(giftCardID string) (*entity.GiftCard, error) {
+	return s.getGiftCard("SELECT * FROM gift_cards WHERE id = ?", giftCardID)
+}
+
+func (s *Store) GetGiftCardByCode(code string) (*entity.GiftCard, error) {
+	return s.getGiftCard("SELECT * FROM gift_cards WHERE code = ?", code)
+}
+
+func (s *Store) GetGiftCards() ([]*entity.GiftCard, error) {
+	return s.getGiftCards("SELECT * FROM gift_cards ORDER BY createdAt DESC")
+}
+
+func (s *Store) SetGiftCardStatus(giftCardID, status string) (bool, error) {
+	result, err := s.db.Exec("UPDATE gift_cards SET status = ? WHERE id = ?", status, giftCardID)
+	if err != nil {
+		return false, fmt.Errorf("failed to update gift card status: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 1 {
+		return true, nil
+	}
+
+	// setting the status a card already has affects no row either
+	card, err := s.GetGiftCardByID(giftCardID)
+	if err != nil {
+		return false, err
+	}
+	return card.ID != "", nil
+}
+
+// RedeemGiftCard empties a card into a wallet in one transaction, so the same
+// balance cannot be redeemed twice. Disabled and expired cards are left alone.
+func (s *Store) RedeemGiftCard(giftCardID, userID string) (entity.Money, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return entity.Money{}, err
+	}
+	defer tx.Rollback()
+
+	var balance, currency string
+	err = tx.QueryRow("SELECT balance, currency FROM gift_cards WHERE id = ? AND status = ? AND (expiresAt IS NULL OR expiresAt > NOW()) FOR UPDATE",
+		giftCardID, entity.GiftCardActive).Scan(&balance, &currency)
+	if err == sql.ErrNoRows {
+		return entity.Money{}, nil
+	}
+	if err != nil {
+		return entity.Money{}, err
+	}
+
+	amount, err := entity.ParseMoney(balance, currency)
+	if err != nil {
+		return entity.Money{}, err
+	}
+	if amount.IsZero() || amount.IsNegative() {
+		return entity.NewMoney(0, currency), nil
+	}
+
+	if _, err := tx.Exec("UPDATE gift_cards SET balance = 0, updatedAt = NOW() WHERE id = ?", giftCardID); err != nil {
+		return entity.Money{}, fmt.Errorf("failed to empty gift card: %w", err)
+	}
+	if _, err := creditWallet(tx, entity.WalletEntry{
+		UserID:     userID,
+		Amount:     amount,
+		Currency:   amount.Currency,
+		Reason:     entity.WalletGiftCard,
+		GiftCardID: giftCardID,
+	}); err != nil {
+		return entity.Money{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return entity.Money{}, err
+	}
+
+	return amount, nil
+}
+
+func (s *Store) GetWalletBalance(userID, currency string) (entity.Money, error) {
+	var balance string
+	err := s.db.QueryRow("SELECT balance FROM wallets WHERE userId = ? AND currency = ?", userID, currency).Scan(&balance)
+	if err == sql.ErrNoRows {
+		return entity.NewMoney(0, currency), nil
+	}
+	if err != nil {
+		return entity.Money{}, fmt.Errorf("failed to query wallet: %w", err)
+	}
+
+	return entity.ParseMoney(balance, currency)
+}
+
+func (s *Store) GetWalletBalances(userID string) ([]*entity.WalletBalance, error) {
+	rows, err := s.db.Query("SELECT currency, balance FROM wallets WHERE userId = ? ORDER BY currency", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wallets: %w", err)
+	}
+	defer rows.Close()
+
+	var balances []*entity.WalletBalance
+	for rows.Next() {
+		wallet := new(entity.WalletBalance)
+		var balance string
+		if err := rows.Scan(&wallet.Currency, &balance); err != nil {
+			return nil, err
+		}
+		if wallet.Balance, err = entity.ParseMoney(balance, wallet.Currency); err != nil {
+			return nil, err
+		}
+		balances = append(balances, wallet)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return balances, nil
+}
+
+func (s *Store) GetWalletEntries(userID string) ([]*entity.WalletEntry, error) {
+	rows, err := s.db.Query("SELECT * FROM wallet_entries WHERE userId = ? ORDER BY createdAt DESC", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wallet entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*entity.WalletEntry
+	for rows.Next() {
+		entry, err := scanRowsIntoWalletEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (s *Store) CreditWallet(entry entity.WalletEntry) (string, error) {
+	if entry.Amount.IsZero() || entry.Amount.IsNegative() {
+		return "", fmt.Errorf("wallet credit must be positive")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	entryID, err := creditWallet(tx, entry)
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+
+	return entryID, nil
+}
+
+// RefundOrderToWallet credits amount of an order to the wallet of its buyer,
+// what is left to refund when amount is zero. The order row is locked while
+// what was already refunded, on the card and into the wallet, is taken off
+// its total, so concurrent refunds cannot give back more than was paid. When
+// amount is more than is left nothing is credited and the entry ID is empty.
+// Returns the entry ID and what was left to refund before it.
+func (s *Store) RefundOrderToWallet(orderID string, amount entity.Money) (string, entity.Money, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return "", entity.Money{}, err
+	}
+	defer tx.Rollback()
+
+	var userID sql.NullString
+	var total, cardRefunded, currency string
+	err = tx.QueryRow("SELECT userId, total, cardRefunded, currency FROM orders WHERE id = ? FOR UPDATE", orderID).Scan(&userID, &total, &cardRefunded, &currency)
+	if err == sql.ErrNoRows {
+		return "", entity.Money{}, fmt.Errorf("order %s not found", orderID)
+	}
+	if err != nil {
+		return "", entity.Money{}, err
+	}
+	if userID.String == "" {
+		return "", entity.Money{}, fmt.Errorf("order %s has no buyer wallet", orderID)
+	}
+
+	var walletRefunded string
+	err = tx.QueryRow("SELECT COALESCE(SUM(amount), 0) FROM wallet_entries WHERE orderId = ? AND reason = ?", orderID, entity.WalletRefund).Scan(&walletRefunded)
+	if err != nil {
+		return "", entity.Money{}, fmt.Errorf("failed to query wallet refunds: %w", err)
+	}
+
+	refundable, err := entity.ParseMoney(total, currency)
+	if err != nil {
+		return "", entity.Money{}, err
+	}
+	for _, refunded := range []string{cardRefunded, walletRefunded} {
+		amount, err := entity.ParseMoney(refunded, currency)
+		if err != nil {
+			return "", entity.Money{}, err
+		}
+		if refundable, err = refundable.Sub(amount); err != nil {
+			return "", entity.Money{}, err
+		}
+	}
+
+	if amount.IsZero() {
+		amount = refundable
+	}
+	if amount.Currency != currency || amount.Amount > refundable.Amount || amount.IsZero() || amount.IsNegative() {
+		return "", refundable, nil
+	}
+
+	entryID, err := creditWallet(tx, entity.WalletEntry{
+		UserID:   userID.String,
+		Amount:   amount,
+		Currency: currency,
+		Reason:   entity.WalletRefund,
+		OrderID:  orderID,
+	})
+	if err != nil {
+		return "", entity.Money{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", entity.Money{}, err
+	}
+
+	return entryID, refundable, nil
+}
+
+// creditWallet adds to the balance of a wallet, opening it on first use, and
+// records the entry.
+func creditWallet(tx *sql.Tx, entry entity.WalletEntry) (string, error) {
+	_, err := tx.Exec("INSERT INTO wallets (userId, currency, balance) VALUES (?,?,?) ON DUPLICATE KEY UPDATE balance = balance + VALUES(balance)",
+		entry.UserID, entry.Currency, entry.Amount.String())
+	if err != nil {
+		return "", fmt.Errorf("failed to credit wallet: %w", err)
+	}
+
+	return createWalletEntry(tx, entry)
+}
+
+// debitWallet takes from the balance of a wallet only when it covers the
+// amount, and records the entry. Returns false when it does not.
+func debitWallet(tx *sql.Tx, entry entity.WalletEntry) (bool, error) {
+	result, err := tx.Exec("UPDATE wallets SET balance = balance - ? WHERE userId = ? AND currency = ? AND balance >= ?",
+		entry.Amount.String(), entry.UserID, entry.Currency, entry.Amount.String())
+	if err != nil {
+		return false, fmt.Errorf("failed to debit wallet: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected != 1 {
+		return false, nil
+	}
+
+	entry.Amount = entity.NewMoney(-entry.Amount.Amount, entry.Currency)
+	if _, err := createWalletEntry(tx, entry); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func createWalletEntry(tx *sql.Tx, entry entity.WalletEntry) (string, error) {
+	entry.ID = utils.GenerateRandomUniqueIdentifier()
+
+	_, err := tx.Exec("INSERT INTO wallet_entries (id, userId, amount, currency, reason, orderId, giftCardId) VALUES (?,?,?,?,?,?,?)",
+		entry.ID, entry.UserID, entry.Amount.String(), entry.Currency, entry.Reason, nullableString(entry.OrderID), nullableString(entry.GiftCardID))
+	if err != nil {
+		return "", fmt.Errorf("failed to create wallet entry: %w", err)
+	}
+
+	return entry.ID, nil
+}
+
+func (s *Store) getGiftCard(query string, arg string) (*entity.GiftCard, error) {
+	cards, err := s.getGiftCards(query, arg)
+	if err != nil {
+		return nil, err
+	}
+	if len(cards) == 0 {
+		return new(entity.GiftCard), nil
+	}
+	return cards[0], nil
+}
+
+func (s *Store) getGiftCards(query string, args ...interface{}) ([]*entity.GiftCard, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query gift cards: %w", err)
+	}
+	defer rows.Close()
+
+	var cards []*entity.GiftCard
+	for rows.Next() {
+		card, err := scanRowsIntoGiftCard(rows)
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, card)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return cards, nil
+}
+
+func scanRowsIntoGiftCard(rows *sql.Rows) (*entity.GiftCard, error) {
+	card := new(entity.GiftCard)
+	var initialBalance, balance string
+	var recipientEmail sql.NullString
+	var expiresAt sql.NullTime
+
+	err := rows.Scan(
+		&card.ID,
+		&card.Code,
+		&initialBalance,
+		&balance,
+		&card.Currency,
+		&card.Status,
+		&recipientEmail,
+		&card.Note,
+		&expiresAt,
+		&card.CreatedAt,
+		&card.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if card.InitialBalance, err = entity.ParseMoney(initialBalance, card.Currency); err != nil {
+		return nil, err
+	}
+	if card.Balance, err = entity.ParseMoney(balance, card.Currency); err != nil {
+		return nil, err
+	}
+	card.RecipientEmail = recipientEmail.String
+	card.ExpiresAt = nullableTime(expiresAt)
+
+	return card, nil
+}
+
+func scanRowsIntoWalletEntry(rows *sql.Rows) (*entity.WalletEntry, error) {
+	entry := new(entity.WalletEntry)
+	var amount string
+	var orderID, giftCardID sql.NullString
+
+	err := rows.Scan(
+		&entry.ID,
+		&entry.UserID,
+		&amount,
+		&entry.Currency,
+		&entry.Reason,
+		&orderID,
+		&giftCardID,
+		&entry.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.Amount, err = entity.ParseMoney(amount, entry.Currency); err != nil {
+		return nil, err
+	}
+	entry.OrderID = orderID.String
+	entry.GiftCardID = giftCardID.String
+
+	return entry, nil
+}
+
+func nullableString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+func nullableTime(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Time
+}
@@ -0,0 +1,64 @@
+package credit_repo
+
+import (
+	"regexp"
+	"testing"
+
+	"ecom-api/internal/application/core/types/entity"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefundOrderToWallet(t *testing.T) {
+	// the order paid 42.00, 10.00 went back on the card and 12.00 into the wallet
+	expectRefunded := func(mock sqlmock.Sqlmock) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT userId, total, cardRefunded, currency FROM orders WHERE id = ? FOR UPDATE")).
+			WithArgs("order-1").
+			WillReturnRows(sqlmock.NewRows([]string{"userId", "total", "cardRefunded", "currency"}).AddRow("user-1", "42.00", "10.0000", "USD"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(SUM(amount), 0) FROM wallet_entries WHERE orderId = ? AND reason = ?")).
+			WithArgs("order-1", entity.WalletRefund).
+			WillReturnRows(sqlmock.NewRows([]string{"refunded"}).AddRow("12.0000"))
+	}
+
+	t.Run("what was refunded on the card and into the wallet is not refunded again", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error opening mock database %v", err)
+		}
+		defer db.Close()
+
+		expectRefunded(mock)
+		mock.ExpectRollback()
+
+		entryID, refundable, err := NewStore(db).RefundOrderToWallet("order-1", entity.NewMoney(2500, "USD"))
+		assert.NoError(t, err)
+		assert.Empty(t, entryID)
+		assert.Equal(t, entity.NewMoney(2000, "USD"), refundable)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("the rest is credited in the transaction that checked it", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error opening mock database %v", err)
+		}
+		defer db.Close()
+
+		expectRefunded(mock)
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO wallets (userId, currency, balance)")).
+			WithArgs("user-1", "USD", "20.00").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO wallet_entries")).
+			WithArgs(sqlmock.AnyArg(), "user-1", "20.00", "USD", entity.WalletRefund, "order-1", nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		entryID, refundable, err := NewStore(db).RefundOrderToWallet("order-1", entity.NewMoney(0, "USD"))
+		assert.NoError(t, err)
+		assert.NotEmpty(t, entryID)
+		assert.Equal(t, entity.NewMoney(2000, "USD"), refundable)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,177 @@
+package credit_repo
+
+import (
+	"database/sql"
+	"fmt"
+
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/utils"
+)
+
+// ApplyOrderTenders takes every tender in one transaction. A card or wallet
+// spent elsewhere since the tenders were worked out rolls everything back and
+// false is returned, nothing is taken then.
+func (s *Store) ApplyOrderTenders(orderID string, tenders []entity.OrderTender) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	for _, tender := range tenders {
+		var taken bool
+		switch tender.Type {
+		case entity.TenderGiftCard:
+			taken, err = debitGiftCard(tx, tender.GiftCardID, tender.Amount)
+		case entity.TenderWallet:
+			taken, err = debitWallet(tx, entity.WalletEntry{
+				UserID:   tender.UserID,
+				Amount:   tender.Amount,
+				Currency: tender.Currency,
+				Reason:   entity.WalletOrder,
+				OrderID:  orderID,
+			})
+		default:
+			return false, fmt.Errorf("unknown tender type %q", tender.Type)
+		}
+		if err != nil || !taken {
+			return false, err
+		}
+
+		tender.ID = utils.GenerateRandomUniqueIdentifier()
+		_, err = tx.Exec("INSERT INTO order_tenders (id, orderId, type, giftCardId, userId, amount, currency, status) VALUES (?,?,?,?,?,?,?,?)",
+			tender.ID, orderID, tender.Type, nullableString(tender.GiftCardID), nullableString(tender.UserID), tender.Amount.String(), tender.Currency, entity.TenderApplied)
+		if err != nil {
+			return false, fmt.Errorf("failed to create order tender: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *Store) GetOrderTenders(orderID string) ([]*entity.OrderTender, error) {
+	rows, err := s.db.Query("SELECT * FROM order_tenders WHERE orderId = ? ORDER BY createdAt", orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order tenders: %w", err)
+	}
+	defer rows.Close()
+
+	return scanOrderTenders(rows)
+}
+
+// ReleaseOrderTenders puts the credit of an order back on the cards and in the
+// wallets it came from. Tenders already given back are skipped, so releasing
+// an order twice gives nothing back the second time.
+func (s *Store) ReleaseOrderTenders(orderID, status string) (entity.Money, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return entity.Money{}, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT * FROM order_tenders WHERE orderId = ? AND status = ? FOR UPDATE", orderID, entity.TenderApplied)
+	if err != nil {
+		return entity.Money{}, fmt.Errorf("failed to query order tenders: %w", err)
+	}
+	tenders, err := scanOrderTenders(rows)
+	rows.Close()
+	if err != nil {
+		return entity.Money{}, err
+	}
+
+	reason := entity.WalletRelease
+	if status == entity.TenderRefunded {
+		reason = entity.WalletRefund
+	}
+
+	released := entity.Money{}
+	for _, tender := range tenders {
+		switch tender.Type {
+		case entity.TenderGiftCard:
+			_, err = tx.Exec("UPDATE gift_cards SET balance = balance + ?, updatedAt = NOW() WHERE id = ?", tender.Amount.String(), tender.GiftCardID)
+		case entity.TenderWallet:
+			_, err = creditWallet(tx, entity.WalletEntry{
+				UserID:   tender.UserID,
+				Amount:   tender.Amount,
+				Currency: tender.Currency,
+				Reason:   reason,
+				OrderID:  orderID,
+			})
+		}
+		if err != nil {
+			return entity.Money{}, fmt.Errorf("failed to release tender %s: %w", tender.ID, err)
+		}
+
+		if _, err := tx.Exec("UPDATE order_tenders SET status = ? WHERE id = ?", status, tender.ID); err != nil {
+			return entity.Money{}, fmt.Errorf("failed to update order tender: %w", err)
+		}
+		if released, err = released.Add(tender.Amount); err != nil {
+			return entity.Money{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return entity.Money{}, err
+	}
+
+	return released, nil
+}
+
+// debitGiftCard takes from a card only while it is active, unexpired and
+// covers the amount. Returns false when it does not.
+func debitGiftCard(tx *sql.Tx, giftCardID string, amount entity.Money) (bool, error) {
+	result, err := tx.Exec("UPDATE gift_cards SET balance = balance - ?, updatedAt = NOW() WHERE id = ? AND status = ? AND currency = ? AND balance >= ? AND (expiresAt IS NULL OR expiresAt > NOW())",
+		amount.String(), giftCardID, entity.GiftCardActive, amount.Currency, amount.String())
+	if err != nil {
+		return false, fmt.Errorf("failed to debit gift card: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+func scanOrderTenders(rows *sql.Rows) ([]*entity.OrderTender, error) {
+	var tenders []*entity.OrderTender
+	for rows.Next() {
+		tender := new(entity.OrderTender)
+		var giftCardID, userID sql.NullString
+		var amount string
+
+		err := rows.Scan(
+			&tender.ID,
+			&tender.OrderID,
+			&tender.Type,
+			&giftCardID,
+			&userID,
+			&amount,
+			&tender.Currency,
+			&tender.Status,
+			&tender.CreatedAt,
+			&tender.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if tender.Amount, err = entity.ParseMoney(amount, tender.Currency); err != nil {
+			return nil, err
+		}
+		tender.GiftCardID = giftCardID.String
+		tender.UserID = userID.String
+
+		tenders = append(tenders, tender)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tenders, nil
+}
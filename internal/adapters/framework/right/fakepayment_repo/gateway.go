@@ -0,0 +1,791 @@
+package fakepayment_repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/application/core/types/entity/payloads"
+)
+
+// Test cards, named after the Stripe test payment methods they stand in for.
+// A charge succeeds, is declined or asks for 3D Secure depending on the card.
+const (
+	CardSucceeds       = "pm_card_visa"
+	CardDeclined       = "pm_card_visa_chargeDeclined"
+	CardRequiresAction = "pm_card_threeDSecure2Required"
+)
+
+// checkoutPageURL is where the fake pretends to host checkout sessions.
+const checkoutPageURL = "https://checkout.fake.test/pay/"
+
+var cardLast4 = map[string]string{
+	CardSucceeds:       "4242",
+	CardDeclined:       "0002",
+	CardRequiresAction: "3220",
+}
+
+type checkoutSession struct {
+	session         entity.CheckoutSession
+	orderID         string
+	email           string
+	amount          entity.Money
+	status          string // open, complete or expired
+	paymentIntentID string
+	split           entity.PaymentSplit
+}
+
+// reply is what an idempotency key returns when it is used again.
+type reply struct {
+	value interface{}
+	err   error
+}
+
+// Gateway is an in-memory payment gateway for development and tests. It keeps
+// everything in maps, never calls the network and reports what happens through
+// events shaped like Stripe webhook events, so they go through the same event
+// processing as the real ones. Outcomes are chosen with the test cards and the
+// Confirm, Complete and Expire methods play the part of the buyer.
+type Gateway struct {
+	mu       sync.Mutex
+	sequence int
+
+	customers      map[string]*entity.PaymentCustomer
+	customerOrder  []string
+	paymentMethods map[string]*entity.PaymentMethod
+	methodOrder    []string
+	cards          map[string]string // payment method ID to the test card it was saved from
+	setupIntents   map[string]*entity.SetupIntent
+	paymentIntents map[string]*entity.PaymentIntent
+	refunded       map[string]int64 // payment intent ID to the amount refunded so far
+	sessions       map[string]*checkoutSession
+	transfers      []*entity.Transfer
+	reversed       map[string]bool // transfer ID to whether it was taken back
+	subscriptions  map[string]*gatewaySubscription
+	invoices       map[string]string // payment intent ID to the subscription its invoice renews
+	replies        map[string]reply
+
+	events []entity.PaymentEvent
+	sink   func(entity.PaymentEvent) error
+}
+
+func NewGateway() *Gateway {
+	return &Gateway{
+		customers:      map[string]*entity.PaymentCustomer{},
+		paymentMethods: map[string]*entity.PaymentMethod{},
+		cards:          map[string]string{},
+		setupIntents:   map[string]*entity.SetupIntent{},
+		paymentIntents: map[string]*entity.PaymentIntent{},
+		refunded:       map[string]int64{},
+		sessions:       map[string]*checkoutSession{},
+		reversed:       map[string]bool{},
+		subscriptions:  map[string]*gatewaySubscription{},
+		invoices:       map[string]string{},
+		replies:        map[string]reply{},
+	}
+}
+
+// OnEvent hands every event the gateway emits to sink, the way Stripe would
+// call the webhook. A failing sink is logged, the event stays in Events.
+func (gateway *Gateway) OnEvent(sink func(entity.PaymentEvent) error) {
+	gateway.mu.Lock()
+	defer gateway.mu.Unlock()
+	gateway.sink = sink
+}
+
+// Events returns the events emitted so far, oldest first.
+func (gateway *Gateway) Events() []entity.PaymentEvent {
+	gateway.mu.Lock()
+	defer gateway.mu.Unlock()
+	return append([]entity.PaymentEvent(nil), gateway.events...)
+}
+
+func (gateway *Gateway) CreateCustomer(customerParams *payloads.CustomerPayload, idempotencyKey string) (*entity.PaymentCustomer, error) {
+	gateway.mu.Lock()
+	defer gateway.mu.Unlock()
+
+	if previous, ok := gateway.replies[idempotencyKey]; ok && idempotencyKey != "" {
+		return previous.value.(*entity.PaymentCustomer), previous.err
+	}
+
+	customer := &entity.PaymentCustomer{
+		ID:          gateway.nextID("cus"),
+		Email:       customerParams.Email,
+		Name:        customerParams.Name,
+		Phone:       customerParams.Phone,
+		Description: customerParams.Description,
+		Balance:     customerParams.Balance,
+		Metadata:    copyMetadata(customerParams.Metadata),
+		CreatedAt:   time.Now(),
+	}
+	gateway.customers[customer.ID] = customer
+	gateway.customerOrder = append(gateway.customerOrder, customer.ID)
+
+	created := *customer
+	gateway.remember(idempotencyKey, &created, nil)
+	return &created, nil
+}
+
+func (gateway *Gateway) GetCustomer(customerId string) (*entity.PaymentCustomer, error) {
+	gateway.mu.Lock()
+	defer gateway.mu.Unlock()
+
+	customer, ok := gateway.customers[customerId]
+	if !ok {
+		return nil, fmt.Errorf("customer detail retrival failed :no such customer: %s", customerId)
+	}
+	found := *customer
+	return &found, nil
+}
+
+// GetAllCustomers lists the customers newest first, like Stripe does.
+func (gateway *Gateway) GetAllCustomers() ([]*entity.PaymentCustomer, error) {
+	gateway.mu.Lock()
+	defer gateway.mu.Unlock()
+
+	var customers []*entity.PaymentCustomer
+	for i := len(gateway.customerOrder) - 1; i >= 0; i-- {
+		customer, ok := gateway.customers[gateway.customerOrder[i]]
+		if !ok {
+			continue
+		}
+		found := *customer
+		customers = append(customers, &found)
+	}
+	return customers, nil
+}
+
+// DeleteCustomer deletes the customer and detaches its payment methods.
+func (gateway *Gateway) DeleteCustomer(customerId string) (bool, error) {
+	gateway.mu.Lock()
+	defer gateway.mu.Unlock()
+
+	if _, ok := gateway.customers[customerId]; !ok {
+		return false, fmt.Errorf("no such customer: %s", customerId)
+	}
+	delete(gateway.customers, customerId)
+	for _, paymentMethod := range gateway.paymentMethods {
+		if paymentMethod.CustomerID == customerId {
+			paymentMethod.CustomerID = ""
+		}
+	}
+	return true, nil
+}
+
+func (gateway *Gateway) CreateCheckoutSession(order entity.Order, items []*entity.OrderItem, email string, split entity.PaymentSplit, idempotencyKey string) (*entity.CheckoutSession, error) {
+	gateway.mu.Lock()
+	defer gateway.mu.Unlock()
+
+	if previous, ok := gateway.replies[idempotencyKey]; ok && idempotencyKey != "" {
+		return previous.value.(*entity.CheckoutSession), previous.err
+	}
+	due := order.AmountDue()
+	if due.Amount <= 0 {
+		return nil, fmt.Errorf("checkout session needs an amount, order %s has none", order.ID)
+	}
+
+	id := gateway.nextID("cs")
+	session := &checkoutSession{
+		session: entity.CheckoutSession{ID: id, URL: checkoutPageURL + id},
+		orderID: order.ID,
+		email:   email,
+		amount:  due,
+		status:  "open",
+		split:   split,
+	}
+	gateway.sessions[id] = session
+
+	created := session.session
+	gateway.remember(idempotencyKey, &created, nil)
+	return &created, nil
+}
+
+// CreatePaymentMethod saves the succeeding test card on the customer.
+func (gateway *Gateway) CreatePaymentMethod(customerId string) (*entity.PaymentMethod, error) {
+	gateway.mu.Lock()
+	defer gateway.mu.Unlock()
+
+	if _, ok := gateway.customers[customerId]; !ok {
+		return nil, fmt.Errorf("unable to attach payment method to customer %s", customerId)
+	}
+	attached := *gateway.attachCard(customerId, CardSucceeds)
+	return &attached, nil
+}
+
+func (gateway *Gateway) CreateSetupIntent(customerId, idempotencyKey string) (*entity.SetupIntent, error) {
+	gateway.mu.Lock()
+	defer gateway.mu.Unlock()
+
+	if previous, ok := gateway.replies[idempotencyKey]; ok && idempotencyKey != "" {
+		return previous.value.(*entity.SetupIntent), previous.err
+	}
+	if _, ok := gateway.customers[customerId]; !ok {
+		return nil, fmt.Errorf("unable to create setup intent: no such customer: %s", customerId)
+	}
+
+	id := gateway.nextID("seti")
+	intent := &entity.SetupIntent{
+		ID:           id,
+		CustomerID:   customerId,
+		ClientSecret: id + "_secret_fake",
+		Status:       "requires_payment_method",
+	}
+	gateway.setupIntents[id] = intent
+
+	created := *intent
+	gateway.remember(idempotencyKey, &created, nil)
+	return &created, nil
+}
+
+// ListPaymentMethods lists the cards saved on the customer, oldest first.
+func (gateway *Gateway) ListPaymentMethods(customerId string) ([]*entity.PaymentMethod, error) {
+	gateway.mu.Lock()
+	defer gateway.mu.Unlock()
+
+	var paymentMethods []*entity.PaymentMethod
+	for _, id := range gateway.methodOrder {
+		if paymentMethod := gateway.paymentMethods[id]; paymentMethod.CustomerID == customerId {
+			found := *paymentMethod
+			paymentMethods = append(paymentMethods, &found)
+		}
+	}
+	return paymentMethods, nil
+}
+
+func (gateway *Gateway) GetPaymentMethod(paymentMethodId string) (*entity.PaymentMethod, error) {
+	gateway.mu.Lock()
+	defer gateway.mu.Unlock()
+
+	paymentMethod, ok := gateway.paymentMethods[paymentMethodId]
+	if !ok {
+		return nil, fmt.Errorf("payment method retrival failed: no such payment method: %s", paymentMethodId)
+	}
+	found := *paymentMethod
+	return &found, nil
+}
+
+func (gateway *Gateway) SetDefaultPaymentMethod(customerId, paymentMethodId string) error {
+	gateway.mu.Lock()
+	defer gateway.mu.Unlock()
+
+	customer, ok := gateway.customers[customerId]
+	if !ok {
+		return fmt.Errorf("unable to set default payment method: no such customer: %s", customerId)
+	}
+	paymentMethod, ok := gateway.paymentMethods[paymentMethodId]
+	if !ok || paymentMethod.CustomerID != customerId {
+		return fmt.Errorf("unable to set default payment method: %s is not attached to %s", paymentMethodId, customerId)
+	}
+	customer.DefaultPaymentMethodID = paymentMethodId
+	return nil
+}
+
+// DetachPaymentMethod removes the card from its customer, and from the
+// customer's default when it was that.
+func (gateway *Gateway) DetachPaymentMethod(paymentMethodId string) error {
+	gateway.mu.Lock()
+	paymentMethod, ok := gateway.paymentMethods[paymentMethodId]
+	if !ok || paymentMethod.CustomerID == "" {
+		gateway.mu.Unlock()
+		return fmt.Errorf("unable to detach payment method %s", paymentMethodId)
+	}
+	if customer, ok := gateway.customers[paymentMethod.CustomerID]; ok && customer.DefaultPaymentMethodID == paymentMethodId {
+		customer.DefaultPaymentMethodID = ""
+	}
+	paymentMethod.CustomerID = ""
+	event := gateway.event("payment_method.detached", paymentMethodObject(paymentMethod))
+	gateway.mu.Unlock()
+
+	gateway.publish(event)
+	return nil
+}
+
+// CreateCharge charges the chosen card of the customer. The succeeding card
+// is charged at once, the declined one fails with entity.ErrPaymentDeclined and
+// the 3D Secure one waits in requires_action until ConfirmPaymentIntent.
+func (gateway *Gateway) CreateCharge(chargeParams *payloads.CustomerChargeRequest, customerId, idempotencyKey string) (*entity.PaymentIntent, error) {
+	gateway.mu.Lock()
+	if previous, ok := gateway.replies[idempotencyKey]; ok && idempotencyKey != "" {
+		gateway.mu.Unlock()
+		intent, _ := previous.value.(*entity.PaymentIntent)
+		return intent, previous.err
+	}
+	intent, events, err := gateway.createCharge(chargeParams, customerId)
+	gateway.remember(idempotencyKey, intent, err)
+	gateway.mu.Unlock()
+
+	gateway.publish(events...)
+	return intent, err
+}
+
+func (gateway *Gateway) createCharge(chargeParams *payloads.CustomerChargeRequest, customerId string) (*entity.PaymentIntent, []entity.PaymentEvent, error) {
+	if _, ok := gateway.customers[customerId]; !ok {
+		return nil, nil, fmt.Errorf("payment faild:no such customer: %s", customerId)
+	}
+	if chargeParams.PaymentMethodID == "" {
+		return nil, nil, fmt.Errorf("payment faild:no payment method to charge")
+	}
+
+	// test cards can be charged directly, like on Stripe
+	card, ok := gateway.cards[chargeParams.PaymentMethodID]
+	if !ok {
+		if _, known := cardLast4[chargeParams.PaymentMethodID]; !known {
+			return nil, nil, fmt.Errorf("payment faild:no such payment method: %s", chargeParams.PaymentMethodID)
+		}
+		card = chargeParams.PaymentMethodID
+	}
+
+	id := gateway.nextID("pi")
+	intent := &entity.PaymentIntent{
+		ID:              id,
+		CustomerID:      customerId,
+		PaymentMethodID: chargeParams.PaymentMethodID,
+		Amount:          entity.NewMoney(chargeParams.Amount, chargeParams.Currency),
+		Description:     chargeParams.Description,
+		Metadata:        map[string]string{},
+	}
+	gateway.paymentIntents[id] = intent
+
+	var events []entity.PaymentEvent
+	var err error
+	switch card {
+	case CardDeclined:
+		intent.Status = entity.PaymentIntentFailed
+		events = append(events, gateway.event("payment_intent.payment_failed", paymentIntentObject(intent)))
+		err = fmt.Errorf("%w: your card was declined", entity.ErrPaymentDeclined)
+	case CardRequiresAction:
+		intent.Status = entity.PaymentIntentRequiresAction
+		intent.ClientSecret = id + "_secret_fake"
+	default:
+		intent.Status = entity.PaymentIntentSucceeded
+		events = gateway.paymentSucceeded(intent, chargeParams.ReceiptEmail, nil)
+	}
+
+	charged := *intent
+	return &charged, events, err
+}
+
+// ConfirmPaymentIntent completes 3D Secure for a payment waiting on it, as the
+// buyer would in the browser.
+func (gateway *Gateway) ConfirmPaymentIntent(paymentIntentId string) (*entity.PaymentIntent, error) {
+	gateway.mu.Lock()
+	intent, ok := gateway.paymentIntents[paymentIntentId]
+	if !ok || intent.Status != entity.PaymentIntentRequiresAction {
+		gateway.mu.Unlock()
+		return nil, fmt.Errorf("payment intent %s does not require action", paymentIntentId)
+	}
+	intent.Status = entity.PaymentIntentSucceeded
+	events := gateway.paymentSucceeded(intent, "", nil)
+	if subscription, ok := gateway.subscriptions[gateway.invoices[intent.ID]]; ok {
+		subscription.subscription.Status = entity.SubscriptionActive
+		events = append(events, gateway.invoicePaid(subscription, intent, "subscription_create"), gateway.event("customer.subscription.updated", subscriptionObject(subscription)))
+	}
+	confirmed := *intent
+	gateway.mu.Unlock()
+
+	gateway.publish(events...)
+	return &confirmed, nil
+}
+
+// CreateRefund refunds a succeeded payment, up to what is left of it.
+func (gateway *Gateway) CreateRefund(paymentIntentId string, amount int64, idempotencyKey string) (*entity.Refund, error) {
+	gateway.mu.Lock()
+	if previous, ok := gateway.replies[idempotencyKey]; ok && idempotencyKey != "" {
+		gateway.mu.Unlock()
+		refund, _ := previous.value.(*entity.Refund)
+		return refund, previous.err
+	}
+
+	intent, ok := gateway.paymentIntents[paymentIntentId]
+	if !ok || intent.Status != entity.PaymentIntentSucceeded {
+		gateway.mu.Unlock()
+		return nil, fmt.Errorf("refund failed: payment %s has not succeeded", paymentIntentId)
+	}
+	remaining := intent.Amount.Amount - gateway.refunded[paymentIntentId]
+	if amount == 0 {
+		amount = remaining
+	}
+	if amount <= 0 || amount > remaining {
+		gateway.mu.Unlock()
+		return nil, fmt.Errorf("refund failed: %d exceeds the %d left to refund", amount, remaining)
+	}
+	gateway.refunded[paymentIntentId] += amount
+
+	refund := &entity.Refund{
+		ID:              gateway.nextID("re"),
+		PaymentIntentID: paymentIntentId,
+		Amount:          entity.NewMoney(amount, intent.Amount.Currency),
+		Status:          "succeeded",
+	}
+	gateway.remember(idempotencyKey, refund, nil)
+	event := gateway.event("charge.refunded", map[string]interface{}{
+		"id":              "ch_" + strings.TrimPrefix(paymentIntentId, "pi_"),
+		"object":          "charge",
+		"amount":          intent.Amount.Amount,
+		"amount_refunded": gateway.refunded[paymentIntentId],
+		"currency":        strings.ToLower(intent.Amount.Currency),
+		"payment_intent":  paymentIntentId,
+		"refunded":        gateway.refunded[paymentIntentId] == intent.Amount.Amount,
+		"metadata":        intent.Metadata,
+	})
+	gateway.mu.Unlock()
+
+	gateway.publish(event)
+	return refund, nil
+}
+
+// CreateTransfer pays a connected account. The fake keeps no balances, so a
+// transfer always goes through.
+func (gateway *Gateway) CreateTransfer(destination string, amount entity.Money, transferGroup, sourceChargeId, idempotencyKey string) (*entity.Transfer, error) {
+	gateway.mu.Lock()
+	defer gateway.mu.Unlock()
+
+	if previous, ok := gateway.replies[idempotencyKey]; ok && idempotencyKey != "" {
+		return previous.value.(*entity.Transfer), previous.err
+	}
+	if destination == "" || amount.Amount <= 0 {
+		return nil, fmt.Errorf("transfer failed: %d to %q", amount.Amount, destination)
+	}
+
+	created := *gateway.transfer(destination, amount, transferGroup)
+	gateway.remember(idempotencyKey, &created, nil)
+	return &created, nil
+}
+
+func (gateway *Gateway) ReverseTransfer(transferId, idempotencyKey string) error {
+	gateway.mu.Lock()
+	defer gateway.mu.Unlock()
+
+	for _, transfer := range gateway.transfers {
+		if transfer.ID != transferId {
+			continue
+		}
+		if gateway.reversed[transferId] && idempotencyKey == "" {
+			return fmt.Errorf("transfer reversal failed: %s is already reversed", transferId)
+		}
+		gateway.reversed[transferId] = true
+		return nil
+	}
+	return fmt.Errorf("transfer reversal failed: no such transfer: %s", transferId)
+}
+
+// Transfers returns the transfers made so far, oldest first, and which of them
+// were taken back.
+func (gateway *Gateway) Transfers() ([]entity.Transfer, map[string]bool) {
+	gateway.mu.Lock()
+	defer gateway.mu.Unlock()
+
+	transfers := make([]entity.Transfer, len(gateway.transfers))
+	reversed := map[string]bool{}
+	for i, transfer := range gateway.transfers {
+		transfers[i] = *transfer
+		reversed[transfer.ID] = gateway.reversed[transfer.ID]
+	}
+	return transfers, reversed
+}
+
+// ConfirmSetupIntent saves card on the customer of the setup, as the buyer
+// would by confirming it in the browser.
+func (gateway *Gateway) ConfirmSetupIntent(setupIntentId, card string) (*entity.PaymentMethod, error) {
+	gateway.mu.Lock()
+	intent, ok := gateway.setupIntents[setupIntentId]
+	if !ok || intent.Status == "succeeded" {
+		gateway.mu.Unlock()
+		return nil, fmt.Errorf("setup intent %s cannot be confirmed", setupIntentId)
+	}
+	if _, known := cardLast4[card]; !known {
+		gateway.mu.Unlock()
+		return nil, fmt.Errorf("unknown test card %s", card)
+	}
+
+	paymentMethod := gateway.attachCard(intent.CustomerID, card)
+	intent.Status = "succeeded"
+	event := gateway.event("setup_intent.succeeded", map[string]interface{}{
+		"id":             intent.ID,
+		"object":         "setup_intent",
+		"customer":       intent.CustomerID,
+		"payment_method": paymentMethod.ID,
+		"status":         intent.Status,
+	})
+	attached := *paymentMethod
+	gateway.mu.Unlock()
+
+	gateway.publish(event)
+	return &attached, nil
+}
+
+// CompleteCheckoutSession pays a checkout session, as the buyer would on the
+// hosted page.
+func (gateway *Gateway) CompleteCheckoutSession(sessionId string) error {
+	gateway.mu.Lock()
+	session, ok := gateway.sessions[sessionId]
+	if !ok || session.status != "open" {
+		gateway.mu.Unlock()
+		return fmt.Errorf("checkout session %s is not open", sessionId)
+	}
+
+	intent := &entity.PaymentIntent{
+		ID:          gateway.nextID("pi"),
+		Amount:      session.amount,
+		Status:      entity.PaymentIntentSucceeded,
+		Description: "Order " + session.orderID,
+		Metadata:    map[string]string{"order_id": session.orderID},
+	}
+	gateway.paymentIntents[intent.ID] = intent
+	session.status = "complete"
+	session.paymentIntentID = intent.ID
+
+	events := []entity.PaymentEvent{gateway.event("checkout.session.completed", sessionObject(session, "paid"))}
+	// a destination charge pays the store with the charge, less the fee
+	split := map[string]interface{}{"transfer_group": nullable(session.split.TransferGroup)}
+	if session.split.Destination != "" {
+		amount, err := session.amount.Sub(session.split.ApplicationFee)
+		if err != nil {
+			gateway.mu.Unlock()
+			return err
+		}
+		split["transfer"] = gateway.transfer(session.split.Destination, amount, session.split.TransferGroup).ID
+		split["application_fee_amount"] = session.split.ApplicationFee.Amount
+	}
+	events = append(events, gateway.paymentSucceeded(intent, session.email, split)...)
+	gateway.mu.Unlock()
+
+	gateway.publish(events...)
+	return nil
+}
+
+// ExpireCheckoutSession lets a checkout session run out without being paid.
+func (gateway *Gateway) ExpireCheckoutSession(sessionId string) error {
+	gateway.mu.Lock()
+	session, ok := gateway.sessions[sessionId]
+	if !ok || session.status != "open" {
+		gateway.mu.Unlock()
+		return fmt.Errorf("checkout session %s is not open", sessionId)
+	}
+	session.status = "expired"
+	event := gateway.event("checkout.session.expired", sessionObject(session, "unpaid"))
+	gateway.mu.Unlock()
+
+	gateway.publish(event)
+	return nil
+}
+
+// FailCheckoutSession fails the payment of a checkout session, as a bank debit
+// started on the hosted page may once it bounces. The session is complete but
+// unpaid.
+func (gateway *Gateway) FailCheckoutSession(sessionId string) error {
+	gateway.mu.Lock()
+	session, ok := gateway.sessions[sessionId]
+	if !ok || session.status != "open" {
+		gateway.mu.Unlock()
+		return fmt.Errorf("checkout session %s is not open", sessionId)
+	}
+	session.status = "complete"
+	event := gateway.event("checkout.session.async_payment_failed", sessionObject(session, "unpaid"))
+	gateway.mu.Unlock()
+
+	gateway.publish(event)
+	return nil
+}
+
+// DeclineCheckoutSession declines a card tried on the hosted page. The session
+// stays open for the buyer to try again with the same payment intent.
+func (gateway *Gateway) DeclineCheckoutSession(sessionId string) error {
+	gateway.mu.Lock()
+	session, ok := gateway.sessions[sessionId]
+	if !ok || session.status != "open" {
+		gateway.mu.Unlock()
+		return fmt.Errorf("checkout session %s is not open", sessionId)
+	}
+
+	intent, ok := gateway.paymentIntents[session.paymentIntentID]
+	if !ok {
+		intent = &entity.PaymentIntent{
+			ID:          gateway.nextID("pi"),
+			Amount:      session.amount,
+			Description: "Order " + session.orderID,
+			Metadata:    map[string]string{"order_id": session.orderID},
+		}
+		gateway.paymentIntents[intent.ID] = intent
+		session.paymentIntentID = intent.ID
+	}
+	intent.Status = entity.PaymentIntentFailed
+	event := gateway.event("payment_intent.payment_failed", paymentIntentObject(intent))
+	gateway.mu.Unlock()
+
+	gateway.publish(event)
+	return nil
+}
+
+// nextID returns a new identifier with the prefix Stripe uses for the object.
+func (gateway *Gateway) nextID(prefix string) string {
+	gateway.sequence++
+	return fmt.Sprintf("%s_fake_%d", prefix, gateway.sequence)
+}
+
+func (gateway *Gateway) remember(idempotencyKey string, value interface{}, err error) {
+	if idempotencyKey != "" {
+		gateway.replies[idempotencyKey] = reply{value: value, err: err}
+	}
+}
+
+func (gateway *Gateway) transfer(destination string, amount entity.Money, transferGroup string) *entity.Transfer {
+	transfer := &entity.Transfer{
+		ID:            gateway.nextID("tr"),
+		Destination:   destination,
+		Amount:        amount,
+		TransferGroup: transferGroup,
+	}
+	gateway.transfers = append(gateway.transfers, transfer)
+	return transfer
+}
+
+func (gateway *Gateway) attachCard(customerId, card string) *entity.PaymentMethod {
+	paymentMethod := &entity.PaymentMethod{
+		ID:         gateway.nextID("pm"),
+		CustomerID: customerId,
+		Type:       "card",
+		Brand:      "visa",
+		Last4:      cardLast4[card],
+		ExpMonth:   12,
+		ExpYear:    time.Now().Year() + 1,
+	}
+	gateway.paymentMethods[paymentMethod.ID] = paymentMethod
+	gateway.methodOrder = append(gateway.methodOrder, paymentMethod.ID)
+	gateway.cards[paymentMethod.ID] = card
+	return paymentMethod
+}
+
+// paymentSucceeded returns the events Stripe sends once money is taken. The
+// fields of chargeFields are added to the charge.
+func (gateway *Gateway) paymentSucceeded(intent *entity.PaymentIntent, receiptEmail string, chargeFields map[string]interface{}) []entity.PaymentEvent {
+	charge := map[string]interface{}{
+		"id":             "ch_" + strings.TrimPrefix(intent.ID, "pi_"),
+		"object":         "charge",
+		"amount":         intent.Amount.Amount,
+		"currency":       strings.ToLower(intent.Amount.Currency),
+		"customer":       nullable(intent.CustomerID),
+		"payment_intent": intent.ID,
+		"payment_method": nullable(intent.PaymentMethodID),
+		"receipt_email":  receiptEmail,
+		"description":    intent.Description,
+		"metadata":       intent.Metadata,
+		"paid":           true,
+		"status":         "succeeded",
+	}
+	for key, value := range chargeFields {
+		charge[key] = value
+	}
+	return []entity.PaymentEvent{
+		gateway.event("payment_intent.succeeded", paymentIntentObject(intent)),
+		gateway.event("charge.succeeded", charge),
+	}
+}
+
+// event wraps object the way a Stripe webhook event does.
+func (gateway *Gateway) event(eventType string, object map[string]interface{}) entity.PaymentEvent {
+	id := gateway.nextID("evt")
+	payload, err := json.Marshal(map[string]interface{}{
+		"id":       id,
+		"object":   "event",
+		"type":     eventType,
+		"created":  time.Now().Unix(),
+		"livemode": false,
+		"data":     map[string]interface{}{"object": object},
+	})
+	if err != nil {
+		// the objects are built from plain values above and always marshal
+		panic(err)
+	}
+	return entity.PaymentEvent{ID: id, Type: eventType, Payload: payload}
+}
+
+func (gateway *Gateway) publish(events ...entity.PaymentEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	gateway.mu.Lock()
+	gateway.events = append(gateway.events, events...)
+	sink := gateway.sink
+	gateway.mu.Unlock()
+
+	if sink == nil {
+		return
+	}
+	for _, event := range events {
+		if err := sink(event); err != nil {
+			log.Printf("fake payment gateway failed to deliver event %s: %v", event.ID, err)
+		}
+	}
+}
+
+func paymentIntentObject(intent *entity.PaymentIntent) map[string]interface{} {
+	status := intent.Status
+	if status == entity.PaymentIntentFailed {
+		status = "requires_payment_method"
+	}
+	return map[string]interface{}{
+		"id":             intent.ID,
+		"object":         "payment_intent",
+		"amount":         intent.Amount.Amount,
+		"currency":       strings.ToLower(intent.Amount.Currency),
+		"customer":       nullable(intent.CustomerID),
+		"payment_method": nullable(intent.PaymentMethodID),
+		"description":    intent.Description,
+		"metadata":       intent.Metadata,
+		"status":         status,
+	}
+}
+
+func paymentMethodObject(paymentMethod *entity.PaymentMethod) map[string]interface{} {
+	return map[string]interface{}{
+		"id":       paymentMethod.ID,
+		"object":   "payment_method",
+		"type":     paymentMethod.Type,
+		"customer": nullable(paymentMethod.CustomerID),
+		"card": map[string]interface{}{
+			"brand":     paymentMethod.Brand,
+			"last4":     paymentMethod.Last4,
+			"exp_month": paymentMethod.ExpMonth,
+			"exp_year":  paymentMethod.ExpYear,
+		},
+	}
+}
+
+func sessionObject(session *checkoutSession, paymentStatus string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                  session.session.ID,
+		"object":              "checkout.session",
+		"client_reference_id": session.orderID,
+		"customer_email":      session.email,
+		"amount_total":        session.amount.Amount,
+		"currency":            strings.ToLower(session.amount.Currency),
+		"metadata":            map[string]string{"order_id": session.orderID},
+		"mode":                "payment",
+		"payment_intent":      nullable(session.paymentIntentID),
+		"payment_status":      paymentStatus,
+		"status":              session.status,
+		"url":                 session.session.URL,
+	}
+}
+
+// nullable leaves out empty references the way Stripe sends them, as null.
+func nullable(id string) interface{} {
+	if id == "" {
+		return nil
+	}
+	return id
+}
+
+func copyMetadata(metadata map[string]string) map[string]string {
+	copied := make(map[string]string, len(metadata))
+	for key, value := range metadata {
+		copied[key] = value
+	}
+	return copied
+}
@@ -0,0 +1,147 @@
+package fakepayment_repo
+
+import (
+	"errors"
+	"testing"
+
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/application/core/types/entity/payloads"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func eventTypes(events []entity.PaymentEvent) []string {
+	var types []string
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func TestGatewayCharges(t *testing.T) {
+	gateway := NewGateway()
+	customer, err := gateway.CreateCustomer(&payloads.CustomerPayload{Name: "Jane Doe", Email: "jane@example.com"}, "")
+	assert.NoError(t, err)
+
+	charge := func(card, key string) (*entity.PaymentIntent, error) {
+		return gateway.CreateCharge(&payloads.CustomerChargeRequest{
+			Amount:          1500,
+			Currency:        "usd",
+			ReceiptEmail:    "jane@example.com",
+			Description:     "test",
+			PaymentMethodID: card,
+		}, customer.ID, key)
+	}
+
+	t.Run("takes the money with the succeeding card", func(t *testing.T) {
+		before := len(gateway.Events())
+		intent, err := charge(CardSucceeds, "")
+		assert.NoError(t, err)
+		assert.Equal(t, entity.PaymentIntentSucceeded, intent.Status)
+		assert.Equal(t, entity.NewMoney(1500, "USD"), intent.Amount)
+		assert.Equal(t, []string{"payment_intent.succeeded", "charge.succeeded"}, eventTypes(gateway.Events()[before:]))
+	})
+
+	t.Run("declines the declined card", func(t *testing.T) {
+		before := len(gateway.Events())
+		_, err := charge(CardDeclined, "")
+		assert.True(t, errors.Is(err, entity.ErrPaymentDeclined))
+		assert.Equal(t, []string{"payment_intent.payment_failed"}, eventTypes(gateway.Events()[before:]))
+	})
+
+	t.Run("waits for 3D Secure until confirmed", func(t *testing.T) {
+		before := len(gateway.Events())
+		intent, err := charge(CardRequiresAction, "")
+		assert.NoError(t, err)
+		assert.Equal(t, entity.PaymentIntentRequiresAction, intent.Status)
+		assert.NotEmpty(t, intent.ClientSecret)
+		assert.Empty(t, gateway.Events()[before:])
+
+		confirmed, err := gateway.ConfirmPaymentIntent(intent.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, entity.PaymentIntentSucceeded, confirmed.Status)
+		assert.Equal(t, []string{"payment_intent.succeeded", "charge.succeeded"}, eventTypes(gateway.Events()[before:]))
+
+		_, err = gateway.ConfirmPaymentIntent(intent.ID)
+		assert.Error(t, err)
+	})
+
+	t.Run("charges a saved card by the card it was saved from", func(t *testing.T) {
+		intent, err := gateway.CreateSetupIntent(customer.ID, "")
+		assert.NoError(t, err)
+		paymentMethod, err := gateway.ConfirmSetupIntent(intent.ID, CardDeclined)
+		assert.NoError(t, err)
+		assert.Equal(t, customer.ID, paymentMethod.CustomerID)
+
+		_, err = charge(paymentMethod.ID, "")
+		assert.True(t, errors.Is(err, entity.ErrPaymentDeclined))
+	})
+
+	t.Run("replays a retried charge without charging again", func(t *testing.T) {
+		first, err := charge(CardSucceeds, "charge-1")
+		assert.NoError(t, err)
+		before := len(gateway.Events())
+
+		retry, err := charge(CardSucceeds, "charge-1")
+		assert.NoError(t, err)
+		assert.Equal(t, first.ID, retry.ID)
+		assert.Len(t, gateway.Events(), before)
+	})
+
+	t.Run("refuses unknown cards", func(t *testing.T) {
+		_, err := charge("pm_unknown", "")
+		assert.Error(t, err)
+		assert.False(t, errors.Is(err, entity.ErrPaymentDeclined))
+	})
+}
+
+func TestGatewayRefunds(t *testing.T) {
+	gateway := NewGateway()
+	customer, _ := gateway.CreateCustomer(&payloads.CustomerPayload{Name: "Jane Doe", Email: "jane@example.com"}, "")
+	intent, err := gateway.CreateCharge(&payloads.CustomerChargeRequest{Amount: 1000, Currency: "eur", PaymentMethodID: CardSucceeds}, customer.ID, "")
+	assert.NoError(t, err)
+
+	refund, err := gateway.CreateRefund(intent.ID, 400, "")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.NewMoney(400, "EUR"), refund.Amount)
+
+	_, err = gateway.CreateRefund(intent.ID, 700, "")
+	assert.Error(t, err, "refunds more than what is left")
+
+	refund, err = gateway.CreateRefund(intent.ID, 0, "")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(600), refund.Amount.Amount, "refunds the rest")
+
+	_, err = gateway.CreateRefund(intent.ID, 0, "")
+	assert.Error(t, err, "nothing is left")
+}
+
+func TestGatewayCheckoutSessions(t *testing.T) {
+	gateway := NewGateway()
+	var delivered []entity.PaymentEvent
+	gateway.OnEvent(func(event entity.PaymentEvent) error {
+		delivered = append(delivered, event)
+		return nil
+	})
+
+	order := entity.Order{ID: "order-1", Total: entity.NewMoney(2500, "USD"), Currency: "USD"}
+	session, err := gateway.CreateCheckoutSession(order, nil, "jane@example.com", entity.PaymentSplit{}, "checkout-1")
+	assert.NoError(t, err)
+	assert.Contains(t, session.URL, session.ID)
+
+	retry, err := gateway.CreateCheckoutSession(order, nil, "jane@example.com", entity.PaymentSplit{}, "checkout-1")
+	assert.NoError(t, err)
+	assert.Equal(t, session.ID, retry.ID)
+
+	assert.NoError(t, gateway.CompleteCheckoutSession(session.ID))
+	assert.Equal(t, []string{"checkout.session.completed", "payment_intent.succeeded", "charge.succeeded"}, eventTypes(delivered))
+	assert.Contains(t, string(delivered[0].Payload), `"order_id":"order-1"`)
+	assert.Error(t, gateway.ExpireCheckoutSession(session.ID), "a paid session cannot expire")
+
+	other, _ := gateway.CreateCheckoutSession(order, nil, "", entity.PaymentSplit{}, "")
+	assert.NoError(t, gateway.DeclineCheckoutSession(other.ID))
+	assert.Equal(t, "payment_intent.payment_failed", delivered[len(delivered)-1].Type)
+	assert.Contains(t, string(delivered[len(delivered)-1].Payload), `"order_id":"order-1"`)
+	assert.NoError(t, gateway.ExpireCheckoutSession(other.ID), "a declined session stays open")
+	assert.Equal(t, "checkout.session.expired", delivered[len(delivered)-1].Type)
+}
@@ -0,0 +1,281 @@
+package fakepayment_repo
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"ecom-api/internal/application/core/subscription"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/application/core/types/entity/payloads"
+)
+
+type gatewaySubscription struct {
+	subscription entity.PaymentSubscription
+	plan         entity.SubscriptionPlan
+	skipped      bool // the coming renewal is not charged
+}
+
+// CreateSubscription subscribes the customer and charges the first renewal
+// with the chosen card, the customer's default one otherwise. The test cards
+// decide the outcome the way they do for charges: the 3D Secure card leaves
+// the subscription incomplete until ConfirmPaymentIntent.
+func (gateway *Gateway) CreateSubscription(customerId string, plan entity.SubscriptionPlan, idempotencyKey string) (*entity.PaymentSubscription, error) {
+	gateway.mu.Lock()
+	if previous, ok := gateway.replies[idempotencyKey]; ok && idempotencyKey != "" {
+		gateway.mu.Unlock()
+		created, _ := previous.value.(*entity.PaymentSubscription)
+		return created, previous.err
+	}
+	created, events, err := gateway.createSubscription(customerId, plan)
+	gateway.remember(idempotencyKey, created, err)
+	gateway.mu.Unlock()
+
+	gateway.publish(events...)
+	return created, err
+}
+
+func (gateway *Gateway) createSubscription(customerId string, plan entity.SubscriptionPlan) (*entity.PaymentSubscription, []entity.PaymentEvent, error) {
+	customer, ok := gateway.customers[customerId]
+	if !ok {
+		return nil, nil, fmt.Errorf("payment faild:no such customer: %s", customerId)
+	}
+	if plan.PaymentMethodID == "" {
+		plan.PaymentMethodID = customer.DefaultPaymentMethodID
+	}
+	if plan.Quantity < 1 {
+		return nil, nil, fmt.Errorf("subscription needs a quantity")
+	}
+	plan.Metadata = copyMetadata(plan.Metadata)
+
+	sub := &gatewaySubscription{
+		subscription: entity.PaymentSubscription{
+			ID:         gateway.nextID("sub"),
+			CustomerID: customerId,
+			Status:     entity.SubscriptionIncomplete,
+		},
+		plan: plan,
+	}
+	if err := sub.advance(time.Now()); err != nil {
+		return nil, nil, err
+	}
+
+	intent, events, err := gateway.chargeRenewal(sub, "subscription_create")
+	if err != nil {
+		return nil, nil, err
+	}
+	gateway.subscriptions[sub.subscription.ID] = sub
+
+	created := sub.subscription
+	if intent.Status == entity.PaymentIntentRequiresAction {
+		created.ClientSecret = intent.ClientSecret
+	}
+	return &created, events, nil
+}
+
+func (gateway *Gateway) GetSubscription(subscriptionId string) (*entity.PaymentSubscription, error) {
+	gateway.mu.Lock()
+	defer gateway.mu.Unlock()
+
+	sub, ok := gateway.subscriptions[subscriptionId]
+	if !ok {
+		return nil, fmt.Errorf("subscription retrival failed: no such subscription: %s", subscriptionId)
+	}
+	found := sub.subscription
+	return &found, nil
+}
+
+// PauseSubscription stops charging renewals, or charges them again.
+func (gateway *Gateway) PauseSubscription(subscriptionId string, paused bool) (*entity.PaymentSubscription, error) {
+	return gateway.updateSubscription(subscriptionId, func(sub *gatewaySubscription) error {
+		if paused {
+			sub.subscription.Status = entity.SubscriptionPaused
+		} else {
+			sub.subscription.Status = entity.SubscriptionActive
+		}
+		return nil
+	})
+}
+
+// SkipSubscriptionRenewal leaves the coming renewal uncharged and renews next
+// at until.
+func (gateway *Gateway) SkipSubscriptionRenewal(subscriptionId string, until time.Time) (*entity.PaymentSubscription, error) {
+	return gateway.updateSubscription(subscriptionId, func(sub *gatewaySubscription) error {
+		sub.skipped = true
+		sub.subscription.RenewsAt = &until
+		return nil
+	})
+}
+
+// UpdateSubscriptionQuantity charges quantity units from the next renewal on.
+func (gateway *Gateway) UpdateSubscriptionQuantity(subscriptionId string, quantity int) (*entity.PaymentSubscription, error) {
+	return gateway.updateSubscription(subscriptionId, func(sub *gatewaySubscription) error {
+		if quantity < 1 {
+			return fmt.Errorf("subscription update failed: quantity %d", quantity)
+		}
+		sub.plan.Quantity = quantity
+		return nil
+	})
+}
+
+func (gateway *Gateway) CancelSubscription(subscriptionId string) error {
+	gateway.mu.Lock()
+	sub, ok := gateway.subscriptions[subscriptionId]
+	if !ok || sub.subscription.Status == entity.SubscriptionCancelled {
+		gateway.mu.Unlock()
+		return fmt.Errorf("subscription cancellation failed: no such subscription: %s", subscriptionId)
+	}
+	sub.subscription.Status = entity.SubscriptionCancelled
+	sub.subscription.RenewsAt = nil
+	event := gateway.event("customer.subscription.deleted", subscriptionObject(sub))
+	gateway.mu.Unlock()
+
+	gateway.publish(event)
+	return nil
+}
+
+// RenewSubscription plays the billing clock: the renewal of the subscription
+// is due. An active subscription is charged and paid by an invoice, like
+// Stripe does every billing interval. A paused one is not charged, and a
+// skipped renewal only ends the skip. A declined card leaves the subscription
+// past due.
+func (gateway *Gateway) RenewSubscription(subscriptionId string) error {
+	gateway.mu.Lock()
+	sub, ok := gateway.subscriptions[subscriptionId]
+	if !ok || sub.subscription.Status == entity.SubscriptionCancelled || sub.subscription.Status == entity.SubscriptionIncomplete {
+		gateway.mu.Unlock()
+		return fmt.Errorf("subscription %s cannot renew", subscriptionId)
+	}
+
+	var events []entity.PaymentEvent
+	switch {
+	case sub.skipped:
+		sub.skipped = false
+	case sub.subscription.Status == entity.SubscriptionPaused:
+		if err := sub.advance(*sub.subscription.RenewsAt); err != nil {
+			gateway.mu.Unlock()
+			return err
+		}
+	default:
+		if err := sub.advance(*sub.subscription.RenewsAt); err != nil {
+			gateway.mu.Unlock()
+			return err
+		}
+		_, charged, err := gateway.chargeRenewal(sub, "subscription_cycle")
+		if err != nil {
+			gateway.mu.Unlock()
+			return err
+		}
+		events = charged
+	}
+	events = append(events, gateway.event("customer.subscription.updated", subscriptionObject(sub)))
+	gateway.mu.Unlock()
+
+	gateway.publish(events...)
+	return nil
+}
+
+func (gateway *Gateway) updateSubscription(subscriptionId string, update func(sub *gatewaySubscription) error) (*entity.PaymentSubscription, error) {
+	gateway.mu.Lock()
+	sub, ok := gateway.subscriptions[subscriptionId]
+	if !ok || sub.subscription.Status == entity.SubscriptionCancelled {
+		gateway.mu.Unlock()
+		return nil, fmt.Errorf("subscription update failed: no such subscription: %s", subscriptionId)
+	}
+	if err := update(sub); err != nil {
+		gateway.mu.Unlock()
+		return nil, err
+	}
+	event := gateway.event("customer.subscription.updated", subscriptionObject(sub))
+	updated := sub.subscription
+	gateway.mu.Unlock()
+
+	gateway.publish(event)
+	return &updated, nil
+}
+
+// chargeRenewal charges one renewal of the subscription. A paid renewal comes
+// with the invoice that paid it, a declined one leaves the subscription past
+// due, or fails the subscription when it is its first payment.
+func (gateway *Gateway) chargeRenewal(sub *gatewaySubscription, billingReason string) (*entity.PaymentIntent, []entity.PaymentEvent, error) {
+	plan := sub.plan
+	intent, events, err := gateway.createCharge(&payloads.CustomerChargeRequest{
+		Amount:          plan.UnitPrice.Mul(plan.Quantity).Amount,
+		Currency:        plan.UnitPrice.Currency,
+		Description:     "Subscription " + sub.subscription.ID,
+		PaymentMethodID: plan.PaymentMethodID,
+	}, sub.subscription.CustomerID)
+	if err != nil {
+		if billingReason == "subscription_create" || intent == nil {
+			return nil, nil, err
+		}
+		sub.subscription.Status = entity.SubscriptionPastDue
+		return intent, events, nil
+	}
+
+	switch intent.Status {
+	case entity.PaymentIntentRequiresAction:
+		gateway.invoices[intent.ID] = sub.subscription.ID
+	case entity.PaymentIntentSucceeded:
+		sub.subscription.Status = entity.SubscriptionActive
+		events = append(events, gateway.invoicePaid(sub, gateway.paymentIntents[intent.ID], billingReason))
+	}
+	return intent, events, nil
+}
+
+// advance moves the next renewal one billing interval past from.
+func (sub *gatewaySubscription) advance(from time.Time) error {
+	next, err := subscription.NextRenewal(from, sub.plan.Interval, sub.plan.IntervalCount)
+	if err != nil {
+		return err
+	}
+	sub.subscription.RenewsAt = &next
+	return nil
+}
+
+// invoicePaid is the event of the invoice a renewal was paid with.
+func (gateway *Gateway) invoicePaid(sub *gatewaySubscription, intent *entity.PaymentIntent, billingReason string) entity.PaymentEvent {
+	return gateway.event("invoice.paid", map[string]interface{}{
+		"id":             gateway.nextID("in"),
+		"object":         "invoice",
+		"billing_reason": billingReason,
+		"subscription":   sub.subscription.ID,
+		"customer":       sub.subscription.CustomerID,
+		"amount_paid":    intent.Amount.Amount,
+		"currency":       strings.ToLower(intent.Amount.Currency),
+		"charge":         "ch_" + strings.TrimPrefix(intent.ID, "pi_"),
+		"payment_intent": intent.ID,
+		"paid":           true,
+		"status":         "paid",
+		"metadata":       sub.plan.Metadata,
+	})
+}
+
+func subscriptionObject(sub *gatewaySubscription) map[string]interface{} {
+	status := map[string]string{
+		entity.SubscriptionActive:     "active",
+		entity.SubscriptionPaused:     "active",
+		entity.SubscriptionPastDue:    "past_due",
+		entity.SubscriptionCancelled:  "canceled",
+		entity.SubscriptionIncomplete: "incomplete",
+	}[sub.subscription.Status]
+	if sub.skipped {
+		status = "trialing"
+	}
+
+	object := map[string]interface{}{
+		"id":       sub.subscription.ID,
+		"object":   "subscription",
+		"customer": sub.subscription.CustomerID,
+		"status":   status,
+		"quantity": sub.plan.Quantity,
+		"metadata": sub.plan.Metadata,
+	}
+	if sub.subscription.Status == entity.SubscriptionPaused {
+		object["pause_collection"] = map[string]interface{}{"behavior": "void"}
+	}
+	if sub.subscription.RenewsAt != nil {
+		object["current_period_end"] = sub.subscription.RenewsAt.Unix()
+	}
+	return object
+}
@@ -0,0 +1,101 @@
+package idempotency_repo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"ecom-api/internal/application/core/types/entity"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// ReserveIdempotencyKey inserts the key unless a live record already holds
+// it. The primary key makes the claim atomic, so of two concurrent requests
+// with the same key only one is handled. Expired records are purged first.
+func (s *Store) ReserveIdempotencyKey(record entity.IdempotencyRecord) (*entity.IdempotencyRecord, error) {
+	if _, err := s.db.Exec("DELETE FROM idempotencykeys WHERE expiresAt < ?", time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to purge idempotency keys: %w", err)
+	}
+
+	result, err := s.db.Exec("INSERT IGNORE INTO idempotencykeys (idempotencyKey, scope, fingerprint, expiresAt) VALUES (?,?,?,?)",
+		record.Key, record.Scope, record.Fingerprint, record.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 1 {
+		return nil, nil
+	}
+
+	rows, err := s.db.Query("SELECT * FROM idempotencykeys WHERE idempotencyKey = ? AND scope = ?", record.Key, record.Scope)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, fmt.Errorf("idempotency key %s was released concurrently, retry the request", record.Key)
+	}
+
+	return scanRowsIntoIdempotencyRecord(rows)
+}
+
+func (s *Store) CompleteIdempotencyKey(record entity.IdempotencyRecord) error {
+	headers, err := json.Marshal(record.ResponseHeaders)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec("UPDATE idempotencykeys SET statusCode = ?, responseHeaders = ?, responseBody = ?, completed = TRUE WHERE idempotencyKey = ? AND scope = ?",
+		record.StatusCode, headers, record.ResponseBody, record.Key, record.Scope)
+	if err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) ReleaseIdempotencyKey(key, scope string) error {
+	_, err := s.db.Exec("DELETE FROM idempotencykeys WHERE idempotencyKey = ? AND scope = ? AND completed = FALSE", key, scope)
+	return err
+}
+
+func scanRowsIntoIdempotencyRecord(rows *sql.Rows) (*entity.IdempotencyRecord, error) {
+	record := new(entity.IdempotencyRecord)
+	var headers []byte
+
+	err := rows.Scan(
+		&record.Key,
+		&record.Scope,
+		&record.Fingerprint,
+		&record.StatusCode,
+		&headers,
+		&record.ResponseBody,
+		&record.Completed,
+		&record.CreatedAt,
+		&record.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(headers) > 0 {
+		if err := json.Unmarshal(headers, &record.ResponseHeaders); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response headers: %w", err)
+		}
+	}
+
+	return record, nil
+}
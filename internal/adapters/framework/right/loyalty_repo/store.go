@@ -0,0 +1,317 @@
+package loyalty_repo
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/utils"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) CreateLoyaltyEarnRule(rule entity.LoyaltyEarnRule) (string, error) {
+	rule.ID = utils.GenerateRandomUniqueIdentifier()
+
+	_, err := s.db.Exec("INSERT INTO loyalty_earn_rules (id, type, currency, category, rate, isActive) VALUES (?,?,?,?,?,?)",
+		rule.ID, rule.Type, rule.Currency, rule.Category, rule.Rate, rule.IsActive)
+	if err != nil {
+		return "", fmt.Errorf("failed to create loyalty earn rule: %w", err)
+	}
+
+	return rule.ID, nil
+}
+
+func (s *Store) GetLoyaltyEarnRules() ([]*entity.LoyaltyEarnRule, error) {
+	rows, err := s.db.Query("SELECT * FROM loyalty_earn_rules ORDER BY createdAt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query loyalty earn rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []*entity.LoyaltyEarnRule
+	for rows.Next() {
+		rule := new(entity.LoyaltyEarnRule)
+		var updatedAt time.Time
+		err := rows.Scan(
+			&rule.ID,
+			&rule.Type,
+			&rule.Currency,
+			&rule.Category,
+			&rule.Rate,
+			&rule.IsActive,
+			&rule.CreatedAt,
+			&updatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func (s *Store) DeleteLoyaltyEarnRule(ruleID string) error {
+	_, err := s.db.Exec("DELETE FROM loyalty_earn_rules WHERE id = ?", ruleID)
+	if err != nil {
+		return fmt.Errorf("failed to delete loyalty earn rule: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) CreateLoyaltyTier(tier entity.LoyaltyTier) (string, error) {
+	tier.ID = utils.GenerateRandomUniqueIdentifier()
+
+	_, err := s.db.Exec("INSERT INTO loyalty_tiers (id, name, minSpend, currency, multiplier) VALUES (?,?,?,?,?)",
+		tier.ID, tier.Name, tier.MinSpend.String(), tier.Currency, tier.Multiplier)
+	if err != nil {
+		return "", fmt.Errorf("failed to create loyalty tier: %w", err)
+	}
+
+	return tier.ID, nil
+}
+
+func (s *Store) GetLoyaltyTiers() ([]*entity.LoyaltyTier, error) {
+	rows, err := s.db.Query("SELECT * FROM loyalty_tiers ORDER BY currency, minSpend")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query loyalty tiers: %w", err)
+	}
+	defer rows.Close()
+
+	var tiers []*entity.LoyaltyTier
+	for rows.Next() {
+		tier := new(entity.LoyaltyTier)
+		var minSpend string
+		err := rows.Scan(
+			&tier.ID,
+			&tier.Name,
+			&minSpend,
+			&tier.Currency,
+			&tier.Multiplier,
+			&tier.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if tier.MinSpend, err = entity.ParseMoney(minSpend, tier.Currency); err != nil {
+			return nil, err
+		}
+		tiers = append(tiers, tier)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tiers, nil
+}
+
+func (s *Store) DeleteLoyaltyTier(tierID string) error {
+	_, err := s.db.Exec("DELETE FROM loyalty_tiers WHERE id = ?", tierID)
+	if err != nil {
+		return fmt.Errorf("failed to delete loyalty tier: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) GetLoyaltyBalance(userID string) (int, error) {
+	var balance int
+	err := s.db.QueryRow("SELECT balance FROM loyalty_accounts WHERE userId = ?", userID).Scan(&balance)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to query loyalty account: %w", err)
+	}
+
+	return balance, nil
+}
+
+func (s *Store) GetLoyaltyEntries(userID string) ([]*entity.LoyaltyEntry, error) {
+	return s.getLoyaltyEntries("SELECT * FROM loyalty_entries WHERE userId = ? ORDER BY createdAt DESC", userID)
+}
+
+func (s *Store) GetOrderLoyaltyEntries(orderID string) ([]*entity.LoyaltyEntry, error) {
+	return s.getLoyaltyEntries("SELECT * FROM loyalty_entries WHERE orderId = ? ORDER BY createdAt", orderID)
+}
+
+func (s *Store) GetLoyaltySpend(userID string, since time.Time) ([]entity.Money, error) {
+	rows, err := s.db.Query("SELECT currency, SUM(spend) FROM loyalty_entries WHERE userId = ? AND createdAt >= ? AND currency <> '' GROUP BY currency ORDER BY currency", userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query loyalty spend: %w", err)
+	}
+	defer rows.Close()
+
+	var spend []entity.Money
+	for rows.Next() {
+		var currency, amount string
+		if err := rows.Scan(&currency, &amount); err != nil {
+			return nil, err
+		}
+		total, err := entity.ParseMoney(amount, currency)
+		if err != nil {
+			return nil, err
+		}
+		spend = append(spend, total)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return spend, nil
+}
+
+// AddLoyaltyEntry records an entry and moves the balance in one transaction.
+// An order has one entry per reason, so replaying the payment or refund of an
+// order does not earn or take points twice. The balance may go below zero
+// when points earned on a refunded order were already spent.
+func (s *Store) AddLoyaltyEntry(entry entity.LoyaltyEntry) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	created, err := createLoyaltyEntry(tx, entry)
+	if err != nil || !created {
+		return false, err
+	}
+
+	_, err = tx.Exec("INSERT INTO loyalty_accounts (userId, balance) VALUES (?,?) ON DUPLICATE KEY UPDATE balance = balance + VALUES(balance)",
+		entry.UserID, entry.Points)
+	if err != nil {
+		return false, fmt.Errorf("failed to update loyalty account: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// RedeemLoyaltyPoints takes points off a balance only when it covers them, so
+// two checkouts cannot spend the same points.
+func (s *Store) RedeemLoyaltyPoints(userID, orderID string, points int) (bool, error) {
+	if points <= 0 {
+		return false, fmt.Errorf("redeemed points must be positive")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE loyalty_accounts SET balance = balance - ? WHERE userId = ? AND balance >= ?", points, userID, points)
+	if err != nil {
+		return false, fmt.Errorf("failed to redeem loyalty points: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected != 1 {
+		return false, nil
+	}
+
+	created, err := createLoyaltyEntry(tx, entity.LoyaltyEntry{
+		UserID:  userID,
+		Points:  -points,
+		Reason:  entity.LoyaltyRedeem,
+		OrderID: orderID,
+	})
+	if err != nil || !created {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// createLoyaltyEntry records an entry, false when its order already has one
+// for the same reason.
+func createLoyaltyEntry(tx *sql.Tx, entry entity.LoyaltyEntry) (bool, error) {
+	entry.ID = utils.GenerateRandomUniqueIdentifier()
+
+	result, err := tx.Exec("INSERT IGNORE INTO loyalty_entries (id, userId, points, reason, orderId, spend, currency, note) VALUES (?,?,?,?,?,?,?,?)",
+		entry.ID, entry.UserID, entry.Points, entry.Reason, nullableString(entry.OrderID), entry.Spend.String(), entry.Currency, entry.Note)
+	if err != nil {
+		return false, fmt.Errorf("failed to create loyalty entry: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+func (s *Store) getLoyaltyEntries(query string, arg string) ([]*entity.LoyaltyEntry, error) {
+	rows, err := s.db.Query(query, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query loyalty entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*entity.LoyaltyEntry
+	for rows.Next() {
+		entry, err := scanRowsIntoLoyaltyEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func scanRowsIntoLoyaltyEntry(rows *sql.Rows) (*entity.LoyaltyEntry, error) {
+	entry := new(entity.LoyaltyEntry)
+	var spend string
+	var orderID sql.NullString
+
+	err := rows.Scan(
+		&entry.ID,
+		&entry.UserID,
+		&entry.Points,
+		&entry.Reason,
+		&orderID,
+		&spend,
+		&entry.Currency,
+		&entry.Note,
+		&entry.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.Spend, err = entity.ParseMoney(spend, entry.Currency); err != nil {
+		return nil, err
+	}
+	entry.OrderID = orderID.String
+
+	return entry, nil
+}
+
+func nullableString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
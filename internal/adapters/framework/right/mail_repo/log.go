@@ -0,0 +1,49 @@
+package mail_repo
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"ecom-api/internal/application/core/types/entity"
+)
+
+// LogMailer is for development. It sends nothing, it appends every email to
+// a file as the raw message an SMTP server would get, or logs its plain text
+// when there is no file.
+type LogMailer struct {
+	mu   sync.Mutex
+	from string
+	path string
+}
+
+func NewLogMailer(from, path string) *LogMailer {
+	return &LogMailer{from: from, path: path}
+}
+
+func (m *LogMailer) Send(email entity.Email) error {
+	if m.path == "" {
+		log.Printf("Email to %v: %s\n%s", email.To, email.Subject, email.Text)
+		return nil
+	}
+
+	message, err := buildMessage(m.from, email)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, err := os.OpenFile(m.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open mail log: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(message, "\r\n"...)); err != nil {
+		return fmt.Errorf("failed to write mail log: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package mail_repo
+
+import (
+	"sync"
+
+	"ecom-api/internal/application/core/types/entity"
+)
+
+// MemoryMailer keeps the emails it is given, for tests to look at.
+type MemoryMailer struct {
+	mu   sync.Mutex
+	sent []entity.Email
+	err  error
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (m *MemoryMailer) Send(email entity.Email) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, email)
+	return nil
+}
+
+// Sent returns the emails sent so far, oldest first.
+func (m *MemoryMailer) Sent() []entity.Email {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]entity.Email(nil), m.sent...)
+}
+
+// FailWith makes every following Send fail with err, or succeed again when
+// err is nil.
+func (m *MemoryMailer) FailWith(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.err = err
+}
@@ -0,0 +1,78 @@
+package mail_repo
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ecom-api/internal/application/core/types/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildMessage(t *testing.T) {
+	email := entity.Email{
+		To:      []string{"jane@example.com", "john@example.com"},
+		Subject: "Purchase Successful!! 🎉",
+		HTML:    "<p>Thanks for your order, Jane</p>",
+		Text:    "Thanks for your order, Jane",
+	}
+
+	t.Run("is multipart with the plain text first", func(t *testing.T) {
+		raw, err := buildMessage("shop@example.com", email)
+		assert.NoError(t, err)
+
+		message, err := mail.ReadMessage(bytes.NewReader(raw))
+		assert.NoError(t, err)
+		assert.Equal(t, "shop@example.com", message.Header.Get("From"))
+		assert.Equal(t, "jane@example.com, john@example.com", message.Header.Get("To"))
+
+		subject, err := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+		assert.NoError(t, err)
+		assert.Equal(t, email.Subject, subject)
+
+		mediaType, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+		assert.NoError(t, err)
+		assert.Equal(t, "multipart/alternative", mediaType)
+
+		parts := multipart.NewReader(message.Body, params["boundary"])
+		var types, bodies []string
+		for {
+			part, err := parts.NextPart()
+			if err == io.EOF {
+				break
+			}
+			assert.NoError(t, err)
+			body, err := io.ReadAll(part)
+			assert.NoError(t, err)
+			types = append(types, strings.Split(part.Header.Get("Content-Type"), ";")[0])
+			bodies = append(bodies, string(body))
+		}
+		assert.Equal(t, []string{"text/plain", "text/html"}, types)
+		assert.Equal(t, []string{email.Text, email.HTML}, bodies)
+	})
+
+	t.Run("needs a recipient", func(t *testing.T) {
+		_, err := buildMessage("shop@example.com", entity.Email{Subject: "Hi"})
+		assert.Error(t, err)
+	})
+}
+
+func TestLogMailer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.log")
+	mailer := NewLogMailer("shop@example.com", path)
+
+	assert.NoError(t, mailer.Send(entity.Email{To: []string{"jane@example.com"}, Subject: "One", Text: "first", HTML: "<p>first</p>"}))
+	assert.NoError(t, mailer.Send(entity.Email{To: []string{"jane@example.com"}, Subject: "Two", Text: "second", HTML: "<p>second</p>"}))
+
+	written, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(written), "Subject: "))
+	assert.Contains(t, string(written), "Subject: Two")
+}
@@ -0,0 +1,31 @@
+package mail_repo
+
+import (
+	"net/smtp"
+
+	"ecom-api/internal/application/core/types/entity"
+)
+
+// SMTPMailer sends emails through an SMTP server with plain authentication.
+type SMTPMailer struct {
+	addr     string
+	host     string
+	from     string
+	password string
+}
+
+// NewSMTPMailer sends as from through the server at addr, host being the name
+// its certificate is checked against.
+func NewSMTPMailer(addr, host, from, password string) *SMTPMailer {
+	return &SMTPMailer{addr: addr, host: host, from: from, password: password}
+}
+
+func (m *SMTPMailer) Send(email entity.Email) error {
+	message, err := buildMessage(m.from, email)
+	if err != nil {
+		return err
+	}
+
+	auth := smtp.PlainAuth("", m.from, m.password, m.host)
+	return smtp.SendMail(m.addr, auth, m.from, email.To, message)
+}
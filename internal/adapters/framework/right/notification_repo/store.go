@@ -0,0 +1,52 @@
+package notification_repo
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// addresses are compared without case, the way mail servers treat them
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (s *Store) GetNotificationPreferences(email string) (map[string]bool, error) {
+	rows, err := s.db.Query("SELECT category, enabled FROM notification_preferences WHERE email = ?", normalizeEmail(email))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notification preferences: %w", err)
+	}
+	defer rows.Close()
+
+	preferences := map[string]bool{}
+	for rows.Next() {
+		var category string
+		var enabled bool
+		if err := rows.Scan(&category, &enabled); err != nil {
+			return nil, err
+		}
+		preferences[category] = enabled
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return preferences, nil
+}
+
+func (s *Store) SetNotificationPreference(email, category string, enabled bool) error {
+	_, err := s.db.Exec("INSERT INTO notification_preferences (email, category, enabled) VALUES (?,?,?) ON DUPLICATE KEY UPDATE enabled = VALUES(enabled)",
+		normalizeEmail(email), category, enabled)
+	if err != nil {
+		return fmt.Errorf("failed to set notification preference: %w", err)
+	}
+	return nil
+}
@@ -2,8 +2,11 @@ package order
 
 import (
 	"database/sql"
+	"ecom-api/internal/adapters/framework/right/outbox_repo"
 	"ecom-api/internal/application/core/types/entity"
 	"ecom-api/pkg/configs"
+	"ecom-api/utils"
+	"encoding/json"
 	"fmt"
 	"log"
 )
@@ -16,19 +19,76 @@ func NewStore(db *sql.DB) *Store {
 	return &Store{db: db}
 }
 
+// execer is what the inserts run on, the database or a transaction.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
 func (store *Store) CreateOrder(order entity.Order) (string, error) {
-	_, err := store.db.Exec("INSERT INTO orders (userId, total, subtotal, status, paymentStatus, paymentMethod, address, currency) VALUES (?,?,?,?,?,?,?,?)", order.UserID, order.Total, order.Subtotal, order.Status, order.PaymentStatus, order.PaymentMethod, order.Address, order.Currency)
+	id := utils.GenerateRandomUniqueIdentifier()
+	if err := insertOrder(store.db, id, order); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// PlaceOrder creates a checkout order with its items, its store orders and
+// the redemptions of its coupons in one transaction, so an order is never
+// left without the items it charges for or the store orders fulfilling them.
+// The IDs of the order are filled in, the new order ID is returned.
+func (store *Store) PlaceOrder(order entity.Order, items []*entity.OrderItem, storeOrders []entity.StoreOrder, redemptions []entity.PromotionRedemption) (string, error) {
+	tx, err := store.db.Begin()
 	if err != nil {
 		return "", err
 	}
+	defer tx.Rollback()
+
+	id := utils.GenerateRandomUniqueIdentifier()
+	if err := insertOrder(tx, id, order); err != nil {
+		return "", err
+	}
+	for _, item := range items {
+		item.OrderID = id
+		if err := insertOrderItem(tx, *item); err != nil {
+			return "", fmt.Errorf("failed to create order item: %w", err)
+		}
+	}
+	for _, storeOrder := range storeOrders {
+		storeOrder.ID = utils.GenerateRandomUniqueIdentifier()
+		storeOrder.OrderID = id
+		if err := insertStoreOrder(tx, storeOrder); err != nil {
+			return "", err
+		}
+	}
+	for _, redemption := range redemptions {
+		if _, err := tx.Exec("INSERT INTO promotionredemptions (id, promotionId, orderId, userId, guestEmail, code, discount) VALUES (?,?,?,?,?,?,?)",
+			utils.GenerateRandomUniqueIdentifier(), redemption.PromotionID, id,
+			nullableString(redemption.UserID), nullableString(redemption.GuestEmail), redemption.Code, redemption.Discount.String()); err != nil {
+			return "", fmt.Errorf("failed to record redemption: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
 
-	var uuid string
-	err = store.db.QueryRow("SELECT id FROM orders ORDER BY createdAt DESC LIMIT 1").Scan(&uuid)
+	return id, nil
+}
+
+func insertOrder(exec execer, id string, order entity.Order) error {
+	shippingAddress, billingAddress, err := marshalOrderAddresses(order)
+	if err != nil {
+		return err
+	}
+
+	taxBreakdown, err := json.Marshal(order.TaxBreakdown)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch order UUID: %w", err)
+		return err
 	}
 
-	return uuid, nil
+	_, err = exec.Exec("INSERT INTO orders (id, userId, total, subtotal, status, paymentStatus, paymentMethod, address, currency, shippingAddress, billingAddress, guestEmail, discount, tax, taxBreakdown, taxInclusive, shippingMethodId, shippingMethod, shippingCost, subscriptionId, invoiceId) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)", id, nullableString(order.UserID), order.Total.String(), order.Subtotal.String(), order.Status, order.PaymentStatus, order.PaymentMethod, order.Address, order.Currency, shippingAddress, billingAddress, nullableString(order.GuestEmail), order.Discount.String(), order.Tax.String(), taxBreakdown, order.TaxInclusive, nullableString(order.ShippingMethodID), order.ShippingMethod, order.ShippingCost.String(), nullableString(order.SubscriptionID), nullableString(order.InvoiceID))
+	return err
 }
 
 func (store *Store) GetOrderByID(orderID string) (*entity.Order, error) {
@@ -53,6 +113,30 @@ func (store *Store) GetOrderByID(orderID string) (*entity.Order, error) {
 	return order, nil
 }
 
+// GetOrderByInvoiceID returns the renewal order an invoice paid for, an empty
+// order when there is none yet.
+func (store *Store) GetOrderByInvoiceID(invoiceID string) (*entity.Order, error) {
+	rows, err := store.db.Query("SELECT * FROM orders WHERE invoiceId = ?", invoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order by invoice: %w", err)
+	}
+	defer rows.Close()
+
+	order := new(entity.Order)
+
+	for rows.Next() {
+		order, err = ScanRowsIntoOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
 func (store *Store) GetOrdersByUserID(userID string) ([]*entity.Order, error) {
 	rows, err := store.db.Query("SELECT * FROM orders WHERE userId = ?", userID)
 	if err != nil {
@@ -78,8 +162,22 @@ func (store *Store) GetOrdersByUserID(userID string) ([]*entity.Order, error) {
 	return orders, nil
 }
 
+func (store *Store) AttachGuestOrdersToUser(email, userID string) (int64, error) {
+	result, err := store.db.Exec("UPDATE orders SET userId = ? WHERE guestEmail = ? AND userId IS NULL", userID, email)
+	if err != nil {
+		return 0, fmt.Errorf("failed to attach guest orders: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
 func (store *Store) UpdateOrder(order entity.Order) error {
-	_, err := store.db.Exec("UPDATE orders SET total = ?, subtotal = ?, status = ?, paymentStatus = ?, paymentMethod = ?, address = ?, currency = ?, updatedAt = NOW() WHERE id = ?", order.Total, order.Subtotal, order.Status, order.PaymentStatus, order.PaymentMethod, order.Address, order.Currency, order.ID)
+	shippingAddress, billingAddress, err := marshalOrderAddresses(order)
+	if err != nil {
+		return err
+	}
+
+	_, err = store.db.Exec("UPDATE orders SET total = ?, subtotal = ?, status = ?, paymentStatus = ?, paymentMethod = ?, address = ?, currency = ?, shippingAddress = ?, billingAddress = ?, discount = ?, updatedAt = NOW() WHERE id = ?", order.Total.String(), order.Subtotal.String(), order.Status, order.PaymentStatus, order.PaymentMethod, order.Address, order.Currency, shippingAddress, billingAddress, order.Discount.String(), order.ID)
 	if err != nil {
 		return err
 	}
@@ -152,6 +250,244 @@ func (store *Store) UpdateOrderStatus(orderId, status string) error {
 	return nil
 }
 
+func (store *Store) SetOrderPaymentSession(orderID, sessionID string) error {
+	_, err := store.db.Exec("UPDATE orders SET paymentSessionId = ?, updatedAt = NOW() WHERE id = ?", nullableString(sessionID), orderID)
+	if err != nil {
+		return fmt.Errorf("failed to set payment session: %w", err)
+	}
+	return nil
+}
+
+func (store *Store) SetOrderStoreCredit(orderID string, amount entity.Money) error {
+	_, err := store.db.Exec("UPDATE orders SET storeCredit = ?, updatedAt = NOW() WHERE id = ?", amount.String(), orderID)
+	if err != nil {
+		return fmt.Errorf("failed to set store credit: %w", err)
+	}
+	return nil
+}
+
+func (store *Store) SetOrderLoyaltyDiscount(orderID string, points int, amount entity.Money) error {
+	_, err := store.db.Exec("UPDATE orders SET loyaltyPoints = ?, loyaltyDiscount = ?, updatedAt = NOW() WHERE id = ?", points, amount.String(), orderID)
+	if err != nil {
+		return fmt.Errorf("failed to set loyalty discount: %w", err)
+	}
+	return nil
+}
+
+// MarkOrderPaid records the payment of an order and starts processing it with
+// its store orders, writing messages to the outbox in the same transaction.
+// An order that is already paid, or no longer pending because it was
+// cancelled, is left alone and false is returned, so the messages go out once
+// however often the payment is reported.
+func (store *Store) MarkOrderPaid(orderID string, messages ...entity.OutboxMessage) (bool, error) {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var status, paymentStatus string
+	err = tx.QueryRow("SELECT status, paymentStatus FROM orders WHERE id = ? FOR UPDATE", orderID).Scan(&status, &paymentStatus)
+	if err == sql.ErrNoRows {
+		return false, fmt.Errorf("order %s not found", orderID)
+	}
+	if err != nil {
+		return false, err
+	}
+	if paymentStatus == configs.Envs.PaymentStatusPaid || status != configs.Envs.OrderStatusPending {
+		return false, nil
+	}
+
+	if _, err := tx.Exec("UPDATE orders SET paymentStatus = ?, status = ?, updatedAt = NOW() WHERE id = ?",
+		configs.Envs.PaymentStatusPaid, configs.Envs.OrderStatusProcessing, orderID); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec("UPDATE store_orders SET status = ?, updatedAt = NOW() WHERE orderId = ? AND status = ?",
+		configs.Envs.OrderStatusProcessing, orderID, configs.Envs.OrderStatusPending); err != nil {
+		return false, fmt.Errorf("failed to start store orders: %w", err)
+	}
+	if err := outbox_repo.AddMessages(tx, messages...); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// CancelOrder cancels an order that is still waiting for its payment with its
+// store orders, puts the ordered quantities back in stock and gives back the
+// coupon uses it reserved, writing messages to the outbox in the same
+// transaction. An order that was paid or has already moved on is left alone
+// and false is returned, so the cancellation can safely be repeated.
+func (store *Store) CancelOrder(orderID string, messages ...entity.OutboxMessage) (bool, error) {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var status, paymentStatus string
+	err = tx.QueryRow("SELECT status, paymentStatus FROM orders WHERE id = ? FOR UPDATE", orderID).Scan(&status, &paymentStatus)
+	if err == sql.ErrNoRows {
+		return false, fmt.Errorf("order %s not found", orderID)
+	}
+	if err != nil {
+		return false, err
+	}
+	if status != configs.Envs.OrderStatusPending || paymentStatus != configs.Envs.PaymentStatusPending {
+		return false, nil
+	}
+
+	quantities, err := queryCounts(tx, "SELECT productId, quantity FROM orderitems WHERE orderId = ?", orderID)
+	if err != nil {
+		return false, fmt.Errorf("failed to query order items: %w", err)
+	}
+	for productID, quantity := range quantities {
+		if _, err := tx.Exec("UPDATE products SET quantity = quantity + ? WHERE productId = ?", quantity, productID); err != nil {
+			return false, fmt.Errorf("failed to restock product %s: %w", productID, err)
+		}
+	}
+
+	redemptions, err := queryCounts(tx, "SELECT promotionId, COUNT(*) FROM promotionredemptions WHERE orderId = ? GROUP BY promotionId", orderID)
+	if err != nil {
+		return false, fmt.Errorf("failed to query redemptions: %w", err)
+	}
+	for promotionID, count := range redemptions {
+		if _, err := tx.Exec("UPDATE promotions SET usageCount = GREATEST(usageCount - ?, 0) WHERE id = ?", count, promotionID); err != nil {
+			return false, fmt.Errorf("failed to release promotion %s: %w", promotionID, err)
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM promotionredemptions WHERE orderId = ?", orderID); err != nil {
+		return false, fmt.Errorf("failed to delete redemptions: %w", err)
+	}
+
+	if _, err := tx.Exec("UPDATE orders SET status = ?, updatedAt = NOW() WHERE id = ?", configs.Envs.OrderStatusCancelled, orderID); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec("UPDATE store_orders SET status = ?, updatedAt = NOW() WHERE orderId = ?", configs.Envs.OrderStatusCancelled, orderID); err != nil {
+		return false, fmt.Errorf("failed to cancel store orders: %w", err)
+	}
+	if err := outbox_repo.AddMessages(tx, messages...); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// queryCounts sums a count per key, read fully before the transaction is used
+// for anything else.
+// RecordCardRefund records what was refunded on the card of an order so far.
+// The gateway reports the running total with every refund, so a redelivered
+// or late event never lowers it.
+func (store *Store) RecordCardRefund(orderID string, refunded entity.Money) error {
+	_, err := store.db.Exec("UPDATE orders SET cardRefunded = GREATEST(cardRefunded, ?) WHERE id = ?", refunded.String(), orderID)
+	if err != nil {
+		return fmt.Errorf("failed to record card refund: %w", err)
+	}
+	return nil
+}
+
+// MoveOrderStatus moves an order on only from the status it was read in,
+// writing messages to the outbox in the same transaction. An order that was
+// moved meanwhile is left alone and false is returned, without the messages.
+func (store *Store) MoveOrderStatus(orderID, fromStatus, toStatus string, messages ...entity.OutboxMessage) (bool, error) {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE orders SET status = ?, updatedAt = NOW() WHERE id = ? AND status = ?", toStatus, orderID, fromStatus)
+	if err != nil {
+		return false, fmt.Errorf("failed to update order status: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+	if err := outbox_repo.AddMessages(tx, messages...); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// RefundOrder marks an order and its store orders refunded, writing messages
+// to the outbox in the same transaction. An order that is already refunded is
+// left alone and false is returned, so the messages go out once however often
+// the refund is reported.
+func (store *Store) RefundOrder(orderID string, messages ...entity.OutboxMessage) (bool, error) {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var paymentStatus string
+	err = tx.QueryRow("SELECT paymentStatus FROM orders WHERE id = ? FOR UPDATE", orderID).Scan(&paymentStatus)
+	if err == sql.ErrNoRows {
+		return false, fmt.Errorf("order %s not found", orderID)
+	}
+	if err != nil {
+		return false, err
+	}
+	if paymentStatus == configs.Envs.PaymentStatusRefunded {
+		return false, nil
+	}
+
+	if _, err := tx.Exec("UPDATE orders SET paymentStatus = ?, status = ?, updatedAt = NOW() WHERE id = ?",
+		configs.Envs.PaymentStatusRefunded, configs.Envs.OrderStatusRefunded, orderID); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec("UPDATE store_orders SET status = ?, updatedAt = NOW() WHERE orderId = ? AND status IN (?,?,?)",
+		configs.Envs.OrderStatusRefunded, orderID, configs.Envs.OrderStatusProcessing, configs.Envs.OrderStatusShipped, configs.Envs.OrderStatusCompleted); err != nil {
+		return false, fmt.Errorf("failed to refund store orders: %w", err)
+	}
+	if err := outbox_repo.AddMessages(tx, messages...); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func queryCounts(tx *sql.Tx, query string, args ...interface{}) (map[string]int, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var key string
+		var count int
+		if err := rows.Scan(&key, &count); err != nil {
+			return nil, err
+		}
+		counts[key] += count
+	}
+
+	return counts, rows.Err()
+}
+
 func (store *Store) DeleteOrder(orderID string) error {
 	_, err := store.db.Exec("DELETE FROM orders WHERE id=?", orderID)
 	if err != nil {
@@ -161,14 +497,25 @@ func (store *Store) DeleteOrder(orderID string) error {
 }
 
 func (store *Store) CreateOrderItem(orderitem entity.OrderItem) error {
+	if err := insertOrderItem(store.db, orderitem); err != nil {
+		log.Println(err)
+		return err
+	}
 
-	_, err := store.db.Exec("INSERT INTO orderitems (orderId, productId, productName, quantity, price, totalPrice, subTotal, currency, discount, tax) VALUES (?,?,?,?,?,?,?,?,?,?)", orderitem.OrderID, orderitem.ProductID, orderitem.ProductName, orderitem.Quantity, orderitem.Price, orderitem.TotalPrice, orderitem.Subtotal, orderitem.Currency, orderitem.Discount, orderitem.Tax)
+	return nil
+}
 
+func insertOrderItem(exec execer, orderitem entity.OrderItem) error {
+	appliedDiscounts, err := json.Marshal(orderitem.AppliedDiscounts)
+	if err != nil {
+		return err
+	}
+	taxBreakdown, err := json.Marshal(orderitem.TaxBreakdown)
 	if err != nil {
-		log.Println(err)
 		return err
 	}
 
+	_, err = exec.Exec("INSERT INTO orderitems (orderId, productId, productName, quantity, price, totalPrice, subTotal, currency, discount, tax, appliedDiscounts, taxBreakdown, storeId, category) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?)", orderitem.OrderID, orderitem.ProductID, orderitem.ProductName, orderitem.Quantity, orderitem.Price.String(), orderitem.TotalPrice.String(), orderitem.Subtotal.String(), orderitem.Currency, orderitem.Discount.String(), orderitem.Tax.String(), appliedDiscounts, taxBreakdown, nullableString(orderitem.StoreID), orderitem.Category)
 	return err
 }
 
@@ -207,12 +554,15 @@ func (store *Store) DeleteOrderItem(orderItemId string) error {
 
 func ScanRowsIntoOrder(rows *sql.Rows) (*entity.Order, error) {
 	order := new(entity.Order)
+	var userID, guestEmail, shippingMethodID, paymentSessionID, subscriptionID, invoiceID sql.NullString
+	var shippingAddress, billingAddress, taxBreakdown []byte
+	var total, subtotal, discount, tax, shippingCost, storeCredit, loyaltyDiscount, cardRefunded string
 
 	err := rows.Scan(
 		&order.ID,
-		&order.UserID,
-		&order.Total,
-		&order.Subtotal,
+		&userID,
+		&total,
+		&subtotal,
 		&order.Status,
 		&order.PaymentStatus,
 		&order.PaymentMethod,
@@ -220,16 +570,95 @@ func ScanRowsIntoOrder(rows *sql.Rows) (*entity.Order, error) {
 		&order.Currency,
 		&order.CreatedAt,
 		&order.UpdatedAt,
+		&shippingAddress,
+		&billingAddress,
+		&guestEmail,
+		&discount,
+		&tax,
+		&taxBreakdown,
+		&order.TaxInclusive,
+		&shippingMethodID,
+		&order.ShippingMethod,
+		&shippingCost,
+		&paymentSessionID,
+		&subscriptionID,
+		&invoiceID,
+		&storeCredit,
+		&order.LoyaltyPoints,
+		&loyaltyDiscount,
+		&cardRefunded,
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	err = parseMoneyColumns(order.Currency,
+		[]string{total, subtotal, discount, tax, shippingCost, storeCredit, loyaltyDiscount, cardRefunded},
+		&order.Total, &order.Subtotal, &order.Discount, &order.Tax, &order.ShippingCost, &order.StoreCredit, &order.LoyaltyDiscount, &order.CardRefunded)
+	if err != nil {
+		return nil, err
+	}
+
+	order.UserID = userID.String
+	order.GuestEmail = guestEmail.String
+	order.ShippingMethodID = shippingMethodID.String
+	order.PaymentSessionID = paymentSessionID.String
+	order.SubscriptionID = subscriptionID.String
+	order.InvoiceID = invoiceID.String
+
+	if len(shippingAddress) > 0 {
+		if err := json.Unmarshal(shippingAddress, &order.ShippingAddress); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal shipping address: %w", err)
+		}
+	}
+	if len(billingAddress) > 0 {
+		if err := json.Unmarshal(billingAddress, &order.BillingAddress); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal billing address: %w", err)
+		}
+	}
+	if len(taxBreakdown) > 0 {
+		if err := json.Unmarshal(taxBreakdown, &order.TaxBreakdown); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tax breakdown: %w", err)
+		}
+	}
+
 	return order, nil
 }
 
+func nullableString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+// parseMoneyColumns parses DECIMAL columns read as text into the Money fields
+// they belong to, all in the currency of the row.
+func parseMoneyColumns(currency string, values []string, fields ...*entity.Money) error {
+	for i, value := range values {
+		amount, err := entity.ParseMoney(value, currency)
+		if err != nil {
+			return err
+		}
+		*fields[i] = amount
+	}
+	return nil
+}
+
+func marshalOrderAddresses(order entity.Order) ([]byte, []byte, error) {
+	shippingAddress, err := json.Marshal(order.ShippingAddress)
+	if err != nil {
+		return nil, nil, err
+	}
+	billingAddress, err := json.Marshal(order.BillingAddress)
+	if err != nil {
+		return nil, nil, err
+	}
+	return shippingAddress, billingAddress, nil
+}
+
 func ScanRowsIntoOrderItem(rows *sql.Rows) (*entity.OrderItem, error) {
 	orderItem := new(entity.OrderItem)
+	var appliedDiscounts, taxBreakdown []byte
+	var price, totalPrice, subtotal, discount, tax string
+	var storeID sql.NullString
 
 	err := rows.Scan(
 		&orderItem.ID,
@@ -237,18 +666,42 @@ func ScanRowsIntoOrderItem(rows *sql.Rows) (*entity.OrderItem, error) {
 		&orderItem.ProductID,
 		&orderItem.ProductName,
 		&orderItem.Quantity,
-		&orderItem.Price,
-		&orderItem.TotalPrice,
-		&orderItem.Subtotal,
+		&price,
+		&totalPrice,
+		&subtotal,
 		&orderItem.Currency,
-		&orderItem.Discount,
-		&orderItem.Tax,
+		&discount,
+		&tax,
 		&orderItem.CreatedAt,
 		&orderItem.UpdatedAt,
+		&appliedDiscounts,
+		&taxBreakdown,
+		&storeID,
+		&orderItem.Category,
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	orderItem.StoreID = storeID.String
+
+	err = parseMoneyColumns(orderItem.Currency,
+		[]string{price, totalPrice, subtotal, discount, tax},
+		&orderItem.Price, &orderItem.TotalPrice, &orderItem.Subtotal, &orderItem.Discount, &orderItem.Tax)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(appliedDiscounts) > 0 {
+		if err := json.Unmarshal(appliedDiscounts, &orderItem.AppliedDiscounts); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal applied discounts: %w", err)
+		}
+	}
+	if len(taxBreakdown) > 0 {
+		if err := json.Unmarshal(taxBreakdown, &orderItem.TaxBreakdown); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tax breakdown: %w", err)
+		}
+	}
+
 	return orderItem, nil
 }
@@ -0,0 +1,264 @@
+package order
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/pkg/configs"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// capturedArg matches any argument and keeps it.
+type capturedArg struct {
+	value interface{}
+}
+
+func (c *capturedArg) Match(value driver.Value) bool {
+	c.value = value
+	return true
+}
+
+func TestCreateOrder(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error opening mock database %v", err)
+	}
+	defer db.Close()
+
+	// the ID is made before the insert rather than read back from the newest
+	// order, so concurrent checkouts each get their own
+	inserted := &capturedArg{}
+	args := []driver.Value{inserted}
+	for i := 0; i < 20; i++ {
+		args = append(args, sqlmock.AnyArg())
+	}
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO orders (id, userId,")).
+		WithArgs(args...).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	id, err := NewStore(db).CreateOrder(entity.Order{UserID: "user-1", Currency: "USD"})
+	assert.NoError(t, err)
+	assert.Len(t, id, 36)
+	assert.Equal(t, id, inserted.value)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPlaceOrder(t *testing.T) {
+	order := entity.Order{UserID: "user-1", Currency: "USD", Status: configs.Envs.OrderStatusPending}
+	newItems := func() []*entity.OrderItem {
+		return []*entity.OrderItem{{ProductID: "product-1", Quantity: 2, StoreID: "store-1", Currency: "USD"}}
+	}
+	storeOrders := []entity.StoreOrder{{StoreID: "store-1", Status: configs.Envs.OrderStatusPending, Currency: "USD"}}
+	redemptions := []entity.PromotionRedemption{{PromotionID: "promotion-1", UserID: "user-1", Code: "SAVE10", Discount: entity.NewMoney(1000, "USD")}}
+
+	t.Run("the order is written with its items, store orders and redemptions", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error opening mock database %v", err)
+		}
+		defer db.Close()
+
+		inserted := &capturedArg{}
+		args := []driver.Value{inserted}
+		for i := 0; i < 20; i++ {
+			args = append(args, sqlmock.AnyArg())
+		}
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO orders (id, userId,")).
+			WithArgs(args...).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO orderitems")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO store_orders")).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO promotionredemptions")).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		items := newItems()
+		id, err := NewStore(db).PlaceOrder(order, items, storeOrders, redemptions)
+		assert.NoError(t, err)
+		assert.Equal(t, id, inserted.value)
+		assert.Equal(t, id, items[0].OrderID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a failed item leaves no order behind", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error opening mock database %v", err)
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO orders (id, userId,")).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO orderitems")).
+			WillReturnError(fmt.Errorf("connection lost"))
+		mock.ExpectRollback()
+
+		_, err = NewStore(db).PlaceOrder(order, newItems(), storeOrders, redemptions)
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestCancelOrder(t *testing.T) {
+	t.Run("a pending order gives its stock back to its products", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error opening mock database %v", err)
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT status, paymentStatus FROM orders WHERE id = ? FOR UPDATE")).
+			WithArgs("order-1").
+			WillReturnRows(sqlmock.NewRows([]string{"status", "paymentStatus"}).AddRow(configs.Envs.OrderStatusPending, configs.Envs.PaymentStatusPending))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT productId, quantity FROM orderitems WHERE orderId = ?")).
+			WithArgs("order-1").
+			WillReturnRows(sqlmock.NewRows([]string{"productId", "quantity"}).AddRow("product-1", 2))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE products SET quantity = quantity + ? WHERE productId = ?")).
+			WithArgs(2, "product-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT promotionId, COUNT(*) FROM promotionredemptions WHERE orderId = ? GROUP BY promotionId")).
+			WithArgs("order-1").
+			WillReturnRows(sqlmock.NewRows([]string{"promotionId", "count"}))
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM promotionredemptions WHERE orderId = ?")).
+			WithArgs("order-1").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE orders SET status = ?, updatedAt = NOW() WHERE id = ?")).
+			WithArgs(configs.Envs.OrderStatusCancelled, "order-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE store_orders SET status = ?, updatedAt = NOW() WHERE orderId = ?")).
+			WithArgs(configs.Envs.OrderStatusCancelled, "order-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		cancelled, err := NewStore(db).CancelOrder("order-1")
+		assert.NoError(t, err)
+		assert.True(t, cancelled)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a paid order is left alone", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error opening mock database %v", err)
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT status, paymentStatus FROM orders WHERE id = ? FOR UPDATE")).
+			WithArgs("order-1").
+			WillReturnRows(sqlmock.NewRows([]string{"status", "paymentStatus"}).AddRow(configs.Envs.OrderStatusProcessing, configs.Envs.PaymentStatusPaid))
+		mock.ExpectRollback()
+
+		cancelled, err := NewStore(db).CancelOrder("order-1")
+		assert.NoError(t, err)
+		assert.False(t, cancelled)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestMoveOrderStatus(t *testing.T) {
+	const move = "UPDATE orders SET status = ?, updatedAt = NOW() WHERE id = ? AND status = ?"
+	event := entity.OutboxMessage{Topic: entity.TopicOrderStatusChanged, Key: "order.status_changed:order-1:completed", Payload: []byte("{}")}
+
+	t.Run("the status and its messages are written together", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error opening mock database %v", err)
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(move)).
+			WithArgs(configs.Envs.OrderStatusCompleted, "order-1", configs.Envs.OrderStatusShipped).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT IGNORE INTO outbox_messages")).
+			WithArgs(sqlmock.AnyArg(), event.Topic, event.Key, event.Payload, entity.OutboxPending, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		moved, err := NewStore(db).MoveOrderStatus("order-1", configs.Envs.OrderStatusShipped, configs.Envs.OrderStatusCompleted, event)
+		assert.NoError(t, err)
+		assert.True(t, moved)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("an order moved meanwhile writes no messages", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error opening mock database %v", err)
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(move)).
+			WithArgs(configs.Envs.OrderStatusCompleted, "order-1", configs.Envs.OrderStatusShipped).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		moved, err := NewStore(db).MoveOrderStatus("order-1", configs.Envs.OrderStatusShipped, configs.Envs.OrderStatusCompleted, event)
+		assert.NoError(t, err)
+		assert.False(t, moved)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRefundOrder(t *testing.T) {
+	const lock = "SELECT paymentStatus FROM orders WHERE id = ? FOR UPDATE"
+	event := entity.OutboxMessage{Topic: entity.TopicOrderStatusChanged, Key: "order.status_changed:order-1:refunded", Payload: []byte("{}")}
+
+	t.Run("the refund and its messages are written together", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error opening mock database %v", err)
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(lock)).
+			WithArgs("order-1").
+			WillReturnRows(sqlmock.NewRows([]string{"paymentStatus"}).AddRow(configs.Envs.PaymentStatusPaid))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE orders SET paymentStatus = ?, status = ?, updatedAt = NOW() WHERE id = ?")).
+			WithArgs(configs.Envs.PaymentStatusRefunded, configs.Envs.OrderStatusRefunded, "order-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE store_orders SET status = ?, updatedAt = NOW() WHERE orderId = ? AND status IN (?,?,?)")).
+			WithArgs(configs.Envs.OrderStatusRefunded, "order-1", configs.Envs.OrderStatusProcessing, configs.Envs.OrderStatusShipped, configs.Envs.OrderStatusCompleted).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT IGNORE INTO outbox_messages")).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		refunded, err := NewStore(db).RefundOrder("order-1", event)
+		assert.NoError(t, err)
+		assert.True(t, refunded)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a refunded order is left alone", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error opening mock database %v", err)
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(lock)).
+			WithArgs("order-1").
+			WillReturnRows(sqlmock.NewRows([]string{"paymentStatus"}).AddRow(configs.Envs.PaymentStatusRefunded))
+		mock.ExpectRollback()
+
+		refunded, err := NewStore(db).RefundOrder("order-1", event)
+		assert.NoError(t, err)
+		assert.False(t, refunded)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,187 @@
+package order
+
+import (
+	"database/sql"
+	"fmt"
+
+	"ecom-api/internal/adapters/framework/right/outbox_repo"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/utils"
+)
+
+func (store *Store) CreateStoreOrder(storeOrder entity.StoreOrder) (string, error) {
+	storeOrder.ID = utils.GenerateRandomUniqueIdentifier()
+	if err := insertStoreOrder(store.db, storeOrder); err != nil {
+		return "", err
+	}
+
+	return storeOrder.ID, nil
+}
+
+func insertStoreOrder(exec execer, storeOrder entity.StoreOrder) error {
+	_, err := exec.Exec("INSERT INTO store_orders (id, orderId, storeId, status, subtotal, discount, tax, shippingCost, total, currency) VALUES (?,?,?,?,?,?,?,?,?,?)",
+		storeOrder.ID, storeOrder.OrderID, nullableString(storeOrder.StoreID), storeOrder.Status, storeOrder.Subtotal.String(), storeOrder.Discount.String(), storeOrder.Tax.String(), storeOrder.ShippingCost.String(), storeOrder.Total.String(), storeOrder.Currency)
+	if err != nil {
+		return fmt.Errorf("failed to create store order: %w", err)
+	}
+	return nil
+}
+
+func (store *Store) GetStoreOrderByID(storeOrderID string) (*entity.StoreOrder, error) {
+	storeOrders, err := store.getStoreOrders("SELECT * FROM store_orders WHERE id = ?", storeOrderID)
+	if err != nil {
+		return nil, err
+	}
+	if len(storeOrders) == 0 {
+		return new(entity.StoreOrder), nil
+	}
+	return storeOrders[0], nil
+}
+
+func (store *Store) GetStoreOrdersByOrderID(orderID string) ([]*entity.StoreOrder, error) {
+	return store.getStoreOrders("SELECT * FROM store_orders WHERE orderId = ? ORDER BY storeId", orderID)
+}
+
+func (store *Store) GetStoreOrdersByStoreID(storeID string) ([]*entity.StoreOrder, error) {
+	return store.getStoreOrders("SELECT * FROM store_orders WHERE storeId = ? ORDER BY createdAt DESC", storeID)
+}
+
+// UpdateStoreOrderStatus moves a store order on only from the status it was
+// read in, so two requests cannot both move it.
+func (store *Store) UpdateStoreOrderStatus(storeOrderID, fromStatus, toStatus string) (bool, error) {
+	result, err := store.db.Exec("UPDATE store_orders SET status = ?, updatedAt = NOW() WHERE id = ? AND status = ?", toStatus, storeOrderID, fromStatus)
+	if err != nil {
+		return false, fmt.Errorf("failed to update store order status: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+func (store *Store) UpdateStoreOrdersStatus(orderID, fromStatus, toStatus string) error {
+	_, err := store.db.Exec("UPDATE store_orders SET status = ?, updatedAt = NOW() WHERE orderId = ? AND status = ?", toStatus, orderID, fromStatus)
+	if err != nil {
+		return fmt.Errorf("failed to update store orders status: %w", err)
+	}
+	return nil
+}
+
+// CreateShipment records a parcel sent for a store order, writing messages to
+// the outbox in the same transaction. A shipment without an ID is given one,
+// callers that key messages by the shipment set it beforehand.
+func (store *Store) CreateShipment(shipment entity.Shipment, messages ...entity.OutboxMessage) (string, error) {
+	if shipment.ID == "" {
+		shipment.ID = utils.GenerateRandomUniqueIdentifier()
+	}
+
+	tx, err := store.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("INSERT INTO shipments (id, storeOrderId, carrier, trackingNumber) VALUES (?,?,?,?)",
+		shipment.ID, shipment.StoreOrderID, shipment.Carrier, shipment.TrackingNumber)
+	if err != nil {
+		return "", fmt.Errorf("failed to create shipment: %w", err)
+	}
+	if err := outbox_repo.AddMessages(tx, messages...); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+
+	return shipment.ID, nil
+}
+
+func (store *Store) GetShipmentsByStoreOrderID(storeOrderID string) ([]*entity.Shipment, error) {
+	rows, err := store.db.Query("SELECT * FROM shipments WHERE storeOrderId = ? ORDER BY createdAt", storeOrderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shipments: %w", err)
+	}
+	defer rows.Close()
+
+	var shipments []*entity.Shipment
+	for rows.Next() {
+		shipment := new(entity.Shipment)
+		err := rows.Scan(
+			&shipment.ID,
+			&shipment.StoreOrderID,
+			&shipment.Carrier,
+			&shipment.TrackingNumber,
+			&shipment.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		shipments = append(shipments, shipment)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return shipments, nil
+}
+
+func (store *Store) getStoreOrders(query string, arg string) ([]*entity.StoreOrder, error) {
+	rows, err := store.db.Query(query, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query store orders: %w", err)
+	}
+	defer rows.Close()
+
+	var storeOrders []*entity.StoreOrder
+	for rows.Next() {
+		storeOrder, err := scanRowsIntoStoreOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		storeOrders = append(storeOrders, storeOrder)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return storeOrders, nil
+}
+
+func scanRowsIntoStoreOrder(rows *sql.Rows) (*entity.StoreOrder, error) {
+	storeOrder := new(entity.StoreOrder)
+	var storeID sql.NullString
+	var subtotal, discount, tax, shippingCost, total string
+
+	err := rows.Scan(
+		&storeOrder.ID,
+		&storeOrder.OrderID,
+		&storeID,
+		&storeOrder.Status,
+		&subtotal,
+		&discount,
+		&tax,
+		&shippingCost,
+		&total,
+		&storeOrder.Currency,
+		&storeOrder.CreatedAt,
+		&storeOrder.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	storeOrder.StoreID = storeID.String
+
+	err = parseMoneyColumns(storeOrder.Currency,
+		[]string{subtotal, discount, tax, shippingCost, total},
+		&storeOrder.Subtotal, &storeOrder.Discount, &storeOrder.Tax, &storeOrder.ShippingCost, &storeOrder.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	return storeOrder, nil
+}
@@ -0,0 +1,234 @@
+package outbox_repo
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/utils"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) AddOutboxMessages(messages ...entity.OutboxMessage) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := AddMessages(tx, messages...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AddMessages writes messages to the outbox in tx, which is how other stores
+// send an email or a domain event along with the change they commit. A
+// message whose key is already in the outbox is skipped.
+func AddMessages(tx *sql.Tx, messages ...entity.OutboxMessage) error {
+	now := time.Now()
+	for _, message := range messages {
+		_, err := tx.Exec("INSERT IGNORE INTO outbox_messages (id, topic, dedupeKey, payload, status, nextAttemptAt) VALUES (?,?,?,?,?,?)",
+			utils.GenerateRandomUniqueIdentifier(), message.Topic, nullableString(message.Key), message.Payload, entity.OutboxPending, now)
+		if err != nil {
+			return fmt.Errorf("failed to add %s outbox message: %w", message.Topic, err)
+		}
+	}
+	return nil
+}
+
+// ClaimOutboxMessages takes up to limit due messages, oldest first, and marks
+// them processing until the lease runs out. Rows locked by another dispatcher
+// are skipped, and a message whose dispatcher died before finishing is due
+// again once its lease has passed.
+func (s *Store) ClaimOutboxMessages(limit int, lease time.Duration) ([]*entity.OutboxMessage, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	rows, err := tx.Query("SELECT * FROM outbox_messages WHERE status IN (?,?,?) AND nextAttemptAt <= ? ORDER BY createdAt LIMIT ? FOR UPDATE SKIP LOCKED",
+		entity.OutboxPending, entity.OutboxFailed, entity.OutboxProcessing, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query due outbox messages: %w", err)
+	}
+
+	var messages []*entity.OutboxMessage
+	for rows.Next() {
+		message, err := scanRowsIntoOutboxMessage(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	leaseEnd := now.Add(lease)
+	for _, message := range messages {
+		_, err := tx.Exec("UPDATE outbox_messages SET status = ?, attempts = attempts + 1, nextAttemptAt = ? WHERE id = ?",
+			entity.OutboxProcessing, leaseEnd, message.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim outbox message %s: %w", message.ID, err)
+		}
+		message.Status = entity.OutboxProcessing
+		message.Attempts++
+		message.NextAttemptAt = leaseEnd
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (s *Store) MarkOutboxMessageSent(id string) error {
+	_, err := s.db.Exec("UPDATE outbox_messages SET status = ?, lastError = NULL, sentAt = ? WHERE id = ?",
+		entity.OutboxSent, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox message sent: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) MarkOutboxMessageFailed(id, lastError string, nextAttemptAt time.Time, dead bool) error {
+	status := entity.OutboxFailed
+	if dead {
+		status = entity.OutboxDead
+	}
+
+	_, err := s.db.Exec("UPDATE outbox_messages SET status = ?, lastError = ?, nextAttemptAt = ? WHERE id = ?",
+		status, lastError, nextAttemptAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox message failed: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) GetOutboxMessagesByStatus(statuses ...string) ([]*entity.OutboxMessage, error) {
+	if len(statuses) == 0 {
+		return nil, nil
+	}
+
+	args := make([]interface{}, len(statuses))
+	for i, status := range statuses {
+		args[i] = status
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(statuses)), ",")
+	rows, err := s.db.Query("SELECT * FROM outbox_messages WHERE status IN ("+placeholders+") ORDER BY createdAt DESC", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []*entity.OutboxMessage
+	for rows.Next() {
+		message, err := scanRowsIntoOutboxMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (s *Store) GetOutboxDepth() (map[string]int, *time.Time, error) {
+	rows, err := s.db.Query("SELECT status, COUNT(*) FROM outbox_messages WHERE status <> ? GROUP BY status", entity.OutboxSent)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to count outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	depth := map[string]int{}
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, nil, err
+		}
+		depth[status] = count
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var oldestDueAt sql.NullTime
+	err = s.db.QueryRow("SELECT MIN(nextAttemptAt) FROM outbox_messages WHERE status IN (?,?) AND nextAttemptAt <= ?",
+		entity.OutboxPending, entity.OutboxFailed, time.Now()).Scan(&oldestDueAt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query the oldest outbox message: %w", err)
+	}
+	if !oldestDueAt.Valid {
+		return depth, nil, nil
+	}
+
+	return depth, &oldestDueAt.Time, nil
+}
+
+// ReplayOutboxMessage queues a failed or dead message to be delivered right
+// away with a fresh set of attempts.
+func (s *Store) ReplayOutboxMessage(id string) (bool, error) {
+	result, err := s.db.Exec("UPDATE outbox_messages SET status = ?, attempts = 0, nextAttemptAt = ? WHERE id = ? AND status IN (?,?)",
+		entity.OutboxPending, time.Now(), id, entity.OutboxFailed, entity.OutboxDead)
+	if err != nil {
+		return false, fmt.Errorf("failed to replay outbox message: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+func scanRowsIntoOutboxMessage(rows *sql.Rows) (*entity.OutboxMessage, error) {
+	message := new(entity.OutboxMessage)
+	var key, lastError sql.NullString
+
+	err := rows.Scan(
+		&message.ID,
+		&message.Topic,
+		&key,
+		&message.Payload,
+		&message.Status,
+		&message.Attempts,
+		&lastError,
+		&message.NextAttemptAt,
+		&message.SentAt,
+		&message.CreatedAt,
+		&message.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	message.Key = key.String
+	message.LastError = lastError.String
+
+	return message, nil
+}
+
+func nullableString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
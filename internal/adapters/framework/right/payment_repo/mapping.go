@@ -0,0 +1,160 @@
+package paymentrepo
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"ecom-api/internal/application/core/types/entity"
+
+	"github.com/stripe/stripe-go"
+)
+
+// The functions below map Stripe's types to the domain types of the payment
+// port, so nothing outside this package depends on Stripe.
+
+func toPaymentCustomer(customer *stripe.Customer) *entity.PaymentCustomer {
+	paymentCustomer := &entity.PaymentCustomer{
+		ID:          customer.ID,
+		Email:       customer.Email,
+		Name:        customer.Name,
+		Phone:       customer.Phone,
+		Description: customer.Description,
+		Balance:     customer.Balance,
+		Metadata:    customer.Metadata,
+		CreatedAt:   time.Unix(customer.Created, 0),
+	}
+	if customer.InvoiceSettings != nil && customer.InvoiceSettings.DefaultPaymentMethod != nil {
+		paymentCustomer.DefaultPaymentMethodID = customer.InvoiceSettings.DefaultPaymentMethod.ID
+	}
+	return paymentCustomer
+}
+
+func toPaymentMethod(paymentMethod *stripe.PaymentMethod) *entity.PaymentMethod {
+	method := &entity.PaymentMethod{
+		ID:   paymentMethod.ID,
+		Type: string(paymentMethod.Type),
+	}
+	if paymentMethod.Customer != nil {
+		method.CustomerID = paymentMethod.Customer.ID
+	}
+	if paymentMethod.Card != nil {
+		method.Brand = string(paymentMethod.Card.Brand)
+		method.Last4 = paymentMethod.Card.Last4
+		method.ExpMonth = int(paymentMethod.Card.ExpMonth)
+		method.ExpYear = int(paymentMethod.Card.ExpYear)
+	}
+	return method
+}
+
+func toPaymentIntent(intent *stripe.PaymentIntent) *entity.PaymentIntent {
+	paymentIntent := &entity.PaymentIntent{
+		ID:           intent.ID,
+		Amount:       entity.NewMoney(intent.Amount, strings.ToUpper(intent.Currency)),
+		Status:       paymentIntentStatus(intent.Status),
+		ClientSecret: intent.ClientSecret,
+		Description:  intent.Description,
+		Metadata:     intent.Metadata,
+	}
+	if intent.Customer != nil {
+		paymentIntent.CustomerID = intent.Customer.ID
+	}
+	if intent.PaymentMethod != nil {
+		paymentIntent.PaymentMethodID = intent.PaymentMethod.ID
+	}
+	return paymentIntent
+}
+
+func paymentIntentStatus(status stripe.PaymentIntentStatus) string {
+	switch status {
+	case stripe.PaymentIntentStatusSucceeded:
+		return entity.PaymentIntentSucceeded
+	case stripe.PaymentIntentStatusRequiresAction:
+		return entity.PaymentIntentRequiresAction
+	case stripe.PaymentIntentStatusCanceled:
+		return entity.PaymentIntentCanceled
+	case stripe.PaymentIntentStatusRequiresPaymentMethod:
+		return entity.PaymentIntentFailed
+	default:
+		return entity.PaymentIntentProcessing
+	}
+}
+
+func toSetupIntent(intent *stripe.SetupIntent) *entity.SetupIntent {
+	setupIntent := &entity.SetupIntent{
+		ID:           intent.ID,
+		ClientSecret: intent.ClientSecret,
+		Status:       string(intent.Status),
+	}
+	if intent.Customer != nil {
+		setupIntent.CustomerID = intent.Customer.ID
+	}
+	return setupIntent
+}
+
+func toRefund(refund *stripe.Refund, paymentIntentId string) *entity.Refund {
+	return &entity.Refund{
+		ID:              refund.ID,
+		PaymentIntentID: paymentIntentId,
+		Amount:          entity.NewMoney(refund.Amount, strings.ToUpper(string(refund.Currency))),
+		Status:          string(refund.Status),
+	}
+}
+
+// chargeError tells card declines apart from other failures.
+func chargeError(err error) error {
+	if stripeErr, ok := err.(*stripe.Error); ok && stripeErr.Type == stripe.ErrorTypeCard {
+		return fmt.Errorf("%w: %s", entity.ErrPaymentDeclined, stripeErr.Msg)
+	}
+	return fmt.Errorf("payment faild:%s", err)
+}
+
+func toPaymentSubscription(subscription *stripe.Subscription) *entity.PaymentSubscription {
+	paymentSubscription := &entity.PaymentSubscription{
+		ID:     subscription.ID,
+		Status: subscriptionStatus(subscription),
+	}
+	if subscription.Customer != nil {
+		paymentSubscription.CustomerID = subscription.Customer.ID
+	}
+	if renewsAt := subscriptionRenewsAt(subscription); !renewsAt.IsZero() {
+		paymentSubscription.RenewsAt = &renewsAt
+	}
+	return paymentSubscription
+}
+
+// subscriptionStatus maps the status of a Stripe subscription to one of the
+// entity.Subscription statuses. A subscription whose collection is paused is
+// paused whatever Stripe calls it, and a skipped renewal is a trial that is
+// still active.
+func subscriptionStatus(subscription *stripe.Subscription) string {
+	if subscription.PauseCollection.Behavior != "" && subscription.Status != stripe.SubscriptionStatusCanceled {
+		return entity.SubscriptionPaused
+	}
+	switch subscription.Status {
+	case stripe.SubscriptionStatusActive, stripe.SubscriptionStatusTrialing:
+		return entity.SubscriptionActive
+	case stripe.SubscriptionStatusPastDue, stripe.SubscriptionStatusUnpaid:
+		return entity.SubscriptionPastDue
+	case stripe.SubscriptionStatusCanceled, stripe.SubscriptionStatusIncompleteExpired:
+		return entity.SubscriptionCancelled
+	default:
+		return entity.SubscriptionIncomplete
+	}
+}
+
+// subscriptionRenewsAt returns when a Stripe subscription is charged next, the
+// end of its trial while a renewal is skipped. Zero once it is cancelled.
+func subscriptionRenewsAt(subscription *stripe.Subscription) time.Time {
+	if subscription.Status == stripe.SubscriptionStatusCanceled {
+		return time.Time{}
+	}
+	next := subscription.CurrentPeriodEnd
+	if subscription.Status == stripe.SubscriptionStatusTrialing && subscription.TrialEnd > 0 {
+		next = subscription.TrialEnd
+	}
+	if next == 0 {
+		return time.Time{}
+	}
+	return time.Unix(next, 0)
+}
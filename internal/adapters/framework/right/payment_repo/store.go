@@ -1,14 +1,21 @@
 package paymentrepo
 
 import (
+	"ecom-api/internal/application/core/types/entity"
 	"ecom-api/internal/application/core/types/entity/payloads"
+	"ecom-api/pkg/configs"
 	"fmt"
+	"net/http"
+	"strings"
 
 	"github.com/stripe/stripe-go"
-	"github.com/stripe/stripe-go/checkout/session"
 	"github.com/stripe/stripe-go/customer"
 	"github.com/stripe/stripe-go/paymentintent"
 	"github.com/stripe/stripe-go/paymentmethod"
+	"github.com/stripe/stripe-go/refund"
+	"github.com/stripe/stripe-go/reversal"
+	"github.com/stripe/stripe-go/setupintent"
+	"github.com/stripe/stripe-go/transfer"
 )
 
 type PaymentStore struct{}
@@ -17,20 +24,27 @@ func NewPaymentStore() *PaymentStore {
 	return &PaymentStore{}
 }
 
-func (store *PaymentStore) CreateStripeCustomer(customerParams *payloads.CustomerPayload) (*stripe.Customer, error) {
-
-	address := mapAddressToStripe(customerParams.Address)
-	shipping := mapShippingToStripe(customerParams.Shipping, address)
-
+// CreateCustomer creates a customer from the payload. Only the name and
+// email are needed, empty fields are left out since Stripe refuses empty
+// strings.
+func (store *PaymentStore) CreateCustomer(customerParams *payloads.CustomerPayload, idempotencyKey string) (*entity.PaymentCustomer, error) {
 	params := &stripe.CustomerParams{
 		Name:        stripe.String(customerParams.Name),
 		Email:       stripe.String(customerParams.Email),
-		Phone:       stripe.String(customerParams.Phone),
-		Description: stripe.String(customerParams.Description),
+		Phone:       optionalString(customerParams.Phone),
+		Description: optionalString(customerParams.Description),
 		Balance:     stripe.Int64(customerParams.Balance),
-		Address:     address,
-		Shipping:    shipping,
 	}
+	if customerParams.Address != (payloads.Address{}) {
+		params.Address = mapAddressToStripe(customerParams.Address)
+	}
+	if customerParams.Shipping.Name != "" {
+		params.Shipping = mapShippingToStripe(customerParams.Shipping, mapAddressToStripe(customerParams.Shipping.Address))
+	}
+	for key, value := range customerParams.Metadata {
+		params.AddMetadata(key, value)
+	}
+	setIdempotencyKey(&params.Params, idempotencyKey)
 
 	newCustomer, err := customer.New(params)
 
@@ -38,10 +52,10 @@ func (store *PaymentStore) CreateStripeCustomer(customerParams *payloads.Custome
 		return nil, err
 	}
 
-	return newCustomer, err
+	return toPaymentCustomer(newCustomer), err
 }
 
-func (store *PaymentStore) GetStripeCustomer(customerId string) (*stripe.Customer, error) {
+func (store *PaymentStore) GetCustomer(customerId string) (*entity.PaymentCustomer, error) {
 	params := &stripe.CustomerParams{}
 
 	customer, err := customer.Get(customerId, params)
@@ -50,20 +64,20 @@ func (store *PaymentStore) GetStripeCustomer(customerId string) (*stripe.Custome
 		return nil, fmt.Errorf("customer detail retrival failed :%v", err)
 	}
 
-	return customer, nil
+	return toPaymentCustomer(customer), nil
 }
 
-func (store *PaymentStore) GetAllStripeCustomers() ([]stripe.Customer, error) {
+func (store *PaymentStore) GetAllCustomers() ([]*entity.PaymentCustomer, error) {
 	params := &stripe.CustomerListParams{}
 	params.Limit = stripe.Int64(100)
 
-	var customers []stripe.Customer
+	var customers []*entity.PaymentCustomer
 
 	iter := customer.List(params)
 
 	for iter.Next() {
 		customer := iter.Customer()
-		customers = append(customers, *customer)
+		customers = append(customers, toPaymentCustomer(customer))
 	}
 
 	if iter.Err() != nil {
@@ -73,9 +87,14 @@ func (store *PaymentStore) GetAllStripeCustomers() ([]stripe.Customer, error) {
 	return customers, nil
 }
 
-func (store *PaymentStore) CreatePaymentMethod(customerId string) (*stripe.PaymentMethod, error) {
+// CreatePaymentMethod attaches Stripe's test card to the customer. It is a
+// test-mode fixture, real cards are saved through a SetupIntent the client
+// confirms.
+func (store *PaymentStore) CreatePaymentMethod(customerId string) (*entity.PaymentMethod, error) {
+	if !configs.Envs.StripeTestMode {
+		return nil, fmt.Errorf("test cards are only available in test mode, save cards through a setup intent")
+	}
 
-	//test-mode
 	params := &stripe.PaymentMethodParams{
 		Type: stripe.String("card"),
 		Card: &stripe.PaymentMethodCardParams{
@@ -88,17 +107,101 @@ func (store *PaymentStore) CreatePaymentMethod(customerId string) (*stripe.Payme
 		return nil, fmt.Errorf("unable to create payment method: %v", err)
 	}
 
-	_, err = AttachCustomerPaymentMethod(customerId, paymentMethod.ID)
+	attached, err := AttachCustomerPaymentMethod(customerId, paymentMethod.ID)
 
 	if err != nil {
 		return nil, fmt.Errorf("unable to attach payment method to customer %s", customerId)
 	}
 
-	return paymentMethod, nil
+	return toPaymentMethod(attached), nil
+}
+
+// CreateSetupIntent starts saving a card for the customer. The client confirms
+// the intent with its client secret and Stripe attaches the card once it is
+// confirmed, so card details never reach this API.
+func (store *PaymentStore) CreateSetupIntent(customerId, idempotencyKey string) (*entity.SetupIntent, error) {
+	params := &stripe.SetupIntentParams{
+		Customer:           stripe.String(customerId),
+		PaymentMethodTypes: stripe.StringSlice([]string{"card"}),
+		Usage:              stripe.String(string(stripe.SetupIntentUsageOffSession)),
+	}
+	setIdempotencyKey(&params.Params, idempotencyKey)
+
+	intent, err := setupintent.New(params)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create setup intent: %v", err)
+	}
+
+	return toSetupIntent(intent), nil
+}
+
+func (store *PaymentStore) ListPaymentMethods(customerId string) ([]*entity.PaymentMethod, error) {
+	params := &stripe.PaymentMethodListParams{
+		Customer: stripe.String(customerId),
+		Type:     stripe.String("card"),
+	}
+	params.Limit = stripe.Int64(100)
+
+	var paymentMethods []*entity.PaymentMethod
+
+	iter := paymentmethod.List(params)
+
+	for iter.Next() {
+		paymentMethods = append(paymentMethods, toPaymentMethod(iter.PaymentMethod()))
+	}
+
+	if iter.Err() != nil {
+		return nil, fmt.Errorf("failed to list payment methods: %v", iter.Err())
+	}
+
+	return paymentMethods, nil
 }
 
-func (store *PaymentStore) CreateStripeCharge(chargeParams *payloads.CustomerChargeRequest, customerId string) (*stripe.PaymentIntent, error) {
-	testPaymentMethodID := "pm_card_amex" // Stripe's test Visa payment method
+func (store *PaymentStore) GetPaymentMethod(paymentMethodId string) (*entity.PaymentMethod, error) {
+	paymentMethod, err := paymentmethod.Get(paymentMethodId, nil)
+	if err != nil {
+		return nil, fmt.Errorf("payment method retrival failed: %v", err)
+	}
+
+	return toPaymentMethod(paymentMethod), nil
+}
+
+// SetDefaultPaymentMethod makes the payment method the one charged when no
+// other is chosen.
+func (store *PaymentStore) SetDefaultPaymentMethod(customerId, paymentMethodId string) error {
+	params := &stripe.CustomerParams{
+		InvoiceSettings: &stripe.CustomerInvoiceSettingsParams{
+			DefaultPaymentMethod: stripe.String(paymentMethodId),
+		},
+	}
+
+	if _, err := customer.Update(customerId, params); err != nil {
+		return fmt.Errorf("unable to set default payment method: %v", err)
+	}
+
+	return nil
+}
+
+func (store *PaymentStore) DetachPaymentMethod(paymentMethodId string) error {
+	if _, err := paymentmethod.Detach(paymentMethodId, nil); err != nil {
+		return fmt.Errorf("unable to detach payment method: %v", err)
+	}
+
+	return nil
+}
+
+// CreateCharge charges the customer with the payment method of the
+// request. In test mode Stripe's test card stands in when none is given. A
+// card that needs 3D Secure leaves the intent in requires_action, for the
+// client to complete with the client secret.
+func (store *PaymentStore) CreateCharge(chargeParams *payloads.CustomerChargeRequest, customerId, idempotencyKey string) (*entity.PaymentIntent, error) {
+	paymentMethodID := chargeParams.PaymentMethodID
+	if paymentMethodID == "" && configs.Envs.StripeTestMode {
+		paymentMethodID = "pm_card_amex" // Stripe's test Amex payment method
+	}
+	if paymentMethodID == "" {
+		return nil, fmt.Errorf("no payment method to charge, save a card first")
+	}
 
 	params := &stripe.PaymentIntentParams{
 		Amount:             stripe.Int64(chargeParams.Amount),
@@ -106,49 +209,190 @@ func (store *PaymentStore) CreateStripeCharge(chargeParams *payloads.CustomerCha
 		ReceiptEmail:       stripe.String(chargeParams.ReceiptEmail),
 		Description:        stripe.String(chargeParams.Description),
 		Customer:           stripe.String(customerId),
-		PaymentMethod:      stripe.String(testPaymentMethodID),
-		Confirm:            stripe.Bool(true), // Automatically confirm in test mode
+		PaymentMethod:      stripe.String(paymentMethodID),
+		Confirm:            stripe.Bool(true),
 		ConfirmationMethod: stripe.String(string(stripe.PaymentIntentConfirmationMethodAutomatic)),
 	}
 
-	// Add test-specific metadata (optional)
-	params.AddMetadata("environment", "test")
-	params.AddMetadata("test_case", "successful_payment")
+	if configs.Envs.StripeTestMode {
+		params.AddMetadata("environment", "test")
+	}
+	setIdempotencyKey(&params.Params, idempotencyKey)
 
 	charge, err := paymentintent.New(params)
 	if err != nil {
-		return nil, fmt.Errorf("payment faild:%s", err)
+		return nil, chargeError(err)
+	}
+
+	return toPaymentIntent(charge), nil
+}
+
+// CreateTransfer pays a connected account. Tied to the charge of the order the
+// money is only moved once the charge is settled.
+func (store *PaymentStore) CreateTransfer(destination string, amount entity.Money, transferGroup, sourceChargeId, idempotencyKey string) (*entity.Transfer, error) {
+	params := &stripe.TransferParams{
+		Amount:        stripe.Int64(amount.Amount),
+		Currency:      stripe.String(strings.ToLower(amount.Currency)),
+		Destination:   stripe.String(destination),
+		TransferGroup: optionalString(transferGroup),
+	}
+	if sourceChargeId != "" {
+		params.SourceTransaction = stripe.String(sourceChargeId)
+	}
+	setIdempotencyKey(&params.Params, idempotencyKey)
+
+	newTransfer, err := transfer.New(params)
+	if err != nil {
+		return nil, fmt.Errorf("transfer failed: %v", err)
 	}
 
-	return charge, nil
+	return &entity.Transfer{
+		ID:            newTransfer.ID,
+		Destination:   destination,
+		Amount:        entity.NewMoney(newTransfer.Amount, string(newTransfer.Currency)),
+		TransferGroup: newTransfer.TransferGroup,
+	}, nil
 }
 
-func (store *PaymentStore) CreateCheckoutSession(orderID string, totalPrice float64, email string) (*stripe.CheckoutSession, error) {
+// ReverseTransfer takes the whole of a transfer back.
+func (store *PaymentStore) ReverseTransfer(transferId, idempotencyKey string) error {
+	params := &stripe.ReversalParams{
+		Transfer: stripe.String(transferId),
+	}
+	setIdempotencyKey(&params.Params, idempotencyKey)
+
+	if _, err := reversal.New(params); err != nil {
+		return fmt.Errorf("transfer reversal failed: %v", err)
+	}
+	return nil
+}
+
+// CreateRefund refunds amount of a payment, all of what is left of it when
+// amount is 0.
+func (store *PaymentStore) CreateRefund(paymentIntentId string, amount int64, idempotencyKey string) (*entity.Refund, error) {
+	params := &stripe.RefundParams{
+		PaymentIntent: stripe.String(paymentIntentId),
+	}
+	if amount > 0 {
+		params.Amount = stripe.Int64(amount)
+	}
+	setIdempotencyKey(&params.Params, idempotencyKey)
+
+	newRefund, err := refund.New(params)
+	if err != nil {
+		return nil, fmt.Errorf("refund failed: %v", err)
+	}
+
+	return toRefund(newRefund, paymentIntentId), nil
+}
+
+// CreateCheckoutSession opens a hosted Stripe Checkout page for the order,
+// listing its items and shipping in the order currency. Stripe takes amounts in
+// the minor unit of the currency, which is what Money already holds. The order
+// ID is set as metadata on the session and its payment intent so the webhook
+// can find the order again. The split makes the payment a destination charge
+// or puts it in the transfer group the stores are paid from.
+func (store *PaymentStore) CreateCheckoutSession(order entity.Order, items []*entity.OrderItem, email string, split entity.PaymentSplit, idempotencyKey string) (*entity.CheckoutSession, error) {
 	params := &stripe.CheckoutSessionParams{
 		PaymentMethodTypes: stripe.StringSlice([]string{"card"}),
 		Mode:               stripe.String(string(stripe.CheckoutSessionModePayment)),
-		LineItems: []*stripe.CheckoutSessionLineItemParams{
-			{
-				Currency: stripe.String("usd"),
-				Amount:   stripe.Int64(int64(totalPrice * 100)),
-				Quantity: stripe.Int64(1),
-			},
-		},
-		CustomerEmail: stripe.String(email),
-		Params: stripe.Params{
-			Metadata: map[string]string{
-				"order_id": orderID,
-			},
+		LineItems:          checkoutLineItems(order, items),
+		ClientReferenceID:  stripe.String(order.ID),
+		SuccessURL:         stripe.String(checkoutURL(configs.Envs.CheckoutSuccessURL, order.ID)),
+		CancelURL:          stripe.String(checkoutURL(configs.Envs.CheckoutCancelURL, order.ID)),
+		PaymentIntentData: &stripe.CheckoutSessionPaymentIntentDataParams{
+			Description: stripe.String("Order " + order.ID),
 		},
-		SuccessURL: stripe.String("https://localhost:8080/order/" + orderID),
 	}
+	if email != "" {
+		params.CustomerEmail = stripe.String(email)
+	}
+	params.AddMetadata("order_id", order.ID)
+	params.PaymentIntentData.AddMetadata("order_id", order.ID)
+	if split.Destination != "" {
+		params.PaymentIntentData.TransferData = &stripe.CheckoutSessionPaymentIntentDataTransferDataParams{
+			Destination: stripe.String(split.Destination),
+		}
+		params.PaymentIntentData.ApplicationFeeAmount = stripe.Int64(split.ApplicationFee.Amount)
+	}
+	if split.TransferGroup != "" {
+		// the session params of this client predate transfer groups
+		params.AddExtra("payment_intent_data[transfer_group]", split.TransferGroup)
+	}
+	setIdempotencyKey(&params.Params, idempotencyKey)
 
-	session, err := session.New(params)
+	// the session type of this client predates the hosted url, so decode the
+	// response into our own type
+	var hosted struct {
+		ID  string `json:"id"`
+		URL string `json:"url"`
+	}
+	err := stripe.GetBackend(stripe.APIBackend).Call(http.MethodPost, "/v1/checkout/sessions", stripe.Key, params, &hosted)
 	if err != nil {
 		return nil, err
 	}
 
-	return session, nil
+	if hosted.URL == "" {
+		hosted.URL = checkoutPageURL + hosted.ID
+	}
+
+	return &entity.CheckoutSession{ID: hosted.ID, URL: hosted.URL}, nil
+}
+
+// checkoutPageURL is where Stripe hosts a session when the response carries no
+// url of its own.
+const checkoutPageURL = "https://checkout.stripe.com/pay/"
+
+// checkoutLineItems lists each order item at what the buyer pays for it and
+// shipping as a line of its own. Order-wide discounts are not attributed to
+// items and neither is store credit, so when the lines do not add up to what
+// is due the order is sent as a single line and Stripe charges exactly that.
+func checkoutLineItems(order entity.Order, items []*entity.OrderItem) []*stripe.CheckoutSessionLineItemParams {
+	currency := strings.ToLower(order.Currency)
+	var lines []*stripe.CheckoutSessionLineItemParams
+	var sum int64
+
+	addLine := func(name, description string, amount int64) {
+		if amount <= 0 {
+			return
+		}
+		line := &stripe.CheckoutSessionLineItemParams{
+			Name:     stripe.String(name),
+			Amount:   stripe.Int64(amount),
+			Currency: stripe.String(currency),
+			Quantity: stripe.Int64(1),
+		}
+		// Stripe refuses empty strings
+		if description != "" {
+			line.Description = stripe.String(description)
+		}
+		lines = append(lines, line)
+		sum += amount
+	}
+
+	for _, item := range items {
+		addLine(item.ProductName, fmt.Sprintf("Qty %d", item.Quantity), item.TotalPrice.Amount)
+	}
+	addLine("Shipping", order.ShippingMethod, order.ShippingCost.Amount)
+
+	due := order.AmountDue()
+	if sum != due.Amount || len(lines) == 0 {
+		return []*stripe.CheckoutSessionLineItemParams{
+			{
+				Name:     stripe.String("Order " + order.ID),
+				Amount:   stripe.Int64(due.Amount),
+				Currency: stripe.String(currency),
+				Quantity: stripe.Int64(1),
+			},
+		}
+	}
+
+	return lines
+}
+
+// checkoutURL fills the order ID into a configured redirect URL.
+func checkoutURL(template, orderID string) string {
+	return strings.ReplaceAll(template, "{ORDER_ID}", orderID)
 }
 
 func (store *PaymentStore) DeleteCustomer(id string) (bool, error) {
@@ -165,23 +409,30 @@ func (store *PaymentStore) DeleteCustomer(id string) (bool, error) {
 func mapAddressToStripe(address payloads.Address) *stripe.AddressParams {
 
 	return &stripe.AddressParams{
-		Line1:      stripe.String(address.Line1),
-		Line2:      stripe.String(address.Line2),
-		City:       stripe.String(address.City),
-		State:      stripe.String(address.State),
-		PostalCode: stripe.String(address.PostalCode),
-		Country:    stripe.String(address.Country),
+		Line1:      optionalString(address.Line1),
+		Line2:      optionalString(address.Line2),
+		City:       optionalString(address.City),
+		State:      optionalString(address.State),
+		PostalCode: optionalString(address.PostalCode),
+		Country:    optionalString(address.Country),
 	}
 }
 
 func mapShippingToStripe(shipping payloads.Shipping, address *stripe.AddressParams) *stripe.CustomerShippingDetailsParams {
 	return &stripe.CustomerShippingDetailsParams{
 		Name:    stripe.String(shipping.Name),
-		Phone:   stripe.String(shipping.Phone),
+		Phone:   optionalString(shipping.Phone),
 		Address: address,
 	}
 }
 
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return stripe.String(value)
+}
+
 func AttachCustomerPaymentMethod(customerId string, paymentMethodId string) (*stripe.PaymentMethod, error) {
 
 	params := &stripe.PaymentMethodAttachParams{
@@ -222,3 +473,11 @@ func InProduction() {
 	// paymentMethod, err := paymentmethod.New(params)
 	// ... rest of the code ...
 }
+
+// setIdempotencyKey makes Stripe return the result of the first call for
+// retries made with the same key instead of acting twice.
+func setIdempotencyKey(params *stripe.Params, idempotencyKey string) {
+	if idempotencyKey != "" {
+		params.SetIdempotencyKey(idempotencyKey)
+	}
+}
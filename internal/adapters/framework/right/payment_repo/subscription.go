@@ -0,0 +1,184 @@
+package paymentrepo
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"ecom-api/internal/application/core/types/entity"
+
+	"github.com/stripe/stripe-go"
+	"github.com/stripe/stripe-go/plan"
+	"github.com/stripe/stripe-go/sub"
+)
+
+// CreateSubscription subscribes the customer to the plan and charges the first
+// renewal at once. A card that needs 3D Secure leaves the subscription
+// incomplete, for the client to confirm with the client secret. A declined
+// card cancels the subscription, nothing is left behind to pay for.
+func (store *PaymentStore) CreateSubscription(customerId string, subscriptionPlan entity.SubscriptionPlan, idempotencyKey string) (*entity.PaymentSubscription, error) {
+	planID, err := stripePlan(subscriptionPlan)
+	if err != nil {
+		return nil, err
+	}
+
+	params := &stripe.SubscriptionParams{
+		Customer: stripe.String(customerId),
+		Items: []*stripe.SubscriptionItemsParams{
+			{
+				Plan:     stripe.String(planID),
+				Quantity: stripe.Int64(int64(subscriptionPlan.Quantity)),
+			},
+		},
+		DefaultPaymentMethod: optionalString(subscriptionPlan.PaymentMethodID),
+		PaymentBehavior:      stripe.String("allow_incomplete"),
+	}
+	for key, value := range subscriptionPlan.Metadata {
+		params.AddMetadata(key, value)
+	}
+	params.AddExpand("latest_invoice.payment_intent")
+	setIdempotencyKey(&params.Params, idempotencyKey)
+
+	newSubscription, err := sub.New(params)
+	if err != nil {
+		return nil, chargeError(err)
+	}
+
+	subscription := toPaymentSubscription(newSubscription)
+	if intent := latestPaymentIntent(newSubscription); intent != nil {
+		switch intent.Status {
+		case stripe.PaymentIntentStatusRequiresAction:
+			subscription.ClientSecret = intent.ClientSecret
+		case stripe.PaymentIntentStatusRequiresPaymentMethod:
+			if _, err := sub.Cancel(newSubscription.ID, nil); err != nil {
+				return nil, fmt.Errorf("failed to cancel declined subscription %s: %v", newSubscription.ID, err)
+			}
+			reason := "your card was declined"
+			if intent.LastPaymentError != nil {
+				reason = intent.LastPaymentError.Msg
+			}
+			return nil, fmt.Errorf("%w: %s", entity.ErrPaymentDeclined, reason)
+		}
+	}
+
+	return subscription, nil
+}
+
+func (store *PaymentStore) GetSubscription(subscriptionId string) (*entity.PaymentSubscription, error) {
+	subscription, err := sub.Get(subscriptionId, nil)
+	if err != nil {
+		return nil, fmt.Errorf("subscription retrival failed: %v", err)
+	}
+	return toPaymentSubscription(subscription), nil
+}
+
+// PauseSubscription stops charging renewals, the invoices of a paused
+// subscription are voided, or charges them again.
+func (store *PaymentStore) PauseSubscription(subscriptionId string, paused bool) (*entity.PaymentSubscription, error) {
+	params := &stripe.SubscriptionParams{}
+	if paused {
+		params.PauseCollection = &stripe.SubscriptionPauseCollectionParams{
+			Behavior: stripe.String("void"),
+		}
+	} else {
+		// an empty value is how Stripe is told to clear the field
+		params.AddExtra("pause_collection", "")
+	}
+
+	updated, err := sub.Update(subscriptionId, params)
+	if err != nil {
+		return nil, fmt.Errorf("subscription update failed: %v", err)
+	}
+	return toPaymentSubscription(updated), nil
+}
+
+// SkipSubscriptionRenewal moves the next renewal to until. The time in between
+// is a free trial, so nothing is charged or prorated for it.
+func (store *PaymentStore) SkipSubscriptionRenewal(subscriptionId string, until time.Time) (*entity.PaymentSubscription, error) {
+	params := &stripe.SubscriptionParams{
+		TrialEnd:          stripe.Int64(until.Unix()),
+		ProrationBehavior: stripe.String("none"),
+	}
+
+	updated, err := sub.Update(subscriptionId, params)
+	if err != nil {
+		return nil, fmt.Errorf("subscription update failed: %v", err)
+	}
+	return toPaymentSubscription(updated), nil
+}
+
+// UpdateSubscriptionQuantity charges quantity units from the next renewal on,
+// without prorating the current one.
+func (store *PaymentStore) UpdateSubscriptionQuantity(subscriptionId string, quantity int) (*entity.PaymentSubscription, error) {
+	current, err := sub.Get(subscriptionId, nil)
+	if err != nil {
+		return nil, fmt.Errorf("subscription retrival failed: %v", err)
+	}
+	if current.Items == nil || len(current.Items.Data) == 0 {
+		return nil, fmt.Errorf("subscription %s has no items", subscriptionId)
+	}
+
+	params := &stripe.SubscriptionParams{
+		Items: []*stripe.SubscriptionItemsParams{
+			{
+				ID:       stripe.String(current.Items.Data[0].ID),
+				Quantity: stripe.Int64(int64(quantity)),
+			},
+		},
+		ProrationBehavior: stripe.String("none"),
+	}
+
+	updated, err := sub.Update(subscriptionId, params)
+	if err != nil {
+		return nil, fmt.Errorf("subscription update failed: %v", err)
+	}
+	return toPaymentSubscription(updated), nil
+}
+
+// CancelSubscription ends the subscription at once, nothing more is charged.
+func (store *PaymentStore) CancelSubscription(subscriptionId string) error {
+	if _, err := sub.Cancel(subscriptionId, nil); err != nil {
+		return fmt.Errorf("subscription cancellation failed: %v", err)
+	}
+	return nil
+}
+
+// stripePlan returns the plan charging the price of a subscription plan every
+// interval. Plans are named after the product, interval and price, so each is
+// created once and shared by everyone paying the same.
+func stripePlan(subscriptionPlan entity.SubscriptionPlan) (string, error) {
+	count := subscriptionPlan.IntervalCount
+	if count < 1 {
+		count = 1
+	}
+	currency := strings.ToLower(subscriptionPlan.UnitPrice.Currency)
+	id := fmt.Sprintf("plan_%s_%d%s_%d%s", subscriptionPlan.ProductID, count, subscriptionPlan.Interval, subscriptionPlan.UnitPrice.Amount, currency)
+
+	params := &stripe.PlanParams{
+		ID:            stripe.String(id),
+		Amount:        stripe.Int64(subscriptionPlan.UnitPrice.Amount),
+		Currency:      stripe.String(currency),
+		Interval:      stripe.String(subscriptionPlan.Interval),
+		IntervalCount: stripe.Int64(int64(count)),
+		Product: &stripe.PlanProductParams{
+			Name: stripe.String(subscriptionPlan.ProductName),
+		},
+	}
+	params.AddMetadata("product_id", subscriptionPlan.ProductID)
+
+	_, err := plan.New(params)
+	if stripeErr, ok := err.(*stripe.Error); ok && stripeErr.Code == stripe.ErrorCodeResourceAlreadyExists {
+		return id, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("plan creation failed: %v", err)
+	}
+	return id, nil
+}
+
+func latestPaymentIntent(subscription *stripe.Subscription) *stripe.PaymentIntent {
+	if subscription.LatestInvoice == nil {
+		return nil
+	}
+	return subscription.LatestInvoice.PaymentIntent
+}
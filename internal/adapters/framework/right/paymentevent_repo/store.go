@@ -0,0 +1,204 @@
+package paymentevent_repo
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"ecom-api/internal/application/core/types/entity"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// SavePaymentEvent stores a received event. The event ID is the primary key,
+// so an event delivered again is not stored twice and false is returned.
+func (s *Store) SavePaymentEvent(event entity.PaymentEvent) (bool, error) {
+	result, err := s.db.Exec("INSERT IGNORE INTO payment_events (id, type, payload, status, nextAttemptAt) VALUES (?,?,?,?,?)",
+		event.ID, event.Type, event.Payload, entity.PaymentEventPending, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("failed to save payment event: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// ClaimPaymentEvents takes up to limit due events, oldest first, and marks
+// them processing until the lease runs out. Rows locked by another worker are
+// skipped, and an event whose worker died before finishing is due again once
+// its lease has passed.
+func (s *Store) ClaimPaymentEvents(limit int, lease time.Duration) ([]*entity.PaymentEvent, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	rows, err := tx.Query("SELECT * FROM payment_events WHERE status IN (?,?,?) AND nextAttemptAt <= ? ORDER BY createdAt LIMIT ? FOR UPDATE SKIP LOCKED",
+		entity.PaymentEventPending, entity.PaymentEventFailed, entity.PaymentEventProcessing, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query due payment events: %w", err)
+	}
+
+	var events []*entity.PaymentEvent
+	for rows.Next() {
+		event, err := scanRowsIntoPaymentEvent(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	leaseEnd := now.Add(lease)
+	for _, event := range events {
+		_, err := tx.Exec("UPDATE payment_events SET status = ?, attempts = attempts + 1, nextAttemptAt = ? WHERE id = ?",
+			entity.PaymentEventProcessing, leaseEnd, event.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim payment event %s: %w", event.ID, err)
+		}
+		event.Status = entity.PaymentEventProcessing
+		event.Attempts++
+		event.NextAttemptAt = leaseEnd
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (s *Store) MarkPaymentEventProcessed(id string) error {
+	_, err := s.db.Exec("UPDATE payment_events SET status = ?, lastError = NULL, processedAt = ? WHERE id = ?",
+		entity.PaymentEventProcessed, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to mark payment event processed: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) MarkPaymentEventFailed(id, lastError string, nextAttemptAt time.Time, dead bool) error {
+	status := entity.PaymentEventFailed
+	if dead {
+		status = entity.PaymentEventDead
+	}
+
+	_, err := s.db.Exec("UPDATE payment_events SET status = ?, lastError = ?, nextAttemptAt = ? WHERE id = ?",
+		status, lastError, nextAttemptAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark payment event failed: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) GetPaymentEventByID(id string) (*entity.PaymentEvent, error) {
+	rows, err := s.db.Query("SELECT * FROM payment_events WHERE id = ?", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query payment event by id: %w", err)
+	}
+	defer rows.Close()
+
+	event := new(entity.PaymentEvent)
+	for rows.Next() {
+		event, err = scanRowsIntoPaymentEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+func (s *Store) GetPaymentEventsByStatus(statuses ...string) ([]*entity.PaymentEvent, error) {
+	if len(statuses) == 0 {
+		return nil, nil
+	}
+
+	args := make([]interface{}, len(statuses))
+	for i, status := range statuses {
+		args[i] = status
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(statuses)), ",")
+	rows, err := s.db.Query("SELECT * FROM payment_events WHERE status IN ("+placeholders+") ORDER BY createdAt DESC", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query payment events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*entity.PaymentEvent
+	for rows.Next() {
+		event, err := scanRowsIntoPaymentEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// ReplayPaymentEvent queues a failed or dead event to be processed right away
+// with a fresh set of attempts.
+func (s *Store) ReplayPaymentEvent(id string) (bool, error) {
+	result, err := s.db.Exec("UPDATE payment_events SET status = ?, attempts = 0, nextAttemptAt = ? WHERE id = ? AND status IN (?,?)",
+		entity.PaymentEventPending, time.Now(), id, entity.PaymentEventFailed, entity.PaymentEventDead)
+	if err != nil {
+		return false, fmt.Errorf("failed to replay payment event: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+func scanRowsIntoPaymentEvent(rows *sql.Rows) (*entity.PaymentEvent, error) {
+	event := new(entity.PaymentEvent)
+	var lastError sql.NullString
+
+	err := rows.Scan(
+		&event.ID,
+		&event.Type,
+		&event.Payload,
+		&event.Status,
+		&event.Attempts,
+		&lastError,
+		&event.NextAttemptAt,
+		&event.ProcessedAt,
+		&event.CreatedAt,
+		&event.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	event.LastError = lastError.String
+
+	return event, nil
+}
@@ -0,0 +1,203 @@
+package payout_repo
+
+import (
+	"database/sql"
+	"fmt"
+
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/utils"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) CreateCommissionRule(rule entity.CommissionRule) (string, error) {
+	rule.ID = utils.GenerateRandomUniqueIdentifier()
+
+	_, err := s.db.Exec("INSERT INTO commission_rules (id, storeId, category, rate, isActive) VALUES (?,?,?,?,?)",
+		rule.ID, nullableString(rule.StoreID), rule.Category, rule.Rate, rule.IsActive)
+	if err != nil {
+		return "", fmt.Errorf("failed to create commission rule: %w", err)
+	}
+
+	return rule.ID, nil
+}
+
+func (s *Store) GetCommissionRules() ([]*entity.CommissionRule, error) {
+	rows, err := s.db.Query("SELECT * FROM commission_rules ORDER BY createdAt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query commission rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []*entity.CommissionRule
+	for rows.Next() {
+		rule, err := scanRowsIntoCommissionRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func (s *Store) DeleteCommissionRule(ruleID string) error {
+	_, err := s.db.Exec("DELETE FROM commission_rules WHERE id = ?", ruleID)
+	if err != nil {
+		return fmt.Errorf("failed to delete commission rule: %w", err)
+	}
+	return nil
+}
+
+// CreateStorePayout records a ledger entry. A store has one entry per order,
+// so recording the payouts of an order again changes nothing and returns false.
+func (s *Store) CreateStorePayout(payout entity.StorePayout) (bool, error) {
+	result, err := s.db.Exec("INSERT IGNORE INTO store_payouts (storeId, orderId, gross, commission, amount, currency, status) VALUES (?,?,?,?,?,?,?)",
+		payout.StoreID, payout.OrderID, payout.Gross.String(), payout.Commission.String(), payout.Amount.String(), payout.Currency, entity.StorePayoutPending)
+	if err != nil {
+		return false, fmt.Errorf("failed to create store payout: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+func (s *Store) GetStorePayoutsByOrderID(orderID string) ([]*entity.StorePayout, error) {
+	return s.getStorePayouts("SELECT * FROM store_payouts WHERE orderId = ? ORDER BY storeId", orderID)
+}
+
+func (s *Store) GetStorePayoutsByStoreID(storeID string) ([]*entity.StorePayout, error) {
+	return s.getStorePayouts("SELECT * FROM store_payouts WHERE storeId = ? ORDER BY createdAt DESC", storeID)
+}
+
+func (s *Store) GetPendingStorePayouts(storeID string) ([]*entity.StorePayout, error) {
+	return s.getStorePayouts("SELECT * FROM store_payouts WHERE storeId = ? AND status = ? ORDER BY createdAt", storeID, entity.StorePayoutPending)
+}
+
+// MarkStorePayoutPaid only moves pending payouts, so a payout is never
+// recorded as paid twice.
+func (s *Store) MarkStorePayoutPaid(payoutID, transferID string) (bool, error) {
+	result, err := s.db.Exec("UPDATE store_payouts SET status = ?, transferId = ? WHERE id = ? AND status = ?",
+		entity.StorePayoutPaid, nullableString(transferID), payoutID, entity.StorePayoutPending)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark store payout paid: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+func (s *Store) MarkStorePayoutReversed(payoutID string) (bool, error) {
+	result, err := s.db.Exec("UPDATE store_payouts SET status = ? WHERE id = ? AND status <> ?",
+		entity.StorePayoutReversed, payoutID, entity.StorePayoutReversed)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark store payout reversed: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+func (s *Store) getStorePayouts(query string, args ...interface{}) ([]*entity.StorePayout, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query store payouts: %w", err)
+	}
+	defer rows.Close()
+
+	var payouts []*entity.StorePayout
+	for rows.Next() {
+		payout, err := scanRowsIntoStorePayout(rows)
+		if err != nil {
+			return nil, err
+		}
+		payouts = append(payouts, payout)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return payouts, nil
+}
+
+func scanRowsIntoCommissionRule(rows *sql.Rows) (*entity.CommissionRule, error) {
+	rule := new(entity.CommissionRule)
+	var storeID sql.NullString
+
+	err := rows.Scan(
+		&rule.ID,
+		&storeID,
+		&rule.Category,
+		&rule.Rate,
+		&rule.IsActive,
+		&rule.CreatedAt,
+		&rule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.StoreID = storeID.String
+
+	return rule, nil
+}
+
+func scanRowsIntoStorePayout(rows *sql.Rows) (*entity.StorePayout, error) {
+	payout := new(entity.StorePayout)
+	var gross, commission, amount string
+	var transferID sql.NullString
+
+	err := rows.Scan(
+		&payout.ID,
+		&payout.StoreID,
+		&payout.OrderID,
+		&gross,
+		&commission,
+		&amount,
+		&payout.Currency,
+		&payout.Status,
+		&transferID,
+		&payout.CreatedAt,
+		&payout.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, column := range []struct {
+		value string
+		field *entity.Money
+	}{{gross, &payout.Gross}, {commission, &payout.Commission}, {amount, &payout.Amount}} {
+		if *column.field, err = entity.ParseMoney(column.value, payout.Currency); err != nil {
+			return nil, err
+		}
+	}
+	payout.TransferID = transferID.String
+
+	return payout, nil
+}
+
+func nullableString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
@@ -0,0 +1,184 @@
+package pricing_repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"ecom-api/internal/application/core/pricing"
+	"ecom-api/internal/application/core/types/entity"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) SetProductPrice(price entity.ProductPrice) error {
+	_, err := s.db.Exec("INSERT INTO productprices (productId, currency, price) VALUES (?,?,?) ON DUPLICATE KEY UPDATE price = VALUES(price)",
+		price.ProductID, price.Price.Currency, price.Price.String())
+	if err != nil {
+		return fmt.Errorf("failed to set product price: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) GetProductPrices(productID string) ([]*entity.ProductPrice, error) {
+	return s.getProductPrices("SELECT * FROM productprices WHERE productId = ? ORDER BY currency", productID)
+}
+
+func (s *Store) GetPricesByProductIDs(productIDs []string) (map[string][]entity.Money, error) {
+	prices := make(map[string][]entity.Money)
+	if len(productIDs) == 0 {
+		return prices, nil
+	}
+
+	placeholders := make([]string, len(productIDs))
+	args := make([]interface{}, len(productIDs))
+	for i, id := range productIDs {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+
+	entries, err := s.getProductPrices(fmt.Sprintf("SELECT * FROM productprices WHERE productId IN (%s)", strings.Join(placeholders, ",")), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		prices[entry.ProductID] = append(prices[entry.ProductID], entry.Price)
+	}
+
+	return prices, nil
+}
+
+func (s *Store) DeleteProductPrice(productID, currency string) error {
+	result, err := s.db.Exec("DELETE FROM productprices WHERE productId = ? AND currency = ?", productID, strings.ToUpper(currency))
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(result, "no price found for the given product and currency")
+}
+
+func (s *Store) SetExchangeRate(rate entity.ExchangeRate) error {
+	_, err := s.db.Exec("INSERT INTO exchangerates (base, quote, rate) VALUES (?,?,?) ON DUPLICATE KEY UPDATE rate = VALUES(rate)",
+		strings.ToUpper(rate.Base), strings.ToUpper(rate.Quote), rate.Rate)
+	if err != nil {
+		return fmt.Errorf("failed to set exchange rate: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) GetAllExchangeRates() ([]*entity.ExchangeRate, error) {
+	rows, err := s.db.Query("SELECT * FROM exchangerates ORDER BY base, quote")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []*entity.ExchangeRate{}
+	for rows.Next() {
+		rate := new(entity.ExchangeRate)
+		if err := rows.Scan(&rate.Base, &rate.Quote, &rate.Rate, &rate.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rates, nil
+}
+
+func (s *Store) DeleteExchangeRate(base, quote string) error {
+	result, err := s.db.Exec("DELETE FROM exchangerates WHERE base = ? AND quote = ?", strings.ToUpper(base), strings.ToUpper(quote))
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(result, "no exchange rate found for the given currencies")
+}
+
+// Rate implements rports.ExchangeRateProvider with the rates of the table. A
+// pair without a row is derived from the inverse pair when that one is set.
+func (s *Store) Rate(base, quote string) (float64, error) {
+	rates, err := s.GetAllExchangeRates()
+	if err != nil {
+		return 0, err
+	}
+
+	table := pricing.RateTable{}
+	for _, rate := range rates {
+		table.Set(rate.Base, rate.Quote, rate.Rate)
+	}
+
+	return table.Rate(base, quote)
+}
+
+func (s *Store) getProductPrices(query string, args ...interface{}) ([]*entity.ProductPrice, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := []*entity.ProductPrice{}
+	for rows.Next() {
+		price, err := scanRowsIntoProductPrice(rows)
+		if err != nil {
+			return nil, err
+		}
+		prices = append(prices, price)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return prices, nil
+}
+
+func scanRowsIntoProductPrice(rows *sql.Rows) (*entity.ProductPrice, error) {
+	price := new(entity.ProductPrice)
+	var currency, amount string
+
+	err := rows.Scan(
+		&price.ProductID,
+		&currency,
+		&amount,
+		&price.CreatedAt,
+		&price.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	price.Price, err = entity.ParseMoney(amount, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	return price, nil
+}
+
+func checkRowsAffected(result sql.Result, message string) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New(message)
+	}
+
+	return nil
+}
@@ -23,7 +23,11 @@ func (s *Store) CreateProduct(product payloads.CreateProductPayload) error {
 	if errr != nil {
 		return errr
 	}
-	_, err := s.db.Exec("INSERT INTO products(name, description, image, price, currency, quantity, category, tags, isActive) VALUES (?,?,?,?,?,?,?,?,?)", product.Name, product.Description, product.Image, product.Price, product.Currency, product.Quantity, product.Category, tagsJSON, product.IsActive)
+	// renewing every interval is renewing every one of them
+	if product.BillingIntervalCount == 0 {
+		product.BillingIntervalCount = 1
+	}
+	_, err := s.db.Exec("INSERT INTO products(name, description, image, price, currency, quantity, category, tags, isActive, taxCategory, weight, length, width, height, storeId, isSubscribable, billingInterval, billingIntervalCount) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)", product.Name, product.Description, product.Image, entity.MoneyFromMajor(product.Price, product.Currency).String(), product.Currency, product.Quantity, product.Category, tagsJSON, product.IsActive, product.TaxCategory, product.Weight, product.Length, product.Width, product.Height, nullableString(product.StoreID), product.IsSubscribable, product.BillingInterval, product.BillingIntervalCount)
 	if err != nil {
 		return err
 	}
@@ -122,7 +126,7 @@ func (s *Store) GetAllProducts() ([]*entity.Product, error) {
 }
 
 func (s *Store) UpdateProduct(product entity.Product) error {
-	_, err := s.db.Exec("UPDATE products SET name = ?, description = ?, image = ?, price = ?, currency = ?, quantity = ?, category = ?, tags = ?, isActive = ?, updatedAt = CURRENT_TIMESTAMP WHERE productId = ?", product.Name, product.Description, product.Image, product.Price, product.Currency, product.Quantity, product.Category, product.Tags, product.IsActive, product.ProductId)
+	_, err := s.db.Exec("UPDATE products SET name = ?, description = ?, image = ?, price = ?, currency = ?, quantity = ?, category = ?, tags = ?, isActive = ?, taxCategory = ?, weight = ?, length = ?, width = ?, height = ?, isSubscribable = ?, billingInterval = ?, billingIntervalCount = ?, updatedAt = CURRENT_TIMESTAMP WHERE productId = ?", product.Name, product.Description, product.Image, product.Price.String(), product.Currency, product.Quantity, product.Category, product.Tags, product.IsActive, product.TaxCategory, product.Weight, product.Length, product.Width, product.Height, product.IsSubscribable, product.BillingInterval, product.BillingIntervalCount, product.ProductId)
 
 	if err != nil {
 		return err
@@ -215,6 +219,52 @@ func (s *Store) DecreaseProductStock(id string, quantity int) error {
 	return nil
 }
 
+// TakeStock takes the quantities, keyed by product ID, out of stock in one
+// transaction. Each product is only decremented while it has enough left, so
+// concurrent checkouts cannot oversell it, and when any product runs short
+// nothing is taken.
+func (s *Store) TakeStock(quantities map[string]int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for id, quantity := range quantities {
+		result, err := tx.Exec("UPDATE products SET quantity = quantity - ? WHERE productId = ? AND quantity >= ?", quantity, id, quantity)
+		if err != nil {
+			return fmt.Errorf("failed to take stock of product %s: %w", id, err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return fmt.Errorf("not enough stock of product %s", id)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ReturnStock puts the quantities, keyed by product ID, back in stock in one
+// transaction.
+func (s *Store) ReturnStock(quantities map[string]int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for id, quantity := range quantities {
+		if _, err := tx.Exec("UPDATE products SET quantity = quantity + ? WHERE productId = ?", quantity, id); err != nil {
+			return fmt.Errorf("failed to return stock of product %s: %w", id, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
 // GetProductsByCategory implements rports.ProductStore.
 func (s *Store) GetProductsByCategory(category string) ([]*entity.Product, error) {
 	rows, err := s.db.Query("SELECT * FROM products WHERE category =?", category)
@@ -384,12 +434,14 @@ func tagsContain(tagsData []byte, query string) bool {
 
 func scanRowsIntoProduct(rows *sql.Rows) (*entity.Product, error) {
 	product := new(entity.Product)
+	var price string
+	var storeID sql.NullString
 	err := rows.Scan(
 		&product.ProductId,
 		&product.Name,
 		&product.Description,
 		&product.Image,
-		&product.Price,
+		&price,
 		&product.Currency,
 		&product.Quantity,
 		&product.Category,
@@ -397,11 +449,27 @@ func scanRowsIntoProduct(rows *sql.Rows) (*entity.Product, error) {
 		&product.IsActive,
 		&product.CreatedAt,
 		&product.UpdatedAt,
+		&product.TaxCategory,
+		&product.Weight,
+		&product.Length,
+		&product.Width,
+		&product.Height,
+		&storeID,
+		&product.IsSubscribable,
+		&product.BillingInterval,
+		&product.BillingIntervalCount,
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	product.StoreID = storeID.String
+
+	product.Price, err = entity.ParseMoney(price, product.Currency)
+	if err != nil {
+		return nil, err
+	}
+
 	// If the Tags field is a valid JSON array in the database, unmarshal it
 	// if len(product.Tags) > 0 {
 	// 	if err = json.Unmarshal(product.Tags, &tags); err != nil {
@@ -411,3 +479,7 @@ func scanRowsIntoProduct(rows *sql.Rows) (*entity.Product, error) {
 
 	return product, err
 }
+
+func nullableString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
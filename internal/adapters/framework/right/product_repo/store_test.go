@@ -0,0 +1,65 @@
+package product_repo
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTakeStock(t *testing.T) {
+	const take = "UPDATE products SET quantity = quantity - ? WHERE productId = ? AND quantity >= ?"
+
+	t.Run("stock is taken only while there is enough of it", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error opening mock database %v", err)
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(take)).
+			WithArgs(3, "product-1", 3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		assert.NoError(t, NewStore(db).TakeStock(map[string]int{"product-1": 3}))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a product running short takes nothing", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error opening mock database %v", err)
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(take)).
+			WithArgs(5, "product-1", 5).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err = NewStore(db).TakeStock(map[string]int{"product-1": 5})
+		assert.EqualError(t, err, "not enough stock of product product-1")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestReturnStock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error opening mock database %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE products SET quantity = quantity + ? WHERE productId = ?")).
+		WithArgs(2, "product-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, NewStore(db).ReturnStock(map[string]int{"product-1": 2}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
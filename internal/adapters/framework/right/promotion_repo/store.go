@@ -0,0 +1,258 @@
+package promotion_repo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/utils"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) CreatePromotion(promotion entity.Promotion) (string, error) {
+	promotion.ID = utils.GenerateRandomUniqueIdentifier()
+
+	productIds, categories, err := marshalScope(promotion)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s.db.Exec("INSERT INTO promotions (id, code, name, type, value, amount, currency, buyQuantity, getQuantity, productIds, categories, minSpend, startsAt, endsAt, usageLimit, perUserLimit, isActive) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)",
+		promotion.ID, nullableString(normalizeCode(promotion.Code)), promotion.Name, promotion.Type, promotion.Value, promotion.Amount.String(), promotion.Currency,
+		promotion.BuyQuantity, promotion.GetQuantity, productIds, categories, promotion.MinSpend.String(),
+		promotion.StartsAt, promotion.EndsAt, promotion.UsageLimit, promotion.PerUserLimit, promotion.IsActive)
+	if err != nil {
+		return "", fmt.Errorf("failed to create promotion: %w", err)
+	}
+
+	return promotion.ID, nil
+}
+
+func (s *Store) GetPromotionByID(id string) (*entity.Promotion, error) {
+	return s.getPromotion("SELECT * FROM promotions WHERE id = ?", id)
+}
+
+func (s *Store) GetPromotionByCode(code string) (*entity.Promotion, error) {
+	return s.getPromotion("SELECT * FROM promotions WHERE code = ?", normalizeCode(code))
+}
+
+func (s *Store) GetAutomaticPromotions() ([]*entity.Promotion, error) {
+	return s.getPromotions("SELECT * FROM promotions WHERE code IS NULL AND isActive = TRUE ORDER BY createdAt")
+}
+
+func (s *Store) GetAllPromotions() ([]*entity.Promotion, error) {
+	return s.getPromotions("SELECT * FROM promotions ORDER BY createdAt")
+}
+
+func (s *Store) UpdatePromotion(promotion entity.Promotion) error {
+	productIds, categories, err := marshalScope(promotion)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec("UPDATE promotions SET code = ?, name = ?, type = ?, value = ?, amount = ?, currency = ?, buyQuantity = ?, getQuantity = ?, productIds = ?, categories = ?, minSpend = ?, startsAt = ?, endsAt = ?, usageLimit = ?, perUserLimit = ?, isActive = ? WHERE id = ?",
+		nullableString(normalizeCode(promotion.Code)), promotion.Name, promotion.Type, promotion.Value, promotion.Amount.String(), promotion.Currency,
+		promotion.BuyQuantity, promotion.GetQuantity, productIds, categories, promotion.MinSpend.String(),
+		promotion.StartsAt, promotion.EndsAt, promotion.UsageLimit, promotion.PerUserLimit, promotion.IsActive, promotion.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update promotion: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) SetPromotionActive(id string, isActive bool) error {
+	result, err := s.db.Exec("UPDATE promotions SET isActive = ? WHERE id = ?", isActive, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("no promotion found with the given ID")
+	}
+
+	return nil
+}
+
+// ReservePromotionUsage increments the usage counter only while it is below
+// the limit, so two checkouts racing for the last redemption cannot both win.
+func (s *Store) ReservePromotionUsage(id string) error {
+	result, err := s.db.Exec("UPDATE promotions SET usageCount = usageCount + 1 WHERE id = ? AND (usageLimit = 0 OR usageCount < usageLimit)", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("promotion has been fully redeemed")
+	}
+
+	return nil
+}
+
+func (s *Store) ReleasePromotionUsage(id string) error {
+	_, err := s.db.Exec("UPDATE promotions SET usageCount = usageCount - 1 WHERE id = ? AND usageCount > 0", id)
+	return err
+}
+
+func (s *Store) CountRedemptions(promotionID, userID, guestEmail string) (int, error) {
+	var count int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM promotionredemptions WHERE promotionId = ? AND ((userId IS NOT NULL AND userId = ?) OR (guestEmail IS NOT NULL AND guestEmail = ?))",
+		promotionID, userID, guestEmail).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count redemptions: %w", err)
+	}
+
+	return count, nil
+}
+
+func (s *Store) CreateRedemption(redemption entity.PromotionRedemption) error {
+	_, err := s.db.Exec("INSERT INTO promotionredemptions (id, promotionId, orderId, userId, guestEmail, code, discount) VALUES (?,?,?,?,?,?,?)",
+		utils.GenerateRandomUniqueIdentifier(), redemption.PromotionID, redemption.OrderID,
+		nullableString(redemption.UserID), nullableString(redemption.GuestEmail), redemption.Code, redemption.Discount.String())
+	if err != nil {
+		return fmt.Errorf("failed to record redemption: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) getPromotion(query string, arg string) (*entity.Promotion, error) {
+	promotions, err := s.getPromotions(query, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(promotions) == 0 {
+		return nil, errors.New("promotion not found")
+	}
+
+	return promotions[0], nil
+}
+
+func (s *Store) getPromotions(query string, args ...interface{}) ([]*entity.Promotion, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query promotions: %w", err)
+	}
+	defer rows.Close()
+
+	promotions := make([]*entity.Promotion, 0)
+	for rows.Next() {
+		promotion, err := scanRowsIntoPromotion(rows)
+		if err != nil {
+			return nil, err
+		}
+		promotions = append(promotions, promotion)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return promotions, nil
+}
+
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func nullableString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+func marshalScope(promotion entity.Promotion) ([]byte, []byte, error) {
+	productIds, err := json.Marshal(promotion.ProductIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	categories, err := json.Marshal(promotion.Categories)
+	if err != nil {
+		return nil, nil, err
+	}
+	return productIds, categories, nil
+}
+
+func scanRowsIntoPromotion(rows *sql.Rows) (*entity.Promotion, error) {
+	promotion := new(entity.Promotion)
+	var code sql.NullString
+	var productIds, categories []byte
+	var amount, minSpend string
+	var startsAt, endsAt sql.NullTime
+
+	err := rows.Scan(
+		&promotion.ID,
+		&code,
+		&promotion.Name,
+		&promotion.Type,
+		&promotion.Value,
+		&amount,
+		&promotion.Currency,
+		&promotion.BuyQuantity,
+		&promotion.GetQuantity,
+		&productIds,
+		&categories,
+		&minSpend,
+		&startsAt,
+		&endsAt,
+		&promotion.UsageLimit,
+		&promotion.PerUserLimit,
+		&promotion.UsageCount,
+		&promotion.IsActive,
+		&promotion.CreatedAt,
+		&promotion.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if promotion.Amount, err = entity.ParseMoney(amount, promotion.Currency); err != nil {
+		return nil, err
+	}
+	if promotion.MinSpend, err = entity.ParseMoney(minSpend, promotion.Currency); err != nil {
+		return nil, err
+	}
+
+	promotion.Code = code.String
+	promotion.StartsAt = nullableTime(startsAt)
+	promotion.EndsAt = nullableTime(endsAt)
+
+	if len(productIds) > 0 {
+		if err := json.Unmarshal(productIds, &promotion.ProductIDs); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal product ids: %w", err)
+		}
+	}
+	if len(categories) > 0 {
+		if err := json.Unmarshal(categories, &promotion.Categories); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal categories: %w", err)
+		}
+	}
+
+	return promotion, nil
+}
+
+func nullableTime(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Time
+}
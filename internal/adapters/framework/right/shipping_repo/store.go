@@ -0,0 +1,309 @@
+package shipping_repo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/utils"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) CreateShippingZone(zone entity.ShippingZone) (string, error) {
+	zone.ID = utils.GenerateRandomUniqueIdentifier()
+
+	countries, regions, err := marshalDestinations(zone)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s.db.Exec("INSERT INTO shippingzones (id, name, countries, regions, isActive) VALUES (?,?,?,?,?)", zone.ID, zone.Name, countries, regions, zone.IsActive)
+	if err != nil {
+		return "", fmt.Errorf("failed to create shipping zone: %w", err)
+	}
+
+	return zone.ID, nil
+}
+
+func (s *Store) GetShippingZoneByID(id string) (*entity.ShippingZone, error) {
+	zones, err := s.getShippingZones("SELECT * FROM shippingzones WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(zones) == 0 {
+		return nil, fmt.Errorf("shipping zone %s not found", id)
+	}
+	return zones[0], nil
+}
+
+func (s *Store) GetAllShippingZones() ([]*entity.ShippingZone, error) {
+	return s.getShippingZones("SELECT * FROM shippingzones ORDER BY createdAt")
+}
+
+func (s *Store) UpdateShippingZone(zone entity.ShippingZone) error {
+	countries, regions, err := marshalDestinations(zone)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.Exec("UPDATE shippingzones SET name = ?, countries = ?, regions = ?, isActive = ? WHERE id = ?", zone.Name, countries, regions, zone.IsActive, zone.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update shipping zone: %w", err)
+	}
+
+	return checkRowsAffected(result, "no shipping zone found with the given ID")
+}
+
+func (s *Store) DeleteShippingZone(id string) error {
+	result, err := s.db.Exec("DELETE FROM shippingzones WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(result, "no shipping zone found with the given ID")
+}
+
+func (s *Store) CreateShippingMethod(method entity.ShippingMethod) (string, error) {
+	method.ID = utils.GenerateRandomUniqueIdentifier()
+
+	tiers, err := marshalTiers(method.Tiers)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s.db.Exec("INSERT INTO shippingmethods (id, zoneId, name, rateType, flatRate, tiers, freeAbove, volumetricDivisor, currency, minDeliveryDays, maxDeliveryDays, isActive) VALUES (?,?,?,?,?,?,?,?,?,?,?,?)",
+		method.ID, method.ZoneID, method.Name, method.RateType, method.FlatRate.String(), tiers, method.FreeAbove.String(), method.VolumetricDivisor,
+		method.Currency, method.MinDeliveryDays, method.MaxDeliveryDays, method.IsActive)
+	if err != nil {
+		return "", fmt.Errorf("failed to create shipping method: %w", err)
+	}
+
+	return method.ID, nil
+}
+
+func (s *Store) GetShippingMethodByID(id string) (*entity.ShippingMethod, error) {
+	methods, err := s.getShippingMethods("SELECT * FROM shippingmethods WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("shipping method %s not found", id)
+	}
+	return methods[0], nil
+}
+
+func (s *Store) GetShippingMethodsByZoneID(zoneID string) ([]*entity.ShippingMethod, error) {
+	return s.getShippingMethods("SELECT * FROM shippingmethods WHERE zoneId = ? ORDER BY createdAt", zoneID)
+}
+
+func (s *Store) UpdateShippingMethod(method entity.ShippingMethod) error {
+	tiers, err := marshalTiers(method.Tiers)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.Exec("UPDATE shippingmethods SET name = ?, rateType = ?, flatRate = ?, tiers = ?, freeAbove = ?, volumetricDivisor = ?, currency = ?, minDeliveryDays = ?, maxDeliveryDays = ?, isActive = ? WHERE id = ?",
+		method.Name, method.RateType, method.FlatRate.String(), tiers, method.FreeAbove.String(), method.VolumetricDivisor,
+		method.Currency, method.MinDeliveryDays, method.MaxDeliveryDays, method.IsActive, method.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update shipping method: %w", err)
+	}
+
+	return checkRowsAffected(result, "no shipping method found with the given ID")
+}
+
+func (s *Store) DeleteShippingMethod(id string) error {
+	result, err := s.db.Exec("DELETE FROM shippingmethods WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(result, "no shipping method found with the given ID")
+}
+
+func (s *Store) getShippingZones(query string, args ...interface{}) ([]*entity.ShippingZone, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	zones := []*entity.ShippingZone{}
+	for rows.Next() {
+		zone, err := scanRowsIntoShippingZone(rows)
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, zone)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return zones, nil
+}
+
+func (s *Store) getShippingMethods(query string, args ...interface{}) ([]*entity.ShippingMethod, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	methods := []*entity.ShippingMethod{}
+	for rows.Next() {
+		method, err := scanRowsIntoShippingMethod(rows)
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, method)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return methods, nil
+}
+
+func checkRowsAffected(result sql.Result, message string) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New(message)
+	}
+
+	return nil
+}
+
+func marshalDestinations(zone entity.ShippingZone) ([]byte, []byte, error) {
+	countries, err := json.Marshal(zone.Countries)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(zone.Regions) == 0 {
+		return countries, nil, nil
+	}
+	regions, err := json.Marshal(zone.Regions)
+	if err != nil {
+		return nil, nil, err
+	}
+	return countries, regions, nil
+}
+
+func scanRowsIntoShippingZone(rows *sql.Rows) (*entity.ShippingZone, error) {
+	zone := new(entity.ShippingZone)
+	var countries, regions []byte
+
+	err := rows.Scan(
+		&zone.ID,
+		&zone.Name,
+		&countries,
+		&regions,
+		&zone.IsActive,
+		&zone.CreatedAt,
+		&zone.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(countries, &zone.Countries); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal zone countries: %w", err)
+	}
+	if len(regions) > 0 {
+		if err := json.Unmarshal(regions, &zone.Regions); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal zone regions: %w", err)
+		}
+	}
+
+	return zone, nil
+}
+
+func scanRowsIntoShippingMethod(rows *sql.Rows) (*entity.ShippingMethod, error) {
+	method := new(entity.ShippingMethod)
+	var flatRate, freeAbove string
+	var tiers []byte
+
+	err := rows.Scan(
+		&method.ID,
+		&method.ZoneID,
+		&method.Name,
+		&method.RateType,
+		&flatRate,
+		&tiers,
+		&freeAbove,
+		&method.VolumetricDivisor,
+		&method.Currency,
+		&method.MinDeliveryDays,
+		&method.MaxDeliveryDays,
+		&method.IsActive,
+		&method.CreatedAt,
+		&method.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if method.FlatRate, err = entity.ParseMoney(flatRate, method.Currency); err != nil {
+		return nil, err
+	}
+	if method.FreeAbove, err = entity.ParseMoney(freeAbove, method.Currency); err != nil {
+		return nil, err
+	}
+	if method.Tiers, err = unmarshalTiers(tiers, method.Currency); err != nil {
+		return nil, err
+	}
+
+	return method, nil
+}
+
+// storedTier is a rate tier as kept in the tiers column, with the cost as a
+// decimal in major units like the other amount columns.
+type storedTier struct {
+	Min  float64     `json:"min"`
+	Max  float64     `json:"max"`
+	Cost json.Number `json:"cost"`
+}
+
+func marshalTiers(tiers []entity.ShippingRateTier) ([]byte, error) {
+	stored := make([]storedTier, len(tiers))
+	for i, tier := range tiers {
+		stored[i] = storedTier{Min: tier.Min, Max: tier.Max, Cost: json.Number(tier.Cost.String())}
+	}
+	return json.Marshal(stored)
+}
+
+func unmarshalTiers(data []byte, currency string) ([]entity.ShippingRateTier, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var stored []storedTier
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal rate tiers: %w", err)
+	}
+
+	tiers := make([]entity.ShippingRateTier, len(stored))
+	for i, tier := range stored {
+		cost, err := entity.ParseMoney(tier.Cost.String(), currency)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cost in rate tier: %w", err)
+		}
+		tiers[i] = entity.ShippingRateTier{Min: tier.Min, Max: tier.Max, Cost: cost}
+	}
+	return tiers, nil
+}
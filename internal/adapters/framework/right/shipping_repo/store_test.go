@@ -0,0 +1,39 @@
+package shipping_repo
+
+import (
+	"testing"
+
+	"ecom-api/internal/application/core/types/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateTiers(t *testing.T) {
+	t.Run("costs are stored as decimals and read back in minor units", func(t *testing.T) {
+		tiers := []entity.ShippingRateTier{
+			{Min: 0, Max: 5, Cost: entity.NewMoney(499, "USD")},
+			{Min: 5, Cost: entity.NewMoney(900, "USD")},
+		}
+
+		data, err := marshalTiers(tiers)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `[{"min":0,"max":5,"cost":4.99},{"min":5,"max":0,"cost":9.00}]`, string(data))
+
+		read, err := unmarshalTiers(data, "USD")
+		assert.NoError(t, err)
+		assert.Equal(t, tiers, read)
+	})
+
+	t.Run("tables written with float costs still parse", func(t *testing.T) {
+		read, err := unmarshalTiers([]byte(`[{"min":0,"max":1000,"cost":1500}]`), "JPY")
+
+		assert.NoError(t, err)
+		assert.Equal(t, entity.NewMoney(1500, "JPY"), read[0].Cost)
+	})
+
+	t.Run("costs finer than the currency are rejected", func(t *testing.T) {
+		_, err := unmarshalTiers([]byte(`[{"min":0,"cost":4.999}]`), "USD")
+
+		assert.Error(t, err)
+	})
+}
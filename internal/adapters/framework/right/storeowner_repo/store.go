@@ -0,0 +1,93 @@
+package storeowner_repo
+
+import (
+	"database/sql"
+	"fmt"
+
+	"ecom-api/internal/application/core/types/entity"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) GetStoreOwnerByID(storeID string) (*entity.StoreOwner, error) {
+	return s.getStoreOwner("SELECT * FROM storeowners WHERE storeId = ?", storeID)
+}
+
+func (s *Store) GetStoreOwnerByEmail(email string) (*entity.StoreOwner, error) {
+	return s.getStoreOwner("SELECT * FROM storeowners WHERE email = ?", email)
+}
+
+// SetStoreStripeAccount records the connected account of a store. The account
+// is unique, so two stores cannot be paid out to the same account.
+func (s *Store) SetStoreStripeAccount(storeID, accountID string) (bool, error) {
+	result, err := s.db.Exec("UPDATE storeowners SET stripeAccountId = ? WHERE storeId = ?", accountID, storeID)
+	if err != nil {
+		return false, fmt.Errorf("failed to set stripe account of store: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 1 {
+		return true, nil
+	}
+
+	// an update to the same account affects no row either
+	store, err := s.GetStoreOwnerByID(storeID)
+	if err != nil {
+		return false, err
+	}
+	return store.StoreID != "", nil
+}
+
+func (s *Store) getStoreOwner(query string, arg string) (*entity.StoreOwner, error) {
+	rows, err := s.db.Query(query, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query store owner: %w", err)
+	}
+	defer rows.Close()
+
+	store := new(entity.StoreOwner)
+	for rows.Next() {
+		store, err = scanRowsIntoStoreOwner(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+func scanRowsIntoStoreOwner(rows *sql.Rows) (*entity.StoreOwner, error) {
+	store := new(entity.StoreOwner)
+	var phone, stripeAccountID sql.NullString
+
+	err := rows.Scan(
+		&store.StoreID,
+		&store.Name,
+		&store.OwnerName,
+		&store.Email,
+		&phone,
+		&store.CreatedAt,
+		&store.UpdatedAt,
+		&stripeAccountID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	store.Phone = phone.String
+	store.StripeAccountID = stripeAccountID.String
+
+	return store, nil
+}
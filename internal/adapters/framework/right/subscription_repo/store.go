@@ -0,0 +1,149 @@
+package subscription_repo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/utils"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) CreateSubscription(subscription entity.Subscription) (string, error) {
+	subscription.ID = utils.GenerateRandomUniqueIdentifier()
+
+	shippingAddress, err := json.Marshal(subscription.ShippingAddress)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s.db.Exec("INSERT INTO subscriptions (id, userId, productId, storeId, category, productName, quantity, unitPrice, currency, billingInterval, billingIntervalCount, status, paymentSubscriptionId, shippingAddress, renewsAt) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)",
+		subscription.ID, subscription.UserID, subscription.ProductID, nullableString(subscription.StoreID), subscription.Category, subscription.ProductName, subscription.Quantity, subscription.UnitPrice.String(), subscription.Currency, subscription.Interval, subscription.IntervalCount, subscription.Status, nullableString(subscription.PaymentSubscriptionID), shippingAddress, subscription.RenewsAt)
+	if err != nil {
+		return "", fmt.Errorf("failed to create subscription: %w", err)
+	}
+
+	return subscription.ID, nil
+}
+
+func (s *Store) GetSubscriptionByID(subscriptionID string) (*entity.Subscription, error) {
+	return s.getSubscription("SELECT * FROM subscriptions WHERE id = ?", subscriptionID)
+}
+
+func (s *Store) GetSubscriptionByPaymentID(paymentSubscriptionID string) (*entity.Subscription, error) {
+	return s.getSubscription("SELECT * FROM subscriptions WHERE paymentSubscriptionId = ?", paymentSubscriptionID)
+}
+
+func (s *Store) GetSubscriptionsByUserID(userID string) ([]*entity.Subscription, error) {
+	return s.getSubscriptions("SELECT * FROM subscriptions WHERE userId = ? ORDER BY createdAt DESC", userID)
+}
+
+func (s *Store) UpdateSubscription(subscription entity.Subscription) error {
+	_, err := s.db.Exec("UPDATE subscriptions SET quantity = ?, status = ?, paymentSubscriptionId = ?, renewsAt = ?, updatedAt = NOW() WHERE id = ?",
+		subscription.Quantity, subscription.Status, nullableString(subscription.PaymentSubscriptionID), subscription.RenewsAt, subscription.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update subscription: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) getSubscription(query string, arg string) (*entity.Subscription, error) {
+	subscriptions, err := s.getSubscriptions(query, arg)
+	if err != nil {
+		return nil, err
+	}
+	if len(subscriptions) == 0 {
+		return new(entity.Subscription), nil
+	}
+	return subscriptions[0], nil
+}
+
+func (s *Store) getSubscriptions(query string, arg string) ([]*entity.Subscription, error) {
+	rows, err := s.db.Query(query, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subscriptions []*entity.Subscription
+	for rows.Next() {
+		subscription, err := scanRowsIntoSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+func scanRowsIntoSubscription(rows *sql.Rows) (*entity.Subscription, error) {
+	subscription := new(entity.Subscription)
+	var storeID, paymentSubscriptionID sql.NullString
+	var renewsAt sql.NullTime
+	var unitPrice string
+	var shippingAddress []byte
+
+	err := rows.Scan(
+		&subscription.ID,
+		&subscription.UserID,
+		&subscription.ProductID,
+		&storeID,
+		&subscription.Category,
+		&subscription.ProductName,
+		&subscription.Quantity,
+		&unitPrice,
+		&subscription.Currency,
+		&subscription.Interval,
+		&subscription.IntervalCount,
+		&subscription.Status,
+		&paymentSubscriptionID,
+		&shippingAddress,
+		&renewsAt,
+		&subscription.CreatedAt,
+		&subscription.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription.StoreID = storeID.String
+	subscription.PaymentSubscriptionID = paymentSubscriptionID.String
+	subscription.RenewsAt = nullableTime(renewsAt)
+
+	subscription.UnitPrice, err = entity.ParseMoney(unitPrice, subscription.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(shippingAddress) > 0 {
+		if err := json.Unmarshal(shippingAddress, &subscription.ShippingAddress); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal shipping address: %w", err)
+		}
+	}
+
+	return subscription, nil
+}
+
+func nullableString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+func nullableTime(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Time
+}
@@ -0,0 +1,142 @@
+package tax_repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"ecom-api/internal/application/core/tax"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/pkg/configs"
+	"ecom-api/utils"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) CreateTaxRate(rate entity.TaxRate) (string, error) {
+	rate.ID = utils.GenerateRandomUniqueIdentifier()
+
+	_, err := s.db.Exec("INSERT INTO taxrates (id, name, country, region, taxCategory, rate, isActive) VALUES (?,?,?,?,?,?,?)",
+		rate.ID, rate.Name, rate.Country, rate.Region, rate.TaxCategory, rate.Rate, rate.IsActive)
+	if err != nil {
+		return "", fmt.Errorf("failed to create tax rate: %w", err)
+	}
+
+	return rate.ID, nil
+}
+
+func (s *Store) GetTaxRateByID(id string) (*entity.TaxRate, error) {
+	rates, err := s.getTaxRates("SELECT * FROM taxrates WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("tax rate %s not found", id)
+	}
+	return rates[0], nil
+}
+
+func (s *Store) GetAllTaxRates() ([]*entity.TaxRate, error) {
+	return s.getTaxRates("SELECT * FROM taxrates ORDER BY country, region, taxCategory, name")
+}
+
+func (s *Store) GetTaxRatesByCountry(country string) ([]*entity.TaxRate, error) {
+	return s.getTaxRates("SELECT * FROM taxrates WHERE country = ? AND isActive = TRUE ORDER BY name, createdAt", country)
+}
+
+func (s *Store) UpdateTaxRate(rate entity.TaxRate) error {
+	result, err := s.db.Exec("UPDATE taxrates SET name = ?, country = ?, region = ?, taxCategory = ?, rate = ?, isActive = ? WHERE id = ?",
+		rate.Name, rate.Country, rate.Region, rate.TaxCategory, rate.Rate, rate.IsActive, rate.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update tax rate: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("no tax rate found with the given ID")
+	}
+
+	return nil
+}
+
+func (s *Store) DeleteTaxRate(id string) error {
+	result, err := s.db.Exec("DELETE FROM taxrates WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("no tax rate found with the given ID")
+	}
+
+	return nil
+}
+
+// CalculateTax implements rports.TaxCalculator with the rates of the table.
+func (s *Store) CalculateTax(request entity.TaxRequest) (*entity.TaxResult, error) {
+	rates, err := s.GetTaxRatesByCountry(request.Address.Country)
+	if err != nil {
+		return nil, err
+	}
+
+	return tax.Calculate(rates, request, configs.Envs.TaxPricing, configs.Envs.TaxRounding), nil
+}
+
+func (s *Store) getTaxRates(query string, args ...interface{}) ([]*entity.TaxRate, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []*entity.TaxRate{}
+	for rows.Next() {
+		rate, err := scanRowsIntoTaxRate(rows)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rates, nil
+}
+
+func scanRowsIntoTaxRate(rows *sql.Rows) (*entity.TaxRate, error) {
+	rate := new(entity.TaxRate)
+
+	err := rows.Scan(
+		&rate.ID,
+		&rate.Name,
+		&rate.Country,
+		&rate.Region,
+		&rate.TaxCategory,
+		&rate.Rate,
+		&rate.IsActive,
+		&rate.CreatedAt,
+		&rate.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return rate, nil
+}
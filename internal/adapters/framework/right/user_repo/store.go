@@ -109,8 +109,59 @@ func (s *Store) SetUserLocking(userEmail string, isLocked bool) error {
 	return nil
 }
 
+// LinkStripeCustomer records the Stripe customer of a user unless the user
+// already has one, so two requests creating a customer at once cannot both
+// link theirs.
+func (s *Store) LinkStripeCustomer(userID, customerID string) (bool, error) {
+	result, err := s.db.Exec("UPDATE users SET stripeCustomerId = ? WHERE id = ? AND stripeCustomerId IS NULL", customerID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to link stripe customer: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+func (s *Store) UnlinkStripeCustomer(customerID string) error {
+	_, err := s.db.Exec("UPDATE users SET stripeCustomerId = NULL WHERE stripeCustomerId = ?", customerID)
+	if err != nil {
+		return fmt.Errorf("failed to unlink stripe customer: %w", err)
+	}
+	return nil
+}
+
+// GetUserByStripeCustomerID returns the user a Stripe customer belongs to, nil
+// when the customer is not linked to anyone.
+func (s *Store) GetUserByStripeCustomerID(customerID string) (*entity.User, error) {
+	rows, err := s.db.Query("SELECT * FROM users WHERE stripeCustomerId = ?", customerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users by stripe customer: %w", err)
+	}
+
+	defer rows.Close()
+
+	var user *entity.User
+	for rows.Next() {
+		user, err = scanRowsIntoUser(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
 func scanRowsIntoUser(rows *sql.Rows) (*entity.User, error) {
 	user := new(entity.User)
+	var stripeCustomerID sql.NullString
 
 	err := rows.Scan(
 		&user.ID,
@@ -124,10 +175,13 @@ func scanRowsIntoUser(rows *sql.Rows) (*entity.User, error) {
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.DeletedAt,
+		&stripeCustomerID,
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	user.StripeCustomerID = stripeCustomerID.String
+
 	return user, nil
 }
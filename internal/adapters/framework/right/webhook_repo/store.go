@@ -0,0 +1,314 @@
+package webhook_repo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/utils"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) CreateWebhookEndpoint(endpoint entity.WebhookEndpoint) (string, error) {
+	endpoint.ID = utils.GenerateRandomUniqueIdentifier()
+
+	events, err := json.Marshal(endpoint.Events)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s.db.Exec("INSERT INTO webhook_endpoints (id, storeId, url, secret, events, isActive) VALUES (?,?,?,?,?,?)",
+		endpoint.ID, nullableString(endpoint.StoreID), endpoint.URL, endpoint.Secret, events, endpoint.IsActive)
+	if err != nil {
+		return "", fmt.Errorf("failed to create webhook endpoint: %w", err)
+	}
+
+	return endpoint.ID, nil
+}
+
+func (s *Store) GetWebhookEndpointByID(id string) (*entity.WebhookEndpoint, error) {
+	endpoints, err := s.queryEndpoints("SELECT * FROM webhook_endpoints WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(endpoints) == 0 {
+		return &entity.WebhookEndpoint{}, nil
+	}
+	return endpoints[0], nil
+}
+
+func (s *Store) GetWebhookEndpoints() ([]*entity.WebhookEndpoint, error) {
+	return s.queryEndpoints("SELECT * FROM webhook_endpoints ORDER BY createdAt")
+}
+
+func (s *Store) GetWebhookEndpointsByStoreID(storeID string) ([]*entity.WebhookEndpoint, error) {
+	return s.queryEndpoints("SELECT * FROM webhook_endpoints WHERE storeId = ? ORDER BY createdAt", storeID)
+}
+
+func (s *Store) GetActiveWebhookEndpoints(event string) ([]*entity.WebhookEndpoint, error) {
+	return s.queryEndpoints("SELECT * FROM webhook_endpoints WHERE isActive = TRUE AND JSON_CONTAINS(events, JSON_QUOTE(?)) ORDER BY createdAt", event)
+}
+
+// DeleteWebhookEndpoint removes an endpoint, its deliveries go with it.
+func (s *Store) DeleteWebhookEndpoint(id string) (bool, error) {
+	result, err := s.db.Exec("DELETE FROM webhook_endpoints WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete webhook endpoint: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// AddWebhookDeliveries queues deliveries in one transaction. An endpoint gets
+// an event once, a delivery of an event already queued for its endpoint is
+// skipped, so the fan-out of an event can be run again.
+func (s *Store) AddWebhookDeliveries(deliveries ...entity.WebhookDelivery) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	for _, delivery := range deliveries {
+		_, err := tx.Exec("INSERT IGNORE INTO webhook_deliveries (id, endpointId, eventId, event, payload, status, nextAttemptAt) VALUES (?,?,?,?,?,?,?)",
+			utils.GenerateRandomUniqueIdentifier(), delivery.EndpointID, delivery.EventID, delivery.Event, delivery.Payload, entity.WebhookPending, now)
+		if err != nil {
+			return fmt.Errorf("failed to add webhook delivery: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ClaimWebhookDeliveries takes up to limit due deliveries, oldest first, and
+// marks them processing until the lease runs out. Rows locked by another
+// sender are skipped, and a delivery whose sender died before finishing is due
+// again once its lease has passed.
+func (s *Store) ClaimWebhookDeliveries(limit int, lease time.Duration) ([]*entity.WebhookDelivery, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	rows, err := tx.Query("SELECT * FROM webhook_deliveries WHERE status IN (?,?,?) AND nextAttemptAt <= ? ORDER BY createdAt LIMIT ? FOR UPDATE SKIP LOCKED",
+		entity.WebhookPending, entity.WebhookFailed, entity.WebhookProcessing, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query due webhook deliveries: %w", err)
+	}
+
+	var deliveries []*entity.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanRowsIntoWebhookDelivery(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	leaseEnd := now.Add(lease)
+	for _, delivery := range deliveries {
+		_, err := tx.Exec("UPDATE webhook_deliveries SET status = ?, attempts = attempts + 1, nextAttemptAt = ? WHERE id = ?",
+			entity.WebhookProcessing, leaseEnd, delivery.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim webhook delivery %s: %w", delivery.ID, err)
+		}
+		delivery.Status = entity.WebhookProcessing
+		delivery.Attempts++
+		delivery.NextAttemptAt = leaseEnd
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (s *Store) MarkWebhookDeliveryDelivered(id string, responseStatus int, responseBody string) error {
+	_, err := s.db.Exec("UPDATE webhook_deliveries SET status = ?, responseStatus = ?, responseBody = ?, lastError = NULL, deliveredAt = ? WHERE id = ?",
+		entity.WebhookDelivered, responseStatus, responseBody, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to mark webhook delivery delivered: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) MarkWebhookDeliveryFailed(id string, responseStatus int, responseBody, lastError string, nextAttemptAt time.Time, dead bool) error {
+	status := entity.WebhookFailed
+	if dead {
+		status = entity.WebhookDead
+	}
+
+	_, err := s.db.Exec("UPDATE webhook_deliveries SET status = ?, responseStatus = ?, responseBody = ?, lastError = ?, nextAttemptAt = ? WHERE id = ?",
+		status, responseStatus, responseBody, lastError, nextAttemptAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark webhook delivery failed: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) GetWebhookDeliveryByID(id string) (*entity.WebhookDelivery, error) {
+	rows, err := s.db.Query("SELECT * FROM webhook_deliveries WHERE id = ?", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook delivery: %w", err)
+	}
+	defer rows.Close()
+
+	delivery := new(entity.WebhookDelivery)
+	for rows.Next() {
+		delivery, err = scanRowsIntoWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+func (s *Store) GetWebhookDeliveriesByEndpointID(endpointID string) ([]*entity.WebhookDelivery, error) {
+	rows, err := s.db.Query("SELECT * FROM webhook_deliveries WHERE endpointId = ? ORDER BY createdAt DESC", endpointID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*entity.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanRowsIntoWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// RedeliverWebhookDelivery queues a delivery to be posted right away with a
+// fresh set of attempts, whether it was delivered or not. A delivery being
+// posted is left alone.
+func (s *Store) RedeliverWebhookDelivery(id string) (bool, error) {
+	result, err := s.db.Exec("UPDATE webhook_deliveries SET status = ?, attempts = 0, nextAttemptAt = ? WHERE id = ? AND status <> ?",
+		entity.WebhookPending, time.Now(), id, entity.WebhookProcessing)
+	if err != nil {
+		return false, fmt.Errorf("failed to redeliver webhook delivery: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+func (s *Store) queryEndpoints(query string, args ...interface{}) ([]*entity.WebhookEndpoint, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook endpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var endpoints []*entity.WebhookEndpoint
+	for rows.Next() {
+		endpoint, err := scanRowsIntoWebhookEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+func scanRowsIntoWebhookEndpoint(rows *sql.Rows) (*entity.WebhookEndpoint, error) {
+	endpoint := new(entity.WebhookEndpoint)
+	var storeID sql.NullString
+	var events []byte
+
+	err := rows.Scan(
+		&endpoint.ID,
+		&storeID,
+		&endpoint.URL,
+		&endpoint.Secret,
+		&events,
+		&endpoint.IsActive,
+		&endpoint.CreatedAt,
+		&endpoint.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint.StoreID = storeID.String
+	if err := json.Unmarshal(events, &endpoint.Events); err != nil {
+		return nil, fmt.Errorf("invalid events of webhook endpoint %s: %w", endpoint.ID, err)
+	}
+
+	return endpoint, nil
+}
+
+func scanRowsIntoWebhookDelivery(rows *sql.Rows) (*entity.WebhookDelivery, error) {
+	delivery := new(entity.WebhookDelivery)
+	var responseBody, lastError sql.NullString
+
+	err := rows.Scan(
+		&delivery.ID,
+		&delivery.EndpointID,
+		&delivery.EventID,
+		&delivery.Event,
+		&delivery.Payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.ResponseStatus,
+		&responseBody,
+		&lastError,
+		&delivery.NextAttemptAt,
+		&delivery.DeliveredAt,
+		&delivery.CreatedAt,
+		&delivery.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.ResponseBody = responseBody.String
+	delivery.LastError = lastError.String
+
+	return delivery, nil
+}
+
+func nullableString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
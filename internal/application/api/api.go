@@ -1,19 +1,53 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"net/http"
 
+	"ecom-api/internal/adapters/framework/left/services/address"
 	"ecom-api/internal/adapters/framework/left/services/auth/token"
 	"ecom-api/internal/adapters/framework/left/services/cart"
+	"ecom-api/internal/adapters/framework/left/services/credit"
+	"ecom-api/internal/adapters/framework/left/services/loyalty"
+	"ecom-api/internal/adapters/framework/left/services/notification"
+	"ecom-api/internal/adapters/framework/left/services/outbox"
 	"ecom-api/internal/adapters/framework/left/services/payment"
+	"ecom-api/internal/adapters/framework/left/services/pricing"
 	"ecom-api/internal/adapters/framework/left/services/product"
+	"ecom-api/internal/adapters/framework/left/services/promotion"
+	"ecom-api/internal/adapters/framework/left/services/shipping"
+	"ecom-api/internal/adapters/framework/left/services/stream"
+	"ecom-api/internal/adapters/framework/left/services/tax"
 	"ecom-api/internal/adapters/framework/left/services/user"
+	"ecom-api/internal/adapters/framework/left/services/webhook"
+	"ecom-api/internal/adapters/framework/right/address_repo"
+	"ecom-api/internal/adapters/framework/right/bus_repo"
+	"ecom-api/internal/adapters/framework/right/cart_repo"
+	"ecom-api/internal/adapters/framework/right/credit_repo"
+	"ecom-api/internal/adapters/framework/right/fakepayment_repo"
+	"ecom-api/internal/adapters/framework/right/idempotency_repo"
+	"ecom-api/internal/adapters/framework/right/loyalty_repo"
+	"ecom-api/internal/adapters/framework/right/mail_repo"
+	"ecom-api/internal/adapters/framework/right/notification_repo"
 	order "ecom-api/internal/adapters/framework/right/order_repo"
+	"ecom-api/internal/adapters/framework/right/outbox_repo"
 	paymentrepo "ecom-api/internal/adapters/framework/right/payment_repo"
+	"ecom-api/internal/adapters/framework/right/paymentevent_repo"
+	"ecom-api/internal/adapters/framework/right/payout_repo"
+	"ecom-api/internal/adapters/framework/right/pricing_repo"
 	"ecom-api/internal/adapters/framework/right/product_repo"
+	"ecom-api/internal/adapters/framework/right/promotion_repo"
+	"ecom-api/internal/adapters/framework/right/shipping_repo"
+	"ecom-api/internal/adapters/framework/right/storeowner_repo"
+	"ecom-api/internal/adapters/framework/right/subscription_repo"
+	"ecom-api/internal/adapters/framework/right/tax_repo"
 	"ecom-api/internal/adapters/framework/right/user_repo"
+	"ecom-api/internal/adapters/framework/right/webhook_repo"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/ports/right/rports"
+	"ecom-api/pkg/configs"
 
 	"github.com/gorilla/mux"
 )
@@ -35,23 +69,113 @@ func (api *APIServer) Run() error {
 	router := mux.NewRouter()
 	subrouter := router.PathPrefix("/api/v1").Subrouter()
 
-	tokenStore := token.NewTokenStore()
+	// the log mailer sends nothing, for running the API without an SMTP server
+	var mailer rports.Mailer = mail_repo.NewSMTPMailer(configs.Envs.SMTPAddress, configs.Envs.FromEmailSMTP, configs.Envs.FromEmail, configs.Envs.FromEmailPassword)
+	if configs.Envs.Mailer == "log" {
+		mailer = mail_repo.NewLogMailer(configs.Envs.FromEmail, configs.Envs.MailLogPath)
+		log.Println("Using the log mailer, emails are not sent")
+	}
 	userStore := user_repo.NewStore(api.db)
-	userHandler := user.NewUserHandler(userStore, tokenStore)
+	outboxStore := outbox_repo.NewStore(api.db)
+	outboxDispatcher := outbox.NewDispatcher(outboxStore)
+	notificationStore := notification_repo.NewStore(api.db)
+	notifier := notification.NewNotifier(mailer, notification.NewTemplates(configs.Envs.EmailTemplateDir), outboxStore, notificationStore, userStore)
+	notifier.OnQueued(outboxDispatcher.Wake)
+	outboxDispatcher.Subscribe(entity.TopicEmail, notifier.Deliver)
+	notificationHandler := notification.NewNotificationHandler(notificationStore, userStore)
+	notificationHandler.RegisterRoutes(subrouter)
+
+	tokenStore := token.NewTokenStore()
+	orderStore := order.NewStore(api.db)
+	userHandler := user.NewUserHandler(userStore, tokenStore, orderStore, notifier)
 	userHandler.RegisterRoutes(subrouter)
 
 	productStore := product_repo.NewStore(api.db)
-	productHandler := product.NewProductHandler(productStore, userStore)
+	productHandler := product.NewProductHandler(productStore, userStore, outboxDispatcher)
 	productHandler.RegisterRoutes(subrouter)
 
-	orderStore := order.NewStore(api.db)
-	paymentStore := paymentrepo.NewPaymentStore()
+	addressStore := address_repo.NewStore(api.db)
+	addressHandler := address.NewAddressHandler(addressStore, userStore)
+	addressHandler.RegisterRoutes(subrouter)
+
+	promotionStore := promotion_repo.NewStore(api.db)
+	promotionHandler := promotion.NewPromotionHandler(promotionStore, userStore)
+	promotionHandler.RegisterRoutes(subrouter)
+
+	taxStore := tax_repo.NewStore(api.db)
+	taxHandler := tax.NewTaxHandler(taxStore, userStore)
+	taxHandler.RegisterRoutes(subrouter)
+
+	shippingStore := shipping_repo.NewStore(api.db)
+	shippingHandler := shipping.NewShippingHandler(shippingStore, userStore)
+	shippingHandler.RegisterRoutes(subrouter)
+
+	pricingStore := pricing_repo.NewStore(api.db)
+	pricingHandler := pricing.NewPricingHandler(pricingStore, pricingStore, productStore, userStore)
+	pricingHandler.RegisterRoutes(subrouter)
+
+	creditStore := credit_repo.NewStore(api.db)
+	creditHandler := credit.NewCreditHandler(creditStore, userStore)
+	creditHandler.RegisterRoutes(subrouter)
 
-	cartHandler := cart.NewCartHandler(productStore, orderStore, userStore, paymentStore)
+	loyaltyProgram := loyalty.NewProgram(loyalty_repo.NewStore(api.db), orderStore)
+	loyaltyHandler := loyalty.NewLoyaltyHandler(loyaltyProgram, userStore)
+	loyaltyHandler.RegisterRoutes(subrouter)
+
+	idempotencyStore := idempotency_repo.NewStore(api.db)
+	// the fake gateway keeps payments in memory, for running the API offline
+	var paymentStore rports.PaymentStore = paymentrepo.NewPaymentStore()
+	var fakeGateway *fakepayment_repo.Gateway
+	if configs.Envs.PaymentGateway == "fake" {
+		fakeGateway = fakepayment_repo.NewGateway()
+		paymentStore = fakeGateway
+		log.Println("Using the in-memory fake payment gateway")
+	}
+	cartStore := cart_repo.NewStore(api.db)
+	payoutStore := payout_repo.NewStore(api.db)
+	storeOwnerStore := storeowner_repo.NewStore(api.db)
+
+	cartHandler := cart.NewCartHandler(productStore, orderStore, userStore, paymentStore, addressStore, cartStore, promotionStore, taxStore, shippingStore, pricingStore, pricingStore, idempotencyStore, payoutStore, storeOwnerStore, creditStore, loyaltyProgram, notifier, outboxDispatcher)
 	cartHandler.RegisterRoutes(subrouter)
 
-	paymentHandler := payment.NewPaymentHandler(paymentStore, userStore, orderStore)
+	paymentEventStore := paymentevent_repo.NewStore(api.db)
+	subscriptionStore := subscription_repo.NewStore(api.db)
+	paymentHandler := payment.NewPaymentHandler(paymentStore, userStore, orderStore, idempotencyStore, paymentEventStore, payoutStore, storeOwnerStore, subscriptionStore, productStore, addressStore, creditStore, loyaltyProgram, notifier, outboxDispatcher)
 	paymentHandler.RegisterRoutes(subrouter)
+	if fakeGateway != nil {
+		fakeGateway.OnEvent(paymentHandler.QueuePaymentEvent)
+	}
+	go paymentHandler.RunEventWorker(context.Background())
+
+	// merchants hear about the domain events through their webhook endpoints
+	webhookStore := webhook_repo.NewStore(api.db)
+	webhookSender := webhook.NewSender(webhookStore, orderStore)
+	for _, event := range entity.WebhookEvents {
+		outboxDispatcher.Subscribe(event, webhookSender.Publish)
+	}
+	webhookHandler := webhook.NewWebhookHandler(webhookStore, webhookSender, userStore, storeOwnerStore)
+	webhookHandler.RegisterRoutes(subrouter)
+	go webhookSender.Run(context.Background())
+
+	// the memory bus only reaches the streams of this instance, several
+	// instances share the updates through the database
+	var eventBus rports.EventBus = bus_repo.NewMemoryBus()
+	if configs.Envs.EventBus == "mysql" {
+		mysqlBus := bus_repo.NewMySQLBus(api.db)
+		go mysqlBus.Run(context.Background())
+		eventBus = mysqlBus
+		log.Println("Using the database event bus")
+	}
+	streamRelay := stream.NewRelay(eventBus)
+	for _, topic := range stream.Topics {
+		outboxDispatcher.Subscribe(topic, streamRelay.Forward)
+	}
+	streamHandler := stream.NewStreamHandler(eventBus, productStore, userStore)
+	streamHandler.RegisterRoutes(subrouter)
+
+	outboxHandler := outbox.NewOutboxHandler(outboxDispatcher, userStore)
+	outboxHandler.RegisterRoutes(subrouter)
+	go outboxDispatcher.Run(context.Background())
 
 	log.Println("Listening to ", api.addr)
 	return http.ListenAndServe(api.addr, router)
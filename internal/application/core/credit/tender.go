@@ -0,0 +1,110 @@
+// Package credit issues gift card codes and decides how gift cards and wallet
+// balance pay for an order. It holds no state, balances are read and taken by
+// the caller through rports.CreditStore.
+package credit
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"ecom-api/internal/application/core/types/entity"
+)
+
+// codeAlphabet leaves out the letters and digits that read alike.
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// CodeLength is the number of characters of a gift card code.
+const CodeLength = 16
+
+// NewCode returns a random gift card code.
+func NewCode() (string, error) {
+	var code strings.Builder
+	max := big.NewInt(int64(len(codeAlphabet)))
+	for i := 0; i < CodeLength; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code.WriteByte(codeAlphabet[n.Int64()])
+	}
+	return code.String(), nil
+}
+
+// NormalizeCode returns a code as it is stored, upper case and without the
+// spaces and dashes people type it with.
+func NormalizeCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(code)))
+}
+
+// Spendable reports why a gift card cannot pay in currency at now, nil when it
+// can.
+func Spendable(card *entity.GiftCard, currency string, now time.Time) error {
+	switch {
+	case card.Status != entity.GiftCardActive:
+		return fmt.Errorf("gift card is %s", card.Status)
+	case card.ExpiresAt != nil && !now.Before(*card.ExpiresAt):
+		return fmt.Errorf("gift card expired on %s", card.ExpiresAt.Format("2006-01-02"))
+	case card.Currency != strings.ToUpper(currency):
+		return fmt.Errorf("gift card is in %s, the order is in %s", card.Currency, strings.ToUpper(currency))
+	case card.Balance.IsZero() || card.Balance.IsNegative():
+		return fmt.Errorf("gift card has no balance left")
+	}
+	return nil
+}
+
+// Tender pays as much of due as it can from the gift cards, in the given
+// order, then from the wallet balance of userID. It returns the tenders to
+// take, without zero ones, and the credit they add up to. The rest of due is
+// left to charge. Every card must be spendable.
+func Tender(due entity.Money, cards []*entity.GiftCard, wallet entity.Money, userID string, now time.Time) ([]entity.OrderTender, entity.Money, error) {
+	remaining := due
+	credit := entity.NewMoney(0, due.Currency)
+	var tenders []entity.OrderTender
+
+	take := func(tender entity.OrderTender, available entity.Money) error {
+		amount, err := available.Min(remaining)
+		if err != nil {
+			return err
+		}
+		if amount.IsZero() || amount.IsNegative() {
+			return nil
+		}
+		if remaining, err = remaining.Sub(amount); err != nil {
+			return err
+		}
+		if credit, err = credit.Add(amount); err != nil {
+			return err
+		}
+
+		tender.Amount = amount
+		tender.Currency = amount.Currency
+		tender.Status = entity.TenderApplied
+		tenders = append(tenders, tender)
+		return nil
+	}
+
+	for _, card := range cards {
+		if err := Spendable(card, due.Currency, now); err != nil {
+			return nil, entity.Money{}, fmt.Errorf("gift card %s: %v", card.Code, err)
+		}
+		if err := take(entity.OrderTender{Type: entity.TenderGiftCard, GiftCardID: card.ID}, card.Balance); err != nil {
+			return nil, entity.Money{}, err
+		}
+	}
+
+	if userID != "" && !wallet.IsZero() {
+		if err := take(entity.OrderTender{Type: entity.TenderWallet, UserID: userID}, wallet); err != nil {
+			return nil, entity.Money{}, err
+		}
+	}
+
+	return tenders, credit, nil
+}
@@ -0,0 +1,101 @@
+package credit
+
+import (
+	"testing"
+	"time"
+
+	"ecom-api/internal/application/core/types/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func usd(amount float64) entity.Money {
+	return entity.MoneyFromMajor(amount, "USD")
+}
+
+func card(id string, balance float64) *entity.GiftCard {
+	return &entity.GiftCard{ID: id, Code: id, Balance: usd(balance), Currency: "USD", Status: entity.GiftCardActive}
+}
+
+func TestTender(t *testing.T) {
+	now := time.Date(2025, 4, 15, 12, 0, 0, 0, time.UTC)
+
+	t.Run("cards pay first in the given order, then the wallet", func(t *testing.T) {
+		tenders, credit, err := Tender(usd(100), []*entity.GiftCard{card("a", 30), card("b", 25)}, usd(60), "user-1", now)
+
+		assert.NoError(t, err)
+		assert.Equal(t, usd(100), credit)
+		assert.Equal(t, []entity.OrderTender{
+			{Type: entity.TenderGiftCard, GiftCardID: "a", Amount: usd(30), Currency: "USD", Status: entity.TenderApplied},
+			{Type: entity.TenderGiftCard, GiftCardID: "b", Amount: usd(25), Currency: "USD", Status: entity.TenderApplied},
+			{Type: entity.TenderWallet, UserID: "user-1", Amount: usd(45), Currency: "USD", Status: entity.TenderApplied},
+		}, tenders)
+	})
+
+	t.Run("the rest is left to charge", func(t *testing.T) {
+		tenders, credit, err := Tender(usd(100), []*entity.GiftCard{card("a", 30)}, usd(0), "user-1", now)
+
+		assert.NoError(t, err)
+		assert.Equal(t, usd(30), credit)
+		assert.Len(t, tenders, 1)
+	})
+
+	t.Run("credit left over once the order is paid is not taken", func(t *testing.T) {
+		tenders, credit, err := Tender(usd(20), []*entity.GiftCard{card("a", 30), card("b", 25)}, usd(60), "user-1", now)
+
+		assert.NoError(t, err)
+		assert.Equal(t, usd(20), credit)
+		assert.Len(t, tenders, 1)
+		assert.Equal(t, usd(20), tenders[0].Amount)
+	})
+
+	t.Run("guests have no wallet", func(t *testing.T) {
+		tenders, credit, err := Tender(usd(20), nil, usd(60), "", now)
+
+		assert.NoError(t, err)
+		assert.Equal(t, usd(0), credit)
+		assert.Empty(t, tenders)
+	})
+
+	t.Run("a card that cannot be spent fails the tender", func(t *testing.T) {
+		expired := card("a", 30)
+		yesterday := now.AddDate(0, 0, -1)
+		expired.ExpiresAt = &yesterday
+
+		_, _, err := Tender(usd(20), []*entity.GiftCard{expired}, usd(0), "", now)
+		assert.Error(t, err)
+	})
+}
+
+func TestSpendable(t *testing.T) {
+	now := time.Date(2025, 4, 15, 12, 0, 0, 0, time.UTC)
+	tomorrow := now.AddDate(0, 0, 1)
+
+	valid := card("a", 10)
+	valid.ExpiresAt = &tomorrow
+	assert.NoError(t, Spendable(valid, "usd", now))
+
+	disabled := card("a", 10)
+	disabled.Status = entity.GiftCardDisabled
+	assert.Error(t, Spendable(disabled, "USD", now))
+
+	assert.Error(t, Spendable(card("a", 0), "USD", now), "nothing left to spend")
+	assert.Error(t, Spendable(card("a", 10), "EUR", now), "cards pay in their own currency")
+
+	expiring := card("a", 10)
+	expiring.ExpiresAt = &now
+	assert.Error(t, Spendable(expiring, "USD", now), "expired at that very moment")
+}
+
+func TestCodes(t *testing.T) {
+	code, err := NewCode()
+	assert.NoError(t, err)
+	assert.Len(t, code, CodeLength)
+	assert.Equal(t, code, NormalizeCode(code))
+
+	other, err := NewCode()
+	assert.NoError(t, err)
+	assert.NotEqual(t, code, other)
+
+	assert.Equal(t, "ABCD2345EFGH6789", NormalizeCode(" abcd-2345 efgh-6789 "))
+}
@@ -0,0 +1,146 @@
+// Package fulfilment splits an order into the parts each store fulfils and
+// keeps the status of the order in line with the status of its parts. It holds
+// no state, store orders are read and written by the caller through
+// rports.OrderStore.
+package fulfilment
+
+import (
+	"sort"
+
+	"ecom-api/internal/application/core/types/entity"
+)
+
+// Statuses names the order statuses, which are configurable.
+type Statuses struct {
+	Pending    string
+	Processing string
+	Shipped    string
+	Completed  string
+	Cancelled  string
+	Refunded   string
+}
+
+// CanTransition reports whether a store order may move from one status to
+// another. An order is paid for before it is processed, shipped, then
+// completed. It can be cancelled until it ships and refunded once paid.
+func (s Statuses) CanTransition(from, to string) bool {
+	switch from {
+	case s.Pending:
+		return to == s.Processing || to == s.Cancelled
+	case s.Processing:
+		return to == s.Shipped || to == s.Cancelled || to == s.Refunded
+	case s.Shipped:
+		return to == s.Completed || to == s.Refunded
+	case s.Completed:
+		return to == s.Refunded
+	}
+	return false
+}
+
+// Rollup returns the status of an order made of store orders in the given
+// statuses. The order is as far along as its least advanced live store order,
+// cancelled or refunded ones are left out. An order with nothing live left is
+// refunded when any part was, cancelled otherwise. Returns empty when there are
+// no store orders.
+func (s Statuses) Rollup(statuses []string) string {
+	if len(statuses) == 0 {
+		return ""
+	}
+	progress := []string{s.Pending, s.Processing, s.Shipped, s.Completed}
+
+	least := len(progress)
+	refunded := false
+	for _, status := range statuses {
+		if status == s.Refunded {
+			refunded = true
+		}
+		for i, step := range progress {
+			if status == step && i < least {
+				least = i
+			}
+		}
+	}
+
+	if least < len(progress) {
+		return progress[least]
+	}
+	if refunded {
+		return s.Refunded
+	}
+	return s.Cancelled
+}
+
+// Split groups the items of an order by the store selling them, the
+// platform's own items are a store order with no store. The shipping of the
+// order is shared in proportion to the subtotals, so the totals of the store
+// orders add up to the total of the order. Store orders start in the status of
+// the order and are sorted by store.
+func Split(order entity.Order, items []*entity.OrderItem) ([]entity.StoreOrder, error) {
+	byStore := map[string]*entity.StoreOrder{}
+	var stores []string
+
+	for _, item := range items {
+		storeOrder, ok := byStore[item.StoreID]
+		if !ok {
+			zero := entity.NewMoney(0, order.Currency)
+			storeOrder = &entity.StoreOrder{
+				OrderID:  order.ID,
+				StoreID:  item.StoreID,
+				Status:   order.Status,
+				Subtotal: zero,
+				Discount: zero,
+				Tax:      zero,
+				Total:    zero,
+				Currency: zero.Currency,
+			}
+			byStore[item.StoreID] = storeOrder
+			stores = append(stores, item.StoreID)
+		}
+
+		var err error
+		if storeOrder.Subtotal, err = storeOrder.Subtotal.Add(item.Subtotal); err != nil {
+			return nil, err
+		}
+		if storeOrder.Discount, err = storeOrder.Discount.Add(item.Discount); err != nil {
+			return nil, err
+		}
+		if storeOrder.Tax, err = storeOrder.Tax.Add(item.Tax); err != nil {
+			return nil, err
+		}
+		if storeOrder.Total, err = storeOrder.Total.Add(item.TotalPrice); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(stores)
+	weights := make([]int64, len(stores))
+	var weight int64
+	for i, storeID := range stores {
+		weights[i] = byStore[storeID].Subtotal.Amount
+		weight += weights[i]
+	}
+	// free items still share the shipping, evenly
+	if weight == 0 {
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+	shipping := order.ShippingCost
+	if shipping.Currency == "" {
+		shipping = entity.NewMoney(shipping.Amount, order.Currency)
+	}
+
+	storeOrders := make([]entity.StoreOrder, 0, len(stores))
+	for i, share := range shipping.Allocate(weights) {
+		storeOrder := byStore[stores[i]]
+		storeOrder.ShippingCost = share
+		total, err := storeOrder.Total.Add(share)
+		if err != nil {
+			return nil, err
+		}
+		storeOrder.Total = total
+		storeOrders = append(storeOrders, *storeOrder)
+	}
+
+	return storeOrders, nil
+}
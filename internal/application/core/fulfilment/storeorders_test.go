@@ -0,0 +1,95 @@
+package fulfilment
+
+import (
+	"testing"
+
+	"ecom-api/internal/application/core/types/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var statuses = Statuses{
+	Pending:    "pending",
+	Processing: "processing",
+	Shipped:    "shipped",
+	Completed:  "completed",
+	Cancelled:  "cancelled",
+	Refunded:   "refunded",
+}
+
+func usd(amount int64) entity.Money {
+	return entity.NewMoney(amount, "USD")
+}
+
+func TestCanTransition(t *testing.T) {
+	assert.True(t, statuses.CanTransition("pending", "processing"))
+	assert.True(t, statuses.CanTransition("processing", "shipped"))
+	assert.True(t, statuses.CanTransition("shipped", "completed"))
+	assert.True(t, statuses.CanTransition("completed", "refunded"))
+	assert.True(t, statuses.CanTransition("processing", "cancelled"))
+
+	assert.False(t, statuses.CanTransition("pending", "shipped"), "not paid yet")
+	assert.False(t, statuses.CanTransition("shipped", "cancelled"), "already on its way")
+	assert.False(t, statuses.CanTransition("pending", "refunded"), "nothing to refund")
+	assert.False(t, statuses.CanTransition("cancelled", "processing"))
+	assert.False(t, statuses.CanTransition("refunded", "completed"))
+	assert.False(t, statuses.CanTransition("shipped", "shipped"))
+}
+
+func TestRollup(t *testing.T) {
+	assert.Equal(t, "processing", statuses.Rollup([]string{"shipped", "processing", "completed"}))
+	assert.Equal(t, "shipped", statuses.Rollup([]string{"shipped", "cancelled", "completed"}))
+	assert.Equal(t, "completed", statuses.Rollup([]string{"completed", "refunded"}))
+	assert.Equal(t, "refunded", statuses.Rollup([]string{"cancelled", "refunded"}))
+	assert.Equal(t, "cancelled", statuses.Rollup([]string{"cancelled", "cancelled"}))
+	assert.Equal(t, "", statuses.Rollup(nil))
+}
+
+func TestSplit(t *testing.T) {
+	order := entity.Order{ID: "order-1", Status: "pending", Currency: "USD", ShippingCost: usd(1000), Total: usd(8600)}
+	items := []*entity.OrderItem{
+		{StoreID: "s2", Subtotal: usd(2000), Discount: usd(200), Tax: usd(180), TotalPrice: usd(1980)},
+		{StoreID: "s1", Subtotal: usd(3000), TotalPrice: usd(3000)},
+		{Subtotal: usd(1000), Tax: usd(100), TotalPrice: usd(1100)},
+		{StoreID: "s1", Subtotal: usd(1000), Discount: usd(100), TotalPrice: usd(900)},
+		{StoreID: "s2", Subtotal: usd(500), TotalPrice: usd(620), Tax: usd(120)},
+	}
+
+	storeOrders, err := Split(order, items)
+	assert.NoError(t, err)
+	assert.Len(t, storeOrders, 3)
+
+	platform, s1, s2 := storeOrders[0], storeOrders[1], storeOrders[2]
+	assert.Equal(t, "", platform.StoreID)
+	assert.Equal(t, "s1", s1.StoreID)
+	assert.Equal(t, "s2", s2.StoreID)
+
+	assert.Equal(t, usd(4000), s1.Subtotal)
+	assert.Equal(t, usd(100), s1.Discount)
+	assert.Equal(t, usd(300), s2.Tax)
+
+	// shipping is shared 1000:4000:2500
+	assert.Equal(t, usd(134), platform.ShippingCost)
+	assert.Equal(t, usd(533), s1.ShippingCost)
+	assert.Equal(t, usd(333), s2.ShippingCost)
+	assert.Equal(t, usd(4433), s1.Total)
+
+	total, err := entity.SumMoney(platform.Total, s1.Total, s2.Total)
+	assert.NoError(t, err)
+	assert.Equal(t, order.Total, total)
+
+	for _, storeOrder := range storeOrders {
+		assert.Equal(t, "order-1", storeOrder.OrderID)
+		assert.Equal(t, "pending", storeOrder.Status)
+	}
+
+	t.Run("free items share the shipping evenly", func(t *testing.T) {
+		storeOrders, err := Split(entity.Order{Currency: "USD", ShippingCost: usd(500)}, []*entity.OrderItem{
+			{StoreID: "s1", Subtotal: usd(0), TotalPrice: usd(0)},
+			{StoreID: "s2", Subtotal: usd(0), TotalPrice: usd(0)},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, usd(250), storeOrders[0].Total)
+		assert.Equal(t, usd(250), storeOrders[1].Total)
+	})
+}
@@ -0,0 +1,151 @@
+// Package loyalty works out the points a paid order earns, the tier a user's
+// spend reaches and what points take off an order at checkout. It holds no
+// state, ledgers are read and written by the caller through
+// rports.LoyaltyStore.
+package loyalty
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"ecom-api/internal/application/core/types/entity"
+)
+
+// spendRate returns the points earned per unit spent in currency: the rate of
+// the active spend rule for currency, else of the one for every currency.
+// Zero when neither exists.
+func spendRate(rules []*entity.LoyaltyEarnRule, currency string) float64 {
+	rate, found := 0.0, false
+	for _, rule := range rules {
+		if !rule.IsActive || rule.Type != entity.LoyaltyRuleSpend {
+			continue
+		}
+		if strings.EqualFold(rule.Currency, currency) {
+			return rule.Rate
+		}
+		if rule.Currency == "" && !found {
+			rate, found = rule.Rate, true
+		}
+	}
+	return rate
+}
+
+// categoryMultiplier returns the highest multiplier of the active category
+// rules matching category in currency, 1 when none does.
+func categoryMultiplier(rules []*entity.LoyaltyEarnRule, category, currency string) float64 {
+	multiplier := 1.0
+	found := false
+	for _, rule := range rules {
+		if !rule.IsActive || rule.Type != entity.LoyaltyRuleCategory || category == "" {
+			continue
+		}
+		if !strings.EqualFold(rule.Category, category) {
+			continue
+		}
+		if rule.Currency != "" && !strings.EqualFold(rule.Currency, currency) {
+			continue
+		}
+		if !found || rule.Rate > multiplier {
+			multiplier, found = rule.Rate, true
+		}
+	}
+	return multiplier
+}
+
+// Earn returns the points a paid order earns. Every item earns on what was
+// paid for it before tax, at the spend rate of the order currency times the
+// multiplier of its category. The share of the order paid with points earns
+// nothing and the total is multiplied by the tier of the buyer, if any, then
+// rounded down.
+func Earn(order entity.Order, items []*entity.OrderItem, rules []*entity.LoyaltyEarnRule, tier *entity.LoyaltyTier) int {
+	rate := spendRate(rules, order.Currency)
+	if rate <= 0 {
+		return 0
+	}
+
+	points := 0.0
+	for _, item := range items {
+		paid := item.Subtotal.Amount - item.Discount.Amount
+		if paid <= 0 {
+			continue
+		}
+		major := entity.NewMoney(paid, order.Currency).Major()
+		points += major * rate * categoryMultiplier(rules, item.Category, order.Currency)
+	}
+
+	if order.LoyaltyDiscount.Amount > 0 && order.Total.Amount > 0 {
+		points *= math.Max(0, 1-float64(order.LoyaltyDiscount.Amount)/float64(order.Total.Amount))
+	}
+	if tier != nil && tier.Multiplier > 0 {
+		points *= tier.Multiplier
+	}
+
+	return int(math.Floor(points + 1e-9))
+}
+
+// Spend returns what a paid order counts towards the tier of its buyer, what
+// was paid for it with anything but points.
+func Spend(order entity.Order) entity.Money {
+	return entity.NewMoney(order.Total.Amount-order.LoyaltyDiscount.Amount, order.Currency)
+}
+
+// Tier returns the tier reached with the given rolling spend, one amount per
+// currency. A tier is reached when the spend in its currency is at least its
+// minimum, the reached tier with the highest multiplier wins. Returns nil when
+// no tier is reached.
+func Tier(tiers []*entity.LoyaltyTier, spend []entity.Money) *entity.LoyaltyTier {
+	spent := map[string]int64{}
+	for _, amount := range spend {
+		spent[strings.ToUpper(amount.Currency)] += amount.Amount
+	}
+
+	var reached *entity.LoyaltyTier
+	for _, tier := range tiers {
+		amount, ok := spent[strings.ToUpper(tier.Currency)]
+		if !ok || amount < tier.MinSpend.Amount {
+			continue
+		}
+		if reached == nil || tier.Multiplier > reached.Multiplier {
+			reached = tier
+		}
+	}
+	return reached
+}
+
+// Value returns what points take off an order in currency when pointsPerUnit
+// points are worth one unit of it, rounded down to the minor unit.
+func Value(points int, pointsPerUnit int64, currency string) entity.Money {
+	if points <= 0 || pointsPerUnit <= 0 {
+		return entity.NewMoney(0, currency)
+	}
+	minor := new(big.Int).Mul(big.NewInt(int64(points)), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(entity.CurrencyExponent(currency))), nil))
+	minor.Quo(minor, big.NewInt(pointsPerUnit))
+	return entity.NewMoney(minor.Int64(), currency)
+}
+
+// Redeem works out how many of the requested points to spend on an order with
+// due left to pay, and what they take off it. No more points are spent than it
+// takes to pay due in full. Fails when the user has fewer than requested.
+func Redeem(points, balance int, due entity.Money, pointsPerUnit int64) (int, entity.Money, error) {
+	if points <= 0 || pointsPerUnit <= 0 {
+		return 0, entity.NewMoney(0, due.Currency), nil
+	}
+	if points > balance {
+		return 0, entity.Money{}, fmt.Errorf("only %d loyalty points are available", balance)
+	}
+
+	// the points it takes to pay due, rounded up
+	unit := int64(math.Pow10(entity.CurrencyExponent(due.Currency)))
+	needed := (due.Amount*pointsPerUnit + unit - 1) / unit
+	if int64(points) > needed {
+		points = int(needed)
+	}
+
+	discount, err := Value(points, pointsPerUnit, due.Currency).Min(due)
+	if err != nil {
+		return 0, entity.Money{}, err
+	}
+	return points, discount, nil
+}
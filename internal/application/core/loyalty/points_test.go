@@ -0,0 +1,108 @@
+package loyalty
+
+import (
+	"testing"
+
+	"ecom-api/internal/application/core/types/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func usd(amount float64) entity.Money {
+	return entity.MoneyFromMajor(amount, "USD")
+}
+
+func item(category string, subtotal, discount float64) *entity.OrderItem {
+	return &entity.OrderItem{Category: category, Subtotal: usd(subtotal), Discount: usd(discount), Currency: "USD"}
+}
+
+func TestEarn(t *testing.T) {
+	rules := []*entity.LoyaltyEarnRule{
+		{Type: entity.LoyaltyRuleSpend, Rate: 1, IsActive: true},
+		{Type: entity.LoyaltyRuleSpend, Currency: "USD", Rate: 2, IsActive: true},
+		{Type: entity.LoyaltyRuleCategory, Category: "books", Rate: 3, IsActive: true},
+		{Type: entity.LoyaltyRuleCategory, Category: "games", Rate: 5, IsActive: false},
+	}
+	order := entity.Order{Total: usd(60), Currency: "USD"}
+
+	t.Run("items earn on what was paid for them at the rate of the currency", func(t *testing.T) {
+		points := Earn(order, []*entity.OrderItem{item("toys", 20, 5), item("games", 10, 0)}, rules, nil)
+		assert.Equal(t, 50, points, "(15 + 10) * 2, the games rule is inactive")
+	})
+
+	t.Run("categories multiply their items", func(t *testing.T) {
+		points := Earn(order, []*entity.OrderItem{item("toys", 10, 0), item("Books", 10.50, 0)}, rules, nil)
+		assert.Equal(t, 83, points, "10 * 2 + 10.50 * 2 * 3")
+	})
+
+	t.Run("currencies without a rule of their own use the one for every currency", func(t *testing.T) {
+		eur := entity.Order{Total: entity.MoneyFromMajor(10, "EUR"), Currency: "EUR"}
+		points := Earn(eur, []*entity.OrderItem{{Subtotal: entity.MoneyFromMajor(10, "EUR"), Discount: entity.MoneyFromMajor(0, "EUR")}}, rules, nil)
+		assert.Equal(t, 10, points)
+	})
+
+	t.Run("nothing is earned without a spend rule", func(t *testing.T) {
+		assert.Equal(t, 0, Earn(order, []*entity.OrderItem{item("books", 10, 0)}, rules[2:], nil))
+	})
+
+	t.Run("what was paid with points earns nothing and the tier multiplies the rest", func(t *testing.T) {
+		paidWithPoints := order
+		paidWithPoints.LoyaltyDiscount = usd(15)
+		tier := &entity.LoyaltyTier{Name: "Gold", Multiplier: 1.5}
+
+		points := Earn(paidWithPoints, []*entity.OrderItem{item("toys", 40, 0)}, rules, tier)
+		assert.Equal(t, 90, points, "40 * 2 * 3/4 * 1.5")
+	})
+}
+
+func TestTier(t *testing.T) {
+	silver := &entity.LoyaltyTier{Name: "Silver", MinSpend: usd(100), Currency: "USD", Multiplier: 1.25}
+	gold := &entity.LoyaltyTier{Name: "Gold", MinSpend: usd(500), Currency: "USD", Multiplier: 1.5}
+	euroGold := &entity.LoyaltyTier{Name: "Gold", MinSpend: entity.MoneyFromMajor(450, "EUR"), Currency: "EUR", Multiplier: 1.5}
+	tiers := []*entity.LoyaltyTier{gold, silver, euroGold}
+
+	assert.Nil(t, Tier(tiers, nil))
+	assert.Nil(t, Tier(tiers, []entity.Money{usd(99.99)}))
+	assert.Equal(t, silver, Tier(tiers, []entity.Money{usd(100)}))
+	assert.Equal(t, gold, Tier(tiers, []entity.Money{usd(120), usd(400)}))
+	assert.Equal(t, euroGold, Tier(tiers, []entity.Money{usd(20), entity.MoneyFromMajor(450, "EUR")}))
+}
+
+func TestRedeem(t *testing.T) {
+	t.Run("points are worth a unit per hundred", func(t *testing.T) {
+		points, discount, err := Redeem(250, 300, usd(10), 100)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 250, points)
+		assert.Equal(t, usd(2.50), discount)
+	})
+
+	t.Run("no more points are spent than the order takes", func(t *testing.T) {
+		points, discount, err := Redeem(5000, 5000, usd(12.345), 100)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1235, points)
+		assert.Equal(t, usd(12.35), discount)
+	})
+
+	t.Run("currencies without decimals round down", func(t *testing.T) {
+		points, discount, err := Redeem(150, 150, entity.NewMoney(1000, "JPY"), 100)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 150, points)
+		assert.Equal(t, entity.NewMoney(1, "JPY"), discount)
+	})
+
+	t.Run("spending more than the balance fails", func(t *testing.T) {
+		_, _, err := Redeem(301, 300, usd(10), 100)
+		assert.Error(t, err)
+	})
+
+	t.Run("no points take nothing off", func(t *testing.T) {
+		points, discount, err := Redeem(0, 0, usd(10), 100)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, points)
+		assert.True(t, discount.IsZero())
+	})
+}
@@ -0,0 +1,184 @@
+// Package payout works out what each store of an order is owed once the
+// platform has taken its commission. It holds no state, commission rules are
+// read by the caller through rports.PayoutStore.
+package payout
+
+import (
+	"sort"
+	"strings"
+
+	"ecom-api/internal/application/core/types/entity"
+)
+
+// Share is what one store sold in an order and what it is owed for it.
+type Share struct {
+	StoreID    string
+	Gross      entity.Money // What the buyer paid for the store's items, without tax
+	Commission entity.Money // Kept by the platform
+	Net        entity.Money // Owed to the store
+}
+
+// CommissionRate returns the percentage the platform keeps of a sale by store
+// in category. The most specific active rule wins: store and category, then
+// store, then category, then a rule for everything. defaultRate applies when
+// no rule matches.
+func CommissionRate(rules []*entity.CommissionRule, storeID, category string, defaultRate float64) float64 {
+	rate := defaultRate
+	best := 0
+
+	for _, rule := range rules {
+		if !rule.IsActive {
+			continue
+		}
+		if rule.StoreID != "" && rule.StoreID != storeID {
+			continue
+		}
+		if rule.Category != "" && !strings.EqualFold(rule.Category, category) {
+			continue
+		}
+
+		score := 1
+		if rule.StoreID != "" {
+			score += 2
+		}
+		if rule.Category != "" {
+			score++
+		}
+
+		if score > best {
+			best = score
+			rate = rule.Rate
+		}
+	}
+
+	return rate
+}
+
+// Split groups the items of an order by store and takes the commission off
+// every item. An item is sold at what the buyer paid for it less its tax, the
+// tax and shipping stay with the platform, which collects and remits them.
+// Items of the platform's own products are left out. Shares are sorted by
+// store.
+func Split(items []*entity.OrderItem, rules []*entity.CommissionRule, defaultRate float64) ([]Share, error) {
+	byStore := map[string]*Share{}
+	var stores []string
+
+	for _, item := range items {
+		if item.StoreID == "" {
+			continue
+		}
+
+		gross, err := item.TotalPrice.Sub(item.Tax)
+		if err != nil {
+			return nil, err
+		}
+		if gross.IsNegative() {
+			gross = entity.Money{Currency: gross.Currency}
+		}
+		commission := gross.Percent(CommissionRate(rules, item.StoreID, item.Category, defaultRate))
+
+		share, ok := byStore[item.StoreID]
+		if !ok {
+			share = &Share{StoreID: item.StoreID}
+			byStore[item.StoreID] = share
+			stores = append(stores, item.StoreID)
+		}
+		if share.Gross, err = share.Gross.Add(gross); err != nil {
+			return nil, err
+		}
+		if share.Commission, err = share.Commission.Add(commission); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(stores)
+	shares := make([]Share, 0, len(stores))
+	for _, storeID := range stores {
+		share := byStore[storeID]
+		net, err := share.Gross.Sub(share.Commission)
+		if err != nil {
+			return nil, err
+		}
+		share.Net = net
+		shares = append(shares, *share)
+	}
+
+	return shares, nil
+}
+
+// PaymentSplit decides how the payment of an order reaches its stores. An
+// order sold entirely by one store with a connected account is a destination
+// charge, the store gets its share with the payment and the platform keeps the
+// rest of what is charged as its fee. Any other order with store items, or one
+// whose charge does not cover the store's share because store credit paid for
+// part of it, is charged to the platform and its stores are paid by transfers
+// grouped under the order ID. accounts maps stores to their connected accounts.
+func PaymentSplit(order entity.Order, items []*entity.OrderItem, shares []Share, accounts map[string]string) (entity.PaymentSplit, error) {
+	if len(shares) == 0 {
+		return entity.PaymentSplit{}, nil
+	}
+	split := entity.PaymentSplit{TransferGroup: order.ID}
+
+	if len(shares) > 1 || accounts[shares[0].StoreID] == "" {
+		return split, nil
+	}
+	for _, item := range items {
+		if item.StoreID != shares[0].StoreID {
+			return split, nil
+		}
+	}
+
+	fee, err := order.AmountDue().Sub(shares[0].Net)
+	if err != nil {
+		return entity.PaymentSplit{}, err
+	}
+	if fee.IsNegative() {
+		return split, nil
+	}
+
+	split.Destination = accounts[shares[0].StoreID]
+	split.ApplicationFee = fee
+	return split, nil
+}
+
+// Balances sums payouts by currency and status, currencies in alphabetical
+// order.
+func Balances(payouts []*entity.StorePayout) ([]entity.PayoutBalance, error) {
+	byCurrency := map[string]*entity.PayoutBalance{}
+	var currencies []string
+
+	for _, payout := range payouts {
+		balance, ok := byCurrency[payout.Currency]
+		if !ok {
+			zero := entity.NewMoney(0, payout.Currency)
+			balance = &entity.PayoutBalance{Currency: zero.Currency, Pending: zero, Paid: zero, Reversed: zero}
+			byCurrency[payout.Currency] = balance
+			currencies = append(currencies, payout.Currency)
+		}
+
+		var total *entity.Money
+		switch payout.Status {
+		case entity.StorePayoutPending:
+			total = &balance.Pending
+		case entity.StorePayoutPaid:
+			total = &balance.Paid
+		case entity.StorePayoutReversed:
+			total = &balance.Reversed
+		default:
+			continue
+		}
+
+		sum, err := total.Add(payout.Amount)
+		if err != nil {
+			return nil, err
+		}
+		*total = sum
+	}
+
+	sort.Strings(currencies)
+	balances := make([]entity.PayoutBalance, 0, len(currencies))
+	for _, currency := range currencies {
+		balances = append(balances, *byCurrency[currency])
+	}
+	return balances, nil
+}
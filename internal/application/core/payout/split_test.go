@@ -0,0 +1,119 @@
+package payout
+
+import (
+	"testing"
+
+	"ecom-api/internal/application/core/types/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func usd(amount int64) entity.Money {
+	return entity.NewMoney(amount, "USD")
+}
+
+func TestCommissionRate(t *testing.T) {
+	rules := []*entity.CommissionRule{
+		{ID: "all", Rate: 12, IsActive: true},
+		{ID: "books", Category: "books", Rate: 8, IsActive: true},
+		{ID: "store", StoreID: "s1", Rate: 15, IsActive: true},
+		{ID: "store-books", StoreID: "s1", Category: "books", Rate: 5, IsActive: true},
+		{ID: "off", StoreID: "s2", Rate: 1, IsActive: false},
+	}
+
+	assert.Equal(t, 5.0, CommissionRate(rules, "s1", "Books", 10))
+	assert.Equal(t, 15.0, CommissionRate(rules, "s1", "toys", 10))
+	assert.Equal(t, 8.0, CommissionRate(rules, "s2", "books", 10))
+	assert.Equal(t, 12.0, CommissionRate(rules, "s2", "toys", 10))
+	assert.Equal(t, 10.0, CommissionRate(nil, "s2", "toys", 10))
+}
+
+func TestSplit(t *testing.T) {
+	items := []*entity.OrderItem{
+		{StoreID: "s2", Category: "toys", TotalPrice: usd(2200), Tax: usd(200)},
+		{StoreID: "s1", Category: "books", TotalPrice: usd(1000)},
+		{StoreID: "s1", Category: "toys", TotalPrice: usd(3000)},
+		{Category: "toys", TotalPrice: usd(5000)},
+	}
+	rules := []*entity.CommissionRule{
+		{StoreID: "s1", Category: "books", Rate: 5, IsActive: true},
+	}
+
+	shares, err := Split(items, rules, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []Share{
+		{StoreID: "s1", Gross: usd(4000), Commission: usd(350), Net: usd(3650)},
+		{StoreID: "s2", Gross: usd(2000), Commission: usd(200), Net: usd(1800)},
+	}, shares)
+
+	t.Run("platform-only orders have no shares", func(t *testing.T) {
+		shares, err := Split(items[3:], rules, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, shares)
+	})
+}
+
+func TestPaymentSplit(t *testing.T) {
+	order := entity.Order{ID: "order-1", Total: usd(6500)}
+	single := []*entity.OrderItem{
+		{StoreID: "s1", TotalPrice: usd(3000)},
+		{StoreID: "s1", TotalPrice: usd(3000)},
+	}
+	shares := []Share{{StoreID: "s1", Gross: usd(6000), Commission: usd(600), Net: usd(5400)}}
+
+	t.Run("one onboarded store is a destination charge", func(t *testing.T) {
+		split, err := PaymentSplit(order, single, shares, map[string]string{"s1": "acct_1"})
+		assert.NoError(t, err)
+		assert.Equal(t, entity.PaymentSplit{TransferGroup: "order-1", Destination: "acct_1", ApplicationFee: usd(1100)}, split)
+	})
+
+	t.Run("store credit is taken off the platform fee", func(t *testing.T) {
+		credited := order
+		credited.StoreCredit = usd(500)
+		split, err := PaymentSplit(credited, single, shares, map[string]string{"s1": "acct_1"})
+		assert.NoError(t, err)
+		assert.Equal(t, entity.PaymentSplit{TransferGroup: "order-1", Destination: "acct_1", ApplicationFee: usd(600)}, split)
+
+		// a charge short of the store's share cannot carry it
+		credited.StoreCredit = usd(2000)
+		split, err = PaymentSplit(credited, single, shares, map[string]string{"s1": "acct_1"})
+		assert.NoError(t, err)
+		assert.Equal(t, entity.PaymentSplit{TransferGroup: "order-1"}, split)
+	})
+
+	t.Run("a store without an account is paid by transfer", func(t *testing.T) {
+		split, err := PaymentSplit(order, single, shares, map[string]string{})
+		assert.NoError(t, err)
+		assert.Equal(t, entity.PaymentSplit{TransferGroup: "order-1"}, split)
+	})
+
+	t.Run("platform items keep the charge on the platform", func(t *testing.T) {
+		mixed := append(single, &entity.OrderItem{TotalPrice: usd(500)})
+		split, err := PaymentSplit(order, mixed, shares, map[string]string{"s1": "acct_1"})
+		assert.NoError(t, err)
+		assert.Equal(t, entity.PaymentSplit{TransferGroup: "order-1"}, split)
+	})
+
+	t.Run("platform-only orders are not split", func(t *testing.T) {
+		split, err := PaymentSplit(order, nil, nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, entity.PaymentSplit{}, split)
+	})
+}
+
+func TestBalances(t *testing.T) {
+	balances, err := Balances([]*entity.StorePayout{
+		{Currency: "USD", Amount: usd(1000), Status: entity.StorePayoutPending},
+		{Currency: "USD", Amount: usd(500), Status: entity.StorePayoutPending},
+		{Currency: "USD", Amount: usd(700), Status: entity.StorePayoutPaid},
+		{Currency: "EUR", Amount: entity.NewMoney(300, "EUR"), Status: entity.StorePayoutReversed},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, balances, 2)
+
+	assert.Equal(t, "EUR", balances[0].Currency)
+	assert.Equal(t, int64(300), balances[0].Reversed.Amount)
+	assert.Equal(t, usd(1500), balances[1].Pending)
+	assert.Equal(t, usd(700), balances[1].Paid)
+	assert.True(t, balances[1].Reversed.IsZero())
+}
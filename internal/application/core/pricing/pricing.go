@@ -0,0 +1,99 @@
+// Package pricing resolves what a product costs in the storefront currency,
+// from its price list when it has an entry for that currency and otherwise by
+// converting its base price at the exchange rate. It holds no state, price
+// lists and rates are read by the caller through rports.
+package pricing
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"ecom-api/internal/application/core/types/entity"
+)
+
+// rateScale is the precision rates are fixed to before converting, matching
+// the eight decimals they are stored with, so a conversion never depends on
+// float rounding.
+const rateScale = 100000000
+
+// Rates gives the amount of quote currency one unit of base currency buys.
+type Rates interface {
+	Rate(base, quote string) (float64, error)
+}
+
+// RateTable is a fixed set of rates keyed by "BASE/QUOTE". A pair missing
+// from the table is derived from its inverse when that one is present.
+type RateTable map[string]float64
+
+// Set records the rate of a currency pair.
+func (t RateTable) Set(base, quote string, rate float64) {
+	t[pair(base, quote)] = rate
+}
+
+func (t RateTable) Rate(base, quote string) (float64, error) {
+	if strings.EqualFold(base, quote) {
+		return 1, nil
+	}
+	if rate, ok := t[pair(base, quote)]; ok && rate > 0 {
+		return rate, nil
+	}
+	if rate, ok := t[pair(quote, base)]; ok && rate > 0 {
+		return 1 / rate, nil
+	}
+	return 0, fmt.Errorf("no exchange rate from %s to %s", strings.ToUpper(base), strings.ToUpper(quote))
+}
+
+func pair(base, quote string) string {
+	return strings.ToUpper(base) + "/" + strings.ToUpper(quote)
+}
+
+// Convert converts m into currency at rate, rounding half away from zero to
+// the minor unit of currency.
+func Convert(m entity.Money, rate float64, currency string) entity.Money {
+	currency = strings.ToUpper(currency)
+	if strings.EqualFold(m.Currency, currency) {
+		return m
+	}
+
+	num := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(int64(math.Round(rate*rateScale))))
+	num.Mul(num, pow10(entity.CurrencyExponent(currency)))
+	den := new(big.Int).Mul(big.NewInt(rateScale), pow10(entity.CurrencyExponent(m.Currency)))
+
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Abs(rem).Lsh(rem, 1).Cmp(den) >= 0 {
+		if num.Sign() < 0 {
+			quo.Sub(quo, big.NewInt(1))
+		} else {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+
+	return entity.NewMoney(quo.Int64(), currency)
+}
+
+func pow10(exponent int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil)
+}
+
+// Resolve returns the price of a product in currency: its price-list entry
+// for that currency, its base price when already in currency, or its base
+// price converted at the exchange rate.
+func Resolve(base entity.Money, prices []entity.Money, currency string, rates Rates) (entity.Money, error) {
+	for _, price := range prices {
+		if strings.EqualFold(price.Currency, currency) {
+			return price, nil
+		}
+	}
+	if strings.EqualFold(base.Currency, currency) {
+		return base, nil
+	}
+
+	rate, err := rates.Rate(base.Currency, currency)
+	if err != nil {
+		return entity.Money{}, err
+	}
+
+	return Convert(base, rate, currency), nil
+}
@@ -0,0 +1,76 @@
+package pricing
+
+import (
+	"testing"
+
+	"ecom-api/internal/application/core/types/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateTable(t *testing.T) {
+	rates := RateTable{}
+	rates.Set("eur", "usd", 1.25)
+
+	rate, err := rates.Rate("EUR", "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, 1.25, rate)
+
+	rate, err = rates.Rate("USD", "EUR")
+	assert.NoError(t, err)
+	assert.Equal(t, 0.8, rate)
+
+	rate, err = rates.Rate("usd", "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, rate)
+
+	_, err = rates.Rate("USD", "GBP")
+	assert.Error(t, err)
+}
+
+func TestConvert(t *testing.T) {
+	t.Run("rounds half away from zero to the target minor unit", func(t *testing.T) {
+		assert.Equal(t, entity.NewMoney(1234, "USD"), Convert(entity.NewMoney(987, "EUR"), 1.25, "usd"))
+		assert.Equal(t, entity.NewMoney(1, "USD"), Convert(entity.NewMoney(1, "EUR"), 0.5, "USD"))
+		assert.Equal(t, entity.NewMoney(-1, "USD"), Convert(entity.NewMoney(-1, "EUR"), 0.5, "USD"))
+	})
+
+	t.Run("accounts for currency exponents", func(t *testing.T) {
+		assert.Equal(t, entity.NewMoney(1500, "JPY"), Convert(entity.NewMoney(1000, "USD"), 150, "JPY"))
+		assert.Equal(t, entity.NewMoney(1000, "USD"), Convert(entity.NewMoney(1500, "JPY"), 1.0/150, "USD"))
+		assert.Equal(t, entity.NewMoney(3770, "BHD"), Convert(entity.NewMoney(1000, "USD"), 0.377, "BHD"))
+	})
+
+	t.Run("leaves the same currency untouched", func(t *testing.T) {
+		assert.Equal(t, entity.NewMoney(999, "USD"), Convert(entity.NewMoney(999, "USD"), 2, "usd"))
+	})
+}
+
+func TestResolve(t *testing.T) {
+	rates := RateTable{}
+	rates.Set("USD", "EUR", 0.9)
+	base := entity.NewMoney(1000, "USD")
+
+	t.Run("prefers the price list", func(t *testing.T) {
+		price, err := Resolve(base, []entity.Money{entity.NewMoney(950, "EUR")}, "EUR", rates)
+		assert.NoError(t, err)
+		assert.Equal(t, entity.NewMoney(950, "EUR"), price)
+	})
+
+	t.Run("uses the base price in its own currency", func(t *testing.T) {
+		price, err := Resolve(base, []entity.Money{entity.NewMoney(950, "EUR")}, "USD", rates)
+		assert.NoError(t, err)
+		assert.Equal(t, base, price)
+	})
+
+	t.Run("converts the base price otherwise", func(t *testing.T) {
+		price, err := Resolve(base, nil, "EUR", rates)
+		assert.NoError(t, err)
+		assert.Equal(t, entity.NewMoney(900, "EUR"), price)
+	})
+
+	t.Run("fails without a rate", func(t *testing.T) {
+		_, err := Resolve(base, nil, "GBP", rates)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,219 @@
+// Package promotion decides which promotions apply to a cart and how much
+// each one takes off every line. It holds no state, usage counters are read
+// and written by the caller through rports.PromotionStore.
+package promotion
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"ecom-api/internal/application/core/types/entity"
+)
+
+type Line struct {
+	ProductID string
+	Category  string
+	Quantity  int
+	UnitPrice entity.Money
+}
+
+func (l Line) Total() entity.Money {
+	return l.UnitPrice.Mul(l.Quantity)
+}
+
+type Result struct {
+	LineDiscounts [][]entity.AppliedDiscount // Discounts per line, same order as the input lines
+	Applied       []entity.AppliedDiscount   // Total discount per promotion
+	Discount      entity.Money               // Sum of every line discount
+	FreeShipping  bool                       // A free shipping promotion applies
+}
+
+// LineDiscount sums the discounts applied to the line at index i.
+func (r *Result) LineDiscount(i int) entity.Money {
+	total := entity.Money{Currency: r.Discount.Currency}
+	for _, discount := range r.LineDiscounts[i] {
+		total.Amount += discount.Amount.Amount
+	}
+	return total
+}
+
+// CheckEligibility reports why a promotion cannot be used for a cart with the
+// given subtotal, by a buyer who already redeemed it buyerRedemptions times.
+// It returns nil when the promotion can be used. A minimum spend is only
+// compared in its own currency, so one without a currency never qualifies.
+func CheckEligibility(p *entity.Promotion, now time.Time, subtotal entity.Money, buyerRedemptions int) error {
+	switch {
+	case !p.IsActive:
+		return fmt.Errorf("promotion %s is not active", p.Name)
+	case p.StartsAt != nil && now.Before(*p.StartsAt):
+		return fmt.Errorf("promotion %s has not started yet", p.Name)
+	case p.EndsAt != nil && !now.Before(*p.EndsAt):
+		return fmt.Errorf("promotion %s has expired", p.Name)
+	case p.UsageLimit > 0 && p.UsageCount >= p.UsageLimit:
+		return fmt.Errorf("promotion %s has been fully redeemed", p.Name)
+	case p.PerUserLimit > 0 && buyerRedemptions >= p.PerUserLimit:
+		return fmt.Errorf("promotion %s has already been used the maximum number of times", p.Name)
+	case p.MinSpend.Amount > 0 && p.Currency == "":
+		return fmt.Errorf("promotion %s has a minimum spend without a currency", p.Name)
+	case p.Currency != "" && !strings.EqualFold(p.Currency, subtotal.Currency):
+		return fmt.Errorf("promotion %s is not valid for %s orders", p.Name, subtotal.Currency)
+	case subtotal.Amount < p.MinSpend.Amount:
+		return fmt.Errorf("promotion %s requires a minimum spend of %s", p.Name, p.MinSpend)
+	}
+	return nil
+}
+
+// Apply computes the discounts of the given promotions in order. Every
+// promotion only sees what is left of a line after the previous ones, so the
+// discount of a line never exceeds its total. All lines share one currency.
+func Apply(promotions []*entity.Promotion, lines []Line) *Result {
+	result := &Result{LineDiscounts: make([][]entity.AppliedDiscount, len(lines))}
+
+	currency := ""
+	remaining := make([]int64, len(lines))
+	for i, line := range lines {
+		remaining[i] = line.Total().Amount
+		currency = line.UnitPrice.Currency
+	}
+	result.Discount = entity.Money{Currency: currency}
+
+	for _, p := range promotions {
+		var amounts []int64
+
+		switch p.Type {
+		case entity.PromotionTypePercentage:
+			amounts = percentageOff(p, lines, remaining)
+		case entity.PromotionTypeFixedAmount:
+			amounts = fixedAmountOff(p, lines, remaining)
+		case entity.PromotionTypeBuyXGetY:
+			amounts = buyXGetYOff(p, lines, remaining)
+		case entity.PromotionTypeFreeShipping:
+			if len(inScope(p, lines)) > 0 {
+				result.FreeShipping = true
+				result.Applied = append(result.Applied, applied(p, entity.Money{Currency: currency}))
+			}
+			continue
+		default:
+			continue
+		}
+
+		var total int64
+		for i, amount := range amounts {
+			if amount > remaining[i] {
+				amount = remaining[i]
+			}
+			if amount <= 0 {
+				continue
+			}
+			remaining[i] -= amount
+			result.LineDiscounts[i] = append(result.LineDiscounts[i], applied(p, entity.NewMoney(amount, currency)))
+			total += amount
+		}
+
+		if total > 0 {
+			result.Applied = append(result.Applied, applied(p, entity.NewMoney(total, currency)))
+			result.Discount.Amount += total
+		}
+	}
+
+	return result
+}
+
+func percentageOff(p *entity.Promotion, lines []Line, remaining []int64) []int64 {
+	amounts := make([]int64, len(lines))
+	for _, i := range inScope(p, lines) {
+		amounts[i] = percentOf(remaining[i], p.Value)
+	}
+	return amounts
+}
+
+// fixedAmountOff spreads the amount over the in-scope lines in proportion to
+// what is left of them, without losing or adding a minor unit.
+func fixedAmountOff(p *entity.Promotion, lines []Line, remaining []int64) []int64 {
+	amounts := make([]int64, len(lines))
+	scope := inScope(p, lines)
+
+	var base int64
+	weights := make([]int64, len(scope))
+	for n, i := range scope {
+		weights[n] = remaining[i]
+		base += remaining[i]
+	}
+	if base <= 0 {
+		return amounts
+	}
+
+	total := p.Amount
+	if total.Amount > base {
+		total.Amount = base
+	}
+	for n, part := range total.Allocate(weights) {
+		amounts[scope[n]] = part.Amount
+	}
+	return amounts
+}
+
+// buyXGetYOff discounts GetQuantity units for every BuyQuantity+GetQuantity
+// units of the same product. Value is the percentage off those units, a zero
+// value makes them free.
+func buyXGetYOff(p *entity.Promotion, lines []Line, remaining []int64) []int64 {
+	amounts := make([]int64, len(lines))
+	if p.BuyQuantity <= 0 || p.GetQuantity <= 0 {
+		return amounts
+	}
+
+	percentage := p.Value
+	if percentage <= 0 {
+		percentage = 100
+	}
+
+	for _, i := range inScope(p, lines) {
+		discountedUnits := (lines[i].Quantity / (p.BuyQuantity + p.GetQuantity)) * p.GetQuantity
+		amounts[i] = lines[i].UnitPrice.Mul(discountedUnits).Percent(percentage).Amount
+	}
+	return amounts
+}
+
+// inScope returns the indexes of the lines the promotion is restricted to.
+// A promotion without products or categories applies to every line.
+func inScope(p *entity.Promotion, lines []Line) []int {
+	scope := []int{}
+	for i, line := range lines {
+		if len(p.ProductIDs) == 0 && len(p.Categories) == 0 {
+			scope = append(scope, i)
+			continue
+		}
+		if contains(p.ProductIDs, line.ProductID) || containsFold(p.Categories, line.Category) {
+			scope = append(scope, i)
+		}
+	}
+	return scope
+}
+
+func applied(p *entity.Promotion, amount entity.Money) entity.AppliedDiscount {
+	return entity.AppliedDiscount{PromotionID: p.ID, Code: p.Code, Type: p.Type, Amount: amount}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func percentOf(amount int64, percent float64) int64 {
+	return int64(math.Round(float64(amount) * percent / 100))
+}
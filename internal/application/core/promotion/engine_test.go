@@ -0,0 +1,93 @@
+package promotion
+
+import (
+	"testing"
+	"time"
+
+	"ecom-api/internal/application/core/types/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func usd(amount float64) entity.Money {
+	return entity.MoneyFromMajor(amount, "USD")
+}
+
+func TestApply(t *testing.T) {
+	lines := []Line{
+		{ProductID: "p1", Category: "books", Quantity: 2, UnitPrice: usd(10)},
+		{ProductID: "p2", Category: "games", Quantity: 1, UnitPrice: usd(30)},
+	}
+
+	t.Run("percentage off scoped to a category", func(t *testing.T) {
+		result := Apply([]*entity.Promotion{{ID: "a", Type: entity.PromotionTypePercentage, Value: 10, Categories: []string{"Books"}}}, lines)
+
+		assert.Equal(t, usd(2), result.LineDiscount(0))
+		assert.Equal(t, usd(0), result.LineDiscount(1))
+		assert.Equal(t, usd(2), result.Discount)
+	})
+
+	t.Run("fixed amount is spread over the lines", func(t *testing.T) {
+		result := Apply([]*entity.Promotion{{ID: "b", Type: entity.PromotionTypeFixedAmount, Amount: usd(10), Currency: "USD"}}, lines)
+
+		assert.Equal(t, usd(4), result.LineDiscount(0))
+		assert.Equal(t, usd(6), result.LineDiscount(1))
+		assert.Equal(t, usd(10), result.Discount)
+	})
+
+	t.Run("fixed amount never exceeds the cart", func(t *testing.T) {
+		result := Apply([]*entity.Promotion{{ID: "c", Type: entity.PromotionTypeFixedAmount, Amount: usd(100), Currency: "USD"}}, lines)
+
+		assert.Equal(t, usd(50), result.Discount)
+	})
+
+	t.Run("buy one get one free", func(t *testing.T) {
+		result := Apply([]*entity.Promotion{{ID: "d", Type: entity.PromotionTypeBuyXGetY, BuyQuantity: 1, GetQuantity: 1, ProductIDs: []string{"p1"}}}, lines)
+
+		assert.Equal(t, usd(10), result.LineDiscount(0))
+		assert.Equal(t, usd(10), result.Discount)
+	})
+
+	t.Run("stacked promotions apply to what is left", func(t *testing.T) {
+		result := Apply([]*entity.Promotion{
+			{ID: "e", Type: entity.PromotionTypeBuyXGetY, BuyQuantity: 1, GetQuantity: 1, ProductIDs: []string{"p1"}},
+			{ID: "f", Type: entity.PromotionTypePercentage, Value: 50},
+		}, lines)
+
+		assert.Equal(t, usd(15), result.LineDiscount(0))
+		assert.Equal(t, usd(15), result.LineDiscount(1))
+		assert.Len(t, result.Applied, 2)
+	})
+
+	t.Run("free shipping", func(t *testing.T) {
+		result := Apply([]*entity.Promotion{{ID: "g", Type: entity.PromotionTypeFreeShipping}}, lines)
+
+		assert.True(t, result.FreeShipping)
+		assert.Equal(t, usd(0), result.Discount)
+	})
+}
+
+func TestCheckEligibility(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	valid := &entity.Promotion{Name: "valid", IsActive: true, StartsAt: &past, EndsAt: &future, MinSpend: usd(20), Currency: "USD"}
+	assert.NoError(t, CheckEligibility(valid, now, usd(25), 0))
+	assert.Error(t, CheckEligibility(valid, now, usd(15), 0), "expected minimum spend to be enforced")
+
+	notStarted := &entity.Promotion{Name: "soon", IsActive: true, StartsAt: &future}
+	assert.Error(t, CheckEligibility(notStarted, now, usd(25), 0))
+
+	exhausted := &entity.Promotion{Name: "gone", IsActive: true, UsageLimit: 5, UsageCount: 5}
+	assert.Error(t, CheckEligibility(exhausted, now, usd(25), 0))
+
+	perUser := &entity.Promotion{Name: "once", IsActive: true, PerUserLimit: 1}
+	assert.Error(t, CheckEligibility(perUser, now, usd(25), 1))
+
+	otherCurrency := &entity.Promotion{Name: "eur", IsActive: true, Currency: "EUR"}
+	assert.Error(t, CheckEligibility(otherCurrency, now, usd(25), 0))
+
+	noCurrency := &entity.Promotion{Name: "any", IsActive: true, MinSpend: entity.NewMoney(2000, "")}
+	assert.Error(t, CheckEligibility(noCurrency, now, entity.MoneyFromMajor(5000, "JPY"), 0), "expected a minimum spend without a currency to be refused")
+}
@@ -0,0 +1,142 @@
+// Package shipping picks the zone of a destination and prices the methods of
+// that zone for a parcel. It holds no state, zones and methods are read by the
+// caller through rports.ShippingStore.
+package shipping
+
+import (
+	"math"
+	"strings"
+
+	"ecom-api/internal/application/core/types/entity"
+)
+
+// AnyCountry in a zone's countries makes it the fallback for every destination.
+const AnyCountry = "*"
+
+// Item is one cart line as far as shipping is concerned. Weight is in
+// kilograms and dimensions in centimetres, all per unit.
+type Item struct {
+	Quantity int
+	Weight   float64
+	Length   float64
+	Width    float64
+	Height   float64
+}
+
+// ChargeableWeight sums, for every item, the larger of its actual and
+// volumetric weight. A zero divisor ignores the dimensions.
+func ChargeableWeight(items []Item, volumetricDivisor float64) float64 {
+	var total float64
+	for _, item := range items {
+		weight := item.Weight
+		if volumetricDivisor > 0 {
+			weight = math.Max(weight, item.Length*item.Width*item.Height/volumetricDivisor)
+		}
+		total += weight * float64(item.Quantity)
+	}
+	return total
+}
+
+// MatchZone returns the zone that covers address. A zone limited to regions
+// wins over a zone covering the whole country, which wins over the fallback
+// zone. Returns nil when no zone ships there.
+func MatchZone(zones []*entity.ShippingZone, address entity.Address) *entity.ShippingZone {
+	var match *entity.ShippingZone
+	best := 0
+
+	for _, zone := range zones {
+		if !zone.IsActive {
+			continue
+		}
+
+		score := 0
+		switch {
+		case containsFold(zone.Countries, address.Country) && len(zone.Regions) > 0:
+			if containsFold(zone.Regions, address.State) {
+				score = 3
+			}
+		case containsFold(zone.Countries, address.Country):
+			score = 2
+		case containsFold(zone.Countries, AnyCountry):
+			score = 1
+		}
+
+		if score > best {
+			best = score
+			match = zone
+		}
+	}
+
+	return match
+}
+
+// Cost prices a method for the items of an order worth amount after
+// discounts. It reports false when the rate table of the method does not
+// cover the parcel.
+func Cost(method *entity.ShippingMethod, items []Item, amount entity.Money) (entity.Money, bool) {
+	var cost entity.Money
+	var ok bool
+
+	switch method.RateType {
+	case entity.ShippingRateFlat:
+		cost, ok = method.FlatRate, true
+	case entity.ShippingRateWeight:
+		cost, ok = tierCost(method.Tiers, ChargeableWeight(items, method.VolumetricDivisor))
+	case entity.ShippingRatePrice:
+		cost, ok = tierCost(method.Tiers, amount.Major())
+	}
+
+	return cost, ok
+}
+
+// Quote prices every active method of the amount's currency that covers the
+// parcel. Shipping is free when the amount reaches the method's threshold or
+// when freeShipping is set by a promotion.
+func Quote(methods []*entity.ShippingMethod, items []Item, amount entity.Money, freeShipping bool) []entity.ShippingQuote {
+	quotes := []entity.ShippingQuote{}
+
+	for _, method := range methods {
+		if !method.IsActive || !strings.EqualFold(method.Currency, amount.Currency) {
+			continue
+		}
+
+		cost, ok := Cost(method, items, amount)
+		if !ok {
+			continue
+		}
+
+		free := freeShipping || (method.FreeAbove.Amount > 0 && amount.Amount >= method.FreeAbove.Amount)
+		if free {
+			cost.Amount = 0
+		}
+
+		quotes = append(quotes, entity.ShippingQuote{
+			MethodID:        method.ID,
+			Name:            method.Name,
+			Cost:            cost,
+			Free:            free,
+			MinDeliveryDays: method.MinDeliveryDays,
+			MaxDeliveryDays: method.MaxDeliveryDays,
+		})
+	}
+
+	return quotes
+}
+
+func tierCost(tiers []entity.ShippingRateTier, value float64) (entity.Money, bool) {
+	for _, tier := range tiers {
+		if value >= tier.Min && (tier.Max == 0 || value < tier.Max) {
+			return tier.Cost, true
+		}
+	}
+	return entity.Money{}, false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
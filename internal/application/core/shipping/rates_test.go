@@ -0,0 +1,81 @@
+package shipping
+
+import (
+	"testing"
+
+	"ecom-api/internal/application/core/types/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchZone(t *testing.T) {
+	zones := []*entity.ShippingZone{
+		{ID: "world", Countries: []string{AnyCountry}, IsActive: true},
+		{ID: "us", Countries: []string{"US"}, IsActive: true},
+		{ID: "west", Countries: []string{"US"}, Regions: []string{"CA", "OR"}, IsActive: true},
+		{ID: "closed", Countries: []string{"NZ"}, IsActive: false},
+	}
+
+	assert.Equal(t, "west", MatchZone(zones, entity.Address{Country: "us", State: "ca"}).ID)
+	assert.Equal(t, "us", MatchZone(zones, entity.Address{Country: "US", State: "NY"}).ID)
+	assert.Equal(t, "world", MatchZone(zones, entity.Address{Country: "NZ"}).ID)
+	assert.Nil(t, MatchZone(zones[1:], entity.Address{Country: "FR"}))
+}
+
+func usd(amount float64) entity.Money {
+	return entity.MoneyFromMajor(amount, "USD")
+}
+
+func TestQuote(t *testing.T) {
+	items := []Item{
+		{Quantity: 2, Weight: 1.5},
+		{Quantity: 1, Weight: 0.2, Length: 40, Width: 30, Height: 20},
+	}
+	methods := []*entity.ShippingMethod{
+		{ID: "flat", RateType: entity.ShippingRateFlat, FlatRate: usd(4.99), FreeAbove: usd(100), Currency: "USD", IsActive: true},
+		{ID: "weight", RateType: entity.ShippingRateWeight, VolumetricDivisor: 5000, Currency: "USD", IsActive: true, Tiers: []entity.ShippingRateTier{
+			{Min: 0, Max: 5, Cost: usd(6)},
+			{Min: 5, Max: 10, Cost: usd(9)},
+		}},
+		{ID: "price", RateType: entity.ShippingRatePrice, Currency: "USD", IsActive: true, Tiers: []entity.ShippingRateTier{
+			{Min: 0, Max: 50, Cost: usd(8)},
+			{Min: 50, Cost: usd(3)},
+		}},
+		{ID: "euro", RateType: entity.ShippingRateFlat, FlatRate: entity.MoneyFromMajor(5, "EUR"), Currency: "EUR", IsActive: true},
+		{ID: "off", RateType: entity.ShippingRateFlat, FlatRate: usd(1), Currency: "USD", IsActive: false},
+	}
+
+	t.Run("volumetric weight counts when larger", func(t *testing.T) {
+		assert.InDelta(t, 7.8, ChargeableWeight(items, 5000), 0.0001)
+		assert.InDelta(t, 3.2, ChargeableWeight(items, 0), 0.0001)
+	})
+
+	t.Run("prices every method of the currency", func(t *testing.T) {
+		quotes := Quote(methods, items, usd(40), false)
+
+		assert.Len(t, quotes, 3)
+		assert.Equal(t, usd(4.99), quotes[0].Cost)
+		assert.Equal(t, usd(9), quotes[1].Cost)
+		assert.Equal(t, usd(8), quotes[2].Cost)
+	})
+
+	t.Run("free above the threshold or with a promotion", func(t *testing.T) {
+		quotes := Quote(methods, items, usd(120), false)
+		assert.True(t, quotes[0].Free)
+		assert.Equal(t, usd(0), quotes[0].Cost)
+		assert.Equal(t, usd(3), quotes[2].Cost)
+
+		quotes = Quote(methods, items, usd(40), true)
+		for _, quote := range quotes {
+			assert.Equal(t, usd(0), quote.Cost)
+		}
+	})
+
+	t.Run("skips methods whose table does not cover the parcel", func(t *testing.T) {
+		heavy := []Item{{Quantity: 1, Weight: 12}}
+
+		quotes := Quote(methods, heavy, usd(40), false)
+
+		assert.Len(t, quotes, 2)
+	})
+}
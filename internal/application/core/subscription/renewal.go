@@ -0,0 +1,95 @@
+// Package subscription works out when a subscription renews and prices the
+// order of every paid renewal. It holds no state, subscriptions are read and
+// written by the caller through rports.SubscriptionStore.
+package subscription
+
+import (
+	"fmt"
+	"time"
+
+	"ecom-api/internal/application/core/types/entity"
+)
+
+// Billing intervals, named the way Stripe names them.
+const (
+	Day   = "day"
+	Week  = "week"
+	Month = "month"
+	Year  = "year"
+)
+
+// NextRenewal returns when a subscription renewing every count intervals
+// renews after from. A count below one is one interval.
+func NextRenewal(from time.Time, interval string, count int) (time.Time, error) {
+	if count < 1 {
+		count = 1
+	}
+	switch interval {
+	case Day:
+		return from.AddDate(0, 0, count), nil
+	case Week:
+		return from.AddDate(0, 0, 7*count), nil
+	case Month:
+		return from.AddDate(0, count, 0), nil
+	case Year:
+		return from.AddDate(count, 0, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("unknown billing interval %q", interval)
+}
+
+// Amount is what a subscription charges every renewal.
+func Amount(sub entity.Subscription) entity.Money {
+	return sub.UnitPrice.Mul(sub.Quantity)
+}
+
+// RenewalOrder returns the order of a renewal paid with paid, and its only
+// item. The renewal is priced at the subscription. The gateway may charge
+// less, for a coupon, or more, for tax, and the difference is recorded as a
+// discount or as tax so the order adds up to what was paid. Statuses are left
+// to the caller.
+func RenewalOrder(sub entity.Subscription, paid entity.Money, invoiceID string) (entity.Order, entity.OrderItem, error) {
+	subtotal := Amount(sub)
+	zero := entity.NewMoney(0, subtotal.Currency)
+
+	difference, err := paid.Sub(subtotal)
+	if err != nil {
+		return entity.Order{}, entity.OrderItem{}, err
+	}
+	discount, tax := zero, zero
+	if difference.IsNegative() {
+		discount = entity.NewMoney(-difference.Amount, subtotal.Currency)
+	} else {
+		tax = difference
+	}
+
+	order := entity.Order{
+		UserID:          sub.UserID,
+		Total:           paid,
+		Subtotal:        subtotal,
+		PaymentMethod:   "Subscription",
+		Address:         sub.ShippingAddress.String(),
+		Currency:        subtotal.Currency,
+		ShippingAddress: sub.ShippingAddress,
+		BillingAddress:  sub.ShippingAddress,
+		Discount:        discount,
+		Tax:             tax,
+		ShippingCost:    zero,
+		SubscriptionID:  sub.ID,
+		InvoiceID:       invoiceID,
+	}
+	item := entity.OrderItem{
+		ProductID:   sub.ProductID,
+		ProductName: sub.ProductName,
+		Quantity:    sub.Quantity,
+		Price:       sub.UnitPrice,
+		TotalPrice:  paid,
+		Subtotal:    subtotal,
+		Currency:    subtotal.Currency,
+		Discount:    discount,
+		Tax:         tax,
+		StoreID:     sub.StoreID,
+		Category:    sub.Category,
+	}
+
+	return order, item, nil
+}
@@ -0,0 +1,82 @@
+package subscription
+
+import (
+	"testing"
+	"time"
+
+	"ecom-api/internal/application/core/types/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func usd(amount int64) entity.Money {
+	return entity.NewMoney(amount, "USD")
+}
+
+func TestNextRenewal(t *testing.T) {
+	from := time.Date(2025, time.January, 15, 10, 0, 0, 0, time.UTC)
+
+	next, err := NextRenewal(from, Day, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, time.January, 18, 10, 0, 0, 0, time.UTC), next)
+
+	next, err = NextRenewal(from, Week, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, time.January, 29, 10, 0, 0, 0, time.UTC), next)
+
+	next, err = NextRenewal(from, Month, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, time.February, 15, 10, 0, 0, 0, time.UTC), next, "no count is one interval")
+
+	next, err = NextRenewal(from, Year, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2026, time.January, 15, 10, 0, 0, 0, time.UTC), next)
+
+	_, err = NextRenewal(from, "fortnight", 1)
+	assert.Error(t, err)
+}
+
+func TestRenewalOrder(t *testing.T) {
+	sub := entity.Subscription{
+		ID:              "sub-1",
+		UserID:          "user-1",
+		ProductID:       "product-1",
+		ProductName:     "Coffee beans",
+		StoreID:         "store-1",
+		Category:        "grocery",
+		Quantity:        2,
+		UnitPrice:       usd(1250),
+		Currency:        "USD",
+		ShippingAddress: entity.OrderAddress{FullName: "Ada Lovelace"},
+	}
+
+	order, item, err := RenewalOrder(sub, usd(2500), "in_1")
+	assert.NoError(t, err)
+	assert.Equal(t, usd(2500), order.Subtotal)
+	assert.Equal(t, usd(2500), order.Total)
+	assert.True(t, order.Discount.IsZero())
+	assert.True(t, order.Tax.IsZero())
+	assert.Equal(t, "sub-1", order.SubscriptionID)
+	assert.Equal(t, "in_1", order.InvoiceID)
+	assert.Equal(t, "user-1", order.UserID)
+	assert.Equal(t, sub.ShippingAddress, order.ShippingAddress)
+	assert.Equal(t, "store-1", item.StoreID)
+	assert.Equal(t, 2, item.Quantity)
+	assert.Equal(t, usd(1250), item.Price)
+	assert.Equal(t, usd(2500), item.TotalPrice)
+
+	order, item, err = RenewalOrder(sub, usd(2000), "in_2")
+	assert.NoError(t, err)
+	assert.Equal(t, usd(500), order.Discount, "a coupon at the gateway is a discount")
+	assert.Equal(t, usd(500), item.Discount)
+	assert.Equal(t, usd(2000), order.Total)
+
+	order, item, err = RenewalOrder(sub, usd(2750), "in_3")
+	assert.NoError(t, err)
+	assert.Equal(t, usd(250), order.Tax, "what the gateway adds is tax")
+	assert.Equal(t, usd(250), item.Tax)
+	assert.True(t, order.Discount.IsZero())
+
+	_, _, err = RenewalOrder(sub, entity.NewMoney(2500, "EUR"), "in_4")
+	assert.Error(t, err, "paid in another currency")
+}
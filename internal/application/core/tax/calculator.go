@@ -0,0 +1,181 @@
+// Package tax computes the tax of an order from a table of rates. It holds no
+// state, the rates are read by the caller through rports.TaxStore.
+package tax
+
+import (
+	"math"
+	"strings"
+
+	"ecom-api/internal/application/core/types/entity"
+)
+
+const (
+	PricingExclusive = "exclusive" // Tax is added on top of the prices
+	PricingInclusive = "inclusive" // Prices already contain the tax
+
+	RoundPerLine  = "line"  // Every line is rounded on its own, the order total is their sum
+	RoundPerOrder = "order" // The order total is rounded once and spread over the lines
+)
+
+// MatchRates returns the rates that apply to a product of the given tax
+// category shipped to address. Rates sharing a name replace each other, the
+// most specific one wins: region and category, then region, then category,
+// then the country-wide rate.
+func MatchRates(rates []*entity.TaxRate, address entity.Address, category string) []*entity.TaxRate {
+	matched := []*entity.TaxRate{}
+	scores := make(map[string]int)
+	positions := make(map[string]int)
+
+	for _, rate := range rates {
+		switch {
+		case !rate.IsActive:
+			continue
+		case !strings.EqualFold(rate.Country, address.Country):
+			continue
+		case rate.Region != "" && !strings.EqualFold(rate.Region, address.State):
+			continue
+		case rate.TaxCategory != "" && !strings.EqualFold(rate.TaxCategory, category):
+			continue
+		}
+
+		score := 0
+		if rate.Region != "" {
+			score += 2
+		}
+		if rate.TaxCategory != "" {
+			score++
+		}
+
+		name := strings.ToLower(rate.Name)
+		position, seen := positions[name]
+		switch {
+		case !seen:
+			positions[name] = len(matched)
+			scores[name] = score
+			matched = append(matched, rate)
+		case score > scores[name]:
+			scores[name] = score
+			matched[position] = rate
+		}
+	}
+
+	return matched
+}
+
+// Calculate computes the tax of every line of the request and of the whole
+// order. With inclusive pricing the tax is taken out of the line amounts
+// rather than added to them.
+func Calculate(rates []*entity.TaxRate, request entity.TaxRequest, pricing, rounding string) *entity.TaxResult {
+	currency := strings.ToUpper(request.Currency)
+	result := &entity.TaxResult{
+		Lines:     make([]entity.LineTax, len(request.Lines)),
+		Tax:       entity.Money{Currency: currency},
+		Breakdown: []entity.TaxComponent{},
+		Inclusive: pricing == PricingInclusive,
+	}
+
+	// exact holds every tax of every line in unrounded minor units
+	exact := make([][]share, len(request.Lines))
+	for i, line := range request.Lines {
+		matched := MatchRates(rates, request.Address, line.TaxCategory)
+
+		base := float64(line.Amount.Amount)
+		if result.Inclusive {
+			var combined float64
+			for _, rate := range matched {
+				combined += rate.Rate
+			}
+			base = base / (1 + combined/100)
+		}
+
+		for _, rate := range matched {
+			exact[i] = append(exact[i], share{name: rate.Name, rate: rate.Rate, amount: base * rate.Rate / 100})
+		}
+	}
+
+	rounded := make([][]int64, len(exact))
+	if rounding == RoundPerOrder {
+		rounded = roundPerOrder(exact)
+	} else {
+		for i, shares := range exact {
+			for _, s := range shares {
+				rounded[i] = append(rounded[i], int64(math.Round(s.amount)))
+			}
+		}
+	}
+
+	totals := make(map[share]int)
+	for i, shares := range exact {
+		lineTax := entity.LineTax{Tax: entity.Money{Currency: currency}, Breakdown: []entity.TaxComponent{}}
+		for j, s := range shares {
+			amount := rounded[i][j]
+			if amount == 0 {
+				continue
+			}
+			lineTax.Tax.Amount += amount
+			lineTax.Breakdown = append(lineTax.Breakdown, entity.TaxComponent{Name: s.name, Rate: s.rate, Amount: entity.NewMoney(amount, currency)})
+
+			key := share{name: s.name, rate: s.rate}
+			position, ok := totals[key]
+			if !ok {
+				position = len(result.Breakdown)
+				totals[key] = position
+				result.Breakdown = append(result.Breakdown, entity.TaxComponent{Name: s.name, Rate: s.rate, Amount: entity.Money{Currency: currency}})
+			}
+			result.Breakdown[position].Amount.Amount += amount
+		}
+		result.Lines[i] = lineTax
+		result.Tax.Amount += lineTax.Tax.Amount
+	}
+
+	return result
+}
+
+// share is one tax of one line before rounding.
+type share struct {
+	name   string
+	rate   float64
+	amount float64
+}
+
+// roundPerOrder rounds the total of every tax once and hands the rounded
+// minor units out to the lines, the line with the largest share absorbing the
+// remainder.
+func roundPerOrder(exact [][]share) [][]int64 {
+	type position struct{ line, index int }
+	groups := make(map[share][]position)
+	order := []share{}
+
+	rounded := make([][]int64, len(exact))
+	for i, shares := range exact {
+		rounded[i] = make([]int64, len(shares))
+		for j, s := range shares {
+			key := share{name: s.name, rate: s.rate}
+			if _, ok := groups[key]; !ok {
+				order = append(order, key)
+			}
+			groups[key] = append(groups[key], position{i, j})
+		}
+	}
+
+	for _, key := range order {
+		positions := groups[key]
+
+		var total float64
+		var sum int64
+		largest := positions[0]
+		for _, p := range positions {
+			amount := exact[p.line][p.index].amount
+			total += amount
+			if amount > exact[largest.line][largest.index].amount {
+				largest = p
+			}
+			rounded[p.line][p.index] = int64(math.Round(amount))
+			sum += rounded[p.line][p.index]
+		}
+
+		rounded[largest.line][largest.index] += int64(math.Round(total)) - sum
+	}
+
+	return rounded
+}
@@ -0,0 +1,62 @@
+package tax
+
+import (
+	"testing"
+
+	"ecom-api/internal/application/core/types/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func usd(amount float64) entity.Money {
+	return entity.MoneyFromMajor(amount, "USD")
+}
+
+func TestCalculate(t *testing.T) {
+	rates := []*entity.TaxRate{
+		{Name: "VAT", Country: "GB", Rate: 20, IsActive: true},
+		{Name: "VAT", Country: "GB", TaxCategory: "children", Rate: 5, IsActive: true},
+		{Name: "Sales tax", Country: "US", Region: "CA", Rate: 7.25, IsActive: true},
+		{Name: "Sales tax", Country: "US", Region: "NY", Rate: 4, IsActive: false},
+	}
+	gb := entity.Address{Country: "gb"}
+
+	t.Run("category rate replaces the country rate", func(t *testing.T) {
+		result := Calculate(rates, entity.TaxRequest{Address: gb, Currency: "USD", Lines: []entity.TaxLine{{Amount: usd(100)}, {TaxCategory: "Children", Amount: usd(50)}}}, PricingExclusive, RoundPerLine)
+
+		assert.Equal(t, usd(20), result.Lines[0].Tax)
+		assert.Equal(t, usd(2.5), result.Lines[1].Tax)
+		assert.Equal(t, usd(22.5), result.Tax)
+		assert.Len(t, result.Breakdown, 2)
+	})
+
+	t.Run("inclusive prices contain the tax", func(t *testing.T) {
+		result := Calculate(rates, entity.TaxRequest{Address: gb, Currency: "USD", Lines: []entity.TaxLine{{Amount: usd(120)}}}, PricingInclusive, RoundPerLine)
+
+		assert.True(t, result.Inclusive)
+		assert.Equal(t, usd(20), result.Tax)
+	})
+
+	t.Run("region rates only apply to their region", func(t *testing.T) {
+		lines := []entity.TaxLine{{Amount: usd(100)}}
+
+		ca := Calculate(rates, entity.TaxRequest{Address: entity.Address{Country: "US", State: "ca"}, Currency: "USD", Lines: lines}, PricingExclusive, RoundPerLine)
+		ny := Calculate(rates, entity.TaxRequest{Address: entity.Address{Country: "US", State: "NY"}, Currency: "USD", Lines: lines}, PricingExclusive, RoundPerLine)
+
+		assert.Equal(t, usd(7.25), ca.Tax)
+		assert.Equal(t, usd(0), ny.Tax)
+		assert.Empty(t, ny.Breakdown)
+	})
+
+	t.Run("rounding per line or per order", func(t *testing.T) {
+		tenPercent := []*entity.TaxRate{{Name: "GST", Country: "AU", Rate: 10, IsActive: true}}
+		request := entity.TaxRequest{Address: entity.Address{Country: "AU"}, Currency: "USD", Lines: []entity.TaxLine{{Amount: usd(0.33)}, {Amount: usd(0.33)}, {Amount: usd(0.33)}}}
+
+		perLine := Calculate(tenPercent, request, PricingExclusive, RoundPerLine)
+		perOrder := Calculate(tenPercent, request, PricingExclusive, RoundPerOrder)
+
+		assert.Equal(t, usd(0.09), perLine.Tax)
+		assert.Equal(t, usd(0.1), perOrder.Tax)
+		assert.Equal(t, usd(0.1), perOrder.Breakdown[0].Amount)
+	})
+}
@@ -0,0 +1,58 @@
+package entity
+
+import (
+	"strings"
+	"time"
+)
+
+type Address struct {
+	City       string `json:"city,omitempty" validate:"omitempty"`
+	Country    string `json:"country,omitempty" validate:"omitempty"`
+	Line1      string `json:"line1,omitempty" validate:"omitempty"`
+	Line2      string `json:"line2,omitempty" validate:"omitempty"`
+	PostalCode string `json:"postal_code,omitempty" validate:"omitempty,numeric"`
+	State      string `json:"state,omitempty" validate:"omitempty"`
+}
+
+// String renders the address on a single line, skipping empty parts.
+func (a Address) String() string {
+	parts := []string{}
+	for _, part := range []string{a.Line1, a.Line2, a.City, a.State, a.PostalCode, a.Country} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+type UserAddress struct {
+	ID                string    `json:"id"`                // Unique identifier for the address
+	UserID            string    `json:"userId"`            // Owner of the address
+	Label             string    `json:"label"`             // User facing label (e.g., "Home", "Office")
+	FullName          string    `json:"fullName"`          // Recipient name
+	Phone             string    `json:"phone"`             // Recipient phone number (E.164)
+	Address           Address   `json:"address"`           // Structured address fields
+	IsDefaultShipping bool      `json:"isDefaultShipping"` // Used for shipping when checkout does not specify one
+	IsDefaultBilling  bool      `json:"isDefaultBilling"`  // Used for billing when checkout does not specify one
+	CreatedAt         time.Time `json:"createdAt"`         // Timestamp for when the address was created
+	UpdatedAt         time.Time `json:"updatedAt"`         // Timestamp for when the address was last updated
+}
+
+// OrderAddress is the snapshot of an address stored on an order, so later
+// edits to the address book do not rewrite order history.
+type OrderAddress struct {
+	FullName string  `json:"fullName"`
+	Phone    string  `json:"phone"`
+	Address  Address `json:"address"`
+}
+
+func (a OrderAddress) String() string {
+	if a.FullName == "" {
+		return a.Address.String()
+	}
+	return a.FullName + ", " + a.Address.String()
+}
+
+func (a UserAddress) Snapshot() OrderAddress {
+	return OrderAddress{FullName: a.FullName, Phone: a.Phone, Address: a.Address}
+}
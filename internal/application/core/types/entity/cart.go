@@ -0,0 +1,50 @@
+package entity
+
+import (
+	"time"
+)
+
+type Cart struct {
+	ID        string      `json:"id"`                  // Unique identifier for the cart
+	UserID    string      `json:"userId,omitempty"`    // Owner of the cart, empty for anonymous carts
+	SessionID string      `json:"sessionId,omitempty"` // Anonymous session the cart belongs to
+	Items     []*CartItem `json:"items"`               // Items stored in the cart
+	CreatedAt time.Time   `json:"createdAt"`           // Timestamp for when the cart was created
+	UpdatedAt time.Time   `json:"updatedAt"`           // Timestamp for when the cart was last touched
+}
+
+type CartItem struct {
+	ID         string    `json:"id"`                        // Unique identifier for the cart item
+	CartID     string    `json:"cartId"`                    // Foreign key to associate with the cart
+	ProductID  string    `json:"productId"`                 // Foreign key to associate with the product
+	Quantity   int       `json:"quantity" validate:"gte=1"` // Quantity requested
+	PriceAtAdd Money     `json:"priceAtAdd"`                // Unit price when the item was added or last updated
+	Currency   string    `json:"currency" validate:"len=3"` // ISO 4217 currency code of PriceAtAdd
+	CreatedAt  time.Time `json:"createdAt"`                 // Timestamp for when the item was added
+	UpdatedAt  time.Time `json:"updatedAt"`                 // Timestamp for when the item was last updated
+}
+
+// PricedCart is a cart re-priced against the live catalogue.
+type PricedCart struct {
+	ID        string       `json:"id"`
+	UserID    string       `json:"userId,omitempty"`
+	SessionID string       `json:"sessionId,omitempty"`
+	Lines     []PricedLine `json:"lines"`
+	Subtotal  Money        `json:"subtotal"`  // Sum of the available lines at live prices
+	Currency  string       `json:"currency"`  // Currency of the subtotal
+	HasIssues bool         `json:"hasIssues"` // True when at least one line is unavailable or re-priced
+	UpdatedAt time.Time    `json:"updatedAt"`
+}
+
+type PricedLine struct {
+	ProductID    string `json:"productId"`
+	ProductName  string `json:"productName"`
+	Quantity     int    `json:"quantity"`
+	UnitPrice    Money  `json:"unitPrice"`  // Live unit price
+	PriceAtAdd   Money  `json:"priceAtAdd"` // Unit price the customer saw when adding the item
+	LineTotal    Money  `json:"lineTotal"`  // UnitPrice * Quantity
+	Currency     string `json:"currency"`
+	Available    bool   `json:"available"`    // Product exists, is active and has enough stock
+	PriceChanged bool   `json:"priceChanged"` // Live price differs from PriceAtAdd
+	Issue        string `json:"issue,omitempty"`
+}
@@ -0,0 +1,10 @@
+package entity
+
+// Email is a rendered message ready to be handed to a mailer. Every email
+// carries an HTML body and a plain text one for clients that do not show HTML.
+type Email struct {
+	To      []string `json:"to"`      // Addresses the email is sent to
+	Subject string   `json:"subject"` // Subject line, may hold any UTF-8
+	HTML    string   `json:"html"`    // HTML body
+	Text    string   `json:"text"`    // Plain text body
+}
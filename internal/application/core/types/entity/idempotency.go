@@ -0,0 +1,20 @@
+package entity
+
+import (
+	"net/http"
+	"time"
+)
+
+// IdempotencyRecord is a request made with an Idempotency-Key header and, once
+// it has been handled, the response replayed to retries of that request.
+type IdempotencyRecord struct {
+	Key             string      `json:"key"`             // Idempotency-Key sent by the client
+	Scope           string      `json:"scope"`           // User the key belongs to, session:<id> for anonymous requests
+	Fingerprint     string      `json:"fingerprint"`     // Hash of the method, path and body of the request
+	StatusCode      int         `json:"statusCode"`      // Status of the stored response
+	ResponseHeaders http.Header `json:"responseHeaders"` // Headers of the stored response
+	ResponseBody    []byte      `json:"responseBody"`    // Body of the stored response
+	Completed       bool        `json:"completed"`       // False while the first request is still being handled
+	CreatedAt       time.Time   `json:"createdAt"`       // Timestamp for when the key was first seen
+	ExpiresAt       time.Time   `json:"expiresAt"`       // The key can be reused for any request after this
+}
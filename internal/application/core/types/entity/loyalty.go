@@ -0,0 +1,58 @@
+package entity
+
+import (
+	"time"
+)
+
+const (
+	LoyaltyRuleSpend    = "spend"    // Points earned per unit of currency spent
+	LoyaltyRuleCategory = "category" // Multiplies the points earned on items of a category
+)
+
+// LoyaltyEarnRule sets how many points paid orders earn. An order earns the
+// rate of the spend rule for its currency on every unit spent, more on the
+// items of a category with a category rule.
+type LoyaltyEarnRule struct {
+	ID        string    `json:"id"`                 // Unique identifier for the rule
+	Type      string    `json:"type"`               // One of the LoyaltyRule constants
+	Currency  string    `json:"currency,omitempty"` // Orders in this currency only, every currency when empty
+	Category  string    `json:"category,omitempty"` // Product category a category rule applies to
+	Rate      float64   `json:"rate"`               // Points per unit spent, or the multiplier of a category
+	IsActive  bool      `json:"isActive"`           // Inactive rules are ignored
+	CreatedAt time.Time `json:"createdAt"`          // Timestamp for when the rule was created
+}
+
+// LoyaltyTier is reached by users whose spend over the tier window adds up to
+// MinSpend in its currency. The points they earn are multiplied while they
+// stay in it.
+type LoyaltyTier struct {
+	ID         string    `json:"id"`         // Unique identifier for the tier
+	Name       string    `json:"name"`       // Name shown to the user, e.g. "Gold"
+	MinSpend   Money     `json:"minSpend"`   // Rolling spend that reaches the tier
+	Currency   string    `json:"currency"`   // ISO 4217 currency code of MinSpend
+	Multiplier float64   `json:"multiplier"` // Applied to the points earned in the tier
+	CreatedAt  time.Time `json:"createdAt"`  // Timestamp for when the tier was created
+}
+
+const (
+	LoyaltyEarn       = "earn"       // Earned on a paid order
+	LoyaltyRedeem     = "redeem"     // Spent as a discount on an order
+	LoyaltyRelease    = "release"    // Given back when the order they were spent on was cancelled
+	LoyaltyReverse    = "reverse"    // Taken back when the order they were earned on was refunded
+	LoyaltyRefund     = "refund"     // Given back when the order they were spent on was refunded
+	LoyaltyAdjustment = "adjustment" // Added or taken by an admin
+)
+
+// LoyaltyEntry is a line of a user's points ledger. The balance of a user is
+// the sum of their entries.
+type LoyaltyEntry struct {
+	ID        string    `json:"id"`                // Unique identifier for the entry
+	UserID    string    `json:"userId"`            // Owner of the points
+	Points    int       `json:"points"`            // Earned when positive, spent or taken back when negative
+	Reason    string    `json:"reason"`            // One of the Loyalty constants
+	OrderID   string    `json:"orderId,omitempty"` // Order the points were earned on or spent on
+	Spend     Money     `json:"spend"`             // Counted towards the tier of the user, negative when taken back
+	Currency  string    `json:"currency,omitempty"`
+	Note      string    `json:"note,omitempty"` // Why an admin adjusted the balance
+	CreatedAt time.Time `json:"createdAt"`      // Timestamp for when the entry was recorded
+}
@@ -0,0 +1,248 @@
+package entity
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// currencyExponents lists the ISO 4217 currencies that do not have two
+// decimal places. Every other currency has two.
+var currencyExponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// CurrencyExponent returns the number of decimal places of an ISO 4217 currency.
+func CurrencyExponent(currency string) int {
+	if exponent, ok := currencyExponents[strings.ToUpper(currency)]; ok {
+		return exponent
+	}
+	return 2
+}
+
+// Money is an amount in the minor unit of its currency, cents for USD and
+// yen for JPY. Arithmetic refuses to mix currencies, except that a zero value
+// without a currency takes the currency of the other operand so it can be used
+// as the starting point of a sum.
+type Money struct {
+	Amount   int64  // Amount in minor units
+	Currency string // ISO 4217 currency code, upper case
+}
+
+// NewMoney returns an amount already expressed in minor units.
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: strings.ToUpper(currency)}
+}
+
+// MoneyFromMajor converts an amount in major units, such as a price typed in
+// by an admin, rounding half away from zero to the currency's minor unit.
+func MoneyFromMajor(amount float64, currency string) Money {
+	factor := math.Pow10(CurrencyExponent(currency))
+	return NewMoney(int64(math.Round(amount*factor)), currency)
+}
+
+// ParseMoney reads a decimal string such as the ones MySQL returns for
+// DECIMAL columns. Digits past the currency's exponent must be zero.
+func ParseMoney(value, currency string) (Money, error) {
+	exponent := CurrencyExponent(currency)
+	value = strings.TrimSpace(value)
+
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(value, "-")
+
+	whole, fraction, _ := strings.Cut(value, ".")
+	if len(fraction) > exponent {
+		if strings.Trim(fraction[exponent:], "0") != "" {
+			return Money{}, fmt.Errorf("%s has more decimals than %s allows", value, strings.ToUpper(currency))
+		}
+		fraction = fraction[:exponent]
+	}
+	fraction += strings.Repeat("0", exponent-len(fraction))
+
+	amount, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount %q: %v", value, err)
+	}
+	if negative {
+		amount = -amount
+	}
+
+	return NewMoney(amount, currency), nil
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Major returns the amount in major units. It is meant for display and for
+// comparing against thresholds configured in major units, not for arithmetic.
+func (m Money) Major() float64 {
+	return float64(m.Amount) / math.Pow10(CurrencyExponent(m.Currency))
+}
+
+// String formats the amount with the currency's decimal places, e.g. "19.99".
+func (m Money) String() string {
+	exponent := CurrencyExponent(m.Currency)
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	digits := strconv.FormatInt(amount, 10)
+	if exponent == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+func (m Money) sameCurrency(other Money) (string, error) {
+	switch {
+	case m.Currency == "" && m.Amount == 0:
+		return other.Currency, nil
+	case other.Currency == "" && other.Amount == 0:
+		return m.Currency, nil
+	case !strings.EqualFold(m.Currency, other.Currency):
+		return "", fmt.Errorf("cannot combine %s and %s amounts", m.Currency, other.Currency)
+	}
+	return m.Currency, nil
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	currency, err := m.sameCurrency(other)
+	if err != nil {
+		return Money{}, err
+	}
+	return NewMoney(m.Amount+other.Amount, currency), nil
+}
+
+func (m Money) Sub(other Money) (Money, error) {
+	currency, err := m.sameCurrency(other)
+	if err != nil {
+		return Money{}, err
+	}
+	return NewMoney(m.Amount-other.Amount, currency), nil
+}
+
+// Cmp compares two amounts of the same currency, returning -1, 0 or 1.
+func (m Money) Cmp(other Money) (int, error) {
+	if _, err := m.sameCurrency(other); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.Amount < other.Amount:
+		return -1, nil
+	case m.Amount > other.Amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Mul multiplies the amount by a quantity.
+func (m Money) Mul(quantity int) Money {
+	return Money{Amount: m.Amount * int64(quantity), Currency: m.Currency}
+}
+
+// Percent returns percent of the amount, rounded half away from zero to the
+// minor unit.
+func (m Money) Percent(percent float64) Money {
+	return Money{Amount: int64(math.Round(float64(m.Amount) * percent / 100)), Currency: m.Currency}
+}
+
+// Min returns the smaller of two amounts of the same currency.
+func (m Money) Min(other Money) (Money, error) {
+	cmp, err := m.Cmp(other)
+	if err != nil {
+		return Money{}, err
+	}
+	if cmp <= 0 {
+		return m, nil
+	}
+	return other, nil
+}
+
+// Allocate splits the amount in proportion to weights without losing or
+// creating a minor unit, the leftover units go to the first weights.
+func (m Money) Allocate(weights []int64) []Money {
+	parts := make([]Money, len(weights))
+
+	var total int64
+	for _, weight := range weights {
+		total += weight
+	}
+	if total == 0 {
+		for i := range parts {
+			parts[i] = Money{Currency: m.Currency}
+		}
+		return parts
+	}
+
+	remainder := m.Amount
+	for i, weight := range weights {
+		share := m.Amount * weight / total
+		parts[i] = Money{Amount: share, Currency: m.Currency}
+		remainder -= share
+	}
+	for i := 0; remainder != 0 && i < len(parts); i++ {
+		if weights[i] == 0 {
+			continue
+		}
+		if remainder > 0 {
+			parts[i].Amount++
+			remainder--
+		} else {
+			parts[i].Amount--
+			remainder++
+		}
+	}
+
+	return parts
+}
+
+// SumMoney adds up amounts of the same currency.
+func SumMoney(values ...Money) (Money, error) {
+	var sum Money
+	for _, value := range values {
+		var err error
+		if sum, err = sum.Add(value); err != nil {
+			return Money{}, err
+		}
+	}
+	return sum, nil
+}
+
+type moneyJSON struct {
+	Amount   int64  `json:"amount"`            // Minor units
+	Currency string `json:"currency"`          // ISO 4217 currency code
+	Display  string `json:"display,omitempty"` // Formatted amount, ignored when reading
+}
+
+// MarshalJSON writes the amount in minor units so no client has to parse a
+// float, with the formatted value alongside for display.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{Amount: m.Amount, Currency: m.Currency, Display: m.String()})
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var value moneyJSON
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("money must be an object with an integer amount in minor units and a currency: %v", err)
+	}
+	if len(value.Currency) != 3 && !(value.Currency == "" && value.Amount == 0) {
+		return fmt.Errorf("invalid currency %q", value.Currency)
+	}
+	*m = NewMoney(value.Amount, value.Currency)
+	return nil
+}
@@ -0,0 +1,64 @@
+package entity
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoney(t *testing.T) {
+	t.Run("uses the exponent of the currency", func(t *testing.T) {
+		assert.Equal(t, int64(1999), MoneyFromMajor(19.99, "usd").Amount)
+		assert.Equal(t, int64(1500), MoneyFromMajor(1500, "JPY").Amount)
+		assert.Equal(t, int64(1234), MoneyFromMajor(1.234, "BHD").Amount)
+
+		assert.Equal(t, "19.99", NewMoney(1999, "USD").String())
+		assert.Equal(t, "0.05", NewMoney(5, "USD").String())
+		assert.Equal(t, "-1.50", NewMoney(-150, "EUR").String())
+		assert.Equal(t, "1500", NewMoney(1500, "JPY").String())
+		assert.Equal(t, "1.234", NewMoney(1234, "BHD").String())
+	})
+
+	t.Run("parses decimal columns exactly", func(t *testing.T) {
+		m, err := ParseMoney("1234567.89", "USD")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(123456789), m.Amount)
+
+		m, err = ParseMoney("1500.00", "JPY")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1500), m.Amount)
+
+		_, err = ParseMoney("1500.50", "JPY")
+		assert.Error(t, err)
+	})
+
+	t.Run("refuses to mix currencies", func(t *testing.T) {
+		_, err := NewMoney(100, "USD").Add(NewMoney(100, "EUR"))
+		assert.Error(t, err)
+
+		sum, err := SumMoney(NewMoney(100, "usd"), NewMoney(250, "USD"))
+		assert.NoError(t, err)
+		assert.Equal(t, NewMoney(350, "USD"), sum)
+	})
+
+	t.Run("allocates without losing a cent", func(t *testing.T) {
+		parts := NewMoney(1000, "USD").Allocate([]int64{1, 1, 1})
+
+		assert.Equal(t, int64(334), parts[0].Amount)
+		assert.Equal(t, int64(333), parts[1].Amount)
+		assert.Equal(t, int64(333), parts[2].Amount)
+	})
+
+	t.Run("serializes minor units to JSON", func(t *testing.T) {
+		data, err := json.Marshal(NewMoney(1999, "USD"))
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"amount":1999,"currency":"USD","display":"19.99"}`, string(data))
+
+		var m Money
+		assert.NoError(t, json.Unmarshal([]byte(`{"amount":500,"currency":"jpy"}`), &m))
+		assert.Equal(t, NewMoney(500, "JPY"), m)
+
+		assert.Error(t, json.Unmarshal([]byte(`19.99`), &m))
+	})
+}
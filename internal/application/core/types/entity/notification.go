@@ -0,0 +1,22 @@
+package entity
+
+const (
+	NotifyOrders   = "orders"   // The order was placed or cancelled
+	NotifyPayments = "payments" // A payment was received, failed or refunded
+	NotifyShipping = "shipping" // The order was shipped or delivered
+)
+
+// NotificationCategories are the categories of emails about orders a buyer
+// can turn off. Account emails, like the address verification, always go.
+var NotificationCategories = []string{NotifyOrders, NotifyPayments, NotifyShipping}
+
+// IsNotificationCategory reports whether category is one of the
+// NotificationCategories.
+func IsNotificationCategory(category string) bool {
+	for _, known := range NotificationCategories {
+		if category == known {
+			return true
+		}
+	}
+	return false
+}
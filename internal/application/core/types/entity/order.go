@@ -6,14 +6,44 @@ import (
 
 type Order struct {
 	ID            string    `json:"id"`                        // Unique identifier for the order
-	UserID        string    `json:"userID"`                    // Foreign key to associate with the user
-	Total         float64   `json:"total"`                     // Total amount for the order
-	Subtotal      float64   `json:"subtotal"`                  // Subtotal before tax and discounts
+	UserID        string    `json:"userID"`                    // Foreign key to associate with the user, empty for guest orders
+	Total         Money     `json:"total"`                     // Total amount for the order
+	Subtotal      Money     `json:"subtotal"`                  // Subtotal before tax and discounts
 	Status        string    `json:"status"`                    // Order status (e.g., "Pending", "Shipped", "Delivered", "Cancelled")
 	PaymentStatus string    `json:"paymentStatus"`             // Payment status (e.g., "Paid", "Pending", "Refunded")
 	PaymentMethod string    `json:"paymentMethod"`             // Payment method used (e.g., "Credit Card", "PayPal")
-	Address       string    `json:"address"`                   // Shipping address
+	Address       string    `json:"address"`                   // Shipping address rendered on a single line
 	Currency      string    `json:"currency" validate:"len=3"` // ISO 4217 currency code
 	CreatedAt     time.Time `json:"createdAt"`                 // Timestamp for when the order was created
 	UpdatedAt     time.Time `json:"updatedAt"`                 // Timestamp for when the order was last updated
+
+	ShippingAddress OrderAddress   `json:"shippingAddress"`      // Snapshot of the shipping address at checkout
+	BillingAddress  OrderAddress   `json:"billingAddress"`       // Snapshot of the billing address at checkout
+	GuestEmail      string         `json:"guestEmail,omitempty"` // Buyer email for guest checkout
+	Discount        Money          `json:"discount"`             // Total discount from promotions
+	Tax             Money          `json:"tax"`                  // Total tax, computed from the tax rates
+	TaxBreakdown    []TaxComponent `json:"taxBreakdown"`         // Tax per name and rate
+	TaxInclusive    bool           `json:"taxInclusive"`         // Tax is contained in the prices rather than added to Total
+
+	ShippingMethodID string `json:"shippingMethodId,omitempty"` // Shipping method chosen at checkout
+	ShippingMethod   string `json:"shippingMethod,omitempty"`   // Name of the shipping method at checkout
+	ShippingCost     Money  `json:"shippingCost"`               // Shipping charged, included in Total
+
+	PaymentSessionID string `json:"paymentSessionId,omitempty"` // Stripe Checkout Session the buyer pays through
+
+	SubscriptionID string `json:"subscriptionId,omitempty"` // Subscription the order renews
+	InvoiceID      string `json:"invoiceId,omitempty"`      // Gateway invoice that paid the renewal
+
+	StoreCredit Money `json:"storeCredit"` // Paid with gift cards and wallet balance, included in Total
+
+	LoyaltyPoints   int   `json:"loyaltyPoints"`   // Points redeemed on the order
+	LoyaltyDiscount Money `json:"loyaltyDiscount"` // Taken off Total by the redeemed points
+
+	CardRefunded Money `json:"cardRefunded"` // Given back on the card so far, as the gateway reported it
+}
+
+// AmountDue returns what is left to charge once the redeemed points and the
+// store credit tendered for the order are taken off.
+func (o Order) AmountDue() Money {
+	return NewMoney(o.Total.Amount-o.LoyaltyDiscount.Amount-o.StoreCredit.Amount, o.Total.Currency)
 }
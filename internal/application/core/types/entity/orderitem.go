@@ -10,12 +10,18 @@ type OrderItem struct {
 	ProductID   string    `json:"productId"`                 // Foreign key to associate with the product
 	ProductName string    `json:"productName"`               // Cached product name to prevent dependency on product table
 	Quantity    int       `json:"quantity" validate:"gte=1"` // Quantity ordered, minimum of 1
-	Price       float64   `json:"price" validate:"gt=0"`     // Price per unit
-	TotalPrice  float64   `json:"totalPrice"`                // Calculated total price (Quantity * Price)
-	Subtotal    float64   `json:"subtotal"`                  // Subtotal before tax and discounts
+	Price       Money     `json:"price"`                     // Price per unit
+	TotalPrice  Money     `json:"totalPrice"`                // Calculated total price (Quantity * Price)
+	Subtotal    Money     `json:"subtotal"`                  // Subtotal before tax and discounts
 	Currency    string    `json:"currency" validate:"len=3"` // ISO 4217 currency code
-	Discount    float64   `json:"discount"`                  // Discount applied to this item
-	Tax         float64   `json:"tax"`                       // Tax applied to this item
+	Discount    Money     `json:"discount"`                  // Discount applied to this item, computed from promotions
+	Tax         Money     `json:"tax"`                       // Tax applied to this item, computed from the tax rates
 	CreatedAt   time.Time `json:"createdAt"`                 // Timestamp for when the item was created
 	UpdatedAt   time.Time `json:"updatedAt"`                 // Timestamp for when the item was last updated
+
+	AppliedDiscounts []AppliedDiscount `json:"appliedDiscounts"` // Promotions that make up Discount, for auditing
+	TaxBreakdown     []TaxComponent    `json:"taxBreakdown"`     // Taxes that make up Tax
+
+	StoreID  string `json:"storeId,omitempty"` // Store the item was sold by, empty for the platform's own products
+	Category string `json:"category"`          // Product category at checkout
 }
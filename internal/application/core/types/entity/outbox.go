@@ -0,0 +1,115 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	OutboxPending    = "pending"    // Waiting for the dispatcher
+	OutboxProcessing = "processing" // Claimed by the dispatcher
+	OutboxSent       = "sent"       // Delivered
+	OutboxFailed     = "failed"     // Failed, retried at NextAttemptAt
+	OutboxDead       = "dead"       // Failed too many times, only replayed by an admin
+)
+
+// Outbox topics. Emails are delivered through the mailer, domain events to
+// whoever subscribed to them.
+const (
+	TopicEmail               = "email"
+	TopicOrderPaid           = "order.paid"
+	TopicOrderCancelled      = "order.cancelled"
+	TopicOrderStatusChanged  = "order.status_changed"
+	TopicProductUpdated      = "product.updated"
+	TopicProductDeleted      = "product.deleted"
+	TopicProductStockChanged = "product.stock_changed"
+)
+
+// OutboxMessage is an email or a domain event waiting to leave the API. It is
+// written in the same transaction as the change it tells about, so it is sent
+// when that change is committed and never when it is rolled back.
+type OutboxMessage struct {
+	ID            string     `json:"id"`            // Unique identifier for the message
+	Topic         string     `json:"topic"`         // One of the Topic constants
+	Key           string     `json:"key,omitempty"` // Deduplication key, a message with a key already in the outbox is not added again
+	Payload       []byte     `json:"-"`             // JSON body, an Email for the email topic
+	Status        string     `json:"status"`        // One of the Outbox constants
+	Attempts      int        `json:"attempts"`      // Number of times delivery was tried
+	LastError     string     `json:"lastError"`     // Error of the last failed attempt
+	NextAttemptAt time.Time  `json:"nextAttemptAt"` // The dispatcher picks the message up again after this
+	SentAt        *time.Time `json:"sentAt"`        // Timestamp for when the message was delivered (nullable)
+	CreatedAt     time.Time  `json:"createdAt"`     // Timestamp for when the message was written
+	UpdatedAt     time.Time  `json:"updatedAt"`     // Timestamp for when the message was last updated
+}
+
+// NewOutboxMessage encodes payload as the body of a message on topic.
+func NewOutboxMessage(topic, key string, payload interface{}) (OutboxMessage, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return OutboxMessage{}, err
+	}
+	return OutboxMessage{Topic: topic, Key: key, Payload: body}, nil
+}
+
+// OrderEvent is the body of the order domain events.
+type OrderEvent struct {
+	OrderID       string    `json:"orderId"`          // The order the event is about
+	UserID        string    `json:"userId,omitempty"` // Buyer, empty for guests
+	Status        string    `json:"status"`           // Order status after the event
+	PaymentStatus string    `json:"paymentStatus"`    // Payment status after the event
+	Total         Money     `json:"total"`            // Order total
+	OccurredAt    time.Time `json:"occurredAt"`       // When the change was made
+}
+
+// NewOrderEvent writes the domain event topic about order, which holds the
+// order as the change left it. An order has one event per topic, and one
+// order.status_changed event per status it moves to.
+func NewOrderEvent(topic string, order Order) (OutboxMessage, error) {
+	key := topic + ":" + order.ID
+	if topic == TopicOrderStatusChanged {
+		key += ":" + order.Status
+	}
+	return NewOutboxMessage(topic, key, OrderEvent{
+		OrderID:       order.ID,
+		UserID:        order.UserID,
+		Status:        order.Status,
+		PaymentStatus: order.PaymentStatus,
+		Total:         order.Total,
+		OccurredAt:    time.Now(),
+	})
+}
+
+// ProductEvent is the body of the product domain events.
+type ProductEvent struct {
+	ProductID  string    `json:"productId"`         // The product the event is about
+	StoreID    string    `json:"storeId,omitempty"` // Store selling the product, empty for the platform's own
+	Name       string    `json:"name"`              // Product name
+	Price      Money     `json:"price"`             // Price in the product currency
+	Quantity   int       `json:"quantity"`          // Stock after the event
+	IsActive   bool      `json:"isActive"`          // Whether the product can be bought after the event
+	OccurredAt time.Time `json:"occurredAt"`        // When the change was made
+}
+
+// NewProductEvent writes the domain event topic about product, which holds
+// the product as the change left it. A product changes many times, so its
+// events are never deduplicated.
+func NewProductEvent(topic string, product Product) (OutboxMessage, error) {
+	return NewOutboxMessage(topic, "", ProductEvent{
+		ProductID:  product.ProductId,
+		StoreID:    product.StoreID,
+		Name:       product.Name,
+		Price:      product.Price,
+		Quantity:   product.Quantity,
+		IsActive:   product.IsActive,
+		OccurredAt: time.Now(),
+	})
+}
+
+// OutboxStats is how far behind the dispatcher is.
+type OutboxStats struct {
+	Depth        map[string]int `json:"depth"`        // Messages per status, sent ones excluded
+	OldestDueAt  *time.Time     `json:"oldestDueAt"`  // When the longest waiting pending or failed message became due (nullable)
+	Delivered    int64          `json:"delivered"`    // Messages delivered since the API started
+	Failed       int64          `json:"failed"`       // Failed attempts since the API started
+	DeadLettered int64          `json:"deadLettered"` // Messages given up on since the API started
+}
@@ -1,6 +1,8 @@
 package payloads
 
 import (
+	"time"
+
 	"ecom-api/internal/application/core/types/entity"
 )
 
@@ -14,6 +16,16 @@ type CreateProductPayload struct {
 	Category    string   `json:"category" validate:"required"`
 	Tags        []string `json:"tags"` // JSON array of tags
 	IsActive    bool     `json:"isActive" validate:"required"`
+	TaxCategory string   `json:"taxCategory,omitempty"`   // Empty for the standard rate
+	Weight      float64  `json:"weight" validate:"gte=0"` // Kilograms
+	Length      float64  `json:"length" validate:"gte=0"` // Centimetres
+	Width       float64  `json:"width" validate:"gte=0"`
+	Height      float64  `json:"height" validate:"gte=0"`
+	StoreID     string   `json:"storeId,omitempty" validate:"omitempty,uuid"` // Store selling the product, empty for the platform's own
+
+	IsSubscribable       bool   `json:"isSubscribable"`                                                                                           // Can be bought as a recurring subscription
+	BillingInterval      string `json:"billingInterval,omitempty" validate:"required_if=IsSubscribable true,omitempty,oneof=day week month year"` // Needed for subscribable products
+	BillingIntervalCount int    `json:"billingIntervalCount,omitempty" validate:"gte=0,lte=365"`                                                  // Intervals between renewals, 1 when empty
 }
 
 type RegisterUserPayload struct {
@@ -39,8 +51,126 @@ type LoginUserPayload struct {
 }
 
 type CartCheckoutPayload struct {
-	Items []entity.CartCheckoutItem `json:"items" validate:"required,dive,required"` // List of items in the cart, each item is required
+	Items             []entity.CartCheckoutItem `json:"items,omitempty" validate:"omitempty,dive"`                        // Items to order, the stored cart when empty
+	ShippingAddressID string                    `json:"shippingAddressId,omitempty" validate:"omitempty,uuid"`            // Saved address to ship to
+	ShippingAddress   *OrderAddressPayload      `json:"shippingAddress,omitempty" validate:"omitempty"`                   // Inline shipping address, used when no ID is given
+	BillingAddressID  string                    `json:"billingAddressId,omitempty" validate:"omitempty,uuid"`             // Saved address to bill
+	BillingAddress    *OrderAddressPayload      `json:"billingAddress,omitempty" validate:"omitempty"`                    // Inline billing address, used when no ID is given
+	CouponCodes       []string                  `json:"couponCodes,omitempty" validate:"omitempty,max=5,dive,required"`   // Coupons to redeem, applied in the given order
+	ShippingMethodID  string                    `json:"shippingMethodId,omitempty" validate:"omitempty,uuid"`             // One of the quoted shipping methods
+	GiftCardCodes     []string                  `json:"giftCardCodes,omitempty" validate:"omitempty,max=5,dive,required"` // Gift cards to pay with, spent in the given order
+	UseWallet         bool                      `json:"useWallet,omitempty"`                                              // Pay what the gift cards leave from the wallet balance
+	LoyaltyPoints     int                       `json:"loyaltyPoints,omitempty" validate:"gte=0"`                         // Points to take off the order, no more than it takes to pay it
+}
+
+type GuestCheckoutPayload struct {
+	Email string `json:"email" validate:"required,email"` // Buyer email, receives the order access link
+	CartCheckoutPayload
+}
+
+type CartItemPayload struct {
+	ProductID string `json:"productID" validate:"required,uuid"`
+	Quantity  int    `json:"quantity" validate:"required,gt=0"`
+}
+
+type CartItemQuantityPayload struct {
+	Quantity int `json:"quantity" validate:"gte=0"` // Zero removes the item
+}
+
+// PostalAddressPayload is an address goods are shipped to or billed at. It
+// has the fields of Address, which stays optional for payment profiles.
+type PostalAddressPayload struct {
+	City       string `json:"city" validate:"required"`
+	Country    string `json:"country" validate:"required"`
+	Line1      string `json:"line1" validate:"required"`
+	Line2      string `json:"line2,omitempty" validate:"omitempty"`
+	PostalCode string `json:"postal_code" validate:"required,numeric"`
+	State      string `json:"state,omitempty" validate:"omitempty"`
+}
+
+type OrderAddressPayload struct {
+	FullName string               `json:"fullName" validate:"required"`
+	Phone    string               `json:"phone,omitempty" validate:"omitempty,e164"`
+	Address  PostalAddressPayload `json:"address" validate:"required"`
+}
+
+type UserAddressPayload struct {
+	Label             string               `json:"label" validate:"required,max=64"`
+	FullName          string               `json:"fullName" validate:"required"`
+	Phone             string               `json:"phone,omitempty" validate:"omitempty,e164"`
+	Address           PostalAddressPayload `json:"address" validate:"required"`
+	IsDefaultShipping bool                 `json:"isDefaultShipping"`
+	IsDefaultBilling  bool                 `json:"isDefaultBilling"`
+}
+
+type PromotionPayload struct {
+	Code         string     `json:"code,omitempty" validate:"omitempty,alphanum,max=64"` // Leave empty for a promotion applied automatically
+	Name         string     `json:"name" validate:"required"`
+	Type         string     `json:"type" validate:"required,oneof=percentage fixed_amount free_shipping buy_x_get_y"`
+	Value        float64    `json:"value" validate:"gte=0"`                        // Percentage, or amount in major units for fixed amounts
+	Currency     string     `json:"currency,omitempty" validate:"omitempty,len=3"` // ISO 4217 currency of a fixed amount and MinSpend
+	BuyQuantity  int        `json:"buyQuantity,omitempty" validate:"gte=0"`
+	GetQuantity  int        `json:"getQuantity,omitempty" validate:"gte=0"`
+	ProductIDs   []string   `json:"productIds,omitempty" validate:"omitempty,dive,uuid"`
+	Categories   []string   `json:"categories,omitempty" validate:"omitempty,dive,required"`
+	MinSpend     float64    `json:"minSpend" validate:"gte=0"` // Minimum cart subtotal in major units
+	StartsAt     *time.Time `json:"startsAt,omitempty"`
+	EndsAt       *time.Time `json:"endsAt,omitempty"`
+	UsageLimit   int        `json:"usageLimit" validate:"gte=0"`
+	PerUserLimit int        `json:"perUserLimit" validate:"gte=0"`
+	IsActive     bool       `json:"isActive"`
 }
+
+type ShippingQuotePayload struct {
+	Items             []entity.CartCheckoutItem `json:"items,omitempty" validate:"omitempty,dive"`                      // Items to quote, the stored cart when empty
+	ShippingAddressID string                    `json:"shippingAddressId,omitempty" validate:"omitempty,uuid"`          // Saved address to ship to
+	ShippingAddress   *OrderAddressPayload      `json:"shippingAddress,omitempty" validate:"omitempty"`                 // Inline shipping address, used when no ID is given
+	CouponCodes       []string                  `json:"couponCodes,omitempty" validate:"omitempty,max=5,dive,required"` // Coupons that may waive shipping
+}
+
+type ShippingZonePayload struct {
+	Name      string   `json:"name" validate:"required"`
+	Countries []string `json:"countries" validate:"required,min=1,dive,required"` // "*" covers every destination
+	Regions   []string `json:"regions,omitempty" validate:"omitempty,dive,required"`
+	IsActive  bool     `json:"isActive"`
+}
+
+type ShippingRateTierPayload struct {
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	Cost float64 `json:"cost"` // Cost in major units of the method's currency
+}
+
+type ShippingMethodPayload struct {
+	Name              string                    `json:"name" validate:"required"`
+	RateType          string                    `json:"rateType" validate:"required,oneof=flat weight price"`
+	FlatRate          float64                   `json:"flatRate" validate:"gte=0"` // Major units of Currency
+	Tiers             []ShippingRateTierPayload `json:"tiers,omitempty" validate:"omitempty,dive"`
+	FreeAbove         float64                   `json:"freeAbove" validate:"gte=0"` // Major units of Currency
+	VolumetricDivisor float64                   `json:"volumetricDivisor" validate:"gte=0"`
+	Currency          string                    `json:"currency" validate:"required,len=3"`
+	MinDeliveryDays   int                       `json:"minDeliveryDays" validate:"gte=0"`
+	MaxDeliveryDays   int                       `json:"maxDeliveryDays" validate:"gtefield=MinDeliveryDays"`
+	IsActive          bool                      `json:"isActive"`
+}
+
+type TaxRatePayload struct {
+	Name        string  `json:"name" validate:"required,max=64"`
+	Country     string  `json:"country" validate:"required,max=64"`
+	Region      string  `json:"region,omitempty" validate:"max=64"`      // Leave empty for the whole country
+	TaxCategory string  `json:"taxCategory,omitempty" validate:"max=64"` // Leave empty for every category
+	Rate        float64 `json:"rate" validate:"gte=0,lte=100"`           // Rate in percent
+	IsActive    bool    `json:"isActive"`
+}
+
+type ProductPricePayload struct {
+	Price float64 `json:"price" validate:"required,gt=0"` // Price in the currency of the URL
+}
+
+type ExchangeRatePayload struct {
+	Rate float64 `json:"rate" validate:"required,gt=0"` // Units of quote currency per unit of base currency
+}
+
 type CustomerPayload struct {
 	Email       string            `json:"email" validate:"required,email"`
 	Name        string            `json:"name" validate:"required"`
@@ -52,15 +182,19 @@ type CustomerPayload struct {
 	Shipping    Shipping          `json:"shipping,omitempty" validate:"omitempty"`
 }
 
-type Address struct {
-	City       string `json:"city,omitempty" validate:"omitempty"`
-	Country    string `json:"country,omitempty" validate:"omitempty"`
-	Line1      string `json:"line1,omitempty" validate:"omitempty"`
-	Line2      string `json:"line2,omitempty" validate:"omitempty"`
-	PostalCode string `json:"postal_code,omitempty" validate:"omitempty,numeric"`
-	State      string `json:"state,omitempty" validate:"omitempty"`
+// PaymentProfilePayload sets up the Stripe customer of the caller. The email is
+// always the one of the account.
+type PaymentProfilePayload struct {
+	Name        string    `json:"name,omitempty" validate:"omitempty"` // Defaults to the name on the account
+	Phone       string    `json:"phone,omitempty" validate:"omitempty,e164"`
+	Description string    `json:"description,omitempty" validate:"omitempty"`
+	Address     Address   `json:"address,omitempty" validate:"omitempty"`
+	Shipping    *Shipping `json:"shipping,omitempty" validate:"omitempty"`
 }
 
+// Address is shared with the address book and order snapshots.
+type Address = entity.Address
+
 type Shipping struct {
 	Name    string  `json:"name" validate:"required"`
 	Phone   string  `json:"phone" validate:"required,e164"`
@@ -80,4 +214,108 @@ type CustomerChargeRequest struct {
 	Currency     string `json:"currency" validate:"required"`
 	ReceiptEmail string `json:"receipt_email" validate:"required"`
 	Description  string `json:"description" validate:"required"`
+
+	PaymentMethodID string `json:"payment_method_id,omitempty" validate:"omitempty"` // Saved card to charge, the default card when empty
+}
+
+// RefundPayload gives back part of a payment.
+type RefundPayload struct {
+	Amount int64 `json:"amount,omitempty" validate:"omitempty,gt=0"` // Amount in the minor unit, the whole payment when empty
+}
+
+// GiftCardPayload issues a gift card, its code is generated.
+type GiftCardPayload struct {
+	Amount         float64    `json:"amount" validate:"required,gt=0"`                     // Value of the card in major units
+	Currency       string     `json:"currency" validate:"required,len=3"`                  // ISO 4217 currency the card is spent in
+	RecipientEmail string     `json:"recipientEmail,omitempty" validate:"omitempty,email"` // Who the card is for
+	Note           string     `json:"note,omitempty" validate:"max=255"`                   // Why the card is issued
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty"`                                 // The card never expires when empty
+}
+
+type GiftCardRedeemPayload struct {
+	Code string `json:"code" validate:"required"` // Code of the card, dashes and spaces are ignored
+}
+
+// WalletCreditPayload adds store credit to a user's wallet by hand.
+type WalletCreditPayload struct {
+	Amount   float64 `json:"amount" validate:"required,gt=0"`    // Credit in major units
+	Currency string  `json:"currency" validate:"required,len=3"` // ISO 4217 currency code
+}
+
+// LoyaltyEarnRulePayload sets how many points paid orders earn.
+type LoyaltyEarnRulePayload struct {
+	Type     string  `json:"type" validate:"required,oneof=spend category"`           // spend or category
+	Currency string  `json:"currency,omitempty" validate:"omitempty,len=3"`           // Orders in this currency only, every currency when empty
+	Category string  `json:"category,omitempty" validate:"required_if=Type category"` // Product category a category rule applies to
+	Rate     float64 `json:"rate" validate:"gt=0"`                                    // Points per unit spent, or the multiplier of the category
+}
+
+// LoyaltyTierPayload creates a tier reached by rolling spend.
+type LoyaltyTierPayload struct {
+	Name       string  `json:"name" validate:"required,max=100"`
+	MinSpend   float64 `json:"minSpend" validate:"gte=0"`          // Rolling spend in major units that reaches the tier
+	Currency   string  `json:"currency" validate:"required,len=3"` // ISO 4217 currency of MinSpend
+	Multiplier float64 `json:"multiplier" validate:"gt=0"`         // Applied to the points earned in the tier
+}
+
+// LoyaltyAdjustmentPayload adds points to a user's balance by hand, or takes
+// them when negative.
+type LoyaltyAdjustmentPayload struct {
+	Points int    `json:"points" validate:"required"`
+	Note   string `json:"note" validate:"required,max=255"` // Why the balance is adjusted
+}
+
+// StorePayoutAccountPayload connects a store to the Stripe account it is paid out to.
+type StorePayoutAccountPayload struct {
+	StripeAccountID string `json:"stripeAccountId" validate:"required,startswith=acct_"` // Connected account, onboarded on Stripe
+}
+
+type CommissionRulePayload struct {
+	StoreID  string  `json:"storeId,omitempty" validate:"omitempty,uuid"` // Store the rule applies to, every store when empty
+	Category string  `json:"category,omitempty"`                          // Product category the rule applies to, every category when empty
+	Rate     float64 `json:"rate" validate:"gte=0,lte=100"`               // Percentage of the sale kept by the platform
+}
+
+// OrderStatusPayload moves an order and its store orders to another status.
+type OrderStatusPayload struct {
+	Status string `json:"status" validate:"required"` // One of the order statuses
+}
+
+// StoreOrderStatusPayload moves a store order to another status.
+type StoreOrderStatusPayload struct {
+	Status string `json:"status" validate:"required"` // One of the order statuses
+}
+
+// ShipmentPayload records a parcel sent for a store order.
+type ShipmentPayload struct {
+	Carrier        string `json:"carrier" validate:"required,max=100"`
+	TrackingNumber string `json:"trackingNumber" validate:"required,max=255"`
+}
+
+// SubscriptionPayload subscribes the customer to a product.
+type SubscriptionPayload struct {
+	ProductID         string               `json:"productId" validate:"required,uuid"`
+	Quantity          int                  `json:"quantity" validate:"required,gt=0"`
+	PaymentMethodID   string               `json:"paymentMethodId,omitempty"`                             // Saved card to charge, the default card when empty
+	ShippingAddressID string               `json:"shippingAddressId,omitempty" validate:"omitempty,uuid"` // Saved address renewals are shipped to
+	ShippingAddress   *OrderAddressPayload `json:"shippingAddress,omitempty" validate:"omitempty"`        // Inline address, used when no ID is given
+}
+
+// SubscriptionQuantityPayload changes how many units every renewal delivers.
+type SubscriptionQuantityPayload struct {
+	Quantity int `json:"quantity" validate:"required,gt=0"`
+}
+
+// NotificationPreferencesPayload turns categories of order emails on or off,
+// categories left out keep their setting.
+type NotificationPreferencesPayload struct {
+	Preferences map[string]bool `json:"preferences" validate:"required,min=1"` // Whether each category is emailed, keyed by category
+}
+
+// WebhookEndpointPayload registers a webhook endpoint. Store owners register
+// endpoints of their store, admins of any store or of the platform.
+type WebhookEndpointPayload struct {
+	URL     string   `json:"url" validate:"required,url,max=2048"`        // https URL of a public host
+	Events  []string `json:"events" validate:"required,min=1"`            // Event types to subscribe to, e.g. order.paid
+	StoreID string   `json:"storeId,omitempty" validate:"omitempty,uuid"` // Store of the endpoint, for admins, the platform when empty
 }
@@ -0,0 +1,118 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrPaymentDeclined is wrapped by payment gateways when the card or bank
+// refuses a payment, as opposed to the gateway failing.
+var ErrPaymentDeclined = errors.New("payment declined")
+
+const (
+	PaymentIntentSucceeded      = "succeeded"       // The money was taken
+	PaymentIntentRequiresAction = "requires_action" // The buyer has to complete 3D Secure with the client secret
+	PaymentIntentProcessing     = "processing"      // The outcome is not known yet
+	PaymentIntentFailed         = "failed"          // The payment was declined
+	PaymentIntentCanceled       = "canceled"        // The payment was abandoned
+)
+
+// PaymentCustomer is a buyer as known to the payment gateway.
+type PaymentCustomer struct {
+	ID                     string            `json:"id"`                     // Customer identifier at the gateway
+	Email                  string            `json:"email"`                  // Email receipts are sent to
+	Name                   string            `json:"name"`                   // Full name of the customer
+	Phone                  string            `json:"phone"`                  // Phone number in E.164 format
+	Description            string            `json:"description"`            // Free-form note on the customer
+	Balance                int64             `json:"balance"`                // Credit balance in the minor unit of the account currency
+	DefaultPaymentMethodID string            `json:"defaultPaymentMethodId"` // Payment method charged when none is chosen
+	Metadata               map[string]string `json:"metadata"`               // Key-value pairs stored with the customer
+	CreatedAt              time.Time         `json:"createdAt"`              // Timestamp for when the customer was created
+}
+
+// PaymentMethod is a card saved on a customer.
+type PaymentMethod struct {
+	ID         string `json:"id"`         // Payment method identifier at the gateway
+	CustomerID string `json:"customerId"` // Customer the method is saved on, empty when detached
+	Type       string `json:"type"`       // Kind of payment method (e.g., "card")
+	Brand      string `json:"brand"`      // Card brand (e.g., "visa")
+	Last4      string `json:"last4"`      // Last four digits of the card number
+	ExpMonth   int    `json:"expMonth"`   // Expiry month of the card
+	ExpYear    int    `json:"expYear"`    // Expiry year of the card
+}
+
+// PaymentIntent is a single payment and the state it is in.
+type PaymentIntent struct {
+	ID              string            `json:"id"`              // Payment identifier at the gateway
+	CustomerID      string            `json:"customerId"`      // Customer paying, empty for guests
+	PaymentMethodID string            `json:"paymentMethodId"` // Payment method charged
+	Amount          Money             `json:"amount"`          // Amount charged
+	Status          string            `json:"status"`          // One of the PaymentIntent constants
+	ClientSecret    string            `json:"-"`               // Lets the buyer complete 3D Secure, never stored
+	Description     string            `json:"description"`     // Shown on the receipt
+	Metadata        map[string]string `json:"metadata"`        // Key-value pairs stored with the payment
+}
+
+// SetupIntent saves a card on a customer once the buyer confirms it.
+type SetupIntent struct {
+	ID           string `json:"id"`           // Setup identifier at the gateway
+	CustomerID   string `json:"customerId"`   // Customer the card is saved on
+	ClientSecret string `json:"clientSecret"` // Lets the buyer confirm the setup on the client
+	Status       string `json:"status"`       // Setup status as reported by the gateway
+}
+
+// Refund gives back part or all of a payment.
+type Refund struct {
+	ID              string `json:"id"`              // Refund identifier at the gateway
+	PaymentIntentID string `json:"paymentIntentId"` // Payment refunded
+	Amount          Money  `json:"amount"`          // Amount given back
+	Status          string `json:"status"`          // Refund status as reported by the gateway
+}
+
+// Transfer moves money from the platform to a store's connected account.
+type Transfer struct {
+	ID            string `json:"id"`            // Transfer identifier at the gateway
+	Destination   string `json:"destination"`   // Connected account paid
+	Amount        Money  `json:"amount"`        // Amount moved
+	TransferGroup string `json:"transferGroup"` // Groups the transfers of one order
+}
+
+// PaymentSplit says how the money of an order is shared with its stores. An
+// order sold by a single onboarded store is a destination charge, the store is
+// paid with the charge and the platform keeps ApplicationFee. Any other order
+// is charged to the platform and its stores are paid by transfers in
+// TransferGroup. The zero value keeps everything on the platform.
+type PaymentSplit struct {
+	TransferGroup  string // Groups the charge and transfers of the order
+	Destination    string // Connected account of a destination charge
+	ApplicationFee Money  // What the platform keeps of a destination charge
+}
+
+// CheckoutSession is a hosted payment page the buyer is sent to in order to
+// pay for an order.
+type CheckoutSession struct {
+	ID  string `json:"id"`  // Session identifier at the payment provider
+	URL string `json:"url"` // Hosted payment page to redirect the buyer to
+}
+
+// SubscriptionPlan is what a subscription charges the customer every billing
+// interval.
+type SubscriptionPlan struct {
+	ProductID       string            // Product renewed
+	ProductName     string            // Shown on the invoices
+	UnitPrice       Money             // Price per unit
+	Interval        string            // day, week, month or year
+	IntervalCount   int               // Number of intervals between renewals
+	Quantity        int               // Units charged for
+	PaymentMethodID string            // Payment method charged, the customer's default when empty
+	Metadata        map[string]string // Key-value pairs stored with the subscription
+}
+
+// PaymentSubscription is a subscription as known to the payment gateway.
+type PaymentSubscription struct {
+	ID           string     `json:"id"`         // Subscription identifier at the gateway
+	CustomerID   string     `json:"customerId"` // Customer charged
+	Status       string     `json:"status"`     // One of the Subscription constants
+	RenewsAt     *time.Time `json:"renewsAt"`   // When the next renewal is charged
+	ClientSecret string     `json:"-"`          // Lets the buyer complete 3D Secure for the first payment, never stored
+}
@@ -0,0 +1,28 @@
+package entity
+
+import (
+	"time"
+)
+
+const (
+	PaymentEventPending    = "pending"    // Waiting for the worker
+	PaymentEventProcessing = "processing" // Claimed by the worker
+	PaymentEventProcessed  = "processed"  // Handled successfully
+	PaymentEventFailed     = "failed"     // Failed, retried at NextAttemptAt
+	PaymentEventDead       = "dead"       // Failed too many times, only replayed by an admin
+)
+
+// PaymentEvent is a webhook event received from the payment provider, kept
+// so that each event is handled once even when it is delivered again.
+type PaymentEvent struct {
+	ID            string     `json:"id"`            // Event ID at the provider, unique per event
+	Type          string     `json:"type"`          // Event type (e.g., "checkout.session.completed")
+	Payload       []byte     `json:"-"`             // Verified event body as received
+	Status        string     `json:"status"`        // One of the PaymentEvent constants
+	Attempts      int        `json:"attempts"`      // Number of times processing was tried
+	LastError     string     `json:"lastError"`     // Error of the last failed attempt
+	NextAttemptAt time.Time  `json:"nextAttemptAt"` // The worker picks the event up again after this
+	ProcessedAt   *time.Time `json:"processedAt"`   // Timestamp for when the event was handled (nullable)
+	CreatedAt     time.Time  `json:"createdAt"`     // Timestamp for when the event was received
+	UpdatedAt     time.Time  `json:"updatedAt"`     // Timestamp for when the event was last updated
+}
@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"time"
+)
+
+const (
+	StorePayoutPending  = "pending"  // Owed to the store, not transferred yet
+	StorePayoutPaid     = "paid"     // Transferred to the store's connected account
+	StorePayoutReversed = "reversed" // Taken back after the order was refunded
+)
+
+// CommissionRule sets the share of a sale the platform keeps. A rule limited to
+// a store and a category wins over one limited to a store, which wins over one
+// limited to a category, which wins over a rule for everything.
+type CommissionRule struct {
+	ID        string    `json:"id"`                 // Unique identifier for the rule
+	StoreID   string    `json:"storeId,omitempty"`  // Store the rule applies to, empty for every store
+	Category  string    `json:"category,omitempty"` // Product category the rule applies to, empty for every category
+	Rate      float64   `json:"rate"`               // Percentage of the sale kept by the platform
+	IsActive  bool      `json:"isActive"`           // Inactive rules are ignored
+	CreatedAt time.Time `json:"createdAt"`          // Timestamp for when the rule was created
+	UpdatedAt time.Time `json:"updatedAt"`          // Timestamp for when the rule was last updated
+}
+
+// StorePayout is what the platform owes a store for one order, an entry of the
+// store's payout ledger.
+type StorePayout struct {
+	ID         string    `json:"id"`                   // Unique identifier for the payout
+	StoreID    string    `json:"storeId"`              // Store paid out to
+	OrderID    string    `json:"orderId"`              // Order the store sold items in
+	Gross      Money     `json:"gross"`                // What the buyer paid for the store's items, without tax
+	Commission Money     `json:"commission"`           // Kept by the platform
+	Amount     Money     `json:"amount"`               // Owed to the store, Gross less Commission
+	Currency   string    `json:"currency"`             // ISO 4217 currency code
+	Status     string    `json:"status"`               // One of the StorePayout constants
+	TransferID string    `json:"transferId,omitempty"` // Transfer that paid the store
+	CreatedAt  time.Time `json:"createdAt"`            // Timestamp for when the payout was recorded
+	UpdatedAt  time.Time `json:"updatedAt"`            // Timestamp for when the payout was last updated
+}
+
+// PayoutBalance sums a store's payouts in one currency by status.
+type PayoutBalance struct {
+	Currency string `json:"currency"` // ISO 4217 currency code
+	Pending  Money  `json:"pending"`  // Owed and not transferred yet
+	Paid     Money  `json:"paid"`     // Transferred
+	Reversed Money  `json:"reversed"` // Taken back after refunds
+}
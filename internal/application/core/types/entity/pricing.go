@@ -0,0 +1,22 @@
+package entity
+
+import (
+	"time"
+)
+
+// ProductPrice is a price-list entry, the price of a product in one currency
+// instead of its base price converted at the exchange rate.
+type ProductPrice struct {
+	ProductID string    `json:"productId"` // Product the price belongs to
+	Price     Money     `json:"price"`     // Price in the currency of the entry
+	CreatedAt time.Time `json:"createdAt"` // Timestamp for when the entry was created
+	UpdatedAt time.Time `json:"updatedAt"` // Timestamp for when the entry was last updated
+}
+
+// ExchangeRate is the amount of Quote currency one unit of Base currency buys.
+type ExchangeRate struct {
+	Base      string    `json:"base"`      // ISO 4217 currency converted from
+	Quote     string    `json:"quote"`     // ISO 4217 currency converted to
+	Rate      float64   `json:"rate"`      // Units of Quote per unit of Base
+	UpdatedAt time.Time `json:"updatedAt"` // Timestamp for when the rate was last set
+}
@@ -9,7 +9,7 @@ type Product struct {
 	Name        string    `json:"name"`        // Product name
 	Description string    `json:"description"` // Product description
 	Image       string    `json:"image"`       // URL or path to the product's image
-	Price       float64   `json:"price"`       // Price of the product
+	Price       Money     `json:"price"`       // Price of the product
 	Currency    string    `json:"currency"`    // Currency code (e.g., USD, EUR)
 	Quantity    int       `json:"quantity"`    // Inventory count
 	Category    string    `json:"category"`    // Product category
@@ -17,4 +17,14 @@ type Product struct {
 	IsActive    bool      `json:"isActive"`    // Indicates if the product is active/available for purchase
 	CreatedAt   time.Time `json:"createdAt"`   // Timestamp for when the product was created
 	UpdatedAt   time.Time `json:"updatedAt"`   // Timestamp for when the product was last updated
+	TaxCategory string    `json:"taxCategory"` // Tax category, empty for the standard rate
+	Weight      float64   `json:"weight"`      // Weight in kilograms
+	Length      float64   `json:"length"`      // Length in centimetres
+	Width       float64   `json:"width"`       // Width in centimetres
+	Height      float64   `json:"height"`      // Height in centimetres
+	StoreID     string    `json:"storeId"`     // Store selling the product, empty for the platform's own
+
+	IsSubscribable       bool   `json:"isSubscribable"`            // Can be bought as a recurring subscription
+	BillingInterval      string `json:"billingInterval,omitempty"` // day, week, month or year, empty when not subscribable
+	BillingIntervalCount int    `json:"billingIntervalCount"`      // Number of intervals between renewals
 }
@@ -0,0 +1,54 @@
+package entity
+
+import (
+	"time"
+)
+
+const (
+	PromotionTypePercentage   = "percentage"    // Value is a percentage off the in-scope lines
+	PromotionTypeFixedAmount  = "fixed_amount"  // Amount is taken off, spread over the in-scope lines
+	PromotionTypeFreeShipping = "free_shipping" // Waives the shipping cost of the order
+	PromotionTypeBuyXGetY     = "buy_x_get_y"   // Every BuyQuantity units of a product unlock GetQuantity units at Value percent off
+)
+
+type Promotion struct {
+	ID           string     `json:"id"`                    // Unique identifier for the promotion
+	Code         string     `json:"code,omitempty"`        // Coupon code, empty for promotions applied automatically
+	Name         string     `json:"name"`                  // Name shown to customers
+	Type         string     `json:"type"`                  // One of the PromotionType constants
+	Value        float64    `json:"value"`                 // Percentage off for percentage and buy-X-get-Y promotions
+	Amount       Money      `json:"amount"`                // Amount off for fixed amount promotions
+	Currency     string     `json:"currency,omitempty"`    // ISO 4217 currency of Amount and MinSpend, empty when neither is set
+	BuyQuantity  int        `json:"buyQuantity,omitempty"` // Units to buy for buy-X-get-Y
+	GetQuantity  int        `json:"getQuantity,omitempty"` // Units discounted for buy-X-get-Y
+	ProductIDs   []string   `json:"productIds,omitempty"`  // Restricts the promotion to these products
+	Categories   []string   `json:"categories,omitempty"`  // Restricts the promotion to these categories
+	MinSpend     Money      `json:"minSpend"`              // Minimum cart subtotal, zero for none
+	StartsAt     *time.Time `json:"startsAt,omitempty"`    // Start of the validity window, nil for open
+	EndsAt       *time.Time `json:"endsAt,omitempty"`      // End of the validity window, nil for open
+	UsageLimit   int        `json:"usageLimit"`            // Total redemptions allowed, zero for unlimited
+	PerUserLimit int        `json:"perUserLimit"`          // Redemptions allowed per buyer, zero for unlimited
+	UsageCount   int        `json:"usageCount"`            // Redemptions so far
+	IsActive     bool       `json:"isActive"`              // Inactive promotions are never applied
+	CreatedAt    time.Time  `json:"createdAt"`             // Timestamp for when the promotion was created
+	UpdatedAt    time.Time  `json:"updatedAt"`             // Timestamp for when the promotion was last updated
+}
+
+type PromotionRedemption struct {
+	ID          string    `json:"id"`
+	PromotionID string    `json:"promotionId"`
+	OrderID     string    `json:"orderId"`
+	UserID      string    `json:"userId,omitempty"`
+	GuestEmail  string    `json:"guestEmail,omitempty"`
+	Code        string    `json:"code,omitempty"`
+	Discount    Money     `json:"discount"` // Amount taken off the order by this promotion
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// AppliedDiscount records how much a promotion took off an order item.
+type AppliedDiscount struct {
+	PromotionID string `json:"promotionId"`
+	Code        string `json:"code,omitempty"`
+	Type        string `json:"type"`
+	Amount      Money  `json:"amount"`
+}
@@ -0,0 +1,59 @@
+package entity
+
+import (
+	"time"
+)
+
+const (
+	ShippingRateFlat   = "flat"   // FlatRate whatever the parcel
+	ShippingRateWeight = "weight" // Tiers on the chargeable weight in kilograms
+	ShippingRatePrice  = "price"  // Tiers on the order amount after discounts
+)
+
+// ShippingZone groups the destinations that share shipping methods. A zone
+// with Regions only covers those regions of its countries, the country "*"
+// covers every destination no other zone does.
+type ShippingZone struct {
+	ID        string    `json:"id"`                // Unique identifier for the zone
+	Name      string    `json:"name"`              // Zone name (e.g., Domestic, Europe)
+	Countries []string  `json:"countries"`         // Countries as stored on addresses
+	Regions   []string  `json:"regions,omitempty"` // States or regions, empty for the whole countries
+	IsActive  bool      `json:"isActive"`          // Inactive zones never match
+	CreatedAt time.Time `json:"createdAt"`         // Timestamp for when the zone was created
+	UpdatedAt time.Time `json:"updatedAt"`         // Timestamp for when the zone was last updated
+}
+
+// ShippingRateTier is one row of a weight or price rate table. Max is
+// exclusive, zero leaves the tier open-ended.
+type ShippingRateTier struct {
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	Cost Money   `json:"cost"`
+}
+
+type ShippingMethod struct {
+	ID                string             `json:"id"`                          // Unique identifier for the method
+	ZoneID            string             `json:"zoneId"`                      // Zone the method ships to
+	Name              string             `json:"name"`                        // Name shown to customers (e.g., Standard, Express)
+	RateType          string             `json:"rateType"`                    // One of the ShippingRate constants
+	FlatRate          Money              `json:"flatRate"`                    // Cost for flat methods
+	Tiers             []ShippingRateTier `json:"tiers,omitempty"`             // Rate table for weight and price methods
+	FreeAbove         Money              `json:"freeAbove"`                   // Order amount from which shipping is free, zero for never
+	VolumetricDivisor float64            `json:"volumetricDivisor,omitempty"` // Divides L*W*H in cm to a volumetric weight, zero to ignore dimensions
+	Currency          string             `json:"currency"`                    // ISO 4217 currency of the costs and thresholds
+	MinDeliveryDays   int                `json:"minDeliveryDays"`
+	MaxDeliveryDays   int                `json:"maxDeliveryDays"`
+	IsActive          bool               `json:"isActive"`  // Inactive methods are never quoted
+	CreatedAt         time.Time          `json:"createdAt"` // Timestamp for when the method was created
+	UpdatedAt         time.Time          `json:"updatedAt"` // Timestamp for when the method was last updated
+}
+
+// ShippingQuote is what a method costs for a given cart and address.
+type ShippingQuote struct {
+	MethodID        string `json:"methodId"`
+	Name            string `json:"name"`
+	Cost            Money  `json:"cost"`
+	Free            bool   `json:"free"` // Waived by FreeAbove or a free shipping promotion
+	MinDeliveryDays int    `json:"minDeliveryDays"`
+	MaxDeliveryDays int    `json:"maxDeliveryDays"`
+}
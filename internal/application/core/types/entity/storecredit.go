@@ -0,0 +1,79 @@
+package entity
+
+import (
+	"time"
+)
+
+const (
+	GiftCardActive   = "active"   // Can be spent until it expires or runs out
+	GiftCardDisabled = "disabled" // Blocked by an admin, its balance cannot be spent
+)
+
+// GiftCard holds a balance spendable by whoever knows its code, at checkout or
+// by moving it into their wallet.
+type GiftCard struct {
+	ID             string     `json:"id"`                       // Unique identifier for the gift card
+	Code           string     `json:"code"`                     // Redeemable code, without separators
+	InitialBalance Money      `json:"initialBalance"`           // Value the card was issued with
+	Balance        Money      `json:"balance"`                  // Value left to spend
+	Currency       string     `json:"currency"`                 // ISO 4217 currency code
+	Status         string     `json:"status"`                   // One of the GiftCard constants
+	RecipientEmail string     `json:"recipientEmail,omitempty"` // Who the card was issued to
+	Note           string     `json:"note,omitempty"`           // Why the card was issued
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty"`      // The card cannot be spent after, nil when it never expires
+	CreatedAt      time.Time  `json:"createdAt"`                // Timestamp for when the card was issued
+	UpdatedAt      time.Time  `json:"updatedAt"`                // Timestamp for when the card was last updated
+}
+
+const (
+	WalletGiftCard   = "gift_card"  // A gift card was redeemed into the wallet
+	WalletOrder      = "order"      // Spent on an order
+	WalletRelease    = "release"    // Given back when the order it was spent on was cancelled
+	WalletRefund     = "refund"     // An order was refunded as store credit
+	WalletAdjustment = "adjustment" // Credited by an admin
+)
+
+// WalletEntry is a line of a user's wallet ledger. The balance of a wallet is
+// the sum of its entries in a currency.
+type WalletEntry struct {
+	ID         string    `json:"id"`                   // Unique identifier for the entry
+	UserID     string    `json:"userId"`               // Owner of the wallet
+	Amount     Money     `json:"amount"`               // Credited when positive, spent when negative
+	Currency   string    `json:"currency"`             // ISO 4217 currency code
+	Reason     string    `json:"reason"`               // One of the Wallet constants
+	OrderID    string    `json:"orderId,omitempty"`    // Order the credit was spent on or refunded from
+	GiftCardID string    `json:"giftCardId,omitempty"` // Gift card redeemed into the wallet
+	CreatedAt  time.Time `json:"createdAt"`            // Timestamp for when the entry was recorded
+}
+
+// WalletBalance is what a user can spend from their wallet in one currency.
+type WalletBalance struct {
+	Currency string `json:"currency"` // ISO 4217 currency code
+	Balance  Money  `json:"balance"`  // Spendable store credit
+}
+
+const (
+	TenderGiftCard = "gift_card" // Paid with a gift card
+	TenderWallet   = "wallet"    // Paid from the buyer's wallet
+)
+
+const (
+	TenderApplied  = "applied"  // Taken off the card or wallet for the order
+	TenderReleased = "released" // Given back after the order was cancelled
+	TenderRefunded = "refunded" // Given back after the order was refunded
+)
+
+// OrderTender is store credit paid towards an order. What the tenders of an
+// order do not cover is charged through the payment gateway.
+type OrderTender struct {
+	ID         string    `json:"id"`                   // Unique identifier for the tender
+	OrderID    string    `json:"orderId"`              // Order paid for
+	Type       string    `json:"type"`                 // One of the Tender type constants
+	GiftCardID string    `json:"giftCardId,omitempty"` // Card paid with, for gift card tenders
+	UserID     string    `json:"userId,omitempty"`     // Wallet paid with, for wallet tenders
+	Amount     Money     `json:"amount"`               // Credit taken
+	Currency   string    `json:"currency"`             // ISO 4217 currency code
+	Status     string    `json:"status"`               // One of the Tender status constants
+	CreatedAt  time.Time `json:"createdAt"`            // Timestamp for when the tender was applied
+	UpdatedAt  time.Time `json:"updatedAt"`            // Timestamp for when the tender was last updated
+}
@@ -0,0 +1,34 @@
+package entity
+
+import (
+	"time"
+)
+
+// StoreOrder is the part of an order one store fulfils. It moves through the
+// order statuses on its own, the status of the order follows its store orders.
+type StoreOrder struct {
+	ID           string    `json:"id"`                // Unique identifier for the store order
+	OrderID      string    `json:"orderId"`           // Order the buyer placed
+	StoreID      string    `json:"storeId,omitempty"` // Store fulfilling it, empty for the platform's own products
+	Status       string    `json:"status"`            // Same statuses as an order
+	Subtotal     Money     `json:"subtotal"`          // Items before discounts and tax
+	Discount     Money     `json:"discount"`          // Discounts taken off the items
+	Tax          Money     `json:"tax"`               // Tax of the items
+	ShippingCost Money     `json:"shippingCost"`      // Share of the order's shipping
+	Total        Money     `json:"total"`             // Share of the order's total
+	Currency     string    `json:"currency"`          // ISO 4217 currency code
+	CreatedAt    time.Time `json:"createdAt"`         // Timestamp for when the store order was created
+	UpdatedAt    time.Time `json:"updatedAt"`         // Timestamp for when the store order was last updated
+
+	Items     []*OrderItem `json:"items,omitempty"`     // Items of the order sold by the store, filled in for display
+	Shipments []*Shipment  `json:"shipments,omitempty"` // Parcels sent for it, filled in for display
+}
+
+// Shipment is a parcel a store sent for its part of an order.
+type Shipment struct {
+	ID             string    `json:"id"`             // Unique identifier for the shipment
+	StoreOrderID   string    `json:"storeOrderId"`   // Store order the parcel belongs to
+	Carrier        string    `json:"carrier"`        // Carrier delivering the parcel
+	TrackingNumber string    `json:"trackingNumber"` // Tracking number given by the carrier
+	CreatedAt      time.Time `json:"createdAt"`      // Timestamp for when the parcel was sent
+}
@@ -0,0 +1,18 @@
+package entity
+
+import (
+	"time"
+)
+
+// StoreOwner is a store selling on the marketplace and the person running it.
+type StoreOwner struct {
+	StoreID   string    `json:"storeId"`   // Unique identifier for the store
+	Name      string    `json:"name"`      // Store name
+	OwnerName string    `json:"ownerName"` // Name of the store owner
+	Email     string    `json:"email"`     // Email of the store owner, also the email of their user account
+	Phone     string    `json:"phone"`     // Contact phone number
+	CreatedAt time.Time `json:"createdAt"` // Timestamp for when the store was created
+	UpdatedAt time.Time `json:"updatedAt"` // Timestamp for when the store was last updated
+
+	StripeAccountID string `json:"stripeAccountId,omitempty"` // Stripe connected account payouts go to, empty until the store is onboarded
+}
@@ -0,0 +1,12 @@
+package entity
+
+import "encoding/json"
+
+// StreamEvent is a live update pushed to the storefront over the event
+// stream, an order of the user changing status or the stock of a watched
+// product moving.
+type StreamEvent struct {
+	ID   string          `json:"id"`   // Outbox message the update came from, the same on every instance
+	Type string          `json:"type"` // Domain event topic, e.g. order.status_changed
+	Data json.RawMessage `json:"data"` // OrderEvent or ProductEvent
+}
@@ -0,0 +1,36 @@
+package entity
+
+import (
+	"time"
+)
+
+const (
+	SubscriptionIncomplete = "incomplete" // Waiting for the first payment
+	SubscriptionActive     = "active"     // Renewed every billing interval
+	SubscriptionPaused     = "paused"     // Renewals are not charged until resumed
+	SubscriptionPastDue    = "past_due"   // The last renewal could not be charged, the gateway retries it
+	SubscriptionCancelled  = "cancelled"  // No longer renewed
+)
+
+// Subscription renews an order for a product every billing interval. The
+// product is bought at the price and quantity of the subscription, every paid
+// renewal becomes an order of its own.
+type Subscription struct {
+	ID                    string       `json:"id"`                    // Unique identifier for the subscription
+	UserID                string       `json:"userId"`                // Customer subscribed
+	ProductID             string       `json:"productId"`             // Product renewed
+	StoreID               string       `json:"storeId,omitempty"`     // Store selling the product, empty for the platform's own
+	Category              string       `json:"category"`              // Product category at subscription time
+	ProductName           string       `json:"productName"`           // Product name at subscription time
+	Quantity              int          `json:"quantity"`              // Units delivered on every renewal
+	UnitPrice             Money        `json:"unitPrice"`             // Price per unit charged on every renewal
+	Currency              string       `json:"currency"`              // ISO 4217 currency code
+	Interval              string       `json:"interval"`              // day, week, month or year
+	IntervalCount         int          `json:"intervalCount"`         // Number of intervals between renewals
+	Status                string       `json:"status"`                // One of the Subscription constants
+	PaymentSubscriptionID string       `json:"paymentSubscriptionId"` // Subscription at the payment gateway
+	ShippingAddress       OrderAddress `json:"shippingAddress"`       // Renewals are shipped here
+	RenewsAt              *time.Time   `json:"renewsAt,omitempty"`    // When the next renewal is charged
+	CreatedAt             time.Time    `json:"createdAt"`             // Timestamp for when the subscription was created
+	UpdatedAt             time.Time    `json:"updatedAt"`             // Timestamp for when the subscription was last updated
+}
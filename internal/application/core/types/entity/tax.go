@@ -0,0 +1,59 @@
+package entity
+
+import (
+	"time"
+)
+
+// TaxRate is one row of the tax rule table. Region and TaxCategory narrow the
+// rate down, an empty value matches every region or category of the country.
+type TaxRate struct {
+	ID          string    `json:"id"`                    // Unique identifier for the tax rate
+	Name        string    `json:"name"`                  // Tax name shown in the breakdown (e.g., VAT, State tax)
+	Country     string    `json:"country"`               // Country the rate applies to, as stored on addresses
+	Region      string    `json:"region,omitempty"`      // State or region, empty for the whole country
+	TaxCategory string    `json:"taxCategory,omitempty"` // Product tax category, empty for every category
+	Rate        float64   `json:"rate"`                  // Rate in percent
+	IsActive    bool      `json:"isActive"`              // Inactive rates are never applied
+	CreatedAt   time.Time `json:"createdAt"`             // Timestamp for when the rate was created
+	UpdatedAt   time.Time `json:"updatedAt"`             // Timestamp for when the rate was last updated
+}
+
+// TaxLine is a line to be taxed, Amount is what the buyer pays for it after discounts.
+type TaxLine struct {
+	TaxCategory string `json:"taxCategory"`
+	Amount      Money  `json:"amount"`
+}
+
+type TaxRequest struct {
+	Address  Address   `json:"address"` // Destination the tax is computed for
+	Currency string    `json:"currency"`
+	Lines    []TaxLine `json:"lines"`
+}
+
+// TaxComponent is the amount of one tax in a breakdown.
+type TaxComponent struct {
+	Name   string  `json:"name"`
+	Rate   float64 `json:"rate"` // Rate in percent
+	Amount Money   `json:"amount"`
+}
+
+type LineTax struct {
+	Tax       Money          `json:"tax"`
+	Breakdown []TaxComponent `json:"breakdown"`
+}
+
+type TaxResult struct {
+	Lines     []LineTax      `json:"lines"`     // Tax per line, same order as the request lines
+	Tax       Money          `json:"tax"`       // Sum of every line tax
+	Breakdown []TaxComponent `json:"breakdown"` // Tax per name and rate over the whole order
+	Inclusive bool           `json:"inclusive"` // Tax is already part of the line amounts
+}
+
+// AddedTax returns the tax to add on top of the line at index i, which is zero
+// when the prices already include it.
+func (r *TaxResult) AddedTax(i int) Money {
+	if r.Inclusive {
+		return Money{Currency: r.Tax.Currency}
+	}
+	return r.Lines[i].Tax
+}
@@ -16,4 +16,6 @@ type User struct {
 	CreatedAt  time.Time  `json:"createdAt"`  // Record creation timestamp
 	UpdatedAt  time.Time  `json:"updatedAt"`  // Last update timestamp
 	DeletedAt  *time.Time `json:"deletedAt"`  // Soft delete timestamp (nullable)
+
+	StripeCustomerID string `json:"stripeCustomerId,omitempty"` // Stripe customer the user pays as, empty until one is created
 }
@@ -0,0 +1,80 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebhookEvents are the domain events merchants can have posted to their
+// webhook endpoints.
+var WebhookEvents = []string{TopicOrderPaid, TopicOrderCancelled, TopicOrderStatusChanged, TopicProductUpdated, TopicProductDeleted, TopicProductStockChanged}
+
+// IsWebhookEvent reports whether event is one of the WebhookEvents.
+func IsWebhookEvent(event string) bool {
+	for _, known := range WebhookEvents {
+		if event == known {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookEndpoint is a URL of a merchant the events it subscribed to are
+// posted to. An endpoint of a store gets the events about the orders and
+// products of that store, a platform endpoint gets every event.
+type WebhookEndpoint struct {
+	ID        string    `json:"id"`                // Unique identifier for the endpoint
+	StoreID   string    `json:"storeId,omitempty"` // Store the endpoint belongs to, empty for the platform
+	URL       string    `json:"url"`               // Where events are posted
+	Secret    string    `json:"-"`                 // Signs the payloads, only shown when the endpoint is registered
+	Events    []string  `json:"events"`            // Event types the endpoint subscribed to
+	IsActive  bool      `json:"isActive"`          // Inactive endpoints get no new events
+	CreatedAt time.Time `json:"createdAt"`         // Timestamp for when the endpoint was registered
+	UpdatedAt time.Time `json:"updatedAt"`         // Timestamp for when the endpoint was last updated
+}
+
+// Subscribes reports whether the endpoint takes event.
+func (e WebhookEndpoint) Subscribes(event string) bool {
+	for _, subscribed := range e.Events {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	WebhookPending    = "pending"    // Waiting for the sender
+	WebhookProcessing = "processing" // Claimed by the sender
+	WebhookDelivered  = "delivered"  // The endpoint answered with a 2xx
+	WebhookFailed     = "failed"     // Failed, retried at NextAttemptAt
+	WebhookDead       = "dead"       // Failed too many times, only sent again on redelivery
+)
+
+// WebhookDelivery is an event on its way to an endpoint, and the log of how
+// its attempts went.
+type WebhookDelivery struct {
+	ID             string     `json:"id"`             // Unique identifier for the delivery
+	EndpointID     string     `json:"endpointId"`     // Endpoint the event is posted to
+	EventID        string     `json:"eventId"`        // Identifier of the event, the same for every endpoint it goes to
+	Event          string     `json:"event"`          // Event type
+	Payload        []byte     `json:"-"`              // Body posted, a WebhookBody
+	Status         string     `json:"status"`         // One of the Webhook status constants
+	Attempts       int        `json:"attempts"`       // Number of times the event was posted
+	ResponseStatus int        `json:"responseStatus"` // HTTP status of the last attempt, 0 when no response came
+	ResponseBody   string     `json:"responseBody"`   // Start of the body of the last response
+	LastError      string     `json:"lastError"`      // Error of the last failed attempt
+	NextAttemptAt  time.Time  `json:"nextAttemptAt"`  // The sender posts the event again after this
+	DeliveredAt    *time.Time `json:"deliveredAt"`    // Timestamp for when the endpoint took the event (nullable)
+	CreatedAt      time.Time  `json:"createdAt"`      // Timestamp for when the delivery was created
+	UpdatedAt      time.Time  `json:"updatedAt"`      // Timestamp for when the delivery was last updated
+}
+
+// WebhookBody is what is posted to an endpoint. Endpoints tell retries apart
+// by ID.
+type WebhookBody struct {
+	ID        string          `json:"id"`        // Identifier of the event
+	Type      string          `json:"type"`      // Event type
+	CreatedAt time.Time       `json:"createdAt"` // When the event happened
+	Data      json.RawMessage `json:"data"`      // The domain event, e.g. an OrderEvent
+}
@@ -0,0 +1,17 @@
+package rports
+
+import (
+	"ecom-api/internal/application/core/types/entity"
+)
+
+type AddressStore interface {
+	CreateAddress(address entity.UserAddress) (string, error)             // Create a new address and return its ID
+	GetAddressByID(addressID string) (*entity.UserAddress, error)         // Retrieve an address by its ID
+	GetAddressesByUserID(userID string) ([]*entity.UserAddress, error)    // Retrieve the address book of a user
+	UpdateAddress(address entity.UserAddress) error                       // Update an existing address
+	DeleteAddress(addressID string) error                                 // Delete an address
+	SetDefaultShippingAddress(userID, addressID string) error             // Mark an address as the user's default shipping address
+	SetDefaultBillingAddress(userID, addressID string) error              // Mark an address as the user's default billing address
+	GetDefaultShippingAddress(userID string) (*entity.UserAddress, error) // Retrieve the default shipping address, nil if none
+	GetDefaultBillingAddress(userID string) (*entity.UserAddress, error)  // Retrieve the default billing address, nil if none
+}
@@ -0,0 +1,18 @@
+package rports
+
+import (
+	"ecom-api/internal/application/core/types/entity"
+)
+
+type CartStore interface {
+	CreateCart(cart entity.Cart) (string, error)               // Create an empty cart and return its ID
+	GetCartByUserID(userID string) (*entity.Cart, error)       // Retrieve the cart of a user with its items, nil if none
+	GetCartBySessionID(sessionID string) (*entity.Cart, error) // Retrieve an anonymous cart with its items, nil if none
+	AssignCartToUser(cartID, userID string) error              // Hand an anonymous cart over to a user
+	DeleteCart(cartID string) error                            // Delete a cart and its items
+
+	UpsertCartItem(item entity.CartItem) error              // Add an item or replace the quantity of an existing one
+	RemoveCartItem(cartID, productID string) error          // Remove a product from the cart
+	ClearCart(cartID string) error                          // Remove every item from the cart
+	GetCartItems(cartID string) ([]*entity.CartItem, error) // Retrieve all items of a cart
+}
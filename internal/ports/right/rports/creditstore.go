@@ -0,0 +1,27 @@
+package rports
+
+import (
+	"ecom-api/internal/application/core/types/entity"
+)
+
+type CreditStore interface {
+	//gift cards
+	CreateGiftCard(card entity.GiftCard) (string, error)            // Issue a gift card and return its ID, fails when the code is taken
+	GetGiftCardByID(giftCardID string) (*entity.GiftCard, error)    // Retrieve a gift card by its ID
+	GetGiftCardByCode(code string) (*entity.GiftCard, error)        // Retrieve a gift card by its normalized code
+	GetGiftCards() ([]*entity.GiftCard, error)                      // Retrieve every gift card, newest first
+	SetGiftCardStatus(giftCardID, status string) (bool, error)      // Enable or disable a gift card, false when it does not exist
+	RedeemGiftCard(giftCardID, userID string) (entity.Money, error) // Move what is left on a spendable card into the user's wallet, zero when nothing was moved
+
+	//wallets
+	GetWalletBalance(userID, currency string) (entity.Money, error)                        // Retrieve what a user can spend in a currency
+	GetWalletBalances(userID string) ([]*entity.WalletBalance, error)                      // Retrieve the balances of a user in every currency they hold
+	GetWalletEntries(userID string) ([]*entity.WalletEntry, error)                         // Retrieve the ledger of a user, newest first
+	CreditWallet(entry entity.WalletEntry) (string, error)                                 // Add a positive entry to the wallet of its user and return its ID
+	RefundOrderToWallet(orderID string, amount entity.Money) (string, entity.Money, error) // Refund amount of an order, the rest when zero, into its buyer's wallet and return the entry ID, empty when more than is left, and what was left before
+
+	//order tenders
+	ApplyOrderTenders(orderID string, tenders []entity.OrderTender) (bool, error) // Take every tender off its card or wallet at once, false and nothing taken when one no longer covers its amount
+	GetOrderTenders(orderID string) ([]*entity.OrderTender, error)                // Retrieve the store credit tendered for an order
+	ReleaseOrderTenders(orderID, status string) (entity.Money, error)             // Give the applied tenders of an order back, marking them status, and return the credit given back
+}
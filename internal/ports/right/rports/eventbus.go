@@ -0,0 +1,11 @@
+package rports
+
+import "ecom-api/internal/application/core/types/entity"
+
+// EventBus carries live updates to the event streams open on every instance
+// of the API. It is memory on a single node and in tests, and the database
+// when several instances share the traffic.
+type EventBus interface {
+	Publish(topic string, event entity.StreamEvent) error           // Deliver an event to the subscribers of topic on every instance
+	Subscribe(topics ...string) (<-chan entity.StreamEvent, func()) // Receive the events of topics until the returned function is called
+}
@@ -0,0 +1,11 @@
+package rports
+
+import (
+	"ecom-api/internal/application/core/types/entity"
+)
+
+type IdempotencyStore interface {
+	ReserveIdempotencyKey(record entity.IdempotencyRecord) (*entity.IdempotencyRecord, error) // Claim a key for a request, returns the stored record when the key is already taken
+	CompleteIdempotencyKey(record entity.IdempotencyRecord) error                             // Store the response of the request that claimed the key
+	ReleaseIdempotencyKey(key, scope string) error                                            // Give up an uncompleted key so the request can be retried
+}
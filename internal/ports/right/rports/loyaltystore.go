@@ -0,0 +1,25 @@
+package rports
+
+import (
+	"time"
+
+	"ecom-api/internal/application/core/types/entity"
+)
+
+type LoyaltyStore interface {
+	//earn rules and tiers
+	CreateLoyaltyEarnRule(rule entity.LoyaltyEarnRule) (string, error) // Create a rule and return its ID
+	GetLoyaltyEarnRules() ([]*entity.LoyaltyEarnRule, error)           // Retrieve every rule, active or not
+	DeleteLoyaltyEarnRule(ruleID string) error
+	CreateLoyaltyTier(tier entity.LoyaltyTier) (string, error) // Create a tier and return its ID, fails when its name is taken in its currency
+	GetLoyaltyTiers() ([]*entity.LoyaltyTier, error)           // Retrieve every tier, lowest minimum spend first
+	DeleteLoyaltyTier(tierID string) error
+
+	//ledger
+	GetLoyaltyBalance(userID string) (int, error)                           // Retrieve the points a user can spend
+	GetLoyaltyEntries(userID string) ([]*entity.LoyaltyEntry, error)        // Retrieve the ledger of a user, newest first
+	GetOrderLoyaltyEntries(orderID string) ([]*entity.LoyaltyEntry, error)  // Retrieve the points earned, spent and given back on an order
+	GetLoyaltySpend(userID string, since time.Time) ([]entity.Money, error) // Retrieve the spend of a user since a time, one amount per currency
+	AddLoyaltyEntry(entry entity.LoyaltyEntry) (bool, error)                // Record an entry and move the balance of its user, false when the order already has an entry for the reason
+	RedeemLoyaltyPoints(userID, orderID string, points int) (bool, error)   // Take points spent on an order off the balance, false and nothing taken when it does not cover them
+}
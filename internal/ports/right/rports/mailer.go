@@ -0,0 +1,9 @@
+package rports
+
+import "ecom-api/internal/application/core/types/entity"
+
+// Mailer delivers rendered emails. It is the SMTP server in production, a log
+// or a file in development and memory in tests.
+type Mailer interface {
+	Send(email entity.Email) error // Deliver an email to every address in To
+}
@@ -0,0 +1,9 @@
+package rports
+
+// NotificationStore keeps which categories of order emails every address
+// gets. Preferences are kept per address rather than per user, so guest
+// buyers can unsubscribe as well.
+type NotificationStore interface {
+	GetNotificationPreferences(email string) (map[string]bool, error)     // Retrieve the categories set for an address, a category missing from it is enabled
+	SetNotificationPreference(email, category string, enabled bool) error // Turn a category of emails on or off for an address
+}
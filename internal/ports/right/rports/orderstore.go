@@ -5,16 +5,37 @@ import (
 )
 
 type OrderStore interface {
-	CreateOrder(order entity.Order) (string, error)           // Create a new order and return its ID
-	GetOrderByID(orderID string) (*entity.Order, error)       // Retrieve an order by its ID
-	GetOrdersByUserID(userID string) ([]*entity.Order, error) // Retrieve all orders for a specific user
-	UpdateOrder(order entity.Order) error                     // Update an existing order
-	DeleteOrder(orderID string) error                         // Delete an order and its associated items
+	CreateOrder(order entity.Order) (string, error)              // Create a new order and return its ID
+	GetOrderByID(orderID string) (*entity.Order, error)          // Retrieve an order by its ID
+	GetOrderByInvoiceID(invoiceID string) (*entity.Order, error) // Retrieve the renewal order an invoice paid for
+	GetOrdersByUserID(userID string) ([]*entity.Order, error)    // Retrieve all orders for a specific user
+	AttachGuestOrdersToUser(email, userID string) (int64, error) // Link guest orders placed with an email to a user account
+	UpdateOrder(order entity.Order) error                        // Update an existing order
+	DeleteOrder(orderID string) error                            // Delete an order and its associated items
 	UpdateOrderPaymentStatus(orderId, status string) error
 	UpdateOrderStatus(orderId, status string) error
+	SetOrderPaymentSession(orderID, sessionID string) error                                               // Record the checkout session the order is paid through
+	SetOrderStoreCredit(orderID string, amount entity.Money) error                                        // Record the gift card and wallet credit tendered for the order
+	SetOrderLoyaltyDiscount(orderID string, points int, amount entity.Money) error                        // Record the points redeemed on the order and what they took off
+	MarkOrderPaid(orderID string, messages ...entity.OutboxMessage) (bool, error)                         // Record the payment and start processing a pending order, with outbox messages in the same transaction, false when it was already paid or is no longer pending
+	CancelOrder(orderID string, messages ...entity.OutboxMessage) (bool, error)                           // Cancel an unpaid order and give back its stock and coupons, with outbox messages in the same transaction, false when it was not pending
+	MoveOrderStatus(orderID, fromStatus, toStatus string, messages ...entity.OutboxMessage) (bool, error) // Move an order on with outbox messages in the same transaction, false when it was no longer in fromStatus
+	RecordCardRefund(orderID string, refunded entity.Money) error                                         // Record what was refunded on the card of an order so far, never lowering it
+	RefundOrder(orderID string, messages ...entity.OutboxMessage) (bool, error)                           // Mark an order and its store orders refunded with outbox messages in the same transaction, false when it already was
+
+	PlaceOrder(order entity.Order, items []*entity.OrderItem, storeOrders []entity.StoreOrder, redemptions []entity.PromotionRedemption) (string, error) // Create a checkout order with its items, store orders and coupon redemptions in one transaction and return its ID
 
 	CreateOrderItem(orderItem entity.OrderItem) error                   // Add an item to an order
 	GetOrderItemsByOrderId(orderID string) ([]*entity.OrderItem, error) // Retrieve all items for a specific order
 	DeleteOrderItem(orderItemID string) error                           // Delete a specific order item
 
+	//store orders
+	CreateStoreOrder(storeOrder entity.StoreOrder) (string, error)                             // Create the part of an order a store fulfils and return its ID
+	GetStoreOrderByID(storeOrderID string) (*entity.StoreOrder, error)                         // Retrieve a store order by its ID
+	GetStoreOrdersByOrderID(orderID string) ([]*entity.StoreOrder, error)                      // Retrieve the store orders of an order
+	GetStoreOrdersByStoreID(storeID string) ([]*entity.StoreOrder, error)                      // Retrieve the store orders of a store, newest first
+	UpdateStoreOrderStatus(storeOrderID, fromStatus, toStatus string) (bool, error)            // Move a store order on, false when it was no longer in fromStatus
+	UpdateStoreOrdersStatus(orderID, fromStatus, toStatus string) error                        // Move every store order of an order in fromStatus on
+	CreateShipment(shipment entity.Shipment, messages ...entity.OutboxMessage) (string, error) // Record a parcel sent for a store order with outbox messages in the same transaction and return its ID
+	GetShipmentsByStoreOrderID(storeOrderID string) ([]*entity.Shipment, error)                // Retrieve the parcels sent for a store order
 }
@@ -0,0 +1,20 @@
+package rports
+
+import (
+	"time"
+
+	"ecom-api/internal/application/core/types/entity"
+)
+
+// OutboxStore keeps the emails and domain events waiting to be delivered.
+// Stores that change state along with a message write it in their own
+// transaction, AddOutboxMessages is for messages that go with no change.
+type OutboxStore interface {
+	AddOutboxMessages(messages ...entity.OutboxMessage) error                               // Store messages for the dispatcher, those with a key already stored are skipped
+	ClaimOutboxMessages(limit int, lease time.Duration) ([]*entity.OutboxMessage, error)    // Take due messages for delivery, they become due again once the lease runs out
+	MarkOutboxMessageSent(id string) error                                                  // Record that a message was delivered
+	MarkOutboxMessageFailed(id, lastError string, nextAttemptAt time.Time, dead bool) error // Record a failed attempt and when to retry, or give up when dead
+	GetOutboxMessagesByStatus(statuses ...string) ([]*entity.OutboxMessage, error)          // Retrieve messages in any of the statuses, newest first
+	GetOutboxDepth() (map[string]int, *time.Time, error)                                    // Count the messages not yet sent per status, and when the oldest due one became due
+	ReplayOutboxMessage(id string) (bool, error)                                            // Queue a failed or dead message again, false when it is not failed
+}
@@ -0,0 +1,17 @@
+package rports
+
+import (
+	"time"
+
+	"ecom-api/internal/application/core/types/entity"
+)
+
+type PaymentEventStore interface {
+	SavePaymentEvent(event entity.PaymentEvent) (bool, error)                              // Store a received event, false when it was already stored
+	ClaimPaymentEvents(limit int, lease time.Duration) ([]*entity.PaymentEvent, error)     // Take due events for processing, they become due again once the lease runs out
+	MarkPaymentEventProcessed(id string) error                                             // Record that an event was handled
+	MarkPaymentEventFailed(id, lastError string, nextAttemptAt time.Time, dead bool) error // Record a failed attempt and when to retry, or give up when dead
+	GetPaymentEventByID(id string) (*entity.PaymentEvent, error)                           // Retrieve an event by its ID
+	GetPaymentEventsByStatus(statuses ...string) ([]*entity.PaymentEvent, error)           // Retrieve events in any of the statuses, newest first
+	ReplayPaymentEvent(id string) (bool, error)                                            // Queue a failed or dead event again, false when it is not failed
+}
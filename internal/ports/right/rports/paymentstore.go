@@ -1,22 +1,43 @@
 package rports
 
 import (
-	"ecom-api/internal/application/core/types/entity/payloads"
+	"time"
 
-	"github.com/stripe/stripe-go"
+	"ecom-api/internal/application/core/types/entity"
+	"ecom-api/internal/application/core/types/entity/payloads"
 )
 
+// PaymentStore is the payment gateway. It speaks in domain types only, so the
+// gateway behind it can be swapped, for instance for the in-memory fake.
 type PaymentStore interface {
 	//customer
-	CreateStripeCustomer(customerParams *payloads.CustomerPayload) (*stripe.Customer, error)
-	GetStripeCustomer(customerId string) (*stripe.Customer, error)
-	GetAllStripeCustomers() ([]stripe.Customer, error)
+	CreateCustomer(customerParams *payloads.CustomerPayload, idempotencyKey string) (*entity.PaymentCustomer, error)
+	GetCustomer(customerId string) (*entity.PaymentCustomer, error)
+	GetAllCustomers() ([]*entity.PaymentCustomer, error)
 	DeleteCustomer(customerId string) (bool, error)
 
 	//payment method
-	CreateCheckoutSession(orderID string, totalPrice float64, email string) (*stripe.CheckoutSession, error)
-	CreatePaymentMethod(customerId string) (*stripe.PaymentMethod, error)
+	CreateCheckoutSession(order entity.Order, items []*entity.OrderItem, email string, split entity.PaymentSplit, idempotencyKey string) (*entity.CheckoutSession, error)
+	CreatePaymentMethod(customerId string) (*entity.PaymentMethod, error) // Attach the test card, test mode only
+	CreateSetupIntent(customerId, idempotencyKey string) (*entity.SetupIntent, error)
+	ListPaymentMethods(customerId string) ([]*entity.PaymentMethod, error)
+	GetPaymentMethod(paymentMethodId string) (*entity.PaymentMethod, error)
+	SetDefaultPaymentMethod(customerId, paymentMethodId string) error
+	DetachPaymentMethod(paymentMethodId string) error
 
 	//charge method
-	CreateStripeCharge(chargeParams *payloads.CustomerChargeRequest, customerId string) (*stripe.PaymentIntent, error)
+	CreateCharge(chargeParams *payloads.CustomerChargeRequest, customerId, idempotencyKey string) (*entity.PaymentIntent, error) // Declines wrap entity.ErrPaymentDeclined
+	CreateRefund(paymentIntentId string, amount int64, idempotencyKey string) (*entity.Refund, error)                            // Refund amount in the minor unit, the whole payment when 0
+
+	//marketplace payouts
+	CreateTransfer(destination string, amount entity.Money, transferGroup, sourceChargeId, idempotencyKey string) (*entity.Transfer, error) // Pay a store out of the charge, from the balance when sourceChargeId is empty
+	ReverseTransfer(transferId, idempotencyKey string) error                                                                                // Take a transfer back from the store
+
+	//subscriptions
+	CreateSubscription(customerId string, plan entity.SubscriptionPlan, idempotencyKey string) (*entity.PaymentSubscription, error) // Charge the first renewal at once, declines wrap entity.ErrPaymentDeclined
+	GetSubscription(subscriptionId string) (*entity.PaymentSubscription, error)
+	PauseSubscription(subscriptionId string, paused bool) (*entity.PaymentSubscription, error)           // Stop charging renewals, or charge them again
+	SkipSubscriptionRenewal(subscriptionId string, until time.Time) (*entity.PaymentSubscription, error) // Charge nothing until then
+	UpdateSubscriptionQuantity(subscriptionId string, quantity int) (*entity.PaymentSubscription, error) // From the next renewal on
+	CancelSubscription(subscriptionId string) error
 }
@@ -0,0 +1,20 @@
+package rports
+
+import (
+	"ecom-api/internal/application/core/types/entity"
+)
+
+type PayoutStore interface {
+	//commission rules
+	CreateCommissionRule(rule entity.CommissionRule) (string, error) // Create a rule and return its ID
+	GetCommissionRules() ([]*entity.CommissionRule, error)           // Retrieve every rule, active or not
+	DeleteCommissionRule(ruleID string) error
+
+	//payout ledger
+	CreateStorePayout(payout entity.StorePayout) (bool, error)              // Record what a store is owed for an order, false when it was already recorded
+	GetStorePayoutsByOrderID(orderID string) ([]*entity.StorePayout, error) // Retrieve the payouts of an order
+	GetStorePayoutsByStoreID(storeID string) ([]*entity.StorePayout, error) // Retrieve the ledger of a store, newest first
+	GetPendingStorePayouts(storeID string) ([]*entity.StorePayout, error)   // Retrieve what is still owed to a store, oldest first
+	MarkStorePayoutPaid(payoutID, transferID string) (bool, error)          // Record the transfer that paid a pending payout, false when it was not pending
+	MarkStorePayoutReversed(payoutID string) (bool, error)                  // Record that a payout was taken back, false when it already was
+}
@@ -0,0 +1,24 @@
+package rports
+
+import (
+	"ecom-api/internal/application/core/types/entity"
+)
+
+type PriceListStore interface {
+	SetProductPrice(price entity.ProductPrice) error                              // Create or replace the price of a product in a currency
+	GetProductPrices(productID string) ([]*entity.ProductPrice, error)            // Retrieve the price list of a product
+	GetPricesByProductIDs(productIDs []string) (map[string][]entity.Money, error) // Retrieve the price lists of several products, keyed by product ID
+	DeleteProductPrice(productID, currency string) error                          // Remove the price of a product in a currency
+}
+
+type ExchangeRateStore interface {
+	SetExchangeRate(rate entity.ExchangeRate) error       // Create or replace the rate of a currency pair
+	GetAllExchangeRates() ([]*entity.ExchangeRate, error) // Retrieve every rate
+	DeleteExchangeRate(base, quote string) error          // Remove the rate of a currency pair
+}
+
+// ExchangeRateProvider converts between currencies. Checkout only depends on
+// this, so the manual rate table can be swapped for a market data feed.
+type ExchangeRateProvider interface {
+	Rate(base, quote string) (float64, error) // Units of quote currency per unit of base currency
+}
@@ -25,6 +25,8 @@ type ProductStore interface {
 	UpdateProductQuantity(id string, quantity int) error // Update the quantity of a product
 	IncreaseProductStock(id string, quantity int) error  // Increase product stock
 	DecreaseProductStock(id string, quantity int) error  // Decrease product stock
+	TakeStock(quantities map[string]int) error           // Take quantities keyed by product ID out of stock at once, none when any runs short
+	ReturnStock(quantities map[string]int) error         // Put quantities keyed by product ID back in stock
 
 	// Product activation
 	ActivateProduct(id string) error   // Mark a product as active
@@ -0,0 +1,20 @@
+package rports
+
+import (
+	"ecom-api/internal/application/core/types/entity"
+)
+
+type PromotionStore interface {
+	CreatePromotion(promotion entity.Promotion) (string, error) // Create a new promotion and return its ID
+	GetPromotionByID(id string) (*entity.Promotion, error)      // Retrieve a promotion by its ID
+	GetPromotionByCode(code string) (*entity.Promotion, error)  // Retrieve a coupon by its code, case-insensitive
+	GetAutomaticPromotions() ([]*entity.Promotion, error)       // Retrieve active promotions that apply without a code
+	GetAllPromotions() ([]*entity.Promotion, error)             // Retrieve every promotion
+	UpdatePromotion(promotion entity.Promotion) error           // Update an existing promotion
+	SetPromotionActive(id string, isActive bool) error          // Activate or deactivate a promotion
+
+	ReservePromotionUsage(id string) error                                // Count one redemption, fails when the usage limit is reached
+	ReleasePromotionUsage(id string) error                                // Give back a redemption reserved for an order that was not placed
+	CountRedemptions(promotionID, userID, guestEmail string) (int, error) // Redemptions of a promotion by one buyer
+	CreateRedemption(redemption entity.PromotionRedemption) error         // Record that a promotion was used on an order
+}
@@ -0,0 +1,19 @@
+package rports
+
+import (
+	"ecom-api/internal/application/core/types/entity"
+)
+
+type ShippingStore interface {
+	CreateShippingZone(zone entity.ShippingZone) (string, error) // Create a new zone and return its ID
+	GetShippingZoneByID(id string) (*entity.ShippingZone, error) // Retrieve a zone by its ID
+	GetAllShippingZones() ([]*entity.ShippingZone, error)        // Retrieve every zone
+	UpdateShippingZone(zone entity.ShippingZone) error           // Update an existing zone
+	DeleteShippingZone(id string) error                          // Delete a zone along with its methods
+
+	CreateShippingMethod(method entity.ShippingMethod) (string, error)          // Create a new method and return its ID
+	GetShippingMethodByID(id string) (*entity.ShippingMethod, error)            // Retrieve a method by its ID
+	GetShippingMethodsByZoneID(zoneID string) ([]*entity.ShippingMethod, error) // Retrieve the methods of a zone
+	UpdateShippingMethod(method entity.ShippingMethod) error                    // Update an existing method
+	DeleteShippingMethod(id string) error                                       // Delete a method
+}
@@ -0,0 +1,11 @@
+package rports
+
+import (
+	"ecom-api/internal/application/core/types/entity"
+)
+
+type StoreOwnerStore interface {
+	GetStoreOwnerByID(storeID string) (*entity.StoreOwner, error)  // Retrieve a store by its ID
+	GetStoreOwnerByEmail(email string) (*entity.StoreOwner, error) // Retrieve the store run by the owner with this email
+	SetStoreStripeAccount(storeID, accountID string) (bool, error) // Record the connected account payouts go to, false when the store does not exist
+}
@@ -0,0 +1,13 @@
+package rports
+
+import (
+	"ecom-api/internal/application/core/types/entity"
+)
+
+type SubscriptionStore interface {
+	CreateSubscription(subscription entity.Subscription) (string, error)                   // Create a subscription and return its ID
+	GetSubscriptionByID(subscriptionID string) (*entity.Subscription, error)               // Retrieve a subscription by its ID
+	GetSubscriptionByPaymentID(paymentSubscriptionID string) (*entity.Subscription, error) // Retrieve a subscription by its ID at the payment gateway
+	GetSubscriptionsByUserID(userID string) ([]*entity.Subscription, error)                // Retrieve the subscriptions of a user, newest first
+	UpdateSubscription(subscription entity.Subscription) error                             // Update the quantity, status, gateway subscription and renewal date
+}
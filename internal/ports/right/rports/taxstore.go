@@ -0,0 +1,20 @@
+package rports
+
+import (
+	"ecom-api/internal/application/core/types/entity"
+)
+
+type TaxStore interface {
+	CreateTaxRate(rate entity.TaxRate) (string, error)              // Create a new tax rate and return its ID
+	GetTaxRateByID(id string) (*entity.TaxRate, error)              // Retrieve a tax rate by its ID
+	GetAllTaxRates() ([]*entity.TaxRate, error)                     // Retrieve every tax rate
+	GetTaxRatesByCountry(country string) ([]*entity.TaxRate, error) // Retrieve the active rates of a country
+	UpdateTaxRate(rate entity.TaxRate) error                        // Update an existing tax rate
+	DeleteTaxRate(id string) error                                  // Delete a tax rate
+}
+
+// TaxCalculator computes the tax of an order. Checkout only depends on this,
+// so the rule table can be swapped for an external tax service.
+type TaxCalculator interface {
+	CalculateTax(request entity.TaxRequest) (*entity.TaxResult, error)
+}
@@ -10,4 +10,7 @@ type UserStore interface {
 	CreateUser(user entity.User) error
 	GetUsersByRole(role string) ([]*entity.User, error)
 	SetUserLocking(email string, isLocked bool) error
+	LinkStripeCustomer(userID, customerID string) (bool, error) // Link a Stripe customer to a user that has none, false when the user already has one
+	UnlinkStripeCustomer(customerID string) error               // Forget a deleted Stripe customer
+	GetUserByStripeCustomerID(customerID string) (*entity.User, error)
 }
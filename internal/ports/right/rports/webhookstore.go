@@ -0,0 +1,25 @@
+package rports
+
+import (
+	"time"
+
+	"ecom-api/internal/application/core/types/entity"
+)
+
+// WebhookStore keeps the webhook endpoints of merchants and the deliveries of
+// events to them.
+type WebhookStore interface {
+	CreateWebhookEndpoint(endpoint entity.WebhookEndpoint) (string, error)                                                             // Register an endpoint
+	GetWebhookEndpointByID(id string) (*entity.WebhookEndpoint, error)                                                                 // Retrieve an endpoint, with an empty ID when it does not exist
+	GetWebhookEndpoints() ([]*entity.WebhookEndpoint, error)                                                                           // Retrieve every endpoint
+	GetWebhookEndpointsByStoreID(storeID string) ([]*entity.WebhookEndpoint, error)                                                    // Retrieve the endpoints of a store
+	GetActiveWebhookEndpoints(event string) ([]*entity.WebhookEndpoint, error)                                                         // Retrieve the active endpoints subscribed to an event
+	DeleteWebhookEndpoint(id string) (bool, error)                                                                                     // Remove an endpoint and its deliveries, false when it does not exist
+	AddWebhookDeliveries(deliveries ...entity.WebhookDelivery) error                                                                   // Queue deliveries, one of an event already queued for an endpoint is skipped
+	ClaimWebhookDeliveries(limit int, lease time.Duration) ([]*entity.WebhookDelivery, error)                                          // Take due deliveries, they become due again once the lease runs out
+	MarkWebhookDeliveryDelivered(id string, responseStatus int, responseBody string) error                                             // Record that the endpoint took the event
+	MarkWebhookDeliveryFailed(id string, responseStatus int, responseBody, lastError string, nextAttemptAt time.Time, dead bool) error // Record a failed attempt and when to retry, or give up when dead
+	GetWebhookDeliveryByID(id string) (*entity.WebhookDelivery, error)                                                                 // Retrieve a delivery, with an empty ID when it does not exist
+	GetWebhookDeliveriesByEndpointID(endpointID string) ([]*entity.WebhookDelivery, error)                                             // Retrieve the deliveries to an endpoint, newest first
+	RedeliverWebhookDelivery(id string) (bool, error)                                                                                  // Queue a delivery again right away, false when it is being posted
+}
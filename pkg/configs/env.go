@@ -17,6 +17,7 @@ type Config struct {
 	DBName                 string
 	JWTSecret              string
 	JWTExpirationInSeconds int64
+	OrderTokenTTLInSeconds int64
 	FromEmail              string
 	FromEmailPassword      string
 	FromEmailSMTP          string
@@ -25,6 +26,7 @@ type Config struct {
 	StripeSecretKey        string
 	StripePublshableKey    string
 	StripeWebhookSecret    string
+	StripeTestMode         bool
 	StripeWebhookId        string
 	StringWebhookUrl       string
 	OrderStatusPending     string
@@ -36,6 +38,27 @@ type Config struct {
 	PaymentStatusPending   string
 	PaymentStatusPaid      string
 	PaymentStatusRefunded  string
+	TaxPricing             string
+	TaxRounding            string
+	IdempotencyKeyTTL      int64
+	CheckoutSuccessURL     string
+	CheckoutCancelURL      string
+	PaymentEventAttempts   int64
+	PaymentEventInterval   int64
+	PaymentGateway         string
+	CommissionPercent      int64
+	LoyaltyPointsPerUnit   int64
+	LoyaltyTierWindow      int64
+	Mailer                 string
+	MailLogPath            string
+	EmailTemplateDir       string
+	OutboxAttempts         int64
+	OutboxInterval         int64
+	WebhookAttempts        int64
+	WebhookInterval        int64
+	WebhookTimeout         int64
+	EventBus               string
+	EventBusInterval       int64
 }
 
 var Envs = initConfig()
@@ -51,6 +74,7 @@ func initConfig() Config {
 		DBName:                 getEnv("DB_NAME", "ecom"),
 		JWTSecret:              getEnv("JWT_SECRET", "Uh3BnyZivL99alxVwRQpbjdkPFu2l9MnCSfgWn8HeXRPSlkXano7sdYYOwKhvpB+eq3mo9SRKpDTMdNqHOuQWA=="),
 		JWTExpirationInSeconds: getEnvAsInt("JWT_EXPIRATION_IN_SECONDS", 3600*24*7),
+		OrderTokenTTLInSeconds: getEnvAsInt("ORDER_TOKEN_TTL_IN_SECONDS", 3600*24*30),
 		FromEmail:              getEnv("FROM_EMAIL", ""),
 		FromEmailPassword:      getEnv("FROM_EMAIL_PASSWORD", ""),
 		FromEmailSMTP:          getEnv("FROM_EMAIL_SMTP", "smtp.gmail.com"),
@@ -59,6 +83,7 @@ func initConfig() Config {
 		StripeSecretKey:        getEnv("SECRET_KEY_STRIPE", ""),
 		StripePublshableKey:    getEnv("PUBLISHABLE_KEY_STRIPE", ""),
 		StripeWebhookSecret:    getEnv("WEBHOOK_SECRET_STRIPE", ""),
+		StripeTestMode:         getEnv("STRIPE_TEST_MODE", "false") == "true",
 		StripeWebhookId:        getEnv("WEBHOOK_ID", "we_1QmYMpIxe6f8RrXlfX5urS8g"),
 		StringWebhookUrl:       getEnv("WEBHOOK_URL", "https://dashboard.stripe.com/webhook"),
 		OrderStatusPending:     getEnv("ORDER_STATUS_PENDING", "pending"),
@@ -70,6 +95,27 @@ func initConfig() Config {
 		PaymentStatusPending:   getEnv("PAYMENT_STATUS_PENDING", "pending"),
 		PaymentStatusPaid:      getEnv("PAYMENT_STATUS_PAID", "paid"),
 		PaymentStatusRefunded:  getEnv("PAYMENT_STATUS_REFUNDED", "refunded"),
+		TaxPricing:             getEnv("TAX_PRICING", "exclusive"),
+		TaxRounding:            getEnv("TAX_ROUNDING", "line"),
+		IdempotencyKeyTTL:      getEnvAsInt("IDEMPOTENCY_KEY_TTL_IN_SECONDS", 3600*24),
+		CheckoutSuccessURL:     getEnv("CHECKOUT_SUCCESS_URL", "http://localhost:8080/api/v1/order/{ORDER_ID}"),
+		CheckoutCancelURL:      getEnv("CHECKOUT_CANCEL_URL", "http://localhost:8080/api/v1/cart"),
+		PaymentEventAttempts:   getEnvAsInt("PAYMENT_EVENT_MAX_ATTEMPTS", 8),
+		PaymentEventInterval:   getEnvAsInt("PAYMENT_EVENT_INTERVAL_IN_SECONDS", 5),
+		PaymentGateway:         getEnv("PAYMENT_GATEWAY", "stripe"),
+		CommissionPercent:      getEnvAsInt("DEFAULT_COMMISSION_PERCENT", 10),
+		LoyaltyPointsPerUnit:   getEnvAsInt("LOYALTY_POINTS_PER_UNIT", 100),
+		LoyaltyTierWindow:      getEnvAsInt("LOYALTY_TIER_WINDOW_IN_DAYS", 365),
+		Mailer:                 getEnv("MAILER", "smtp"),
+		MailLogPath:            getEnv("MAIL_LOG_PATH", ""),
+		EmailTemplateDir:       getEnv("EMAIL_TEMPLATE_DIR", "./static"),
+		OutboxAttempts:         getEnvAsInt("OUTBOX_MAX_ATTEMPTS", 10),
+		OutboxInterval:         getEnvAsInt("OUTBOX_INTERVAL_IN_SECONDS", 2),
+		WebhookAttempts:        getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookInterval:        getEnvAsInt("WEBHOOK_INTERVAL_IN_SECONDS", 5),
+		WebhookTimeout:         getEnvAsInt("WEBHOOK_TIMEOUT_IN_SECONDS", 10),
+		EventBus:               getEnv("EVENT_BUS", "memory"),
+		EventBusInterval:       getEnvAsInt("EVENT_BUS_INTERVAL_IN_MILLISECONDS", 500),
 	}
 }
 